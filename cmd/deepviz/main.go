@@ -3,11 +3,13 @@ package main
 import (
 	"os"
 
-	"deepviz/internal/app"
+	"github.com/yukiyan/deepviz/internal/app"
+	"github.com/yukiyan/deepviz/pkg/deepviz"
 )
 
 func main() {
-	if err := app.NewRootCommand().Execute(); err != nil {
-		os.Exit(1)
+	// The pipelines of the command run through the Client of the library
+	if err := app.NewRootCommand(app.WithPipelineRunner(deepviz.CommandRunner)).Execute(); err != nil {
+		os.Exit(app.ExitCode(err))
 	}
 }
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// AgentInfo is a Deep Research agent available to the API key.
+type AgentInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Default     bool   `json:"default"`            // Configured as deep_research_agent
+	Unlisted    bool   `json:"unlisted,omitempty"` // Configured but not returned by the API
+}
+
+// isDeepResearchModel reports whether a listed model is a Deep Research agent.
+func isDeepResearchModel(model ModelInfo) bool {
+	return strings.Contains(model.ID(), "deep-research")
+}
+
+// ListAgents returns the Deep Research agents with the configured one marked as default.
+//
+// The agents are listed from the models endpoint and cached for listingCacheTTL; refresh
+// ignores the cache. The configured agent is included even when the API does not list it.
+func (c *ModelsClient) ListAgents(ctx context.Context, refresh bool) ([]AgentInfo, error) {
+	cache, err := loadListingCache[AgentInfo](c.config.AgentsCachePath())
+	if err == nil && !refresh && cache.fresh(time.Now()) {
+		return markDefaultAgent(cache.Items, c.config.DeepResearchAgent), nil
+	}
+
+	agents, err := c.fetchAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return markDefaultAgent(agents, c.config.DeepResearchAgent), nil
+}
+
+// fetchAgents lists the Deep Research agents from the API and caches them.
+func (c *ModelsClient) fetchAgents(ctx context.Context) ([]AgentInfo, error) {
+	models, err := c.ListModels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+
+	var agents []AgentInfo
+	for _, model := range models {
+		if isDeepResearchModel(model) {
+			agents = append(agents, AgentInfo{Name: model.ID(), DisplayName: model.DisplayName, Description: model.Description})
+		}
+	}
+	if err := saveListingCache(c.config.AgentsCachePath(), agents, time.Now()); err != nil {
+		c.logger.Warn("Failed to cache agents", "error", err)
+	}
+	return agents, nil
+}
+
+// markDefaultAgent marks the configured agent, appending it when it is not listed.
+func markDefaultAgent(agents []AgentInfo, configured string) []AgentInfo {
+	found := false
+	for i := range agents {
+		if agents[i].Name == configured {
+			agents[i].Default = true
+			found = true
+		}
+	}
+	if !found && configured != "" {
+		agents = append(agents, AgentInfo{Name: configured, Default: true, Unlisted: true})
+	}
+	return agents
+}
+
+// listingError adds a hint for authentication failures to a model or agent listing error.
+func listingError(err error) error {
+	if errors.Is(err, ErrNoAPIKey) {
+		return &ConfigError{Err: err}
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.IsAuthError() {
+		return fmt.Errorf("%w (%s)", err, apiErr.Hint())
+	}
+	return err
+}
+
+// printAgents prints agents as a table with the default agent marked by "*".
+func printAgents(w io.Writer, agents []AgentInfo) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  NAME\tDESCRIPTION")
+	for _, agent := range agents {
+		marker := " "
+		if agent.Default {
+			marker = "*"
+		}
+		description := cmp.Or(agent.DisplayName, agent.Description)
+		if agent.Unlisted {
+			description = "(configured, not listed by the API)"
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\n", marker, agent.Name, description)
+	}
+	tw.Flush()
+}
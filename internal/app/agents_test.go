@@ -0,0 +1,103 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const testModelsJSON = `{"models":[
+	{"name":"models/deep-research-pro-preview-12-2025","displayName":"Deep Research Pro Preview"},
+	{"name":"models/gemini-3-pro-image-preview","displayName":"Gemini 3 Pro Image Preview"},
+	{"name":"models/deep-research-flash","description":"Faster research"}
+]}`
+
+func TestModelsClient_ListAgents(t *testing.T) {
+	var calls atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		fmt.Fprint(w, testModelsJSON)
+	})
+	config := &ViperConfig{StateDir: t.TempDir(), DeepResearchAgent: "deep-research-pro-preview-12-2025"}
+	client := newTestModelsClient(t, handler, config)
+
+	agents, err := client.ListAgents(context.Background(), false)
+	if err != nil {
+		t.Fatalf("ListAgents() error = %v", err)
+	}
+	if len(agents) != 2 {
+		t.Fatalf("agents = %+v, want the two deep-research models", agents)
+	}
+	if !agents[0].Default || agents[1].Default {
+		t.Errorf("agents = %+v, want only the configured agent as default", agents)
+	}
+
+	// The second listing is served from the cache
+	if _, err := client.ListAgents(context.Background(), false); err != nil {
+		t.Fatalf("ListAgents() error = %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("API calls = %d, want 1 (cached)", calls.Load())
+	}
+
+	// refresh ignores the cache
+	if _, err := client.ListAgents(context.Background(), true); err != nil {
+		t.Fatalf("ListAgents() error = %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("API calls = %d, want 2 after refresh", calls.Load())
+	}
+}
+
+func TestModelsClient_ListAgents_UnlistedDefault(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testModelsJSON)
+	})
+	config := &ViperConfig{StateDir: t.TempDir(), DeepResearchAgent: "deep-research-custom"}
+	client := newTestModelsClient(t, handler, config)
+
+	agents, err := client.ListAgents(context.Background(), false)
+	if err != nil {
+		t.Fatalf("ListAgents() error = %v", err)
+	}
+	last := agents[len(agents)-1]
+	if last.Name != "deep-research-custom" || !last.Default || !last.Unlisted {
+		t.Errorf("last agent = %+v, want the configured agent marked as unlisted default", last)
+	}
+}
+
+func TestPrintAgents(t *testing.T) {
+	var buf bytes.Buffer
+	printAgents(&buf, []AgentInfo{
+		{Name: "deep-research-pro", DisplayName: "Deep Research Pro", Default: true},
+		{Name: "deep-research-custom", Unlisted: true},
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output = %q, want a header and two agents", buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "* deep-research-pro") || !strings.Contains(lines[1], "Deep Research Pro") {
+		t.Errorf("default agent line = %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "not listed") {
+		t.Errorf("unlisted agent line = %q", lines[2])
+	}
+}
+
+func TestListingError(t *testing.T) {
+	var configErr *ConfigError
+	if err := listingError(ErrNoAPIKey); !errors.As(err, &configErr) || !strings.Contains(err.Error(), "GEMINI_API_KEY") {
+		t.Errorf("listingError(ErrNoAPIKey) = %v, want a ConfigError asking for GEMINI_API_KEY", err)
+	}
+
+	err := listingError(fmt.Errorf("failed to list agents: %w", &APIError{Operation: OpListModels, StatusCode: 403}))
+	if !strings.Contains(err.Error(), "check GEMINI_API_KEY") {
+		t.Errorf("listingError() = %v, want the auth hint", err)
+	}
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// apiKeyCmdTimeout bounds api_key_cmd, which may wait for a secret manager to be unlocked.
+const apiKeyCmdTimeout = 30 * time.Second
+
+// apiKeysSource is the source of an API key taken from api_keys.
+const apiKeysSource = "config file (api_keys)"
+
+// apiKeysSetting returns the keys of api_keys, a YAML list or a comma-separated string.
+func apiKeysSetting(v *viper.Viper) []string {
+	var keys []string
+	for _, key := range strings.Split(strings.Join(v.GetStringSlice("api_keys"), ","), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// resolveAPIKey returns the API key of the configuration and where it comes from.
+//
+// Priority (high to low): DEEPVIZ_API_KEY (env), GEMINI_API_KEY (env), api_key_file, api_key_cmd,
+// api_keys (its first key), api_key, then the keyring of the OS (opened with keyring) with use_keyring. The file, the command
+// and the keyring are only read when no key of a higher priority is set.
+func resolveAPIKey(v *viper.Viper, keyring func() (Keyring, error)) (apiKey, source string, err error) {
+	for _, name := range []string{"DEEPVIZ_API_KEY", "GEMINI_API_KEY"} {
+		if apiKey := os.Getenv(name); apiKey != "" {
+			return apiKey, "env " + name, nil
+		}
+	}
+	if path := v.GetString("api_key_file"); path != "" {
+		apiKey, err := ReadAPIKeyFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid api_key_file: %w", err)
+		}
+		return apiKey, "file " + path, nil
+	}
+	if command := v.GetString("api_key_cmd"); command != "" {
+		apiKey, err := RunAPIKeyCmd(command)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid api_key_cmd: %w", err)
+		}
+		return apiKey, "command", nil
+	}
+	if keys := apiKeysSetting(v); len(keys) > 0 {
+		return keys[0], apiKeysSource, nil
+	}
+	if apiKey := v.GetString("api_key"); apiKey != "" {
+		return apiKey, "config file", nil
+	}
+	if v.GetBool("use_keyring") {
+		apiKey, err := readKeyring(keyring)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid use_keyring: %w", err)
+		}
+		return apiKey, "keyring", nil
+	}
+	return "", "", nil
+}
+
+// apiKeyLoader resolves the API key of a configuration with resolveAPIKey the first time a
+// command needs it (see ViperConfig.loadAPIKey), so that api_key_cmd and the keyring are neither
+// run nor read by the commands which don't call the API. It is shared by the copies of the
+// configuration (e.g., the runs of a batch), which resolve the key once.
+type apiKeyLoader struct {
+	v       *viper.Viper
+	keyring func() (Keyring, error)
+
+	once   sync.Once
+	apiKey string
+	source string
+	keys   []string    // Keys of api_keys when rotated
+	pool   *APIKeyPool // Pool of keys, nil without rotation
+	err    error
+}
+
+// load resolves the API key once, returning the result of the first call to every call.
+func (l *apiKeyLoader) load() error {
+	l.once.Do(func() {
+		l.apiKey, l.source, l.err = resolveAPIKey(l.v, l.keyring)
+		// The keys of api_keys are rotated when they are used
+		if keys := apiKeysSetting(l.v); l.err == nil && l.source == apiKeysSource && len(keys) > 1 {
+			l.keys, l.pool = keys, NewAPIKeyPool(keys)
+		}
+	})
+	return l.err
+}
+
+// loadAPIKey sets APIKey (with its source and the keys of api_keys to rotate) from the environment
+// and the config file, the first time it is called. A configuration without a config loaded by
+// NewViperConfig (e.g., the library's) or with APIKey or APIKeys already set is left as is.
+func (c *ViperConfig) loadAPIKey() error {
+	if c.apiKeyLoader == nil || c.APIKey != "" || len(c.APIKeys) > 0 {
+		return nil
+	}
+	l := c.apiKeyLoader
+	if err := l.load(); err != nil {
+		return err
+	}
+	c.APIKey, c.apiKeySource, c.APIKeys, c.apiKeyPool = l.apiKey, l.source, l.keys, l.pool
+	return nil
+}
+
+// ReadAPIKeyFile reads the API key from a file (the api_key_file setting), without its trailing
+// whitespace and newline.
+func ReadAPIKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("API key file %s does not exist", path)
+		}
+		return "", fmt.Errorf("failed to read API key file: %w", err)
+	}
+	apiKey := strings.TrimRight(string(data), " \t\r\n")
+	if apiKey == "" {
+		return "", fmt.Errorf("API key file %s is empty", path)
+	}
+	return apiKey, nil
+}
+
+// RunAPIKeyCmd runs a command with the shell (the api_key_cmd setting) and returns its output
+// without the trailing whitespace and newline as the API key.
+func RunAPIKeyCmd(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), apiKeyCmdTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/c", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("API key command did not finish within %s", apiKeyCmdTimeout)
+	}
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("API key command failed: %w: %s", err, message)
+		}
+		return "", fmt.Errorf("API key command failed: %w", err)
+	}
+	apiKey := strings.TrimRight(string(output), " \t\r\n")
+	if apiKey == "" {
+		return "", errors.New("API key command printed nothing")
+	}
+	return apiKey, nil
+}
+
+// readKeyring reads the API key from the keyring opened with keyring.
+func readKeyring(keyring func() (Keyring, error)) (string, error) {
+	k, err := keyring()
+	if err != nil {
+		return "", err
+	}
+	return k.Get()
+}
+
+// secrets returns the API keys of the configuration, masked in the logs and saved files.
+func (c *ViperConfig) secrets() []string {
+	if len(c.APIKeys) > 0 {
+		return c.APIKeys
+	}
+	return []string{c.APIKey}
+}
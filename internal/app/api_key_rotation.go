@@ -0,0 +1,216 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// APIKeyPool holds the API keys of api_keys and the one in use. It is shared by the runs of a
+// batch: when a key hits its quota, every run switches to the next one.
+//
+// An interaction can only be read with the key that created it, so the requests of an
+// interaction (polls, resume, cancel) are pinned to that key instead of the one in use.
+type APIKeyPool struct {
+	mu           sync.Mutex
+	keys         []string
+	current      int
+	interactions map[string]int // Index of the key that created each interaction
+}
+
+// NewAPIKeyPool creates a pool starting with the first of keys.
+func NewAPIKeyPool(keys []string) *APIKeyPool {
+	return &APIKeyPool{keys: keys, interactions: make(map[string]int)}
+}
+
+// Current returns the key in use and its index.
+func (p *APIKeyPool) Current() (int, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.current, p.keys[p.current]
+}
+
+// Rotate switches from the key of index exhausted to the next one and returns the key in use. When
+// another request already switched away from it, the key it switched to is kept.
+func (p *APIKeyPool) Rotate(exhausted int) (int, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current == exhausted {
+		p.current = (p.current + 1) % len(p.keys)
+	}
+	return p.current, p.keys[p.current]
+}
+
+// Len returns the number of keys.
+func (p *APIKeyPool) Len() int {
+	return len(p.keys)
+}
+
+// Pin pins the requests of an interaction to the key of index (e.g., the key recorded by the run
+// being resumed).
+func (p *APIKeyPool) Pin(interactionID string, index int) error {
+	if index < 0 || index >= len(p.keys) {
+		return fmt.Errorf("key index %d out of range: api_keys has %d keys", index, len(p.keys))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.interactions[interactionID] = index
+	return nil
+}
+
+// Pinned returns the key the requests of an interaction are pinned to and its index.
+func (p *APIKeyPool) Pinned(interactionID string) (int, string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	index, ok := p.interactions[interactionID]
+	if !ok {
+		return 0, "", false
+	}
+	return index, p.keys[index], true
+}
+
+// apiKeyRotation authenticates the API requests of a run with the key in use of a pool, switching
+// to the next key when a request fails with RESOURCE_EXHAUSTED (429).
+type apiKeyRotation struct {
+	pool   *APIKeyPool
+	logger Logger
+
+	mu   sync.Mutex
+	used int // Index of the key of the last request
+}
+
+// newAPIKeyRotation creates the key rotation of a run logging the switches to logger.
+func newAPIKeyRotation(pool *APIKeyPool, logger Logger) *apiKeyRotation {
+	used, _ := pool.Current()
+	return &apiKeyRotation{pool: pool, logger: logger, used: used}
+}
+
+// Used returns the index and the masked key of the last request of the run. The key of the
+// research of a run is the one its interaction is pinned to (see APIKeyPool.Pinned).
+func (r *apiKeyRotation) Used() (int, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.used, maskAPIKey(r.pool.keys[r.used])
+}
+
+// Transport returns a transport sending the requests to base with the key in use.
+func (r *apiKeyRotation) Transport(base http.RoundTripper) http.RoundTripper {
+	return &apiKeyTransport{base: base, rotation: r}
+}
+
+// apiKeyTransport is the transport of an apiKeyRotation.
+type apiKeyTransport struct {
+	base     http.RoundTripper
+	rotation *apiKeyRotation
+}
+
+// RoundTrip sends the request with the key in use, then with the next keys while it fails with
+// RESOURCE_EXHAUSTED. Once every key is exhausted, the last response is returned and the request
+// is retried as usual (max_retries, retry_max_wait).
+//
+// The requests of a pinned interaction are sent with its key only, and the interactions created
+// are pinned to the key that created them.
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	pool := t.rotation.pool
+	if id := interactionIDOf(req.URL.Path); id != "" {
+		if index, key, ok := pool.Pinned(id); ok {
+			return t.send(req, index, key)
+		}
+	}
+
+	index, key := pool.Current()
+	for tried := 1; ; tried++ {
+		attempt := req
+		if tried > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt = req.Clone(req.Context())
+			attempt.Body = body
+		}
+		resp, err := t.send(attempt, index, key)
+		if err != nil || !isResourceExhausted(resp) {
+			if err == nil && req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/interactions") {
+				if id := createdInteractionID(resp); id != "" {
+					pool.Pin(id, index)
+				}
+			}
+			return resp, err
+		}
+		next, nextKey := pool.Rotate(index)
+		t.rotation.logger.Warn("API key quota exhausted, switching to the next key", "key_index", index, "key", maskAPIKey(key), "next_key_index", next, "next_key", maskAPIKey(nextKey))
+		// The request is sent again with each key once, when its body can be read again
+		if tried >= pool.Len() || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+		resp.Body.Close()
+		index, key = next, nextKey
+	}
+}
+
+// send sends the request with the key of index.
+func (t *apiKeyTransport) send(req *http.Request, index int, key string) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("x-goog-api-key", key)
+	t.rotation.mu.Lock()
+	t.rotation.used = index
+	t.rotation.mu.Unlock()
+	return t.base.RoundTrip(req)
+}
+
+// interactionIDOf returns the interaction ID of a request path of an interaction (e.g.,
+// /v1beta/interactions/<id> or /v1beta/interactions/<id>/cancel), or "".
+func interactionIDOf(path string) string {
+	_, rest, ok := strings.Cut(path, "/interactions/")
+	if !ok {
+		return ""
+	}
+	id, _, _ := strings.Cut(rest, "/")
+	return id
+}
+
+// createdInteractionID returns the ID of the interaction created by a successful response, or "".
+// The body is read and replaced, to be read again by the caller.
+func createdInteractionID(resp *http.Response) string {
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var interaction struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal(body, &interaction) != nil {
+		return ""
+	}
+	return interaction.ID
+}
+
+// isResourceExhausted reports whether a response is a quota error (429 RESOURCE_EXHAUSTED). The
+// body of a quota error is read and replaced, to be read again by the caller.
+func isResourceExhausted(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	var errorBody struct {
+		Error struct {
+			Status string `json:"status"`
+		} `json:"error"`
+	}
+	return json.Unmarshal(body, &errorBody) == nil && errorBody.Error.Status == "RESOURCE_EXHAUSTED"
+}
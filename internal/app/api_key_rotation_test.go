@@ -0,0 +1,174 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAPIKeyPool_Rotate(t *testing.T) {
+	pool := NewAPIKeyPool([]string{"key-a", "key-b", "key-c"})
+	if index, key := pool.Current(); index != 0 || key != "key-a" {
+		t.Errorf("Current() = %d, %s, want the first key", index, key)
+	}
+
+	// Concurrent requests exhausting the same key switch once
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() { pool.Rotate(0) })
+	}
+	wg.Wait()
+	if index, key := pool.Current(); index != 1 || key != "key-b" {
+		t.Errorf("Current() = %d, %s, want the second key", index, key)
+	}
+
+	pool.Rotate(1)
+	if index, _ := pool.Rotate(2); index != 0 {
+		t.Errorf("Rotate() of the last key = %d, want the first key again", index)
+	}
+}
+
+func TestAPIKeyTransport(t *testing.T) {
+	const exhausted = `{"error": {"code": 429, "message": "Resource has been exhausted (e.g. check quota).", "status": "RESOURCE_EXHAUSTED"}}`
+	tests := []struct {
+		name          string
+		exhaustedKeys []string // Keys answered with RESOURCE_EXHAUSTED
+		body          string   // Body of the 429 responses
+		wantKeys      []string // Keys of the requests received
+		wantStatus    int
+		wantUsed      int
+	}{
+		{name: "first key", wantKeys: []string{"key-a"}, wantStatus: http.StatusOK, wantUsed: 0},
+		{name: "switch", exhaustedKeys: []string{"key-a"}, body: exhausted, wantKeys: []string{"key-a", "key-b"}, wantStatus: http.StatusOK, wantUsed: 1},
+		{name: "all exhausted", exhaustedKeys: []string{"key-a", "key-b", "key-c"}, body: exhausted, wantKeys: []string{"key-a", "key-b", "key-c"}, wantStatus: http.StatusTooManyRequests, wantUsed: 2},
+		{name: "other 429", exhaustedKeys: []string{"key-a"}, body: `{"error": {"code": 429, "status": "UNAVAILABLE"}}`, wantKeys: []string{"key-a"}, wantStatus: http.StatusTooManyRequests, wantUsed: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var keys []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				key := r.Header.Get("x-goog-api-key")
+				mu.Lock()
+				keys = append(keys, key)
+				mu.Unlock()
+				if string(body) != `{"input":"test"}` {
+					t.Errorf("body = %q, want the request body", body)
+				}
+				if slices.Contains(tt.exhaustedKeys, key) {
+					w.WriteHeader(http.StatusTooManyRequests)
+					w.Write([]byte(tt.body))
+					return
+				}
+				w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			pool := NewAPIKeyPool([]string{"key-a", "key-b", "key-c"})
+			rotation := newAPIKeyRotation(pool, NewNullLogger())
+			client := &http.Client{Transport: rotation.Transport(http.DefaultTransport)}
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"input":"test"}`))
+			req.Header.Set("x-goog-api-key", "key-a")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus || !slices.Equal(keys, tt.wantKeys) {
+				t.Errorf("status = %d, keys = %v, want %d with %v", resp.StatusCode, keys, tt.wantStatus, tt.wantKeys)
+			}
+			if resp.StatusCode == http.StatusTooManyRequests && string(body) != tt.body {
+				t.Errorf("body = %q, want the body of the quota error", body)
+			}
+			if index, key := rotation.Used(); index != tt.wantUsed || key != maskAPIKey(pool.keys[tt.wantUsed]) {
+				t.Errorf("Used() = %d, %s, want key %d", index, key, tt.wantUsed)
+			}
+		})
+	}
+}
+
+func TestAPIKeyTransport_PinnedInteraction(t *testing.T) {
+	const exhausted = `{"error": {"code": 429, "status": "RESOURCE_EXHAUSTED"}}`
+	var mu sync.Mutex
+	var keys []string
+	exhaustedKeys := []string{"key-a"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("x-goog-api-key")
+		mu.Lock()
+		keys = append(keys, r.Method+" "+r.URL.Path+" "+key)
+		exhausted429 := slices.Contains(exhaustedKeys, key)
+		mu.Unlock()
+		if exhausted429 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(exhausted))
+			return
+		}
+		w.Write([]byte(`{"id": "interaction-1", "status": "in_progress"}`))
+	}))
+	defer server.Close()
+
+	pool := NewAPIKeyPool([]string{"key-a", "key-b", "key-c"})
+	rotation := newAPIKeyRotation(pool, NewNullLogger())
+	client := &http.Client{Transport: rotation.Transport(http.DefaultTransport)}
+	do := func(method, path string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(`{"input":"test"}`))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp
+	}
+
+	// key-a is exhausted: the interaction is created (and pinned) with key-b
+	do(http.MethodPost, "/v1beta/interactions")
+	if index, key, ok := pool.Pinned("interaction-1"); !ok || index != 1 || key != "key-b" {
+		t.Fatalf("Pinned() = %d, %s, %t, want key-b", index, key, ok)
+	}
+
+	// The polls keep key-b after the pool switched away from it, even when it is exhausted
+	mu.Lock()
+	exhaustedKeys = []string{"key-a", "key-b"}
+	keys = nil
+	mu.Unlock()
+	pool.Rotate(1)
+	if resp := do(http.MethodGet, "/v1beta/interactions/interaction-1"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want the quota error of the pinned key", resp.StatusCode)
+	}
+	do(http.MethodPost, "/v1beta/interactions/interaction-1/cancel")
+	want := []string{"GET /v1beta/interactions/interaction-1 key-b", "POST /v1beta/interactions/interaction-1/cancel key-b"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("requests = %v, want %v", keys, want)
+	}
+	if index, _ := rotation.Used(); index != 1 {
+		t.Errorf("Used() = %d, want the pinned key", index)
+	}
+
+	if err := pool.Pin("interaction-2", 3); err == nil {
+		t.Error("Pin() with an index out of range succeeded")
+	}
+}
+
+func TestInteractionIDOf(t *testing.T) {
+	tests := map[string]string{
+		"/v1beta/interactions":                  "",
+		"/v1beta/interactions/abc":              "abc",
+		"/v1beta/interactions/abc/cancel":       "abc",
+		"/v1beta/models/gemini:generateContent": "",
+	}
+	for path, want := range tests {
+		if got := interactionIDOf(path); got != want {
+			t.Errorf("interactionIDOf(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestReadAPIKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content *string // nil for a missing file
+		want    string
+		wantErr string
+	}{
+		{name: "key", content: ptr("AIzaTestKey"), want: "AIzaTestKey"},
+		{name: "trailing newline", content: ptr("AIzaTestKey \r\n"), want: "AIzaTestKey"},
+		{name: "empty file", content: ptr("\n"), wantErr: "is empty"},
+		{name: "missing file", wantErr: "does not exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_"))
+			if tt.content != nil {
+				if err := os.WriteFile(path, []byte(*tt.content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := ReadAPIKeyFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ReadAPIKeyFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ReadAPIKeyFile() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunAPIKeyCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh commands")
+	}
+	tests := []struct {
+		command string
+		want    string
+		wantErr string
+	}{
+		{command: "echo AIzaTestKey", want: "AIzaTestKey"},
+		{command: "printf 'AIzaTestKey\\n\\n'", want: "AIzaTestKey"},
+		{command: "echo 'vault is locked' >&2; exit 1", wantErr: "vault is locked"},
+		{command: "true", wantErr: "printed nothing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got, err := RunAPIKeyCmd(tt.command)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("RunAPIKeyCmd() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("RunAPIKeyCmd() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewViperConfig_APIKeySources(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh commands")
+	}
+	keyFile := filepath.Join(t.TempDir(), "gemini")
+	if err := os.WriteFile(keyFile, []byte("file-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		env        map[string]string
+		config     string
+		wantKey    string
+		wantSource string
+		wantErr    string
+	}{
+		{name: "config file", config: "api_key: config-key\n", wantKey: "config-key", wantSource: "config file"},
+		{name: "file over config", config: "api_key: config-key\napi_key_file: " + keyFile + "\n", wantKey: "file-key", wantSource: "file " + keyFile},
+		{name: "file from env", env: map[string]string{"DEEPVIZ_API_KEY_FILE": keyFile}, wantKey: "file-key", wantSource: "file " + keyFile},
+		{name: "command", config: "api_key: config-key\napi_key_cmd: echo cmd-key\n", wantKey: "cmd-key", wantSource: "command"},
+		{name: "file over command", config: "api_key_file: " + keyFile + "\napi_key_cmd: echo cmd-key\n", wantKey: "file-key", wantSource: "file " + keyFile},
+		{name: "env over file", env: map[string]string{"GEMINI_API_KEY": "env-key"}, config: "api_key_file: /missing\n", wantKey: "env-key", wantSource: "env GEMINI_API_KEY"},
+		{name: "missing file", config: "api_key_file: /missing/gemini\n", wantErr: "invalid api_key_file"},
+		{name: "failing command", config: "api_key_cmd: exit 1\n", wantErr: "invalid api_key_cmd"},
+		{name: "api keys", config: "api_key: config-key\napi_keys: [key-a, key-b]\n", wantKey: "key-a", wantSource: apiKeysSource},
+		{name: "comma-separated api keys", env: map[string]string{"DEEPVIZ_API_KEYS": "key-a, key-b"}, wantKey: "key-a", wantSource: apiKeysSource},
+		{name: "env over api keys", env: map[string]string{"DEEPVIZ_API_KEY": "env-key"}, config: "api_keys: [key-a, key-b]\n", wantKey: "env-key", wantSource: "env DEEPVIZ_API_KEY"},
+		{name: "no key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"DEEPVIZ_API_KEY", "GEMINI_API_KEY", "DEEPVIZ_API_KEY_FILE", "DEEPVIZ_API_KEY_CMD", "DEEPVIZ_API_KEYS"} {
+				t.Setenv(name, tt.env[name])
+			}
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := NewViperConfig(dir)
+			if err != nil {
+				t.Fatalf("NewViperConfig() error = %v", err)
+			}
+			err = config.loadAPIKey()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadAPIKey() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadAPIKey() error = %v", err)
+			}
+			if config.APIKey != tt.wantKey || config.apiKeySource != tt.wantSource {
+				t.Errorf("API key = %q from %q, want %q from %q", config.APIKey, config.apiKeySource, tt.wantKey, tt.wantSource)
+			}
+			// Only several keys of api_keys are rotated
+			if rotated := tt.wantSource == apiKeysSource; (config.apiKeyPool != nil) != rotated || (len(config.APIKeys) == 2) != rotated {
+				t.Errorf("APIKeys = %v, pool = %v, want a pool of api_keys only", config.APIKeys, config.apiKeyPool)
+			}
+		})
+	}
+}
+
+func TestViperConfig_LoadAPIKeyOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command appends with a POSIX shell")
+	}
+	t.Setenv("DEEPVIZ_API_KEY", "")
+	t.Setenv("GEMINI_API_KEY", "")
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	t.Setenv("DEEPVIZ_API_KEY_CMD", "echo >> "+calls+"; echo cmd-key")
+
+	config, err := NewViperConfig(dir)
+	if err != nil {
+		t.Fatalf("NewViperConfig() error = %v", err)
+	}
+	if _, err := os.Stat(calls); !os.IsNotExist(err) {
+		t.Fatalf("api_key_cmd ran when loading the config (stat error = %v)", err)
+	}
+
+	// The copies of the config (e.g., the runs of a batch) share the resolved key
+	run := *config
+	for _, c := range []*ViperConfig{config, &run, config} {
+		if err := c.loadAPIKey(); err != nil {
+			t.Fatalf("loadAPIKey() error = %v", err)
+		}
+		if c.APIKey != "cmd-key" || c.apiKeySource != "command" {
+			t.Errorf("API key = %q from %q, want cmd-key from command", c.APIKey, c.apiKeySource)
+		}
+	}
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 1 {
+		t.Errorf("api_key_cmd ran %d times, want once", n)
+	}
+}
+
+func TestResolveAPIKey_Keyring(t *testing.T) {
+	errUnavailable := errors.New("no keyring is supported")
+	tests := []struct {
+		name       string
+		settings   map[string]any
+		keyring    *fakeKeyring
+		openErr    error
+		wantKey    string
+		wantSource string
+		wantErr    bool
+	}{
+		{name: "keyring", settings: map[string]any{"use_keyring": true}, keyring: &fakeKeyring{apiKey: "keyring-key"}, wantKey: "keyring-key", wantSource: "keyring"},
+		{name: "keyring disabled", keyring: &fakeKeyring{apiKey: "keyring-key"}},
+		{name: "config file over keyring", settings: map[string]any{"use_keyring": true, "api_key": "config-key"}, keyring: &fakeKeyring{apiKey: "keyring-key"}, wantKey: "config-key", wantSource: "config file"},
+		{name: "empty keyring", settings: map[string]any{"use_keyring": true}, keyring: &fakeKeyring{}, wantErr: true},
+		{name: "no keyring", settings: map[string]any{"use_keyring": true}, openErr: errUnavailable, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEEPVIZ_API_KEY", "")
+			t.Setenv("GEMINI_API_KEY", "")
+			v := viper.New()
+			setViperDefaults(v)
+			for key, value := range tt.settings {
+				v.Set(key, value)
+			}
+
+			apiKey, source, err := resolveAPIKey(v, func() (Keyring, error) { return tt.keyring, tt.openErr })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveAPIKey() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "invalid use_keyring") {
+				t.Errorf("resolveAPIKey() error = %v, want it to name use_keyring", err)
+			}
+			if apiKey != tt.wantKey || source != tt.wantSource {
+				t.Errorf("resolveAPIKey() = %q from %q, want %q from %q", apiKey, source, tt.wantKey, tt.wantSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// clock abstracts time so that polling can be tested without real waits.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is a clock backed by the time package.
+type realClock struct{}
+
+// Now returns the current time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for the duration to elapse and then sends the current time on the returned channel.
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+const (
+	// pollBackoffMultiplier is the growth factor applied to the interval after each poll.
+	pollBackoffMultiplier = 1.5
+	// pollBackoffJitter is the maximum relative jitter applied to each interval (±10%).
+	pollBackoffJitter = 0.1
+)
+
+// pollBackoff computes the wait between status checks.
+//
+// The interval starts at initial and grows by pollBackoffMultiplier up to maxInterval.
+// When initial equals maxInterval, the interval is fixed and no jitter is applied.
+type pollBackoff struct {
+	initial     time.Duration
+	maxInterval time.Duration
+	current     time.Duration
+	rand        func() float64
+}
+
+// newPollBackoff creates a new pollBackoff.
+//
+// If maxInterval is smaller than initial, initial is used as the maximum.
+func newPollBackoff(initial, maxInterval time.Duration) *pollBackoff {
+	if maxInterval < initial {
+		maxInterval = initial
+	}
+	return &pollBackoff{
+		initial:     initial,
+		maxInterval: maxInterval,
+		current:     initial,
+		rand:        rand.Float64,
+	}
+}
+
+// Next returns the next wait duration and advances the schedule.
+func (b *pollBackoff) Next() time.Duration {
+	delay := b.current
+
+	// Apply jitter only when the interval is adaptive
+	if b.initial < b.maxInterval {
+		factor := 1 + pollBackoffJitter*(2*b.rand()-1)
+		delay = time.Duration(float64(delay) * factor)
+		delay = min(max(delay, b.initial), b.maxInterval)
+	}
+
+	b.current = min(time.Duration(float64(b.current)*pollBackoffMultiplier), b.maxInterval)
+
+	return delay
+}
+
+// Reset restarts the schedule from the initial interval.
+func (b *pollBackoff) Reset() {
+	b.current = b.initial
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that advances instantly and records every wait.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.waits = append(c.waits, d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *fakeClock) Waits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.waits...)
+}
+
+// TestPollBackoff_Schedule tests the exponential schedule without jitter.
+func TestPollBackoff_Schedule(t *testing.T) {
+	b := newPollBackoff(10*time.Second, 60*time.Second)
+	b.rand = func() float64 { return 0.5 } // No jitter
+
+	want := []time.Duration{
+		10 * time.Second,
+		15 * time.Second,
+		22500 * time.Millisecond,
+		33750 * time.Millisecond,
+		50625 * time.Millisecond,
+		60 * time.Second,
+		60 * time.Second,
+	}
+	for i, w := range want {
+		if got := b.Next(); got != w {
+			t.Errorf("Next() #%d = %v, want %v", i, got, w)
+		}
+	}
+}
+
+// TestPollBackoff_Reset tests that Reset restarts from the initial interval.
+func TestPollBackoff_Reset(t *testing.T) {
+	b := newPollBackoff(10*time.Second, 60*time.Second)
+	b.rand = func() float64 { return 0.5 }
+
+	b.Next()
+	b.Next()
+	b.Reset()
+
+	if got := b.Next(); got != 10*time.Second {
+		t.Errorf("Next() after Reset = %v, want 10s", got)
+	}
+}
+
+// TestPollBackoff_Fixed tests that equal intervals keep the fixed cadence.
+func TestPollBackoff_Fixed(t *testing.T) {
+	b := newPollBackoff(10*time.Second, 10*time.Second)
+	b.rand = func() float64 { return 0.99 } // Jitter must not be applied
+
+	for i := 0; i < 5; i++ {
+		if got := b.Next(); got != 10*time.Second {
+			t.Errorf("Next() #%d = %v, want 10s", i, got)
+		}
+	}
+}
+
+// TestPollBackoff_MaxSmallerThanInitial tests that a smaller max falls back to the fixed interval.
+func TestPollBackoff_MaxSmallerThanInitial(t *testing.T) {
+	b := newPollBackoff(10*time.Second, 0)
+
+	if got := b.Next(); got != 10*time.Second {
+		t.Errorf("Next() = %v, want 10s", got)
+	}
+}
+
+// TestPollBackoff_Jitter tests that jitter stays within bounds.
+func TestPollBackoff_Jitter(t *testing.T) {
+	tests := []struct {
+		name string
+		rand float64
+		want time.Duration
+	}{
+		{name: "lowest jitter is clamped to initial", rand: 0, want: 10 * time.Second},
+		{name: "highest jitter", rand: 1, want: 11 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newPollBackoff(10*time.Second, 60*time.Second)
+			b.rand = func() float64 { return tt.rand }
+
+			if got := b.Next(); got != tt.want {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// batchFailure is a prompt file that failed in a batch run.
+type batchFailure struct {
+	File string
+	Err  error
+}
+
+// expandPromptFiles expands glob patterns in prompt file arguments.
+//
+// Arguments without glob metacharacters are kept as-is so that a missing file is reported when it is read.
+// A pattern matching no file is an error.
+func expandPromptFiles(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, "*?[") {
+			files = append(files, pattern)
+			continue
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no prompt files match %q", pattern)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// runBatch executes the pipeline for each prompt file.
+//
+// Up to Concurrency files run in parallel. Each file gets its own timestamp, log file, and artifacts.
+// A failed file is reported and the remaining files still run unless FailFast is set. An interrupt
+// stops starting new files.
+func runBatch(ctx context.Context, opts *Options, config *ViperConfig, files []string) error {
+	errs := runPool(ctx, opts.Concurrency, len(files), opts.FailFast, func(ctx context.Context, i int) error {
+		file := files[i]
+		if !opts.Quiet && !opts.JSON {
+			fmt.Fprintf(opts.stdout(), "\n=== [%d/%d] %s ===\n", i+1, len(files), file)
+		}
+
+		// Each file starts from the same options and configuration (front matter applies per file)
+		fileOpts := *opts
+		fileOpts.File = file
+		fileOpts.Tags = slices.Clone(opts.Tags)
+		fileConfig := *config
+
+		err := runPipeline(ctx, &fileOpts, &fileConfig)
+		if err != nil && opts.Progress == nil {
+			fmt.Fprintf(os.Stderr, "%s %s: %v\n", NewStyler(os.Stderr).Error(msg("batch.failed")), file, err)
+		}
+		return err
+	})
+
+	attempted := 0
+	var failures []batchFailure
+	for i, err := range errs {
+		if errors.Is(err, errTaskSkipped) {
+			continue
+		}
+		attempted++
+		if err != nil {
+			failures = append(failures, batchFailure{File: files[i], Err: err})
+		}
+	}
+
+	// Quiet and JSON modes print only the results; failures are returned
+	if !opts.Quiet && !opts.JSON {
+		printBatchSummary(opts.stdout(), len(files), attempted, failures)
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	joined := make([]error, len(failures))
+	for i, failure := range failures {
+		joined[i] = fmt.Errorf("%s: %w", failure.File, failure.Err)
+	}
+	return fmt.Errorf("%d of %d prompt files failed: %w", len(failures), len(files), errors.Join(joined...))
+}
+
+// printBatchSummary prints the aggregate result of a batch run.
+func printBatchSummary(w io.Writer, total, attempted int, failures []batchFailure) {
+	style := NewStyler(w)
+	fmt.Fprintf(w, "\n%s\n", style.Success(msg("batch.completed")))
+	fmt.Fprintln(w, msg("batch.succeeded", attempted-len(failures), total))
+	if skipped := total - attempted; skipped > 0 {
+		fmt.Fprintln(w, msg("batch.skipped", skipped))
+	}
+	for _, failure := range failures {
+		fmt.Fprintf(w, "%s %s: %v\n", style.Error(msg("batch.failed")), failure.File, failure.Err)
+	}
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandPromptFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.md", "a.md", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("prompt"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "literal paths are kept",
+			patterns: []string{"missing.md", filepath.Join(dir, "notes.txt")},
+			want:     []string{"missing.md", filepath.Join(dir, "notes.txt")},
+		},
+		{
+			name:     "glob is expanded in order",
+			patterns: []string{filepath.Join(dir, "*.md")},
+			want:     []string{filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md")},
+		},
+		{
+			name:     "glob without match",
+			patterns: []string{filepath.Join(dir, "*.yaml")},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid glob",
+			patterns: []string{filepath.Join(dir, "[.md")},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandPromptFiles(tt.patterns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandPromptFiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expandPromptFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newBatchTestConfig(t *testing.T) *ViperConfig {
+	t.Helper()
+	return &ViperConfig{
+		OutputDir: t.TempDir(),
+		StateDir:  t.TempDir(),
+		ImageLang: "English",
+	}
+}
+
+func TestRunBatch_ContinuesAfterFailure(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.md")
+	if err := os.WriteFile(good, []byte("Research AI trends"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.md")
+
+	// Dry-run keeps the pipeline offline
+	opts := &Options{DryRun: true, ImageOnly: true}
+	err := runBatch(context.Background(), opts, newBatchTestConfig(t), []string{missing, good, good})
+	if err == nil {
+		t.Fatal("runBatch() should return error when a file fails")
+	}
+	if !strings.Contains(err.Error(), "1 of 3 prompt files failed") {
+		t.Errorf("error = %v, want 1 of 3 failed", err)
+	}
+	if ExitCode(err) != ExitUsage {
+		t.Errorf("ExitCode() = %d, want %d", ExitCode(err), ExitUsage)
+	}
+}
+
+func TestRunBatch_FailFast(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.md")
+	alsoMissing := filepath.Join(dir, "also-missing.md")
+
+	opts := &Options{DryRun: true, ImageOnly: true, FailFast: true}
+	err := runBatch(context.Background(), opts, newBatchTestConfig(t), []string{missing, alsoMissing})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 prompt files failed") {
+		t.Errorf("runBatch() error = %v, want only the first file to fail", err)
+	}
+}
+
+func TestPrintBatchSummary(t *testing.T) {
+	var buf bytes.Buffer
+	printBatchSummary(&buf, 3, 2, []batchFailure{{File: "b.md", Err: os.ErrNotExist}})
+
+	out := buf.String()
+	for _, want := range []string{"Succeeded: 1/3", "Skipped: 1", "Failed: b.md"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q: %q", want, out)
+		}
+	}
+}
+
+func TestRootCommand_PromptWithMultipleFiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", t.TempDir())
+	t.Setenv("DEEPVIZ_STATE_DIR", t.TempDir())
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--prompt", "test", "-f", "a.md", "-f", "b.md"})
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+
+	err := cmd.Execute()
+	if ExitCode(err) != ExitUsage {
+		t.Errorf("ExitCode() = %d, want %d (err = %v)", ExitCode(err), ExitUsage, err)
+	}
+}
@@ -0,0 +1,219 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cassetteVersion is the version of the cassette file format.
+const cassetteVersion = 1
+
+// ErrUnrecordedRequest is returned by a replayed cassette for a request it has no response for.
+var ErrUnrecordedRequest = errors.New("no recorded response for request")
+
+// CassetteExchange is an API request and its response recorded in a cassette.
+//
+// Secrets are stripped from the URL and the bodies before they are recorded.
+type CassetteExchange struct {
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	Query        string `json:"query,omitempty"`
+	RequestBody  string `json:"request_body,omitempty"`
+	Status       int    `json:"status"`
+	ContentType  string `json:"content_type,omitempty"`
+	ResponseBody string `json:"response_body"`
+}
+
+// cassetteFile is the JSON document of a cassette.
+type cassetteFile struct {
+	Version    int                `json:"version"`
+	RecordedAt time.Time          `json:"recorded_at"`
+	Exchanges  []CassetteExchange `json:"exchanges"`
+}
+
+// Cassette records the API exchanges of a run to a file (--record) or serves them back without
+// network access (--replay).
+//
+// A replayed request is answered with the first unused exchange of the same method and path, so
+// repeated requests (e.g. polling) get the recorded responses in order. A request without a
+// matching exchange fails with ErrUnrecordedRequest instead of reaching the network.
+type Cassette struct {
+	path    string
+	replay  bool
+	secrets []string
+
+	mu         sync.Mutex
+	recordedAt time.Time
+	exchanges  []CassetteExchange
+	used       []bool
+}
+
+// NewCassetteRecorder creates a Cassette recording exchanges to path, rewritten after each
+// exchange so that an interrupted run keeps what it recorded. Secrets are stripped from the
+// recorded exchanges.
+func NewCassetteRecorder(path string, secrets ...string) *Cassette {
+	return &Cassette{path: path, secrets: secrets, recordedAt: time.Now()}
+}
+
+// LoadCassette loads a recorded cassette for replay.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if file.Version != cassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d in %s", file.Version, path)
+	}
+	return &Cassette{
+		path:       path,
+		replay:     true,
+		recordedAt: file.RecordedAt,
+		exchanges:  file.Exchanges,
+		used:       make([]bool, len(file.Exchanges)),
+	}, nil
+}
+
+// Path returns the file of the cassette.
+func (c *Cassette) Path() string {
+	return c.path
+}
+
+// Replaying reports whether the cassette serves recorded responses instead of recording.
+func (c *Cassette) Replaying() bool {
+	return c.replay
+}
+
+// Exchanges returns the exchanges of the cassette.
+func (c *Cassette) Exchanges() []CassetteExchange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CassetteExchange(nil), c.exchanges...)
+}
+
+// Transport returns a RoundTripper recording the exchanges made through next (the default
+// transport when nil) or, when replaying, serving the recorded responses without calling next.
+func (c *Cassette) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &cassetteTransport{cassette: c, next: next}
+}
+
+// cassetteTransport is the RoundTripper of a Cassette.
+type cassetteTransport struct {
+	cassette *Cassette
+	next     http.RoundTripper
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.cassette.replay {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return t.cassette.play(req)
+	}
+
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(reqBody)), nil
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	if err := t.cassette.record(req, reqBody, resp, respBody); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// record appends an exchange and rewrites the cassette file.
+func (c *Cassette) record(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.exchanges = append(c.exchanges, CassetteExchange{
+		Method:       req.Method,
+		Path:         req.URL.Path,
+		Query:        redactSecrets(req.URL.RawQuery, c.secrets...),
+		RequestBody:  string(redactBody(reqBody, c.secrets...)),
+		Status:       resp.StatusCode,
+		ContentType:  resp.Header.Get("Content-Type"),
+		ResponseBody: string(redactBody(respBody, c.secrets...)),
+	})
+
+	data, err := json.MarshalIndent(cassetteFile{Version: cassetteVersion, RecordedAt: c.recordedAt, Exchanges: c.exchanges}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if dir := filepath.Dir(c.path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// play returns the response of the first unused exchange matching the method and path of req.
+func (c *Cassette) play(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, exchange := range c.exchanges {
+		if c.used[i] || exchange.Method != req.Method || exchange.Path != req.URL.Path {
+			continue
+		}
+		c.used[i] = true
+
+		header := make(http.Header)
+		if exchange.ContentType != "" {
+			header.Set("Content-Type", exchange.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", exchange.Status, http.StatusText(exchange.Status)),
+			StatusCode:    exchange.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(exchange.ResponseBody)),
+			ContentLength: int64(len(exchange.ResponseBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s (cassette %s)", ErrUnrecordedRequest, req.Method, req.URL.Path, c.path)
+}
@@ -0,0 +1,257 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCassette writes a cassette file with the given exchanges and returns its path.
+func writeCassette(t *testing.T, exchanges ...CassetteExchange) string {
+	t.Helper()
+	data, err := json.Marshal(cassetteFile{Version: cassetteVersion, Exchanges: exchanges})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// cassetteGet sends a GET request through a cassette transport and returns the response body.
+func cassetteGet(client *http.Client, url string) (int, string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), err
+}
+
+func TestCassette_RecordReplay(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			fmt.Fprint(w, `{"id":"abc","status":"in_progress"}`)
+			return
+		}
+		polls++
+		fmt.Fprintf(w, `{"id":"abc","poll":%d}`, polls)
+	}))
+
+	path := filepath.Join(t.TempDir(), "cassettes", "run.json")
+	recorder := NewCassetteRecorder(path, testSecretKey)
+	client := &http.Client{Transport: recorder.Transport(nil)}
+
+	resp, err := client.Post(server.URL+"/v1beta/interactions", "application/json", strings.NewReader(`{"key":"`+testSecretKey+`"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	for range 2 {
+		if _, _, err := cassetteGet(client, server.URL+"/v1beta/interactions/abc?key="+testSecretKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), testSecretKey) {
+		t.Errorf("cassette contains the API key: %s", data)
+	}
+
+	// Replay serves the recorded responses in order without the server
+	replay, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("LoadCassette() error = %v", err)
+	}
+	if len(replay.Exchanges()) != 3 {
+		t.Fatalf("exchanges = %+v, want 3", replay.Exchanges())
+	}
+	client = &http.Client{Transport: replay.Transport(nil)}
+	for _, want := range []string{`{"id":"abc","poll":1}`, `{"id":"abc","poll":2}`} {
+		status, body, err := cassetteGet(client, server.URL+"/v1beta/interactions/abc")
+		if err != nil {
+			t.Fatalf("replayed GET error = %v", err)
+		}
+		if status != http.StatusOK || body != want {
+			t.Errorf("replayed GET = %d %s, want 200 %s", status, body, want)
+		}
+	}
+
+	// Every recorded poll is used: a third one is not matched
+	if _, _, err := cassetteGet(client, server.URL+"/v1beta/interactions/abc"); !errors.Is(err, ErrUnrecordedRequest) {
+		t.Errorf("third GET error = %v, want ErrUnrecordedRequest", err)
+	}
+	resp, err = client.Post(server.URL+"/v1beta/interactions", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("replayed POST error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q, want the recorded one", resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestCassette_RecordReplayImage(t *testing.T) {
+	body := imageResponseJSON(noisePNG(t, 300))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder := NewCassetteRecorder(path, testSecretKey)
+	if _, _, err := cassetteGet(&http.Client{Transport: recorder.Transport(nil)}, server.URL+"/v1beta/models/image:generateContent"); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	replay, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, got, err := cassetteGet(&http.Client{Transport: replay.Transport(nil)}, "http://replay/v1beta/models/image:generateContent")
+	if err != nil || got != body {
+		t.Errorf("replayed image response differs from the recorded one (error %v)", err)
+	}
+}
+
+func TestCassette_ReplayUnmatched(t *testing.T) {
+	path := writeCassette(t, CassetteExchange{Method: http.MethodGet, Path: "/v1beta/models", Status: http.StatusOK, ResponseBody: `{}`})
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: cassette.Transport(nil)}
+
+	// Method and path must both match; the request never reaches the network
+	for _, req := range []struct{ method, path string }{
+		{http.MethodPost, "/v1beta/models"},
+		{http.MethodGet, "/v1beta/interactions/abc"},
+	} {
+		r, _ := http.NewRequest(req.method, "http://127.0.0.1:1"+req.path, nil)
+		_, err := client.Do(r)
+		if !errors.Is(err, ErrUnrecordedRequest) || !strings.Contains(err.Error(), req.path) {
+			t.Errorf("%s %s error = %v, want ErrUnrecordedRequest", req.method, req.path, err)
+		}
+	}
+}
+
+func TestLoadCassette_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"broken.json":  "{",
+		"version.json": `{"version":99,"exchanges":[]}`,
+	}
+	for name, content := range tests {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadCassette(path); err == nil {
+			t.Errorf("LoadCassette(%s) error = nil, want an error", name)
+		}
+	}
+	if _, err := LoadCassette(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadCassette(missing) error = nil, want an error")
+	}
+}
+
+func TestNewHTTPClient_Cassette(t *testing.T) {
+	config := &ViperConfig{cassette: NewCassetteRecorder(filepath.Join(t.TempDir(), "cassette.json"))}
+	if _, ok := newHTTPClient(config, apiTimeout{}, NewNullLogger()).Transport.(*cassetteTransport); !ok {
+		t.Error("Transport does not use the cassette")
+	}
+
+	// The capture wraps the cassette so that replayed exchanges are saved too
+	config.httpCapture = NewHTTPCapture(t.TempDir(), NewNullLogger())
+	capture, ok := newHTTPClient(config, apiTimeout{}, NewNullLogger()).Transport.(*captureTransport)
+	if !ok {
+		t.Fatal("Transport does not capture the exchanges")
+	}
+	if _, ok := capture.next.(*cassetteTransport); !ok {
+		t.Error("captured transport does not use the cassette")
+	}
+}
+
+// TestRunWithConfig_Replay runs the whole pipeline offline from a cassette.
+func TestRootCommand_ReplaySerializesBatch(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", t.TempDir())
+	t.Setenv("DEEPVIZ_STATE_DIR", t.TempDir())
+	t.Setenv("GEMINI_API_KEY", "invalid")
+	path := writeCassette(t)
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--prompt", "test", "--image-only", "--dry-run", "--no-open", "--replay", path, "--concurrency", "4"})
+	cmd.SetOut(new(bytes.Buffer))
+	var stderr bytes.Buffer
+	cmd.SetErr(&stderr)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(stderr.String(), "ignoring --concurrency 4") {
+		t.Errorf("stderr = %q, want the runs serialized", stderr.String())
+	}
+}
+
+func TestRunWithConfig_Replay(t *testing.T) {
+	path := writeCassette(t,
+		CassetteExchange{Method: http.MethodPost, Path: "/v1beta/interactions", Status: http.StatusOK, ContentType: "application/json",
+			ResponseBody: `{"id":"v1_replayed","status":"in_progress"}`},
+		CassetteExchange{Method: http.MethodGet, Path: "/v1beta/interactions/v1_replayed", Status: http.StatusOK, ContentType: "application/json",
+			ResponseBody: `{"id":"v1_replayed","status":"in_progress"}`},
+		CassetteExchange{Method: http.MethodGet, Path: "/v1beta/interactions/v1_replayed", Status: http.StatusOK, ContentType: "application/json",
+			ResponseBody: `{"id":"v1_replayed","status":"completed","outputs":[{"type":"text","text":"# Replayed research"}]}`},
+		CassetteExchange{Method: http.MethodPost, Path: "/v1beta/models/gemini-3-pro-image-preview:generateContent", Status: http.StatusOK, ContentType: "application/json",
+			ResponseBody: imageResponseJSON(testImageData)},
+	)
+
+	dir := t.TempDir()
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", filepath.Join(dir, "output"))
+	t.Setenv("DEEPVIZ_STATE_DIR", filepath.Join(dir, "state"))
+	config, err := NewViperConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.cassette, err = LoadCassette(path); err != nil {
+		t.Fatal(err)
+	}
+	config.PollInterval, config.PollMaxInterval = 0, 0
+
+	opts := &Options{Prompt: "replayed prompt", Model: config.Model, Count: 1, Quiet: true, NoOpen: true}
+	result, err := RunWithConfig(opts, config)
+	if err != nil {
+		t.Fatalf("RunWithConfig() error = %v", err)
+	}
+	if result.InteractionID != "v1_replayed" || result.Research == nil || result.Image == nil {
+		t.Fatalf("result = %+v, want the replayed research and image", result)
+	}
+
+	paths := []string{result.Research.MarkdownPath, result.Image.ImagePath}
+	research, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(research), "# Replayed research") {
+		t.Errorf("research = %q, want the replayed result", research)
+	}
+	if image, err := os.ReadFile(paths[1]); err != nil || !bytes.Equal(image, testImageData) {
+		t.Errorf("image = %q (%v), want the replayed image", image, err)
+	}
+}
@@ -0,0 +1,355 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Artifact kinds removed by `deepviz clean --what`.
+const (
+	ArtifactImages    = "images"
+	ArtifactResponses = "responses"
+	ArtifactLogs      = "logs"
+	ArtifactAll       = "all" // Every file of the run, including its research, prompts and manifest
+)
+
+// CleanOptions selects the runs and artifacts removed by `deepviz clean`.
+//
+// A run is removed when it matches every criterion set.
+type CleanOptions struct {
+	OlderThan time.Duration // Only runs started longer ago than this (zero for any age)
+	KeepLast  int           // Keep the most recent runs
+	What      string        // Artifact kind to remove
+	Keep      string        // Timestamp of a run that is never removed (empty for none)
+	Tags      []string      // Only runs with every tag (runs without a manifest have no tags)
+	Now       time.Time
+}
+
+// CleanFile is a file removed by `deepviz clean`.
+type CleanFile struct {
+	Timestamp string
+	Kind      string
+	Path      string
+	Size      int64
+}
+
+// PlanClean returns the files `deepviz clean` removes, grouped by run from the newest run.
+//
+// Files are found in both layouts. Runs whose start time is unknown are never removed.
+func PlanClean(config *ViperConfig, opts CleanOptions, warn func(path string, err error)) ([]CleanFile, error) {
+	if opts.What != ArtifactAll && !slices.Contains([]string{ArtifactImages, ArtifactResponses, ArtifactLogs}, opts.What) {
+		return nil, fmt.Errorf("invalid artifact kind %q: must be %s, %s, %s or %s", opts.What, ArtifactImages, ArtifactResponses, ArtifactLogs, ArtifactAll)
+	}
+
+	files, err := runArtifactFiles(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Manifests give the start time of runs; other runs are dated by their timestamp
+	runs, err := ListRuns(config, warn)
+	if err != nil {
+		return nil, err
+	}
+	namer := config.Namer()
+	startedAt := make(map[string]time.Time)
+	tagged := make(map[string]bool)
+	for _, run := range runs {
+		startedAt[run.runKey(namer)] = run.StartedAt
+		tagged[run.runKey(namer)] = hasTags(run.Tags, opts.Tags)
+	}
+
+	// Files placed by an output template are only found through the manifests linking them
+	addManifestFiles(files, runs, namer)
+
+	// With tags, only the tagged runs are considered (--keep-last keeps the most recent of them)
+	var timestamps []string
+	for timestamp := range files {
+		if len(opts.Tags) > 0 && !tagged[timestamp] {
+			continue
+		}
+		if _, ok := startedAt[timestamp]; !ok {
+			t, err := namer.ParseTimestamp(timestamp)
+			if err != nil {
+				continue
+			}
+			startedAt[timestamp] = t
+		}
+		timestamps = append(timestamps, timestamp)
+	}
+	slices.SortFunc(timestamps, func(a, b string) int {
+		if c := startedAt[b].Compare(startedAt[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(b, a)
+	})
+
+	var plan []CleanFile
+	for i, timestamp := range timestamps {
+		if i < opts.KeepLast || timestamp == opts.Keep {
+			continue
+		}
+		if opts.OlderThan > 0 && startedAt[timestamp].After(opts.Now.Add(-opts.OlderThan)) {
+			continue
+		}
+		for _, file := range files[timestamp] {
+			if opts.What == ArtifactAll || file.Kind == opts.What {
+				plan = append(plan, file)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// runArtifactFiles returns the files of every run in the output directory by run timestamp.
+func runArtifactFiles(config *ViperConfig) (map[string][]CleanFile, error) {
+	files := make(map[string][]CleanFile)
+	namer := config.Namer()
+	add := func(timestamp, kind, path string, info os.FileInfo) {
+		files[timestamp] = append(files[timestamp], CleanFile{Timestamp: timestamp, Kind: kind, Path: path, Size: info.Size()})
+	}
+
+	// by-type layout: the directory is the kind and the file name starts with the artifact name
+	for _, kind := range []string{"research", ArtifactImages, ArtifactResponses, "prompts", ArtifactLogs, "manifests"} {
+		if err := walkRunFiles(filepath.Join(config.OutputDir, kind), func(name, path string, info os.FileInfo) {
+			stem, _, _ := strings.Cut(name, ".")
+			timestamp, _ := namer.SplitRunName(stem)
+			add(timestamp, kind, path, info)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Captured HTTP exchanges (save_http_exchange) are in a directory named after the run
+	responseDirs, err := readDirIfExists(config.ResponsesDir())
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range responseDirs {
+		if !entry.IsDir() {
+			continue
+		}
+		timestamp, _ := namer.SplitRunName(entry.Name())
+		if err := walkRunFiles(filepath.Join(config.ResponsesDir(), entry.Name()), func(name, path string, info os.FileInfo) {
+			add(timestamp, ArtifactResponses, path, info)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// per-run layout: the kind is given by the file name
+	runDirs, err := readDirIfExists(config.RunsDir())
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range runDirs {
+		if !entry.IsDir() {
+			continue
+		}
+		timestamp := entry.Name()
+		if err := walkRunFiles(config.RunDir(timestamp), func(name, path string, info os.FileInfo) {
+			add(timestamp, perRunArtifactKind(name), path, info)
+		}); err != nil {
+			return nil, err
+		}
+		if err := walkRunFiles(filepath.Join(config.RunDir(timestamp), httpExchangeDirName), func(name, path string, info os.FileInfo) {
+			add(timestamp, ArtifactResponses, path, info)
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// isHTTPExchangeDir reports whether dir holds the captured HTTP exchanges of a run.
+func isHTTPExchangeDir(config *ViperConfig, dir string) bool {
+	if filepath.Dir(dir) == config.ResponsesDir() {
+		return true
+	}
+	return filepath.Base(dir) == httpExchangeDirName && filepath.Dir(filepath.Dir(dir)) == config.RunsDir()
+}
+
+// addManifestFiles adds the existing files linked from run manifests that were not found yet.
+func addManifestFiles(files map[string][]CleanFile, runs []*RunManifest, namer *RunNamer) {
+	known := make(map[string]bool)
+	for _, runFiles := range files {
+		for _, file := range runFiles {
+			known[file.Path] = true
+		}
+	}
+	for _, run := range runs {
+		key := run.runKey(namer)
+		for _, file := range manifestArtifacts(run) {
+			if file.Path == "" || known[file.Path] {
+				continue
+			}
+			info, err := os.Stat(file.Path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			known[file.Path] = true
+			files[key] = append(files[key], CleanFile{Timestamp: key, Kind: file.Kind, Path: file.Path, Size: info.Size()})
+		}
+	}
+}
+
+// manifestArtifacts returns the files a manifest links to with their kind.
+//
+// Input images are not artifacts of the run and are never returned.
+func manifestArtifacts(run *RunManifest) []CleanFile {
+	files := []CleanFile{{Kind: ArtifactLogs, Path: run.LogPath}, {Kind: "reports", Path: run.ReportPath}}
+	if research := run.Research; research != nil {
+		files = append(files,
+			CleanFile{Kind: "research", Path: research.MarkdownPath},
+			CleanFile{Kind: "research", Path: research.SourcesPath},
+			CleanFile{Kind: "research", Path: research.PDFPath},
+			CleanFile{Kind: ArtifactResponses, Path: research.ResponsePath},
+		)
+	}
+	if image := run.Image; image != nil {
+		for _, path := range slices.Concat(image.ImagePaths, image.OriginalPaths, []string{image.CaptionPath}) {
+			files = append(files, CleanFile{Kind: ArtifactImages, Path: path})
+		}
+		for _, path := range image.PromptPaths {
+			files = append(files, CleanFile{Kind: "prompts", Path: path})
+		}
+		files = append(files,
+			CleanFile{Kind: ArtifactResponses, Path: image.ResponsePath},
+			CleanFile{Kind: ArtifactResponses, Path: image.RequestPath},
+		)
+	}
+	return files
+}
+
+// perRunArtifactKind returns the kind of a file in a per-run directory.
+func perRunArtifactKind(name string) string {
+	switch {
+	case strings.HasPrefix(name, "image"):
+		return ArtifactImages
+	case strings.HasPrefix(name, "response_"), strings.HasPrefix(name, "request_"), strings.HasPrefix(name, "poll_"):
+		return ArtifactResponses
+	case isLogFile(name):
+		return ArtifactLogs
+	case strings.HasPrefix(name, "research"), strings.HasPrefix(name, "sources"):
+		return "research"
+	case strings.HasPrefix(name, "prompt"):
+		return "prompts"
+	case strings.HasPrefix(name, "manifest"):
+		return "manifests"
+	case strings.HasPrefix(name, "report"):
+		return "reports"
+	}
+	return "other"
+}
+
+// RemoveCleanFiles removes the planned files and the run directories they leave empty.
+//
+// Every path is checked to be inside the output directory before it is removed.
+func RemoveCleanFiles(config *ViperConfig, plan []CleanFile) (int, int64, error) {
+	var removed int
+	var freed int64
+	for _, file := range plan {
+		if err := validateCleanPath(config.OutputDir, file.Path); err != nil {
+			return removed, freed, err
+		}
+		if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+			return removed, freed, fmt.Errorf("failed to remove %s: %w", file.Path, err)
+		}
+		removed++
+		freed += file.Size
+
+		// Remove HTTP exchange directories, then per-run directories, once empty (os.Remove fails
+		// on non-empty directories)
+		dir := filepath.Dir(file.Path)
+		if isHTTPExchangeDir(config, dir) {
+			os.Remove(dir)
+			dir = filepath.Dir(dir)
+		}
+		if filepath.Dir(dir) == config.RunsDir() {
+			os.Remove(dir)
+		}
+	}
+	return removed, freed, nil
+}
+
+// validateCleanPath returns an error unless path is a file inside outputDir.
+//
+// Symbolic links are resolved so that a linked directory cannot lead outside the output directory.
+func validateCleanPath(outputDir, path string) error {
+	if outputDir == "" {
+		return fmt.Errorf("output directory is not set")
+	}
+	root, err := filepath.EvalSymlinks(outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	rel, err := filepath.Rel(root, filepath.Join(dir, filepath.Base(path)))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("refusing to remove %s outside the output directory %s", path, outputDir)
+	}
+	return nil
+}
+
+// ParseAge parses the --older-than value, a number of days ("30d"), weeks ("2w") or a Go duration ("12h").
+func ParseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			days, err := strconv.Atoi(n)
+			if err != nil || days < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(days) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q: use a number of days (30d), weeks (2w) or a duration (12h)", s)
+	}
+	return d, nil
+}
+
+// formatSize returns a byte count in human-readable units.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// printCleanPlan writes the files to remove with their sizes and the total.
+func printCleanPlan(w io.Writer, plan []CleanFile) {
+	var total int64
+	runs := make(map[string]bool)
+	for _, file := range plan {
+		fmt.Fprintf(w, "%10s  %s\n", formatSize(file.Size), file.Path)
+		total += file.Size
+		runs[file.Timestamp] = true
+	}
+	fmt.Fprintln(w, msg("clean.plan", len(plan), len(runs), formatSize(total)))
+}
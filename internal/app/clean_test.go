@@ -0,0 +1,295 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// planTimestamps returns the run timestamps of a clean plan in order.
+func planTimestamps(plan []CleanFile) []string {
+	var timestamps []string
+	for _, file := range plan {
+		if !slices.Contains(timestamps, file.Timestamp) {
+			timestamps = append(timestamps, file.Timestamp)
+		}
+	}
+	return timestamps
+}
+
+func TestPlanClean(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	config := &ViperConfig{OutputDir: outputDir}
+	now := time.Date(2025, 12, 31, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name           string
+		opts           CleanOptions
+		wantTimestamps []string
+		wantKinds      []string
+	}{
+		{
+			name:           "keep last",
+			opts:           CleanOptions{KeepLast: 3, What: ArtifactAll, Now: now},
+			wantTimestamps: []string{"20251224_103045", "20251120_080000", "20251001_120000"},
+		},
+		{
+			name:           "older than",
+			opts:           CleanOptions{OlderThan: 30 * 24 * time.Hour, What: ArtifactAll, Now: now},
+			wantTimestamps: []string{"20251120_080000", "20251001_120000"},
+		},
+		{
+			name:           "both criteria",
+			opts:           CleanOptions{OlderThan: 30 * 24 * time.Hour, KeepLast: 5, What: ArtifactAll, Now: now},
+			wantTimestamps: []string{"20251001_120000"},
+		},
+		{
+			name:           "images only",
+			opts:           CleanOptions{KeepLast: 1, What: ArtifactImages, Now: now},
+			wantTimestamps: []string{"20251225_090000", "20251120_080000"},
+			wantKinds:      []string{ArtifactImages},
+		},
+		{
+			name:           "logs of a kept run",
+			opts:           CleanOptions{OlderThan: time.Hour, What: ArtifactLogs, Keep: "20251225_090000", Now: now},
+			wantTimestamps: []string{"20251120_080000", "20251001_120000"},
+			wantKinds:      []string{ArtifactLogs},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := PlanClean(config, tt.opts, func(string, error) {})
+			if err != nil {
+				t.Fatalf("PlanClean() error = %v", err)
+			}
+			if got := planTimestamps(plan); !slices.Equal(got, tt.wantTimestamps) {
+				t.Errorf("runs = %v, want %v", got, tt.wantTimestamps)
+			}
+			for _, file := range plan {
+				if tt.wantKinds != nil && !slices.Contains(tt.wantKinds, file.Kind) {
+					t.Errorf("file %s of kind %s should not be removed", file.Path, file.Kind)
+				}
+				if !strings.HasPrefix(file.Path, outputDir) {
+					t.Errorf("file %s is outside the output directory", file.Path)
+				}
+			}
+		})
+	}
+
+	if _, err := PlanClean(config, CleanOptions{What: "videos"}, func(string, error) {}); err == nil {
+		t.Error("PlanClean() should reject unknown artifact kinds")
+	}
+}
+
+func TestPlanClean_Tags(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	config := &ViperConfig{OutputDir: outputDir}
+
+	// Tag the manifest run of the fixtures and add a newer one with the same tag
+	manifest, err := LoadManifest(config.ManifestPath("20251224_103045"))
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	manifest.Tags = []string{"project-x"}
+	if err := manifest.Save(config.ManifestPath("20251224_103045")); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+	newer := newRunManifest("20251228_090000", "prompt", "", "", []string{"project-x", "weekly"}, time.Date(2025, 12, 28, 9, 0, 0, 0, time.Local))
+	if err := newer.Save(config.ManifestPath("20251228_090000")); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		opts           CleanOptions
+		wantTimestamps []string
+	}{
+		{name: "tag", opts: CleanOptions{Tags: []string{"project-x"}}, wantTimestamps: []string{"20251228_090000", "20251224_103045"}},
+		{name: "every tag", opts: CleanOptions{Tags: []string{"project-x", "weekly"}}, wantTimestamps: []string{"20251228_090000"}},
+		{name: "keep last among tagged runs", opts: CleanOptions{Tags: []string{"project-x"}, KeepLast: 1}, wantTimestamps: []string{"20251224_103045"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.What = ArtifactAll
+			tt.opts.Now = time.Date(2025, 12, 31, 0, 0, 0, 0, time.Local)
+			plan, err := PlanClean(config, tt.opts, func(string, error) {})
+			if err != nil {
+				t.Fatalf("PlanClean() error = %v", err)
+			}
+			if got := planTimestamps(plan); !slices.Equal(got, tt.wantTimestamps) {
+				t.Errorf("runs = %v, want %v", got, tt.wantTimestamps)
+			}
+		})
+	}
+}
+
+func TestPlanClean_OutputTemplate(t *testing.T) {
+	outputDir := t.TempDir()
+	config := &ViperConfig{OutputDir: outputDir, OutputTemplate: "{{.Dir}}/{{.Year}}"}
+
+	// Templated files are found through the manifest linking them
+	imagePath := config.ImagePath("20251001_120000", ".png")
+	if err := WriteFile(imagePath, []byte("image")); err != nil {
+		t.Fatalf("failed to write image: %v", err)
+	}
+	manifest := newRunManifest("20251001_120000", "prompt", "", config.LogPath("20251001_120000"), nil, time.Date(2025, 10, 1, 12, 0, 0, 0, time.Local))
+	manifest.Image = &ManifestImage{Status: RunStatusCompleted, ImagePaths: []string{imagePath}}
+	if err := manifest.Save(config.ManifestPath("20251001_120000")); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+
+	plan, err := PlanClean(config, CleanOptions{What: ArtifactImages, Now: time.Now()}, func(string, error) {})
+	if err != nil {
+		t.Fatalf("PlanClean() error = %v", err)
+	}
+	if len(plan) != 1 || plan[0].Path != imagePath || plan[0].Timestamp != "20251001_120000" {
+		t.Errorf("plan = %+v, want the templated image %s", plan, imagePath)
+	}
+}
+
+func TestRemoveCleanFiles(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	config := &ViperConfig{OutputDir: outputDir}
+
+	plan, err := PlanClean(config, CleanOptions{KeepLast: 2, What: ArtifactAll, Now: time.Now()}, func(string, error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, freed, err := RemoveCleanFiles(config, plan)
+	if err != nil {
+		t.Fatalf("RemoveCleanFiles() error = %v", err)
+	}
+	if removed != len(plan) || freed <= 0 {
+		t.Errorf("RemoveCleanFiles() = %d, %d, want %d files", removed, freed, len(plan))
+	}
+	for _, file := range plan {
+		if _, err := os.Stat(file.Path); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", file.Path)
+		}
+	}
+	// The emptied per-run directory is removed and the kept one is not
+	perRun := &ViperConfig{OutputDir: outputDir, Layout: LayoutPerRun}
+	if _, err := os.Stat(perRun.RunDir("20251225_090000")); !os.IsNotExist(err) {
+		t.Error("the emptied run directory should be removed")
+	}
+	if _, err := os.Stat(perRun.ManifestPath("20251226_070000")); err != nil {
+		t.Errorf("kept run should remain: %v", err)
+	}
+}
+
+// TestRemoveCleanFiles_HTTPExchanges tests that captured HTTP exchanges are removed with the
+// responses of their run.
+func TestRemoveCleanFiles_HTTPExchanges(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	byType := &ViperConfig{OutputDir: outputDir}
+	perRun := &ViperConfig{OutputDir: outputDir, Layout: LayoutPerRun}
+	dirs := []string{byType.HTTPExchangeDir("20251120_080000"), perRun.HTTPExchangeDir("20251225_090000")}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, httpCaptureIndex), []byte("[]"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan, err := PlanClean(byType, CleanOptions{KeepLast: 2, What: ArtifactResponses, Now: time.Now()}, func(string, error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := RemoveCleanFiles(byType, plan); err != nil {
+		t.Fatalf("RemoveCleanFiles() error = %v", err)
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed: %v", dir, err)
+		}
+	}
+}
+
+func TestValidateCleanPath(t *testing.T) {
+	outputDir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(outputDir, "linked")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+	if err := EnsureDir(filepath.Join(outputDir, "images")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		path    string
+		wantErr bool
+	}{
+		{name: "inside", dir: outputDir, path: filepath.Join(outputDir, "images", "a.png")},
+		{name: "outside", dir: outputDir, path: filepath.Join(outside, "a.png"), wantErr: true},
+		{name: "parent traversal", dir: outputDir, path: filepath.Join(outputDir, "images", "..", "..", "a.png"), wantErr: true},
+		{name: "output directory itself", dir: outputDir, path: outputDir, wantErr: true},
+		{name: "through a symbolic link", dir: outputDir, path: filepath.Join(outputDir, "linked", "a.png"), wantErr: true},
+		{name: "unset output directory", dir: "", path: "a.png", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateCleanPath(tt.dir, tt.path); (err != nil) != tt.wantErr {
+				t.Errorf("validateCleanPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "30d", want: 30 * 24 * time.Hour},
+		{input: "2w", want: 14 * 24 * time.Hour},
+		{input: "12h", want: 12 * time.Hour},
+		{input: "-1d", wantErr: true},
+		{input: "d", wantErr: true},
+		{input: "a month", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseAge(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{size: 512, want: "512 B"},
+		{size: 1536, want: "1.5 KiB"},
+		{size: 5 * 1024 * 1024, want: "5.0 MiB"},
+		{size: 3 * 1024 * 1024 * 1024, want: "3.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatSize(tt.size); got != tt.want {
+			t.Errorf("formatSize(%d) = %s, want %s", tt.size, got, tt.want)
+		}
+	}
+}
@@ -1,124 +1,532 @@
 package app
 
 import (
+	"bufio"
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/yukiyan/deepviz/internal/version"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-const version = "0.1.0"
-
 // Options holds CLI options.
 type Options struct {
-	Prompt       string
-	File         string
-	ResearchOnly bool
-	ImageOnly    bool
-	Model        string
-	AspectRatio  string
-	ImageSize    string
-	Output       string
-	Verbose      bool
-	NoOpen       bool
+	Prompt         string
+	Files          []string      // Prompt files or glob patterns (--file, repeatable)
+	File           string        // Prompt file of a single pipeline run (set from Files)
+	FailFast       bool          // Stop a batch at the first failed prompt file
+	Concurrency    int           // Number of prompt files or jobs run in parallel in a batch
+	Timestamp      string        // Run timestamp (generated when empty)
+	Name           string        // Explicit name of the run artifacts (--name, replaces the prompt slug)
+	Count          int           // Number of image variants
+	Candidates     int           // Number of candidates requested per image generation call
+	InputImages    []*InputImage // Existing images sent with the image prompt (--input-image)
+	OpenAll        bool          // Open every image variant instead of only the first
+	InteractionID  string        // Resume an existing research instead of starting a new one
+	ResearchOnly   bool
+	ImageOnly      bool
+	Model          string
+	AspectRatio    string
+	ImageSize      string
+	Output         string
+	Verbose        int  // Console log verbosity: 1 logs DEBUG messages (-v), 2 raw HTTP bodies too (-vv)
+	Quiet          bool // Print only the artifact paths to stdout and log to the log file only
+	JSON           bool // Print the result of the run as JSON (--json)
+	Curl           bool // Print each API request as an equivalent curl command to stderr (--curl)
+	NoOpen         bool
+	QuietPoll      bool              // Do not print thought summaries while polling the research
+	ShowPrompt     bool              // Print the image prompt before image generation
+	ShowPromptFull bool              // Do not truncate the printed image prompt
+	DryRun         bool              // Print the plan without making API requests
+	Vars           map[string]string // Prompt template variables (--var)
+	TemplateVars   bool              // Render --prompt as a template too (files are always rendered)
+	Tags           []string          // Tags recorded in the run history
+	Report         string            // Format of the report written after the run (--report, empty for none)
+	Export         string            // Format the research is exported to after the run (--export, empty for none)
+	OpenReport     bool              // Auto-open the report instead of the image
+	Progress       *ProgressEmitter  // Progress events (--progress ndjson); replaces the console log and thought summaries
+	Stdout         io.Writer         // Destination of the summary (os.Stdout when nil)
+	Clients        *PipelineClients  // Clients of the pipeline stages (the Gemini API clients when nil)
+	Runner         PipelineRunner    // Runs the pipeline of each prompt (ExecutePipeline when nil)
+	LogHandler     slog.Handler      // Receives the console log of the run instead of the console (nil logs to the console)
+	SetFlags       map[string]bool   // Flags explicitly set on the command line
+}
+
+// FlagSet reports whether a flag was explicitly set on the command line.
+func (o *Options) FlagSet(name string) bool {
+	return o.SetFlags[name]
+}
+
+// stdout returns the destination of the summary.
+func (o *Options) stdout() io.Writer {
+	if o.Stdout == nil {
+		return os.Stdout
+	}
+	return o.Stdout
+}
+
+// RootOption configures the root command.
+type RootOption func(*rootOptions)
+
+// rootOptions holds the options of the root command.
+type rootOptions struct {
+	runner PipelineRunner
+}
+
+// WithPipelineRunner runs the pipelines of the commands with run instead of ExecutePipeline.
+func WithPipelineRunner(run PipelineRunner) RootOption {
+	return func(o *rootOptions) {
+		o.runner = run
+	}
 }
 
 // NewRootCommand creates the root command.
 //
-// The root command executes research and image generation.
-func NewRootCommand() *cobra.Command {
+// The root command executes research and image generation. The command descriptions are
+// translated to cli_lang, which is read before the commands are built.
+func NewRootCommand(options ...RootOption) *cobra.Command {
+	setCLILang(loadCLILang(""))
+	root := rootOptions{runner: ExecutePipeline}
+	for _, option := range options {
+		option(&root)
+	}
+
 	var (
-		prompt       string
-		file         string
-		output       string
-		verbose      bool
-		researchOnly bool
-		imageOnly    bool
-		model        string
-		aspectRatio  string
-		imageSize    string
-		noOpen       bool
+		prompt         string
+		files          []string
+		failFast       bool
+		concurrency    int
+		output         string
+		verbose        int
+		trace          bool
+		curl           bool
+		quiet          bool
+		jsonOutput     bool
+		researchOnly   bool
+		imageOnly      bool
+		model          string
+		aspectRatio    string
+		imageSize      string
+		imageLangs     []string
+		tools          []string
+		agent          string
+		noTools        bool
+		noSaveResponse bool
+		noStore        bool
+		record         string
+		replay         string
+		thinking       string
+		noOpen         bool
+		quietPoll      bool
+		keepOnFailure  bool
+		showPrompt     bool
+		showPromptFull bool
+		dryRun         bool
+		promptTemplate string
+		genConfigFile  string
+		systemInstr    string
+		style          string
+		inputImages    []string
+		safetyLevel    string
+		seed           int
+		temperature    float64
+		topP           float64
+		topK           int
+		vars           []string
+		templateVars   bool
+		count          int
+		candidates     int
+		openAll        bool
+		fallbackModel  string
+		imageFormat    string
+		imageQuality   int
+		keepOriginal   bool
+		name           string
+		force          bool
+		tags           []string
+		report         string
+		openReport     bool
+		export         string
+		progress       string
+		progressFile   string
+		logFormat      string
 	)
 
 	rootCmd := &cobra.Command{
 		Use:     "deepviz",
-		Short:   "Research and image generation tool using Gemini API",
-		Version: version,
+		Short:   msg("cmd.root.short"),
+		Long:    msg("cmd.root.short") + "\n\n" + exitCodeHelp(),
+		Version: version.Version(),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Error if neither prompt nor file is specified
-			if prompt == "" && file == "" {
-				return fmt.Errorf("either --prompt or --file must be specified")
+			if prompt == "" && len(files) == 0 {
+				return &UsageError{Err: errors.New(msg("error.prompt_or_file"))}
+			}
+			if concurrency < 1 {
+				return &UsageError{Err: errors.New(msg("error.concurrency"))}
+			}
+			if quiet && (verbose > 0 || trace) {
+				return &UsageError{Err: errors.New(msg("error.quiet_verbose"))}
+			}
+			if name != "" {
+				if err := ValidateRunName(name); err != nil {
+					return &UsageError{Err: err}
+				}
+			}
+
+			promptVars, err := ParsePromptVars(vars)
+			if err != nil {
+				return &UsageError{Err: err}
+			}
+			parsedTags, err := ParseTags(tags)
+			if err != nil {
+				return &UsageError{Err: err}
+			}
+			// --open-report implies --report html
+			if openReport && report == "" {
+				report = ReportFormatHTML
+			}
+			if report != "" && report != ReportFormatHTML {
+				return &UsageError{Err: fmt.Errorf("invalid report format %q: must be %s", report, ReportFormatHTML)}
+			}
+			if export != "" && export != ExportFormatPDF {
+				return &UsageError{Err: fmt.Errorf("invalid export format %q: must be %s", export, ExportFormatPDF)}
+			}
+			// --progress-file implies --progress ndjson
+			if progressFile != "" && progress == "" {
+				progress = ProgressFormatNDJSON
+			}
+			if progress != "" && progress != ProgressFormatNDJSON {
+				return &UsageError{Err: fmt.Errorf("invalid progress format %q: must be %s", progress, ProgressFormatNDJSON)}
 			}
 
 			// Load configuration
 			config, err := NewViperConfig("")
 			if err != nil {
-				return fmt.Errorf("failed to load config: %w", err)
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
 			}
 
 			// Override with flags if explicitly set
 			if output != "" {
 				config.OutputDir = output
 			}
+			if cmd.Flags().Changed("agent") {
+				config.DeepResearchAgent = agent
+			}
+			if cmd.Flags().Changed("log-format") {
+				if config.LogFormat, err = ParseLogFormat(logFormat); err != nil {
+					return &UsageError{Err: err}
+				}
+			}
+			if noTools && cmd.Flags().Changed("tools") {
+				return &UsageError{Err: fmt.Errorf("--no-tools cannot be combined with --tools")}
+			}
+			config.NoResearchTools = noTools
+			if noSaveResponse {
+				config.NoSaveResponses = true
+			}
+			if noStore {
+				return &UsageError{Err: fmt.Errorf("--no-store is not supported: Deep Research runs in the background, and the API only polls and resumes stored interactions")}
+			}
+			if record != "" && replay != "" {
+				return &UsageError{Err: fmt.Errorf("--record cannot be combined with --replay")}
+			}
+			if record != "" {
+				if err := config.loadAPIKey(); err != nil {
+					return &ConfigError{Err: err}
+				}
+				config.cassette = NewCassetteRecorder(record, config.secrets()...)
+			}
+			if replay != "" {
+				if config.cassette, err = LoadCassette(replay); err != nil {
+					return &UsageError{Err: err}
+				}
+				// The recorded responses are served without waiting between polls
+				config.PollInterval, config.PollMaxInterval = 0, 0
+			}
+			// A cassette holds the exchanges of the runs in order: runs in parallel would interleave them
+			if config.cassette != nil && concurrency > 1 {
+				printWarning(cmd.ErrOrStderr(), "--record and --replay run the prompt files one at a time (ignoring --concurrency %d)", concurrency)
+				concurrency = 1
+			}
+			if cmd.Flags().Changed("tools") {
+				if config.ResearchTools, err = ParseResearchTools(strings.Join(tools, ",")); err != nil {
+					return &UsageError{Err: err}
+				}
+			}
+			if cmd.Flags().Changed("thinking-summaries") {
+				if config.ThinkingSummaries, err = ParseThinkingSummaries(thinking); err != nil {
+					return &UsageError{Err: err}
+				}
+			}
 			if cmd.Flags().Changed("model") {
 				config.Model = model
 			}
+			if cmd.Flags().Changed("fallback-model") {
+				config.FallbackModel = fallbackModel
+			}
 			if cmd.Flags().Changed("aspect-ratio") {
 				config.AspectRatio = aspectRatio
 			}
 			if cmd.Flags().Changed("image-size") {
 				config.ImageSize = imageSize
 			}
+			if cmd.Flags().Changed("image-lang") {
+				config.ImageLang = strings.Join(imageLangs, ",")
+			}
+			if cmd.Flags().Changed("keep-on-failure") {
+				config.KeepOnFailure = keepOnFailure
+			}
+			if cmd.Flags().Changed("count") {
+				config.ImageCount = count
+			}
+			if config.ImageCount < 1 {
+				return &UsageError{Err: fmt.Errorf("image count must be at least 1")}
+			}
+			if cmd.Flags().Changed("image-format") {
+				config.ImageFormat = imageFormat
+			}
+			if cmd.Flags().Changed("image-quality") {
+				config.ImageQuality = imageQuality
+			}
+			if cmd.Flags().Changed("keep-original") {
+				config.KeepOriginal = keepOriginal
+			}
+			if cmd.Flags().Changed("force") {
+				config.Overwrite = force
+			}
+			if config.ImageFormat, err = NormalizeImageFormat(config.ImageFormat); err != nil {
+				return &UsageError{Err: err}
+			}
+			if err := ValidateImageQuality(config.ImageQuality); err != nil {
+				return &UsageError{Err: err}
+			}
+			loadedImages, err := LoadInputImages(inputImages)
+			if err != nil {
+				return &UsageError{Err: err}
+			}
+			if candidates < 1 {
+				return &UsageError{Err: fmt.Errorf("--candidates must be at least 1")}
+			}
+			if promptTemplate != "" {
+				data, err := ReadFile(promptTemplate)
+				if err != nil {
+					return &UsageError{Err: fmt.Errorf("failed to read prompt template: %w", err)}
+				}
+				config.ImagePromptTemplate = string(data)
+			}
+
+			if cmd.Flags().Changed("system-instruction") {
+				config.ImageSystemInstruction = systemInstr
+			}
+			if cmd.Flags().Changed("style") {
+				config.Style = style
+			}
+			if _, err := ResolveStyle(config.Style, config.Styles); err != nil {
+				return &UsageError{Err: err}
+			}
+			if safetyLevel != "" {
+				if config.SafetySettings, err = SafetySettingsForThreshold(safetyLevel); err != nil {
+					return &UsageError{Err: err}
+				}
+			}
+			if cmd.Flags().Changed("seed") {
+				config.Seed = ptr(seed)
+			}
+			if cmd.Flags().Changed("temperature") {
+				config.Temperature = ptr(temperature)
+			}
+			if cmd.Flags().Changed("top-p") {
+				config.TopP = ptr(topP)
+			}
+			if cmd.Flags().Changed("top-k") {
+				config.TopK = ptr(topK)
+			}
+			if err := ValidateSampling(config); err != nil {
+				return &UsageError{Err: err}
+			}
+			if genConfigFile != "" {
+				data, err := ReadFile(genConfigFile)
+				if err != nil {
+					return &UsageError{Err: fmt.Errorf("failed to read generation config: %w", err)}
+				}
+				if config.GenerationConfig, err = ParseGenerationConfig(string(data)); err != nil {
+					return &UsageError{Err: err}
+				}
+			}
+			removeFlagOwnedKeys(config.GenerationConfig, cmd.Flags().Changed)
+
+			var progressEmitter *ProgressEmitter
+			if progress == ProgressFormatNDJSON {
+				progressOutput := cmd.ErrOrStderr()
+				if progressFile != "" {
+					file, err := os.Create(progressFile)
+					if err != nil {
+						return &UsageError{Err: fmt.Errorf("failed to create progress file: %w", err)}
+					}
+					defer file.Close()
+					progressOutput = file
+				}
+				progressEmitter = NewProgressEmitter(progressOutput)
+			}
 
 			// Create options
 			opts := &Options{
+				Stdout:       cmd.OutOrStdout(),
+				Runner:       root.runner,
 				Prompt:       prompt,
-				File:         file,
+				Files:        files,
+				FailFast:     failFast,
+				Concurrency:  concurrency,
+				Name:         name,
+				Tags:         parsedTags,
+				Report:       report,
+				OpenReport:   openReport,
+				Export:       export,
+				Progress:     progressEmitter,
+				Count:        config.ImageCount,
+				Candidates:   candidates,
+				InputImages:  loadedImages,
+				OpenAll:      openAll,
 				Output:       config.OutputDir,
-				Verbose:      verbose,
+				Verbose:      verbosity(verbose, trace),
+				Curl:         curl,
+				Quiet:        quiet,
+				JSON:         jsonOutput,
 				ResearchOnly: researchOnly,
 				ImageOnly:    imageOnly,
 				Model:        config.Model,
 				AspectRatio:  config.AspectRatio,
 				ImageSize:    config.ImageSize,
 				NoOpen:       noOpen,
+				QuietPoll:    quietPoll || quiet,
+				// --show-prompt-full implies --show-prompt
+				ShowPrompt:     showPrompt || showPromptFull,
+				ShowPromptFull: showPromptFull,
+				DryRun:         dryRun,
+				Vars:           promptVars,
+				TemplateVars:   templateVars,
+				SetFlags:       changedFlags(cmd),
 			}
 
 			// Execute Run function (existing logic)
-			return RunWithConfig(opts, config)
+			result, err := RunWithConfig(opts, config)
+			if err != nil || result == nil {
+				return err
+			}
+			return printRunResult(opts.stdout(), result, opts.JSON, opts.Quiet)
 		},
 	}
 
 	// Define flags
-	rootCmd.Flags().StringVarP(&prompt, "prompt", "p", "", "Generation prompt")
-	rootCmd.Flags().StringVarP(&file, "file", "f", "", "Prompt file path")
-	rootCmd.Flags().StringVarP(&output, "output", "o", "", "Output directory")
-	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging (DEBUG level)")
-	rootCmd.Flags().BoolVar(&researchOnly, "research-only", false, "Execute research only")
-	rootCmd.Flags().BoolVar(&imageOnly, "image-only", false, "Execute image generation only")
-	rootCmd.Flags().StringVar(&model, "model", "gemini-3-pro-image-preview", "Image generation model name")
-	rootCmd.Flags().StringVar(&aspectRatio, "aspect-ratio", "16:9", "Aspect ratio")
-	rootCmd.Flags().StringVar(&imageSize, "image-size", "2K", "Image size")
-	rootCmd.Flags().BoolVar(&noOpen, "no-open", false, "Disable auto-open after image generation")
+	rootCmd.Flags().StringVarP(&prompt, "prompt", "p", "", msg("cmd.root.flag.prompt"))
+	rootCmd.Flags().StringArrayVarP(&files, "file", "f", nil, msg("cmd.root.flag.file"))
+	rootCmd.Flags().BoolVar(&failFast, "fail-fast", false, msg("cmd.root.flag.fail_fast"))
+	rootCmd.Flags().IntVar(&concurrency, "concurrency", 1, msg("cmd.root.flag.concurrency"))
+	rootCmd.Flags().IntVar(&count, "count", 1, msg("cmd.root.flag.count"))
+	rootCmd.Flags().IntVar(&candidates, "candidates", 1, msg("cmd.root.flag.candidates"))
+	rootCmd.Flags().StringVar(&imageFormat, "image-format", "", msg("cmd.root.flag.image_format"))
+	rootCmd.Flags().IntVar(&imageQuality, "image-quality", 90, msg("cmd.root.flag.image_quality"))
+	rootCmd.Flags().BoolVar(&keepOriginal, "keep-original", false, msg("cmd.root.flag.keep_original"))
+	rootCmd.Flags().BoolVar(&openAll, "open-all", false, msg("cmd.root.flag.open_all"))
+	rootCmd.Flags().StringVarP(&output, "output", "o", "", msg("flag.output"))
+	rootCmd.Flags().StringVar(&name, "name", "", msg("cmd.root.flag.name"))
+	rootCmd.Flags().StringArrayVar(&tags, "tag", nil, msg("cmd.root.flag.tag"))
+	rootCmd.Flags().StringVar(&report, "report", "", msg("cmd.root.flag.report"))
+	rootCmd.Flags().StringVar(&export, "export", "", msg("cmd.root.flag.export"))
+	rootCmd.Flags().StringVar(&progress, "progress", "", msg("cmd.root.flag.progress"))
+	rootCmd.Flags().StringVar(&progressFile, "progress-file", "", msg("cmd.root.flag.progress_file"))
+	rootCmd.Flags().BoolVar(&openReport, "open-report", false, msg("cmd.root.flag.open_report"))
+	rootCmd.Flags().BoolVar(&force, "force", false, msg("cmd.root.flag.force"))
+	rootCmd.Flags().CountVarP(&verbose, "verbose", "v", msg("flag.verbose"))
+	rootCmd.Flags().BoolVar(&trace, "trace", false, msg("flag.trace"))
+	rootCmd.Flags().BoolVar(&curl, "curl", false, msg("flag.curl"))
+	rootCmd.Flags().StringVar(&logFormat, "log-format", "", msg("cmd.root.flag.log_format"))
+	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, msg("cmd.root.flag.quiet"))
+	rootCmd.Flags().BoolVar(&jsonOutput, "json", false, msg("cmd.root.flag.json"))
+	rootCmd.Flags().BoolVar(&researchOnly, "research-only", false, msg("flag.research_only"))
+	rootCmd.Flags().BoolVar(&imageOnly, "image-only", false, msg("cmd.root.flag.image_only"))
+	rootCmd.Flags().StringVar(&agent, "agent", "", msg("cmd.root.flag.agent"))
+	rootCmd.Flags().StringSliceVar(&tools, "tools", nil, msg("cmd.root.flag.tools"))
+	rootCmd.Flags().BoolVar(&noTools, "no-tools", false, msg("cmd.root.flag.no_tools"))
+	rootCmd.Flags().BoolVar(&noSaveResponse, "no-save-response", false, msg("flag.no_save_response"))
+	// --no-store is rejected with the reason instead of as an unknown flag
+	rootCmd.Flags().BoolVar(&noStore, "no-store", false, msg("cmd.root.flag.no_store"))
+	_ = rootCmd.Flags().MarkHidden("no-store")
+	rootCmd.Flags().StringVar(&record, "record", "", msg("cmd.root.flag.record"))
+	rootCmd.Flags().StringVar(&replay, "replay", "", msg("cmd.root.flag.replay"))
+	rootCmd.Flags().StringVar(&thinking, "thinking-summaries", "auto", msg("cmd.root.flag.thinking_summaries"))
+	rootCmd.Flags().StringVar(&model, "model", "gemini-3-pro-image-preview", msg("cmd.root.flag.model"))
+	rootCmd.Flags().StringVar(&fallbackModel, "fallback-model", "", msg("cmd.root.flag.fallback_model"))
+	rootCmd.Flags().StringVar(&aspectRatio, "aspect-ratio", "16:9", msg("cmd.root.flag.aspect_ratio"))
+	rootCmd.Flags().StringVar(&imageSize, "image-size", "2K", msg("cmd.root.flag.image_size"))
+	rootCmd.Flags().StringSliceVar(&imageLangs, "image-lang", nil, msg("cmd.root.flag.image_lang"))
+	rootCmd.Flags().BoolVar(&noOpen, "no-open", false, msg("flag.no_open"))
+	rootCmd.Flags().BoolVar(&quietPoll, "quiet-poll", false, msg("flag.quiet_poll"))
+	rootCmd.Flags().BoolVar(&keepOnFailure, "keep-on-failure", false, msg("cmd.root.flag.keep_on_failure"))
+	rootCmd.Flags().BoolVar(&showPrompt, "show-prompt", false, msg("cmd.root.flag.show_prompt"))
+	rootCmd.Flags().BoolVar(&showPromptFull, "show-prompt-full", false, msg("cmd.root.flag.show_prompt_full"))
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, msg("cmd.root.flag.dry_run"))
+	rootCmd.Flags().StringArrayVar(&vars, "var", nil, msg("cmd.root.flag.var"))
+	rootCmd.Flags().BoolVar(&templateVars, "template-vars", false, msg("cmd.root.flag.template_vars"))
+	rootCmd.Flags().StringArrayVar(&inputImages, "input-image", nil, msg("cmd.root.flag.input_image"))
+	rootCmd.Flags().StringVar(&systemInstr, "system-instruction", "", msg("cmd.root.flag.system_instruction"))
+	rootCmd.Flags().StringVar(&style, "style", "", msg("cmd.root.flag.style"))
+	rootCmd.Flags().StringVar(&safetyLevel, "safety-threshold", "", msg("cmd.root.flag.safety_threshold"))
+	rootCmd.Flags().IntVar(&seed, "seed", 0, msg("cmd.root.flag.seed"))
+	rootCmd.Flags().Float64Var(&temperature, "temperature", 0, msg("cmd.root.flag.temperature"))
+	rootCmd.Flags().Float64Var(&topP, "top-p", 0, msg("cmd.root.flag.top_p"))
+	rootCmd.Flags().IntVar(&topK, "top-k", 0, msg("cmd.root.flag.top_k"))
+	rootCmd.Flags().StringVar(&genConfigFile, "generation-config", "", msg("cmd.root.flag.generation_config"))
+	rootCmd.Flags().StringVar(&promptTemplate, "prompt-template", "", msg("cmd.root.flag.prompt_template"))
 
 	// --no-image is an alias for --research-only
-	rootCmd.Flags().BoolVar(&researchOnly, "no-image", false, "Skip image generation (same as --research-only)")
+	rootCmd.Flags().BoolVar(&researchOnly, "no-image", false, msg("cmd.root.flag.no_image"))
+
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, msg("cmd.root.flag.no_color"))
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		// Invalid configurations are reported by the command itself. The insecure_skip_verify
+		// warning is printed here only, for every command including the pipeline.
+		if config, err := NewViperConfig(""); err == nil {
+			if config.InsecureSkipVerify {
+				printWarning(cmd.ErrOrStderr(), "%s", msg("warning.insecure_skip_verify"))
+			}
+		}
+		cmd.Root().SetErrPrefix(NewStyler(cmd.ErrOrStderr()).Error(msg("error.prefix")))
+	}
+
+	// Classify flag parsing errors as usage errors (applies to subcommands too)
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &UsageError{Err: err}
+	})
 
 	// Register completion functions for flags
 	rootCmd.RegisterFlagCompletionFunc("file", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return nil, cobra.ShellCompDirectiveFilterFileExt
 	})
+	rootCmd.RegisterFlagCompletionFunc("prompt-template", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveDefault
+	})
 	rootCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return nil, cobra.ShellCompDirectiveFilterDirs
 	})
-	rootCmd.RegisterFlagCompletionFunc("model", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{
-			"gemini-3-pro-image-preview\tGemini 3 Pro Image Preview",
-			"gemini-2.0-flash-exp\tGemini 2.0 Flash Experimental",
-		}, cobra.ShellCompDirectiveNoFileComp
-	})
+	rootCmd.RegisterFlagCompletionFunc("model", completeModels)
 	rootCmd.RegisterFlagCompletionFunc("aspect-ratio", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return []string{
 			"16:9\tWidescreen",
@@ -135,41 +543,1459 @@ func NewRootCommand() *cobra.Command {
 		}, cobra.ShellCompDirectiveNoFileComp
 	})
 
-	// Add subcommands
-	rootCmd.AddCommand(newConfigCommand())
-	rootCmd.AddCommand(newCompletionCommand())
+	rootCmd.RegisterFlagCompletionFunc("agent", completeAgents)
+	rootCmd.RegisterFlagCompletionFunc("thinking-summaries", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{
+			"auto\tInclude thinking summaries when available",
+			"none\tDo not include thinking summaries",
+			"off\tSame as none",
+		}, cobra.ShellCompDirectiveNoFileComp
+	})
+	rootCmd.RegisterFlagCompletionFunc("tools", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		prefix := toComplete[:strings.LastIndex(toComplete, ",")+1]
+		var completions []string
+		for _, tool := range researchToolTypes {
+			completions = append(completions, prefix+tool)
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	})
+	rootCmd.RegisterFlagCompletionFunc("image-lang", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		// Complete the last entry of a comma-separated list
+		prefix := toComplete[:strings.LastIndex(toComplete, ",")+1]
+		var completions []string
+		for _, lang := range []string{"Japanese", "English", "French", "German", "Chinese", "Korean", "Spanish", "Portuguese", "Italian"} {
+			completions = append(completions, prefix+lang)
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	})
+	rootCmd.RegisterFlagCompletionFunc("style", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		// Include the presets defined in the config when it can be loaded
+		var custom map[string]string
+		if config, err := NewViperConfig(""); err == nil {
+			custom = config.Styles
+		}
+		return availableStyles(custom), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	// Add subcommands
+	rootCmd.AddCommand(newResumeCommand(root.runner))
+	rootCmd.AddCommand(newLastCommand())
+	rootCmd.AddCommand(newHistoryCommand())
+	rootCmd.AddCommand(newShowCommand())
+	rootCmd.AddCommand(newLogsCommand())
+	rootCmd.AddCommand(newOpenCommand())
+	rootCmd.AddCommand(newReportCommand())
+	rootCmd.AddCommand(newExportCommand())
+	rootCmd.AddCommand(newGalleryCommand())
+	rootCmd.AddCommand(newServeCommand(root.runner))
+	rootCmd.AddCommand(newCleanCommand())
+	rootCmd.AddCommand(newRunCommand(root.runner))
+	rootCmd.AddCommand(newRefineCommand())
+	rootCmd.AddCommand(newAgentsCommand())
+	rootCmd.AddCommand(newModelsCommand())
+	rootCmd.AddCommand(newInteractionsCommand())
+	rootCmd.AddCommand(newDoctorCommand())
+	rootCmd.AddCommand(newConfigCommand())
+	rootCmd.AddCommand(newCompletionCommand())
+	rootCmd.AddCommand(newVersionCommand())
+	rootCmd.AddCommand(newUpgradeCommand())
+	rootCmd.SetVersionTemplate(version.Get().String())
+
+	return rootCmd
+}
+
+// newResumeCommand creates the command that re-attaches to a running research, running the
+// pipeline with runner.
+func newResumeCommand(runner PipelineRunner) *cobra.Command {
+	var (
+		output       string
+		verbose      int
+		trace        bool
+		curl         bool
+		researchOnly bool
+		noOpen       bool
+		quietPoll    bool
+		noSaveResp   bool
+		name         string
+		jsonOutput   bool
+	)
+
+	resumeCmd := &cobra.Command{
+		Use:   "resume <interaction-id>",
+		Short: msg("cmd.resume.short"),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+				return &UsageError{Err: err}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load configuration
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+
+			if output != "" {
+				config.OutputDir = output
+			}
+			if noSaveResp {
+				config.NoSaveResponses = true
+			}
+			if name != "" {
+				if err := ValidateRunName(name); err != nil {
+					return &UsageError{Err: err}
+				}
+			}
+
+			opts := &Options{
+				Stdout:        cmd.OutOrStdout(),
+				Runner:        runner,
+				InteractionID: args[0],
+				Name:          name,
+				Output:        config.OutputDir,
+				Verbose:       verbosity(verbose, trace),
+				Curl:          curl,
+				ResearchOnly:  researchOnly,
+				Model:         config.Model,
+				AspectRatio:   config.AspectRatio,
+				ImageSize:     config.ImageSize,
+				Count:         config.ImageCount,
+				NoOpen:        noOpen,
+				QuietPoll:     quietPoll,
+				JSON:          jsonOutput,
+			}
+
+			result, err := RunWithConfig(opts, config)
+			if err != nil || result == nil {
+				return err
+			}
+			return printRunResult(opts.stdout(), result, opts.JSON, false)
+		},
+	}
+
+	resumeCmd.Flags().StringVarP(&output, "output", "o", "", msg("flag.output"))
+	resumeCmd.Flags().StringVar(&name, "name", "", msg("cmd.resume.flag.name"))
+	resumeCmd.Flags().CountVarP(&verbose, "verbose", "v", msg("flag.verbose"))
+	resumeCmd.Flags().BoolVar(&trace, "trace", false, msg("flag.trace"))
+	resumeCmd.Flags().BoolVar(&curl, "curl", false, msg("flag.curl"))
+	resumeCmd.Flags().BoolVar(&researchOnly, "research-only", false, msg("flag.research_only"))
+	resumeCmd.Flags().BoolVar(&noOpen, "no-open", false, msg("flag.no_open"))
+	resumeCmd.Flags().BoolVar(&quietPoll, "quiet-poll", false, msg("flag.quiet_poll"))
+	resumeCmd.Flags().BoolVar(&jsonOutput, "json", false, msg("cmd.root.flag.json"))
+	resumeCmd.Flags().BoolVar(&noSaveResp, "no-save-response", false, msg("flag.no_save_response"))
+
+	return resumeCmd
+}
+
+// newRunCommand creates the command that executes jobs declared in a job file with runner.
+func newRunCommand(runner PipelineRunner) *cobra.Command {
+	var (
+		output      string
+		verbose     int
+		trace       bool
+		curl        bool
+		noOpen      bool
+		quietPoll   bool
+		only        []string
+		dryRun      bool
+		failFast    bool
+		concurrency int
+	)
+
+	runCmd := &cobra.Command{
+		Use:   "run <jobs.yaml>",
+		Short: msg("cmd.run.short"),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+				return &UsageError{Err: err}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if concurrency < 1 {
+				return &UsageError{Err: errors.New(msg("error.concurrency"))}
+			}
+
+			jobs, err := LoadJobFile(args[0])
+			if err != nil {
+				return &UsageError{Err: err}
+			}
+			jobs, err = selectJobs(jobs, only)
+			if err != nil {
+				return &UsageError{Err: err}
+			}
+
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+			if output != "" {
+				config.OutputDir = output
+			}
+
+			if dryRun {
+				printJobPlan(cmd.OutOrStdout(), jobs, config)
+				return nil
+			}
+
+			ctx, stop := newSignalContext()
+			defer stop()
+
+			opts := &Options{
+				Stdout:      cmd.OutOrStdout(),
+				Runner:      runner,
+				Output:      config.OutputDir,
+				Verbose:     verbosity(verbose, trace),
+				Curl:        curl,
+				NoOpen:      noOpen,
+				QuietPoll:   quietPoll,
+				FailFast:    failFast,
+				Concurrency: concurrency,
+			}
+			return RunJobs(ctx, args[0], jobs, opts, config)
+		},
+	}
+
+	runCmd.Flags().StringVarP(&output, "output", "o", "", msg("flag.output"))
+	runCmd.Flags().CountVarP(&verbose, "verbose", "v", msg("flag.verbose"))
+	runCmd.Flags().BoolVar(&trace, "trace", false, msg("flag.trace"))
+	runCmd.Flags().BoolVar(&curl, "curl", false, msg("flag.curl"))
+	runCmd.Flags().BoolVar(&noOpen, "no-open", false, msg("flag.no_open"))
+	runCmd.Flags().BoolVar(&quietPoll, "quiet-poll", false, msg("flag.quiet_poll"))
+	runCmd.Flags().StringSliceVar(&only, "only", nil, msg("cmd.run.flag.only"))
+	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, msg("cmd.run.flag.dry_run"))
+	runCmd.Flags().BoolVar(&failFast, "fail-fast", false, msg("cmd.run.flag.fail_fast"))
+	runCmd.Flags().IntVar(&concurrency, "concurrency", 1, msg("cmd.run.flag.concurrency"))
+
+	return runCmd
+}
+
+// newRefineCommand creates the command that refines a previous image with feedback.
+func newRefineCommand() *cobra.Command {
+	var (
+		output  string
+		verbose int
+		trace   bool
+		noOpen  bool
+		model   string
+	)
+
+	refineCmd := &cobra.Command{
+		Use:   "refine <timestamp|image> <feedback>",
+		Short: msg("cmd.refine.short"),
+		Long:  msg("cmd.refine.long"),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+				return &UsageError{Err: err}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+			if output != "" {
+				config.OutputDir = output
+			}
+
+			ctx, stop := newSignalContext()
+			defer stop()
+
+			opts := &Options{
+				Stdout:      cmd.OutOrStdout(),
+				Output:      config.OutputDir,
+				Verbose:     verbosity(verbose, trace),
+				NoOpen:      noOpen,
+				Model:       config.Model,
+				AspectRatio: config.AspectRatio,
+				ImageSize:   config.ImageSize,
+			}
+			if model != "" {
+				opts.Model = model
+			}
+			return RunRefine(ctx, args[0], args[1], opts, config)
+		},
+	}
+
+	refineCmd.Flags().StringVarP(&output, "output", "o", "", msg("flag.output"))
+	refineCmd.Flags().CountVarP(&verbose, "verbose", "v", msg("flag.verbose"))
+	refineCmd.Flags().BoolVar(&trace, "trace", false, msg("flag.trace"))
+	refineCmd.Flags().BoolVar(&noOpen, "no-open", false, msg("flag.no_open"))
+	refineCmd.Flags().StringVar(&model, "model", "", msg("cmd.refine.flag.model"))
+
+	return refineCmd
+}
+
+// newAgentsCommand creates the command that lists the available Deep Research agents.
+func newAgentsCommand() *cobra.Command {
+	var (
+		jsonOutput bool
+		refresh    bool
+	)
+
+	agentsCmd := &cobra.Command{
+		Use:   "agents",
+		Short: msg("cmd.agents.short"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+
+			client, err := NewModelsClient(config, NewNullLogger())
+			if err != nil {
+				return listingError(err)
+			}
+
+			ctx, stop := newSignalContext()
+			defer stop()
+
+			agents, err := client.ListAgents(ctx, refresh)
+			if err != nil {
+				return listingError(err)
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(agents, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal agents: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return nil
+			}
+			printAgents(cmd.OutOrStdout(), agents)
+			return nil
+		},
+	}
+
+	agentsCmd.Flags().BoolVar(&jsonOutput, "json", false, msg("flag.json"))
+	agentsCmd.Flags().BoolVar(&refresh, "refresh", false, msg("cmd.agents.flag.refresh"))
+
+	return agentsCmd
+}
+
+// newModelsCommand creates the command that lists the image generation models.
+func newModelsCommand() *cobra.Command {
+	var (
+		jsonOutput bool
+		check      bool
+	)
+
+	modelsCmd := &cobra.Command{
+		Use:   "models",
+		Short: msg("cmd.models.short"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+
+			client, err := NewModelsClient(config, NewNullLogger())
+			if err != nil {
+				return listingError(err)
+			}
+
+			ctx, stop := newSignalContext()
+			defer stop()
+
+			if check {
+				return checkConfiguredModels(ctx, cmd.OutOrStdout(), client, config)
+			}
+
+			models, err := client.ImageModels(ctx)
+			if err != nil {
+				return listingError(fmt.Errorf("failed to list models: %w", err))
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(models, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal models: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return nil
+			}
+			printModels(cmd.OutOrStdout(), models, config.Model)
+			return nil
+		},
+	}
+
+	modelsCmd.Flags().BoolVar(&jsonOutput, "json", false, msg("flag.json"))
+	modelsCmd.Flags().BoolVar(&check, "check", false, msg("cmd.models.flag.check"))
+
+	return modelsCmd
+}
+
+// newDoctorCommand creates the command that checks the configuration and the API.
+func newDoctorCommand() *cobra.Command {
+	return newDoctorCommandWith(defaultDoctorEnv())
+}
+
+// newDoctorCommandWith creates the doctor command running the checks of env.
+func newDoctorCommandWith(env doctorEnv) *cobra.Command {
+	var jsonOutput bool
+
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: msg("cmd.doctor.short"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := newSignalContext()
+			defer stop()
+
+			report := runDoctor(ctx, env)
+			if jsonOutput {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal checks: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			} else {
+				printDoctorReport(cmd.OutOrStdout(), report)
+			}
+
+			var failed int
+			for _, check := range report.Checks {
+				if check.Status == DoctorFail {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d checks failed", failed, len(report.Checks))
+			}
+			return nil
+		},
+	}
+
+	doctorCmd.Flags().BoolVar(&jsonOutput, "json", false, msg("flag.json"))
+
+	return doctorCmd
+}
+
+// newInteractionsCommand creates the command that manages the interactions stored by the
+// Interactions API.
+func newInteractionsCommand() *cobra.Command {
+	interactionsCmd := &cobra.Command{
+		Use:   "interactions",
+		Short: msg("cmd.interactions.short"),
+	}
+
+	var (
+		status     string
+		limit      int
+		jsonOutput bool
+	)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: msg("cmd.interactions.list.short"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+
+			client, err := NewInteractionsClient(config, NewNullLogger())
+			if err != nil {
+				return listingError(err)
+			}
+
+			ctx, stop := newSignalContext()
+			defer stop()
+
+			interactions, err := client.ListInteractions(ctx, status, limit)
+			if err != nil {
+				return listingError(fmt.Errorf("failed to list interactions: %w", err))
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(interactions, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal interactions: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return nil
+			}
+			printInteractions(cmd.OutOrStdout(), interactions)
+			return nil
+		},
+	}
+
+	listCmd.Flags().StringVar(&status, "status", "", msg("cmd.interactions.list.flag.status"))
+	listCmd.Flags().IntVar(&limit, "limit", 50, msg("cmd.interactions.list.flag.limit"))
+	listCmd.Flags().BoolVar(&jsonOutput, "json", false, msg("flag.json"))
+	listCmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions([]string{"in_progress", "completed", "failed", "cancelled", "requires_action"}, cobra.ShellCompDirectiveNoFileComp))
+
+	interactionsCmd.AddCommand(listCmd)
+	interactionsCmd.AddCommand(newInteractionsDeleteCommand())
+	return interactionsCmd
+}
+
+// newInteractionsDeleteCommand creates the command that deletes stored interactions.
+func newInteractionsDeleteCommand() *cobra.Command {
+	var (
+		allCompleted bool
+		olderThan    string
+		yes          bool
+	)
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete [interaction-id...]",
+		Short: msg("cmd.interactions.delete.short"),
+		Long:  msg("cmd.interactions.delete.long"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if allCompleted == (len(args) > 0) {
+				return &UsageError{Err: fmt.Errorf("either interaction IDs or --all-completed must be specified")}
+			}
+			if olderThan != "" && !allCompleted {
+				return &UsageError{Err: fmt.Errorf("--older-than requires --all-completed")}
+			}
+			var age time.Duration
+			if olderThan != "" {
+				var err error
+				if age, err = ParseAge(olderThan); err != nil {
+					return &UsageError{Err: fmt.Errorf("invalid --older-than: %w", err)}
+				}
+			}
+
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+
+			client, err := NewInteractionsClient(config, NewNullLogger())
+			if err != nil {
+				return listingError(err)
+			}
+
+			ctx, stop := newSignalContext()
+			defer stop()
+
+			ids := args
+			if allCompleted {
+				completed, err := client.ListInteractions(ctx, "completed", 0)
+				if err != nil {
+					return listingError(fmt.Errorf("failed to list interactions: %w", err))
+				}
+				selected := selectOldInteractions(completed, age, time.Now())
+				if len(selected) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), msg("interactions.nothing"))
+					return nil
+				}
+
+				printInteractions(cmd.OutOrStdout(), selected)
+				if !yes {
+					fmt.Fprint(cmd.OutOrStdout(), msg("interactions.confirm", len(selected)))
+					answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+					if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+						fmt.Fprintln(cmd.OutOrStdout(), msg("interactions.aborted"))
+						return nil
+					}
+				}
+				ids = make([]string, len(selected))
+				for i, interaction := range selected {
+					ids[i] = interaction.ID
+				}
+			}
+
+			return reportInteractionDeletions(cmd.OutOrStdout(), cmd.ErrOrStderr(), client.DeleteInteractions(ctx, ids))
+		},
+	}
+
+	deleteCmd.Flags().BoolVar(&allCompleted, "all-completed", false, msg("cmd.interactions.delete.flag.all_completed"))
+	deleteCmd.Flags().StringVar(&olderThan, "older-than", "", msg("cmd.interactions.delete.flag.older_than"))
+	deleteCmd.Flags().BoolVarP(&yes, "yes", "y", false, msg("cmd.interactions.delete.flag.yes"))
+
+	return deleteCmd
+}
+
+// checkConfiguredModels verifies that the configured image models exist.
+func checkConfiguredModels(ctx context.Context, w io.Writer, client *ModelsClient, config *ViperConfig) error {
+	models, err := client.ListModels(ctx)
+	if err != nil {
+		return listingError(fmt.Errorf("failed to list models: %w", err))
+	}
+
+	var missing []string
+	for _, name := range []string{config.Model, config.FallbackModel} {
+		if name == "" {
+			continue
+		}
+		if _, ok := findModel(models, name); !ok {
+			fmt.Fprintln(w, msg("models.not_found", name))
+			missing = append(missing, name)
+			continue
+		}
+		fmt.Fprintln(w, msg("models.available", name))
+	}
+	if len(missing) > 0 {
+		return &ConfigError{Err: fmt.Errorf("configured model not found: %s (run `deepviz models` to list available models)", strings.Join(missing, ", "))}
+	}
+	return nil
+}
+
+// fallbackModelCompletions are completed for --model when the image models cannot be listed.
+var fallbackModelCompletions = []string{
+	"gemini-3-pro-image-preview\tGemini 3 Pro Image Preview",
+	"gemini-2.0-flash-exp\tGemini 2.0 Flash Experimental",
+}
+
+// completeModels completes image model names from the cached (or freshly listed) image models.
+//
+// It falls back to fallbackModelCompletions when no API key is configured or nothing is listed.
+func completeModels(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	config, err := NewViperConfig("")
+	if err != nil {
+		return fallbackModelCompletions, cobra.ShellCompDirectiveNoFileComp
+	}
+	client, err := NewModelsClient(config, NewNullLogger())
+	if err != nil {
+		return fallbackModelCompletions, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	models, ok := completionListing(config.ModelsCachePath(), time.Now(), client.ImageModels)
+	if !ok || len(models) == 0 {
+		return fallbackModelCompletions, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, model := range models {
+		names = append(names, model.ID()+"\t"+model.DisplayName)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeAgents completes Deep Research agent names from the cached (or freshly listed) agents.
+//
+// It falls back to the configured agent when no API key is configured or nothing is listed.
+func completeAgents(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	config, err := NewViperConfig("")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var agents []AgentInfo
+	if client, err := NewModelsClient(config, NewNullLogger()); err == nil {
+		agents, _ = completionListing(config.AgentsCachePath(), time.Now(), client.fetchAgents)
+	}
+	agents = markDefaultAgent(agents, config.DeepResearchAgent)
+
+	var names []string
+	for _, agent := range agents {
+		names = append(names, agent.Name+"\t"+cmp.Or(agent.DisplayName, agent.Description))
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// newLastCommand creates the command that shows the most recent run.
+func newLastCommand() *cobra.Command {
+	var (
+		open       bool
+		jsonOutput bool
+	)
+
+	lastCmd := &cobra.Command{
+		Use:   "last",
+		Short: msg("cmd.last.short"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+
+			entry, err := NewRunHistory(config.HistoryPath()).Last()
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(entry, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal history entry: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", msg("summary.timestamp"), entry.Timestamp)
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", msg("summary.prompt"), entry.Prompt)
+				if entry.ResearchPath != "" {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", msg("summary.research"), entry.ResearchPath)
+				}
+				if entry.ResearchResponsePath != "" {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", msg("summary.research_response"), entry.ResearchResponsePath)
+				}
+				if entry.ImagePath != "" {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", msg("summary.image"), entry.ImagePath)
+				}
+			}
+
+			if open {
+				if entry.ImagePath == "" {
+					return fmt.Errorf("the most recent run has no image")
+				}
+				if err := OpenFile(entry.ImagePath); err != nil {
+					return fmt.Errorf("failed to open image: %w", err)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	lastCmd.Flags().BoolVar(&open, "open", false, msg("cmd.last.flag.open"))
+	lastCmd.Flags().BoolVar(&jsonOutput, "json", false, msg("flag.json"))
+
+	return lastCmd
+}
+
+// newHistoryCommand creates the command listing past runs.
+func newHistoryCommand() *cobra.Command {
+	var (
+		limit      int
+		since      string
+		tags       []string
+		jsonOutput bool
+	)
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: msg("cmd.history.short"),
+		Long:  msg("cmd.history.long"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var sinceTime time.Time
+			if since != "" {
+				t, err := ParseSince(since)
+				if err != nil {
+					return &UsageError{Err: fmt.Errorf("invalid --since: %w", err)}
+				}
+				sinceTime = t
+			}
+			parsedTags, err := ParseTags(tags)
+			if err != nil {
+				return &UsageError{Err: err}
+			}
+
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+
+			runs, err := ListRuns(config, func(path string, err error) {
+				printWarning(cmd.ErrOrStderr(), "skipping manifest %s: %v", path, err)
+			})
+			if err != nil {
+				return err
+			}
+			runs = filterRuns(runs, sinceTime, parsedTags, limit)
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(runs, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal runs: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return nil
+			}
+			if len(runs) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), msg("history.none"))
+				return nil
+			}
+			printRuns(cmd.OutOrStdout(), runs)
+			return nil
+		},
+	}
+
+	historyCmd.Flags().IntVar(&limit, "limit", 20, msg("cmd.history.flag.limit"))
+	historyCmd.Flags().StringVar(&since, "since", "", msg("cmd.history.flag.since"))
+	historyCmd.Flags().StringArrayVar(&tags, "tag", nil, msg("cmd.history.flag.tag"))
+	historyCmd.Flags().BoolVar(&jsonOutput, "json", false, msg("flag.json"))
+
+	return historyCmd
+}
+
+// newShowCommand creates the command displaying a past run.
+func newShowCommand() *cobra.Command {
+	var (
+		open bool
+		raw  bool
+	)
+
+	showCmd := &cobra.Command{
+		Use:   "show <timestamp|latest>",
+		Short: msg("cmd.show.short"),
+		Long:  msg("cmd.show.long"),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+
+			runs, err := ListRuns(config, func(path string, err error) {
+				printWarning(cmd.ErrOrStderr(), "skipping manifest %s: %v", path, err)
+			})
+			if err != nil {
+				return err
+			}
+			run, err := ResolveRun(runs, args[0])
+			if err != nil {
+				return &UsageError{Err: err}
+			}
+
+			var markdown []byte
+			if run.Research != nil && run.Research.MarkdownPath != "" {
+				markdown, err = ReadFile(run.Research.MarkdownPath)
+				if err != nil {
+					return fmt.Errorf("failed to read research: %w", err)
+				}
+			}
+
+			if raw {
+				if markdown == nil {
+					return fmt.Errorf("run %s has no research", run.Timestamp)
+				}
+				_, err := cmd.OutOrStdout().Write(markdown)
+				return err
+			}
+
+			printRun(cmd.OutOrStdout(), run)
+			if markdown != nil {
+				_, content := ParseResearchFrontMatter(string(markdown))
+				fmt.Fprintln(cmd.OutOrStdout())
+				fmt.Fprint(cmd.OutOrStdout(), renderTerminalMarkdown(content))
+			}
+
+			if open {
+				if run.Image == nil || len(run.Image.ImagePaths) == 0 {
+					return fmt.Errorf("run %s has no image", run.Timestamp)
+				}
+				if err := OpenFile(run.Image.ImagePaths[0]); err != nil {
+					return fmt.Errorf("failed to open image: %w", err)
+				}
+			}
+			return nil
+		},
+	}
+
+	showCmd.Flags().BoolVar(&open, "open", false, msg("cmd.show.flag.open"))
+	showCmd.Flags().BoolVar(&raw, "raw", false, msg("cmd.show.flag.raw"))
+
+	return showCmd
+}
+
+// newLogsCommand creates the command printing the log of a run.
+func newLogsCommand() *cobra.Command {
+	var (
+		timestamp string
+		level     string
+		follow    bool
+		raw       bool
+	)
+
+	logsCmd := &cobra.Command{
+		Use:   "logs",
+		Short: msg("cmd.logs.short"),
+		Long:  msg("cmd.logs.long"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			view := LogView{MinLevel: LevelTrace, Raw: raw}
+			if level != "" {
+				minLevel, err := ParseLogLevel(level)
+				if err != nil {
+					return &UsageError{Err: err}
+				}
+				view.MinLevel = minLevel
+			}
+
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+
+			runs, err := ListRuns(config, func(path string, err error) {
+				printWarning(cmd.ErrOrStderr(), "skipping manifest %s: %v", path, err)
+			})
+			if err != nil {
+				return err
+			}
+			run, err := ResolveRun(runs, timestamp)
+			if err != nil {
+				return &UsageError{Err: err}
+			}
+			if run.LogPath == "" {
+				return fmt.Errorf("run %s has no log", run.Timestamp)
+			}
+
+			if follow {
+				ctx, stop := newSignalContext()
+				defer stop()
+				if err := view.Follow(ctx, cmd.OutOrStdout(), run.LogPath, logFollowInterval); err != nil {
+					return fmt.Errorf("failed to read log: %w", err)
+				}
+				return nil
+			}
+
+			file, err := openLogFile(run.LogPath)
+			if err != nil {
+				return fmt.Errorf("failed to read log: %w", err)
+			}
+			defer file.Close()
+			if err := view.Print(cmd.OutOrStdout(), file); err != nil {
+				return fmt.Errorf("failed to read log: %w", err)
+			}
+			return nil
+		},
+	}
+
+	logsCmd.Flags().StringVar(&timestamp, "timestamp", "latest", msg("cmd.logs.flag.timestamp"))
+	logsCmd.Flags().StringVar(&level, "level", "", msg("cmd.logs.flag.level"))
+	logsCmd.Flags().BoolVarP(&follow, "follow", "f", false, msg("cmd.logs.flag.follow"))
+	logsCmd.Flags().BoolVar(&raw, "raw", false, msg("cmd.logs.flag.raw"))
+
+	logsCmd.RegisterFlagCompletionFunc("level", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"trace", "debug", "info", "warn", "error"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return logsCmd
+}
+
+// newOpenCommand creates the command reopening the outputs of a run.
+func newOpenCommand() *cobra.Command {
+	return newOpenCommandWith(OpenFile)
+}
+
+// newOpenCommandWith creates the open command opening files with open.
+func newOpenCommandWith(open func(path string) error) *cobra.Command {
+	var (
+		last     bool
+		research bool
+	)
+
+	openCmd := &cobra.Command{
+		Use:   "open [timestamp|latest]",
+		Short: msg("cmd.open.short"),
+		Long:  msg("cmd.open.long"),
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if last == (len(args) == 1) {
+				return &UsageError{Err: fmt.Errorf("specify either a timestamp or --last")}
+			}
+			var ref string
+			if len(args) == 1 {
+				ref = args[0]
+			}
+
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+
+			path, err := ResolveOpenPath(config, ref, last, research, func(path string, err error) {
+				printWarning(cmd.ErrOrStderr(), "skipping manifest %s: %v", path, err)
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), msg("open.opening", path))
+			if err := open(path); err != nil {
+				return fmt.Errorf("failed to open %s: %w", path, err)
+			}
+			return nil
+		},
+	}
+
+	openCmd.Flags().BoolVar(&last, "last", false, msg("cmd.open.flag.last"))
+	openCmd.Flags().BoolVar(&research, "research", false, msg("cmd.open.flag.research"))
+
+	return openCmd
+}
+
+// newReportCommand creates the command writing the HTML report of a past run.
+func newReportCommand() *cobra.Command {
+	return newReportCommandWith(OpenFile)
+}
+
+// newReportCommandWith creates the report command opening reports with open.
+func newReportCommandWith(open func(path string) error) *cobra.Command {
+	var openReport bool
+
+	reportCmd := &cobra.Command{
+		Use:   "report <timestamp|latest>",
+		Short: msg("cmd.report.short"),
+		Long:  msg("cmd.report.long"),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+
+			runs, err := ListRuns(config, func(path string, err error) {
+				printWarning(cmd.ErrOrStderr(), "skipping manifest %s: %v", path, err)
+			})
+			if err != nil {
+				return err
+			}
+			run, err := ResolveRun(runs, args[0])
+			if err != nil {
+				return &UsageError{Err: err}
+			}
+
+			path, err := WriteReport(config, run, "")
+			if err != nil {
+				return err
+			}
+
+			run.ReportPath = path
+			if err := saveRunManifest(config, run); err != nil {
+				printWarning(cmd.ErrOrStderr(), "failed to update manifest: %v", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", msg("summary.report"), path)
+			if openReport {
+				if err := open(path); err != nil {
+					return fmt.Errorf("failed to open %s: %w", path, err)
+				}
+			}
+			return nil
+		},
+	}
+
+	reportCmd.Flags().BoolVar(&openReport, "open", false, msg("cmd.report.flag.open"))
+
+	return reportCmd
+}
+
+// newExportCommand creates the command exporting the research of a past run.
+func newExportCommand() *cobra.Command {
+	var format string
+
+	exportCmd := &cobra.Command{
+		Use:   "export <timestamp|latest>",
+		Short: msg("cmd.export.short"),
+		Long:  msg("cmd.export.long"),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != ExportFormatPDF {
+				return &UsageError{Err: fmt.Errorf("invalid export format %q: must be %s", format, ExportFormatPDF)}
+			}
+
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+
+			runs, err := ListRuns(config, func(path string, err error) {
+				printWarning(cmd.ErrOrStderr(), "skipping manifest %s: %v", path, err)
+			})
+			if err != nil {
+				return err
+			}
+			run, err := ResolveRun(runs, args[0])
+			if err != nil {
+				return &UsageError{Err: err}
+			}
+
+			path, err := ExportPDF(cmd.Context(), config, run)
+			if err != nil {
+				return err
+			}
+			run.Research.PDFPath = path
+			if err := saveRunManifest(config, run); err != nil {
+				printWarning(cmd.ErrOrStderr(), "failed to update manifest: %v", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", msg("summary.pdf"), path)
+			return nil
+		},
+	}
+
+	exportCmd.Flags().StringVar(&format, "format", ExportFormatPDF, msg("cmd.export.flag.format"))
+
+	return exportCmd
+}
+
+// newGalleryCommand creates the command writing or serving the gallery of past runs.
+func newGalleryCommand() *cobra.Command {
+	var (
+		serve string
+		open  bool
+	)
+
+	galleryCmd := &cobra.Command{
+		Use:   "gallery",
+		Short: msg("cmd.gallery.short"),
+		Long:  msg("cmd.gallery.long"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+			warn := func(path string, err error) {
+				printWarning(cmd.ErrOrStderr(), "skipping %s: %v", path, err)
+			}
+
+			if serve == "" {
+				path, err := WriteGallery(config, warn)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", msg("summary.gallery"), path)
+				if open {
+					if err := OpenFile(path); err != nil {
+						return fmt.Errorf("failed to open %s: %w", path, err)
+					}
+				}
+				return nil
+			}
+
+			listener, err := net.Listen("tcp", serve)
+			if err != nil {
+				return &UsageError{Err: fmt.Errorf("failed to listen on %s: %w", serve, err)}
+			}
+			server := &http.Server{Handler: NewGalleryHandler(config, warn), ReadHeaderTimeout: 10 * time.Second}
+			address := "http://" + listener.Addr().String() + "/"
+			fmt.Fprintln(cmd.OutOrStdout(), msg("gallery.serving", config.OutputDir, address))
+			if open {
+				if err := OpenFile(address); err != nil {
+					printWarning(cmd.ErrOrStderr(), "failed to open %s: %v", address, err)
+				}
+			}
+
+			ctx, stop := newSignalContext()
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				server.Shutdown(shutdownCtx)
+			}()
+			if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	galleryCmd.Flags().StringVar(&serve, "serve", "", msg("cmd.gallery.flag.serve"))
+	galleryCmd.Flags().BoolVar(&open, "open", false, msg("cmd.gallery.flag.open"))
+
+	return galleryCmd
+}
+
+// newServeCommand creates the command exposing the pipeline as a local HTTP API, running the
+// pipelines with runner.
+func newServeCommand(runner PipelineRunner) *cobra.Command {
+	var (
+		addr        string
+		concurrency int
+		gracePeriod time.Duration
+	)
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: msg("cmd.serve.short"),
+		Long:  msg("cmd.serve.long"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+			if err := config.loadAPIKey(); err != nil {
+				return &ConfigError{Err: err}
+			}
+			if config.APIKey == "" && config.Auth != AuthADC {
+				return &ConfigError{Err: ErrNoAPIKey}
+			}
+			if cmd.Flags().Changed("concurrency") {
+				config.ServeConcurrency = concurrency
+			}
+			if config.ServeConcurrency < 1 {
+				return &UsageError{Err: fmt.Errorf("concurrency must be at least 1")}
+			}
+			if config.ServeToken == "" {
+				printWarning(cmd.ErrOrStderr(), "serve_token is not set; every request is accepted")
+			}
+
+			server := NewAPIServer(config, config.ServeToken, config.ServeConcurrency, newLogf(cmd.ErrOrStderr()))
+			server.execute = runner
+			ctx, stop := newSignalContext()
+			defer stop()
+			return serveAPI(ctx, server, addr, gracePeriod, func(addr string) {
+				fmt.Fprintln(cmd.OutOrStdout(), msg("serve.serving", addr))
+			})
+		},
+	}
+
+	serveCmd.Flags().StringVar(&addr, "addr", "localhost:8080", msg("cmd.serve.flag.addr"))
+	serveCmd.Flags().IntVar(&concurrency, "concurrency", 1, msg("cmd.serve.flag.concurrency"))
+	serveCmd.Flags().DurationVar(&gracePeriod, "grace-period", 30*time.Second, msg("cmd.serve.flag.grace_period"))
+
+	return serveCmd
+}
+
+// newCleanCommand creates the command removing old run outputs.
+func newCleanCommand() *cobra.Command {
+	var (
+		olderThan  string
+		keepLast   int
+		what       string
+		keepLatest bool
+		tags       []string
+		yes        bool
+		dryRun     bool
+	)
+
+	cleanCmd := &cobra.Command{
+		Use:   "clean",
+		Short: msg("cmd.clean.short"),
+		Long:  msg("cmd.clean.long"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			retention := olderThan == "" && keepLast == 0 && len(tags) == 0
+			if retention && what != ArtifactLogs {
+				return &UsageError{Err: fmt.Errorf("--older-than, --keep-last or --tag is required")}
+			}
+			if keepLast < 0 {
+				return &UsageError{Err: fmt.Errorf("--keep-last must not be negative")}
+			}
+			parsedTags, err := ParseTags(tags)
+			if err != nil {
+				return &UsageError{Err: err}
+			}
+			opts := CleanOptions{KeepLast: keepLast, What: what, Tags: parsedTags, Now: time.Now()}
+			if olderThan != "" {
+				age, err := ParseAge(olderThan)
+				if err != nil {
+					return &UsageError{Err: fmt.Errorf("invalid --older-than: %w", err)}
+				}
+				opts.OlderThan = age
+			}
+
+			config, err := NewViperConfig("")
+			if err != nil {
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+			}
+			if retention {
+				return cleanLogs(cmd, config, yes, dryRun)
+			}
+
+			if keepLatest {
+				entry, err := NewRunHistory(config.HistoryPath()).Last()
+				if err != nil && !errors.Is(err, ErrNoHistory) {
+					return err
+				}
+				if entry != nil {
+					opts.Keep = entry.Timestamp
+				}
+			}
+
+			plan, err := PlanClean(config, opts, func(path string, err error) {
+				printWarning(cmd.ErrOrStderr(), "skipping manifest %s: %v", path, err)
+			})
+			if err != nil {
+				return &UsageError{Err: err}
+			}
+			if len(plan) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), msg("clean.nothing"))
+				return nil
+			}
+
+			printCleanPlan(cmd.OutOrStdout(), plan)
+			if dryRun {
+				return nil
+			}
+			if !yes {
+				fmt.Fprint(cmd.OutOrStdout(), msg("clean.confirm", len(plan)))
+				answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+					fmt.Fprintln(cmd.OutOrStdout(), msg("clean.aborted"))
+					return nil
+				}
+			}
+
+			removed, freed, err := RemoveCleanFiles(config, plan)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), msg("clean.removed", removed, formatSize(freed)))
+			return nil
+		},
+	}
+
+	cleanCmd.Flags().StringVar(&olderThan, "older-than", "", msg("cmd.clean.flag.older_than"))
+	cleanCmd.Flags().IntVar(&keepLast, "keep-last", 0, msg("cmd.clean.flag.keep_last"))
+	cleanCmd.Flags().StringVar(&what, "what", ArtifactAll, msg("cmd.clean.flag.what"))
+	cleanCmd.Flags().StringArrayVar(&tags, "tag", nil, msg("cmd.clean.flag.tag"))
+	cleanCmd.Flags().BoolVar(&keepLatest, "keep-latest", false, msg("cmd.clean.flag.keep_latest"))
+	cleanCmd.Flags().BoolVarP(&yes, "yes", "y", false, msg("cmd.clean.flag.yes"))
+	cleanCmd.Flags().BoolVar(&dryRun, "dry-run", false, msg("cmd.clean.flag.dry_run"))
+
+	cleanCmd.RegisterFlagCompletionFunc("what", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{ArtifactImages, ArtifactResponses, ArtifactLogs, ArtifactAll}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cleanCmd
+}
 
-	return rootCmd
+// cleanLogs applies the log retention policy of the configuration for `deepviz clean --what logs`.
+func cleanLogs(cmd *cobra.Command, config *ViperConfig, yes, dryRun bool) error {
+	policy := config.LogRetention()
+	if !policy.Enabled() {
+		return &UsageError{Err: fmt.Errorf("--older-than, --keep-last or --tag is required unless log_retention_days, log_max_files or log_max_size_mb is set")}
+	}
+	remove, compress, err := PlanLogRetention(config, policy, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(remove) == 0 && len(compress) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), msg("clean.nothing"))
+		return nil
+	}
+
+	if len(remove) > 0 {
+		printCleanPlan(cmd.OutOrStdout(), remove)
+	}
+	for _, file := range compress {
+		fmt.Fprintf(cmd.OutOrStdout(), "%10s  %s (%s)\n", formatSize(file.Size), file.Path, msg("clean.compress"))
+	}
+	if dryRun {
+		return nil
+	}
+	if !yes {
+		fmt.Fprint(cmd.OutOrStdout(), msg("clean.confirm_logs", len(remove), len(compress)))
+		answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			fmt.Fprintln(cmd.OutOrStdout(), msg("clean.aborted"))
+			return nil
+		}
+	}
+
+	removed, freed, err := RemoveCleanFiles(config, remove)
+	if err != nil {
+		return err
+	}
+	compressed, compressedFreed, err := CompressLogFiles(config, compress)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), msg("clean.removed_logs", removed, compressed, formatSize(freed+compressedFreed)))
+	return nil
 }
 
 // newConfigCommand creates the configuration management command.
 func newConfigCommand() *cobra.Command {
 	configCmd := &cobra.Command{
 		Use:   "config",
-		Short: "Configuration management",
+		Short: msg("cmd.config.short"),
 	}
 
 	// config show command
 	configShowCmd := &cobra.Command{
 		Use:   "show",
-		Short: "Display current configuration",
+		Short: msg("cmd.config.show.short"),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			config, err := NewViperConfig("")
 			if err != nil {
-				return fmt.Errorf("failed to load config: %w", err)
+				return &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
 			}
 
 			// Display configuration
-			fmt.Fprintf(cmd.OutOrStdout(), "Current Configuration:\n")
+			fmt.Fprintln(cmd.OutOrStdout(), msg("config.show.header"))
 			fmt.Fprintf(cmd.OutOrStdout(), "  output_dir: %s\n", config.OutputDir)
-			fmt.Fprintf(cmd.OutOrStdout(), "  api_key: %s\n", maskAPIKey(config.APIKey))
+			fmt.Fprintf(cmd.OutOrStdout(), "  layout: %s\n", config.Layout)
+			fmt.Fprintf(cmd.OutOrStdout(), "  filename_style: %s\n", config.FilenameStyle)
+			fmt.Fprintf(cmd.OutOrStdout(), "  output_template: %s\n", config.OutputTemplate)
+			fmt.Fprintf(cmd.OutOrStdout(), "  timestamp_format: %s\n", config.TimestampFormat)
+			fmt.Fprintf(cmd.OutOrStdout(), "  timestamp_utc: %t\n", config.TimestampUTC)
+			fmt.Fprintf(cmd.OutOrStdout(), "  overwrite: %t\n", config.Overwrite)
+			fmt.Fprintf(cmd.OutOrStdout(), "  state_dir: %s\n", config.StateDir)
+			// An API key which cannot be resolved (e.g., a failing api_key_cmd) is shown with the error
+			if err := config.loadAPIKey(); err != nil {
+				fmt.Fprintf(cmd.OutOrStdout(), "  api_key: (%v)\n", err)
+			} else if config.apiKeySource != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "  api_key: %s (from %s)\n", maskAPIKey(config.APIKey), config.apiKeySource)
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "  api_key: %s\n", maskAPIKey(config.APIKey))
+			}
+			masked := make([]string, len(config.APIKeys))
+			for i, key := range config.APIKeys {
+				masked[i] = maskAPIKey(key)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "  api_keys: %s\n", strings.Join(masked, ","))
+			fmt.Fprintf(cmd.OutOrStdout(), "  api_key_file: %s\n", config.APIKeyFile)
+			fmt.Fprintf(cmd.OutOrStdout(), "  api_key_cmd: %s\n", config.APIKeyCmd)
+			fmt.Fprintf(cmd.OutOrStdout(), "  use_keyring: %t\n", config.UseKeyring)
+			fmt.Fprintf(cmd.OutOrStdout(), "  auth: %s\n", config.Auth)
+			fmt.Fprintf(cmd.OutOrStdout(), "  vertex_project: %s\n", config.VertexProject)
+			fmt.Fprintf(cmd.OutOrStdout(), "  vertex_location: %s\n", config.VertexLocation)
 			fmt.Fprintf(cmd.OutOrStdout(), "  deep_research_agent: %s\n", config.DeepResearchAgent)
+			fmt.Fprintf(cmd.OutOrStdout(), "  research_tools: %s\n", strings.Join(config.ResearchTools, ","))
+			fmt.Fprintf(cmd.OutOrStdout(), "  thinking_summaries: %s\n", config.ThinkingSummaries)
 			fmt.Fprintf(cmd.OutOrStdout(), "  poll_interval: %d\n", config.PollInterval)
+			fmt.Fprintf(cmd.OutOrStdout(), "  poll_max_interval: %d\n", config.PollMaxInterval)
 			fmt.Fprintf(cmd.OutOrStdout(), "  poll_timeout: %d\n", config.PollTimeout)
+			fmt.Fprintf(cmd.OutOrStdout(), "  poll_max_failures: %d\n", config.PollMaxFailures)
+			fmt.Fprintf(cmd.OutOrStdout(), "  keep_on_failure: %t\n", config.KeepOnFailure)
+			fmt.Fprintf(cmd.OutOrStdout(), "  save_poll_snapshots: %t\n", config.SavePollSnapshots)
+			fmt.Fprintf(cmd.OutOrStdout(), "  save_http_exchange: %t\n", config.SaveHTTPExchange)
+			fmt.Fprintf(cmd.OutOrStdout(), "  save_responses: %t\n", !config.NoSaveResponses)
+			fmt.Fprintf(cmd.OutOrStdout(), "  append_sources: %t\n", config.AppendSources)
+			fmt.Fprintf(cmd.OutOrStdout(), "  markdown_front_matter: %t\n", config.MarkdownFrontMatter)
+			fmt.Fprintf(cmd.OutOrStdout(), "  max_retries: %d\n", config.MaxRetries)
+			fmt.Fprintf(cmd.OutOrStdout(), "  retry_max_wait: %d\n", config.RetryMaxWait)
+			fmt.Fprintf(cmd.OutOrStdout(), "  image_timeout: %d\n", config.ImageTimeout)
+			fmt.Fprintf(cmd.OutOrStdout(), "  research_timeout: %d\n", config.ResearchTimeout)
+			fmt.Fprintf(cmd.OutOrStdout(), "  request_timeout: %d\n", config.RequestTimeout)
+			fmt.Fprintf(cmd.OutOrStdout(), "  connect_timeout: %d\n", config.ConnectTimeout)
 			fmt.Fprintf(cmd.OutOrStdout(), "  model: %s\n", config.Model)
+			fmt.Fprintf(cmd.OutOrStdout(), "  fallback_model: %s\n", config.FallbackModel)
 			fmt.Fprintf(cmd.OutOrStdout(), "  aspect_ratio: %s\n", config.AspectRatio)
 			fmt.Fprintf(cmd.OutOrStdout(), "  image_size: %s\n", config.ImageSize)
 			fmt.Fprintf(cmd.OutOrStdout(), "  image_lang: %s\n", config.ImageLang)
+			fmt.Fprintf(cmd.OutOrStdout(), "  image_count: %d\n", config.ImageCount)
+			fmt.Fprintf(cmd.OutOrStdout(), "  image_retries: %d\n", config.ImageRetries)
+			fmt.Fprintf(cmd.OutOrStdout(), "  image_format: %s\n", config.ImageFormat)
+			fmt.Fprintf(cmd.OutOrStdout(), "  image_quality: %d\n", config.ImageQuality)
+			fmt.Fprintf(cmd.OutOrStdout(), "  keep_original: %t\n", config.KeepOriginal)
+			generationConfig, err := json.Marshal(config.GenerationConfig)
+			if err != nil {
+				return fmt.Errorf("failed to marshal generation config: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "  safety_settings: %v\n", config.SafetySettings)
+			fmt.Fprintf(cmd.OutOrStdout(), "  seed: %s\n", formatOptional(config.Seed))
+			fmt.Fprintf(cmd.OutOrStdout(), "  temperature: %s\n", formatOptional(config.Temperature))
+			fmt.Fprintf(cmd.OutOrStdout(), "  top_p: %s\n", formatOptional(config.TopP))
+			fmt.Fprintf(cmd.OutOrStdout(), "  top_k: %s\n", formatOptional(config.TopK))
+			fmt.Fprintf(cmd.OutOrStdout(), "  generation_config: %s\n", generationConfig)
+			fmt.Fprintf(cmd.OutOrStdout(), "  save_captions: %t\n", config.SaveCaptions)
+			fmt.Fprintf(cmd.OutOrStdout(), "  image_prompt_template: %q\n", config.ImagePromptTemplate)
+			fmt.Fprintf(cmd.OutOrStdout(), "  image_system_instruction: %q\n", config.ImageSystemInstruction)
+			fmt.Fprintf(cmd.OutOrStdout(), "  style: %s\n", config.Style)
+			fmt.Fprintf(cmd.OutOrStdout(), "  styles: %v\n", availableStyles(config.Styles))
+			fmt.Fprintf(cmd.OutOrStdout(), "  report_template: %s\n", config.ReportTemplate)
+			fmt.Fprintf(cmd.OutOrStdout(), "  pdf_converter: %s\n", config.PDFConverter)
+			fmt.Fprintf(cmd.OutOrStdout(), "  serve_token: %s\n", maskAPIKey(config.ServeToken))
+			fmt.Fprintf(cmd.OutOrStdout(), "  serve_concurrency: %d\n", config.ServeConcurrency)
+			fmt.Fprintf(cmd.OutOrStdout(), "  log_output: %s\n", config.LogOutput)
+			fmt.Fprintf(cmd.OutOrStdout(), "  log_format: %s\n", config.LogFormat)
+			fmt.Fprintf(cmd.OutOrStdout(), "  log_trace_bodies: %t\n", config.LogTraceBodies)
+			fmt.Fprintf(cmd.OutOrStdout(), "  log_retention_days: %d\n", config.LogRetentionDays)
+			fmt.Fprintf(cmd.OutOrStdout(), "  log_max_files: %d\n", config.LogMaxFiles)
+			fmt.Fprintf(cmd.OutOrStdout(), "  log_max_size_mb: %d\n", config.LogMaxSizeMB)
+			fmt.Fprintf(cmd.OutOrStdout(), "  cli_lang: %s\n", config.CLILang)
+			fmt.Fprintf(cmd.OutOrStdout(), "  proxy_url: %s\n", config.proxyURLRedacted())
+			fmt.Fprintf(cmd.OutOrStdout(), "  ca_cert_file: %s\n", config.CACertFile)
+			fmt.Fprintf(cmd.OutOrStdout(), "  insecure_skip_verify: %t\n", config.InsecureSkipVerify)
+			fmt.Fprintf(cmd.OutOrStdout(), "  user_agent_suffix: %s\n", config.UserAgentSuffix)
 
 			return nil
 		},
@@ -179,7 +2005,7 @@ func newConfigCommand() *cobra.Command {
 	var configDir string
 	configInitCmd := &cobra.Command{
 		Use:   "init",
-		Short: "Initialize configuration file",
+		Short: msg("cmd.config.init.short"),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Determine config file directory (XDG Base Directory compliant)
 			if configDir == "" {
@@ -197,32 +2023,76 @@ func newConfigCommand() *cobra.Command {
 			// Create new configuration
 			config, err := NewViperConfig(configDir)
 			if err != nil {
-				return fmt.Errorf("failed to create config: %w", err)
-			}
-
-			// Set default values (XDG Base Directory compliant)
-			defaultOutputDir := "/tmp/deepviz-output"
-			xdgDataHome := os.Getenv("XDG_DATA_HOME")
-			if xdgDataHome == "" {
-				home, err := os.UserHomeDir()
-				if err == nil {
-					xdgDataHome = filepath.Join(home, ".local", "share")
-				}
-			}
-			if xdgDataHome != "" {
-				defaultOutputDir = filepath.Join(xdgDataHome, "deepviz")
+				return &ConfigError{Err: fmt.Errorf("failed to create config: %w", err)}
 			}
 
-			config.Set("output_dir", defaultOutputDir)
+			// Set default values (the defaults of the runtime, see setViperDefaults)
+			config.Set("output_dir", defaultOutputDir())
+			config.Set("layout", LayoutByType)
+			config.Set("filename_style", FilenameStyleTimestamp)
+			config.Set("output_template", "")
+			config.Set("timestamp_format", timestampLayout)
+			config.Set("timestamp_utc", false)
+			config.Set("overwrite", false)
+			config.Set("state_dir", defaultStateDir())
 			config.Set("api_key", "")
+			config.Set("api_key_file", "")
+			config.Set("api_key_cmd", "")
+			config.Set("api_keys", "")
+			config.Set("use_keyring", false)
+			config.Set("auth", AuthAPIKey)
+			config.Set("vertex_project", "")
+			config.Set("vertex_location", "global")
 			config.Set("deep_research_agent", "deep-research-pro-preview-12-2025")
+			config.Set("research_tools", strings.Join(defaultResearchTools, ","))
+			config.Set("thinking_summaries", "auto")
 			config.Set("poll_interval", 10)
+			config.Set("poll_max_interval", 60)
 			config.Set("poll_timeout", 600)
+			config.Set("poll_max_failures", 5)
+			config.Set("keep_on_failure", false)
+			config.Set("save_poll_snapshots", false)
+			config.Set("save_http_exchange", false)
+			config.Set("save_responses", true)
+			config.Set("append_sources", false)
+			config.Set("markdown_front_matter", true)
+			config.Set("max_retries", 3)
+			config.Set("retry_max_wait", 120)
+			config.Set("image_timeout", 120)
+			config.Set("research_timeout", 0)
+			config.Set("request_timeout", 30)
+			config.Set("connect_timeout", 30)
 			config.Set("model", "gemini-3-pro-image-preview")
+			config.Set("fallback_model", "")
 			config.Set("aspect_ratio", "16:9")
 			config.Set("image_size", "2K")
 			config.Set("image_lang", "Japanese")
+			config.Set("image_count", 1)
+			config.Set("image_retries", 2)
+			config.Set("image_format", "")
+			config.Set("image_quality", 90)
+			config.Set("keep_original", false)
+			config.Set("generation_config", "")
+			config.Set("save_captions", true)
+			config.Set("image_prompt_template", "")
+			config.Set("image_system_instruction", "")
+			config.Set("style", "")
 			config.Set("auto_open", true)
+			config.Set("report_template", "")
+			config.Set("pdf_converter", "")
+			config.Set("serve_token", "")
+			config.Set("serve_concurrency", 1)
+			config.Set("log_output", LogOutputStderr)
+			config.Set("log_format", "")
+			config.Set("log_trace_bodies", false)
+			config.Set("log_retention_days", 0)
+			config.Set("log_max_files", 0)
+			config.Set("log_max_size_mb", 0)
+			config.Set("cli_lang", "")
+			config.Set("proxy_url", "")
+			config.Set("ca_cert_file", "")
+			config.Set("insecure_skip_verify", false)
+			config.Set("user_agent_suffix", "")
 
 			// Save config file
 			if err := config.Save(); err != nil {
@@ -230,40 +2100,61 @@ func newConfigCommand() *cobra.Command {
 			}
 
 			configPath := filepath.Join(configDir, "config.yaml")
-			fmt.Fprintf(cmd.OutOrStdout(), "Config file created: %s\n", configPath)
+			fmt.Fprintln(cmd.OutOrStdout(), msg("config.init.created", configPath))
 			return nil
 		},
 	}
-	configInitCmd.Flags().StringVar(&configDir, "config-dir", "", "Configuration file directory")
+	configInitCmd.Flags().StringVar(&configDir, "config-dir", "", msg("cmd.config.init.flag.config_dir"))
 
 	configCmd.AddCommand(configShowCmd)
 	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(newConfigSetKeyCommandWith(OSKeyring))
 
 	return configCmd
 }
 
-// newCompletionCommand creates the shell completion command.
-func newCompletionCommand() *cobra.Command {
+// newConfigSetKeyCommandWith creates the command storing the API key in the keyring opened with
+// keyring.
+func newConfigSetKeyCommandWith(keyring func() (Keyring, error)) *cobra.Command {
 	return &cobra.Command{
-		Use:   "completion [bash|zsh|fish|powershell]",
-		Short: "Generate completion script",
-		Long: `To load completions:
-
-Bash:
-  $ source <(deepviz completion bash)
-  $ echo "source <(deepviz completion bash)" >> ~/.bashrc
-
-Zsh:
-  $ source <(deepviz completion zsh)
-  $ echo "source <(deepviz completion zsh)" >> ~/.zshrc
+		Use:   "set-key",
+		Short: msg("cmd.config.set_key.short"),
+		Long:  msg("cmd.config.set_key.long"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			k, err := keyring()
+			if err != nil {
+				return &ConfigError{Err: err}
+			}
+			apiKey, err := readSecret(cmd.InOrStdin(), cmd.ErrOrStderr())
+			if err != nil {
+				return fmt.Errorf("failed to read the API key: %w", err)
+			}
+			if apiKey == "" {
+				return &UsageError{Err: errors.New("no API key given")}
+			}
+			if strings.ContainsFunc(apiKey, func(r rune) bool { return r <= ' ' || r > '~' }) {
+				return &UsageError{Err: errors.New("the API key must be printable ASCII characters without spaces")}
+			}
+			if err := k.Set(apiKey); err != nil {
+				return fmt.Errorf("failed to store the API key in the keyring: %w", err)
+			}
 
-Fish:
-  $ deepviz completion fish | source
-  $ deepviz completion fish > ~/.config/fish/completions/deepviz.fish
+			fmt.Fprintln(cmd.OutOrStdout(), msg("config.set_key.stored", maskAPIKey(apiKey)))
+			if config, err := NewViperConfig(""); err != nil || !config.UseKeyring {
+				fmt.Fprintln(cmd.OutOrStdout(), msg("config.set_key.use_keyring"))
+			}
+			return nil
+		},
+	}
+}
 
-PowerShell:
-  PS> deepviz completion powershell | Out-String | Invoke-Expression
-`,
+// newCompletionCommand creates the shell completion command.
+func newCompletionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:                   "completion [bash|zsh|fish|powershell]",
+		Short:                 msg("cmd.completion.short"),
+		Long:                  msg("cmd.completion.long"),
 		DisableFlagsInUseLine: true,
 		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
 		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
@@ -282,6 +2173,82 @@ PowerShell:
 	}
 }
 
+// newVersionCommand creates the command that prints the build information.
+func newVersionCommand() *cobra.Command {
+	var jsonOutput bool
+
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: msg("cmd.version.short"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := version.Get()
+			if jsonOutput {
+				data, err := json.MarshalIndent(info, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal version: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return nil
+			}
+			fmt.Fprint(cmd.OutOrStdout(), info.String())
+			return nil
+		},
+	}
+
+	versionCmd.Flags().BoolVar(&jsonOutput, "json", false, msg("flag.json"))
+
+	return versionCmd
+}
+
+// newUpgradeCommand creates the command that replaces the binary with the latest release.
+func newUpgradeCommand() *cobra.Command {
+	return newUpgradeCommandWith(newGitHubReleases(), os.Executable)
+}
+
+// newUpgradeCommandWith creates the upgrade command installing the releases of source over the
+// binary at executable.
+func newUpgradeCommandWith(source ReleaseSource, executable func() (string, error)) *cobra.Command {
+	var check bool
+
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: msg("cmd.upgrade.short"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := executable()
+			if err != nil {
+				return fmt.Errorf("failed to locate the deepviz binary: %w", err)
+			}
+			// Replace the binary itself rather than a symlink to it
+			if resolved, err := filepath.EvalSymlinks(path); err == nil {
+				path = resolved
+			}
+
+			ctx, stop := newSignalContext()
+			defer stop()
+			ctx, cancel := context.WithTimeout(ctx, upgradeTimeout)
+			defer cancel()
+
+			u := &upgrader{source: source, current: version.Version(), executable: path, goos: runtime.GOOS, goarch: runtime.GOARCH}
+			return u.run(ctx, cmd.OutOrStdout(), check)
+		},
+	}
+
+	upgradeCmd.Flags().BoolVar(&check, "check", false, msg("cmd.upgrade.flag.check"))
+
+	return upgradeCmd
+}
+
+// changedFlags returns the names of flags explicitly set on the command line.
+func changedFlags(cmd *cobra.Command) map[string]bool {
+	changed := make(map[string]bool)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		changed[f.Name] = true
+	})
+	return changed
+}
+
 // maskAPIKey masks the API key.
 func maskAPIKey(apiKey string) string {
 	if apiKey == "" {
@@ -293,41 +2260,288 @@ func maskAPIKey(apiKey string) string {
 	return apiKey[:4] + "****" + apiKey[len(apiKey)-4:]
 }
 
-// RunWithConfig executes the main processing using the configuration.
-func RunWithConfig(opts *Options, config *ViperConfig) error {
-	// Create context
-	ctx := context.Background()
+// formatOptional formats an optional configuration value.
+func formatOptional[T any](v *T) string {
+	if v == nil {
+		return "(not set)"
+	}
+	return fmt.Sprint(*v)
+}
+
+// newSignalContext returns a context cancelled on SIGINT/SIGTERM.
+//
+// After the first signal, default signal handling is restored so that a second Ctrl+C force-exits.
+func newSignalContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// RunWithConfig executes the main processing using the configuration and returns the result of the
+// run without printing it (also with an error, describing how far the run got).
+//
+// With multiple prompt files, the pipeline runs once per file, each printing its own result, and
+// the returned result is nil. The result of a dry run is nil too.
+// SIGINT/SIGTERM cancel the context. A second signal terminates the process immediately.
+func RunWithConfig(opts *Options, config *ViperConfig) (*RunResult, error) {
+	// Create context cancelled on Ctrl+C
+	ctx, stop := newSignalContext()
+	defer stop()
 
-	// Generate timestamp
-	timestamp := GenerateTimestamp()
+	files, err := expandPromptFiles(opts.Files)
+	if err != nil {
+		return nil, &UsageError{Err: err}
+	}
+	if len(files) > 1 && opts.Prompt != "" {
+		return nil, &UsageError{Err: errors.New(msg("error.prompt_with_files"))}
+	}
 
-	// Ensure output directories exist
-	if err := config.EnsureDirectories(); err != nil {
-		return fmt.Errorf("failed to ensure directories: %w", err)
+	// Notify about researches left unfinished by previous runs (not among progress events)
+	if opts.Progress == nil {
+		if records, err := NewRunState(config.RunsStateDir()).Load(); err != nil {
+			printWarning(os.Stderr, "failed to load run state: %v", err)
+		} else {
+			printUnfinishedRuns(os.Stderr, records, opts.InteractionID)
+		}
+	}
+
+	// Prune old logs before the logs of this run are created
+	applyLogRetention(opts, config)
+
+	if len(files) > 1 {
+		return nil, runBatch(ctx, opts, config, files)
+	}
+	if len(files) == 1 {
+		opts.File = files[0]
+	}
+	result, err := runPipelineWith(ctx, opts, config)
+	if opts.DryRun {
+		return nil, err
+	}
+	return result, err
+}
+
+// runPipeline executes research and image generation for a single prompt of a batch or job and
+// prints its result.
+func runPipeline(ctx context.Context, opts *Options, config *ViperConfig) error {
+	result, err := runPipelineWith(ctx, opts, config)
+	if err != nil || opts.DryRun {
+		return err
+	}
+	return printRunResult(opts.stdout(), result, opts.JSON, opts.Quiet)
+}
+
+// PipelineRunner runs the pipeline of a single prompt, like ExecutePipeline.
+type PipelineRunner func(ctx context.Context, opts *Options, config *ViperConfig) (*RunResult, error)
+
+// runPipelineWith runs a pipeline with the runner of opts.
+func runPipelineWith(ctx context.Context, opts *Options, config *ViperConfig) (*RunResult, error) {
+	if opts.Runner != nil {
+		return opts.Runner(ctx, opts, config)
 	}
+	return ExecutePipeline(ctx, opts, config)
+}
+
+// ExecutePipeline executes research and image generation for a single prompt.
+//
+// The artifacts, manifest and history entry are written as usual, but no summary is printed. The
+// result describes how far the run got, also when an error is returned.
+func ExecutePipeline(ctx context.Context, opts *Options, config *ViperConfig) (*RunResult, error) {
+	result := &RunResult{OutputDir: config.OutputDir}
+	err := executePipeline(ctx, opts, config, result)
+	result.fillFromManifest()
+	return result, err
+}
 
-	// Create log file path with timestamp
-	logFilePath := filepath.Join(config.LogsDir(), timestamp+".log")
+// executePipeline executes a pipeline run, recording its outcome in result.
+func executePipeline(ctx context.Context, opts *Options, config *ViperConfig, result *RunResult) (retErr error) {
+	// Generate timestamp (batch runs allocate unique ones)
+	timestamp := opts.Timestamp
+	if timestamp == "" {
+		timestamp = config.Namer().GenerateTimestamp()
+	}
+	progress := opts.Progress
+	defer func() {
+		if retErr != nil {
+			progress.EmitError(timestamp, retErr)
+		}
+	}()
 
-	// Create logger
-	logger := NewSlogLogger(opts.Verbose, logFilePath)
+	// Ensure output directories exist
+	if err := config.EnsureDirectories(); err != nil {
+		return &ConfigError{Err: fmt.Errorf("failed to ensure directories: %w", err)}
+	}
+	if err := config.loadAPIKey(); err != nil {
+		return &ConfigError{Err: err}
+	}
 
 	// Get prompt (from file or direct)
 	prompt := opts.Prompt
+	var strippedInteractionID string
+	var appliedFrontMatter bool
 	if opts.File != "" {
 		data, err := ReadFile(opts.File)
 		if err != nil {
-			return fmt.Errorf("failed to read prompt file: %w", err)
+			return &UsageError{Err: fmt.Errorf("failed to read prompt file: %w", err)}
 		}
 		prompt = string(data)
 		if prompt == "" {
-			return fmt.Errorf("prompt file is empty: %s", opts.File)
+			return &UsageError{Err: fmt.Errorf("prompt file is empty: %s", opts.File)}
+		}
+
+		// Saved research markdown (e.g., with --image-only) carries metadata that must not reach the image prompt
+		researchFrontMatter, body := ParseResearchFrontMatter(prompt)
+		if researchFrontMatter != nil {
+			prompt = body
+			strippedInteractionID = researchFrontMatter.InteractionID
+		}
+
+		// Apply per-prompt options from front matter (explicit flags win)
+		frontMatter, body, err := ParseFrontMatter(prompt)
+		if err != nil {
+			return &UsageError{Err: fmt.Errorf("%s: %w", opts.File, err)}
+		}
+		if frontMatter != nil {
+			frontMatter.Apply(opts, config)
+			prompt = body
+			appliedFrontMatter = true
+			if strings.TrimSpace(prompt) == "" {
+				return &UsageError{Err: fmt.Errorf("prompt file has no content after front matter: %s", opts.File)}
+			}
+		}
+	}
+
+	// Render template variables (prompt files always, --prompt only with --template-vars)
+	if opts.File != "" || opts.TemplateVars {
+		name := "prompt"
+		if opts.File != "" {
+			name = filepath.Base(opts.File)
+		}
+		rendered, err := RenderPromptTemplate(name, prompt, promptTemplateData(opts.Vars, timestamp, time.Now()))
+		if err != nil {
+			return &UsageError{Err: err}
 		}
+		prompt = rendered
+	}
+
+	// Name the artifacts of the run (--name implies the both style when filenames are timestamps only)
+	slug, style := opts.Name, config.FilenameStyle
+	if slug == "" {
+		slug = promptSlug(prompt)
+	} else if style == FilenameStyleTimestamp {
+		style = FilenameStyleBoth
+	}
+	name := RunName(timestamp, slug, style)
+	config.runSlug = slug
+	config.runID = NewRunID()
+	result.Timestamp, result.Name, result.RunID = timestamp, name, config.runID
+
+	// Create logger (progress events replace the console log)
+	var slogLogger *SlogLogger
+	if progress != nil {
+		slogLogger = NewFileLogger(config.LogFile(name), config.secrets()...)
+	} else {
+		slogLogger = NewSlogLogger(ConsoleLogOptions{
+			Writer:  LogConsole(config.LogOutput),
+			Format:  config.LogFormat,
+			Level:   opts.logLevel(),
+			Handler: opts.LogHandler,
+		}, config.LogFile(name), config.secrets()...)
+	}
+	slogLogger = slogLogger.With("run_id", config.runID)
+	defer slogLogger.Close()
+	// The logger warns on the console, which progress events and quiet mode replace
+	if err := slogLogger.FileError(); err != nil && (progress != nil || opts.Quiet) {
+		warnPipeline(progress, timestamp, fmt.Sprintf("failed to open log file: %v", err))
+	}
+	var logger Logger = slogLogger
+	if opts.File != "" {
 		logger.Info("Loaded prompt from file", "file", opts.File)
+		if strippedInteractionID != "" {
+			logger.Info("Stripped research front matter", "file", opts.File, "interaction_id", strippedInteractionID)
+		}
+		if appliedFrontMatter {
+			logger.Info("Applied front matter", "file", opts.File)
+		}
+	}
+
+	// Validate the image prompt template before any API call
+	if !opts.ResearchOnly {
+		if _, err := ParseImagePromptTemplate(config.ImagePromptTemplate); err != nil {
+			return &ConfigError{Err: err}
+		}
+	}
+
+	// Stop before any API request in dry-run mode
+	if opts.DryRun {
+		logger.Info("Dry run", "research_only", opts.ResearchOnly, "image_only", opts.ImageOnly)
+		return printDryRun(os.Stderr, opts, config, prompt)
 	}
 
 	logger.Info("Pipeline started")
-	logger.Info("Configuration", "timestamp", timestamp, "output_dir", config.OutputDir)
+	spinner := newTerminalProgress(opts)
+	progress.Emit(ProgressEvent{Event: EventPipelineStarted, Timestamp: timestamp})
+	logger.Info("Configuration", "timestamp", timestamp, "name", name, "output_dir", config.OutputDir)
+	if config.proxyURL != nil {
+		logger.Info("Using proxy", "proxy_url", config.proxyURLRedacted())
+	}
+	if config.CACertFile != "" {
+		logger.Info("Trusting additional CA certificates", "ca_cert_file", config.CACertFile)
+	}
+	if config.adc != nil {
+		logger.Info("Using Vertex AI with Application Default Credentials", "vertex_project", config.VertexProject, "vertex_location", config.VertexLocation)
+	}
+	if config.apiKeyPool != nil {
+		config.apiKeyRotation = newAPIKeyRotation(config.apiKeyPool, logger)
+		index, key := config.apiKeyRotation.Used()
+		logger.Info("Rotating API keys on quota errors", "keys", config.apiKeyPool.Len(), "key_index", index, "key", key)
+	}
+	if config.SaveHTTPExchange {
+		config.httpCapture = NewHTTPCapture(config.HTTPExchangeDir(name), logger, config.secrets()...)
+		logger.Info("Saving HTTP exchanges", "dir", config.httpCapture.Dir())
+	}
+	if opts.Curl {
+		if config.httpCapture != nil {
+			config.httpCapture.PrintCurl(os.Stderr)
+		} else {
+			config.httpCapture = NewCurlCapture(config.HTTPExchangeDir(name), os.Stderr, logger, config.secrets()...)
+		}
+		logger.Info("Printing API requests as curl commands")
+	}
+	if config.cassette != nil {
+		if config.cassette.Replaying() {
+			logger.Info("Replaying HTTP exchanges", "cassette", config.cassette.Path())
+		} else {
+			logger.Info("Recording HTTP exchanges", "cassette", config.cassette.Path())
+		}
+	}
+
+	// Record the run in a manifest updated after each stage (written even when the run fails)
+	manifestPath := config.ManifestPath(name)
+	manifest := newRunManifest(timestamp, prompt, opts.File, config.LogPath(name), opts.Tags, time.Now())
+	if name != timestamp {
+		manifest.Name = name
+	}
+	manifest.RunID = config.runID
+	manifest.ResponsesSkipped = config.NoSaveResponses
+	saveManifest := func() {
+		if err := manifest.Save(manifestPath); err != nil {
+			logger.Error("Failed to save manifest", "error", err)
+		}
+	}
+	saveManifest()
+	defer func() {
+		if config.apiKeyRotation != nil {
+			index, key := config.apiKeyRotation.Used()
+			manifest.APIKey = &ManifestAPIKey{Index: index, Key: key}
+		}
+		manifest.finish(retErr, time.Now())
+		saveManifest()
+	}()
+	result.Manifest, result.ManifestPath = manifest, manifestPath
 
 	var researchResult *ResearchResult
 	var imageResult *ImageResult
@@ -336,35 +2550,185 @@ func RunWithConfig(opts *Options, config *ViperConfig) error {
 	if !opts.ImageOnly {
 		logger.Info("Starting Deep Research")
 
-		researchClient, err := NewGenaiResearchClient(ctx, config, logger)
+		manifest.Research = &ManifestResearch{
+			Status:            RunStatusRunning,
+			InteractionID:     opts.InteractionID,
+			Agent:             config.DeepResearchAgent,
+			ThinkingSummaries: config.ThinkingSummaries,
+			StartedAt:         time.Now(),
+		}
+		if !config.NoResearchTools {
+			manifest.Research.Tools = config.ResearchTools
+			if len(manifest.Research.Tools) == 0 {
+				manifest.Research.Tools = defaultResearchTools
+			}
+		}
+		saveManifest()
+
+		// Persist the in-flight research so that it can be resumed after a crash
+		runState := NewRunState(config.RunsStateDir())
+		interactionID := opts.InteractionID
+		// A resumed research is read with the key that created it
+		if config.apiKeyPool != nil && interactionID != "" {
+			record, err := runState.Get(interactionID)
+			switch {
+			case err != nil:
+				logger.Warn("Failed to read run state", "error", err)
+			case record == nil || record.Options.APIKeyIndex == nil:
+				logger.Warn("No API key recorded for the interaction, resuming with the key in use", "interaction_id", interactionID)
+			default:
+				if err := config.apiKeyPool.Pin(interactionID, *record.Options.APIKeyIndex); err != nil {
+					logger.Warn("Cannot resume with the key that created the interaction", "interaction_id", interactionID, "error", err)
+				}
+			}
+		}
+		pinnedKey := func(id string) *ManifestAPIKey {
+			if config.apiKeyPool == nil {
+				return nil
+			}
+			if index, key, ok := config.apiKeyPool.Pinned(id); ok {
+				return &ManifestAPIKey{Index: index, Key: maskAPIKey(key)}
+			}
+			return nil
+		}
+		manifest.Research.APIKey = pinnedKey(interactionID)
+		var hooks ResearchHooks
+		var thoughtOutput io.Writer = os.Stderr
+		if spinner != nil {
+			hooks.Progress = spinner
+			thoughtOutput = spinner
+		}
+		if !opts.QuietPoll && progress == nil {
+			hooks.OnThought = func(summary string) {
+				printThought(thoughtOutput, summary)
+			}
+		}
+		if progress != nil {
+			hooks.OnPoll = func(status string, elapsed time.Duration) {
+				progress.Emit(ProgressEvent{Event: EventResearchPolling, Timestamp: timestamp, Status: status, ElapsedSeconds: elapsed.Seconds()})
+			}
+		}
+		hooks.OnStarted = func(id string) {
+			interactionID = id
+			progress.Emit(ProgressEvent{Event: EventResearchStarted, Timestamp: timestamp, InteractionID: id})
+			record := &RunRecord{
+				InteractionID: id,
+				PromptHash:    HashPrompt(prompt),
+				Timestamp:     timestamp,
+				StartedAt:     time.Now(),
+				Options: RunRecordOptions{
+					ResearchOnly: opts.ResearchOnly,
+					OutputDir:    config.OutputDir,
+					Model:        opts.Model,
+					AspectRatio:  opts.AspectRatio,
+					ImageSize:    opts.ImageSize,
+					ImageLang:    config.ImageLang,
+				},
+			}
+			manifest.Research.APIKey = pinnedKey(id)
+			if manifest.Research.APIKey != nil {
+				record.Options.APIKeyIndex = &manifest.Research.APIKey.Index
+			}
+			if err := runState.Save(record); err != nil {
+				logger.Error("Failed to save run state", "error", err)
+			}
+			manifest.Research.InteractionID = id
+			saveManifest()
+		}
+
+		researchClient, err := opts.Clients.newResearch(ctx, config, logger, hooks)
 		if err != nil {
-			return fmt.Errorf("failed to create research client: %w", err)
+			manifest.Research.finish(nil, err, time.Now())
+			return &StageError{Stage: StageResearch, Err: fmt.Errorf("failed to create research client: %w", err)}
+		}
+
+		if opts.InteractionID != "" {
+			progress.Emit(ProgressEvent{Event: EventResearchStarted, Timestamp: timestamp, InteractionID: opts.InteractionID})
+			researchResult, err = researchClient.Resume(ctx, opts.InteractionID, name)
+		} else {
+			researchResult, err = researchClient.Execute(ctx, prompt, name)
 		}
 
-		researchResult, err = researchClient.Execute(ctx, prompt, timestamp)
+		// Keep the run record only while the research can still be resumed
+		var resumableErr *ResumableError
+		if err != nil && errors.As(err, &resumableErr) {
+			// The error event carries the interaction ID
+			if progress == nil {
+				fmt.Fprintf(os.Stderr, "\n%s\n", msg("resume.available", resumableErr.InteractionID))
+				fmt.Fprintln(os.Stderr, msg("resume.hint", resumableErr.InteractionID))
+			}
+		} else if interactionID != "" {
+			if err := runState.Complete(interactionID); err != nil {
+				logger.Error("Failed to complete run state", "error", err)
+			}
+		}
+		manifest.Research.finish(researchResult, err, time.Now())
+		saveManifest()
 		if err != nil {
-			return fmt.Errorf("failed to execute research: %w", err)
+			return &StageError{Stage: StageResearch, Err: fmt.Errorf("failed to execute research: %w", err)}
 		}
+		result.Research = researchResult
 		logger.Info("Deep Research completed")
+		progress.Emit(ProgressEvent{Event: EventResearchCompleted, Timestamp: timestamp, MarkdownPath: researchResult.MarkdownPath})
 	}
 
 	// Execute image generation (except ResearchOnly mode)
 	if !opts.ResearchOnly {
 		logger.Info("Starting image generation")
+		progress.Emit(ProgressEvent{Event: EventImageStarted, Timestamp: timestamp})
+
+		manifest.Image = &ManifestImage{
+			Status:      RunStatusRunning,
+			Model:       opts.Model,
+			AspectRatio: opts.AspectRatio,
+			ImageSize:   opts.ImageSize,
+			Lang:        config.ImageLang,
+			Style:       config.Style,
+			StartedAt:   time.Now(),
+		}
+		saveManifest()
 
-		imageClient, err := NewGenaiImageClient(ctx, config, logger)
+		var imageProgress ProgressReporter
+		if spinner != nil {
+			imageProgress = spinner
+		}
+		imageClient, err := opts.Clients.newImage(ctx, config, logger, imageProgress)
 		if err != nil {
-			return fmt.Errorf("failed to create image client: %w", err)
+			manifest.Image.finish(nil, err, time.Now())
+			return &StageError{Stage: StageImage, Err: fmt.Errorf("failed to create image client: %w", err)}
 		}
 
-		// Build prompt for image generation
-		var imagePrompt string
-		if researchResult != nil {
-			// Generate infographics from research results
-			imagePrompt = imageClient.BuildInfographicsPrompt(researchResult.Content)
+		// Build prompt for image generation, one per image language
+		var langs, imagePrompts []string
+		if researchResult == nil && len(opts.InputImages) > 0 {
+			// Editing input images: the prompt is the instruction itself
+			imagePrompts = []string{prompt}
 		} else {
-			// Use prompt template in ImageOnly mode
-			imagePrompt = imageClient.BuildInfographicsPrompt(prompt)
+			// Generate infographics from research results (or the prompt in ImageOnly mode)
+			content := prompt
+			if researchResult != nil {
+				content = researchResult.Content
+			}
+			langs = ParseImageLangs(config.ImageLang)
+			if len(langs) == 0 {
+				langs = []string{config.ImageLang}
+			}
+			for _, lang := range langs {
+				imagePrompt, err := imageClient.BuildLangPrompt(lang, content)
+				if err != nil {
+					return &StageError{Stage: StageImage, Err: fmt.Errorf("failed to build image prompt: %w", err)}
+				}
+				imagePrompts = append(imagePrompts, imagePrompt)
+			}
+		}
+		if opts.ShowPrompt {
+			printSystemInstruction(os.Stderr, config.ImageSystemInstruction, config.APIKey)
+			if config.Style != "" {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", msg("summary.style"), config.Style)
+			}
+			for _, imagePrompt := range imagePrompts {
+				printImagePrompt(os.Stderr, imagePrompt, opts.ShowPromptFull, config.APIKey)
+			}
 		}
 
 		// Image generation configuration
@@ -372,33 +2736,144 @@ func RunWithConfig(opts *Options, config *ViperConfig) error {
 			Model:       opts.Model,
 			AspectRatio: opts.AspectRatio,
 			ImageSize:   opts.ImageSize,
+			Candidates:  opts.Candidates,
+			InputImages: opts.InputImages,
+		}
+
+		if len(langs) > 1 {
+			imageResult, err = imageClient.GenerateLangs(ctx, langs, imagePrompts, imgConfig, name, opts.Count)
+		} else {
+			imageResult, err = imageClient.GenerateVariants(ctx, imagePrompts[0], imgConfig, name, opts.Count)
+		}
+		manifest.Image.finish(imageResult, err, time.Now())
+		saveManifest()
+		if err != nil {
+			return &StageError{Stage: StageImage, Err: fmt.Errorf("failed to generate image: %w", err)}
+		}
+		result.Image = imageResult
+		logger.Info("Image generation completed", "image_path", imageResult.ImagePath, "images", len(imageResult.ImagePaths), "model", imageResult.Model)
+		progress.Emit(ProgressEvent{Event: EventImageCompleted, Timestamp: timestamp, ImagePath: imageResult.ImagePath, ImagePaths: imageResult.ImagePaths})
+	}
+
+	// Export the research; the artifacts are saved, so a failed export only warns
+	if opts.Export == ExportFormatPDF {
+		pdfPath, err := ExportPDF(ctx, config, manifest)
+		if err != nil {
+			logger.Error("Failed to export research", "format", opts.Export, "error", err)
+			warnPipeline(progress, timestamp, fmt.Sprintf("failed to export research: %v", err))
+		} else {
+			logger.Info("Research exported", "path", pdfPath)
+			manifest.Research.PDFPath = pdfPath
+			saveManifest()
 		}
+	}
 
-		imageResult, err = imageClient.Generate(ctx, imagePrompt, imgConfig, timestamp)
+	// Write the report of the run; the artifacts are saved, so a failed report only warns
+	if opts.Report == ReportFormatHTML {
+		reportPath, err := WriteReport(config, manifest, prompt)
 		if err != nil {
-			return fmt.Errorf("failed to generate image: %w", err)
+			logger.Error("Failed to write report", "error", err)
+			warnPipeline(progress, timestamp, fmt.Sprintf("failed to write report: %v", err))
+		} else {
+			logger.Info("Report saved", "path", reportPath)
+			manifest.ReportPath = reportPath
+			saveManifest()
 		}
-		logger.Info("Image generation completed", "image_path", imageResult.ImagePath)
+	}
 
-		// Auto-open image if enabled (flag takes priority, then config)
-		if !opts.NoOpen && config.AutoOpen {
-			if err := OpenFile(imageResult.ImagePath); err != nil {
-				logger.Info("Failed to open image", "error", err)
+	// Auto-open the image, or the report with --open-report, if enabled (flag takes priority, then config)
+	if !opts.NoOpen && config.AutoOpen {
+		var openPaths []string
+		switch {
+		case opts.OpenReport && manifest.ReportPath != "":
+			openPaths = []string{manifest.ReportPath}
+		case imageResult != nil && opts.OpenAll:
+			openPaths = imageResult.ImagePaths
+		case imageResult != nil:
+			openPaths = imageResult.ImagePaths[:1]
+		}
+		for _, path := range openPaths {
+			if err := opts.Clients.open(path); err != nil {
+				logger.Info("Failed to open file", "path", path, "error", err)
 			}
 		}
 	}
 
-	// Output results summary
 	logger.Info("Pipeline completed")
-	fmt.Println("\n=== Pipeline Completed ===")
-	fmt.Printf("Timestamp: %s\n", timestamp)
+	completed := ProgressEvent{Event: EventPipelineCompleted, Timestamp: timestamp, ManifestPath: manifestPath}
 	if researchResult != nil {
-		fmt.Printf("Research: %s\n", researchResult.MarkdownPath)
+		completed.MarkdownPath = researchResult.MarkdownPath
 	}
 	if imageResult != nil {
-		fmt.Printf("Image: %s\n", imageResult.ImagePath)
+		completed.ImagePath, completed.ImagePaths = imageResult.ImagePath, imageResult.ImagePaths
+	}
+	progress.Emit(completed)
+
+	// Record the run in the history ledger
+	entry := &HistoryEntry{
+		Timestamp:  timestamp,
+		FinishedAt: time.Now(),
+		Prompt:     promptExcerpt(prompt),
+		Tags:       opts.Tags,
+	}
+	if researchResult != nil {
+		entry.ResearchPath = researchResult.MarkdownPath
+		entry.ResearchResponsePath = researchResult.ResponsePath
+		entry.ThinkingSummaries = config.ThinkingSummaries
+	}
+	if imageResult != nil {
+		entry.ImagePath = imageResult.ImagePath
+		entry.ImagePaths = imageResult.ImagePaths
+		entry.Style = config.Style
+	}
+	if err := NewRunHistory(config.HistoryPath()).Append(entry); err != nil {
+		logger.Error("Failed to record run history", "error", err)
 	}
-	fmt.Printf("Output directory: %s\n", config.OutputDir)
 
 	return nil
 }
+
+// verbosity returns the console log verbosity of the -v count and --trace.
+func verbosity(verbose int, trace bool) int {
+	if trace {
+		return max(verbose, VerbosityTrace)
+	}
+	return verbose
+}
+
+// logLevel returns the console log level of the run.
+func (o *Options) logLevel() slog.Level {
+	if o.Quiet {
+		return quietLevel
+	}
+	return VerbosityLevel(o.Verbose)
+}
+
+// warnPipeline reports a warning of a pipeline run on stderr, or as a progress event.
+func warnPipeline(progress *ProgressEmitter, timestamp, message string) {
+	if progress != nil {
+		progress.Emit(ProgressEvent{Event: EventWarning, Timestamp: timestamp, Message: message})
+		return
+	}
+	printWarning(os.Stderr, "%s", message)
+}
+
+// printUnfinishedRuns prints researches left unfinished by previous runs with resume commands.
+//
+// The record of the interaction being resumed (exclude) is not listed.
+func printUnfinishedRuns(w io.Writer, records []*RunRecord, exclude string) {
+	var pending []*RunRecord
+	for _, record := range records {
+		if record.InteractionID != exclude {
+			pending = append(pending, record)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, msg("unfinished.header"))
+	for _, record := range pending {
+		fmt.Fprintln(w, msg("unfinished.run", record.InteractionID, record.Timestamp, record.InteractionID))
+	}
+}
@@ -2,10 +2,18 @@ package app
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/yukiyan/deepviz/internal/version"
 )
 
 func TestRootCommand_Execute(t *testing.T) {
@@ -44,11 +52,43 @@ func TestRootCommand_Execute(t *testing.T) {
 	}
 }
 
+func TestVersionCommand(t *testing.T) {
+	want := version.Get()
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--version"}, want: want.String()},
+		{args: []string{"version"}, want: want.String()},
+	}
+	for _, tt := range tests {
+		cmd := NewRootCommand()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(tt.args)
+		if err := cmd.Execute(); err != nil || out.String() != tt.want {
+			t.Errorf("%v = %q, %v, want %q", tt.args, out.String(), err, tt.want)
+		}
+	}
+
+	cmd := NewRootCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"version", "--json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	var got version.Info
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil || got != want {
+		t.Errorf("version --json = %+v, %v, want %+v", got, err, want)
+	}
+}
+
 func TestGenerateCommand_Flags(t *testing.T) {
 	cmd := NewRootCommand()
 
 	// Verify flags are defined
-	flags := []string{"prompt", "file", "output", "verbose", "no-image"}
+	flags := []string{"prompt", "file", "candidates", "fallback-model", "generation-config", "input-image", "image-lang", "agent", "tools", "no-tools", "thinking-summaries", "quiet-poll", "style", "system-instruction", "safety-threshold", "seed", "temperature", "top-p", "top-k", "image-format", "image-quality", "keep-original", "output", "verbose", "no-image", "keep-on-failure", "show-prompt", "show-prompt-full", "dry-run", "var", "template-vars"}
 	for _, flagName := range flags {
 		flag := cmd.Flags().Lookup(flagName)
 		if flag == nil {
@@ -84,6 +124,41 @@ func TestGenerateCommand_PromptRequired(t *testing.T) {
 	}
 }
 
+func TestRootCommand_WithPipelineRunner(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", filepath.Join(dir, "output"))
+	t.Setenv("DEEPVIZ_STATE_DIR", filepath.Join(dir, "state"))
+
+	var prompts []string
+	runner := func(ctx context.Context, opts *Options, config *ViperConfig) (*RunResult, error) {
+		prompts = append(prompts, opts.Prompt)
+		return &RunResult{OutputDir: config.OutputDir}, nil
+	}
+	cmd := NewRootCommand(WithPipelineRunner(runner))
+	cmd.SetArgs([]string{"--prompt", "AI trends", "--no-open", "--quiet"})
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !slices.Equal(prompts, []string{"AI trends"}) {
+		t.Errorf("runner prompts = %q, want the prompt of the command", prompts)
+	}
+}
+
+func TestRootCommand_NoStore(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--prompt", "AI trends", "--no-store"})
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	var usageErr *UsageError
+	if err := cmd.Execute(); !errors.As(err, &usageErr) || !strings.Contains(err.Error(), "--no-store is not supported") {
+		t.Errorf("Execute() error = %v, want a usage error explaining --no-store", err)
+	}
+}
+
 func TestConfigCommand_Show(t *testing.T) {
 	// Temporary directory for testing
 	tmpDir := t.TempDir()
@@ -112,6 +187,74 @@ func TestConfigCommand_Show(t *testing.T) {
 	}
 }
 
+func TestConfigCommand_ShowAPIKeySource(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "gemini")
+	if err := os.WriteFile(keyFile, []byte("AIzaSyTestKey1234\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DEEPVIZ_API_KEY", "")
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("DEEPVIZ_API_KEY_FILE", keyFile)
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"config", "show"})
+	buf := new(bytes.Buffer)
+	cmd.SetOut(buf)
+	cmd.SetErr(buf)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	output := buf.String()
+	if want := "api_key: " + maskAPIKey("AIzaSyTestKey1234") + " (from file " + keyFile + ")"; !strings.Contains(output, want) {
+		t.Errorf("output = %s, want %q", output, want)
+	}
+	if strings.Contains(output, "AIzaSyTestKey1234") {
+		t.Errorf("output = %s, want the API key masked", output)
+	}
+}
+
+func TestConfigCommand_ShowFailingAPIKeyCmd(t *testing.T) {
+	t.Setenv("DEEPVIZ_API_KEY", "")
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("DEEPVIZ_API_KEY_CMD", "exit 1")
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"config", "show"})
+	buf := new(bytes.Buffer)
+	cmd.SetOut(buf)
+	cmd.SetErr(buf)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if output := buf.String(); !strings.Contains(output, "api_key: (invalid api_key_cmd: ") || !strings.Contains(output, "api_key_cmd: exit 1") {
+		t.Errorf("output = %s, want the error of the API key and the other settings", output)
+	}
+}
+
+func TestRootCommand_InsecureSkipVerifyWarning(t *testing.T) {
+	t.Setenv("DEEPVIZ_CLI_LANG", "en")
+	t.Cleanup(func() { setCLILang("") })
+
+	for _, insecure := range []string{"false", "true"} {
+		t.Run(insecure, func(t *testing.T) {
+			t.Setenv("DEEPVIZ_INSECURE_SKIP_VERIFY", insecure)
+			cmd := NewRootCommand()
+			cmd.SetArgs([]string{"config", "show"})
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			cmd.SetOut(stdout)
+			cmd.SetErr(stderr)
+
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := strings.Contains(stderr.String(), "insecure_skip_verify is enabled"); got != (insecure == "true") {
+				t.Errorf("stderr = %q, want the warning only when enabled", stderr.String())
+			}
+		})
+	}
+}
+
 func TestConfigCommand_Init(t *testing.T) {
 	// Temporary directory for testing
 	tmpDir := t.TempDir()
@@ -135,3 +278,700 @@ func TestConfigCommand_Init(t *testing.T) {
 		t.Error("config file should be created")
 	}
 }
+
+func TestConfigCommand_InitDefaults(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the home directory comes from USERPROFILE")
+	}
+	// Without a home directory, the directories fall back to the temporary ones
+	for _, name := range []string{"HOME", "XDG_DATA_HOME", "XDG_STATE_HOME", "DEEPVIZ_OUTPUT_DIR", "DEEPVIZ_STATE_DIR"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("TMPDIR", t.TempDir())
+	tmpDir := t.TempDir()
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"config", "init", "--config-dir", tmpDir})
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	written, err := NewViperConfig(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults, err := NewDefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written.StateDir != defaults.StateDir || written.OutputDir != defaults.OutputDir {
+		t.Errorf("config init directories = %s, %s, want the defaults %s, %s", written.StateDir, written.OutputDir, defaults.StateDir, defaults.OutputDir)
+	}
+}
+
+func TestLastCommand(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_STATE_DIR", stateDir)
+
+	// No runs recorded yet
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"last"})
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	if err := cmd.Execute(); !errors.Is(err, ErrNoHistory) {
+		t.Fatalf("Execute() error = %v, want ErrNoHistory", err)
+	}
+
+	history := NewRunHistory(filepath.Join(stateDir, "history.jsonl"))
+	if err := history.Append(&HistoryEntry{
+		Timestamp:    "20251224_103045",
+		Prompt:       "AI trends",
+		ResearchPath: "/out/research/20251224_103045.md",
+		ImagePath:    "/out/images/20251224_103045.png",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("text", func(t *testing.T) {
+		cmd := NewRootCommand()
+		cmd.SetArgs([]string{"last"})
+		buf := new(bytes.Buffer)
+		cmd.SetOut(buf)
+		cmd.SetErr(buf)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if !strings.Contains(buf.String(), "Image: /out/images/20251224_103045.png") {
+			t.Errorf("output should contain the image path: %q", buf.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		cmd := NewRootCommand()
+		cmd.SetArgs([]string{"last", "--json"})
+		buf := new(bytes.Buffer)
+		cmd.SetOut(buf)
+		cmd.SetErr(new(bytes.Buffer))
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("output is not valid JSON: %v", err)
+		}
+		if entry.ResearchPath != "/out/research/20251224_103045.md" {
+			t.Errorf("ResearchPath = %s, want /out/research/20251224_103045.md", entry.ResearchPath)
+		}
+	})
+}
+
+func TestRootCommand_DryRun(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", t.TempDir())
+	t.Setenv("DEEPVIZ_STATE_DIR", t.TempDir())
+	// An invalid API key must not matter since no request is made
+	t.Setenv("GEMINI_API_KEY", "invalid")
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--prompt", "test", "--image-only", "--show-prompt", "--dry-run", "--no-open"})
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+
+	if err := cmd.Execute(); err != nil {
+		t.Errorf("Execute() error = %v", err)
+	}
+}
+
+// TestRootCommand_LogsToStderr tests that logs go to stderr and stdout keeps the command output only.
+func TestRootCommand_LogsToStderr(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", t.TempDir())
+	t.Setenv("DEEPVIZ_STATE_DIR", t.TempDir())
+	promptDir := t.TempDir()
+	for _, name := range []string{"a.md", "b.md"} {
+		if err := os.WriteFile(filepath.Join(promptDir, name), []byte("AI trends"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--file", filepath.Join(promptDir, "*.md"), "--image-only", "--dry-run", "--no-open"})
+	var stdout bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(new(bytes.Buffer))
+
+	stderr := captureStderr(t, func() {
+		if err := cmd.Execute(); err != nil {
+			t.Errorf("Execute() error = %v", err)
+		}
+	})
+
+	if !strings.Contains(stdout.String(), "=== [2/2]") || !strings.Contains(stdout.String(), "=== Batch Completed ===") {
+		t.Errorf("stdout = %q, want the batch output", stdout.String())
+	}
+	if strings.Contains(stdout.String(), `"level"`) {
+		t.Errorf("stdout = %q, want no log lines", stdout.String())
+	}
+	if !strings.Contains(stderr, `"msg":"Dry run"`) {
+		t.Errorf("stderr = %q, want the log lines", stderr)
+	}
+}
+
+// captureStderr returns what fn writes to os.Stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
+
+// TestRootCommand_ImageLangPrecedence tests that --image-lang overrides the configured language.
+func TestRootCommand_ImageLangPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "config", args: nil, want: []string{"image in Japanese"}},
+		{name: "flag", args: []string{"--image-lang", "English"}, want: []string{"image in English"}},
+		{name: "comma-separated", args: []string{"--image-lang", "English,French"}, want: []string{"image in English", "image in French"}},
+		{name: "repeatable", args: []string{"--image-lang", "English", "--image-lang", "German"}, want: []string{"image in English", "image in German"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			t.Setenv("DEEPVIZ_OUTPUT_DIR", t.TempDir())
+			t.Setenv("DEEPVIZ_STATE_DIR", t.TempDir())
+			t.Setenv("DEEPVIZ_IMAGE_LANG", "Japanese")
+
+			cmd := NewRootCommand()
+			cmd.SetArgs(append([]string{"--prompt", "test", "--image-only", "--show-prompt", "--dry-run"}, tt.args...))
+			cmd.SetOut(new(bytes.Buffer))
+			cmd.SetErr(new(bytes.Buffer))
+
+			var err error
+			output := captureStderr(t, func() { err = cmd.Execute() })
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("output should contain %q: %s", want, output)
+				}
+			}
+			if got := strings.Count(output, "=== Image Prompt ==="); got != len(tt.want) {
+				t.Errorf("printed %d prompts, want %d", got, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestRootCommand_NoToolsConflictsWithTools(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", t.TempDir())
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--prompt", "test", "--dry-run", "--no-tools", "--tools", "google_search"})
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+
+	var usageErr *UsageError
+	if err := cmd.Execute(); !errors.As(err, &usageErr) {
+		t.Errorf("Execute() error = %v, want UsageError", err)
+	}
+}
+
+func TestRootCommand_VerboseCount(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{args: nil, want: 0},
+		{args: []string{"-v"}, want: VerbosityDebug},
+		{args: []string{"-vv"}, want: VerbosityTrace},
+		{args: []string{"--verbose", "-v"}, want: VerbosityTrace},
+	}
+
+	for _, tt := range tests {
+		cmd := NewRootCommand()
+		if err := cmd.ParseFlags(tt.args); err != nil {
+			t.Fatalf("ParseFlags(%v) error = %v", tt.args, err)
+		}
+		if got, _ := cmd.Flags().GetCount("verbose"); got != tt.want {
+			t.Errorf("verbose count of %v = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+
+	if got := verbosity(VerbosityDebug, true); got != VerbosityTrace {
+		t.Errorf("verbosity() with --trace = %d, want %d", got, VerbosityTrace)
+	}
+}
+
+func TestRootCommand_QuietConflictsWithVerbose(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", t.TempDir())
+
+	for _, flag := range []string{"-v", "-vv", "--trace"} {
+		cmd := NewRootCommand()
+		cmd.SetArgs([]string{"--prompt", "test", "--dry-run", "-q", flag})
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetErr(new(bytes.Buffer))
+
+		var usageErr *UsageError
+		if err := cmd.Execute(); !errors.As(err, &usageErr) {
+			t.Errorf("Execute() with %s error = %v, want UsageError", flag, err)
+		}
+	}
+}
+
+func TestCompletion_NoAPIKeyFallback(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_STATE_DIR", t.TempDir())
+	t.Setenv("DEEPVIZ_API_KEY", "")
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("DEEPVIZ_DEEP_RESEARCH_AGENT", "deep-research-custom")
+
+	models, _ := completeModels(nil, nil, "")
+	if !slices.Equal(models, fallbackModelCompletions) {
+		t.Errorf("completeModels() = %v, want the fallback models", models)
+	}
+
+	agents, _ := completeAgents(nil, nil, "")
+	if len(agents) != 1 || !strings.HasPrefix(agents[0], "deep-research-custom\t") {
+		t.Errorf("completeAgents() = %v, want only the configured agent", agents)
+	}
+}
+
+func TestHistoryCommand(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", outputDir)
+	// Listing runs must not need an API key
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("DEEPVIZ_API_KEY", "")
+
+	t.Run("table", func(t *testing.T) {
+		cmd := NewRootCommand()
+		cmd.SetArgs([]string{"history", "--limit", "2"})
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		cmd.SetOut(stdout)
+		cmd.SetErr(stderr)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[1], "20251226_070000") {
+			t.Errorf("output = %q, want the two newest runs", stdout.String())
+		}
+		if !strings.Contains(stderr.String(), "Warning: skipping manifest") {
+			t.Errorf("stderr = %q, want a warning for the corrupt manifest", stderr.String())
+		}
+	})
+
+	t.Run("json since", func(t *testing.T) {
+		cmd := NewRootCommand()
+		cmd.SetArgs([]string{"history", "--json", "--since", "2025-12-01"})
+		buf := new(bytes.Buffer)
+		cmd.SetOut(buf)
+		cmd.SetErr(new(bytes.Buffer))
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		var runs []RunManifest
+		if err := json.Unmarshal(buf.Bytes(), &runs); err != nil {
+			t.Fatalf("output is not valid JSON: %v", err)
+		}
+		if len(runs) != 3 {
+			t.Errorf("got %d runs, want the 3 runs since 2025-12-01", len(runs))
+		}
+	})
+
+	t.Run("invalid since", func(t *testing.T) {
+		cmd := NewRootCommand()
+		cmd.SetArgs([]string{"history", "--since", "yesterday"})
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetErr(new(bytes.Buffer))
+		var usageErr *UsageError
+		if err := cmd.Execute(); !errors.As(err, &usageErr) {
+			t.Errorf("Execute() error = %v, want UsageError", err)
+		}
+	})
+}
+
+func TestShowCommand(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	config := &ViperConfig{OutputDir: outputDir}
+	research := "---\ninteraction_id: v1_abc\nagent: agent\ncreated_at: 2025-12-24T10:30:45Z\ndeepviz_version: 0.1.0\n---\n\n# AI Trends\n\n**Agents** are everywhere.\n"
+	if err := WriteFile(config.ResearchPath("20251224_103045"), []byte(research)); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", outputDir)
+
+	run := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+		cmd := NewRootCommand()
+		cmd.SetArgs(append([]string{"show"}, args...))
+		buf := new(bytes.Buffer)
+		cmd.SetOut(buf)
+		cmd.SetErr(new(bytes.Buffer))
+		err := cmd.Execute()
+		return buf.String(), err
+	}
+
+	t.Run("rendered", func(t *testing.T) {
+		output, err := run(t, "20251224_10")
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		for _, want := range []string{"Timestamp: 20251224_103045", "Prompt: AI trends", "AI Trends\n=========", "Agents are everywhere."} {
+			if !strings.Contains(output, want) {
+				t.Errorf("output should contain %q: %q", want, output)
+			}
+		}
+		if strings.Contains(output, "interaction_id") {
+			t.Errorf("output should not contain the front matter: %q", output)
+		}
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		output, err := run(t, "--raw", "20251224_103045")
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if output != research {
+			t.Errorf("output = %q, want the saved markdown", output)
+		}
+	})
+
+	t.Run("latest", func(t *testing.T) {
+		output, err := run(t, "latest")
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if !strings.Contains(output, "Timestamp: 20251226_070000") || !strings.Contains(output, "Failed stage: image") {
+			t.Errorf("output = %q, want the failed latest run", output)
+		}
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		var usageErr *UsageError
+		if _, err := run(t, "202512"); !errors.As(err, &usageErr) || !strings.Contains(err.Error(), "ambiguous") {
+			t.Errorf("Execute() error = %v, want an ambiguous UsageError", err)
+		}
+	})
+}
+
+func TestLogsCommand(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", outputDir)
+	t.Setenv("DEEPVIZ_STATE_DIR", t.TempDir())
+	if err := WriteFile((&ViperConfig{OutputDir: outputDir}).LogPath("20251120_080000"), []byte(testLogLines)); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+		cmd := NewRootCommand()
+		cmd.SetArgs(append([]string{"logs"}, args...))
+		buf := new(bytes.Buffer)
+		cmd.SetOut(buf)
+		cmd.SetErr(new(bytes.Buffer))
+		err := cmd.Execute()
+		return buf.String(), err
+	}
+
+	output, err := run(t, "--timestamp", "20251120", "--level", "warn")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(output, "Image generation failed") || strings.Contains(output, "Research started") {
+		t.Errorf("output = %q, want the error record only", output)
+	}
+
+	var usageErr *UsageError
+	if _, err := run(t, "--level", "loud"); !errors.As(err, &usageErr) {
+		t.Errorf("Execute() with an invalid level error = %v, want UsageError", err)
+	}
+	if _, err := run(t, "--timestamp", "2024"); !errors.As(err, &usageErr) {
+		t.Errorf("Execute() with an unknown run error = %v, want UsageError", err)
+	}
+}
+
+func TestCleanCommand_LogRetention(t *testing.T) {
+	outputDir := t.TempDir()
+	writeLogFixtures(t, outputDir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", outputDir)
+	t.Setenv("DEEPVIZ_STATE_DIR", t.TempDir())
+
+	run := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+		cmd := NewRootCommand()
+		cmd.SetArgs(append([]string{"clean", "--what", "logs"}, args...))
+		buf := new(bytes.Buffer)
+		cmd.SetOut(buf)
+		cmd.SetErr(new(bytes.Buffer))
+		err := cmd.Execute()
+		return buf.String(), err
+	}
+
+	t.Run("policy required", func(t *testing.T) {
+		var usageErr *UsageError
+		if _, err := run(t, "--yes"); !errors.As(err, &usageErr) {
+			t.Errorf("Execute() error = %v, want UsageError", err)
+		}
+	})
+
+	t.Run("configured policy", func(t *testing.T) {
+		t.Setenv("DEEPVIZ_LOG_MAX_FILES", "4")
+		output, err := run(t, "--yes")
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if !strings.Contains(output, "20250901_120000.log") || !strings.Contains(output, "Removed 1 files") {
+			t.Errorf("output = %q, want the oldest log removed", output)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "logs", "notes.txt")); err != nil {
+			t.Errorf("file other than a log removed: %v", err)
+		}
+	})
+}
+
+func TestCleanCommand(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	stateDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", outputDir)
+	t.Setenv("DEEPVIZ_STATE_DIR", stateDir)
+	if err := NewRunHistory(filepath.Join(stateDir, "history.jsonl")).Append(&HistoryEntry{Timestamp: "20251001_120000"}); err != nil {
+		t.Fatal(err)
+	}
+	logPath := (&ViperConfig{OutputDir: outputDir}).LogPath("20251120_080000")
+
+	run := func(t *testing.T, input string, args ...string) (string, error) {
+		t.Helper()
+		cmd := NewRootCommand()
+		cmd.SetArgs(append([]string{"clean"}, args...))
+		buf := new(bytes.Buffer)
+		cmd.SetOut(buf)
+		cmd.SetErr(new(bytes.Buffer))
+		cmd.SetIn(strings.NewReader(input))
+		err := cmd.Execute()
+		return buf.String(), err
+	}
+
+	t.Run("criteria required", func(t *testing.T) {
+		var usageErr *UsageError
+		if _, err := run(t, ""); !errors.As(err, &usageErr) {
+			t.Errorf("Execute() error = %v, want UsageError", err)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		output, err := run(t, "", "--older-than", "1d", "--what", "logs", "--keep-latest", "--dry-run")
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if !strings.Contains(output, logPath) || !strings.Contains(output, "files from") {
+			t.Errorf("output = %q, want the listed log and total", output)
+		}
+		if strings.Contains(output, "20251001_120000.log") {
+			t.Errorf("output = %q, the latest run should be kept", output)
+		}
+		if _, err := os.Stat(logPath); err != nil {
+			t.Errorf("dry run should not remove files: %v", err)
+		}
+	})
+
+	t.Run("declined", func(t *testing.T) {
+		output, err := run(t, "n\n", "--older-than", "1d", "--what", "logs")
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if !strings.Contains(output, "Aborted") {
+			t.Errorf("output = %q, want Aborted", output)
+		}
+		if _, err := os.Stat(logPath); err != nil {
+			t.Errorf("declined clean should not remove files: %v", err)
+		}
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		output, err := run(t, "y\n", "--older-than", "1d", "--what", "logs")
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if !strings.Contains(output, "Removed") {
+			t.Errorf("output = %q, want the removal summary", output)
+		}
+		if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+			t.Error("log should be removed")
+		}
+	})
+
+	t.Run("yes", func(t *testing.T) {
+		if _, err := run(t, "", "--keep-last", "1", "--yes"); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if output, _ := run(t, "", "--keep-last", "1", "--dry-run"); !strings.Contains(output, "Nothing to clean") {
+			t.Errorf("output = %q, want nothing left to clean", output)
+		}
+	})
+}
+
+func TestOpenCommand(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", outputDir)
+	t.Setenv("DEEPVIZ_STATE_DIR", t.TempDir())
+
+	run := func(t *testing.T, args ...string) ([]string, error) {
+		t.Helper()
+		var opened []string
+		cmd := newOpenCommandWith(func(path string) error {
+			opened = append(opened, path)
+			return nil
+		})
+		cmd.SetArgs(args)
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetErr(new(bytes.Buffer))
+		err := cmd.Execute()
+		return opened, err
+	}
+
+	opened, err := run(t, "20251120", "--research")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := (&ViperConfig{OutputDir: outputDir}).ResearchPath("20251120_080000"); len(opened) != 1 || opened[0] != want {
+		t.Errorf("opened = %v, want %s", opened, want)
+	}
+
+	for _, args := range [][]string{{}, {"20251120", "--last"}} {
+		var usageErr *UsageError
+		if _, err := run(t, args...); !errors.As(err, &usageErr) {
+			t.Errorf("Execute(%v) error = %v, want UsageError", args, err)
+		}
+	}
+}
+
+func TestReportCommand(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", outputDir)
+	t.Setenv("DEEPVIZ_STATE_DIR", t.TempDir())
+
+	run := func(t *testing.T, args ...string) (string, []string, error) {
+		t.Helper()
+		var opened []string
+		cmd := newReportCommandWith(func(path string) error {
+			opened = append(opened, path)
+			return nil
+		})
+		var stdout bytes.Buffer
+		cmd.SetArgs(args)
+		cmd.SetOut(&stdout)
+		cmd.SetErr(new(bytes.Buffer))
+		err := cmd.Execute()
+		return stdout.String(), opened, err
+	}
+
+	config := &ViperConfig{OutputDir: outputDir}
+	output, opened, err := run(t, "20251224", "--open")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := config.ReportPath("20251224_103045")
+	if !strings.Contains(output, want) || len(opened) != 1 || opened[0] != want {
+		t.Errorf("output = %q, opened = %v, want %s", output, opened, want)
+	}
+	manifest, err := LoadManifest(config.ManifestPath("20251224_103045"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.ReportPath != want {
+		t.Errorf("manifest ReportPath = %q, want %q", manifest.ReportPath, want)
+	}
+
+	// A run reconstructed from its files is reported without a manifest
+	if _, _, err := run(t, "20251120"); err != nil {
+		t.Errorf("Execute() error = %v", err)
+	}
+	if _, _, err := run(t, "20251001"); err == nil {
+		t.Error("Execute() error = nil, want an error for a run with only a log")
+	}
+	var usageErr *UsageError
+	if _, _, err := run(t, "20240101"); !errors.As(err, &usageErr) {
+		t.Errorf("Execute() error = %v, want UsageError", err)
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", outputDir)
+	t.Setenv("DEEPVIZ_STATE_DIR", t.TempDir())
+	t.Setenv("DEEPVIZ_PDF_CONVERTER", writeFakeConverter(t))
+
+	run := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+		cmd := newExportCommand()
+		var stdout bytes.Buffer
+		cmd.SetArgs(args)
+		cmd.SetOut(&stdout)
+		cmd.SetErr(new(bytes.Buffer))
+		err := cmd.Execute()
+		return stdout.String(), err
+	}
+
+	config := &ViperConfig{OutputDir: outputDir}
+	output, err := run(t, "20251224")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := filepath.Join(config.ResearchDir(), "20251224_103045.pdf")
+	if !strings.Contains(output, want) {
+		t.Errorf("output = %q, want %s", output, want)
+	}
+	manifest, err := LoadManifest(config.ManifestPath("20251224_103045"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Research.PDFPath != want {
+		t.Errorf("manifest PDFPath = %q, want %q", manifest.Research.PDFPath, want)
+	}
+
+	var usageErr *UsageError
+	if _, err := run(t, "20251224", "--format", "docx"); !errors.As(err, &usageErr) {
+		t.Errorf("Execute() error = %v, want UsageError", err)
+	}
+	if _, err := run(t, "20251001"); err == nil {
+		t.Error("Execute() error = nil, want an error for a run without research")
+	}
+}
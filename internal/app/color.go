@@ -0,0 +1,77 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// noColor disables colors regardless of the terminal (--no-color). Set by the root command.
+var noColor bool
+
+// forceColor enables colors for any writer unless they are disabled, so that tests can check
+// colored output written to buffers.
+var forceColor bool
+
+// ANSI styles of the console output.
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiDim    = "\033[2m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+)
+
+// colorEnabled reports whether ANSI colors are written to w: only to a terminal, unless
+// --no-color or the NO_COLOR environment variable (https://no-color.org) is set.
+func colorEnabled(w io.Writer) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return forceColor || isTerminalWriter(w)
+}
+
+// isTerminalWriter reports whether w is a terminal.
+func isTerminalWriter(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	return ok && isTerminal(file)
+}
+
+// Styler colors human-oriented console output. The zero value writes plain text.
+type Styler struct {
+	Enabled bool
+}
+
+// NewStyler returns the Styler of the output written to w.
+func NewStyler(w io.Writer) Styler {
+	return Styler{Enabled: colorEnabled(w)}
+}
+
+// paint wraps text in an ANSI style when colors are enabled.
+func (s Styler) paint(style, text string) string {
+	if !s.Enabled || text == "" {
+		return text
+	}
+	return style + text + ansiReset
+}
+
+// Success styles a successful outcome (green).
+func (s Styler) Success(text string) string { return s.paint(ansiGreen, text) }
+
+// Warning styles a warning (yellow).
+func (s Styler) Warning(text string) string { return s.paint(ansiYellow, text) }
+
+// Error styles an error (red).
+func (s Styler) Error(text string) string { return s.paint(ansiRed, text) }
+
+// Dim styles secondary information such as paths (dimmed).
+func (s Styler) Dim(text string) string { return s.paint(ansiDim, text) }
+
+// Bold styles a heading (bold).
+func (s Styler) Bold(text string) string { return s.paint(ansiBold, text) }
+
+// printWarning writes a warning line ("Warning: ...", translated) to w.
+func printWarning(w io.Writer, format string, args ...any) {
+	fmt.Fprintf(w, "%s %s\n", NewStyler(w).Warning(msg("warning.prefix")), fmt.Sprintf(format, args...))
+}
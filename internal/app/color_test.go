@@ -0,0 +1,134 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setColor forces colors on or off for the duration of a test.
+func setColor(t *testing.T, enabled bool) {
+	t.Helper()
+	savedForce, savedNo := forceColor, noColor
+	forceColor, noColor = enabled, !enabled
+	t.Cleanup(func() { forceColor, noColor = savedForce, savedNo })
+}
+
+func TestColorEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	tests := []struct {
+		name    string
+		force   bool
+		noColor bool
+		env     string
+		want    bool
+	}{
+		{name: "not a terminal"},
+		{name: "forced", force: true, want: true},
+		{name: "--no-color", force: true, noColor: true},
+		{name: "NO_COLOR", force: true, env: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			savedForce, savedNo := forceColor, noColor
+			forceColor, noColor = tt.force, tt.noColor
+			defer func() { forceColor, noColor = savedForce, savedNo }()
+			t.Setenv("NO_COLOR", tt.env)
+
+			if got := colorEnabled(&buf); got != tt.want {
+				t.Errorf("colorEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStyler(t *testing.T) {
+	style := Styler{Enabled: true}
+	if got, want := style.Error("failed"), "\033[31mfailed\033[0m"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got := style.Success(""); got != "" {
+		t.Errorf("Success(\"\") = %q, want empty", got)
+	}
+	if got := (Styler{}).Warning("plain"); got != "plain" {
+		t.Errorf("Warning() = %q, want plain text when disabled", got)
+	}
+}
+
+func TestPrintWarning(t *testing.T) {
+	var buf bytes.Buffer
+	setColor(t, false)
+	printWarning(&buf, "skipping %s", "a.json")
+	if got, want := buf.String(), "Warning: skipping a.json\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	setColor(t, true)
+	printWarning(&buf, "skipping %s", "a.json")
+	if got, want := buf.String(), "\033[33mWarning:\033[0m skipping a.json\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestRunResult_SummaryColors(t *testing.T) {
+	result := &RunResult{
+		Timestamp:    "20251224_103045",
+		Name:         "20251224_103045",
+		OutputDir:    "output",
+		ManifestPath: "output/manifests/20251224_103045.json",
+		Manifest:     &RunManifest{},
+	}
+
+	// Summary is always plain, e.g. for library callers
+	if strings.Contains(result.Summary(), "\033[") {
+		t.Errorf("Summary() = %q, want no ANSI escapes", result.Summary())
+	}
+
+	var buf bytes.Buffer
+	setColor(t, true)
+	printRunResult(&buf, result, false, false)
+	out := buf.String()
+	for _, want := range []string{ansiGreen + "=== Pipeline Completed ===", "Manifest: " + ansiDim + "output/manifests/20251224_103045.json" + ansiReset} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output = %q, want %q", out, want)
+		}
+	}
+}
+
+func TestTextLogHandler_Colors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(newTextLogHandler(&buf, LevelTrace, Styler{Enabled: true}))
+	at := time.Date(2025, 12, 24, 10, 30, 45, 0, time.Local)
+	for _, level := range []slog.Level{LevelTrace, slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+		record := slog.NewRecord(at, level, "message", 0)
+		if err := logger.Handler().Handle(context.Background(), record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := "10:30:45 " + ansiDim + "TRACE" + ansiReset + " message\n" +
+		"10:30:45 INFO message\n" +
+		"10:30:45 " + ansiYellow + "WARN" + ansiReset + " message\n" +
+		"10:30:45 " + ansiRed + "ERROR" + ansiReset + " message\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestNoColorFlag(t *testing.T) {
+	setColor(t, true)
+	rootCmd := NewRootCommand()
+	rootCmd.SetArgs([]string{"--no-color", "completion", "bash"})
+	rootCmd.SetOut(&bytes.Buffer{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if colorEnabled(&bytes.Buffer{}) {
+		t.Error("colorEnabled() = true, want --no-color to disable colors")
+	}
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// curlMaxInlineBody is the largest request body inlined in a curl command (--curl). Larger bodies
+// (e.g., image requests with input images) are saved to a file referenced by the command.
+const curlMaxInlineBody = 64 << 10
+
+// curlAPIKeyVar is the shell variable replacing the API key in curl commands.
+const curlAPIKeyVar = "$GEMINI_API_KEY"
+
+// curlCommand renders a request as an equivalent curl command: method, URL, headers and body,
+// with the API key (the first secret) replaced by $GEMINI_API_KEY and the other secrets masked.
+// body is the redacted request body, or bodyFile the file it was saved to when it is too large to
+// inline.
+func curlCommand(req *http.Request, body []byte, bodyFile string, secrets ...string) string {
+	// The API key becomes a shell variable and the other secrets are masked (the patterns of
+	// redactSecrets would mask the variable too)
+	redact := func(text string) string {
+		for i, secret := range secrets {
+			switch {
+			case secret == "":
+			case i == 0:
+				text = strings.ReplaceAll(text, secret, curlAPIKeyVar)
+			default:
+				text = strings.ReplaceAll(text, secret, maskAPIKey(secret))
+			}
+		}
+		return text
+	}
+
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "curl -X %s %s", req.Method, shellQuoteKey(redact(req.URL.String())))
+	for _, name := range slices.Sorted(maps.Keys(req.Header)) {
+		for _, value := range req.Header[name] {
+			header := redact(name + ": " + value)
+			if strings.EqualFold(name, "x-goog-api-key") {
+				header = name + ": " + curlAPIKeyVar
+			}
+			fmt.Fprintf(&cmd, " \\\n  -H %s", shellQuoteKey(header))
+		}
+	}
+	switch {
+	case bodyFile != "":
+		fmt.Fprintf(&cmd, " \\\n  --data-binary %s", shellQuote("@"+bodyFile))
+	case len(body) > 0:
+		fmt.Fprintf(&cmd, " \\\n  --data %s", shellQuote(string(body)))
+	}
+	cmd.WriteString("\n")
+	return cmd.String()
+}
+
+// shellQuote single-quotes a word for a POSIX shell.
+func shellQuote(word string) string {
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+// shellQuoteKey quotes a word of the URL or headers for a POSIX shell. Words containing
+// $GEMINI_API_KEY are double-quoted so that the variable is expanded.
+func shellQuoteKey(word string) string {
+	if !strings.Contains(word, curlAPIKeyVar) {
+		return shellQuote(word)
+	}
+	parts := strings.Split(word, curlAPIKeyVar)
+	for i, part := range parts {
+		parts[i] = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(part)
+	}
+	return `"` + strings.Join(parts, curlAPIKeyVar) + `"`
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCurlCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		bodyFile string
+		want     string
+	}{
+		{
+			name:   "request with a body",
+			method: http.MethodPost,
+			url:    "https://generativelanguage.googleapis.com/v1beta/interactions",
+			body:   `{"input":"It's AI"}`,
+			want: `curl -X POST 'https://generativelanguage.googleapis.com/v1beta/interactions' \
+  -H 'Content-Type: application/json' \
+  -H "X-Goog-Api-Key: $GEMINI_API_KEY" \
+  --data '{"input":"It'\''s AI"}'
+`,
+		},
+		{
+			name:   "API key in the URL",
+			method: http.MethodGet,
+			url:    "https://generativelanguage.googleapis.com/v1beta/interactions/v1_abc?key=" + testSecretKey,
+			want: `curl -X GET "https://generativelanguage.googleapis.com/v1beta/interactions/v1_abc?key=$GEMINI_API_KEY" \
+  -H 'Content-Type: application/json' \
+  -H "X-Goog-Api-Key: $GEMINI_API_KEY"
+`,
+		},
+		{
+			name:     "body saved to a file",
+			method:   http.MethodPost,
+			url:      "https://generativelanguage.googleapis.com/v1beta/models/gemini-3-pro-image-preview:generateContent",
+			body:     `{"contents":[]}`,
+			bodyFile: "responses/20251224_103045/curl_01_generate_image.json",
+			want: `curl -X POST 'https://generativelanguage.googleapis.com/v1beta/models/gemini-3-pro-image-preview:generateContent' \
+  -H 'Content-Type: application/json' \
+  -H "X-Goog-Api-Key: $GEMINI_API_KEY" \
+  --data-binary '@responses/20251224_103045/curl_01_generate_image.json'
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("x-goog-api-key", testSecretKey)
+
+			got := curlCommand(req, []byte(tt.body), tt.bodyFile, testSecretKey)
+			if got != tt.want {
+				t.Errorf("curlCommand() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if strings.Contains(got, testSecretKey) {
+				t.Errorf("curlCommand() = %s, want the API key replaced", got)
+			}
+		})
+	}
+}
+
+func TestShellQuoteKey(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{word: "plain", want: `'plain'`},
+		{word: "it's", want: `'it'\''s'`},
+		{word: `key: $GEMINI_API_KEY`, want: `"key: $GEMINI_API_KEY"`},
+		{word: "a\"b`c$d\\ $GEMINI_API_KEY", want: "\"a\\\"b\\`c\\$d\\\\ $GEMINI_API_KEY\""},
+	}
+	for _, tt := range tests {
+		if got := shellQuoteKey(tt.word); got != tt.want {
+			t.Errorf("shellQuoteKey(%q) = %s, want %s", tt.word, got, tt.want)
+		}
+	}
+}
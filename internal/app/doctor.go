@@ -0,0 +1,253 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// Statuses of the checks of `deepviz doctor`.
+const (
+	DoctorOK   = "ok"
+	DoctorWarn = "warn" // Problem that does not prevent runs
+	DoctorFail = "fail"
+	DoctorSkip = "skip" // Not checked because an earlier check failed
+)
+
+// DoctorCheck is the outcome of a check of `deepviz doctor`.
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Hint   string `json:"hint,omitempty"` // How to fix a failed check
+}
+
+// DoctorReport is the outcome of `deepviz doctor`.
+type DoctorReport struct {
+	OK     bool          `json:"ok"` // No check failed
+	Checks []DoctorCheck `json:"checks"`
+}
+
+// doctorEnv is what `deepviz doctor` checks, replaced in tests.
+type doctorEnv struct {
+	loadConfig func() (*ViperConfig, error)
+	listModels func(ctx context.Context, config *ViperConfig) ([]ModelInfo, error)
+	lookPath   func(file string) (string, error)
+	goos       string
+}
+
+// defaultDoctorEnv checks the configuration and the API of the user.
+func defaultDoctorEnv() doctorEnv {
+	return doctorEnv{
+		loadConfig: func() (*ViperConfig, error) { return NewViperConfig("") },
+		listModels: func(ctx context.Context, config *ViperConfig) ([]ModelInfo, error) {
+			client, err := NewModelsClient(config, NewNullLogger())
+			if err != nil {
+				return nil, err
+			}
+			return client.ListModels(ctx)
+		},
+		lookPath: exec.LookPath,
+		goos:     runtime.GOOS,
+	}
+}
+
+// runDoctor runs every check. The checks needing the configuration or the API are skipped when
+// they are not available.
+func runDoctor(ctx context.Context, env doctorEnv) *DoctorReport {
+	config, configCheck := checkDoctorConfig(env.loadConfig)
+	checks := []DoctorCheck{configCheck}
+	if config == nil {
+		for _, name := range []string{"API key", "Models", "Output directory"} {
+			checks = append(checks, DoctorCheck{Name: name, Status: DoctorSkip, Detail: "the configuration could not be loaded"})
+		}
+	} else {
+		models, apiCheck := checkDoctorAPI(ctx, config, env.listModels)
+		checks = append(checks, apiCheck, checkDoctorModels(config, models, apiCheck.Status == DoctorOK), checkDoctorOutputDir(config.OutputDir))
+	}
+	checks = append(checks, checkDoctorOpener(env.goos, env.lookPath))
+
+	report := &DoctorReport{OK: true, Checks: checks}
+	for _, check := range checks {
+		if check.Status == DoctorFail {
+			report.OK = false
+		}
+	}
+	return report
+}
+
+// checkDoctorConfig loads and validates the configuration.
+func checkDoctorConfig(load func() (*ViperConfig, error)) (*ViperConfig, DoctorCheck) {
+	check := DoctorCheck{Name: "Configuration"}
+	config, err := load()
+	if err != nil {
+		check.Status, check.Detail = DoctorFail, err.Error()
+		check.Hint = "fix the setting named in the error in the config file or the DEEPVIZ_* environment variables (`deepviz config show`)"
+		return nil, check
+	}
+	check.Status, check.Detail = DoctorOK, "no config file, defaults and environment variables"
+	if config.v != nil && config.v.ConfigFileUsed() != "" {
+		check.Detail = "loaded from " + config.v.ConfigFileUsed()
+	}
+	return config, check
+}
+
+// checkDoctorAPI checks that the API accepts the credentials by listing the models, which are
+// returned for checkDoctorModels.
+func checkDoctorAPI(ctx context.Context, config *ViperConfig, listModels func(ctx context.Context, config *ViperConfig) ([]ModelInfo, error)) ([]ModelInfo, DoctorCheck) {
+	// Vertex AI has no models endpoint of the Gemini API: the credentials are checked alone
+	if config.adc != nil {
+		check := DoctorCheck{Name: "Credentials"}
+		if _, err := config.adc.Token(); err != nil {
+			check.Status, check.Detail = DoctorFail, err.Error()
+			check.Hint = "run `gcloud auth application-default login` or set GOOGLE_APPLICATION_CREDENTIALS to a service account key"
+			return nil, check
+		}
+		check.Status, check.Detail = DoctorOK, "Application Default Credentials for Vertex AI ("+config.VertexLocation+")"
+		return nil, check
+	}
+
+	check := DoctorCheck{Name: "API key"}
+	if err := config.loadAPIKey(); err != nil {
+		check.Status, check.Detail = DoctorFail, err.Error()
+		check.Hint = "fix api_key_file, api_key_cmd or use_keyring in the config file, or set GEMINI_API_KEY"
+		return nil, check
+	}
+	if config.APIKey == "" {
+		check.Status, check.Detail = DoctorFail, "no API key configured"
+		check.Hint = "set GEMINI_API_KEY, or api_key, api_key_file or api_key_cmd in the config file (https://aistudio.google.com/apikey)"
+		return nil, check
+	}
+	models, err := listModels(ctx, config)
+	if err != nil {
+		check.Status, check.Detail = DoctorFail, err.Error()
+		var apiErr *APIError
+		var timeoutErr *TimeoutError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.IsAuthError():
+			check.Hint = apiErr.Hint()
+		case errors.As(err, &timeoutErr):
+			check.Hint = "check the network, or raise " + timeoutErr.Setting
+		case errors.As(err, &apiErr):
+			check.Hint = "the API answered with an error; retry later"
+		default:
+			check.Hint = "check the network connection, the proxy (proxy_url, HTTPS_PROXY) and the CA certificates (ca_cert_file)"
+		}
+		return nil, check
+	}
+	source := ""
+	if config.apiKeySource != "" {
+		source = ", from " + config.apiKeySource
+	}
+	check.Status, check.Detail = DoctorOK, fmt.Sprintf("%s accepted by the API (%d models%s)", maskAPIKey(config.APIKey), len(models), source)
+	return models, check
+}
+
+// checkDoctorModels checks that the image model, the fallback model and the research agent are
+// listed. The agents may not be listed by the API, so a missing agent is a warning.
+func checkDoctorModels(config *ViperConfig, models []ModelInfo, listed bool) DoctorCheck {
+	check := DoctorCheck{Name: "Models"}
+	if !listed {
+		check.Status, check.Detail = DoctorSkip, "the models could not be listed"
+		if config.adc != nil {
+			check.Detail = "listing models is not supported on Vertex AI"
+		}
+		return check
+	}
+
+	var missing []string
+	for _, name := range []string{config.Model, config.FallbackModel} {
+		if _, ok := findModel(models, name); name != "" && !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		check.Status, check.Detail = DoctorFail, fmt.Sprintf("image model not found: %v", missing)
+		check.Hint = "set model (or fallback_model) to a model of `deepviz models`"
+		return check
+	}
+	if _, ok := findModel(models, config.DeepResearchAgent); !ok {
+		check.Status, check.Detail = DoctorWarn, fmt.Sprintf("image model %s found, research agent %s not listed by the API", config.Model, config.DeepResearchAgent)
+		check.Hint = "check deep_research_agent against `deepviz agents` if the research fails"
+		return check
+	}
+	check.Status, check.Detail = DoctorOK, fmt.Sprintf("image model %s and research agent %s found", config.Model, config.DeepResearchAgent)
+	return check
+}
+
+// checkDoctorOutputDir checks that files can be created in the output directory.
+func checkDoctorOutputDir(dir string) DoctorCheck {
+	check := DoctorCheck{Name: "Output directory"}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		check.Status, check.Detail = DoctorFail, err.Error()
+		check.Hint = "set output_dir (or DEEPVIZ_OUTPUT_DIR) to a writable directory"
+		return check
+	}
+	file, err := os.CreateTemp(dir, ".deepviz-doctor-")
+	if err != nil {
+		check.Status, check.Detail = DoctorFail, err.Error()
+		check.Hint = "set output_dir (or DEEPVIZ_OUTPUT_DIR) to a writable directory, or fix the permissions of " + dir
+		return check
+	}
+	file.Close()
+	os.Remove(file.Name())
+	check.Status, check.Detail = DoctorOK, filepath.Clean(dir)+" is writable"
+	return check
+}
+
+// checkDoctorOpener checks that the command opening the images (auto_open) is installed. Images
+// can be opened by hand without it, so it is a warning.
+func checkDoctorOpener(goos string, lookPath func(file string) (string, error)) DoctorCheck {
+	check := DoctorCheck{Name: "Opener"}
+	var opener, hint string
+	switch goos {
+	case "darwin":
+		opener = "open"
+		hint = "open comes with macOS: add /usr/bin to PATH, or set auto_open: false"
+	case "windows":
+		opener = "cmd"
+		hint = `cmd comes with Windows: add %SystemRoot%\System32 to PATH, or set auto_open: false`
+	case "linux":
+		opener = "xdg-open"
+		hint = "install xdg-utils to open the images automatically, or set auto_open: false"
+	default:
+		check.Status, check.Detail = DoctorWarn, "opening files is not supported on "+goos
+		check.Hint = "set auto_open: false"
+		return check
+	}
+	path, err := lookPath(opener)
+	if err != nil {
+		check.Status, check.Detail = DoctorWarn, opener+" not found"
+		check.Hint = hint
+		return check
+	}
+	check.Status, check.Detail = DoctorOK, path
+	return check
+}
+
+// printDoctorReport writes the checks with a mark each, followed by the hints of the failed ones.
+func printDoctorReport(w io.Writer, report *DoctorReport) {
+	styler := NewStyler(w)
+	for _, check := range report.Checks {
+		var mark string
+		switch check.Status {
+		case DoctorOK:
+			mark = styler.Success("✓")
+		case DoctorWarn:
+			mark = styler.Warning("!")
+		case DoctorFail:
+			mark = styler.Error("✗")
+		default:
+			mark = styler.Dim("-")
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", mark, check.Name, check.Detail)
+		if check.Hint != "" && check.Status != DoctorOK {
+			fmt.Fprintf(w, "    %s\n", styler.Dim("→ "+check.Hint))
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// doctorModels are the models listed by the fake API of the doctor tests.
+var doctorModels = []ModelInfo{
+	{Name: "models/gemini-3-pro-image-preview"},
+	{Name: "models/gemini-2.0-flash-exp"},
+	{Name: "models/deep-research-pro-preview-12-2025"},
+}
+
+// newDoctorTestEnv returns an environment with a valid configuration writing to a temporary
+// directory, an API listing doctorModels and an installed opener.
+func newDoctorTestEnv(t *testing.T) doctorEnv {
+	config := &ViperConfig{
+		APIKey:            "AIzaTestKey123456",
+		Model:             "gemini-3-pro-image-preview",
+		DeepResearchAgent: "deep-research-pro-preview-12-2025",
+		OutputDir:         t.TempDir(),
+	}
+	return doctorEnv{
+		loadConfig: func() (*ViperConfig, error) { return config, nil },
+		listModels: func(ctx context.Context, config *ViperConfig) ([]ModelInfo, error) { return doctorModels, nil },
+		lookPath:   func(file string) (string, error) { return "/usr/bin/" + file, nil },
+		goos:       "linux",
+	}
+}
+
+func TestCheckDoctorConfig(t *testing.T) {
+	config, check := checkDoctorConfig(func() (*ViperConfig, error) { return nil, errors.New("invalid image_size: unknown size") })
+	if config != nil || check.Status != DoctorFail || !strings.Contains(check.Detail, "image_size") || check.Hint == "" {
+		t.Errorf("checkDoctorConfig() = %v, %+v, want a failure with a hint", config, check)
+	}
+
+	config, check = checkDoctorConfig(func() (*ViperConfig, error) { return &ViperConfig{}, nil })
+	if config == nil || check.Status != DoctorOK {
+		t.Errorf("checkDoctorConfig() = %v, %+v, want the configuration", config, check)
+	}
+}
+
+func TestCheckDoctorAPI(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *ViperConfig
+		err        error
+		wantStatus string
+		wantHint   string
+	}{
+		{name: "valid key", config: &ViperConfig{APIKey: "AIzaTestKey123456"}, wantStatus: DoctorOK},
+		{name: "no key", config: &ViperConfig{}, wantStatus: DoctorFail, wantHint: "GEMINI_API_KEY"},
+		{name: "invalid key", config: &ViperConfig{APIKey: "AIzaTestKey123456"}, err: &APIError{StatusCode: 400, Message: "API key not valid"}, wantStatus: DoctorFail, wantHint: "key"},
+		{name: "timeout", config: &ViperConfig{APIKey: "AIzaTestKey123456"}, err: &TimeoutError{Setting: "request_timeout", Limit: time.Minute}, wantStatus: DoctorFail, wantHint: "request_timeout"},
+		{name: "network", config: &ViperConfig{APIKey: "AIzaTestKey123456"}, err: errors.New("dial tcp: no such host"), wantStatus: DoctorFail, wantHint: "proxy"},
+		{name: "adc", config: &ViperConfig{VertexLocation: "global", adc: newTestADC("my-project", &countingTokenSource{validity: time.Hour}, nil)}, wantStatus: DoctorOK},
+		{name: "adc without credentials", config: &ViperConfig{adc: newTestADC("", nil, errors.New("could not find default credentials"))}, wantStatus: DoctorFail, wantHint: "gcloud"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listModels := func(ctx context.Context, config *ViperConfig) ([]ModelInfo, error) {
+				if config.adc != nil {
+					t.Error("listModels() called on Vertex AI")
+				}
+				return doctorModels, tt.err
+			}
+			models, check := checkDoctorAPI(context.Background(), tt.config, listModels)
+			if check.Status != tt.wantStatus || !strings.Contains(check.Hint, tt.wantHint) {
+				t.Errorf("checkDoctorAPI() = %+v, want %s with a hint containing %q", check, tt.wantStatus, tt.wantHint)
+			}
+			if wantModels := tt.wantStatus == DoctorOK && tt.config.adc == nil; (models != nil) != wantModels {
+				t.Errorf("models = %v, want models %t", models, wantModels)
+			}
+			if strings.Contains(check.Detail, "AIzaTestKey123456") {
+				t.Errorf("detail %q shows the API key", check.Detail)
+			}
+		})
+	}
+}
+
+func TestCheckDoctorModels(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *ViperConfig
+		listed     bool
+		wantStatus string
+	}{
+		{name: "found", config: &ViperConfig{Model: "gemini-3-pro-image-preview", FallbackModel: "gemini-2.0-flash-exp", DeepResearchAgent: "deep-research-pro-preview-12-2025"}, listed: true, wantStatus: DoctorOK},
+		{name: "missing model", config: &ViperConfig{Model: "gemini-9-image", DeepResearchAgent: "deep-research-pro-preview-12-2025"}, listed: true, wantStatus: DoctorFail},
+		{name: "missing fallback model", config: &ViperConfig{Model: "gemini-3-pro-image-preview", FallbackModel: "gemini-9-image"}, listed: true, wantStatus: DoctorFail},
+		{name: "unlisted agent", config: &ViperConfig{Model: "gemini-3-pro-image-preview", DeepResearchAgent: "deep-research-next"}, listed: true, wantStatus: DoctorWarn},
+		{name: "not listed", config: &ViperConfig{Model: "gemini-3-pro-image-preview"}, wantStatus: DoctorSkip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var models []ModelInfo
+			if tt.listed {
+				models = doctorModels
+			}
+			if check := checkDoctorModels(tt.config, models, tt.listed); check.Status != tt.wantStatus {
+				t.Errorf("checkDoctorModels() = %+v, want %s", check, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCheckDoctorOutputDir(t *testing.T) {
+	// Missing directories are created
+	dir := filepath.Join(t.TempDir(), "output")
+	if check := checkDoctorOutputDir(dir); check.Status != DoctorOK {
+		t.Errorf("checkDoctorOutputDir() = %+v, want ok", check)
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Errorf("output directory entries = %v, %v, want no file left", entries, err)
+	}
+
+	// A file is not a directory
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if check := checkDoctorOutputDir(file); check.Status != DoctorFail || check.Hint == "" {
+		t.Errorf("checkDoctorOutputDir() = %+v, want a failure with a hint", check)
+	}
+
+	if runtime.GOOS == "windows" || os.Getuid() == 0 {
+		return
+	}
+	readOnly := t.TempDir()
+	if err := os.Chmod(readOnly, 0555); err != nil {
+		t.Fatal(err)
+	}
+	if check := checkDoctorOutputDir(readOnly); check.Status != DoctorFail {
+		t.Errorf("checkDoctorOutputDir() = %+v, want a failure for a read-only directory", check)
+	}
+}
+
+func TestCheckDoctorOpener(t *testing.T) {
+	installed := func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	missing := func(file string) (string, error) { return "", errors.New("executable file not found in $PATH") }
+	tests := []struct {
+		goos       string
+		lookPath   func(file string) (string, error)
+		wantStatus string
+		wantHint   string
+	}{
+		{goos: "darwin", lookPath: installed, wantStatus: DoctorOK},
+		{goos: "darwin", lookPath: missing, wantStatus: DoctorWarn, wantHint: "/usr/bin"},
+		{goos: "windows", lookPath: missing, wantStatus: DoctorWarn, wantHint: "System32"},
+		{goos: "linux", lookPath: installed, wantStatus: DoctorOK},
+		{goos: "linux", lookPath: missing, wantStatus: DoctorWarn, wantHint: "xdg-utils"},
+		{goos: "plan9", lookPath: installed, wantStatus: DoctorWarn, wantHint: "auto_open: false"},
+	}
+	for _, tt := range tests {
+		check := checkDoctorOpener(tt.goos, tt.lookPath)
+		if check.Status != tt.wantStatus || !strings.Contains(check.Hint, tt.wantHint) {
+			t.Errorf("checkDoctorOpener(%s) = %+v, want %s with the hint %q", tt.goos, check, tt.wantStatus, tt.wantHint)
+		}
+	}
+}
+
+func TestRunDoctor_ConfigError(t *testing.T) {
+	env := newDoctorTestEnv(t)
+	env.loadConfig = func() (*ViperConfig, error) { return nil, errors.New("invalid timeout") }
+	env.listModels = func(ctx context.Context, config *ViperConfig) ([]ModelInfo, error) {
+		t.Error("listModels() called without a configuration")
+		return nil, nil
+	}
+
+	report := runDoctor(context.Background(), env)
+	if report.OK {
+		t.Error("OK = true, want false")
+	}
+	var statuses []string
+	for _, check := range report.Checks {
+		statuses = append(statuses, check.Status)
+	}
+	if want := []string{DoctorFail, DoctorSkip, DoctorSkip, DoctorSkip, DoctorOK}; strings.Join(statuses, ",") != strings.Join(want, ",") {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestDoctorCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(env *doctorEnv)
+		json     bool
+		wantErr  bool
+		wantText []string
+	}{
+		{name: "healthy", wantText: []string{"✓ Configuration", "✓ API key", "✓ Models", "✓ Output directory", "✓ Opener"}},
+		{
+			name: "soft failure",
+			modify: func(env *doctorEnv) {
+				env.lookPath = func(file string) (string, error) { return "", errors.New("not found") }
+			},
+			wantText: []string{"! Opener: xdg-open not found", "→ install xdg-utils"},
+		},
+		{
+			name: "hard failure",
+			modify: func(env *doctorEnv) {
+				env.listModels = func(ctx context.Context, config *ViperConfig) ([]ModelInfo, error) {
+					return nil, &APIError{StatusCode: 403, Status: "PERMISSION_DENIED", Message: "denied"}
+				}
+			},
+			wantErr:  true,
+			wantText: []string{"✗ API key", "- Models"},
+		},
+		{name: "json", json: true, wantText: []string{`"ok": true`, `"name": "Opener"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "1")
+			env := newDoctorTestEnv(t)
+			if tt.modify != nil {
+				tt.modify(&env)
+			}
+			cmd := newDoctorCommandWith(env)
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			cmd.SetErr(&out)
+			if tt.json {
+				cmd.SetArgs([]string{"--json"})
+			} else {
+				cmd.SetArgs(nil)
+			}
+
+			if err := cmd.Execute(); (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			for _, want := range tt.wantText {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output = %q, want %q", out.String(), want)
+				}
+			}
+			if tt.json {
+				var report DoctorReport
+				if err := json.Unmarshal(out.Bytes(), &report); err != nil || len(report.Checks) != 5 {
+					t.Errorf("JSON report = %+v, %v, want 5 checks", report, err)
+				}
+			}
+		})
+	}
+}
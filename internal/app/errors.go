@@ -0,0 +1,243 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// API operations reported in APIError.
+const (
+	OpCreateInteraction = "create_interaction"
+	OpPoll              = "poll"
+	OpCancelInteraction = "cancel_interaction"
+	OpGenerateImage     = "generate_image"
+	OpListModels        = "list_models"
+	OpListInteractions  = "list_interactions"
+	OpDeleteInteraction = "delete_interaction"
+)
+
+// APIError is an error returned by the Gemini API.
+//
+// Use errors.As to inspect it from wrapped errors.
+type APIError struct {
+	Operation  string // Operation that failed (e.g., "create_interaction", "generate_image", "poll")
+	StatusCode int    // HTTP status code
+	Code       string // Google error code (e.g., "429" or an error type URI)
+	Status     string // Google error status (e.g., "RESOURCE_EXHAUSTED")
+	Message    string // Human-readable error message
+}
+
+// Error returns a human-readable description of the error.
+func (e *APIError) Error() string {
+	status := fmt.Sprintf("status %d", e.StatusCode)
+	if e.Status != "" {
+		status += " " + e.Status
+	}
+
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+
+	return fmt.Sprintf("%s: API error (%s): %s", e.Operation, status, msg)
+}
+
+// IsAuthError reports whether the error is caused by an invalid or unauthorized API key.
+func (e *APIError) IsAuthError() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden ||
+		e.Status == "UNAUTHENTICATED" || e.Status == "PERMISSION_DENIED" ||
+		strings.Contains(e.Message, "API key not valid")
+}
+
+// IsQuotaError reports whether the error is caused by rate limiting or quota exhaustion.
+func (e *APIError) IsQuotaError() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.Status == "RESOURCE_EXHAUSTED"
+}
+
+// Hint returns a user-facing suggestion for resolving the error, or an empty string.
+func (e *APIError) Hint() string {
+	switch {
+	case e.IsAuthError():
+		return "your API key is invalid or lacks permission; check GEMINI_API_KEY"
+	case e.IsQuotaError():
+		return "the API quota is exhausted; wait a moment and try again"
+	case e.StatusCode == http.StatusNotFound:
+		return "the requested model, agent, or interaction was not found"
+	}
+	return ""
+}
+
+// newAPIError builds an APIError from an HTTP error response.
+//
+// Both the Google standard error body ({"error": {"code": 400, "status": "...", "message": "..."}})
+// and the Interactions API error body ({"error": {"code": "...", "message": "..."}}) are supported.
+// If the body cannot be parsed, it is used as the message.
+func newAPIError(operation string, statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		Operation:  operation,
+		StatusCode: statusCode,
+	}
+
+	var errorBody struct {
+		Error *struct {
+			Code    json.RawMessage `json:"code"`
+			Status  string          `json:"status"`
+			Message string          `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errorBody); err != nil || errorBody.Error == nil {
+		apiErr.Message = strings.TrimSpace(string(body))
+		return apiErr
+	}
+
+	apiErr.Status = errorBody.Error.Status
+	apiErr.Message = errorBody.Error.Message
+
+	// code is a number in Google errors and a string in Interactions API errors
+	var code string
+	if err := json.Unmarshal(errorBody.Error.Code, &code); err == nil {
+		apiErr.Code = code
+	} else if len(errorBody.Error.Code) > 0 {
+		apiErr.Code = string(errorBody.Error.Code)
+	}
+
+	return apiErr
+}
+
+// ErrNoAPIKey is returned when a command needs the API but no API key is configured.
+var ErrNoAPIKey = errors.New("no API key configured; set GEMINI_API_KEY (or DEEPVIZ_API_KEY)")
+
+// ErrPollTimeout is returned when research does not complete within PollTimeout.
+var ErrPollTimeout = errors.New("polling timeout")
+
+// TimeoutError is returned when an API request exceeds one of the timeout settings.
+type TimeoutError struct {
+	Setting string        // image_timeout, research_timeout, request_timeout or connect_timeout
+	Limit   time.Duration // Configured value of the setting
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s of %gs exceeded (raise %s if the network is slow): %v", e.Setting, e.Limit.Seconds(), e.Setting, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports the error as a timeout, like the net.Error of the request.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// ErrContentBlocked is returned when the API blocks a prompt or response for safety reasons.
+//
+// Use errors.As with *ContentBlockedError to get the reason and categories.
+var ErrContentBlocked = errors.New("content blocked")
+
+// ContentBlockedError describes a safety block reported by the image generation API.
+type ContentBlockedError struct {
+	Reason     string          // Block or finish reason (e.g., "SAFETY", "PROHIBITED_CONTENT")
+	Categories []string        // Safety categories that caused the block (e.g., "HARM_CATEGORY_DANGEROUS_CONTENT")
+	Settings   []SafetySetting // Safety settings in effect (empty when the API defaults were used)
+}
+
+func (e *ContentBlockedError) Error() string {
+	msg := fmt.Sprintf("%v: %s", ErrContentBlocked, e.Reason)
+	if len(e.Categories) > 0 {
+		msg += " (" + strings.Join(e.Categories, ", ") + ")"
+	}
+	if len(e.Settings) > 0 {
+		settings := make([]string, len(e.Settings))
+		for i, setting := range e.Settings {
+			settings[i] = setting.String()
+		}
+		msg += "; safety settings in effect: " + strings.Join(settings, ", ")
+	}
+	return msg
+}
+
+// Is reports ContentBlockedError as ErrContentBlocked.
+func (e *ContentBlockedError) Is(target error) bool {
+	return target == ErrContentBlocked
+}
+
+// Pipeline stages reported in StageError.
+const (
+	StageResearch = "research"
+	StageImage    = "image"
+)
+
+// UsageError indicates invalid command-line usage (e.g., unknown flags or missing arguments).
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigError indicates that the configuration could not be loaded or is invalid.
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// StageError indicates that a pipeline stage (research or image generation) failed.
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// InterruptedError indicates that waiting for research was interrupted while the
+// interaction keeps running on the server.
+type InterruptedError struct {
+	InteractionID string
+	Err           error
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("research interrupted (interaction ID: %s): %v", e.InteractionID, e.Err)
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// ResumableError indicates that research failed locally but the interaction was kept
+// on the server and can be re-attached with `deepviz resume`.
+type ResumableError struct {
+	InteractionID string
+	Err           error
+}
+
+func (e *ResumableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ResumableError) Unwrap() error {
+	return e.Err
+}
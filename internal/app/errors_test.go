@@ -0,0 +1,177 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNewAPIError tests parsing of API error bodies.
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCode  int
+		body        string
+		wantCode    string
+		wantStatus  string
+		wantMessage string
+	}{
+		{
+			name:        "google error body",
+			statusCode:  400,
+			body:        `{"error":{"code":400,"message":"API key not valid.","status":"INVALID_ARGUMENT"}}`,
+			wantCode:    "400",
+			wantStatus:  "INVALID_ARGUMENT",
+			wantMessage: "API key not valid.",
+		},
+		{
+			name:        "interactions error body",
+			statusCode:  429,
+			body:        `{"error":{"code":"RESOURCE_EXHAUSTED","message":"quota exceeded"}}`,
+			wantCode:    "RESOURCE_EXHAUSTED",
+			wantMessage: "quota exceeded",
+		},
+		{
+			name:        "non-JSON body",
+			statusCode:  502,
+			body:        "Bad Gateway\n",
+			wantMessage: "Bad Gateway",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := newAPIError(OpGenerateImage, tt.statusCode, []byte(tt.body))
+			if apiErr.Operation != OpGenerateImage {
+				t.Errorf("Operation = %s, want %s", apiErr.Operation, OpGenerateImage)
+			}
+			if apiErr.StatusCode != tt.statusCode {
+				t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, tt.statusCode)
+			}
+			if apiErr.Code != tt.wantCode {
+				t.Errorf("Code = %q, want %q", apiErr.Code, tt.wantCode)
+			}
+			if apiErr.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", apiErr.Status, tt.wantStatus)
+			}
+			if apiErr.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", apiErr.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+// TestAPIError_Error tests the human-readable error string.
+func TestAPIError_Error(t *testing.T) {
+	apiErr := &APIError{
+		Operation:  OpCreateInteraction,
+		StatusCode: 429,
+		Status:     "RESOURCE_EXHAUSTED",
+		Message:    "quota exceeded",
+	}
+
+	want := "create_interaction: API error (status 429 RESOURCE_EXHAUSTED): quota exceeded"
+	if got := apiErr.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+// TestContentBlockedError_Error tests the reason and categories in the error string.
+func TestContentBlockedError_Error(t *testing.T) {
+	tests := []struct {
+		err  *ContentBlockedError
+		want string
+	}{
+		{err: &ContentBlockedError{Reason: "SAFETY"}, want: "content blocked: SAFETY"},
+		{
+			err:  &ContentBlockedError{Reason: "SAFETY", Categories: []string{"HARM_CATEGORY_HARASSMENT", "HARM_CATEGORY_HATE_SPEECH"}},
+			want: "content blocked: SAFETY (HARM_CATEGORY_HARASSMENT, HARM_CATEGORY_HATE_SPEECH)",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	err := fmt.Errorf("generate image: %w", &TimeoutError{Setting: "image_timeout", Limit: 120 * time.Second, Err: context.DeadlineExceeded})
+	if got := err.Error(); !strings.Contains(got, "image_timeout of 120s exceeded") {
+		t.Errorf("Error() = %q, want the setting and its value", got)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || ExitCode(err) != ExitTimeout {
+		t.Errorf("ExitCode() = %d, want ExitTimeout", ExitCode(err))
+	}
+}
+
+// TestAPIError_Hint tests error classification.
+func TestAPIError_Hint(t *testing.T) {
+	tests := []struct {
+		name     string
+		apiErr   *APIError
+		wantAuth bool
+		wantHint string
+	}{
+		{name: "unauthorized", apiErr: &APIError{StatusCode: 401}, wantAuth: true, wantHint: "API key"},
+		{name: "forbidden", apiErr: &APIError{StatusCode: 403}, wantAuth: true, wantHint: "API key"},
+		{name: "invalid key as 400", apiErr: &APIError{StatusCode: 400, Message: "API key not valid. Please pass a valid API key."}, wantAuth: true, wantHint: "API key"},
+		{name: "quota", apiErr: &APIError{StatusCode: 429}, wantHint: "quota"},
+		{name: "bad request", apiErr: &APIError{StatusCode: 400, Message: "invalid prompt"}, wantHint: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.apiErr.IsAuthError(); got != tt.wantAuth {
+				t.Errorf("IsAuthError() = %v, want %v", got, tt.wantAuth)
+			}
+			hint := tt.apiErr.Hint()
+			if tt.wantHint == "" && hint != "" {
+				t.Errorf("Hint() = %q, want empty", hint)
+			}
+			if !strings.Contains(hint, tt.wantHint) {
+				t.Errorf("Hint() = %q, want to contain %q", hint, tt.wantHint)
+			}
+		})
+	}
+}
+
+// TestAPIError_ErrorsAs tests that APIError can be extracted from wrapped errors.
+func TestAPIError_ErrorsAs(t *testing.T) {
+	apiErr := newAPIError(OpGenerateImage, 503, []byte(`{"error":{"status":"UNAVAILABLE"}}`))
+	retryable := newRetryableError(apiErr, 503, http.Header{}, nil, newFakeClock().Now())
+	err := fmt.Errorf("failed to generate image: %w", retryable)
+
+	var got *APIError
+	if !errors.As(err, &got) {
+		t.Fatal("errors.As should find APIError")
+	}
+	if got.Status != "UNAVAILABLE" {
+		t.Errorf("Status = %s, want UNAVAILABLE", got.Status)
+	}
+}
+
+// TestGenaiResearchClient_PollUntilComplete_APIError tests that polling failures carry an APIError.
+func TestGenaiResearchClient_PollUntilComplete_APIError(t *testing.T) {
+	config := &ViperConfig{PollInterval: 10, PollMaxInterval: 10, PollTimeout: 600, PollMaxFailures: 5}
+	handler, _ := scriptedHandler(http.StatusForbidden)
+	client, _ := newTestResearchClient(t, handler, config)
+
+	_, err := client.pollUntilComplete(context.Background(), "test-id", nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want APIError", err)
+	}
+	if apiErr.Operation != OpPoll {
+		t.Errorf("Operation = %s, want %s", apiErr.Operation, OpPoll)
+	}
+	if !apiErr.IsAuthError() {
+		t.Error("IsAuthError() should be true for 403")
+	}
+}
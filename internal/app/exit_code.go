@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Process exit codes.
+const (
+	ExitOK       = 0
+	ExitError    = 1
+	ExitUsage    = 2
+	ExitConfig   = 3
+	ExitResearch = 4
+	ExitImage    = 5
+	ExitTimeout  = 6
+	ExitAPIAuth  = 7
+	// ExitInterrupted follows the shell convention of 128 + SIGINT
+	ExitInterrupted = 130
+)
+
+// exitCodes describes each exit code for the help output by its message ID.
+var exitCodes = []struct {
+	code        int
+	description string
+}{
+	{ExitOK, "exit_code.ok"},
+	{ExitError, "exit_code.error"},
+	{ExitUsage, "exit_code.usage"},
+	{ExitConfig, "exit_code.config"},
+	{ExitResearch, "exit_code.research"},
+	{ExitImage, "exit_code.image"},
+	{ExitTimeout, "exit_code.timeout"},
+	{ExitAPIAuth, "exit_code.api_auth"},
+	{ExitInterrupted, "exit_code.interrupted"},
+}
+
+// ExitCode returns the process exit code for an error returned by the root command.
+//
+// Classification priority: usage, interrupt, config, auth/quota, timeout, research, image.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		return ExitUsage
+	}
+
+	var interruptedErr *InterruptedError
+	if errors.As(err, &interruptedErr) || errors.Is(err, context.Canceled) {
+		return ExitInterrupted
+	}
+
+	var configErr *ConfigError
+	if errors.As(err, &configErr) {
+		return ExitConfig
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.IsAuthError() || apiErr.IsQuotaError()) {
+		return ExitAPIAuth
+	}
+
+	if errors.Is(err, ErrPollTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return ExitTimeout
+	}
+
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		switch stageErr.Stage {
+		case StageResearch:
+			return ExitResearch
+		case StageImage:
+			return ExitImage
+		}
+	}
+
+	return ExitError
+}
+
+// exitCodeHelp returns the exit code table for the help output.
+func exitCodeHelp() string {
+	var b strings.Builder
+	b.WriteString(msg("exit_code.header") + "\n")
+	for _, ec := range exitCodes {
+		fmt.Fprintf(&b, "  %3d  %s\n", ec.code, msg(ec.description))
+	}
+	return b.String()
+}
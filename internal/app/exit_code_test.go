@@ -0,0 +1,160 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExitCode tests classification of errors into exit codes.
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: ExitOK},
+		{name: "generic", err: errors.New("boom"), want: ExitError},
+		{name: "usage", err: &UsageError{Err: errors.New("bad flag")}, want: ExitUsage},
+		{name: "config", err: &ConfigError{Err: errors.New("bad yaml")}, want: ExitConfig},
+		{
+			name: "interrupted",
+			err:  &StageError{Stage: StageResearch, Err: &InterruptedError{InteractionID: "id", Err: context.Canceled}},
+			want: ExitInterrupted,
+		},
+		{
+			name: "research failure",
+			err:  &StageError{Stage: StageResearch, Err: errors.New("research failed")},
+			want: ExitResearch,
+		},
+		{
+			name: "image failure",
+			err:  &StageError{Stage: StageImage, Err: errors.New("no image data found")},
+			want: ExitImage,
+		},
+		{
+			name: "polling timeout",
+			err:  &StageError{Stage: StageResearch, Err: fmt.Errorf("failed to poll research: %w after 600 seconds", ErrPollTimeout)},
+			want: ExitTimeout,
+		},
+		{
+			name: "context deadline",
+			err:  &StageError{Stage: StageImage, Err: fmt.Errorf("failed to do request: %w", context.DeadlineExceeded)},
+			want: ExitTimeout,
+		},
+		{
+			name: "auth error",
+			err:  &StageError{Stage: StageResearch, Err: fmt.Errorf("wrapped: %w", &APIError{StatusCode: 403})},
+			want: ExitAPIAuth,
+		},
+		{
+			name: "quota error",
+			err:  &StageError{Stage: StageImage, Err: fmt.Errorf("wrapped: %w", &APIError{StatusCode: 429})},
+			want: ExitAPIAuth,
+		},
+		{
+			name: "other API error",
+			err:  &StageError{Stage: StageImage, Err: fmt.Errorf("wrapped: %w", &APIError{StatusCode: 400})},
+			want: ExitImage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRootCommand_ErrorClassification tests exit codes of root command failures.
+func TestRootCommand_ErrorClassification(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		setup func(t *testing.T)
+		want  int
+	}{
+		{
+			name: "missing prompt",
+			args: []string{},
+			want: ExitUsage,
+		},
+		{
+			name: "unknown flag",
+			args: []string{"--no-such-flag"},
+			want: ExitUsage,
+		},
+		{
+			name: "resume without interaction ID",
+			args: []string{"resume"},
+			want: ExitUsage,
+		},
+		{
+			name: "missing prompt file",
+			args: []string{"--file", "/nonexistent/prompt.txt"},
+			want: ExitUsage,
+		},
+		{
+			name: "malformed config file",
+			args: []string{"--prompt", "test"},
+			setup: func(t *testing.T) {
+				configDir := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "deepviz")
+				if err := os.MkdirAll(configDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte("output_dir: [unclosed"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: ExitConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "config"))
+			t.Setenv("DEEPVIZ_OUTPUT_DIR", filepath.Join(tmpDir, "output"))
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+			cmd.SetErr(buf)
+
+			err := cmd.Execute()
+			if got := ExitCode(err); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d (error: %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+// TestRootCommand_HelpListsExitCodes tests that the help output documents exit codes.
+func TestRootCommand_HelpListsExitCodes(t *testing.T) {
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--help"})
+	buf := new(bytes.Buffer)
+	cmd.SetOut(buf)
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	for _, ec := range exitCodes {
+		line := fmt.Sprintf("%d  %s", ec.code, msg(ec.description))
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("help output should contain %q", line)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ExportFormatPDF is the format of research exports (--export pdf).
+const ExportFormatPDF = "pdf"
+
+// pdfExportTimeout bounds the run of the PDF converter.
+const pdfExportTimeout = 2 * time.Minute
+
+// ErrNoPDFConverter is returned when no PDF converter is installed.
+var ErrNoPDFConverter = errors.New("no PDF converter found: install wkhtmltopdf (https://wkhtmltopdf.org) or Chromium/Google Chrome, or set pdf_converter to the path of one")
+
+// pdfConverterCandidates are the converters looked up in PATH, in order of preference.
+var pdfConverterCandidates = []string{"wkhtmltopdf", "chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"}
+
+// pdfConverterPaths are the converters looked up outside PATH by platform.
+var pdfConverterPaths = map[string][]string{
+	"darwin": {
+		"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+		"/Applications/Chromium.app/Contents/MacOS/Chromium",
+	},
+	"windows": {
+		`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+		`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+		`C:\Program Files (x86)\Microsoft\Edge\Application\msedge.exe`,
+	},
+}
+
+// pdfExportTemplate is the page converted to PDF: the research followed by each image on its own page.
+var pdfExportTemplate = template.Must(template.New("pdf").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: "Helvetica Neue", Arial, "Hiragino Sans", "Noto Sans JP", sans-serif; font-size: 11pt; line-height: 1.5; color: #1f2328; margin: 0; }
+pre { background: #f6f8fa; padding: 0.5rem; white-space: pre-wrap; }
+blockquote { border-left: 3px solid #d0d7de; margin: 0; padding: 0 1rem; color: #59636e; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 0.2rem 0.5rem; }
+.image { page-break-before: always; break-before: page; text-align: center; }
+.image img { max-width: 100%; max-height: 100vh; }
+</style>
+</head>
+<body>
+{{.Research}}
+{{- range .Images}}
+<div class="image"><img src="{{.Src}}" alt="Infographic"></div>
+{{- end}}
+</body>
+</html>
+`))
+
+// pdfConverter is an external program converting HTML to PDF.
+type pdfConverter struct {
+	Path string
+}
+
+// chromium reports whether the converter is a Chromium-based browser (anything but wkhtmltopdf).
+func (c *pdfConverter) chromium() bool {
+	return !strings.Contains(strings.ToLower(filepath.Base(c.Path)), "wkhtmltopdf")
+}
+
+// args returns the arguments converting htmlPath to pdfPath.
+func (c *pdfConverter) args(htmlPath, pdfPath string) []string {
+	if !c.chromium() {
+		return []string{"--quiet", "--enable-local-file-access", htmlPath, pdfPath}
+	}
+	return []string{"--headless", "--disable-gpu", "--no-pdf-header-footer", "--print-to-pdf=" + pdfPath, fileURL(htmlPath)}
+}
+
+// findPDFConverter returns the configured PDF converter or the first one found.
+func findPDFConverter(configured string, lookPath func(file string) (string, error)) (*pdfConverter, error) {
+	if configured != "" {
+		path, err := lookPath(configured)
+		if err != nil {
+			return nil, &ConfigError{Err: fmt.Errorf("pdf_converter %s not found: %w", configured, err)}
+		}
+		return &pdfConverter{Path: path}, nil
+	}
+	for _, name := range pdfConverterCandidates {
+		if path, err := lookPath(name); err == nil {
+			return &pdfConverter{Path: path}, nil
+		}
+	}
+	for _, path := range pdfConverterPaths[runtime.GOOS] {
+		if _, err := os.Stat(path); err == nil {
+			return &pdfConverter{Path: path}, nil
+		}
+	}
+	return nil, ErrNoPDFConverter
+}
+
+// PDFPath returns the path of the PDF export of research markdown: next to it with a .pdf extension.
+func PDFPath(markdownPath string) string {
+	return strings.TrimSuffix(markdownPath, filepath.Ext(markdownPath)) + ".pdf"
+}
+
+// ExportPDF converts the research of a run to PDF with the images as the final pages, and
+// returns the path of the PDF.
+//
+// The PDF is written next to the research markdown; like reports, it is derived from the run and
+// rewritten in place.
+func ExportPDF(ctx context.Context, config *ViperConfig, run *RunManifest) (string, error) {
+	if run.Research == nil || run.Research.MarkdownPath == "" {
+		return "", fmt.Errorf("run %s has no research to export", run.Timestamp)
+	}
+	converter, err := findPDFConverter(config.PDFConverter, exec.LookPath)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := BuildReportData(run, "")
+	if err != nil {
+		return "", err
+	}
+	var page bytes.Buffer
+	if err := pdfExportTemplate.Execute(&page, data); err != nil {
+		return "", fmt.Errorf("failed to render export page: %w", err)
+	}
+
+	// The page is written next to the PDF: snap-packaged browsers cannot read the system temp directory
+	pdfPath := PDFPath(run.Research.MarkdownPath)
+	htmlFile, err := os.CreateTemp(filepath.Dir(pdfPath), ".export-*.html")
+	if err != nil {
+		return "", fmt.Errorf("failed to create export page: %w", err)
+	}
+	defer os.Remove(htmlFile.Name())
+	if _, err := htmlFile.Write(page.Bytes()); err != nil {
+		htmlFile.Close()
+		return "", fmt.Errorf("failed to write export page: %w", err)
+	}
+	if err := htmlFile.Close(); err != nil {
+		return "", fmt.Errorf("failed to write export page: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pdfExportTimeout)
+	defer cancel()
+	outputPath, err := filepath.Abs(pdfPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", pdfPath, err)
+	}
+	output, err := exec.CommandContext(ctx, converter.Path, converter.args(htmlFile.Name(), outputPath)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", filepath.Base(converter.Path), err, strings.TrimSpace(string(output)))
+	}
+	if _, err := os.Stat(pdfPath); err != nil {
+		return "", fmt.Errorf("%s did not write %s", filepath.Base(converter.Path), pdfPath)
+	}
+	return pdfPath, nil
+}
+
+// fileURL returns the file: URL of a local path.
+func fileURL(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// Windows drive letter
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeFakeConverter writes a wkhtmltopdf stand-in copying the HTML page to the PDF path.
+func writeFakeConverter(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake converter is a shell script")
+	}
+	path := filepath.Join(t.TempDir(), "wkhtmltopdf")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\ncp \"$3\" \"$4\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindPDFConverter(t *testing.T) {
+	installed := func(names ...string) func(string) (string, error) {
+		return func(file string) (string, error) {
+			for _, name := range names {
+				if name == file {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		}
+	}
+
+	converter, err := findPDFConverter("", installed("chromium", "wkhtmltopdf"))
+	if err != nil || converter.Path != "/usr/bin/wkhtmltopdf" {
+		t.Errorf("findPDFConverter() = %+v, %v, want wkhtmltopdf first", converter, err)
+	}
+	converter, err = findPDFConverter("chromium", installed("chromium", "wkhtmltopdf"))
+	if err != nil || converter.Path != "/usr/bin/chromium" {
+		t.Errorf("findPDFConverter() = %+v, %v, want the configured converter", converter, err)
+	}
+
+	var configErr *ConfigError
+	if _, err := findPDFConverter("missing", installed()); !errors.As(err, &configErr) {
+		t.Errorf("findPDFConverter() error = %v, want ConfigError", err)
+	}
+	if runtime.GOOS == "linux" {
+		if _, err := findPDFConverter("", installed()); !errors.Is(err, ErrNoPDFConverter) {
+			t.Errorf("findPDFConverter() error = %v, want ErrNoPDFConverter", err)
+		}
+	}
+}
+
+func TestPDFConverterArgs(t *testing.T) {
+	wkhtmltopdf := &pdfConverter{Path: "/usr/bin/wkhtmltopdf"}
+	if got := strings.Join(wkhtmltopdf.args("/tmp/in.html", "/tmp/out.pdf"), " "); got != "--quiet --enable-local-file-access /tmp/in.html /tmp/out.pdf" {
+		t.Errorf("args() = %q", got)
+	}
+
+	chrome := &pdfConverter{Path: "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome"}
+	args := chrome.args("/tmp/my report.html", "/tmp/out.pdf")
+	if !strings.Contains(strings.Join(args, " "), "--headless") || args[len(args)-2] != "--print-to-pdf=/tmp/out.pdf" {
+		t.Errorf("args() = %q, want headless printing", args)
+	}
+	if runtime.GOOS != "windows" && args[len(args)-1] != "file:///tmp/my%20report.html" {
+		t.Errorf("args() URL = %q, want an escaped file URL", args[len(args)-1])
+	}
+}
+
+func TestPDFPath(t *testing.T) {
+	tests := []struct {
+		markdown string
+		want     string
+	}{
+		{markdown: filepath.Join("research", "20251224_103045.md"), want: filepath.Join("research", "20251224_103045.pdf")},
+		{markdown: filepath.Join("runs", "20251224_103045", "research_ja.md"), want: filepath.Join("runs", "20251224_103045", "research_ja.pdf")},
+	}
+
+	for _, tt := range tests {
+		if got := PDFPath(tt.markdown); got != tt.want {
+			t.Errorf("PDFPath(%q) = %q, want %q", tt.markdown, got, tt.want)
+		}
+	}
+}
+
+func TestExportPDF(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir(), PDFConverter: writeFakeConverter(t)}
+	run := writeReportRun(t, config)
+
+	path, err := ExportPDF(context.Background(), config, run)
+	if err != nil {
+		t.Fatalf("ExportPDF() error = %v", err)
+	}
+	if want := filepath.Join(config.ResearchDir(), "20251224_103045.pdf"); path != want {
+		t.Errorf("path = %s, want %s", path, want)
+	}
+	page, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	research := strings.Index(string(page), "<h1>Findings</h1>")
+	image := strings.Index(string(page), `<div class="image"><img src="data:image/png;base64,`)
+	if research < 0 || image < research {
+		t.Errorf("page = %q, want the research followed by the image", page)
+	}
+
+	// The temporary page is removed
+	if matches, _ := filepath.Glob(filepath.Join(config.ResearchDir(), ".export-*")); len(matches) > 0 {
+		t.Errorf("temporary pages left: %v", matches)
+	}
+
+	run.Research = nil
+	if _, err := ExportPDF(context.Background(), config, run); err == nil {
+		t.Error("ExportPDF() error = nil, want an error for a run without research")
+	}
+}
+
+func TestExportPDF_ConverterFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake converter is a shell script")
+	}
+	converter := filepath.Join(t.TempDir(), "wkhtmltopdf")
+	if err := os.WriteFile(converter, []byte("#!/bin/sh\necho 'cannot load page' >&2\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := &ViperConfig{OutputDir: t.TempDir(), PDFConverter: converter}
+	run := writeReportRun(t, config)
+
+	if _, err := ExportPDF(context.Background(), config, run); err == nil || !strings.Contains(err.Error(), "cannot load page") {
+		t.Errorf("ExportPDF() error = %v, want the converter output", err)
+	}
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// frontMatterDelimiter delimits the front matter block at the top of a prompt file.
+const frontMatterDelimiter = "---"
+
+// frontMatterKeyPattern matches a line that looks like a YAML mapping key.
+var frontMatterKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*:`)
+
+// FrontMatter holds per-prompt options set by YAML front matter in a prompt file.
+//
+// Nil fields are not set by the front matter.
+type FrontMatter struct {
+	AspectRatio  *string  `yaml:"aspect_ratio"`
+	ImageSize    *string  `yaml:"image_size"`
+	ImageLang    *string  `yaml:"image_lang"`
+	Model        *string  `yaml:"model"`
+	ResearchOnly *bool    `yaml:"research_only"`
+	Tags         []string `yaml:"tags"`
+}
+
+// ParseFrontMatter splits a prompt into its front matter and body.
+//
+// Front matter is a YAML mapping between a "---" line at the very top of the prompt and the next
+// "---" line. A prompt without front matter is returned unchanged with nil FrontMatter. A leading
+// "---" that is not followed by a YAML mapping (e.g., a Markdown horizontal rule) is not treated
+// as front matter. An error is returned when the block looks like front matter but is invalid.
+func ParseFrontMatter(prompt string) (*FrontMatter, string, error) {
+	content, body, ok := splitFrontMatter(prompt)
+	if !ok {
+		return nil, prompt, nil
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		return nil, "", fmt.Errorf("failed to parse front matter: %w", err)
+	}
+	if len(node.Content) == 0 || node.Content[0].Kind != yaml.MappingNode {
+		return nil, prompt, nil
+	}
+
+	// Reject unknown keys to catch typos
+	var frontMatter FrontMatter
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(content)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&frontMatter); err != nil {
+		return nil, "", fmt.Errorf("invalid front matter: %w", err)
+	}
+	if _, err := ParseTags(frontMatter.Tags); err != nil {
+		return nil, "", fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	return &frontMatter, body, nil
+}
+
+// splitFrontMatter splits text into the YAML block between "---" lines at its top and the rest.
+//
+// ok is false when the text has no closed block or the block does not start with a YAML key.
+func splitFrontMatter(text string) (block, body string, ok bool) {
+	firstLine, rest, found := strings.Cut(text, "\n")
+	if !found || strings.TrimRight(firstLine, " \t\r") != frontMatterDelimiter {
+		return "", "", false
+	}
+
+	// Find the closing delimiter
+	var lines []string
+	for rest != "" {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		if strings.TrimRight(line, " \t\r") == frontMatterDelimiter {
+			if !looksLikeFrontMatter(lines) {
+				return "", "", false
+			}
+			return strings.Join(lines, "\n"), rest, true
+		}
+		lines = append(lines, line)
+	}
+	return "", "", false
+}
+
+// looksLikeFrontMatter reports whether the first non-blank line of a block is a YAML key.
+func looksLikeFrontMatter(block []string) bool {
+	for _, line := range block {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return frontMatterKeyPattern.MatchString(line)
+	}
+	return false
+}
+
+// Apply applies the front matter to the options and configuration.
+//
+// Options whose flag was explicitly set on the command line are not overridden.
+func (fm *FrontMatter) Apply(opts *Options, config *ViperConfig) {
+	if fm.AspectRatio != nil && !opts.FlagSet("aspect-ratio") {
+		opts.AspectRatio = *fm.AspectRatio
+	}
+	if fm.ImageSize != nil && !opts.FlagSet("image-size") {
+		opts.ImageSize = *fm.ImageSize
+	}
+	if fm.ImageLang != nil && !opts.FlagSet("image-lang") {
+		config.ImageLang = *fm.ImageLang
+	}
+	if fm.Model != nil && !opts.FlagSet("model") {
+		opts.Model = *fm.Model
+	}
+	if fm.ResearchOnly != nil && !opts.FlagSet("research-only") && !opts.FlagSet("no-image") {
+		opts.ResearchOnly = *fm.ResearchOnly
+	}
+	for _, tag := range fm.Tags {
+		if !slices.Contains(opts.Tags, tag) {
+			opts.Tags = append(opts.Tags, tag)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name            string
+		prompt          string
+		wantFrontMatter bool
+		wantBody        string
+		wantErr         string
+	}{
+		{
+			name:     "no front matter",
+			prompt:   "Research AI trends\n---\nmore",
+			wantBody: "Research AI trends\n---\nmore",
+		},
+		{
+			name:            "front matter",
+			prompt:          "---\naspect_ratio: \"1:1\"\ntags: [weekly, ai]\n---\nResearch AI trends\n",
+			wantFrontMatter: true,
+			wantBody:        "Research AI trends\n",
+		},
+		{
+			name:            "front matter with CRLF",
+			prompt:          "---\r\nimage_lang: English\r\n---\r\nResearch AI trends",
+			wantFrontMatter: true,
+			wantBody:        "Research AI trends",
+		},
+		{
+			name:     "leading horizontal rule",
+			prompt:   "---\nSome markdown text\n\n---\nMore text",
+			wantBody: "---\nSome markdown text\n\n---\nMore text",
+		},
+		{
+			name:     "unclosed block",
+			prompt:   "---\naspect_ratio: \"1:1\"\nResearch AI trends",
+			wantBody: "---\naspect_ratio: \"1:1\"\nResearch AI trends",
+		},
+		{
+			name:     "horizontal rule only",
+			prompt:   "---\n",
+			wantBody: "---\n",
+		},
+		{
+			name:    "malformed YAML",
+			prompt:  "---\naspect_ratio: [16:9\n---\nResearch",
+			wantErr: "failed to parse front matter",
+		},
+		{
+			name:    "unknown key",
+			prompt:  "---\naspect_raito: \"1:1\"\n---\nResearch",
+			wantErr: "invalid front matter",
+		},
+		{
+			name:    "wrong type",
+			prompt:  "---\nresearch_only: maybe\n---\nResearch",
+			wantErr: "invalid front matter",
+		},
+		{
+			name:    "invalid tag",
+			prompt:  "---\ntags: [\"project x\"]\n---\nResearch",
+			wantErr: "invalid tag",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frontMatter, body, err := ParseFrontMatter(tt.prompt)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseFrontMatter() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFrontMatter() error = %v", err)
+			}
+			if (frontMatter != nil) != tt.wantFrontMatter {
+				t.Errorf("front matter = %+v, want present %v", frontMatter, tt.wantFrontMatter)
+			}
+			if body != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestFrontMatter_Fields(t *testing.T) {
+	prompt := `---
+aspect_ratio: "1:1"
+image_size: 4K
+image_lang: English
+model: test-model
+research_only: true
+tags:
+  - weekly
+  - ai
+---
+Research AI trends`
+
+	frontMatter, _, err := ParseFrontMatter(prompt)
+	if err != nil {
+		t.Fatalf("ParseFrontMatter() error = %v", err)
+	}
+
+	opts := &Options{AspectRatio: "16:9", ImageSize: "2K", Model: "default-model"}
+	config := &ViperConfig{ImageLang: "Japanese"}
+	frontMatter.Apply(opts, config)
+
+	if opts.AspectRatio != "1:1" || opts.ImageSize != "4K" || opts.Model != "test-model" || !opts.ResearchOnly {
+		t.Errorf("options = %+v, want front matter values", opts)
+	}
+	if config.ImageLang != "English" {
+		t.Errorf("ImageLang = %s, want English", config.ImageLang)
+	}
+	if strings.Join(opts.Tags, ",") != "weekly,ai" {
+		t.Errorf("Tags = %v, want [weekly ai]", opts.Tags)
+	}
+}
+
+func TestFrontMatter_ApplyPrecedence(t *testing.T) {
+	aspectRatio := "1:1"
+	model := "front-matter-model"
+	researchOnly := true
+	frontMatter := &FrontMatter{AspectRatio: &aspectRatio, Model: &model, ResearchOnly: &researchOnly}
+
+	// Explicit flags win over front matter; unset flags are overridden
+	opts := &Options{
+		AspectRatio: "9:16",
+		Model:       "config-model",
+		SetFlags:    map[string]bool{"aspect-ratio": true, "no-image": true},
+	}
+	frontMatter.Apply(opts, &ViperConfig{})
+
+	if opts.AspectRatio != "9:16" {
+		t.Errorf("AspectRatio = %s, want 9:16 (flag)", opts.AspectRatio)
+	}
+	if opts.Model != "front-matter-model" {
+		t.Errorf("Model = %s, want front-matter-model", opts.Model)
+	}
+	if opts.ResearchOnly {
+		t.Error("ResearchOnly should keep the flag value (--no-image)")
+	}
+}
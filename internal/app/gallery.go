@@ -0,0 +1,268 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// galleryIndexName is the gallery page written to the output directory.
+	galleryIndexName = "index.html"
+	// galleryThumbsDir is the thumbnail cache directory in the output directory.
+	galleryThumbsDir = ".thumbs"
+	// galleryThumbWidth is the width of gallery thumbnails in pixels.
+	galleryThumbWidth = 320
+	// galleryThumbQuality is the JPEG quality of gallery thumbnails.
+	galleryThumbQuality = 80
+)
+
+// GalleryRun is a run shown in the gallery.
+//
+// Links are slash-separated paths relative to the output directory, empty when the file is missing.
+type GalleryRun struct {
+	Timestamp string
+	Name      string
+	Title     string
+	Status    string
+	StartedAt time.Time
+	Tags      []string
+	Image     string
+	Thumb     string // Thumbnail of the image, or the image itself when no thumbnail can be made
+	Research  string
+	Manifest  string
+	Report    string
+}
+
+// galleryTemplate is the gallery page.
+var galleryTemplate = template.Must(template.New("gallery").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>deepviz gallery</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "Hiragino Sans", "Noto Sans JP", sans-serif; color: #1f2328; margin: 2rem; }
+.grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(280px, 1fr)); gap: 1.5rem; }
+.run { border: 1px solid #d0d7de; border-radius: 6px; overflow: hidden; }
+.run img, .run .placeholder { display: block; width: 100%; aspect-ratio: 16 / 9; object-fit: cover; background: #f6f8fa; }
+.run .placeholder { display: flex; align-items: center; justify-content: center; color: #59636e; }
+.run .body { padding: 0.75rem; font-size: 0.9rem; }
+.run .title { font-weight: 600; overflow-wrap: anywhere; }
+.run .meta { color: #59636e; font-size: 0.8rem; margin: 0.25rem 0; }
+.run .links a { margin-right: 0.75rem; }
+</style>
+</head>
+<body>
+<h1>deepviz gallery</h1>
+<p>{{len .}} runs</p>
+<div class="grid">
+{{- range .}}
+<div class="run">
+{{- if .Image}}
+<a href="{{.Image}}"><img src="{{.Thumb}}" alt="{{.Title}}" loading="lazy"></a>
+{{- else}}
+<div class="placeholder">No image</div>
+{{- end}}
+<div class="body">
+<div class="title">{{.Title}}</div>
+<div class="meta">{{.Name}}{{if .Status}} · {{.Status}}{{end}}{{range .Tags}} · #{{.}}{{end}}</div>
+<div class="links">
+{{- if .Research}}<a href="{{.Research}}">Research</a>{{end}}
+{{- if .Report}}<a href="{{.Report}}">Report</a>{{end}}
+{{- if .Manifest}}<a href="{{.Manifest}}">Manifest</a>{{end}}
+</div>
+</div>
+</div>
+{{- end}}
+</div>
+</body>
+</html>
+`))
+
+// BuildGallery returns the gallery entries of runs, generating the missing thumbnails.
+//
+// Files that no longer exist are left out, and a thumbnail that cannot be made (e.g., a corrupt
+// image) is replaced by the image itself. Thumbnail failures are reported to warn.
+func BuildGallery(config *ViperConfig, runs []*RunManifest, warn func(path string, err error)) []GalleryRun {
+	gallery := make([]GalleryRun, 0, len(runs))
+	for _, run := range runs {
+		entry := GalleryRun{
+			Timestamp: run.Timestamp,
+			Name:      run.Timestamp,
+			Title:     run.Timestamp,
+			Status:    run.Status,
+			StartedAt: run.StartedAt,
+			Tags:      run.Tags,
+			Report:    galleryLink(config, run.ReportPath),
+		}
+		if run.Name != "" {
+			entry.Name = run.Name
+		}
+		if run.Prompt.Excerpt != "" {
+			entry.Title = reportTitle(run.Prompt.Excerpt)
+		}
+		if run.SchemaVersion > 0 {
+			entry.Manifest = galleryLink(config, runManifestPath(config, run))
+		}
+		if run.Research != nil {
+			entry.Research = galleryLink(config, run.Research.MarkdownPath)
+		}
+		if run.Image != nil && len(run.Image.ImagePaths) > 0 {
+			if entry.Image = galleryLink(config, run.Image.ImagePaths[0]); entry.Image != "" {
+				entry.Thumb = entry.Image
+				thumb, err := galleryThumbnail(config, run.Image.ImagePaths[0])
+				if err != nil {
+					warn(run.Image.ImagePaths[0], err)
+				} else {
+					entry.Thumb = galleryLink(config, thumb)
+				}
+			}
+		}
+		gallery = append(gallery, entry)
+	}
+	return gallery
+}
+
+// RenderGallery writes the gallery page.
+func RenderGallery(w io.Writer, gallery []GalleryRun) error {
+	if err := galleryTemplate.Execute(w, gallery); err != nil {
+		return fmt.Errorf("failed to render gallery: %w", err)
+	}
+	return nil
+}
+
+// WriteGallery writes the gallery page of every run to index.html in the output directory and
+// returns its path.
+func WriteGallery(config *ViperConfig, warn func(path string, err error)) (string, error) {
+	runs, err := ListRuns(config, warn)
+	if err != nil {
+		return "", err
+	}
+	var page bytes.Buffer
+	if err := RenderGallery(&page, BuildGallery(config, runs, warn)); err != nil {
+		return "", err
+	}
+	path := filepath.Join(config.OutputDir, galleryIndexName)
+	if err := WriteFile(path, page.Bytes()); err != nil {
+		return "", fmt.Errorf("failed to save gallery: %w", err)
+	}
+	return path, nil
+}
+
+// NewGalleryHandler returns an HTTP handler serving the gallery and the files of the output
+// directory.
+//
+// The gallery page is built on every request, so that new runs show up on reload.
+func NewGalleryHandler(config *ViperConfig, warn func(path string, err error)) http.Handler {
+	files := http.FileServer(http.Dir(config.OutputDir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Path != "/"+galleryIndexName {
+			files.ServeHTTP(w, r)
+			return
+		}
+		runs, err := ListRuns(config, warn)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var page bytes.Buffer
+		if err := RenderGallery(&page, BuildGallery(config, runs, warn)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page.Bytes())
+	})
+}
+
+// galleryLink returns the link to an existing file relative to the output directory.
+//
+// Files outside the output directory (e.g., per-job output directories) are not linked.
+func galleryLink(config *ViperConfig, path string) string {
+	if path == "" {
+		return ""
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+	rel, err := filepath.Rel(config.OutputDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// galleryThumbnail returns the cached thumbnail of an image, generating it when missing or older
+// than the image.
+func galleryThumbnail(config *ViperConfig, imagePath string) (string, error) {
+	info, err := os.Stat(imagePath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(filepath.ToSlash(imagePath)))
+	path := filepath.Join(config.OutputDir, galleryThumbsDir, hex.EncodeToString(sum[:8])+".jpg")
+	if thumb, err := os.Stat(path); err == nil && !thumb.ModTime().Before(info.ModTime()) {
+		return path, nil
+	}
+
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resizeImage(img, galleryThumbWidth), &jpeg.Options{Quality: galleryThumbQuality}); err != nil {
+		return "", fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	if err := WriteFile(path, buf.Bytes()); err != nil {
+		return "", fmt.Errorf("failed to save thumbnail: %w", err)
+	}
+	return path, nil
+}
+
+// resizeImage scales an image down to width, averaging the source pixels of each thumbnail pixel.
+//
+// Images not wider than width are returned as is.
+func resizeImage(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	if bounds.Dx() <= width {
+		return src
+	}
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+	for y := range height {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := range width {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
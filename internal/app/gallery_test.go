@@ -0,0 +1,187 @@
+package app
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestPNG writes a solid PNG of the given size.
+func writeTestPNG(t *testing.T, path string, width, height int) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildGallery(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	config := &ViperConfig{OutputDir: outputDir}
+	writeTestPNG(t, config.ImagePath("20251224_103045", ".png"), 640, 360)
+
+	runs, err := ListRuns(config, func(string, error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The manifest of 20251224_103045 does not link its image yet
+	for _, run := range runs {
+		if run.Timestamp == "20251224_103045" {
+			run.Image = &ManifestImage{ImagePaths: []string{config.ImagePath("20251224_103045", ".png")}}
+		}
+	}
+
+	var warned []string
+	gallery := BuildGallery(config, runs, func(path string, err error) { warned = append(warned, path) })
+	entries := make(map[string]GalleryRun)
+	for _, entry := range gallery {
+		entries[entry.Timestamp] = entry
+	}
+	if len(entries) != len(runs) {
+		t.Fatalf("gallery has %d runs, want %d", len(entries), len(runs))
+	}
+
+	withManifest := entries["20251224_103045"]
+	if withManifest.Title != "AI trends" || withManifest.Research != "research/20251224_103045.md" || withManifest.Manifest != "manifests/20251224_103045.json" {
+		t.Errorf("entry = %+v, want the title, research and manifest links", withManifest)
+	}
+	if withManifest.Image != "images/20251224_103045.png" || !strings.HasPrefix(withManifest.Thumb, galleryThumbsDir+"/") {
+		t.Errorf("entry = %+v, want the image and its thumbnail", withManifest)
+	}
+
+	// Images that cannot be decoded are their own thumbnail
+	reconstructed := entries["20251120_080000"]
+	if reconstructed.Manifest != "" || reconstructed.Image == "" || reconstructed.Thumb != reconstructed.Image {
+		t.Errorf("entry = %+v, want the image as thumbnail and no manifest", reconstructed)
+	}
+	if len(warned) == 0 {
+		t.Error("no warning for the undecodable images")
+	}
+
+	// A run with only a log has no links
+	if logOnly := entries["20251001_120000"]; logOnly.Image != "" || logOnly.Research != "" {
+		t.Errorf("entry = %+v, want no image and research", logOnly)
+	}
+}
+
+func TestGalleryThumbnail(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir()}
+	imagePath := config.ImagePath("20251224_103045", ".png")
+	writeTestPNG(t, imagePath, 800, 400)
+
+	thumb, err := galleryThumbnail(config, imagePath)
+	if err != nil {
+		t.Fatalf("galleryThumbnail() error = %v", err)
+	}
+	file, err := os.Open(thumb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	cfg, format, err := image.DecodeConfig(file)
+	if err != nil || format != "jpeg" || cfg.Width != galleryThumbWidth || cfg.Height != 160 {
+		t.Errorf("thumbnail = %s %dx%d, %v, want a %dx160 jpeg", format, cfg.Width, cfg.Height, err, galleryThumbWidth)
+	}
+
+	// The cached thumbnail is reused until the image changes
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(imagePath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(thumb, []byte("cached"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := galleryThumbnail(config, imagePath); err != nil || again != thumb {
+		t.Fatalf("galleryThumbnail() = %s, %v", again, err)
+	}
+	if data, _ := os.ReadFile(thumb); string(data) != "cached" {
+		t.Error("cached thumbnail was regenerated")
+	}
+}
+
+func TestResizeImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	src.Set(0, 0, color.RGBA{A: 255})
+	src.Set(1, 0, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	got := resizeImage(src, 2)
+	if got.Bounds().Dx() != 2 || got.Bounds().Dy() != 1 {
+		t.Fatalf("bounds = %v, want 2x1", got.Bounds())
+	}
+	// The first pixel averages black, white and two transparent pixels
+	if r, _, _, a := got.At(0, 0).RGBA(); r != 0xffff/4 || a != 0xffff/2 {
+		t.Errorf("pixel = %v, want the average of its source pixels", got.At(0, 0))
+	}
+
+	if small := resizeImage(src, 10); small != image.Image(src) {
+		t.Error("narrow images should be returned as is")
+	}
+}
+
+func TestWriteGallery(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	config := &ViperConfig{OutputDir: outputDir}
+
+	path, err := WriteGallery(config, func(string, error) {})
+	if err != nil {
+		t.Fatalf("WriteGallery() error = %v", err)
+	}
+	if path != filepath.Join(outputDir, "index.html") {
+		t.Errorf("path = %s", path)
+	}
+	page, _ := os.ReadFile(path)
+	for _, want := range []string{"AI trends", `href="research/20251224_103045.md"`, "No image"} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("gallery does not contain %q", want)
+		}
+	}
+}
+
+func TestNewGalleryHandler(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	server := httptest.NewServer(NewGalleryHandler(&ViperConfig{OutputDir: outputDir}, func(string, error) {}))
+	defer server.Close()
+
+	tests := []struct {
+		path     string
+		wantCode int
+		want     string
+	}{
+		{path: "/", wantCode: http.StatusOK, want: "AI trends"},
+		{path: "/research/20251224_103045.md", wantCode: http.StatusOK, want: "x"},
+		{path: "/missing.png", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.wantCode || !strings.Contains(body.String(), tt.want) {
+			t.Errorf("GET %s = %d %q, want %d with %q", tt.path, resp.StatusCode, body.String(), tt.wantCode, tt.want)
+		}
+	}
+}
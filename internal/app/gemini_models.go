@@ -0,0 +1,174 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// modelsPageSize is the number of models requested per page from the models endpoint.
+const modelsPageSize = 1000
+
+// ModelInfo is a model returned by the Gemini API models endpoint.
+type ModelInfo struct {
+	Name                       string   `json:"name"` // Resource name (e.g., "models/gemini-3-pro-image-preview")
+	DisplayName                string   `json:"displayName"`
+	Description                string   `json:"description"`
+	InputTokenLimit            int      `json:"inputTokenLimit"`
+	OutputTokenLimit           int      `json:"outputTokenLimit"`
+	SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+}
+
+// ID returns the model name without the "models/" prefix, as used in requests and config.
+func (m ModelInfo) ID() string {
+	return strings.TrimPrefix(m.Name, "models/")
+}
+
+// ModelsClient lists the models available to the configured API key.
+type ModelsClient struct {
+	config     *ViperConfig
+	logger     Logger
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewModelsClient creates a new ModelsClient.
+//
+// It returns ErrNoAPIKey if no API key is configured, and ErrNotSupportedOnVertex with auth: adc.
+func NewModelsClient(config *ViperConfig, logger Logger) (*ModelsClient, error) {
+	if config.adc != nil {
+		return nil, fmt.Errorf("%s: %w", "listing models", ErrNotSupportedOnVertex)
+	}
+	if err := config.loadAPIKey(); err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+	if config.APIKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	return &ModelsClient{
+		config:     config,
+		logger:     logger,
+		httpClient: newHTTPClient(config, config.requestTimeout(), logger),
+		baseURL:    defaultImageBaseURL,
+	}, nil
+}
+
+// ListModels returns every model, following pagination.
+func (c *ModelsClient) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	var models []ModelInfo
+	pageToken := ""
+	for {
+		query := url.Values{"pageSize": {fmt.Sprint(modelsPageSize)}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		body, err := apiGet(ctx, c.httpClient, c.config.APIKey, c.baseURL+"/v1beta/models?"+query.Encode(), OpListModels, c.logger)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Models        []ModelInfo `json:"models"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal models response: %w", err)
+		}
+		models = append(models, page.Models...)
+
+		if page.NextPageToken == "" {
+			return models, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// apiGet sends an authenticated GET request to the Gemini API and returns the response body.
+//
+// A non-200 response is returned as an APIError for operation.
+func apiGet(ctx context.Context, httpClient *http.Client, apiKey, url, operation string, logger Logger) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("x-goog-api-key", apiKey)
+
+	logger.Debug("HTTP Request", "url", url, "method", "GET")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	logger.Trace("HTTP Response", "url", url, "status_code", resp.StatusCode, "body", string(body))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(operation, resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// isImageModel reports whether a model can generate images.
+//
+// The models endpoint does not report output modalities, so models supporting generateContent
+// are identified as image models by name (e.g., "gemini-3-pro-image-preview").
+func isImageModel(model ModelInfo) bool {
+	return slices.Contains(model.SupportedGenerationMethods, "generateContent") && strings.Contains(model.ID(), "image")
+}
+
+// ImageModels returns the models that can generate images.
+//
+// The result is cached for shell completion of --model.
+func (c *ModelsClient) ImageModels(ctx context.Context) ([]ModelInfo, error) {
+	models, err := c.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var imageModels []ModelInfo
+	for _, model := range models {
+		if isImageModel(model) {
+			imageModels = append(imageModels, model)
+		}
+	}
+	if err := saveListingCache(c.config.ModelsCachePath(), imageModels, time.Now()); err != nil {
+		c.logger.Warn("Failed to cache image models", "error", err)
+	}
+	return imageModels, nil
+}
+
+// findModel returns the model with the given ID (with or without the "models/" prefix).
+func findModel(models []ModelInfo, id string) (ModelInfo, bool) {
+	id = strings.TrimPrefix(id, "models/")
+	for _, model := range models {
+		if model.ID() == id {
+			return model, true
+		}
+	}
+	return ModelInfo{}, false
+}
+
+// printModels prints models as a table with the configured model marked by "*".
+func printModels(w io.Writer, models []ModelInfo, configured string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  NAME\tDISPLAY NAME\tINPUT TOKENS\tOUTPUT TOKENS")
+	for _, model := range models {
+		marker := " "
+		if model.ID() == configured {
+			marker = "*"
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\t%d\t%d\n", marker, model.ID(), model.DisplayName, model.InputTokenLimit, model.OutputTokenLimit)
+	}
+	tw.Flush()
+}
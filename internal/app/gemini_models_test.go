@@ -0,0 +1,173 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yukiyan/deepviz/internal/version"
+)
+
+// newTestModelsClient creates a ModelsClient that sends requests to handler.
+func newTestModelsClient(t *testing.T, handler http.Handler, config *ViperConfig) *ModelsClient {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	if config.APIKey == "" {
+		config.APIKey = "test-key"
+	}
+	client, err := NewModelsClient(config, NewNullLogger())
+	if err != nil {
+		t.Fatalf("failed to create models client: %v", err)
+	}
+	client.baseURL = srv.URL
+	return client
+}
+
+func TestNewModelsClient_NoAPIKey(t *testing.T) {
+	if _, err := NewModelsClient(&ViperConfig{}, NewNullLogger()); !errors.Is(err, ErrNoAPIKey) {
+		t.Errorf("NewModelsClient() error = %v, want ErrNoAPIKey", err)
+	}
+}
+
+func TestModelsClient_ListModels(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("x-goog-api-key = %q, want test-key", r.Header.Get("x-goog-api-key"))
+		}
+		if !strings.HasPrefix(r.Header.Get("User-Agent"), "deepviz/"+version.Version()+" ") {
+			t.Errorf("User-Agent = %q, want the deepviz User-Agent", r.Header.Get("User-Agent"))
+		}
+		// Two pages
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"models":[{"name":"models/gemini-a","displayName":"Gemini A"}],"nextPageToken":"next"}`)
+			return
+		}
+		fmt.Fprint(w, `{"models":[{"name":"models/gemini-b","inputTokenLimit":32768}]}`)
+	})
+	client := newTestModelsClient(t, handler, &ViperConfig{})
+
+	models, err := client.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 2 || models[0].ID() != "gemini-a" || models[1].ID() != "gemini-b" {
+		t.Fatalf("models = %+v, want gemini-a and gemini-b", models)
+	}
+	if models[1].InputTokenLimit != 32768 {
+		t.Errorf("InputTokenLimit = %d, want 32768", models[1].InputTokenLimit)
+	}
+}
+
+func TestModelsClient_ListModels_APIError(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"code":400,"message":"API key not valid. Please pass a valid API key.","status":"INVALID_ARGUMENT"}}`)
+	})
+	client := newTestModelsClient(t, handler, &ViperConfig{})
+
+	_, err := client.ListModels(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("ListModels() error = %v, want APIError", err)
+	}
+	if apiErr.Operation != OpListModels || !apiErr.IsAuthError() {
+		t.Errorf("APIError = %+v, want an auth error of %s", apiErr, OpListModels)
+	}
+}
+
+func TestModelsClient_ImageModels(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[
+			{"name":"models/gemini-3-pro-image-preview","supportedGenerationMethods":["generateContent","countTokens"]},
+			{"name":"models/gemini-2.5-flash","supportedGenerationMethods":["generateContent"]},
+			{"name":"models/imagen-4.0-generate-001","supportedGenerationMethods":["predict"]},
+			{"name":"models/deep-research-pro-preview-12-2025"}
+		]}`)
+	})
+	config := &ViperConfig{StateDir: t.TempDir()}
+	client := newTestModelsClient(t, handler, config)
+
+	models, err := client.ImageModels(context.Background())
+	if err != nil {
+		t.Fatalf("ImageModels() error = %v", err)
+	}
+	if len(models) != 1 || models[0].ID() != "gemini-3-pro-image-preview" {
+		t.Errorf("models = %+v, want only gemini-3-pro-image-preview", models)
+	}
+
+	cache, err := loadListingCache[ModelInfo](config.ModelsCachePath())
+	if err != nil {
+		t.Fatalf("loadListingCache() error = %v", err)
+	}
+	if len(cache.Items) != 1 || cache.Items[0].ID() != "gemini-3-pro-image-preview" {
+		t.Errorf("cached models = %+v, want only gemini-3-pro-image-preview", cache.Items)
+	}
+}
+
+func TestCheckConfiguredModels(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[{"name":"models/gemini-3-pro-image-preview"}]}`)
+	})
+
+	tests := []struct {
+		name          string
+		model         string
+		fallbackModel string
+		wantErr       bool
+	}{
+		{name: "available", model: "gemini-3-pro-image-preview"},
+		{name: "prefixed name", model: "models/gemini-3-pro-image-preview"},
+		{name: "missing model", model: "gemini-typo", wantErr: true},
+		{name: "missing fallback model", model: "gemini-3-pro-image-preview", fallbackModel: "gemini-typo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ViperConfig{Model: tt.model, FallbackModel: tt.fallbackModel}
+			client := newTestModelsClient(t, handler, config)
+
+			var buf bytes.Buffer
+			err := checkConfiguredModels(context.Background(), &buf, client, config)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("checkConfiguredModels() error = %v", err)
+				}
+				return
+			}
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) || !strings.Contains(err.Error(), "gemini-typo") {
+				t.Errorf("checkConfiguredModels() error = %v, want a ConfigError naming gemini-typo", err)
+			}
+			if !strings.Contains(buf.String(), "gemini-typo: not found") {
+				t.Errorf("output = %q, want the missing model", buf.String())
+			}
+		})
+	}
+}
+
+func TestPrintModels(t *testing.T) {
+	var buf bytes.Buffer
+	printModels(&buf, []ModelInfo{
+		{Name: "models/gemini-3-pro-image-preview", DisplayName: "Gemini 3 Pro Image Preview", InputTokenLimit: 65536, OutputTokenLimit: 32768},
+		{Name: "models/gemini-2.5-flash-image"},
+	}, "gemini-3-pro-image-preview")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output = %q, want a header and two models", buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "* gemini-3-pro-image-preview") || !strings.Contains(lines[1], "65536") {
+		t.Errorf("configured model line = %q", lines[1])
+	}
+	if strings.HasPrefix(lines[2], "*") {
+		t.Errorf("other model line = %q, should not be marked", lines[2])
+	}
+}
@@ -5,12 +5,13 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
-	"path/filepath"
 	"strings"
-	"time"
+	"text/template"
 	"unicode"
 )
 
@@ -19,26 +20,112 @@ type ImageConfig struct {
 	Model       string // Model name (default: gemini-3-pro-image-preview)
 	AspectRatio string // Aspect ratio (default: 16:9)
 	ImageSize   string // Image size (default: 2K)
+	Candidates  int    // Number of candidates requested in one call (candidateCount, default: 1)
+
+	InputImages []*InputImage // Existing images sent ahead of the prompt (e.g., for editing)
 }
 
+// defaultImageBaseURL is the Gemini API base URL for image generation.
+const defaultImageBaseURL = "https://generativelanguage.googleapis.com"
+
+// imageRetryNudge is appended to the prompt when retrying a response without an image.
+const imageRetryNudge = "Return the infographic as an image."
+
+// ErrNoImageData is returned when the image generation response contains no image.
+var ErrNoImageData = errors.New("no image data found in response")
+
+// imageVariantConcurrency is the maximum number of image variants generated in parallel.
+const imageVariantConcurrency = 2
+
 // ImageResult holds image generation result.
 type ImageResult struct {
-	ImagePath    string // Saved image path
-	ResponsePath string // Raw response path
+	ImagePath     string         // Saved image path (the first one when there are several)
+	ImagePaths    []string       // All saved image paths
+	ResponsePath  string         // Raw response path
+	RequestPath   string         // Saved request body path (generation parameters)
+	PromptPath    string         // Saved prompt path
+	CaptionPath   string         // Saved text parts of the response (empty when there are none)
+	Model         string         // Model that produced the image
+	FallbackFrom  string         // Primary model that failed when the fallback model was used
+	OriginalPaths []string       // Images as returned by the API, kept when converting with KeepOriginal
+	InputImages   []string       // Paths of the input images sent with the prompt
+	VariantErrors []VariantError // Variants that failed when generating several
+	PromptPaths   []string       // Saved prompt paths of every language when generating several
+	LangErrors    []LangError    // Languages that failed when generating several
+}
+
+// VariantError is an image variant that failed to generate.
+type VariantError struct {
+	Variant int // 1-based variant number
+	Err     error
+}
+
+func (e VariantError) Error() string {
+	return fmt.Sprintf("variant %d: %v", e.Variant, e.Err)
+}
+
+func (e VariantError) Unwrap() error {
+	return e.Err
 }
 
 // GenaiImageClient is an image generation client.
 type GenaiImageClient struct {
-	config *ViperConfig
-	logger Logger
+	// Progress shows the progress of image requests (optional)
+	Progress ProgressReporter
+
+	config         *ViperConfig
+	logger         Logger
+	clock          clock
+	promptTemplate *template.Template
+	style          string // Style preset instructions appended to infographic prompts
+	imageFormat    string
+	baseURL        string
+	vertex         *vertexEndpoint // Vertex AI endpoint with auth: adc (nil for the Gemini API)
+	httpClient     *http.Client
 }
 
 // NewGenaiImageClient creates a new GenaiImageClient.
+//
+// It returns an error if the configured image prompt template, style or image format is invalid.
 func NewGenaiImageClient(ctx context.Context, config *ViperConfig, logger Logger) (*GenaiImageClient, error) {
-	return &GenaiImageClient{
-		config: config,
-		logger: logger,
-	}, nil
+	promptTemplate, err := ParseImagePromptTemplate(config.ImagePromptTemplate)
+	if err != nil {
+		return nil, err
+	}
+	style, err := ResolveStyle(config.Style, config.Styles)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateSampling(config); err != nil {
+		return nil, err
+	}
+	imageFormat, err := NormalizeImageFormat(config.ImageFormat)
+	if err != nil {
+		return nil, err
+	}
+	if imageFormat != "" {
+		if err := ValidateImageQuality(config.ImageQuality); err != nil {
+			return nil, err
+		}
+	}
+
+	client := &GenaiImageClient{
+		config:         config,
+		logger:         logger,
+		clock:          realClock{},
+		promptTemplate: promptTemplate,
+		style:          style,
+		imageFormat:    imageFormat,
+		baseURL:        defaultImageBaseURL,
+		httpClient:     newHTTPClient(config, config.imageTimeout(), logger),
+	}
+	if config.adc != nil {
+		if client.vertex, err = vertexEndpointOf(config); err != nil {
+			return nil, err
+		}
+		client.baseURL = client.vertex.baseURL()
+	}
+	return client, nil
 }
 
 // sanitizePrompt removes potentially dangerous control characters while preserving valid whitespace.
@@ -59,24 +146,28 @@ func sanitizeImagePrompt(prompt string) string {
 
 // BuildInfographicsPrompt builds an infographics generation prompt from Markdown content.
 //
-// The prompt language is controlled by ImageLang configuration (e.g., "Japanese", "English", "French").
+// The prompt is rendered from ImagePromptTemplate (the built-in template when unset) with
+// the language from ImageLang configuration (e.g., "Japanese", "English", "French").
+// The instructions of the selected style preset are appended to the rendered prompt.
 //
-// Template:
+// Built-in template:
 //
-//	Take a good look at the content below and turn it into a single infographic image in {ImageLang}.
+//	Take a good look at the content below and turn it into a single infographic image in {{.Lang}}.
 //	```
-//	{markdown}
+//	{{.Content}}
 //	```
-func (c *GenaiImageClient) BuildInfographicsPrompt(markdown string) string {
+func (c *GenaiImageClient) BuildInfographicsPrompt(markdown string) (string, error) {
 	// Sanitize markdown content
 	sanitizedMarkdown := sanitizeImagePrompt(markdown)
 
-	promptTemplate := `Take a good look at the content below and turn it into a single infographic image in %s.
-` + "```" + `
-%s
-` + "```"
-
-	return fmt.Sprintf(promptTemplate, c.config.ImageLang, sanitizedMarkdown)
+	prompt, err := renderImagePrompt(c.promptTemplate, ImagePromptData{
+		Lang:    c.config.ImageLang,
+		Content: sanitizedMarkdown,
+	})
+	if err != nil {
+		return "", err
+	}
+	return appendStyle(prompt, c.style), nil
 }
 
 // Generate generates and saves an image.
@@ -84,133 +175,500 @@ func (c *GenaiImageClient) Generate(ctx context.Context, prompt string, imgConfi
 	// Sanitize prompt
 	sanitizedPrompt := sanitizeImagePrompt(prompt)
 
-	// Create request body
-	requestBody := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]interface{}{
-					{"text": sanitizedPrompt},
-				},
+	// Save the exact prompt sent to the API so that the run can be reproduced
+	promptPath, err := writeArtifact(c.logger, c.config.ImagePromptPath(timestamp), []byte(sanitizedPrompt), c.config.Overwrite)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	c.logger.Info("Image prompt saved", "path", promptPath)
+
+	progress := reporterOrNop(c.Progress)
+	progress.Start("Generating image…", 0)
+	defer progress.Stop()
+
+	// Request the image, retrying when the response contains no image (e.g., text only)
+	var request, body []byte
+	var inlineImages []inlineImage
+	var texts []string
+	attemptPrompt := sanitizedPrompt
+	for attempt := 0; ; attempt++ {
+		var response *imageResponse
+		var err error
+		request, body, response, err = c.requestImage(ctx, attemptPrompt, imgConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		inlineImages, texts = c.extractImages(response)
+		if len(inlineImages) > 0 {
+			break
+		}
+		if blockedErr := response.blocked(); blockedErr != nil {
+			blockedErr.Settings = c.config.SafetySettings
+			return nil, blockedErr
+		}
+		if attempt >= c.config.ImageRetries {
+			return nil, newNoImageDataError(texts)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		c.logger.Info("Response contains no image, retrying", "attempt", attempt+1, "max_retries", c.config.ImageRetries, "text", strings.Join(texts, " "))
+		attemptPrompt = sanitizedPrompt + "\n\n" + imageRetryNudge
+	}
+	c.logger.Info("Images found in response", "count", len(inlineImages))
+
+	// Decode and save each image with an index suffix
+	var imagePaths, originalPaths []string
+	for i, image := range inlineImages {
+		imageData, err := base64.StdEncoding.DecodeString(image.data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode base64 image data: %w", err)
+		}
+
+		ext, detected := imageExtension(image.mimeType, imageData)
+		if image.mimeType != "" && detected != "" && detected != mimeTypeBase(image.mimeType) {
+			c.logger.Warn("Image mimeType does not match its content", "mime_type", image.mimeType, "detected", detected)
+		}
+
+		// Convert to the configured format unless the image already has it
+		if c.imageFormat != "" && imageFormatExtensions[c.imageFormat] != ext {
+			if c.config.KeepOriginal {
+				originalPath, err := writeArtifact(c.logger, c.config.ImagePath(imageName(timestamp, i)+"_original", ext), imageData, c.config.Overwrite)
+				if err != nil {
+					return nil, fmt.Errorf("failed to write original image file: %w", err)
+				}
+				c.logger.Info("Original image saved", "path", originalPath)
+				originalPaths = append(originalPaths, originalPath)
+			}
+
+			converted, convertedExt, err := convertImage(imageData, c.imageFormat, c.config.ImageQuality)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert image: %w", err)
+			}
+			c.logger.Info("Image converted", "format", c.imageFormat, "original_bytes", len(imageData), "converted_bytes", len(converted))
+			imageData, ext = converted, convertedExt
+		}
+
+		imagePath, err := writeArtifact(c.logger, c.config.ImagePath(imageName(timestamp, i), ext), imageData, c.config.Overwrite)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write image file: %w", err)
+		}
+		c.logger.Info("Image saved", "path", imagePath, "bytes", len(imageData))
+		imagePaths = append(imagePaths, imagePath)
+	}
+
+	// Save the text parts returned alongside the images as a caption
+	var captionPath string
+	if c.config.SaveCaptions && len(texts) > 0 {
+		var err error
+		captionPath, err = writeArtifact(c.logger, c.config.CaptionPath(timestamp), []byte(strings.Join(texts, "\n\n")+"\n"), c.config.Overwrite)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write caption file: %w", err)
+		}
+		c.logger.Info("Caption saved", "path", captionPath)
+	}
+
+	// Save raw response (it repeats the image data, so it can be skipped with save_responses)
+	var responsePath string
+	if !c.config.NoSaveResponses {
+		responsePath, err = writeArtifact(c.logger, c.config.ImageResponsePath(timestamp), redactBody(body, c.config.secrets()...), c.config.Overwrite)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write response file: %w", err)
+		}
+		c.logger.Info("Raw response saved", "path", responsePath)
+	}
+
+	// Save the request body (generation parameters such as seed and temperature) for reproduction
+	requestPath, err := writeArtifact(c.logger, c.config.ImageRequestPath(timestamp), redactBody(request, c.config.secrets()...), c.config.Overwrite)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write request file: %w", err)
+	}
+	c.logger.Info("Image request saved", "path", requestPath)
+
+	return &ImageResult{
+		ImagePath:     imagePaths[0],
+		ImagePaths:    imagePaths,
+		ResponsePath:  responsePath,
+		RequestPath:   requestPath,
+		PromptPath:    promptPath,
+		InputImages:   inputImagePaths(imgConfig.InputImages),
+		CaptionPath:   captionPath,
+		Model:         imgConfig.Model,
+		OriginalPaths: originalPaths,
+	}, nil
+}
+
+// safetyRating is a safety rating of a prompt or candidate in a generateContent response.
+type safetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// imageResponse is the part of a generateContent response used for image generation.
+type imageResponse struct {
+	PromptFeedback struct {
+		BlockReason   string         `json:"blockReason,omitempty"`
+		SafetyRatings []safetyRating `json:"safetyRatings,omitempty"`
+	} `json:"promptFeedback"`
+	Candidates []struct {
+		FinishReason  string         `json:"finishReason,omitempty"`
+		SafetyRatings []safetyRating `json:"safetyRatings,omitempty"`
+		Content       struct {
+			Parts []struct {
+				Text       string `json:"text,omitempty"`
+				InlineData struct {
+					Data     string `json:"data"`
+					MimeType string `json:"mimeType"`
+				} `json:"inlineData,omitempty"`
+			} `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+}
+
+// requestImage sends a generateContent request and returns the request body with the raw and parsed response.
+//
+// Requests failing with 429/503 are retried.
+func (c *GenaiImageClient) requestImage(ctx context.Context, prompt string, imgConfig ImageConfig) ([]byte, []byte, *imageResponse, error) {
+	// Input images go ahead of the text part. The recorded request refers to them by path
+	// instead of embedding their data.
+	var parts, recordParts []map[string]interface{}
+	for _, image := range imgConfig.InputImages {
+		parts = append(parts, map[string]interface{}{
+			"inlineData": map[string]interface{}{
+				"mimeType": image.MimeType,
+				"data":     base64.StdEncoding.EncodeToString(image.Data),
 			},
-		},
+		})
+		recordParts = append(recordParts, map[string]interface{}{
+			"inlineData": map[string]interface{}{
+				"mimeType": image.MimeType,
+				"data":     "(input image: " + image.Path + ")",
+			},
+		})
+	}
+	parts = append(parts, map[string]interface{}{"text": prompt})
+	recordParts = append(recordParts, map[string]interface{}{"text": prompt})
+
+	// Create request body (Vertex AI requires the role of the contents)
+	content := map[string]interface{}{"parts": parts}
+	if c.vertex != nil {
+		content["role"] = "user"
+	}
+	requestBody := map[string]interface{}{
+		"contents": []map[string]interface{}{content},
 		"tools": []map[string]interface{}{
 			{"google_search": map[string]interface{}{}},
 		},
-		"generationConfig": map[string]interface{}{
-			"responseModalities": []string{"TEXT", "IMAGE"},
-			"imageConfig": map[string]interface{}{
-				"aspectRatio": imgConfig.AspectRatio,
-				"imageSize":   imgConfig.ImageSize,
+	}
+	if instruction := sanitizeImagePrompt(c.config.ImageSystemInstruction); instruction != "" {
+		requestBody["systemInstruction"] = map[string]interface{}{
+			"parts": []map[string]interface{}{
+				{"text": instruction},
 			},
+		}
+	}
+	if len(c.config.SafetySettings) > 0 {
+		requestBody["safetySettings"] = c.config.SafetySettings
+	}
+	generationConfig := map[string]any{
+		"responseModalities": []string{"TEXT", "IMAGE"},
+		"imageConfig": map[string]any{
+			"aspectRatio": imgConfig.AspectRatio,
+			"imageSize":   imgConfig.ImageSize,
 		},
 	}
+	if imgConfig.Candidates > 1 {
+		generationConfig["candidateCount"] = imgConfig.Candidates
+	}
+	addSampling(generationConfig, c.config)
+	requestBody["generationConfig"] = mergeGenerationConfig(generationConfig, c.config.GenerationConfig)
+	c.logger.Debug("Effective generation config", "generation_config", requestBody["generationConfig"])
 
 	bodyBytes, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
-
-	// Get HTTP client
-	httpClient := &http.Client{
-		Timeout: 120 * time.Second, // Image generation takes time
+	recordBytes := bodyBytes
+	if len(imgConfig.InputImages) > 0 {
+		recordContent := maps.Clone(content)
+		recordContent["parts"] = recordParts
+		requestBody["contents"] = []map[string]interface{}{recordContent}
+		if recordBytes, err = json.Marshal(requestBody); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
 	}
 
-	// Create HTTP request
-	baseURL := "https://generativelanguage.googleapis.com"
-	url := baseURL + "/v1beta/models/" + imgConfig.Model + ":generateContent"
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	url := c.baseURL + "/v1beta/models/" + imgConfig.Model + ":generateContent"
+	if c.vertex != nil {
+		url = c.baseURL + c.vertex.modelPath(imgConfig.Model, "generateContent")
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-goog-api-key", c.config.APIKey)
+	c.logger.Info("Generating image", "model", imgConfig.Model, "input_images", len(imgConfig.InputImages), "aspect_ratio", imgConfig.AspectRatio, "size", imgConfig.ImageSize, "candidates", max(imgConfig.Candidates, 1))
 
-	// Execute request
-	c.logger.Info("Generating image", "model", imgConfig.Model, "aspect_ratio", imgConfig.AspectRatio, "size", imgConfig.ImageSize)
-	c.logger.Debug("HTTP Request", "url", url, "method", "POST", "body", string(bodyBytes))
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to do request: %w", err)
-	}
-	defer resp.Body.Close()
+	// Execute request (retried on 429/503)
+	var body []byte
+	err = withRetry(ctx, newRetryConfig(c.config, c.clock), c.logger, OpGenerateImage, func(ctx context.Context) error {
+		// Create HTTP request
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
 
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
+		// Set headers
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-goog-api-key", c.config.APIKey)
 
-	c.logger.Debug("HTTP Response", "url", url, "status_code", resp.StatusCode, "body", string(body))
+		c.logger.Trace("HTTP Request", "url", url, "method", "POST", "body", string(recordBytes))
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to do request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		// Read response
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		c.logger.Trace("HTTP Response", "url", url, "status_code", resp.StatusCode, "body", string(body))
+
+		// Check status code
+		if resp.StatusCode != http.StatusOK {
+			apiErr := newAPIError(OpGenerateImage, resp.StatusCode, body)
+			return newRetryableError(apiErr, resp.StatusCode, resp.Header, body, c.clock.Now())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
 	// Parse JSON
-	var response struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text       string `json:"text,omitempty"`
-					InlineData struct {
-						Data     string `json:"data"`
-						MimeType string `json:"mimeType"`
-					} `json:"inlineData,omitempty"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
+	var response imageResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	// Extract image data
-	var base64ImageData string
-	for _, candidate := range response.Candidates {
+	return recordBytes, body, &response, nil
+}
+
+// extractImages collects every image across all candidates (the model may split content into
+// several images) and the text parts returned alongside them.
+func (c *GenaiImageClient) extractImages(response *imageResponse) ([]inlineImage, []string) {
+	var inlineImages []inlineImage
+	var texts []string
+	for i, candidate := range response.Candidates {
+		found := false
 		for _, part := range candidate.Content.Parts {
 			if part.InlineData.Data != "" {
-				base64ImageData = part.InlineData.Data
-				break
+				inlineImages = append(inlineImages, inlineImage{data: part.InlineData.Data, mimeType: part.InlineData.MimeType})
+				found = true
+			} else if text := strings.TrimSpace(part.Text); text != "" {
+				texts = append(texts, text)
 			}
 		}
-		if base64ImageData != "" {
-			break
+		if !found {
+			c.logger.Info("Candidate contains no image", "candidate", i+1)
 		}
 	}
+	return inlineImages, texts
+}
+
+// blockedFinishReasons are candidate finish reasons that indicate a safety block.
+var blockedFinishReasons = map[string]bool{
+	"SAFETY":                   true,
+	"PROHIBITED_CONTENT":       true,
+	"BLOCKLIST":                true,
+	"SPII":                     true,
+	"IMAGE_SAFETY":             true,
+	"IMAGE_PROHIBITED_CONTENT": true,
+}
 
-	if base64ImageData == "" {
-		return nil, fmt.Errorf("no image data found in response")
+// blocked returns a ContentBlockedError when the prompt or every candidate was blocked, or nil.
+func (r *imageResponse) blocked() *ContentBlockedError {
+	if r.PromptFeedback.BlockReason != "" {
+		return &ContentBlockedError{
+			Reason:     r.PromptFeedback.BlockReason,
+			Categories: blockedCategories(r.PromptFeedback.SafetyRatings),
+		}
 	}
+	for _, candidate := range r.Candidates {
+		if blockedFinishReasons[candidate.FinishReason] {
+			return &ContentBlockedError{
+				Reason:     candidate.FinishReason,
+				Categories: blockedCategories(candidate.SafetyRatings),
+			}
+		}
+	}
+	return nil
+}
 
-	// Decode Base64
-	imageData, err := base64.StdEncoding.DecodeString(base64ImageData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode base64 image data: %w", err)
+// blockedCategories returns the categories of ratings that were blocked or rated HIGH.
+func blockedCategories(ratings []safetyRating) []string {
+	var categories []string
+	for _, rating := range ratings {
+		if rating.Blocked || rating.Probability == "HIGH" {
+			categories = append(categories, rating.Category)
+		}
 	}
+	return categories
+}
 
-	// Build file paths
-	imagePath := filepath.Join(c.config.ImagesDir(), timestamp+".png")
-	responsePath := filepath.Join(c.config.ResponsesDir(), timestamp+"_image.json")
+// newNoImageDataError returns an ErrNoImageData error including the text the model returned instead.
+func newNoImageDataError(texts []string) error {
+	if len(texts) == 0 {
+		return ErrNoImageData
+	}
+	return fmt.Errorf("%w: %s", ErrNoImageData, strings.Join(texts, " "))
+}
 
-	// Save image file
-	if err := WriteFile(imagePath, imageData); err != nil {
-		return nil, fmt.Errorf("failed to write image file: %w", err)
+// inlineImage is a base64-encoded image part of a generateContent response.
+type inlineImage struct {
+	data     string
+	mimeType string
+}
+
+// imageExtensions maps image mime types to file extensions.
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/webp": ".webp",
+}
+
+// imageExtension returns the file extension for an image and the mime type sniffed from its bytes.
+//
+// The response mimeType takes priority; the sniffed type is used when it is absent or unknown,
+// and ".png" when neither is recognized.
+func imageExtension(mimeType string, data []byte) (ext, detected string) {
+	detected = mimeTypeBase(http.DetectContentType(data))
+	if ext, ok := imageExtensions[mimeTypeBase(mimeType)]; ok {
+		return ext, detected
+	}
+	if ext, ok := imageExtensions[detected]; ok {
+		return ext, detected
 	}
+	return ".png", detected
+}
 
-	c.logger.Info("Image saved", "path", imagePath)
+// mimeTypeBase returns the mime type without parameters in lower case (e.g., "image/png").
+func mimeTypeBase(mimeType string) string {
+	base, _, _ := strings.Cut(mimeType, ";")
+	return strings.ToLower(strings.TrimSpace(base))
+}
 
-	// Save raw response
-	if err := WriteFile(responsePath, body); err != nil {
-		return nil, fmt.Errorf("failed to write response file: %w", err)
+// inputImagePaths returns the paths of input images.
+func inputImagePaths(images []*InputImage) []string {
+	var paths []string
+	for _, image := range images {
+		paths = append(paths, image.Path)
 	}
+	return paths
+}
 
-	c.logger.Info("Raw response saved", "path", responsePath)
+// shouldFallback reports whether an image generation error is worth retrying with the fallback model.
+//
+// This is the case when the model is missing (404), overloaded (429/5xx) or returned no image.
+func shouldFallback(err error) bool {
+	if errors.Is(err, ErrNoImageData) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	return false
+}
 
-	return &ImageResult{
-		ImagePath:    imagePath,
-		ResponsePath: responsePath,
-	}, nil
+// generateWithFallback generates an image and retries once with FallbackModel when the primary model fails.
+//
+// Without a fallback model configured this is the same as Generate.
+func (c *GenaiImageClient) generateWithFallback(ctx context.Context, prompt string, imgConfig ImageConfig, timestamp string) (*ImageResult, error) {
+	result, err := c.Generate(ctx, prompt, imgConfig, timestamp)
+	fallbackModel := c.config.FallbackModel
+	if err == nil || fallbackModel == "" || fallbackModel == imgConfig.Model || !shouldFallback(err) || ctx.Err() != nil {
+		return result, err
+	}
+
+	c.logger.Error("Image generation failed, retrying with fallback model", "model", imgConfig.Model, "fallback_model", fallbackModel, "error", err)
+	fallbackConfig := imgConfig
+	fallbackConfig.Model = fallbackModel
+	result, fallbackErr := c.Generate(ctx, prompt, fallbackConfig, timestamp)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("%w (fallback model %s: %w)", err, fallbackModel, fallbackErr)
+	}
+	c.logger.Info("Image generated with fallback model", "model", fallbackModel, "primary_model", imgConfig.Model)
+	result.FallbackFrom = imgConfig.Model
+
+	return result, nil
+}
+
+// imageName returns the artifact name of the i-th (0-based) image of a response.
+//
+// The first image keeps the plain name (<timestamp>.png); the others get an index suffix (<timestamp>_2.png, ...).
+func imageName(timestamp string, i int) string {
+	if i == 0 {
+		return timestamp
+	}
+	return fmt.Sprintf("%s_%d", timestamp, i+1)
+}
+
+// GenerateVariants generates count images from the same prompt.
+//
+// Each variant falls back to FallbackModel when the primary model fails. With a count of 1 only a
+// single image is requested. Otherwise each variant is saved with a
+// numbered suffix (<timestamp>_1.png, <timestamp>_2.png, ...) and up to imageVariantConcurrency
+// variants are generated in parallel. Successful variants are kept when others fail; the failures
+// are reported in VariantErrors. An error is returned only when every variant fails.
+func (c *GenaiImageClient) GenerateVariants(ctx context.Context, prompt string, imgConfig ImageConfig, timestamp string, count int) (*ImageResult, error) {
+	if count <= 1 {
+		return c.generateWithFallback(ctx, prompt, imgConfig, timestamp)
+	}
+
+	c.logger.Info("Generating image variants", "count", count)
+	results := make([]*ImageResult, count)
+	errs := runPool(ctx, imageVariantConcurrency, count, false, func(ctx context.Context, i int) error {
+		result, err := c.generateWithFallback(ctx, prompt, imgConfig, fmt.Sprintf("%s_%d", timestamp, i+1))
+		results[i] = result
+		return err
+	})
+
+	var merged *ImageResult
+	var variantErrs []VariantError
+	for i, result := range results {
+		if errs[i] != nil {
+			c.logger.Error("Image variant failed", "variant", i+1, "error", errs[i])
+			variantErrs = append(variantErrs, VariantError{Variant: i + 1, Err: errs[i]})
+			continue
+		}
+		if merged == nil {
+			merged = &ImageResult{
+				ImagePath:    result.ImagePath,
+				ResponsePath: result.ResponsePath,
+				RequestPath:  result.RequestPath,
+				InputImages:  result.InputImages,
+				PromptPath:   result.PromptPath,
+				CaptionPath:  result.CaptionPath,
+				Model:        result.Model,
+			}
+		}
+		if result.FallbackFrom != "" {
+			merged.FallbackFrom = result.FallbackFrom
+		}
+		merged.ImagePaths = append(merged.ImagePaths, result.ImagePaths...)
+		merged.OriginalPaths = append(merged.OriginalPaths, result.OriginalPaths...)
+	}
+
+	if merged == nil {
+		joined := make([]error, len(variantErrs))
+		for i, variantErr := range variantErrs {
+			joined[i] = variantErr
+		}
+		return nil, fmt.Errorf("all %d image variants failed: %w", count, errors.Join(joined...))
+	}
+	merged.VariantErrors = variantErrs
+
+	return merged, nil
 }
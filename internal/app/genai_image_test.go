@@ -2,77 +2,163 @@ package app
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestNewGenaiImageClient(t *testing.T) {
-	// Skip if API key is not set
-	if os.Getenv("GEMINI_API_KEY") == "" {
-		t.Skip("GEMINI_API_KEY not set")
-	}
+	config := &ViperConfig{APIKey: testSecretKey, ImageTimeout: 120, RequestTimeout: 30}
 
-	ctx := context.Background()
-	config := &ViperConfig{
-		APIKey: os.Getenv("GEMINI_API_KEY"),
-	}
-	logger := NewNullLogger()
-
-	client, err := NewGenaiImageClient(ctx, config, logger)
+	client, err := NewGenaiImageClient(context.Background(), config, NewNullLogger())
 	if err != nil {
-		t.Fatalf("failed to create genai image client: %v", err)
+		t.Fatalf("NewGenaiImageClient() error = %v", err)
 	}
-
-	if client == nil {
-		t.Error("client should not be nil")
+	if client.baseURL != defaultImageBaseURL {
+		t.Errorf("baseURL = %q, want %q", client.baseURL, defaultImageBaseURL)
+	}
+	if transport, ok := client.httpClient.Transport.(*timeoutTransport); !ok || transport.timeout != config.imageTimeout() {
+		t.Errorf("httpClient = %+v, want a client with the image generation timeout", client.httpClient)
 	}
 }
 
 func TestGenaiImageClient_Generate(t *testing.T) {
-	// Skip if API key is not set
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		t.Skip("GEMINI_API_KEY not set")
+	var request map[string]interface{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-3-pro-image-preview:generateContent" {
+			t.Errorf("path = %s, want the generateContent endpoint of the model", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != testSecretKey {
+			t.Errorf("x-goog-api-key = %q, want the API key", r.Header.Get("x-goog-api-key"))
+		}
+		if got := r.Header.Get("User-Agent"); got != userAgent("") {
+			t.Errorf("User-Agent = %q, want %q", got, userAgent(""))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, imageResponseJSON(testImageData))
+	})
+	config := &ViperConfig{OutputDir: t.TempDir(), APIKey: testSecretKey}
+	client := newTestImageClient(t, handler, config)
+
+	prompt := "A beautiful sunset over mountains"
+	imageConfig := ImageConfig{Model: "gemini-3-pro-image-preview", AspectRatio: "16:9", ImageSize: "2K"}
+	result, err := client.Generate(context.Background(), prompt, imageConfig, "20251224_103045")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
 	}
 
-	ctx := context.Background()
-	tmpDir := t.TempDir()
-	config := &ViperConfig{
-		OutputDir: tmpDir,
-		APIKey:    apiKey,
+	data, _ := json.Marshal(request)
+	for _, want := range []string{prompt, `"aspectRatio":"16:9"`, `"imageSize":"2K"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("request = %s, want %s", data, want)
+		}
 	}
-	logger := NewNullLogger()
 
-	client, err := NewGenaiImageClient(ctx, config, logger)
+	if want := config.ImagePath("20251224_103045", ".png"); result.ImagePath != want || !slices.Equal(result.ImagePaths, []string{want}) {
+		t.Errorf("ImagePath = %q, ImagePaths = %v, want %q", result.ImagePath, result.ImagePaths, want)
+	}
+	image, err := os.ReadFile(result.ImagePath)
 	if err != nil {
-		t.Fatalf("failed to create genai image client: %v", err)
+		t.Fatalf("image file should be created: %v", err)
+	}
+	if string(image) != string(testImageData) {
+		t.Errorf("image = %q, want the decoded fixture", image)
 	}
+	if result.PromptPath != config.ImagePromptPath("20251224_103045") {
+		t.Errorf("PromptPath = %q, want %q", result.PromptPath, config.ImagePromptPath("20251224_103045"))
+	}
+	if saved, err := os.ReadFile(result.PromptPath); err != nil || string(saved) != prompt {
+		t.Errorf("saved prompt = %q (%v), want %q", saved, err, prompt)
+	}
+}
 
-	// Test with simple prompt
-	prompt := "A beautiful sunset over mountains"
-	imageConfig := ImageConfig{
-		Model:       "gemini-3-pro-image-preview",
-		AspectRatio: "16:9",
-		ImageSize:   "2K",
+func TestGenaiImageClient_Generate_MalformedBase64(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"candidates":[{"content":{"parts":[{"inlineData":{"mimeType":"image/png","data":"not base64!"}}]}}]}`)
+	})
+	config := &ViperConfig{OutputDir: t.TempDir()}
+	client := newTestImageClient(t, handler, config)
+
+	_, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+	if err == nil || !strings.Contains(err.Error(), "failed to decode base64 image data") {
+		t.Fatalf("Generate() error = %v, want a decode error", err)
+	}
+	if entries, _ := os.ReadDir(config.ImagesDir()); len(entries) != 0 {
+		t.Errorf("images = %v, want no image written", entries)
 	}
+}
 
-	result, err := client.Generate(ctx, prompt, imageConfig, "test-timestamp")
+func TestGenaiImageClient_Generate_RateLimited(t *testing.T) {
+	var calls atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"code":429,"status":"RESOURCE_EXHAUSTED","message":"Quota exceeded"}}`)
+			return
+		}
+		fmt.Fprint(w, imageResponseJSON(testImageData))
+	})
+	config := &ViperConfig{OutputDir: t.TempDir(), MaxRetries: 2, RetryMaxWait: 60}
+	client := newTestImageClient(t, handler, config)
+	fc := newFakeClock()
+	client.clock = fc
+
+	result, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
 	if err != nil {
-		t.Fatalf("failed to generate image: %v", err)
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want a retry after the 429", calls.Load())
+	}
+	if waits := fc.Waits(); len(waits) != 1 || waits[0] != 7*time.Second {
+		t.Errorf("waits = %v, want the Retry-After delay", waits)
+	}
+	if image, err := os.ReadFile(result.ImagePath); err != nil || string(image) != string(testImageData) {
+		t.Errorf("image = %q (%v), want the fixture", image, err)
 	}
+}
 
-	// Verify result
-	if result == nil {
-		t.Fatal("result should not be nil")
+func TestGenaiImageClient_Generate_SavesPromptBeforeRequest(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := &ViperConfig{OutputDir: tmpDir}
+	client, err := NewGenaiImageClient(context.Background(), config, NewNullLogger())
+	if err != nil {
+		t.Fatalf("failed to create genai image client: %v", err)
 	}
 
-	if result.ImagePath == "" {
-		t.Error("image path should not be empty")
+	// A cancelled context makes the request fail without reaching the network
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	prompt := "Prompt\x00 with control characters"
+	if _, err := client.Generate(ctx, prompt, ImageConfig{Model: "test-model"}, "20251224_103045"); err == nil {
+		t.Fatal("Generate() should fail with a cancelled context")
 	}
 
-	// Verify file was created
-	if _, err := os.Stat(result.ImagePath); os.IsNotExist(err) {
-		t.Error("image file should be created")
+	// The prompt is kept for debugging even if the request fails
+	saved, err := os.ReadFile(filepath.Join(config.PromptsDir(), "20251224_103045.txt"))
+	if err != nil {
+		t.Fatalf("prompt file should be created: %v", err)
+	}
+	if want := "Prompt with control characters"; string(saved) != want {
+		t.Errorf("saved prompt = %q, want %q", string(saved), want)
 	}
 }
 
@@ -89,7 +175,10 @@ func TestGenaiImageClient_BuildInfographicsPrompt(t *testing.T) {
 	}
 
 	markdown := "# Test\nThis is a test markdown."
-	prompt := client.BuildInfographicsPrompt(markdown)
+	prompt, err := client.BuildInfographicsPrompt(markdown)
+	if err != nil {
+		t.Fatalf("BuildInfographicsPrompt() error = %v", err)
+	}
 
 	if prompt == "" {
 		t.Error("prompt should not be empty")
@@ -99,3 +188,794 @@ func TestGenaiImageClient_BuildInfographicsPrompt(t *testing.T) {
 		t.Error("prompt should be longer than markdown (contains template)")
 	}
 }
+
+func TestNewGenaiImageClient_InvalidTemplate(t *testing.T) {
+	config := &ViperConfig{ImagePromptTemplate: "no content placeholder"}
+
+	if _, err := NewGenaiImageClient(context.Background(), config, NewNullLogger()); err == nil {
+		t.Error("NewGenaiImageClient() should fail with an invalid template")
+	}
+}
+
+// testImageData is a fake PNG payload returned by the mock image server.
+var testImageData = []byte("\x89PNG\r\n\x1a\nfake")
+
+// newTestImageClient creates a GenaiImageClient that talks to a mock server using a fake clock.
+func newTestImageClient(t *testing.T, handler http.Handler, config *ViperConfig) *GenaiImageClient {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client, err := NewGenaiImageClient(context.Background(), config, NewNullLogger())
+	if err != nil {
+		t.Fatalf("failed to create genai image client: %v", err)
+	}
+	client.baseURL = srv.URL
+	client.clock = newFakeClock()
+	return client
+}
+
+// imageResponseJSON builds a generateContent response with one inline image.
+func imageResponseJSON(data []byte) string {
+	return fmt.Sprintf(`{"candidates":[{"content":{"parts":[{"inlineData":{"mimeType":"image/png","data":%q}}]}}]}`,
+		base64.StdEncoding.EncodeToString(data))
+}
+
+func TestGenaiImageClient_GenerateVariants(t *testing.T) {
+	var calls atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The second request fails permanently
+		if calls.Add(1) == 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"code":400,"message":"bad request","status":"INVALID_ARGUMENT"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, imageResponseJSON(testImageData))
+	})
+
+	config := &ViperConfig{OutputDir: t.TempDir()}
+	client := newTestImageClient(t, handler, config)
+
+	result, err := client.GenerateVariants(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045", 3)
+	if err != nil {
+		t.Fatalf("GenerateVariants() error = %v", err)
+	}
+
+	if len(result.ImagePaths) != 2 {
+		t.Fatalf("ImagePaths = %v, want 2 images", result.ImagePaths)
+	}
+	if len(result.VariantErrors) != 1 {
+		t.Fatalf("VariantErrors = %v, want 1", result.VariantErrors)
+	}
+	if result.ImagePath != result.ImagePaths[0] {
+		t.Errorf("ImagePath = %s, want the first image %s", result.ImagePath, result.ImagePaths[0])
+	}
+	for _, path := range result.ImagePaths {
+		if !strings.HasPrefix(filepath.Base(path), "20251224_103045_") {
+			t.Errorf("image %s should have a variant suffix", path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("image %s should be saved: %v", path, err)
+		}
+	}
+}
+
+func TestGenaiImageClient_Generate_NoSaveResponses(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, imageResponseJSON(testImageData))
+	})
+
+	for _, noSave := range []bool{false, true} {
+		config := &ViperConfig{OutputDir: t.TempDir(), NoSaveResponses: noSave}
+		client := newTestImageClient(t, handler, config)
+
+		result, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		_, statErr := os.Stat(config.ImageResponsePath("20251224_103045"))
+		if noSave && (result.ResponsePath != "" || !os.IsNotExist(statErr)) {
+			t.Errorf("ResponsePath = %q (%v), want no saved response", result.ResponsePath, statErr)
+		}
+		if !noSave && (result.ResponsePath == "" || statErr != nil) {
+			t.Errorf("ResponsePath = %q (%v), want the saved response", result.ResponsePath, statErr)
+		}
+		// The request keeps the generation parameters for reproduction
+		if result.RequestPath == "" {
+			t.Error("RequestPath is empty, want the saved request")
+		}
+	}
+}
+
+func TestGenaiImageClient_Generate_Progress(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, imageResponseJSON(testImageData))
+	})
+	client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir()})
+	progress := &recordingReporter{}
+	client.Progress = progress
+
+	if _, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045"); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if want := []string{"start Generating image… 0s", "stop"}; !slices.Equal(progress.calls, want) {
+		t.Errorf("calls = %v, want %v", progress.calls, want)
+	}
+}
+
+func TestGenaiImageClient_GenerateVariants_AllFail(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"code":400,"message":"bad request","status":"INVALID_ARGUMENT"}}`)
+	})
+
+	client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir()})
+
+	_, err := client.GenerateVariants(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045", 2)
+	if err == nil || !strings.Contains(err.Error(), "all 2 image variants failed") {
+		t.Errorf("GenerateVariants() error = %v, want all variants failed", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Error("error should wrap the APIError")
+	}
+}
+
+func TestGenaiImageClient_GenerateVariants_Single(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, imageResponseJSON(testImageData))
+	})
+
+	config := &ViperConfig{OutputDir: t.TempDir()}
+	client := newTestImageClient(t, handler, config)
+
+	result, err := client.GenerateVariants(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045", 1)
+	if err != nil {
+		t.Fatalf("GenerateVariants() error = %v", err)
+	}
+
+	// A single image keeps the plain timestamp name
+	want := filepath.Join(config.ImagesDir(), "20251224_103045.png")
+	if result.ImagePath != want || len(result.ImagePaths) != 1 {
+		t.Errorf("result = %+v, want only %s", result, want)
+	}
+}
+
+func TestGenaiImageClient_Generate_Candidates(t *testing.T) {
+	var requestBody string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requestBody = string(body)
+		data := base64.StdEncoding.EncodeToString(testImageData)
+		// The second candidate contains only text
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"candidates":[
+			{"content":{"parts":[{"text":"first"},{"inlineData":{"mimeType":"image/png","data":%q}}]}},
+			{"content":{"parts":[{"text":"no image here"}]}},
+			{"content":{"parts":[{"inlineData":{"mimeType":"image/png","data":%q}}]}}
+		]}`, data, data)
+	})
+
+	config := &ViperConfig{OutputDir: t.TempDir()}
+	client := newTestImageClient(t, handler, config)
+
+	result, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model", Candidates: 3}, "20251224_103045")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if !strings.Contains(requestBody, `"candidateCount":3`) {
+		t.Errorf("request body should set candidateCount: %s", requestBody)
+	}
+
+	want := []string{
+		filepath.Join(config.ImagesDir(), "20251224_103045.png"),
+		filepath.Join(config.ImagesDir(), "20251224_103045_2.png"),
+	}
+	if len(result.ImagePaths) != len(want) {
+		t.Fatalf("ImagePaths = %v, want %v", result.ImagePaths, want)
+	}
+	for i, path := range want {
+		if result.ImagePaths[i] != path {
+			t.Errorf("ImagePaths[%d] = %s, want %s", i, result.ImagePaths[i], path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("image %s should be saved: %v", path, err)
+		}
+	}
+}
+
+func TestGenaiImageClient_Generate_SingleCandidateOmitsCount(t *testing.T) {
+	var requestBody string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requestBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, imageResponseJSON(testImageData))
+	})
+
+	client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir()})
+
+	if _, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model", Candidates: 1}, "20251224_103045"); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if strings.Contains(requestBody, "candidateCount") {
+		t.Errorf("request body should not set candidateCount for a single candidate: %s", requestBody)
+	}
+}
+
+func TestGenaiImageClient_Generate_ExistingFiles(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"candidates":[{"content":{"parts":[{"inlineData":{"mimeType":"image/png","data":%q}}]}}]}`,
+			base64.StdEncoding.EncodeToString(testImageData))
+	})
+
+	tests := []struct {
+		name      string
+		overwrite bool
+		want      string
+	}{
+		{name: "kept", want: "20251224_103045-1.png"},
+		{name: "overwritten", overwrite: true, want: "20251224_103045.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ViperConfig{OutputDir: t.TempDir(), Overwrite: tt.overwrite}
+			existing := config.ImagePath("20251224_103045", ".png")
+			if err := WriteFile(existing, []byte("previous run")); err != nil {
+				t.Fatalf("failed to write existing image: %v", err)
+			}
+			client := newTestImageClient(t, handler, config)
+
+			result, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			if want := filepath.Join(config.ImagesDir(), tt.want); result.ImagePath != want {
+				t.Errorf("ImagePath = %s, want %s", result.ImagePath, want)
+			}
+			saved, err := os.ReadFile(existing)
+			if err != nil {
+				t.Fatalf("failed to read existing image: %v", err)
+			}
+			if kept := string(saved) == "previous run"; kept == tt.overwrite {
+				t.Errorf("existing image kept = %t, want %t", kept, !tt.overwrite)
+			}
+		})
+	}
+}
+
+func TestGenaiImageClient_Generate_MultipleParts(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := base64.StdEncoding.EncodeToString(testImageData)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"candidates":[{"content":{"parts":[
+			{"inlineData":{"mimeType":"image/png","data":%q}},
+			{"text":"second panel"},
+			{"inlineData":{"mimeType":"image/png","data":%q}}
+		]}}]}`, data, data)
+	})
+
+	config := &ViperConfig{OutputDir: t.TempDir()}
+	client := newTestImageClient(t, handler, config)
+
+	result, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if len(result.ImagePaths) != 2 {
+		t.Fatalf("ImagePaths = %v, want 2 images", result.ImagePaths)
+	}
+	if want := filepath.Join(config.ImagesDir(), "20251224_103045.png"); result.ImagePath != want {
+		t.Errorf("ImagePath = %s, want %s", result.ImagePath, want)
+	}
+	if want := filepath.Join(config.ImagesDir(), "20251224_103045_2.png"); result.ImagePaths[1] != want {
+		t.Errorf("ImagePaths[1] = %s, want %s", result.ImagePaths[1], want)
+	}
+}
+
+func TestGenaiImageClient_Generate_NoImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{
+			name:    "text only",
+			body:    `{"candidates":[{"content":{"parts":[{"text":"I cannot draw this."}]}}]}`,
+			wantErr: "no image data found in response: I cannot draw this.",
+		},
+		{
+			name:    "no candidates",
+			body:    `{"candidates":[]}`,
+			wantErr: "no image data found in response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, tt.body)
+			})
+			client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir()})
+
+			_, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Generate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImageExtension(t *testing.T) {
+	jpegData := []byte("\xff\xd8\xff\xe0fake")
+	webpData := []byte("RIFF\x00\x00\x00\x00WEBPVP8 fake")
+
+	tests := []struct {
+		name         string
+		mimeType     string
+		data         []byte
+		wantExt      string
+		wantDetected string
+	}{
+		{name: "png", mimeType: "image/png", data: testImageData, wantExt: ".png", wantDetected: "image/png"},
+		{name: "jpeg", mimeType: "image/jpeg", data: jpegData, wantExt: ".jpg", wantDetected: "image/jpeg"},
+		{name: "webp", mimeType: "image/webp", data: webpData, wantExt: ".webp", wantDetected: "image/webp"},
+		{name: "mime type with parameters", mimeType: "Image/JPEG; q=1", data: jpegData, wantExt: ".jpg", wantDetected: "image/jpeg"},
+		{name: "sniffed when mime type is absent", mimeType: "", data: webpData, wantExt: ".webp", wantDetected: "image/webp"},
+		{name: "mime type wins over content", mimeType: "image/jpeg", data: testImageData, wantExt: ".jpg", wantDetected: "image/png"},
+		{name: "unknown falls back to png", mimeType: "", data: []byte("fake"), wantExt: ".png", wantDetected: "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ext, detected := imageExtension(tt.mimeType, tt.data)
+			if ext != tt.wantExt {
+				t.Errorf("imageExtension() ext = %s, want %s", ext, tt.wantExt)
+			}
+			if detected != tt.wantDetected {
+				t.Errorf("imageExtension() detected = %s, want %s", detected, tt.wantDetected)
+			}
+		})
+	}
+}
+
+func TestGenaiImageClient_Generate_MimeType(t *testing.T) {
+	tests := []struct {
+		name     string
+		mimeType string
+		data     []byte
+		wantFile string
+	}{
+		{name: "png", mimeType: "image/png", data: testImageData, wantFile: "20251224_103045.png"},
+		{name: "jpeg", mimeType: "image/jpeg", data: []byte("\xff\xd8\xff\xe0fake"), wantFile: "20251224_103045.jpg"},
+		{name: "webp", mimeType: "image/webp", data: []byte("RIFF\x00\x00\x00\x00WEBPVP8 fake"), wantFile: "20251224_103045.webp"},
+		{name: "missing mime type", mimeType: "", data: []byte("\xff\xd8\xff\xe0fake"), wantFile: "20251224_103045.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"candidates":[{"content":{"parts":[{"inlineData":{"mimeType":%q,"data":%q}}]}}]}`,
+					tt.mimeType, base64.StdEncoding.EncodeToString(tt.data))
+			})
+			config := &ViperConfig{OutputDir: t.TempDir()}
+			client := newTestImageClient(t, handler, config)
+
+			result, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			want := filepath.Join(config.ImagesDir(), tt.wantFile)
+			if result.ImagePath != want || result.ImagePaths[0] != want {
+				t.Errorf("ImagePath = %s, want %s", result.ImagePath, want)
+			}
+			if _, err := os.Stat(want); err != nil {
+				t.Errorf("image should be saved as %s: %v", want, err)
+			}
+		})
+	}
+}
+
+func TestGenaiImageClient_Generate_ImageFormat(t *testing.T) {
+	data := testPNG(t)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, imageResponseJSON(data))
+	})
+
+	config := &ViperConfig{OutputDir: t.TempDir(), ImageFormat: "jpeg", ImageQuality: 80, KeepOriginal: true}
+	client := newTestImageClient(t, handler, config)
+
+	result, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	want := filepath.Join(config.ImagesDir(), "20251224_103045.jpg")
+	if result.ImagePath != want {
+		t.Errorf("ImagePath = %s, want %s", result.ImagePath, want)
+	}
+	converted, err := os.ReadFile(want)
+	if err != nil {
+		t.Fatalf("converted image should be saved: %v", err)
+	}
+	if got := http.DetectContentType(converted); got != "image/jpeg" {
+		t.Errorf("converted content type = %s, want image/jpeg", got)
+	}
+
+	wantOriginal := filepath.Join(config.ImagesDir(), "20251224_103045_original.png")
+	if len(result.OriginalPaths) != 1 || result.OriginalPaths[0] != wantOriginal {
+		t.Errorf("OriginalPaths = %v, want [%s]", result.OriginalPaths, wantOriginal)
+	}
+	if _, err := os.Stat(wantOriginal); err != nil {
+		t.Errorf("original image should be kept: %v", err)
+	}
+}
+
+func TestGenaiImageClient_Generate_Caption(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"candidates":[{"content":{"parts":[
+			{"text":"Here is an infographic summarizing the research."},
+			{"inlineData":{"mimeType":"image/png","data":%q}},
+			{"text":"The left panel covers the timeline."}
+		]}}]}`, base64.StdEncoding.EncodeToString(testImageData))
+	})
+
+	tests := []struct {
+		name         string
+		saveCaptions bool
+	}{
+		{name: "enabled", saveCaptions: true},
+		{name: "disabled", saveCaptions: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ViperConfig{OutputDir: t.TempDir(), SaveCaptions: tt.saveCaptions}
+			client := newTestImageClient(t, handler, config)
+
+			result, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			captionPath := filepath.Join(config.ImagesDir(), "20251224_103045.caption.md")
+			if !tt.saveCaptions {
+				if result.CaptionPath != "" {
+					t.Errorf("CaptionPath = %s, want empty", result.CaptionPath)
+				}
+				if _, err := os.Stat(captionPath); !os.IsNotExist(err) {
+					t.Errorf("caption file should not be written: %v", err)
+				}
+				return
+			}
+
+			if result.CaptionPath != captionPath {
+				t.Errorf("CaptionPath = %s, want %s", result.CaptionPath, captionPath)
+			}
+			data, err := os.ReadFile(captionPath)
+			if err != nil {
+				t.Fatalf("caption should be saved: %v", err)
+			}
+			want := "Here is an infographic summarizing the research.\n\nThe left panel covers the timeline.\n"
+			if string(data) != want {
+				t.Errorf("caption = %q, want %q", data, want)
+			}
+		})
+	}
+}
+
+func TestGenaiImageClient_Generate_RetriesWithoutImage(t *testing.T) {
+	textOnly := `{"candidates":[{"content":{"parts":[{"text":"Would you like a chart or a diagram?"}]}}]}`
+
+	t.Run("succeeds on retry", func(t *testing.T) {
+		var calls atomic.Int32
+		var lastBody string
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			lastBody = string(body)
+			w.Header().Set("Content-Type", "application/json")
+			if calls.Add(1) == 1 {
+				fmt.Fprint(w, textOnly)
+				return
+			}
+			fmt.Fprint(w, imageResponseJSON(testImageData))
+		})
+
+		config := &ViperConfig{OutputDir: t.TempDir(), ImageRetries: 2}
+		client := newTestImageClient(t, handler, config)
+
+		result, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if calls.Load() != 2 {
+			t.Errorf("calls = %d, want 2", calls.Load())
+		}
+		if !strings.Contains(lastBody, imageRetryNudge) {
+			t.Errorf("retried prompt should contain the nudge: %s", lastBody)
+		}
+		if len(result.ImagePaths) != 1 {
+			t.Errorf("ImagePaths = %v, want 1 image", result.ImagePaths)
+		}
+	})
+
+	t.Run("fails after all retries", func(t *testing.T) {
+		var calls atomic.Int32
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, textOnly)
+		})
+
+		client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir(), ImageRetries: 2})
+
+		_, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+		if !errors.Is(err, ErrNoImageData) {
+			t.Fatalf("Generate() error = %v, want ErrNoImageData", err)
+		}
+		if !strings.Contains(err.Error(), "Would you like a chart or a diagram?") {
+			t.Errorf("error should include the model text: %v", err)
+		}
+		if calls.Load() != 3 {
+			t.Errorf("calls = %d, want 3 (1 attempt + 2 retries)", calls.Load())
+		}
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls atomic.Int32
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			cancel()
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, textOnly)
+		})
+
+		client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir(), ImageRetries: 2})
+
+		_, err := client.Generate(ctx, "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Generate() error = %v, want context.Canceled", err)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("calls = %d, want 1", calls.Load())
+		}
+	})
+}
+
+func TestGenaiImageClient_GenerateVariants_FallbackModel(t *testing.T) {
+	// The primary model responds with the given status, the fallback model succeeds
+	newHandler := func(primaryStatus int, models *[]string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*models = append(*models, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1beta/models/"), ":generateContent"))
+			if strings.Contains(r.URL.Path, "primary-model") {
+				w.WriteHeader(primaryStatus)
+				fmt.Fprintf(w, `{"error":{"code":%d,"message":"failed"}}`, primaryStatus)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, imageResponseJSON(testImageData))
+		})
+	}
+
+	tests := []struct {
+		name          string
+		primaryStatus int
+		fallbackModel string
+		wantModels    []string
+		wantErr       bool
+	}{
+		{name: "not found", primaryStatus: http.StatusNotFound, fallbackModel: "fallback-model", wantModels: []string{"primary-model", "fallback-model"}},
+		{name: "server error", primaryStatus: http.StatusInternalServerError, fallbackModel: "fallback-model", wantModels: []string{"primary-model", "fallback-model"}},
+		{name: "bad request is not retried", primaryStatus: http.StatusBadRequest, fallbackModel: "fallback-model", wantModels: []string{"primary-model"}, wantErr: true},
+		{name: "no fallback configured", primaryStatus: http.StatusNotFound, wantModels: []string{"primary-model"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var models []string
+			config := &ViperConfig{OutputDir: t.TempDir(), FallbackModel: tt.fallbackModel}
+			client := newTestImageClient(t, newHandler(tt.primaryStatus, &models), config)
+
+			result, err := client.GenerateVariants(context.Background(), "prompt", ImageConfig{Model: "primary-model"}, "20251224_103045", 1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateVariants() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(models, ",") != strings.Join(tt.wantModels, ",") {
+				t.Errorf("requested models = %v, want %v", models, tt.wantModels)
+			}
+			if tt.wantErr {
+				return
+			}
+			if result.Model != "fallback-model" || result.FallbackFrom != "primary-model" {
+				t.Errorf("Model = %s, FallbackFrom = %s, want fallback-model from primary-model", result.Model, result.FallbackFrom)
+			}
+		})
+	}
+}
+
+func TestGenaiImageClient_GenerateVariants_FallbackModelFails(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":{"code":503,"message":"overloaded","status":"UNAVAILABLE"}}`)
+	})
+
+	client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir(), FallbackModel: "fallback-model"})
+
+	_, err := client.GenerateVariants(context.Background(), "prompt", ImageConfig{Model: "primary-model"}, "20251224_103045", 1)
+	if err == nil || !strings.Contains(err.Error(), "fallback model fallback-model") {
+		t.Fatalf("GenerateVariants() error = %v, want fallback failure", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("error should wrap the APIError: %v", err)
+	}
+}
+
+func TestGenaiImageClient_Generate_ContentBlocked(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		wantReason     string
+		wantCategories []string
+	}{
+		{
+			name: "prompt blocked",
+			body: `{"promptFeedback":{"blockReason":"SAFETY","safetyRatings":[
+				{"category":"HARM_CATEGORY_DANGEROUS_CONTENT","probability":"HIGH"},
+				{"category":"HARM_CATEGORY_HARASSMENT","probability":"NEGLIGIBLE"}
+			]}}`,
+			wantReason:     "SAFETY",
+			wantCategories: []string{"HARM_CATEGORY_DANGEROUS_CONTENT"},
+		},
+		{
+			name: "candidate finished for safety",
+			body: `{"candidates":[{"finishReason":"PROHIBITED_CONTENT","safetyRatings":[
+				{"category":"HARM_CATEGORY_SEXUALLY_EXPLICIT","probability":"MEDIUM","blocked":true}
+			],"content":{"parts":[]}}]}`,
+			wantReason:     "PROHIBITED_CONTENT",
+			wantCategories: []string{"HARM_CATEGORY_SEXUALLY_EXPLICIT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, tt.body)
+			})
+			// Blocked responses are neither retried nor sent to the fallback model
+			config := &ViperConfig{OutputDir: t.TempDir(), ImageRetries: 2, FallbackModel: "fallback-model"}
+			client := newTestImageClient(t, handler, config)
+
+			_, err := client.GenerateVariants(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045", 1)
+			if !errors.Is(err, ErrContentBlocked) {
+				t.Fatalf("GenerateVariants() error = %v, want ErrContentBlocked", err)
+			}
+			var blockedErr *ContentBlockedError
+			if !errors.As(err, &blockedErr) {
+				t.Fatalf("error should be a ContentBlockedError: %v", err)
+			}
+			if blockedErr.Reason != tt.wantReason {
+				t.Errorf("Reason = %s, want %s", blockedErr.Reason, tt.wantReason)
+			}
+			if strings.Join(blockedErr.Categories, ",") != strings.Join(tt.wantCategories, ",") {
+				t.Errorf("Categories = %v, want %v", blockedErr.Categories, tt.wantCategories)
+			}
+			if calls.Load() != 1 {
+				t.Errorf("calls = %d, want 1", calls.Load())
+			}
+		})
+	}
+}
+
+func TestGenaiImageClient_Generate_SystemInstruction(t *testing.T) {
+	tests := []struct {
+		name        string
+		instruction string
+		want        string
+	}{
+		{name: "configured", instruction: "Use a clean flat design", want: `"systemInstruction":{"parts":[{"text":"Use a clean flat design"}]}`},
+		{name: "not configured", instruction: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				raw = string(body)
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, imageResponseJSON(testImageData))
+			})
+			client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir(), ImageSystemInstruction: tt.instruction})
+
+			if _, err := client.Generate(context.Background(), "the infographic prompt", ImageConfig{Model: "test-model"}, "20251224_103045"); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if tt.want == "" {
+				if strings.Contains(raw, "systemInstruction") {
+					t.Errorf("request body should not contain systemInstruction: %s", raw)
+				}
+				return
+			}
+			if !strings.Contains(raw, tt.want) {
+				t.Errorf("request body should contain %s: %s", tt.want, raw)
+			}
+			// The instruction is sent in addition to the prompt, not instead of it
+			if !strings.Contains(raw, "the infographic prompt") {
+				t.Errorf("request body should still contain the prompt: %s", raw)
+			}
+		})
+	}
+}
+
+func TestGenaiImageClient_Generate_InputImages(t *testing.T) {
+	var request struct {
+		Contents []struct {
+			Parts []struct {
+				Text       string `json:"text"`
+				InlineData struct {
+					MimeType string `json:"mimeType"`
+					Data     string `json:"data"`
+				} `json:"inlineData"`
+			} `json:"parts"`
+		} `json:"contents"`
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, imageResponseJSON(testImageData))
+	})
+	client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir()})
+
+	input := &InputImage{Path: "/in/chart.png", MimeType: "image/png", Data: testImageData}
+	imgConfig := ImageConfig{Model: "test-model", InputImages: []*InputImage{input}}
+	result, err := client.Generate(context.Background(), "Translate all labels", imgConfig, "20251224_103045")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	// The input image comes ahead of the text part
+	parts := request.Contents[0].Parts
+	if len(parts) != 2 {
+		t.Fatalf("parts = %+v, want image and text", parts)
+	}
+	if parts[0].InlineData.MimeType != "image/png" || parts[0].InlineData.Data != base64.StdEncoding.EncodeToString(testImageData) {
+		t.Errorf("first part = %+v, want the input image", parts[0])
+	}
+	if parts[1].Text != "Translate all labels" {
+		t.Errorf("second part text = %q, want the prompt", parts[1].Text)
+	}
+
+	// The recorded request names the input image instead of embedding it
+	if len(result.InputImages) != 1 || result.InputImages[0] != "/in/chart.png" {
+		t.Errorf("InputImages = %v, want [/in/chart.png]", result.InputImages)
+	}
+	recorded, err := os.ReadFile(result.RequestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(recorded), "(input image: /in/chart.png)") {
+		t.Errorf("recorded request should refer to the input image: %s", recorded)
+	}
+}
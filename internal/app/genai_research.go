@@ -2,39 +2,93 @@ package app
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"path/filepath"
 	"strings"
 	"time"
 	"unicode"
 
-	"deepviz/internal/genai/interactions"
+	"github.com/yukiyan/deepviz/internal/genai/interactions"
 )
 
 // ResearchResult holds research result.
 type ResearchResult struct {
-	InteractionID string // Research ID
-	Status        string // Completion status
-	Content       string // Markdown content
-	MarkdownPath  string // Save destination path
-	ResponsePath  string // Raw response save destination
+	InteractionID string   // Research ID
+	Status        string   // Completion status
+	Content       string   // Markdown content
+	MarkdownPath  string   // Save destination path
+	ResponsePath  string   // Raw response save destination
+	SourcesPath   string   // Source list save destination (empty when the research has no sources)
+	FailureReason string   // Reason reported by a failed interaction (empty if none)
+	Thoughts      []string // Thought summaries accumulated so far
+	Sources       []Source // Sources cited or consulted by the research
+
+	body []byte // Raw interaction body of the poll
+}
+
+// transientError marks a polling error that may succeed when retried.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+// isTransientStatus reports whether an HTTP status code indicates a temporary failure.
+func isTransientStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
 }
 
 // GenaiResearchClient is a Deep Research API client.
 type GenaiResearchClient struct {
-	config *ViperConfig
-	logger Logger
-	client *interactions.ClientWithResponses
+	ResearchHooks
+
+	config     *ViperConfig
+	logger     Logger
+	httpClient *http.Client // Client of the generated API client, on the shared transport
+	client     *interactions.ClientWithResponses
+	clock      clock
 }
 
 // NewGenaiResearchClient creates a new GenaiResearchClient.
 func NewGenaiResearchClient(ctx context.Context, config *ViperConfig, logger Logger) (*GenaiResearchClient, error) {
-	baseURL := "https://generativelanguage.googleapis.com"
+	// Deep Research runs on the Interactions API of the Gemini API only
+	if config.adc != nil {
+		return nil, fmt.Errorf("Deep Research: %w", ErrNotSupportedOnVertex)
+	}
+	return newGenaiResearchClient(config, logger, defaultImageBaseURL)
+}
+
+// newGenaiResearchClient creates a GenaiResearchClient sending requests to baseURL.
+func newGenaiResearchClient(config *ViperConfig, logger Logger, baseURL string) (*GenaiResearchClient, error) {
+	httpClient := newHTTPClient(config, config.researchTimeout(), logger)
+	client, err := newInteractionsAPI(baseURL, config, httpClient)
+	if err != nil {
+		return nil, err
+	}
 
+	return &GenaiResearchClient{
+		config:     config,
+		logger:     logger,
+		httpClient: httpClient,
+		client:     client,
+		clock:      realClock{},
+	}, nil
+}
+
+// newInteractionsAPI creates the generated Interactions API client authenticated with the API key.
+func newInteractionsAPI(baseURL string, config *ViperConfig, httpClient *http.Client) (*interactions.ClientWithResponses, error) {
 	client, err := interactions.NewClientWithResponses(baseURL,
+		interactions.WithHTTPClient(httpClient),
 		interactions.WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
 			req.Header.Set("x-goog-api-key", config.APIKey)
 			return nil
@@ -43,12 +97,7 @@ func NewGenaiResearchClient(ctx context.Context, config *ViperConfig, logger Log
 	if err != nil {
 		return nil, fmt.Errorf("failed to create interactions client: %w", err)
 	}
-
-	return &GenaiResearchClient{
-		config: config,
-		logger: logger,
-		client: client,
-	}, nil
+	return client, nil
 }
 
 // sanitizePrompt removes potentially dangerous control characters while preserving valid whitespace.
@@ -68,6 +117,9 @@ func sanitizePrompt(prompt string) string {
 }
 
 // Execute executes Deep Research.
+//
+// If waiting for the result fails, the research is cancelled on the server unless it
+// should be kept for resuming (see keepResearch).
 func (c *GenaiResearchClient) Execute(ctx context.Context, prompt string, timestamp string) (*ResearchResult, error) {
 	// Start research
 	interactionID, err := c.startResearch(ctx, prompt)
@@ -76,53 +128,106 @@ func (c *GenaiResearchClient) Execute(ctx context.Context, prompt string, timest
 	}
 
 	c.logger.Info("Research started", "interaction_id", interactionID)
+	if c.OnStarted != nil {
+		c.OnStarted(interactionID)
+	}
 
-	// Cancel research on failure (defer runs even if ctx is cancelled)
-	var success bool
-	defer func() {
-		if !success {
-			if cancelErr := c.cancelResearch(interactionID); cancelErr != nil {
-				c.logger.Error("Failed to cancel research", "error", cancelErr)
-			}
+	result, err := c.awaitResult(ctx, interactionID, prompt, timestamp)
+	if err != nil {
+		if c.keepResearch(err) {
+			c.logger.Info("Research kept on the server", "interaction_id", interactionID, "resume", "deepviz resume "+interactionID)
+			return nil, &ResumableError{InteractionID: interactionID, Err: err}
 		}
-	}()
 
+		// Cancel research on failure (runs with its own context)
+		if cancelErr := c.cancelResearch(interactionID); cancelErr != nil {
+			c.logger.Error("Failed to cancel research", "error", cancelErr)
+		}
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Resume re-attaches to a running research and waits for its completion.
+func (c *GenaiResearchClient) Resume(ctx context.Context, interactionID string, timestamp string) (*ResearchResult, error) {
+	c.logger.Info("Resuming research", "interaction_id", interactionID)
+
+	result, err := c.awaitResult(ctx, interactionID, "", timestamp)
+	if err != nil {
+		if c.keepResearch(err) {
+			return nil, &ResumableError{InteractionID: interactionID, Err: err}
+		}
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// keepResearch reports whether a research should be kept on the server after err.
+//
+// Research is kept when interrupted, when polling timed out (the server may still finish it),
+// or when KeepOnFailure is enabled.
+func (c *GenaiResearchClient) keepResearch(err error) bool {
+	var interruptedErr *InterruptedError
+	return c.config.KeepOnFailure || errors.As(err, &interruptedErr) || errors.Is(err, ErrPollTimeout)
+}
+
+// awaitResult waits for research completion by polling and saves the result.
+//
+// prompt is recorded in the front matter of the saved markdown (empty when resumed).
+func (c *GenaiResearchClient) awaitResult(ctx context.Context, interactionID, prompt, timestamp string) (*ResearchResult, error) {
 	// Wait for completion by polling
-	result, err := c.pollUntilComplete(ctx, interactionID)
+	snapshots := newPollSnapshots(c.config, timestamp)
+	result, err := c.pollUntilComplete(ctx, interactionID, snapshots)
 	if err != nil {
+		// Keep what the server last reported for inspecting the failure
+		if path, saveErr := snapshots.saveLast(); saveErr != nil {
+			c.logger.Warn("Failed to save last polled interaction", "error", saveErr)
+		} else if path != "" {
+			c.logger.Info("Last polled interaction saved", "path", path)
+		}
+
+		if ctx.Err() != nil {
+			return nil, &InterruptedError{InteractionID: interactionID, Err: err}
+		}
 		return nil, fmt.Errorf("failed to poll research: %w", err)
 	}
 
 	// Save result
-	if err := c.saveResult(result, timestamp); err != nil {
+	if err := c.saveResult(result, prompt, timestamp); err != nil {
 		return nil, fmt.Errorf("failed to save result: %w", err)
 	}
 
-	success = true
 	return result, nil
 }
 
-// startResearch starts a research.
-func (c *GenaiResearchClient) startResearch(ctx context.Context, prompt string) (string, error) {
-	// Sanitize prompt to remove potentially dangerous control characters
-	sanitizedPrompt := sanitizePrompt(prompt)
-
+// buildResearchRequest builds the body of the request starting a research.
+func (c *GenaiResearchClient) buildResearchRequest(prompt string) map[string]interface{} {
 	// Create request body manually to avoid generated code issues with agent_config type
 	// The generated code sets type="deep_research" but API expects "deep-research"
-	requestBodyMap := map[string]interface{}{
-		"input":      sanitizedPrompt,
+	body := map[string]interface{}{
+		"input":      sanitizePrompt(prompt), // Remove potentially dangerous control characters
 		"agent":      c.config.DeepResearchAgent,
 		"background": true,
-		"store":      true,
+		"store":      true, // Background interactions must be stored to be polled and resumed
 		"agent_config": map[string]interface{}{
 			"type":               "deep-research", // API expects hyphen, not underscore
-			"thinking_summaries": "auto",
-		},
-		"tools": []map[string]interface{}{
-			{"type": "google_search"},
-			{"type": "url_context"},
+			"thinking_summaries": cmp.Or(c.config.ThinkingSummaries, string(interactions.ThinkingSummariesAuto)),
 		},
 	}
+	if !c.config.NoResearchTools {
+		body["tools"] = researchToolsBody(c.config.ResearchTools)
+	}
+	return body
+}
+
+// startResearch starts a research.
+func (c *GenaiResearchClient) startResearch(ctx context.Context, prompt string) (string, error) {
+	requestBodyMap := c.buildResearchRequest(prompt)
+	if c.config.NoResearchTools {
+		c.logger.Warn("Research tools are disabled (--no-tools): the agent relies on the prompt only and results differ from a normal research")
+	}
 
 	c.logger.Debug("Sending request", "agent", c.config.DeepResearchAgent)
 
@@ -133,35 +238,32 @@ func (c *GenaiResearchClient) startResearch(ctx context.Context, prompt string)
 	}
 
 	// Trace log request body
-	c.logger.Debug("HTTP Request", "method", "POST", "body", string(bodyJSON))
-
-	// Execute request using WithBody variant to avoid union type issues
-	resp, err := c.client.CreateInteractionWithBodyWithResponse(ctx, "v1beta", "application/json", bytes.NewReader(bodyJSON))
-	if err != nil {
-		return "", fmt.Errorf("failed to create interaction: %w", err)
-	}
+	c.logger.Trace("HTTP Request", "method", "POST", "body", string(bodyJSON))
+
+	// Execute request using WithBody variant to avoid union type issues (retried on 429/503)
+	var resp *interactions.CreateInteractionResponse
+	err = withRetry(ctx, newRetryConfig(c.config, c.clock), c.logger, OpCreateInteraction, func(ctx context.Context) error {
+		var err error
+		resp, err = c.client.CreateInteractionWithBodyWithResponse(ctx, "v1beta", "application/json", bytes.NewReader(bodyJSON))
+		if err != nil {
+			return fmt.Errorf("failed to create interaction: %w", err)
+		}
 
-	// Trace log response (raw body)
-	c.logger.Debug("HTTP Response", "status_code", resp.StatusCode(), "body", string(resp.Body))
+		// Trace log response (raw body)
+		c.logger.Trace("HTTP Response", "status_code", resp.StatusCode(), "body", string(resp.Body))
 
-	c.logger.Debug("Response received", "status_code", resp.StatusCode())
+		c.logger.Debug("Response received", "status_code", resp.StatusCode())
 
-	// Check status code
-	if resp.StatusCode() != http.StatusOK {
-		// Log error details from JSONDefault if available
-		var errorMsg string
-		if resp.JSONDefault != nil && resp.JSONDefault.Error != nil {
-			if resp.JSONDefault.Error.Message != nil {
-				errorMsg = *resp.JSONDefault.Error.Message
-			}
-			if resp.JSONDefault.Error.Code != nil {
-				errorMsg = fmt.Sprintf("code=%s, message=%s", *resp.JSONDefault.Error.Code, errorMsg)
-			}
-		} else {
-			errorMsg = string(resp.Body)
+		// Check status code
+		if resp.StatusCode() != http.StatusOK {
+			apiErr := newAPIError(OpCreateInteraction, resp.StatusCode(), resp.Body)
+			c.logger.Error("API request failed", "status_code", resp.StatusCode(), "error", apiErr.Message)
+			return newRetryableError(apiErr, resp.StatusCode(), resp.HTTPResponse.Header, resp.Body, c.clock.Now())
 		}
-		c.logger.Error("API request failed", "status_code", resp.StatusCode(), "error", errorMsg)
-		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode(), errorMsg)
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
 	// Parse response
@@ -178,25 +280,55 @@ func (c *GenaiResearchClient) startResearch(ctx context.Context, prompt string)
 }
 
 // pollUntilComplete polls until research completes.
-func (c *GenaiResearchClient) pollUntilComplete(ctx context.Context, interactionID string) (*ResearchResult, error) {
-	ticker := time.NewTicker(time.Duration(c.config.PollInterval) * time.Second)
-	defer ticker.Stop()
+//
+// The first status check runs immediately. Subsequent checks back off from
+// PollInterval up to PollMaxInterval, restarting from PollInterval whenever the status changes.
+// Transient errors (network errors, 429 and 5xx) are tolerated up to PollMaxFailures consecutive times.
+// Each polled body is recorded in snapshots (nil records nothing).
+func (c *GenaiResearchClient) pollUntilComplete(ctx context.Context, interactionID string, snapshots *pollSnapshots) (*ResearchResult, error) {
+	backoff := newPollBackoff(
+		time.Duration(c.config.PollInterval)*time.Second,
+		time.Duration(c.config.PollMaxInterval)*time.Second,
+	)
+	started := c.clock.Now()
+	deadline := started.Add(time.Duration(c.config.PollTimeout) * time.Second)
 
-	timeout := time.After(time.Duration(c.config.PollTimeout) * time.Second)
+	progress := reporterOrNop(c.Progress)
+	progress.Start("Researching…", time.Duration(c.config.PollTimeout)*time.Second)
+	defer progress.Stop()
 
+	var lastStatus string
+	var failures int
+	thoughts := newThoughtTracker()
 	for {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-timeout:
-			return nil, fmt.Errorf("polling timeout after %d seconds", c.config.PollTimeout)
-		case <-ticker.C:
-			// Check status
-			result, err := c.checkStatus(ctx, interactionID)
-			if err != nil {
+		// Check status
+		result, err := c.checkStatus(ctx, interactionID)
+		if err != nil {
+			var transient *transientError
+			if !errors.As(err, &transient) || ctx.Err() != nil {
 				return nil, err
 			}
 
+			failures++
+			if failures >= c.config.PollMaxFailures {
+				return nil, fmt.Errorf("giving up after %d consecutive polling failures: %w", failures, err)
+			}
+			c.logger.Debug("Transient polling error", "error", err, "consecutive_failures", failures)
+		} else {
+			failures = 0
+			progress.Status(result.Status)
+
+			if err := snapshots.record(result.body); err != nil {
+				c.logger.Warn("Failed to save poll snapshot", "error", err)
+			}
+
+			for _, summary := range thoughts.update(result.Thoughts) {
+				c.logger.Debug("Thought summary", "summary", summary)
+				if c.OnThought != nil {
+					c.OnThought(summary)
+				}
+			}
+
 			// Return result if completed
 			if result.Status == "completed" {
 				c.logger.Info("Research completed", "interaction_id", interactionID)
@@ -205,10 +337,34 @@ func (c *GenaiResearchClient) pollUntilComplete(ctx context.Context, interaction
 
 			// Return error if failed
 			if result.Status == "failed" {
+				if result.FailureReason != "" {
+					return nil, fmt.Errorf("research failed: %s. Interaction ID: %s", result.FailureReason, interactionID)
+				}
 				return nil, fmt.Errorf("research failed. Interaction ID: %s", interactionID)
 			}
 
-			c.logger.Info("Research in progress", "status", result.Status)
+			// Poll more frequently again when the status changes
+			if result.Status != lastStatus {
+				backoff.Reset()
+				lastStatus = result.Status
+			}
+			if c.OnPoll != nil {
+				c.OnPoll(result.Status, c.clock.Now().Sub(started))
+			}
+		}
+
+		remaining := deadline.Sub(c.clock.Now())
+		if remaining <= 0 {
+			return nil, fmt.Errorf("%w after %d seconds", ErrPollTimeout, c.config.PollTimeout)
+		}
+		delay := min(backoff.Next(), remaining)
+
+		c.logger.Info("Research in progress", "status", lastStatus, "next_poll", delay.String())
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.clock.After(delay):
 		}
 	}
 }
@@ -217,15 +373,24 @@ func (c *GenaiResearchClient) pollUntilComplete(ctx context.Context, interaction
 func (c *GenaiResearchClient) checkStatus(ctx context.Context, interactionID string) (*ResearchResult, error) {
 	resp, err := c.client.GetInteractionByIdWithResponse(ctx, "v1beta", interactionID, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get interaction: %w", err)
+		err = fmt.Errorf("failed to get interaction: %w", err)
+		// A request missing from a replayed cassette fails the same way on every poll
+		if errors.Is(err, ErrUnrecordedRequest) {
+			return nil, err
+		}
+		return nil, &transientError{err: err}
 	}
 
 	// Trace log response (raw body)
-	c.logger.Debug("HTTP Response", "status_code", resp.StatusCode(), "body", string(resp.Body))
+	c.logger.Trace("HTTP Response", "status_code", resp.StatusCode(), "body", string(resp.Body))
 
 	// Check status code
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode(), string(resp.Body))
+		apiErr := newAPIError(OpPoll, resp.StatusCode(), resp.Body)
+		if isTransientStatus(resp.StatusCode()) {
+			return nil, &transientError{err: apiErr}
+		}
+		return nil, apiErr
 	}
 
 	if resp.JSON200 == nil {
@@ -240,24 +405,61 @@ func (c *GenaiResearchClient) checkStatus(ctx context.Context, interactionID str
 		status = string(*interaction.Status)
 	}
 
-	// Extract text content from outputs
+	// Extract text content and thought summaries from outputs
 	var content string
+	var thoughts []string
+	var sources []Source
 	if interaction.Outputs != nil {
+		sources = researchSources(*interaction.Outputs)
 		for _, output := range *interaction.Outputs {
+			if summaries := thoughtSummaries(output); summaries != nil {
+				thoughts = append(thoughts, summaries...)
+				continue
+			}
+			if content != "" {
+				continue
+			}
 			// Content is a union type, try to extract as TextContent
 			textContent, err := output.AsTextContent()
 			if err == nil && textContent.Text != nil {
 				content = *textContent.Text
-				break
 			}
 		}
 	}
 
-	return &ResearchResult{
+	result := &ResearchResult{
 		InteractionID: interactionID,
 		Status:        status,
 		Content:       content,
-	}, nil
+		Thoughts:      thoughts,
+		Sources:       sources,
+		body:          resp.Body,
+	}
+	if status == "failed" {
+		result.FailureReason = interactionFailureReason(resp.Body)
+	}
+
+	return result, nil
+}
+
+// interactionFailureReason extracts the reason carried by a failed interaction, or an empty string.
+//
+// The generated Interaction type has no error field, so the raw body is inspected for an
+// "error" object ({"error": {"code": "...", "message": "..."}}).
+func interactionFailureReason(body []byte) string {
+	var payload struct {
+		Error *struct {
+			Code    json.RawMessage `json:"code"`
+			Message string          `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Error == nil {
+		return ""
+	}
+	if payload.Error.Message != "" {
+		return payload.Error.Message
+	}
+	return strings.Trim(string(payload.Error.Code), `"`)
 }
 
 // cancelResearch cancels a research interaction.
@@ -272,10 +474,10 @@ func (c *GenaiResearchClient) cancelResearch(interactionID string) error {
 	}
 
 	// Trace log response (raw body)
-	c.logger.Debug("HTTP Response", "status_code", resp.StatusCode(), "body", string(resp.Body))
+	c.logger.Trace("HTTP Response", "status_code", resp.StatusCode(), "body", string(resp.Body))
 
 	if resp.StatusCode() != http.StatusOK {
-		return fmt.Errorf("cancel failed with status %d: %s", resp.StatusCode(), string(resp.Body))
+		return newAPIError(OpCancelInteraction, resp.StatusCode(), resp.Body)
 	}
 
 	c.logger.Info("Research cancelled", "interaction_id", interactionID)
@@ -283,18 +485,53 @@ func (c *GenaiResearchClient) cancelResearch(interactionID string) error {
 }
 
 // saveResult saves the research result.
-func (c *GenaiResearchClient) saveResult(result *ResearchResult, timestamp string) error {
+func (c *GenaiResearchClient) saveResult(result *ResearchResult, prompt, timestamp string) error {
 	// Build file path
-	markdownPath := filepath.Join(c.config.ResearchDir(), timestamp+".md")
+	markdownPath := c.config.ResearchPath(timestamp)
+
+	content := result.Content
+	if c.config.AppendSources {
+		content = appendSources(content, result.Sources)
+	}
+	if c.config.MarkdownFrontMatter {
+		var err error
+		frontMatter := newResearchFrontMatter(c.config, result.InteractionID, prompt, c.clock.Now())
+		if content, err = frontMatter.Prepend(content); err != nil {
+			return err
+		}
+	}
 
 	// Save markdown file
-	if err := WriteFile(markdownPath, []byte(result.Content)); err != nil {
+	markdownPath, err := writeArtifact(c.logger, markdownPath, []byte(content), c.config.Overwrite)
+	if err != nil {
 		return fmt.Errorf("failed to write markdown file: %w", err)
 	}
 
 	c.logger.Info("Research saved", "path", markdownPath)
 
-	// Set path to result
+	// Save the source list separately so that it can be used without parsing the report
+	if len(result.Sources) > 0 {
+		sourcesPath, err := writeArtifact(c.logger, c.config.SourcesPath(timestamp), []byte("# Sources\n\n"+formatSources(result.Sources)), c.config.Overwrite)
+		if err != nil {
+			return fmt.Errorf("failed to write sources file: %w", err)
+		}
+		c.logger.Info("Sources saved", "path", sourcesPath, "count", len(result.Sources))
+		result.SourcesPath = sourcesPath
+	} else {
+		c.logger.Debug("Research has no source metadata")
+	}
+
+	// Save the completed interaction as returned by the API for debugging content extraction
+	if !c.config.NoSaveResponses {
+		responsePath, err := writeArtifact(c.logger, c.config.ResearchResponsePath(timestamp), redactBody(result.body, c.config.secrets()...), c.config.Overwrite)
+		if err != nil {
+			return fmt.Errorf("failed to write response file: %w", err)
+		}
+		c.logger.Info("Raw response saved", "path", responsePath)
+		result.ResponsePath = responsePath
+	}
+
+	// Set paths to result
 	result.MarkdownPath = markdownPath
 
 	return nil
@@ -2,114 +2,622 @@ package app
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
+// newTestResearchClient creates a GenaiResearchClient that talks to a mock server using a fake clock.
+func newTestResearchClient(t *testing.T, handler http.Handler, config *ViperConfig) (*GenaiResearchClient, *fakeClock) {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client, err := newGenaiResearchClient(config, NewNullLogger(), srv.URL)
+	if err != nil {
+		t.Fatalf("failed to create genai research client: %v", err)
+	}
+
+	fc := newFakeClock()
+	client.clock = fc
+	return client, fc
+}
+
+// statusSequenceHandler serves the given interaction statuses in order, repeating the last one.
+func statusSequenceHandler(statuses ...string) http.Handler {
+	var mu sync.Mutex
+	var calls int
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		status := statuses[min(calls, len(statuses)-1)]
+		calls++
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"test-id","status":%q,"outputs":[{"type":"text","text":"result"}]}`, status)
+	})
+}
+
 func TestNewGenaiResearchClient(t *testing.T) {
-	// Skip if API key is not set
-	if os.Getenv("GEMINI_API_KEY") == "" {
-		t.Skip("GEMINI_API_KEY not set")
+	config := &ViperConfig{APIKey: testSecretKey, DeepResearchAgent: "deep-research-pro-preview-12-2025"}
+
+	client, err := NewGenaiResearchClient(context.Background(), config, NewNullLogger())
+	if err != nil {
+		t.Fatalf("NewGenaiResearchClient() error = %v", err)
 	}
+	if client.client == nil || client.config != config {
+		t.Errorf("client = %+v, want the interactions client and config", client)
+	}
+	if _, ok := client.clock.(realClock); !ok {
+		t.Errorf("clock = %T, want the real clock", client.clock)
+	}
+}
 
-	ctx := context.Background()
+func TestGenaiResearchClient_Execute(t *testing.T) {
+	srv := &interactionServer{statuses: []string{"in_progress", "in_progress", "completed"}}
 	config := &ViperConfig{
+		OutputDir:         t.TempDir(),
+		APIKey:            testSecretKey,
 		DeepResearchAgent: "deep-research-pro-preview-12-2025",
+		ResearchTools:     []string{"google_search"},
+		ThinkingSummaries: "none",
 		PollInterval:      10,
+		PollMaxInterval:   10,
 		PollTimeout:       600,
+		PollMaxFailures:   3,
 	}
-	logger := NewNullLogger()
+	client, fc := newTestResearchClient(t, srv, config)
+	var started string
+	client.OnStarted = func(interactionID string) { started = interactionID }
 
-	client, err := NewGenaiResearchClient(ctx, config, logger)
+	result, err := client.Execute(context.Background(), "Go features\x00", "20251224_103045")
 	if err != nil {
-		t.Fatalf("failed to create genai research client: %v", err)
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	// The request starts a background research with the configured agent
+	request := srv.Request()
+	if request.Header.Get("x-goog-api-key") != testSecretKey {
+		t.Errorf("x-goog-api-key = %q, want the API key", request.Header.Get("x-goog-api-key"))
+	}
+	if got := request.Header.Get("User-Agent"); got != userAgent("") {
+		t.Errorf("User-Agent = %q, want %q", got, userAgent(""))
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(request.Body, &body); err != nil {
+		t.Fatalf("request body = %s: %v", request.Body, err)
+	}
+	if body["input"] != "Go features" || body["agent"] != config.DeepResearchAgent || body["background"] != true {
+		t.Errorf("request body = %s, want the sanitized prompt, agent and background", request.Body)
+	}
+	agentConfig, _ := body["agent_config"].(map[string]interface{})
+	if agentConfig["type"] != "deep-research" || agentConfig["thinking_summaries"] != "none" {
+		t.Errorf("agent_config = %v, want deep-research with thinking_summaries none", body["agent_config"])
+	}
+	if tools, _ := body["tools"].([]interface{}); len(tools) != 1 || tools[0].(map[string]interface{})["type"] != "google_search" {
+		t.Errorf("tools = %v, want google_search", body["tools"])
 	}
 
-	if client == nil {
-		t.Error("client should not be nil")
+	if started != "test-id" || result.InteractionID != "test-id" || result.Status != "completed" {
+		t.Errorf("started = %q, result = %+v, want the test-id interaction completed", started, result)
+	}
+	if result.Content != "# Result" {
+		t.Errorf("Content = %q, want # Result", result.Content)
+	}
+	if result.MarkdownPath != config.ResearchPath("20251224_103045") {
+		t.Errorf("MarkdownPath = %q, want %q", result.MarkdownPath, config.ResearchPath("20251224_103045"))
+	}
+	data, err := os.ReadFile(result.MarkdownPath)
+	if err != nil || !strings.Contains(string(data), "# Result") {
+		t.Errorf("markdown = %q (%v), want the research", data, err)
+	}
+	if _, err := os.Stat(result.ResponsePath); err != nil {
+		t.Errorf("response file should exist: %v", err)
+	}
+	if len(fc.Waits()) != 2 {
+		t.Errorf("waits = %v, want one per in-progress poll", fc.Waits())
 	}
 }
 
-func TestGenaiResearchClient_Execute(t *testing.T) {
-	// Skip if API key is not set
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		t.Skip("GEMINI_API_KEY not set")
+func TestGenaiResearchClient_Cancel(t *testing.T) {
+	srv := &interactionServer{statuses: []string{"in_progress"}}
+	client, _ := newTestResearchClient(t, srv, &ViperConfig{OutputDir: t.TempDir(), PollInterval: 10, PollTimeout: 600})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A cancelled context never starts a research
+	_, err := client.Execute(ctx, "prompt", "test-timestamp")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Execute() error = %v, want context.Canceled", err)
 	}
+	if request := srv.Request(); request != nil {
+		t.Errorf("request = %+v, want none", request)
+	}
+}
 
-	ctx := context.Background()
-	tmpDir := t.TempDir()
+func TestGenaiResearchClient_PollUntilComplete_FixedInterval(t *testing.T) {
 	config := &ViperConfig{
-		OutputDir:         tmpDir,
-		APIKey:            apiKey,
-		DeepResearchAgent: "deep-research-pro-preview-12-2025",
-		PollInterval:      2,
-		PollTimeout:       60,
+		PollInterval:    10,
+		PollMaxInterval: 10,
+		PollTimeout:     600,
 	}
-	logger := NewNullLogger()
+	client, fc := newTestResearchClient(t, statusSequenceHandler("in_progress", "in_progress", "completed"), config)
 
-	client, err := NewGenaiResearchClient(ctx, config, logger)
+	result, err := client.pollUntilComplete(context.Background(), "test-id", nil)
 	if err != nil {
-		t.Fatalf("failed to create genai research client: %v", err)
+		t.Fatalf("pollUntilComplete() error = %v", err)
+	}
+	if result.Content != "result" {
+		t.Errorf("Content = %q, want result", result.Content)
 	}
 
-	// Test with simple prompt
-	prompt := "Goプログラミング言語の特徴を3つ教えてください"
-	result, err := client.Execute(ctx, prompt, "test-timestamp")
-	if err != nil {
-		t.Fatalf("failed to execute research: %v", err)
+	// First check is immediate, then a fixed 10s cadence
+	waits := fc.Waits()
+	want := []time.Duration{10 * time.Second, 10 * time.Second}
+	if len(waits) != len(want) {
+		t.Fatalf("waits = %v, want %v", waits, want)
+	}
+	for i := range want {
+		if waits[i] != want[i] {
+			t.Errorf("waits[%d] = %v, want %v", i, waits[i], want[i])
+		}
+	}
+}
+
+func TestGenaiResearchClient_PollUntilComplete_OnPoll(t *testing.T) {
+	config := &ViperConfig{
+		PollInterval:    10,
+		PollMaxInterval: 10,
+		PollTimeout:     600,
+	}
+	client, _ := newTestResearchClient(t, statusSequenceHandler("queued", "in_progress", "completed"), config)
+	var statuses []string
+	var elapsed []time.Duration
+	client.OnPoll = func(status string, d time.Duration) {
+		statuses = append(statuses, status)
+		elapsed = append(elapsed, d)
+	}
+
+	if _, err := client.pollUntilComplete(context.Background(), "test-id", nil); err != nil {
+		t.Fatalf("pollUntilComplete() error = %v", err)
 	}
 
-	// Verify result
-	if result == nil {
-		t.Fatal("result should not be nil")
+	// Called for in-progress polls only, with the time spent polling
+	if want := []string{"queued", "in_progress"}; !slices.Equal(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
 	}
+	if want := []time.Duration{0, 10 * time.Second}; !slices.Equal(elapsed, want) {
+		t.Errorf("elapsed = %v, want %v", elapsed, want)
+	}
+}
 
-	if result.Content == "" {
-		t.Error("content should not be empty")
+func TestGenaiResearchClient_PollUntilComplete_Progress(t *testing.T) {
+	config := &ViperConfig{
+		PollInterval:    10,
+		PollMaxInterval: 10,
+		PollTimeout:     600,
 	}
+	client, _ := newTestResearchClient(t, statusSequenceHandler("in_progress", "completed"), config)
+	progress := &recordingReporter{}
+	client.Progress = progress
 
-	if result.MarkdownPath == "" {
-		t.Error("markdown path should not be empty")
+	if _, err := client.pollUntilComplete(context.Background(), "test-id", nil); err != nil {
+		t.Fatalf("pollUntilComplete() error = %v", err)
 	}
 
-	// Verify file was created
-	if _, err := os.Stat(result.MarkdownPath); os.IsNotExist(err) {
-		t.Error("markdown file should be created")
+	want := []string{"start Researching… 10m0s", "status in_progress", "status completed", "stop"}
+	if !slices.Equal(progress.calls, want) {
+		t.Errorf("calls = %v, want %v", progress.calls, want)
 	}
 }
 
-func TestGenaiResearchClient_Cancel(t *testing.T) {
-	// Skip if API key is not set
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		t.Skip("GEMINI_API_KEY not set")
+func TestGenaiResearchClient_PollUntilComplete_Backoff(t *testing.T) {
+	config := &ViperConfig{
+		PollInterval:    10,
+		PollMaxInterval: 60,
+		PollTimeout:     600,
+	}
+	handler := statusSequenceHandler("queued", "queued", "in_progress", "in_progress", "completed")
+	client, fc := newTestResearchClient(t, handler, config)
+
+	if _, err := client.pollUntilComplete(context.Background(), "test-id", nil); err != nil {
+		t.Fatalf("pollUntilComplete() error = %v", err)
+	}
+
+	// The interval grows while the status is unchanged and resets when it changes
+	waits := fc.Waits()
+	want := []time.Duration{10 * time.Second, 15 * time.Second, 10 * time.Second, 15 * time.Second}
+	if len(waits) != len(want) {
+		t.Fatalf("waits = %v, want %v", waits, want)
+	}
+	for i := range want {
+		low := time.Duration(float64(want[i]) * 0.9)
+		high := time.Duration(float64(want[i]) * 1.1)
+		if waits[i] < low || waits[i] > high {
+			t.Errorf("waits[%d] = %v, want within [%v, %v]", i, waits[i], low, high)
+		}
 	}
+}
 
-	ctx := context.Background()
-	tmpDir := t.TempDir()
+func TestGenaiResearchClient_PollUntilComplete_Timeout(t *testing.T) {
 	config := &ViperConfig{
-		OutputDir:         tmpDir,
-		APIKey:            apiKey,
-		DeepResearchAgent: "deep-research-pro-preview-12-2025",
-		PollInterval:      2,
-		PollTimeout:       60,
+		PollInterval:    10,
+		PollMaxInterval: 10,
+		PollTimeout:     25,
+	}
+	client, fc := newTestResearchClient(t, statusSequenceHandler("in_progress"), config)
+
+	_, err := client.pollUntilComplete(context.Background(), "test-id", nil)
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("pollUntilComplete() error = %v, want timeout", err)
+	}
+
+	// The last wait is shortened so that the final check happens at the deadline
+	waits := fc.Waits()
+	want := []time.Duration{10 * time.Second, 10 * time.Second, 5 * time.Second}
+	if len(waits) != len(want) {
+		t.Fatalf("waits = %v, want %v", waits, want)
 	}
-	logger := NewNullLogger()
+	for i := range want {
+		if waits[i] != want[i] {
+			t.Errorf("waits[%d] = %v, want %v", i, waits[i], want[i])
+		}
+	}
+}
+
+// scriptedHandler serves the given HTTP status codes in order before falling back to a completed interaction.
+func scriptedHandler(codes ...int) (http.Handler, *int) {
+	var mu sync.Mutex
+	var calls int
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		call := calls
+		calls++
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if call < len(codes) {
+			w.WriteHeader(codes[call])
+			fmt.Fprint(w, `{"error":{"message":"scripted error"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"test-id","status":"completed","outputs":[{"type":"text","text":"result"}]}`)
+	}), &calls
+}
+
+func TestGenaiResearchClient_PollUntilComplete_TransientErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		codes     []int
+		wantErr   bool
+		wantCalls int
+	}{
+		{
+			name:      "recovers from 500s",
+			codes:     []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			wantErr:   false,
+			wantCalls: 3,
+		},
+		{
+			name:      "recovers from 429 and 503",
+			codes:     []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+			wantErr:   false,
+			wantCalls: 3,
+		},
+		{
+			name:      "gives up after consecutive failures",
+			codes:     []int{500, 500, 500, 500, 500},
+			wantErr:   true,
+			wantCalls: 5,
+		},
+		{
+			name:      "fails immediately on 404",
+			codes:     []int{http.StatusNotFound},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ViperConfig{
+				PollInterval:    10,
+				PollMaxInterval: 10,
+				PollTimeout:     600,
+				PollMaxFailures: 5,
+			}
+			handler, calls := scriptedHandler(tt.codes...)
+			client, _ := newTestResearchClient(t, handler, config)
+
+			result, err := client.pollUntilComplete(context.Background(), "test-id", nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pollUntilComplete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && result.Status != "completed" {
+				t.Errorf("Status = %s, want completed", result.Status)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", *calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// interactionServer is a mock Interactions API recording the create and cancel requests.
+type interactionServer struct {
+	mu        sync.Mutex
+	statuses  []string
+	pollError int // HTTP status returned by every poll instead of statuses (0 for none)
+	polls     int
+	cancelled bool
+	request   *recordedRequest
+	onPoll    func()
+}
+
+// recordedRequest is a request received by a mock server.
+type recordedRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+func (s *interactionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/cancel"):
+		s.mu.Lock()
+		s.cancelled = true
+		s.mu.Unlock()
+		fmt.Fprint(w, `{"id":"test-id","status":"cancelled"}`)
+	case r.Method == http.MethodPost:
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.request = &recordedRequest{Header: r.Header.Clone(), Body: body}
+		s.mu.Unlock()
+		fmt.Fprint(w, `{"id":"test-id","status":"in_progress"}`)
+	case s.pollError != 0:
+		w.WriteHeader(s.pollError)
+		fmt.Fprint(w, `{"error":{"code":"invalid_argument","message":"Invalid interaction"}}`)
+	default:
+		s.mu.Lock()
+		status := s.statuses[min(s.polls, len(s.statuses)-1)]
+		s.polls++
+		onPoll := s.onPoll
+		s.mu.Unlock()
 
-	client, err := NewGenaiResearchClient(ctx, config, logger)
+		if onPoll != nil {
+			onPoll()
+		}
+		fmt.Fprintf(w, `{"id":"test-id","status":%q,"outputs":[{"type":"text","text":"# Result"}]}`, status)
+	}
+}
+
+// Request returns the request that created the interaction (nil if none).
+func (s *interactionServer) Request() *recordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.request
+}
+
+func (s *interactionServer) Cancelled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cancelled
+}
+
+func TestGenaiResearchClient_Execute_InterruptKeepsResearch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := &interactionServer{statuses: []string{"in_progress"}, onPoll: cancel}
+	config := &ViperConfig{
+		OutputDir:       t.TempDir(),
+		PollInterval:    10,
+		PollMaxInterval: 10,
+		PollTimeout:     600,
+	}
+	client, _ := newTestResearchClient(t, srv, config)
+
+	_, err := client.Execute(ctx, "prompt", "test-timestamp")
+
+	var interruptedErr *InterruptedError
+	if !errors.As(err, &interruptedErr) {
+		t.Fatalf("error = %v, want InterruptedError", err)
+	}
+	if interruptedErr.InteractionID != "test-id" {
+		t.Errorf("InteractionID = %s, want test-id", interruptedErr.InteractionID)
+	}
+	if srv.Cancelled() {
+		t.Error("research should not be cancelled when interrupted")
+	}
+}
+
+func TestGenaiResearchClient_Resume(t *testing.T) {
+	srv := &interactionServer{statuses: []string{"in_progress", "completed"}}
+	config := &ViperConfig{
+		OutputDir:       t.TempDir(),
+		PollInterval:    10,
+		PollMaxInterval: 10,
+		PollTimeout:     600,
+	}
+	client, _ := newTestResearchClient(t, srv, config)
+
+	result, err := client.Resume(context.Background(), "test-id", "test-timestamp")
 	if err != nil {
-		t.Fatalf("failed to create genai research client: %v", err)
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if result.InteractionID != "test-id" {
+		t.Errorf("InteractionID = %s, want test-id", result.InteractionID)
 	}
 
-	// Cancel context
-	ctx, cancel := context.WithCancel(ctx)
-	cancel()
+	data, err := os.ReadFile(result.MarkdownPath)
+	if err != nil {
+		t.Fatalf("failed to read markdown: %v", err)
+	}
+	if string(data) != "# Result" {
+		t.Errorf("markdown = %q, want %q", data, "# Result")
+	}
+}
+
+func TestGenaiResearchClient_Execute_CancelOnFailure(t *testing.T) {
+	tests := []struct {
+		name          string
+		statuses      []string
+		pollError     int
+		outputDir     string
+		pollTimeout   int
+		keepOnFailure bool
+		wantCancelled bool
+		wantResumable bool
+	}{
+		{
+			name:          "research failed is cancelled",
+			statuses:      []string{"failed"},
+			pollTimeout:   600,
+			wantCancelled: true,
+		},
+		{
+			name:          "research failed is kept with keep-on-failure",
+			statuses:      []string{"failed"},
+			pollTimeout:   600,
+			keepOnFailure: true,
+			wantResumable: true,
+		},
+		{
+			name:          "save failure is cancelled",
+			statuses:      []string{"completed"},
+			outputDir:     "/dev/null/invalid",
+			pollTimeout:   600,
+			wantCancelled: true,
+		},
+		{
+			name:          "save failure is kept with keep-on-failure",
+			statuses:      []string{"completed"},
+			outputDir:     "/dev/null/invalid",
+			pollTimeout:   600,
+			keepOnFailure: true,
+			wantResumable: true,
+		},
+		{
+			name:          "polling error is cancelled",
+			pollError:     http.StatusBadRequest,
+			pollTimeout:   600,
+			wantCancelled: true,
+		},
+		{
+			name:          "polling timeout is kept by default",
+			statuses:      []string{"in_progress"},
+			pollTimeout:   30,
+			wantResumable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &interactionServer{statuses: tt.statuses, pollError: tt.pollError}
+			outputDir := tt.outputDir
+			if outputDir == "" {
+				outputDir = t.TempDir()
+			}
+			config := &ViperConfig{
+				OutputDir:       outputDir,
+				PollInterval:    10,
+				PollMaxInterval: 10,
+				PollTimeout:     tt.pollTimeout,
+				KeepOnFailure:   tt.keepOnFailure,
+			}
+			client, _ := newTestResearchClient(t, srv, config)
+
+			_, err := client.Execute(context.Background(), "prompt", "test-timestamp")
+			if err == nil {
+				t.Fatal("Execute() should return error")
+			}
+			if srv.Cancelled() != tt.wantCancelled {
+				t.Errorf("cancelled = %v, want %v", srv.Cancelled(), tt.wantCancelled)
+			}
+
+			var resumableErr *ResumableError
+			if errors.As(err, &resumableErr) != tt.wantResumable {
+				t.Errorf("resumable = %v, want %v (error: %v)", !tt.wantResumable, tt.wantResumable, err)
+			}
+		})
+	}
+}
+
+func TestGenaiResearchClient_PollUntilComplete_FailureReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{
+			name:    "error message",
+			body:    `{"id":"test-id","status":"failed","error":{"code":"SAFETY","message":"The request was blocked for safety reasons"}}`,
+			wantErr: "research failed: The request was blocked for safety reasons. Interaction ID: test-id",
+		},
+		{
+			name:    "error code only",
+			body:    `{"id":"test-id","status":"failed","error":{"code":"RESOURCE_EXHAUSTED"}}`,
+			wantErr: "research failed: RESOURCE_EXHAUSTED. Interaction ID: test-id",
+		},
+		{
+			name:    "no reason",
+			body:    `{"id":"test-id","status":"failed"}`,
+			wantErr: "research failed. Interaction ID: test-id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, tt.body)
+			})
+			client, _ := newTestResearchClient(t, handler, &ViperConfig{PollInterval: 10, PollMaxInterval: 10, PollTimeout: 600})
+
+			_, err := client.pollUntilComplete(context.Background(), "test-id", nil)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("pollUntilComplete() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
 
-	// Execute with cancelled context
-	prompt := "長いリサーチタスク"
-	_, err = client.Execute(ctx, prompt, "test-timestamp")
-	if err == nil {
-		t.Error("should return error when context is cancelled")
+// TestGenaiResearchClient_AwaitResult_SavesResponse tests that the completed interaction is saved as raw JSON.
+func TestGenaiResearchClient_AwaitResult_SavesResponse(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir(), PollInterval: 10, PollMaxInterval: 10, PollTimeout: 600, PollMaxFailures: 3}
+	client, _ := newTestResearchClient(t, statusSequenceHandler("in_progress", "completed"), config)
+
+	result, err := client.awaitResult(context.Background(), "test-id", "", "20251224_103045")
+	if err != nil {
+		t.Fatalf("awaitResult() error = %v", err)
+	}
+
+	wantPath := filepath.Join(config.ResponsesDir(), "20251224_103045_research.json")
+	if result.ResponsePath != wantPath {
+		t.Errorf("ResponsePath = %q, want %q", result.ResponsePath, wantPath)
+	}
+	data, err := os.ReadFile(result.ResponsePath)
+	if err != nil {
+		t.Fatalf("response file should exist: %v", err)
+	}
+	var interaction map[string]interface{}
+	if err := json.Unmarshal(data, &interaction); err != nil {
+		t.Fatalf("response file should contain valid JSON: %v", err)
+	}
+	if interaction["status"] != "completed" {
+		t.Errorf("status = %v, want the completed interaction", interaction["status"])
 	}
 }
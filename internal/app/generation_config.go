@@ -0,0 +1,156 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// generationConfigFlagKeys maps flags to the generationConfig keys they own.
+//
+// An explicitly set flag takes precedence over the same key in the generation config overrides.
+var generationConfigFlagKeys = map[string][]string{
+	"aspect-ratio": {"imageConfig", "aspectRatio"},
+	"image-size":   {"imageConfig", "imageSize"},
+	"candidates":   {"candidateCount"},
+	"seed":         {"seed"},
+	"temperature":  {"temperature"},
+	"top-p":        {"topP"},
+	"top-k":        {"topK"},
+}
+
+// ParseGenerationConfig parses generationConfig overrides given as a JSON object.
+//
+// An empty string returns nil (no overrides).
+func ParseGenerationConfig(text string) (map[string]any, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, fmt.Errorf("invalid generation config: %w", err)
+	}
+	overrides, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid generation config: must be a JSON object")
+	}
+	return overrides, nil
+}
+
+// generationConfigFromViper returns the generation_config overrides of v, given as a JSON object
+// (e.g., in DEEPVIZ_GENERATION_CONFIG) or as a YAML map in the config file. Viper lowercases the
+// keys of maps, so a map is read again from the config file to keep the camelCase keys of the API.
+func generationConfigFromViper(v *viper.Viper) (map[string]any, error) {
+	switch value := v.Get("generation_config").(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return ParseGenerationConfig(value)
+	case map[string]any:
+		if path := v.ConfigFileUsed(); path != "" {
+			overrides, err := generationConfigFromFile(path)
+			if err != nil || overrides != nil {
+				return overrides, err
+			}
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("invalid generation config: must be a map or a JSON object, got %T", value)
+	}
+}
+
+// generationConfigFromFile returns the generation_config map of a YAML config file, or nil when it
+// has none.
+func generationConfigFromFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid generation config: %w", err)
+	}
+	var file struct {
+		GenerationConfig map[string]any `yaml:"generation_config"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid generation config: %w", err)
+	}
+	return file.GenerationConfig, nil
+}
+
+// ValidateSampling validates the optional sampling parameters of image requests.
+func ValidateSampling(config *ViperConfig) error {
+	if t := config.Temperature; t != nil && (*t < 0 || *t > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %g", *t)
+	}
+	if p := config.TopP; p != nil && (*p < 0 || *p > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1, got %g", *p)
+	}
+	if k := config.TopK; k != nil && *k < 1 {
+		return fmt.Errorf("top_k must be at least 1, got %d", *k)
+	}
+	return nil
+}
+
+// addSampling adds the sampling parameters that are set to generationConfig.
+func addSampling(generationConfig map[string]any, config *ViperConfig) {
+	if config.Seed != nil {
+		generationConfig["seed"] = *config.Seed
+	}
+	if config.Temperature != nil {
+		generationConfig["temperature"] = *config.Temperature
+	}
+	if config.TopP != nil {
+		generationConfig["topP"] = *config.TopP
+	}
+	if config.TopK != nil {
+		generationConfig["topK"] = *config.TopK
+	}
+}
+
+// mergeGenerationConfig deep-merges src over dst and returns dst.
+//
+// Nested objects are merged recursively; any other value in src replaces the one in dst.
+func mergeGenerationConfig(dst, src map[string]any) map[string]any {
+	if dst == nil {
+		dst = make(map[string]any, len(src))
+	}
+	for key, srcValue := range src {
+		srcMap, srcIsMap := srcValue.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap {
+			dst[key] = mergeGenerationConfig(dstMap, srcMap)
+			continue
+		}
+		if srcIsMap {
+			// Copy so that later merges never modify the overrides
+			dst[key] = mergeGenerationConfig(nil, srcMap)
+			continue
+		}
+		dst[key] = srcValue
+	}
+	return dst
+}
+
+// removeFlagOwnedKeys removes the generationConfig keys owned by explicitly set flags from overrides.
+func removeFlagOwnedKeys(overrides map[string]any, flagSet func(name string) bool) {
+	for flag, path := range generationConfigFlagKeys {
+		if !flagSet(flag) {
+			continue
+		}
+		parent := overrides
+		for _, key := range path[:len(path)-1] {
+			next, ok := parent[key].(map[string]any)
+			if !ok {
+				parent = nil
+				break
+			}
+			parent = next
+		}
+		if parent != nil {
+			delete(parent, path[len(path)-1])
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseGenerationConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    map[string]any
+		wantErr bool
+	}{
+		{name: "empty", text: "", want: nil},
+		{name: "object", text: `{"temperature": 0.5}`, want: map[string]any{"temperature": 0.5}},
+		{name: "array", text: `[1, 2]`, wantErr: true},
+		{name: "string", text: `"text"`, wantErr: true},
+		{name: "invalid JSON", text: `{"temperature":`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGenerationConfig(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGenerationConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGenerationConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeGenerationConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		dst  string
+		src  string
+		want string
+	}{
+		{
+			name: "adds new keys",
+			dst:  `{"responseModalities": ["TEXT", "IMAGE"]}`,
+			src:  `{"thinkingConfig": {"includeThoughts": true}}`,
+			want: `{"responseModalities": ["TEXT", "IMAGE"], "thinkingConfig": {"includeThoughts": true}}`,
+		},
+		{
+			name: "merges nested objects",
+			dst:  `{"imageConfig": {"aspectRatio": "16:9", "imageSize": "2K"}}`,
+			src:  `{"imageConfig": {"aspectRatio": "1:1"}}`,
+			want: `{"imageConfig": {"aspectRatio": "1:1", "imageSize": "2K"}}`,
+		},
+		{
+			name: "replaces arrays",
+			dst:  `{"responseModalities": ["TEXT", "IMAGE"]}`,
+			src:  `{"responseModalities": ["IMAGE"]}`,
+			want: `{"responseModalities": ["IMAGE"]}`,
+		},
+		{
+			name: "replaces non-object with object",
+			dst:  `{"imageConfig": "none"}`,
+			src:  `{"imageConfig": {"imageSize": "4K"}}`,
+			want: `{"imageConfig": {"imageSize": "4K"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst, src, want := jsonObject(t, tt.dst), jsonObject(t, tt.src), jsonObject(t, tt.want)
+			srcCopy := fmt.Sprint(src)
+
+			got := mergeGenerationConfig(dst, src)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("mergeGenerationConfig() = %v, want %v", got, want)
+			}
+			if fmt.Sprint(src) != srcCopy {
+				t.Errorf("mergeGenerationConfig() modified src: %v", src)
+			}
+		})
+	}
+}
+
+// jsonObject decodes a JSON object for test fixtures.
+func jsonObject(t *testing.T, text string) map[string]any {
+	t.Helper()
+
+	var v map[string]any
+	if err := json.Unmarshal([]byte(text), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestRemoveFlagOwnedKeys(t *testing.T) {
+	overrides := map[string]any{
+		"candidateCount": 2.0,
+		"temperature":    0.5,
+		"imageConfig":    map[string]any{"aspectRatio": "1:1", "imageSize": "4K"},
+	}
+	changed := map[string]bool{"aspect-ratio": true, "candidates": true}
+
+	removeFlagOwnedKeys(overrides, func(name string) bool { return changed[name] })
+
+	want := map[string]any{
+		"temperature": 0.5,
+		"imageConfig": map[string]any{"imageSize": "4K"},
+	}
+	if !reflect.DeepEqual(overrides, want) {
+		t.Errorf("overrides = %v, want %v", overrides, want)
+	}
+}
+
+func TestGenaiImageClient_Generate_GenerationConfig(t *testing.T) {
+	var requestBody struct {
+		GenerationConfig map[string]any `json:"generationConfig"`
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &requestBody); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, imageResponseJSON(testImageData))
+	})
+
+	overrides, err := ParseGenerationConfig(`{"thinkingConfig": {"includeThoughts": false}, "imageConfig": {"imageSize": "4K"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir(), GenerationConfig: overrides})
+
+	imgConfig := ImageConfig{Model: "test-model", AspectRatio: "16:9", ImageSize: "2K"}
+	if _, err := client.Generate(context.Background(), "prompt", imgConfig, "20251224_103045"); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	want := map[string]any{
+		"responseModalities": []any{"TEXT", "IMAGE"},
+		"imageConfig":        map[string]any{"aspectRatio": "16:9", "imageSize": "4K"},
+		"thinkingConfig":     map[string]any{"includeThoughts": false},
+	}
+	if !reflect.DeepEqual(requestBody.GenerationConfig, want) {
+		t.Errorf("generationConfig = %v, want %v", requestBody.GenerationConfig, want)
+	}
+}
+
+func TestValidateSampling(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *ViperConfig
+		wantErr bool
+	}{
+		{name: "unset", config: &ViperConfig{}},
+		{name: "valid", config: &ViperConfig{Seed: ptr(42), Temperature: ptr(0.4), TopP: ptr(1.0), TopK: ptr(40)}},
+		{name: "zero temperature", config: &ViperConfig{Temperature: ptr(0.0)}},
+		{name: "temperature too high", config: &ViperConfig{Temperature: ptr(2.5)}, wantErr: true},
+		{name: "negative temperature", config: &ViperConfig{Temperature: ptr(-0.1)}, wantErr: true},
+		{name: "top_p too high", config: &ViperConfig{TopP: ptr(1.1)}, wantErr: true},
+		{name: "top_k zero", config: &ViperConfig{TopK: ptr(0)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateSampling(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSampling() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenaiImageClient_Generate_Sampling(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *ViperConfig
+		want   map[string]any
+	}{
+		{
+			name:   "unset values are omitted",
+			config: &ViperConfig{},
+			want:   map[string]any{},
+		},
+		{
+			name:   "set values are sent",
+			config: &ViperConfig{Seed: ptr(42), Temperature: ptr(0.0), TopP: ptr(0.9), TopK: ptr(40)},
+			want:   map[string]any{"seed": 42.0, "temperature": 0.0, "topP": 0.9, "topK": 40.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, imageResponseJSON(testImageData))
+			})
+			tt.config.OutputDir = t.TempDir()
+			client := newTestImageClient(t, handler, tt.config)
+
+			result, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			// The saved request records the parameters used
+			data, err := os.ReadFile(result.RequestPath)
+			if err != nil {
+				t.Fatalf("request should be saved: %v", err)
+			}
+			var request struct {
+				GenerationConfig map[string]any `json:"generationConfig"`
+			}
+			if err := json.Unmarshal(data, &request); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range []string{"seed", "temperature", "topP", "topK"} {
+				got, ok := request.GenerationConfig[key]
+				want, wantOK := tt.want[key]
+				if ok != wantOK || got != want {
+					t.Errorf("generationConfig[%s] = %v (present %v), want %v (present %v)", key, got, ok, want, wantOK)
+				}
+			}
+		})
+	}
+}
+
+func TestNewViperConfig_GenerationConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     string
+		want    map[string]any
+		wantErr bool
+	}{
+		{name: "unset", want: nil},
+		{
+			name:    "YAML map",
+			content: "generation_config:\n  thinkingConfig:\n    includeThoughts: false\n  candidateCount: 2\n",
+			want:    map[string]any{"thinkingConfig": map[string]any{"includeThoughts": false}, "candidateCount": 2},
+		},
+		{
+			name:    "JSON string",
+			content: "generation_config: '{\"thinkingConfig\": {\"includeThoughts\": false}}'\n",
+			want:    map[string]any{"thinkingConfig": map[string]any{"includeThoughts": false}},
+		},
+		{
+			name:    "environment",
+			content: "generation_config:\n  topK: 40\n",
+			env:     `{"topP": 0.9}`,
+			want:    map[string]any{"topP": 0.9},
+		},
+		{name: "list", content: "generation_config: [1, 2]\n", wantErr: true},
+		{name: "number", content: "generation_config: 3\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("DEEPVIZ_GENERATION_CONFIG", tt.env)
+			}
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := NewViperConfig(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewViperConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(config.GenerationConfig, tt.want) {
+				t.Errorf("GenerationConfig = %#v, want %#v", config.GenerationConfig, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// historyPromptExcerptLength is the maximum number of characters of the prompt kept in the history.
+const historyPromptExcerptLength = 80
+
+// ErrNoHistory is returned when no run has been recorded yet.
+var ErrNoHistory = errors.New("no runs recorded yet")
+
+// HistoryEntry is a completed run recorded in the history ledger.
+type HistoryEntry struct {
+	Timestamp            string    `json:"timestamp"`
+	FinishedAt           time.Time `json:"finished_at"`
+	Prompt               string    `json:"prompt"`                           // Prompt excerpt
+	ResearchPath         string    `json:"research_path,omitempty"`          // Empty in ImageOnly mode
+	ResearchResponsePath string    `json:"research_response_path,omitempty"` // Raw interaction of the research
+	ImagePath            string    `json:"image_path,omitempty"`             // Empty in ResearchOnly mode
+	ImagePaths           []string  `json:"image_paths,omitempty"`            // All images when several were generated
+	Style                string    `json:"style,omitempty"`                  // Style preset of the image
+	ThinkingSummaries    string    `json:"thinking_summaries,omitempty"`     // Thinking summaries setting of the research
+	Tags                 []string  `json:"tags,omitempty"`
+}
+
+// RunHistory is an append-only ledger of completed runs stored as JSON Lines.
+type RunHistory struct {
+	path string
+}
+
+// NewRunHistory creates a new RunHistory backed by the file at path.
+func NewRunHistory(path string) *RunHistory {
+	return &RunHistory{path: path}
+}
+
+// Append adds an entry to the ledger.
+//
+// Each entry is written with a single write to a file opened in append mode,
+// so entries from runs finishing at the same time are never interleaved.
+func (h *RunHistory) Append(entry *HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	if err := EnsureDir(filepath.Dir(h.path)); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close history file: %w", err)
+	}
+
+	return nil
+}
+
+// Load returns all entries in the order they were recorded.
+//
+// Malformed lines are skipped.
+func (h *RunHistory) Load() ([]*HistoryEntry, error) {
+	f, err := os.Open(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []*HistoryEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Last returns the most recent entry, or ErrNoHistory if the ledger is empty.
+func (h *RunHistory) Last() (*HistoryEntry, error) {
+	entries, err := h.Load()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrNoHistory
+	}
+	return entries[len(entries)-1], nil
+}
+
+// promptExcerpt returns the first line-folded characters of a prompt for the history.
+func promptExcerpt(prompt string) string {
+	excerpt := strings.Join(strings.Fields(prompt), " ")
+	runes := []rune(excerpt)
+	if len(runes) > historyPromptExcerptLength {
+		return string(runes[:historyPromptExcerptLength]) + "..."
+	}
+	return excerpt
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRunHistory_AppendAndLast(t *testing.T) {
+	history := NewRunHistory(filepath.Join(t.TempDir(), "state", "history.jsonl"))
+
+	if _, err := history.Last(); !errors.Is(err, ErrNoHistory) {
+		t.Fatalf("Last() error = %v, want ErrNoHistory", err)
+	}
+
+	first := &HistoryEntry{Timestamp: "20251224_100000", Prompt: "first", ImagePath: "/out/images/20251224_100000.png"}
+	second := &HistoryEntry{Timestamp: "20251224_110000", Prompt: "second", ResearchPath: "/out/research/20251224_110000.md"}
+	for _, entry := range []*HistoryEntry{first, second} {
+		if err := history.Append(entry); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	entries, err := history.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Load() = %d entries, want 2", len(entries))
+	}
+
+	last, err := history.Last()
+	if err != nil {
+		t.Fatalf("Last() error = %v", err)
+	}
+	if last.Timestamp != second.Timestamp || last.ResearchPath != second.ResearchPath || last.ImagePath != "" {
+		t.Errorf("Last() = %+v, want %+v", last, second)
+	}
+}
+
+func TestRunHistory_ConcurrentAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	const runs = 50
+	var wg sync.WaitGroup
+	for i := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each run uses its own RunHistory like separate processes would
+			entry := &HistoryEntry{
+				Timestamp: fmt.Sprintf("run-%02d", i),
+				Prompt:    strings.Repeat("x", 1000),
+			}
+			if err := NewRunHistory(path).Append(entry); err != nil {
+				t.Errorf("Append() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := NewRunHistory(path).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(entries) != runs {
+		t.Fatalf("Load() = %d entries, want %d (entries were interleaved)", len(entries), runs)
+	}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		seen[entry.Timestamp] = true
+	}
+	if len(seen) != runs {
+		t.Errorf("got %d distinct entries, want %d", len(seen), runs)
+	}
+}
+
+func TestRunHistory_LoadSkipsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	data := `{"timestamp":"20251224_100000"}
+not json
+{"timestamp":"20251224_110000"}
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := NewRunHistory(path).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Load() = %d entries, want 2", len(entries))
+	}
+}
+
+func TestPromptExcerpt(t *testing.T) {
+	tests := []struct {
+		name   string
+		prompt string
+		want   string
+	}{
+		{
+			name:   "short prompt",
+			prompt: "Research AI trends",
+			want:   "Research AI trends",
+		},
+		{
+			name:   "whitespace is folded",
+			prompt: "Research\n\n  AI\ttrends\n",
+			want:   "Research AI trends",
+		},
+		{
+			name:   "long prompt is truncated",
+			prompt: strings.Repeat("あ", historyPromptExcerptLength+10),
+			want:   strings.Repeat("あ", historyPromptExcerptLength) + "...",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promptExcerpt(tt.prompt); got != tt.want {
+				t.Errorf("promptExcerpt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"bytes"
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// htmlMarkdown converts markdown to HTML: CommonMark with the pipe tables and strikethrough of
+// GitHub. Raw HTML is not rendered (goldmark replaces it with a comment) and links other than
+// http, https, mailto and relative ones are replaced by their text, so the output is safe to embed
+// in a page.
+var htmlMarkdown = goldmark.New(
+	goldmark.WithExtensions(extension.Table, extension.Strikethrough),
+	goldmark.WithParserOptions(parser.WithASTTransformers(util.Prioritized(unsafeLinkRemover{}, 100))),
+)
+
+// renderHTMLMarkdown renders markdown as HTML (see htmlMarkdown).
+func renderHTMLMarkdown(markdown string) string {
+	var buf bytes.Buffer
+	if err := htmlMarkdown.Convert([]byte(markdown), &buf); err != nil {
+		// Only writing to the buffer can fail: fall back to the escaped text
+		return "<pre>" + html.EscapeString(markdown) + "</pre>\n"
+	}
+	return buf.String()
+}
+
+// unsafeLinkRemover replaces the links with an unsafe URL (see safeLinkURL) by their text.
+type unsafeLinkRemover struct{}
+
+func (unsafeLinkRemover) Transform(document *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var unsafe []ast.Node
+	ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch link := node.(type) {
+		case *ast.Link:
+			if !safeLinkURL(string(link.Destination)) {
+				unsafe = append(unsafe, link)
+			}
+		case *ast.AutoLink:
+			if !safeLinkURL(string(link.URL(source))) {
+				unsafe = append(unsafe, link)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, link := range unsafe {
+		parent := link.Parent()
+		if autoLink, ok := link.(*ast.AutoLink); ok {
+			parent.ReplaceChild(parent, link, ast.NewString(autoLink.Label(source)))
+			continue
+		}
+		for child := link.FirstChild(); child != nil; child = link.FirstChild() {
+			parent.InsertBefore(parent, link, child)
+		}
+		parent.RemoveChild(parent, link)
+	}
+}
+
+// safeLinkURL reports whether a link URL can be kept in HTML output (no javascript: and the like).
+func safeLinkURL(url string) bool {
+	scheme, _, ok := strings.Cut(url, ":")
+	if !ok || strings.ContainsAny(scheme, "/?#") {
+		return true
+	}
+	switch strings.ToLower(scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHTMLMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "heading and paragraph",
+			markdown: "# AI Trends\n\nFirst line\nsecond line",
+			want:     "<h1>AI Trends</h1>\n<p>First line\nsecond line</p>\n",
+		},
+		{
+			name:     "inline markup",
+			markdown: "**Bold**, *italic*, `a < b` and [Go](https://go.dev)",
+			want:     `<p><strong>Bold</strong>, <em>italic</em>, <code>a &lt; b</code> and <a href="https://go.dev">Go</a></p>` + "\n",
+		},
+		{
+			name:     "html block",
+			markdown: "<script>alert(1)</script> & more",
+			want:     "<!-- raw HTML omitted -->\n",
+		},
+		{
+			name:     "inline html",
+			markdown: "a <img src=x onerror=alert(1)> & b",
+			want:     "<p>a <!-- raw HTML omitted --> &amp; b</p>\n",
+		},
+		{
+			name:     "unsafe link",
+			markdown: "[click](javascript:void)",
+			want:     "<p>click</p>\n",
+		},
+		{
+			name:     "unsafe autolink",
+			markdown: "see <javascript:alert(1)>",
+			want:     "<p>see javascript:alert(1)</p>\n",
+		},
+		{
+			name:     "nested lists",
+			markdown: "- one\n  - nested\n- two\n\n1. first\n2. second",
+			want:     "<ul>\n<li>one\n<ul>\n<li>nested</li>\n</ul>\n</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n",
+		},
+		{
+			name:     "code block",
+			markdown: "```go\nif a < b {\n```",
+			want:     "<pre><code class=\"language-go\">if a &lt; b {\n</code></pre>\n",
+		},
+		{
+			name:     "block quote and rule",
+			markdown: "> Quoted\n\n---",
+			want:     "<blockquote>\n<p>Quoted</p>\n</blockquote>\n<hr>\n",
+		},
+		{
+			name:     "table",
+			markdown: "| Name | Share |\n|---|--:|\n| **Go** | 10% |",
+			want:     "<table>\n<thead>\n<tr>\n<th>Name</th>\n<th style=\"text-align:right\">Share</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td><strong>Go</strong></td>\n<td style=\"text-align:right\">10%</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderHTMLMarkdown(tt.markdown); got != tt.want {
+				t.Errorf("renderHTMLMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderHTMLMarkdown_UnclosedCodeBlock(t *testing.T) {
+	got := renderHTMLMarkdown("```\ncode")
+	if !strings.HasSuffix(got, "</code></pre>\n") {
+		t.Errorf("renderHTMLMarkdown() = %q, want a closed code block", got)
+	}
+}
+
+func TestSafeLinkURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://example.com", want: true},
+		{url: "mailto:someone@example.com", want: true},
+		{url: "images/chart.png", want: true},
+		{url: "#sources", want: true},
+		{url: "javascript:alert(1)", want: false},
+		{url: "JavaScript:alert(1)", want: false},
+		{url: "data:text/html,x", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := safeLinkURL(tt.url); got != tt.want {
+			t.Errorf("safeLinkURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
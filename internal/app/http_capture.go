@@ -0,0 +1,259 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// httpCaptureIndex is the file listing the captured exchanges of a run.
+const httpCaptureIndex = "index.json"
+
+// httpExchangeDirName is the directory of the captured exchanges in a per-run directory.
+const httpExchangeDirName = "http"
+
+// secretHeaders are the request headers masked in the capture index.
+var secretHeaders = []string{"x-goog-api-key", "Authorization"}
+
+// HTTPExchange describes a captured API request and its response.
+type HTTPExchange struct {
+	Seq            int               `json:"seq"`
+	Operation      string            `json:"operation"`
+	Method         string            `json:"method"`
+	URL            string            `json:"url"`
+	RequestHeaders map[string]string `json:"request_headers,omitempty"`
+	Status         int               `json:"status,omitempty"`
+	StartedAt      time.Time         `json:"started_at"`
+	DurationMS     int64             `json:"duration_ms"`
+	RequestFile    string            `json:"request_file,omitempty"`  // Request body (none for requests without a body)
+	ResponseFile   string            `json:"response_file,omitempty"` // Response body
+	Error          string            `json:"error,omitempty"`         // Transport error (no response)
+}
+
+// HTTPCapture saves every API request and response body of a run to numbered files in a
+// directory (save_http_exchange), e.g. 01_create_interaction.req.json and
+// 01_create_interaction.resp.json, with an index.json listing the exchanges. It can also print each
+// request as an equivalent curl command (--curl).
+//
+// Secrets are masked in the saved bodies, URLs and headers. Failing to save an exchange is
+// logged and never fails the request.
+type HTTPCapture struct {
+	dir     string
+	logger  Logger
+	secrets []string  // The API key first
+	save    bool      // Save the exchanges (save_http_exchange)
+	curl    io.Writer // Destination of the curl commands (nil prints none)
+
+	mu        sync.Mutex
+	exchanges []HTTPExchange
+	requests  int // Requests printed as curl commands
+}
+
+// NewHTTPCapture creates an HTTPCapture saving exchanges to dir, created on the first exchange.
+func NewHTTPCapture(dir string, logger Logger, secrets ...string) *HTTPCapture {
+	return &HTTPCapture{dir: dir, logger: logger, secrets: secrets, save: true}
+}
+
+// NewCurlCapture creates an HTTPCapture printing each request as a curl command to w without
+// saving the exchanges. Request bodies too large to inline are saved to dir.
+func NewCurlCapture(dir string, w io.Writer, logger Logger, secrets ...string) *HTTPCapture {
+	return &HTTPCapture{dir: dir, logger: logger, secrets: secrets, curl: w}
+}
+
+// PrintCurl prints each request as a curl command to w, in addition to saving the exchanges.
+func (c *HTTPCapture) PrintCurl(w io.Writer) {
+	c.curl = w
+}
+
+// Dir returns the directory of the captured exchanges.
+func (c *HTTPCapture) Dir() string {
+	return c.dir
+}
+
+// Exchanges returns the exchanges captured so far.
+func (c *HTTPCapture) Exchanges() []HTTPExchange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]HTTPExchange(nil), c.exchanges...)
+}
+
+// Transport returns a RoundTripper capturing the exchanges made through next (the default
+// transport when nil).
+func (c *HTTPCapture) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &captureTransport{capture: c, next: next}
+}
+
+// captureTransport is the RoundTripper of an HTTPCapture.
+type captureTransport struct {
+	capture *HTTPCapture
+	next    http.RoundTripper
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The body is read and redacted once for the exchange and the curl command
+	var reqBody, redactedBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		// The request is cloned so that the caller's request is not modified
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(reqBody)), nil
+		}
+		redactedBody = redactBody(reqBody, t.capture.secrets...)
+	}
+	if t.capture.curl != nil {
+		t.capture.printCurl(req, redactedBody)
+	}
+	if !t.capture.save {
+		return t.next.RoundTrip(req)
+	}
+
+	started := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.capture.record(req, redactedBody, nil, nil, started, err)
+		return nil, err
+	}
+
+	respBody, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	t.capture.record(req, redactedBody, resp, respBody, started, readErr)
+	if readErr != nil {
+		return nil, readErr
+	}
+	return resp, nil
+}
+
+// printCurl prints a request as a curl command, saving its body to a file when it is too large
+// to inline.
+func (c *HTTPCapture) printCurl(req *http.Request, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests++
+
+	var bodyFile string
+	if len(body) > curlMaxInlineBody {
+		bodyFile = filepath.Join(c.dir, fmt.Sprintf("curl_%02d_%s.json", c.requests, exchangeOperation(req)))
+		if err := os.MkdirAll(c.dir, 0755); err != nil {
+			c.logger.Warn("Failed to save request body of curl command", "error", err)
+		}
+		if err := os.WriteFile(bodyFile, body, 0644); err != nil {
+			c.logger.Warn("Failed to save request body of curl command", "file", bodyFile, "error", err)
+		}
+	}
+	// Written at once so that the commands of parallel requests do not interleave
+	io.WriteString(c.curl, curlCommand(req, body, bodyFile, c.secrets...))
+}
+
+// record saves an exchange and rewrites the index. reqBody is the redacted request body.
+func (c *HTTPCapture) record(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, started time.Time, exchangeErr error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	exchange := HTTPExchange{
+		Seq:            len(c.exchanges) + 1,
+		Operation:      exchangeOperation(req),
+		Method:         req.Method,
+		URL:            redactSecrets(req.URL.String(), c.secrets...),
+		RequestHeaders: c.redactHeaders(req.Header),
+		StartedAt:      started,
+		DurationMS:     time.Since(started).Milliseconds(),
+	}
+	if resp != nil {
+		exchange.Status = resp.StatusCode
+	}
+	if exchangeErr != nil {
+		exchange.Error = redactSecrets(exchangeErr.Error(), c.secrets...)
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		c.logger.Warn("Failed to save HTTP exchange", "error", err)
+		return
+	}
+	base := fmt.Sprintf("%02d_%s", exchange.Seq, exchange.Operation)
+	if len(reqBody) > 0 {
+		exchange.RequestFile = base + ".req.json"
+		c.saveFile(exchange.RequestFile, reqBody)
+	}
+	if resp != nil {
+		exchange.ResponseFile = base + ".resp.json"
+		c.saveFile(exchange.ResponseFile, redactBody(respBody, c.secrets...))
+	}
+
+	c.exchanges = append(c.exchanges, exchange)
+	index, err := json.MarshalIndent(c.exchanges, "", "  ")
+	if err != nil {
+		c.logger.Warn("Failed to save HTTP exchange index", "error", err)
+		return
+	}
+	c.saveFile(httpCaptureIndex, index)
+}
+
+// saveFile writes a file of the capture directory.
+func (c *HTTPCapture) saveFile(name string, data []byte) {
+	if err := os.WriteFile(filepath.Join(c.dir, name), data, 0644); err != nil {
+		c.logger.Warn("Failed to save HTTP exchange", "file", name, "error", err)
+	}
+}
+
+// redactHeaders returns the request headers with the API key and authorization masked.
+func (c *HTTPCapture) redactHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		for _, secret := range secretHeaders {
+			if strings.EqualFold(name, secret) {
+				value = maskAPIKey(value)
+			}
+		}
+		headers[name] = redactSecrets(value, c.secrets...)
+	}
+	return headers
+}
+
+// exchangeOperation names the API operation of a request from its method and path.
+func exchangeOperation(req *http.Request) string {
+	path := req.URL.Path
+	switch {
+	case strings.HasSuffix(path, ":generateContent"):
+		return OpGenerateImage
+	case strings.HasSuffix(path, "/cancel"):
+		return OpCancelInteraction
+	case strings.Contains(path, "/interactions"):
+		switch req.Method {
+		case http.MethodPost:
+			return OpCreateInteraction
+		case http.MethodGet:
+			if strings.HasSuffix(path, "/interactions") {
+				return OpListInteractions
+			}
+			return OpPoll
+		case http.MethodDelete:
+			return OpDeleteInteraction
+		}
+		return strings.ToLower(req.Method) + "_interaction"
+	case strings.Contains(path, "/models"):
+		return OpListModels
+	}
+	return strings.ToLower(req.Method)
+}
@@ -0,0 +1,198 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTTPCapture(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/interactions"):
+			if string(body) != `{"input":"test"}` {
+				t.Errorf("request body = %q, want the original body", body)
+			}
+			w.Write([]byte(`{"id":"abc","status":"in_progress"}`))
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"message":"overloaded"}}`))
+		default:
+			w.Write([]byte(`{"candidates":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "responses", "20251224_103045")
+	capture := NewHTTPCapture(dir, NewNullLogger(), testSecretKey)
+	client := &http.Client{Transport: capture.Transport(nil)}
+
+	requests := []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/v1beta/interactions", `{"input":"test"}`},
+		{http.MethodGet, "/v1beta/interactions/abc?key=" + testSecretKey, ""},
+		{http.MethodPost, "/v1beta/models/gemini-3-pro-image-preview:generateContent", `{"key":"` + testSecretKey + `"}`},
+	}
+	for _, r := range requests {
+		var body io.Reader
+		if r.body != "" {
+			body = strings.NewReader(r.body)
+		}
+		req, err := http.NewRequest(r.method, server.URL+r.path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("x-goog-api-key", testSecretKey)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", r.method, r.path, err)
+		}
+		// The response body is still readable by the client
+		if data, _ := io.ReadAll(resp.Body); len(data) == 0 {
+			t.Errorf("%s %s: empty response body", r.method, r.path)
+		}
+		resp.Body.Close()
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := "01_create_interaction.req.json 01_create_interaction.resp.json 02_poll.resp.json 03_generate_image.req.json 03_generate_image.resp.json index.json"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("files = %s, want %s", got, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, httpCaptureIndex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), testSecretKey) {
+		t.Errorf("index contains the API key: %s", data)
+	}
+	var index []HTTPExchange
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index) != 3 {
+		t.Fatalf("index = %+v, want 3 exchanges", index)
+	}
+	poll := index[1]
+	if poll.Seq != 2 || poll.Operation != OpPoll || poll.Method != http.MethodGet || poll.Status != http.StatusServiceUnavailable || poll.RequestFile != "" || poll.ResponseFile != "02_poll.resp.json" {
+		t.Errorf("poll exchange = %+v", poll)
+	}
+	if got := poll.RequestHeaders["X-Goog-Api-Key"]; got != maskAPIKey(testSecretKey) {
+		t.Errorf("API key header = %q, want it masked", got)
+	}
+
+	request, err := os.ReadFile(filepath.Join(dir, "03_generate_image.req.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(request), testSecretKey) {
+		t.Errorf("saved request contains the API key: %s", request)
+	}
+}
+
+func TestHTTPCapture_TransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	dir := t.TempDir()
+	capture := NewHTTPCapture(dir, NewNullLogger())
+	client := &http.Client{Transport: capture.Transport(nil)}
+	if _, err := client.Get(server.URL + "/v1beta/models"); err == nil {
+		t.Fatal("Get() error = nil, want a connection error")
+	}
+
+	exchanges := capture.Exchanges()
+	if len(exchanges) != 1 || exchanges[0].Operation != OpListModels || exchanges[0].Error == "" || exchanges[0].ResponseFile != "" {
+		t.Errorf("exchanges = %+v, want the failed request", exchanges)
+	}
+}
+
+func TestHTTPCapture_Curl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if len(body) == 0 && r.Method == http.MethodPost {
+			t.Error("request body not sent")
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	largeBody := `{"data":"` + strings.Repeat("a", curlMaxInlineBody) + `"}`
+	// An edited image: the curl command must send the image as is
+	imageBody := `{"contents":[{"parts":[{"inlineData":{"mimeType":"image/png","data":"` + base64.StdEncoding.EncodeToString(noisePNG(t, 300)) + `"}}]}]}`
+	tests := []struct {
+		name      string
+		save      bool
+		body      string
+		wantFile  bool // The body is saved to a file referenced by the command
+		wantIndex bool
+	}{
+		{name: "inline body", body: `{"input":"test"}`},
+		{name: "large body", body: largeBody, wantFile: true},
+		{name: "image body", body: imageBody, wantFile: true},
+		{name: "with save_http_exchange", save: true, body: `{"input":"test"}`, wantIndex: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "responses", "20251224_103045")
+			var out strings.Builder
+			capture := NewCurlCapture(dir, &out, NewNullLogger(), testSecretKey)
+			if tt.save {
+				capture = NewHTTPCapture(dir, NewNullLogger(), testSecretKey)
+				capture.PrintCurl(&out)
+			}
+			client := &http.Client{Transport: capture.Transport(nil)}
+
+			req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1beta/interactions", strings.NewReader(tt.body))
+			req.Header.Set("x-goog-api-key", testSecretKey)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			command := out.String()
+			if !strings.HasPrefix(command, "curl -X POST") || !strings.Contains(command, `"X-Goog-Api-Key: $GEMINI_API_KEY"`) {
+				t.Errorf("command = %s, want a curl command of the request", command)
+			}
+			bodyFile := filepath.Join(dir, "curl_01_create_interaction.json")
+			if tt.wantFile {
+				if data, err := os.ReadFile(bodyFile); err != nil || string(data) != tt.body || !strings.Contains(command, "--data-binary '@"+bodyFile+"'") {
+					t.Errorf("command = %.200s, body file error = %v, want the body referenced", command, err)
+				}
+			} else if !strings.Contains(command, "--data '"+tt.body+"'") {
+				t.Errorf("command = %s, want the body inlined", command)
+			}
+			if _, err := os.Stat(filepath.Join(dir, httpCaptureIndex)); (err == nil) != tt.wantIndex {
+				t.Errorf("index saved = %t, want %t", err == nil, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	config := &ViperConfig{}
+	if client := newHTTPClient(config, apiTimeout{}, NewNullLogger()); client.Transport != config.sharedTransport() {
+		t.Errorf("Transport = %v, want the shared transport", client.Transport)
+	}
+	config.httpCapture = NewHTTPCapture(t.TempDir(), NewNullLogger())
+	if _, ok := newHTTPClient(config, apiTimeout{}, NewNullLogger()).Transport.(*captureTransport); !ok {
+		t.Error("Transport does not capture the exchanges")
+	}
+}
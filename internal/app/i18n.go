@@ -0,0 +1,104 @@
+package app
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Languages of the CLI messages (cli_lang).
+const (
+	CLILangEnglish  = "en"
+	CLILangJapanese = "ja"
+)
+
+// localeFS holds the message catalog of each language, keyed by message ID.
+//
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// ParseCLILang validates a CLI language. An empty value is valid and means detecting the
+// language from the environment.
+func ParseCLILang(value string) (string, error) {
+	switch value {
+	case "", CLILangEnglish, CLILangJapanese:
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid cli_lang %q: must be %s or %s", value, CLILangEnglish, CLILangJapanese)
+}
+
+// DetectCLILang returns the CLI language of the locale environment variables (LC_ALL,
+// LC_MESSAGES, then LANG, as for gettext). Locales other than Japanese use English.
+func DetectCLILang() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			if strings.HasPrefix(strings.ToLower(locale), CLILangJapanese) {
+				return CLILangJapanese
+			}
+			return CLILangEnglish
+		}
+	}
+	return CLILangEnglish
+}
+
+// Catalog translates user-facing CLI messages. Messages missing from the catalog of the language
+// fall back to English.
+type Catalog struct {
+	Lang     string
+	messages map[string]string
+	fallback map[string]string
+}
+
+// NewCatalog returns the catalog of a language (detected from the environment when empty).
+func NewCatalog(lang string) *Catalog {
+	if lang == "" {
+		lang = DetectCLILang()
+	}
+	catalog := &Catalog{Lang: lang, messages: loadLocale(lang)}
+	if lang != CLILangEnglish {
+		catalog.fallback = loadLocale(CLILangEnglish)
+	}
+	return catalog
+}
+
+// loadLocale reads the embedded messages of a language, or none for an unknown language.
+func loadLocale(lang string) map[string]string {
+	data, err := localeFS.ReadFile("locales/" + lang + ".json")
+	if err != nil {
+		return nil
+	}
+	var messages map[string]string
+	if err := json.Unmarshal(data, &messages); err != nil {
+		panic(fmt.Sprintf("invalid message catalog %s: %v", lang, err))
+	}
+	return messages
+}
+
+// T returns the message of an ID formatted with args. Unknown IDs are returned as is.
+func (c *Catalog) T(id string, args ...any) string {
+	format, ok := c.messages[id]
+	if !ok {
+		if format, ok = c.fallback[id]; !ok {
+			format = id
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// messages is the catalog of the CLI messages, replaced once cli_lang is known.
+var messages = NewCatalog("")
+
+// setCLILang switches the CLI messages to a language (detected from the environment when empty).
+func setCLILang(lang string) {
+	messages = NewCatalog(lang)
+}
+
+// msg returns a translated CLI message.
+func msg(id string, args ...any) string {
+	return messages.T(id, args...)
+}
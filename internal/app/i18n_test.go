@@ -0,0 +1,183 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectCLILang(t *testing.T) {
+	tests := []struct {
+		name       string
+		lcAll      string
+		lcMessages string
+		lang       string
+		want       string
+	}{
+		{name: "unset", want: CLILangEnglish},
+		{name: "japanese LANG", lang: "ja_JP.UTF-8", want: CLILangJapanese},
+		{name: "english LANG", lang: "en_US.UTF-8", want: CLILangEnglish},
+		{name: "C locale", lang: "C", want: CLILangEnglish},
+		{name: "LC_ALL overrides LANG", lcAll: "en_US.UTF-8", lang: "ja_JP.UTF-8", want: CLILangEnglish},
+		{name: "LC_MESSAGES overrides LANG", lcMessages: "ja_JP.UTF-8", lang: "C", want: CLILangJapanese},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LC_ALL", tt.lcAll)
+			t.Setenv("LC_MESSAGES", tt.lcMessages)
+			t.Setenv("LANG", tt.lang)
+			if got := DetectCLILang(); got != tt.want {
+				t.Errorf("DetectCLILang() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalog_T(t *testing.T) {
+	ja := NewCatalog(CLILangJapanese)
+	if got, want := ja.T("batch.succeeded", 1, 3), "成功: 1/3"; got != want {
+		t.Errorf("T() = %q, want %q", got, want)
+	}
+
+	// Messages missing from a catalog fall back to English, then to the ID
+	ja.messages = map[string]string{}
+	if got, want := ja.T("batch.succeeded", 1, 3), "Succeeded: 1/3"; got != want {
+		t.Errorf("T() = %q, want the English fallback %q", got, want)
+	}
+	if got := NewCatalog(CLILangEnglish).T("no.such.message"); got != "no.such.message" {
+		t.Errorf("T() = %q, want the ID", got)
+	}
+}
+
+// TestCatalogs checks that every translation has an English message with the same verbs.
+func TestCatalogs(t *testing.T) {
+	english := loadLocale(CLILangEnglish)
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := localeFS.ReadFile("locales/" + entry.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			t.Fatalf("%s: %v", entry.Name(), err)
+		}
+		for id, message := range messages {
+			source, ok := english[id]
+			if !ok {
+				t.Errorf("%s: %s has no English message", entry.Name(), id)
+				continue
+			}
+			if strings.Count(message, "%") != strings.Count(source, "%") {
+				t.Errorf("%s: %s = %q, want the verbs of %q", entry.Name(), id, message, source)
+			}
+		}
+	}
+}
+
+// TestCatalogs_Complete checks that every English message is translated.
+func TestCatalogs_Complete(t *testing.T) {
+	english := loadLocale(CLILangEnglish)
+	for _, lang := range []string{CLILangJapanese} {
+		messages := loadLocale(lang)
+		for id := range english {
+			if _, ok := messages[id]; !ok {
+				t.Errorf("%s: %s has no translation", lang, id)
+			}
+		}
+	}
+}
+
+func TestViperConfig_CLILang(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+		wantErr bool
+	}{
+		{content: "", want: ""},
+		{content: "cli_lang: ja\n", want: CLILangJapanese},
+		{content: "cli_lang: fr\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tmpDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		config, err := NewViperConfig(tmpDir)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("NewViperConfig(%q) error = %v, wantErr %v", tt.content, err, tt.wantErr)
+		}
+		if !tt.wantErr && config.CLILang != tt.want {
+			t.Errorf("CLILang = %q, want %q", config.CLILang, tt.want)
+		}
+	}
+}
+
+func TestLoadCLILang(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     string
+		want    string
+	}{
+		{name: "unset", want: ""},
+		{name: "config file", content: "cli_lang: ja\n", want: CLILangJapanese},
+		{name: "environment", content: "cli_lang: ja\n", env: "en", want: CLILangEnglish},
+		{name: "invalid", content: "cli_lang: fr\n", want: ""},
+		{name: "unreadable config", content: "cli_lang: [ja\n", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEEPVIZ_CLI_LANG", tt.env)
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if got := loadCLILang(tmpDir); got != tt.want {
+				t.Errorf("loadCLILang() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRootCommand_HelpLanguage tests that the help follows cli_lang, which is read before the
+// commands are built.
+func TestRootCommand_HelpLanguage(t *testing.T) {
+	saved := messages
+	t.Cleanup(func() { messages = saved })
+	t.Setenv("LANG", "en_US.UTF-8")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_CLI_LANG", "ja")
+
+	cmd := NewRootCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--help"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := NewCatalog(CLILangJapanese).T("cmd.resume.short")
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("help = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPrintBatchSummary_Japanese(t *testing.T) {
+	saved := messages
+	setCLILang(CLILangJapanese)
+	t.Cleanup(func() { messages = saved })
+
+	var buf strings.Builder
+	printBatchSummary(&buf, 2, 2, nil)
+	if !strings.Contains(buf.String(), "=== バッチ完了 ===") || !strings.Contains(buf.String(), "成功: 2/2") {
+		t.Errorf("output = %q, want the Japanese summary", buf.String())
+	}
+}
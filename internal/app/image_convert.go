@@ -0,0 +1,82 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"github.com/gen2brain/webp"
+)
+
+// Supported output image formats.
+const (
+	ImageFormatPNG  = "png"
+	ImageFormatJPEG = "jpeg"
+	ImageFormatWebP = "webp"
+)
+
+// imageFormatExtensions maps output image formats to file extensions.
+var imageFormatExtensions = map[string]string{
+	ImageFormatPNG:  ".png",
+	ImageFormatJPEG: ".jpg",
+	ImageFormatWebP: ".webp",
+}
+
+// NormalizeImageFormat validates an output image format and returns its canonical name.
+//
+// An empty format keeps the image as returned by the API. "jpg" is accepted as an alias of "jpeg".
+func NormalizeImageFormat(format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "jpg" {
+		format = ImageFormatJPEG
+	}
+	if format == "" {
+		return "", nil
+	}
+	if _, ok := imageFormatExtensions[format]; !ok {
+		return "", fmt.Errorf("invalid image format %q (supported: png, jpeg, webp)", format)
+	}
+	return format, nil
+}
+
+// ValidateImageQuality validates the quality used for lossy image formats.
+func ValidateImageQuality(quality int) error {
+	if quality < 1 || quality > 100 {
+		return fmt.Errorf("image quality must be between 1 and 100, got %d", quality)
+	}
+	return nil
+}
+
+// convertImage re-encodes image data to the given format and returns the data with its extension.
+//
+// Quality applies to JPEG and WebP (lossy); PNG is encoded with the best compression.
+func convertImage(data []byte, format string, quality int) ([]byte, string, error) {
+	ext, ok := imageFormatExtensions[format]
+	if !ok {
+		return nil, "", fmt.Errorf("invalid image format %q", format)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case ImageFormatJPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	case ImageFormatWebP:
+		err = webp.Encode(&buf, img, webp.Options{Quality: quality, Method: webp.DefaultMethod})
+	default:
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode %s image: %w", format, err)
+	}
+
+	return buf.Bytes(), ext, nil
+}
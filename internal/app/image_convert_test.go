@@ -0,0 +1,102 @@
+package app
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"testing"
+)
+
+// testPNG encodes a small gradient PNG for conversion tests.
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 8), G: uint8(y * 8), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode test PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestNormalizeImageFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{format: "", want: ""},
+		{format: "png", want: "png"},
+		{format: "jpeg", want: "jpeg"},
+		{format: "JPG", want: "jpeg"},
+		{format: "WebP", want: "webp"},
+		{format: "gif", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := NormalizeImageFormat(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeImageFormat(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeImageFormat(%q) = %q, want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateImageQuality(t *testing.T) {
+	for _, quality := range []int{1, 90, 100} {
+		if err := ValidateImageQuality(quality); err != nil {
+			t.Errorf("ValidateImageQuality(%d) error = %v", quality, err)
+		}
+	}
+	for _, quality := range []int{0, 101, -1} {
+		if err := ValidateImageQuality(quality); err == nil {
+			t.Errorf("ValidateImageQuality(%d) should return error", quality)
+		}
+	}
+}
+
+func TestConvertImage(t *testing.T) {
+	data := testPNG(t)
+
+	tests := []struct {
+		format   string
+		wantExt  string
+		wantMime string
+	}{
+		{format: ImageFormatJPEG, wantExt: ".jpg", wantMime: "image/jpeg"},
+		{format: ImageFormatPNG, wantExt: ".png", wantMime: "image/png"},
+		{format: ImageFormatWebP, wantExt: ".webp", wantMime: "image/webp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			converted, ext, err := convertImage(data, tt.format, 80)
+			if err != nil {
+				t.Fatalf("convertImage() error = %v", err)
+			}
+			if ext != tt.wantExt {
+				t.Errorf("ext = %s, want %s", ext, tt.wantExt)
+			}
+			if got := http.DetectContentType(converted); got != tt.wantMime {
+				t.Errorf("converted content type = %s, want %s", got, tt.wantMime)
+			}
+		})
+	}
+}
+
+func TestConvertImage_InvalidData(t *testing.T) {
+	if _, _, err := convertImage([]byte("not an image"), ImageFormatJPEG, 80); err == nil {
+		t.Error("convertImage() should return error for undecodable data")
+	}
+}
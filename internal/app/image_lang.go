@@ -0,0 +1,144 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// imageLangConcurrency is the maximum number of languages generated in parallel.
+const imageLangConcurrency = 2
+
+// imageLangCodes maps common image languages to the codes used in file names.
+var imageLangCodes = map[string]string{
+	"arabic":     "ar",
+	"chinese":    "zh",
+	"dutch":      "nl",
+	"english":    "en",
+	"french":     "fr",
+	"german":     "de",
+	"hindi":      "hi",
+	"indonesian": "id",
+	"italian":    "it",
+	"japanese":   "ja",
+	"korean":     "ko",
+	"portuguese": "pt",
+	"russian":    "ru",
+	"spanish":    "es",
+	"thai":       "th",
+	"turkish":    "tr",
+	"vietnamese": "vi",
+}
+
+// ParseImageLangs splits a comma-separated list of image languages.
+//
+// Blank and duplicate entries are dropped.
+func ParseImageLangs(text string) []string {
+	var langs []string
+	seen := make(map[string]bool)
+	for _, lang := range strings.Split(text, ",") {
+		lang = strings.TrimSpace(lang)
+		key := strings.ToLower(lang)
+		if lang == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		langs = append(langs, lang)
+	}
+	return langs
+}
+
+// imageLangCode returns the file name suffix of an image language (e.g., "ja" for Japanese).
+//
+// Languages without a known code use their lower-cased name.
+func imageLangCode(lang string) string {
+	key := strings.ToLower(strings.TrimSpace(lang))
+	if code, ok := imageLangCodes[key]; ok {
+		return code
+	}
+	return safeFileName(strings.ReplaceAll(key, " ", "-"))
+}
+
+// LangError is an image language that failed to generate.
+type LangError struct {
+	Lang string
+	Err  error
+}
+
+func (e LangError) Error() string {
+	return fmt.Sprintf("language %s: %v", e.Lang, e.Err)
+}
+
+func (e LangError) Unwrap() error {
+	return e.Err
+}
+
+// forLang returns a copy of the client that builds infographic prompts in lang.
+func (c *GenaiImageClient) forLang(lang string) *GenaiImageClient {
+	config := *c.config
+	config.ImageLang = lang
+	client := *c
+	client.config = &config
+	return &client
+}
+
+// BuildLangPrompt builds the prompt of an infographic of markdown in an image language.
+func (c *GenaiImageClient) BuildLangPrompt(lang, markdown string) (string, error) {
+	return c.forLang(lang).BuildInfographicsPrompt(markdown)
+}
+
+// GenerateLangs generates count images per language from prompts built for each language.
+//
+// prompts[i] is the prompt of langs[i]. The images of each language are saved with the language
+// code as a suffix (<timestamp>_ja.png, <timestamp>_en.png, ...) and up to imageLangConcurrency
+// languages are generated in parallel. Languages that succeed are kept when others fail; the
+// failures are reported in LangErrors. An error is returned only when every language fails.
+func (c *GenaiImageClient) GenerateLangs(ctx context.Context, langs, prompts []string, imgConfig ImageConfig, timestamp string, count int) (*ImageResult, error) {
+	c.logger.Info("Generating images per language", "languages", strings.Join(langs, ","))
+	results := make([]*ImageResult, len(langs))
+	errs := runPool(ctx, imageLangConcurrency, len(langs), false, func(ctx context.Context, i int) error {
+		result, err := c.GenerateVariants(ctx, prompts[i], imgConfig, timestamp+"_"+imageLangCode(langs[i]), count)
+		results[i] = result
+		return err
+	})
+
+	var merged *ImageResult
+	var langErrs []LangError
+	for i, result := range results {
+		if errs[i] != nil {
+			c.logger.Error("Image generation failed for language", "lang", langs[i], "error", errs[i])
+			langErrs = append(langErrs, LangError{Lang: langs[i], Err: errs[i]})
+			continue
+		}
+		if merged == nil {
+			merged = &ImageResult{
+				ImagePath:    result.ImagePath,
+				ResponsePath: result.ResponsePath,
+				RequestPath:  result.RequestPath,
+				InputImages:  result.InputImages,
+				PromptPath:   result.PromptPath,
+				CaptionPath:  result.CaptionPath,
+				Model:        result.Model,
+			}
+		}
+		if result.FallbackFrom != "" {
+			merged.FallbackFrom = result.FallbackFrom
+		}
+		merged.ImagePaths = append(merged.ImagePaths, result.ImagePaths...)
+		merged.PromptPaths = append(merged.PromptPaths, result.PromptPath)
+		merged.OriginalPaths = append(merged.OriginalPaths, result.OriginalPaths...)
+		merged.VariantErrors = append(merged.VariantErrors, result.VariantErrors...)
+	}
+
+	if merged == nil {
+		joined := make([]error, len(langErrs))
+		for i, langErr := range langErrs {
+			joined[i] = langErr
+		}
+		return nil, fmt.Errorf("image generation failed for all %d languages: %w", len(langs), errors.Join(joined...))
+	}
+	merged.LangErrors = langErrs
+
+	return merged, nil
+}
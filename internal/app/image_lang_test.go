@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImageLangs(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "", want: nil},
+		{text: "Japanese", want: []string{"Japanese"}},
+		{text: "Japanese, English", want: []string{"Japanese", "English"}},
+		{text: "Japanese,,english,English ", want: []string{"Japanese", "english"}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseImageLangs(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseImageLangs(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestImageLangCode(t *testing.T) {
+	tests := map[string]string{
+		"Japanese":        "ja",
+		"english":         "en",
+		" German ":        "de",
+		"Esperanto":       "esperanto",
+		"Brazilian Portu": "brazilian-portu",
+	}
+
+	for lang, want := range tests {
+		if got := imageLangCode(lang); got != want {
+			t.Errorf("imageLangCode(%q) = %s, want %s", lang, got, want)
+		}
+	}
+}
+
+func TestGenaiImageClient_GenerateLangs(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Requests for French fail permanently
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "in French") {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"code":400,"message":"bad request","status":"INVALID_ARGUMENT"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, imageResponseJSON(testImageData))
+	})
+	client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir()})
+
+	langs := []string{"Japanese", "English", "French"}
+	var prompts []string
+	for _, lang := range langs {
+		prompt, err := client.forLang(lang).BuildInfographicsPrompt("AI trends")
+		if err != nil {
+			t.Fatal(err)
+		}
+		prompts = append(prompts, prompt)
+	}
+
+	result, err := client.GenerateLangs(context.Background(), langs, prompts, ImageConfig{Model: "test-model"}, "20251224_103045", 1)
+	if err != nil {
+		t.Fatalf("GenerateLangs() error = %v", err)
+	}
+
+	var names []string
+	for _, path := range result.ImagePaths {
+		names = append(names, filepath.Base(path))
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("image %s should be saved: %v", path, err)
+		}
+	}
+	if want := []string{"20251224_103045_ja.png", "20251224_103045_en.png"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("images = %v, want %v", names, want)
+	}
+	if len(result.PromptPaths) != 2 {
+		t.Errorf("PromptPaths = %v, want one per successful language", result.PromptPaths)
+	}
+	if len(result.LangErrors) != 1 || result.LangErrors[0].Lang != "French" {
+		t.Errorf("LangErrors = %v, want French", result.LangErrors)
+	}
+
+	// The client's own language is left untouched
+	if client.config.ImageLang != "" {
+		t.Errorf("ImageLang = %q, forLang should not modify the client", client.config.ImageLang)
+	}
+}
+
+func TestGenaiImageClient_GenerateLangs_AllFail(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"code":400,"message":"bad request","status":"INVALID_ARGUMENT"}}`)
+	})
+	client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir()})
+
+	_, err := client.GenerateLangs(context.Background(), []string{"Japanese", "English"}, []string{"a", "b"}, ImageConfig{Model: "test-model"}, "20251224_103045", 1)
+	if err == nil || !strings.Contains(err.Error(), "failed for all 2 languages") {
+		t.Errorf("GenerateLangs() error = %v, want all languages failed", err)
+	}
+}
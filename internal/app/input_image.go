@@ -0,0 +1,62 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+)
+
+// maxInputImageSize is the maximum size of an input image (inline data is limited to 20 MB per request).
+const maxInputImageSize = 20 << 20
+
+// inputImageMimeTypes are the mime types accepted for input images.
+var inputImageMimeTypes = []string{"image/png", "image/jpeg", "image/webp"}
+
+// InputImage is an existing image sent to the image model along with the prompt (e.g., for editing).
+type InputImage struct {
+	Path     string // File path
+	MimeType string // Mime type detected from the file contents
+	Data     []byte // File contents
+}
+
+// LoadInputImage reads an input image and detects its mime type from the contents.
+//
+// Files larger than maxInputImageSize and formats other than PNG, JPEG and WebP are rejected.
+func LoadInputImage(path string) (*InputImage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input image: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("input image %s is a directory", path)
+	}
+	if info.Size() > maxInputImageSize {
+		return nil, fmt.Errorf("input image %s is too large (%.1f MB, max %d MB)", path, float64(info.Size())/(1<<20), maxInputImageSize>>20)
+	}
+
+	data, err := ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input image: %w", err)
+	}
+
+	mimeType := mimeTypeBase(http.DetectContentType(data))
+	if !slices.Contains(inputImageMimeTypes, mimeType) {
+		return nil, fmt.Errorf("input image %s has an unsupported format %s (supported: PNG, JPEG, WebP)", path, mimeType)
+	}
+
+	return &InputImage{Path: path, MimeType: mimeType, Data: data}, nil
+}
+
+// LoadInputImages loads every input image given by --input-image.
+func LoadInputImages(paths []string) ([]*InputImage, error) {
+	images := make([]*InputImage, 0, len(paths))
+	for _, path := range paths {
+		image, err := LoadInputImage(path)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadInputImage(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"chart.png":  testImageData,
+		"photo.jpg":  []byte("\xff\xd8\xff\xe0fake"),
+		"notes.txt":  []byte("plain text"),
+		"large.png":  append(append([]byte{}, testImageData...), make([]byte, maxInputImageSize)...),
+		"anim.gif":   []byte("GIF89a fake"),
+		"image.webp": []byte("RIFF\x00\x00\x00\x00WEBPVP8 fake"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		file         string
+		wantMimeType string
+		wantErr      string
+	}{
+		{name: "png", file: "chart.png", wantMimeType: "image/png"},
+		{name: "jpeg", file: "photo.jpg", wantMimeType: "image/jpeg"},
+		{name: "webp", file: "image.webp", wantMimeType: "image/webp"},
+		{name: "unsupported format", file: "anim.gif", wantErr: "unsupported format image/gif"},
+		{name: "not an image", file: "notes.txt", wantErr: "unsupported format"},
+		{name: "too large", file: "large.png", wantErr: "too large"},
+		{name: "missing", file: "missing.png", wantErr: "failed to read input image"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image, err := LoadInputImage(filepath.Join(dir, tt.file))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadInputImage() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadInputImage() error = %v", err)
+			}
+			if image.MimeType != tt.wantMimeType {
+				t.Errorf("MimeType = %s, want %s", image.MimeType, tt.wantMimeType)
+			}
+		})
+	}
+}
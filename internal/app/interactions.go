@@ -0,0 +1,246 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yukiyan/deepviz/internal/genai/interactions"
+)
+
+// interactionsPageSize is the number of interactions requested per page from the interactions endpoint.
+const interactionsPageSize = 100
+
+// interactionInputWidth is the maximum number of characters of the input column of the
+// interactions table.
+const interactionInputWidth = 60
+
+// InteractionInfo is an interaction stored by the Interactions API.
+type InteractionInfo struct {
+	ID      string    `json:"id"`
+	Status  string    `json:"status"`
+	Created time.Time `json:"created,omitzero"`
+	Input   string    `json:"input"` // First line of the text input
+}
+
+// InteractionsClient lists and deletes the interactions stored for the configured API key.
+//
+// The generated interactions client has no list operation, so the list endpoint is called
+// directly in the same way as the models endpoint.
+type InteractionsClient struct {
+	config     *ViperConfig
+	logger     Logger
+	httpClient *http.Client
+	baseURL    string
+	client     *interactions.ClientWithResponses
+}
+
+// NewInteractionsClient creates a new InteractionsClient.
+//
+// It returns ErrNoAPIKey if no API key is configured, and ErrNotSupportedOnVertex with auth: adc.
+func NewInteractionsClient(config *ViperConfig, logger Logger) (*InteractionsClient, error) {
+	if config.adc != nil {
+		return nil, fmt.Errorf("%s: %w", "the Interactions API", ErrNotSupportedOnVertex)
+	}
+	if err := config.loadAPIKey(); err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+	if config.APIKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	httpClient := newHTTPClient(config, config.requestTimeout(), logger)
+	client, err := newInteractionsAPI(defaultImageBaseURL, config, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return &InteractionsClient{
+		config:     config,
+		logger:     logger,
+		httpClient: httpClient,
+		baseURL:    defaultImageBaseURL,
+		client:     client,
+	}, nil
+}
+
+// ListInteractions returns the interactions with the given status (all when empty), following
+// pagination until every page is read or limit interactions are found (no limit when 0).
+func (c *InteractionsClient) ListInteractions(ctx context.Context, status string, limit int) ([]InteractionInfo, error) {
+	var result []InteractionInfo
+	pageToken := ""
+	for {
+		query := url.Values{"pageSize": {fmt.Sprint(interactionsPageSize)}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		body, err := apiGet(ctx, c.httpClient, c.config.APIKey, c.baseURL+"/v1beta/interactions?"+query.Encode(), OpListInteractions, c.logger)
+		if err != nil {
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+				return nil, fmt.Errorf("%w (listing interactions is not available for this API key)", err)
+			}
+			return nil, err
+		}
+
+		var page struct {
+			Interactions  []interactionEntry `json:"interactions"`
+			NextPageToken string             `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal interactions response: %w", err)
+		}
+		for _, entry := range page.Interactions {
+			if status != "" && entry.Status != status {
+				continue
+			}
+			result = append(result, entry.info())
+			if limit > 0 && len(result) >= limit {
+				return result, nil
+			}
+		}
+
+		if page.NextPageToken == "" {
+			return result, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// DeleteInteraction deletes a stored interaction.
+func (c *InteractionsClient) DeleteInteraction(ctx context.Context, id string) error {
+	c.logger.Debug("HTTP Request", "interaction_id", id, "method", "DELETE")
+	resp, err := c.client.DeleteInteractionWithResponse(ctx, "v1beta", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete interaction: %w", err)
+	}
+	c.logger.Trace("HTTP Response", "status_code", resp.StatusCode(), "body", string(resp.Body))
+
+	if resp.StatusCode() != http.StatusOK {
+		return newAPIError(OpDeleteInteraction, resp.StatusCode(), resp.Body)
+	}
+	return nil
+}
+
+// InteractionDeletion is the result of deleting an interaction.
+type InteractionDeletion struct {
+	ID  string
+	Err error // nil when the interaction was deleted
+}
+
+// DeleteInteractions deletes interactions one at a time. A failure does not stop the deletion of
+// the other interactions; once ctx is cancelled, the remaining ones fail with its error.
+func (c *InteractionsClient) DeleteInteractions(ctx context.Context, ids []string) []InteractionDeletion {
+	results := make([]InteractionDeletion, 0, len(ids))
+	for _, id := range ids {
+		err := ctx.Err()
+		if err == nil {
+			err = c.DeleteInteraction(ctx, id)
+		}
+		results = append(results, InteractionDeletion{ID: id, Err: err})
+	}
+	return results
+}
+
+// selectOldInteractions returns the interactions created longer than olderThan before now (all
+// when 0). Interactions without a creation time are only selected without an age.
+func selectOldInteractions(interactions []InteractionInfo, olderThan time.Duration, now time.Time) []InteractionInfo {
+	if olderThan <= 0 {
+		return interactions
+	}
+	var selected []InteractionInfo
+	for _, interaction := range interactions {
+		if !interaction.Created.IsZero() && interaction.Created.Before(now.Add(-olderThan)) {
+			selected = append(selected, interaction)
+		}
+	}
+	return selected
+}
+
+// interactionEntry is an interaction in a page of the interactions endpoint.
+type interactionEntry struct {
+	ID      string          `json:"id"`
+	Status  string          `json:"status"`
+	Created time.Time       `json:"created"`
+	Input   json.RawMessage `json:"input"`
+}
+
+// info returns the interaction with the first line of its text input.
+func (e interactionEntry) info() InteractionInfo {
+	text, _, _ := strings.Cut(strings.TrimSpace(interactionInputText(e.Input)), "\n")
+	return InteractionInfo{ID: e.ID, Status: e.Status, Created: e.Created, Input: strings.TrimSpace(text)}
+}
+
+// interactionInputText returns the first text of an interaction input, which is a string, a
+// list of contents or a list of turns with contents.
+func interactionInputText(input json.RawMessage) string {
+	var text string
+	if json.Unmarshal(input, &text) == nil {
+		return text
+	}
+
+	var items []struct {
+		Type    string          `json:"type"`
+		Text    string          `json:"text"`
+		Content json.RawMessage `json:"content"`
+	}
+	if json.Unmarshal(input, &items) != nil {
+		return ""
+	}
+	for _, item := range items {
+		if item.Type == "text" && item.Text != "" {
+			return item.Text
+		}
+		if len(item.Content) > 0 {
+			if text := interactionInputText(item.Content); text != "" {
+				return text
+			}
+		}
+	}
+	return ""
+}
+
+// printInteractions prints interactions as a table with the input truncated to fit a line.
+func printInteractions(w io.Writer, interactions []InteractionInfo) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tCREATED\tINPUT")
+	for _, interaction := range interactions {
+		created := "-"
+		if !interaction.Created.IsZero() {
+			created = interaction.Created.Local().Format("2006-01-02 15:04")
+		}
+		input := interaction.Input
+		if runes := []rune(input); len(runes) > interactionInputWidth {
+			input = string(runes[:interactionInputWidth]) + "..."
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", interaction.ID, orDash(interaction.Status), created, orDash(input))
+	}
+	tw.Flush()
+}
+
+// reportInteractionDeletions prints the result of each deletion and returns an error if any
+// deletion failed.
+func reportInteractionDeletions(stdout, stderr io.Writer, results []InteractionDeletion) error {
+	style := NewStyler(stderr)
+	var failures []error
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(stderr, "%s %s: %v\n", style.Error(msg("interactions.delete_failed")), result.ID, result.Err)
+			failures = append(failures, fmt.Errorf("%s: %w", result.ID, result.Err))
+			continue
+		}
+		fmt.Fprintln(stdout, msg("interactions.deleted", result.ID))
+	}
+	fmt.Fprintln(stdout, msg("interactions.deleted_count", len(results)-len(failures), len(results)))
+
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d interactions could not be deleted: %w", len(failures), len(results), errors.Join(failures...))
+}
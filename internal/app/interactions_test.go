@@ -0,0 +1,271 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestInteractionsClient creates an InteractionsClient that sends requests to handler.
+func newTestInteractionsClient(t *testing.T, handler http.Handler) *InteractionsClient {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client, err := NewInteractionsClient(&ViperConfig{APIKey: "test-key"}, NewNullLogger())
+	if err != nil {
+		t.Fatalf("failed to create interactions client: %v", err)
+	}
+	client.baseURL = srv.URL
+	if client.client, err = newInteractionsAPI(srv.URL, client.config, client.httpClient); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// interactionPagesHandler serves two pages of interactions and counts the requested pages.
+func interactionPagesHandler(t *testing.T, pages *int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/interactions" {
+			t.Errorf("path = %s, want /v1beta/interactions", r.URL.Path)
+		}
+		*pages++
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"interactions":[
+				{"id":"a","status":"completed","created":"2025-12-24T10:30:45Z","input":"Weekly report\nwith details"},
+				{"id":"b","status":"in_progress","input":[{"type":"text","text":"AI trends"}]}
+			],"nextPageToken":"next"}`)
+			return
+		}
+		fmt.Fprint(w, `{"interactions":[
+			{"id":"c","status":"in_progress","input":[{"role":"user","content":[{"type":"text","text":"Turn input"}]}]}
+		]}`)
+	})
+}
+
+func TestNewInteractionsClient_NoAPIKey(t *testing.T) {
+	if _, err := NewInteractionsClient(&ViperConfig{}, NewNullLogger()); !errors.Is(err, ErrNoAPIKey) {
+		t.Errorf("NewInteractionsClient() error = %v, want ErrNoAPIKey", err)
+	}
+}
+
+func TestInteractionsClient_ListInteractions(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		limit     int
+		wantIDs   string
+		wantPages int
+	}{
+		{name: "all pages", wantIDs: "a b c", wantPages: 2},
+		{name: "status", status: "in_progress", wantIDs: "b c", wantPages: 2},
+		{name: "limit within the first page", limit: 2, wantIDs: "a b", wantPages: 1},
+		{name: "limit with status", status: "in_progress", limit: 1, wantIDs: "b", wantPages: 1},
+		{name: "no match", status: "failed", wantIDs: "", wantPages: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := 0
+			client := newTestInteractionsClient(t, interactionPagesHandler(t, &pages))
+
+			interactions, err := client.ListInteractions(context.Background(), tt.status, tt.limit)
+			if err != nil {
+				t.Fatalf("ListInteractions() error = %v", err)
+			}
+			var ids []string
+			for _, interaction := range interactions {
+				ids = append(ids, interaction.ID)
+			}
+			if got := strings.Join(ids, " "); got != tt.wantIDs {
+				t.Errorf("IDs = %q, want %q", got, tt.wantIDs)
+			}
+			if pages != tt.wantPages {
+				t.Errorf("requested %d pages, want %d", pages, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestInteractionsClient_ListInteractions_Input(t *testing.T) {
+	pages := 0
+	client := newTestInteractionsClient(t, interactionPagesHandler(t, &pages))
+
+	interactions, err := client.ListInteractions(context.Background(), "", 0)
+	if err != nil {
+		t.Fatalf("ListInteractions() error = %v", err)
+	}
+	want := []InteractionInfo{
+		{ID: "a", Status: "completed", Created: time.Date(2025, 12, 24, 10, 30, 45, 0, time.UTC), Input: "Weekly report"},
+		{ID: "b", Status: "in_progress", Input: "AI trends"},
+		{ID: "c", Status: "in_progress", Input: "Turn input"},
+	}
+	for i, interaction := range interactions {
+		if !interaction.Created.Equal(want[i].Created) || interaction.ID != want[i].ID || interaction.Status != want[i].Status || interaction.Input != want[i].Input {
+			t.Errorf("interactions[%d] = %+v, want %+v", i, interaction, want[i])
+		}
+	}
+
+	// Interactions without a creation time omit it in JSON
+	data, err := json.Marshal(interactions[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "created") {
+		t.Errorf("JSON = %s, want no creation time", data)
+	}
+}
+
+func TestInteractionsClient_ListInteractions_NotFound(t *testing.T) {
+	client := newTestInteractionsClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"code":404,"message":"Not found"}}`)
+	}))
+
+	_, err := client.ListInteractions(context.Background(), "", 0)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Operation != OpListInteractions {
+		t.Fatalf("ListInteractions() error = %v, want a list_interactions APIError", err)
+	}
+	if !strings.Contains(err.Error(), "not available") {
+		t.Errorf("error = %q, want a hint", err)
+	}
+}
+
+func TestInteractionsClient_DeleteInteractions(t *testing.T) {
+	var deleted []string
+	client := newTestInteractionsClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("method = %s, want DELETE", r.Method)
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/v1beta/interactions/")
+		switch id {
+		case "missing":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"code":"not_found","message":"Interaction not found"}}`)
+		case "forbidden":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error":{"code":"permission_denied","message":"Permission denied"}}`)
+		default:
+			deleted = append(deleted, id)
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+
+	// Failures do not stop the other deletions
+	results := client.DeleteInteractions(context.Background(), []string{"a", "missing", "forbidden", "b"})
+	if got := strings.Join(deleted, " "); got != "a b" {
+		t.Errorf("deleted = %q, want a b", got)
+	}
+	for i, wantStatus := range []int{0, http.StatusNotFound, http.StatusForbidden, 0} {
+		var apiErr *APIError
+		switch {
+		case wantStatus == 0 && results[i].Err != nil:
+			t.Errorf("results[%d].Err = %v, want nil", i, results[i].Err)
+		case wantStatus != 0 && (!errors.As(results[i].Err, &apiErr) || apiErr.StatusCode != wantStatus || apiErr.Operation != OpDeleteInteraction):
+			t.Errorf("results[%d].Err = %v, want a %d delete_interaction APIError", i, results[i].Err, wantStatus)
+		}
+	}
+
+	// Once cancelled, the remaining interactions are not requested
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	deleted = nil
+	results = client.DeleteInteractions(ctx, []string{"c"})
+	if len(deleted) != 0 || !errors.Is(results[0].Err, context.Canceled) {
+		t.Errorf("deleted = %v, results = %+v, want no request", deleted, results)
+	}
+}
+
+func TestSelectOldInteractions(t *testing.T) {
+	now := time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC)
+	interactions := []InteractionInfo{
+		{ID: "old", Created: now.AddDate(0, 0, -10)},
+		{ID: "recent", Created: now.AddDate(0, 0, -1)},
+		{ID: "unknown"},
+	}
+
+	tests := []struct {
+		olderThan time.Duration
+		want      string
+	}{
+		{olderThan: 0, want: "old recent unknown"},
+		{olderThan: 7 * 24 * time.Hour, want: "old"},
+		{olderThan: 30 * 24 * time.Hour, want: ""},
+	}
+	for _, tt := range tests {
+		var ids []string
+		for _, interaction := range selectOldInteractions(interactions, tt.olderThan, now) {
+			ids = append(ids, interaction.ID)
+		}
+		if got := strings.Join(ids, " "); got != tt.want {
+			t.Errorf("selectOldInteractions(%v) = %q, want %q", tt.olderThan, got, tt.want)
+		}
+	}
+}
+
+func TestReportInteractionDeletions(t *testing.T) {
+	setColor(t, false)
+	var stdout, stderr strings.Builder
+	err := reportInteractionDeletions(&stdout, &stderr, []InteractionDeletion{
+		{ID: "a"},
+		{ID: "missing", Err: newAPIError(OpDeleteInteraction, http.StatusNotFound, nil)},
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 interactions") {
+		t.Errorf("error = %v, want the failure count", err)
+	}
+	if got, want := stdout.String(), "Deleted a\nDeleted 1 of 2 interactions\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(stderr.String(), "Failed: missing: delete_interaction") {
+		t.Errorf("stderr = %q, want the failed ID", stderr.String())
+	}
+
+	if err := reportInteractionDeletions(&stdout, &stderr, []InteractionDeletion{{ID: "a"}}); err != nil {
+		t.Errorf("error = %v, want nil", err)
+	}
+}
+
+func TestInteractionsDeleteCommand_Usage(t *testing.T) {
+	tests := [][]string{
+		{"interactions", "delete"},
+		{"interactions", "delete", "a", "--all-completed"},
+		{"interactions", "delete", "a", "--older-than", "7d"},
+		{"interactions", "delete", "--all-completed", "--older-than", "soon"},
+	}
+	for _, args := range tests {
+		rootCmd := NewRootCommand()
+		rootCmd.SetArgs(args)
+		rootCmd.SetOut(&strings.Builder{})
+		rootCmd.SetErr(&strings.Builder{})
+		var usageErr *UsageError
+		if err := rootCmd.Execute(); !errors.As(err, &usageErr) {
+			t.Errorf("%v: error = %v, want a UsageError", args, err)
+		}
+	}
+}
+
+func TestPrintInteractions(t *testing.T) {
+	var buf strings.Builder
+	printInteractions(&buf, []InteractionInfo{
+		{ID: "v1_abc", Status: "completed", Created: time.Date(2025, 12, 24, 10, 30, 45, 0, time.Local), Input: strings.Repeat("a", interactionInputWidth+10)},
+		{ID: "v1_def", Status: "in_progress"},
+	})
+
+	out := buf.String()
+	for _, want := range []string{"ID", "STATUS", "v1_abc", "2025-12-24 10:30", strings.Repeat("a", interactionInputWidth) + "...", "v1_def"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output = %q, want %q", out, want)
+		}
+	}
+	if strings.Contains(out, strings.Repeat("a", interactionInputWidth+1)) {
+		t.Errorf("output = %q, want the input truncated", out)
+	}
+}
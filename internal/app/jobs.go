@@ -0,0 +1,293 @@
+package app
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Job statuses recorded in the batch report.
+const (
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusSkipped   = "skipped"
+)
+
+// JobSpec is a single job in a job file.
+type JobSpec struct {
+	Name         string `yaml:"name"`
+	Prompt       string `yaml:"prompt"`        // Inline prompt (exclusive with File)
+	File         string `yaml:"file"`          // Prompt file, relative to the job file
+	AspectRatio  string `yaml:"aspect_ratio"`  // Overrides the configured aspect ratio
+	ImageSize    string `yaml:"image_size"`    // Overrides the configured image size
+	ImageLang    string `yaml:"image_lang"`    // Overrides the configured image language
+	Model        string `yaml:"model"`         // Overrides the configured model
+	OutputDir    string `yaml:"output_dir"`    // Overrides the output directory, relative to the job file
+	ResearchOnly bool   `yaml:"research_only"` // Skip image generation
+	ImageOnly    bool   `yaml:"image_only"`    // Skip research
+}
+
+// jobFile is the top-level structure of a job file.
+type jobFile struct {
+	Jobs []JobSpec `yaml:"jobs"`
+}
+
+// JobReport is the batch report written after running a job file.
+type JobReport struct {
+	JobFile    string            `json:"job_file"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Jobs       []JobReportResult `json:"jobs"`
+}
+
+// JobReportResult is the status of a single job in the batch report.
+type JobReportResult struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+}
+
+// LoadJobFile loads and validates a job file.
+//
+// Relative prompt file and output paths are resolved against the job file directory.
+func LoadJobFile(path string) ([]JobSpec, error) {
+	data, err := ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job file: %w", err)
+	}
+
+	var spec jobFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&spec); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse job file: %w", err)
+	}
+
+	if err := ValidateJobs(spec.Jobs); err != nil {
+		return nil, err
+	}
+
+	baseDir := filepath.Dir(path)
+	for i := range spec.Jobs {
+		if spec.Jobs[i].File != "" && !filepath.IsAbs(spec.Jobs[i].File) {
+			spec.Jobs[i].File = filepath.Join(baseDir, spec.Jobs[i].File)
+		}
+		if spec.Jobs[i].OutputDir != "" && !filepath.IsAbs(spec.Jobs[i].OutputDir) {
+			spec.Jobs[i].OutputDir = filepath.Join(baseDir, spec.Jobs[i].OutputDir)
+		}
+	}
+
+	return spec.Jobs, nil
+}
+
+// ValidateJobs validates job specs.
+func ValidateJobs(jobs []JobSpec) error {
+	if len(jobs) == 0 {
+		return fmt.Errorf("job file has no jobs")
+	}
+
+	var errs []error
+	names := make(map[string]bool)
+	for i, job := range jobs {
+		label := fmt.Sprintf("jobs[%d]", i)
+		if job.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", label))
+		} else {
+			label = fmt.Sprintf("job %q", job.Name)
+			if names[job.Name] {
+				errs = append(errs, fmt.Errorf("%s: duplicate name", label))
+			}
+			names[job.Name] = true
+		}
+		if (job.Prompt == "") == (job.File == "") {
+			errs = append(errs, fmt.Errorf("%s: exactly one of prompt or file is required", label))
+		}
+		if job.ResearchOnly && job.ImageOnly {
+			errs = append(errs, fmt.Errorf("%s: research_only and image_only cannot both be set", label))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// selectJobs returns the jobs whose name is in only (all jobs when only is empty).
+func selectJobs(jobs []JobSpec, only []string) ([]JobSpec, error) {
+	if len(only) == 0 {
+		return jobs, nil
+	}
+
+	known := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		known[job.Name] = true
+	}
+	for _, name := range only {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown job: %s", name)
+		}
+	}
+
+	var selected []JobSpec
+	for _, job := range jobs {
+		if slices.Contains(only, job.Name) {
+			selected = append(selected, job)
+		}
+	}
+	return selected, nil
+}
+
+// jobOptions builds pipeline options and configuration for a job.
+//
+// Values set in the job take priority over front matter in its prompt file, like command-line flags.
+func jobOptions(job JobSpec, base *Options, config *ViperConfig) (*Options, *ViperConfig) {
+	jobConfig := *config
+	opts := *base
+	opts.Prompt = job.Prompt
+	opts.File = job.File
+	opts.ResearchOnly = job.ResearchOnly
+	opts.ImageOnly = job.ImageOnly
+	opts.Model = config.Model
+	opts.AspectRatio = config.AspectRatio
+	opts.ImageSize = config.ImageSize
+	opts.Count = config.ImageCount
+	opts.SetFlags = maps.Clone(base.SetFlags)
+	if opts.SetFlags == nil {
+		opts.SetFlags = make(map[string]bool)
+	}
+
+	if job.AspectRatio != "" {
+		opts.AspectRatio = job.AspectRatio
+		opts.SetFlags["aspect-ratio"] = true
+	}
+	if job.ImageSize != "" {
+		opts.ImageSize = job.ImageSize
+		opts.SetFlags["image-size"] = true
+	}
+	if job.Model != "" {
+		opts.Model = job.Model
+		opts.SetFlags["model"] = true
+	}
+	if job.ImageLang != "" {
+		jobConfig.ImageLang = job.ImageLang
+		opts.SetFlags["image-lang"] = true
+	}
+	if job.ResearchOnly || job.ImageOnly {
+		opts.SetFlags["research-only"] = true
+	}
+	if job.OutputDir != "" {
+		jobConfig.OutputDir = job.OutputDir
+		opts.Output = job.OutputDir
+	}
+
+	return &opts, &jobConfig
+}
+
+// printJobPlan prints the jobs that would run.
+func printJobPlan(w io.Writer, jobs []JobSpec, config *ViperConfig) {
+	fmt.Fprintf(w, "Dry run: %d job(s) would run\n", len(jobs))
+	for _, job := range jobs {
+		source := "prompt"
+		if job.File != "" {
+			source = job.File
+		}
+		stages := "research + image"
+		switch {
+		case job.ResearchOnly:
+			stages = "research only"
+		case job.ImageOnly:
+			stages = "image only"
+		}
+		fmt.Fprintf(w, "  %s: %s (%s, aspect ratio %s, language %s)\n",
+			job.Name, source, stages, cmp.Or(job.AspectRatio, config.AspectRatio), cmp.Or(job.ImageLang, config.ImageLang))
+	}
+}
+
+// RunJobs executes jobs in order and writes a batch report to the output directory.
+//
+// Up to Concurrency jobs run in parallel. A failed job does not stop the others unless FailFast is set.
+// An interrupt stops starting new jobs.
+func RunJobs(ctx context.Context, jobFilePath string, jobs []JobSpec, base *Options, config *ViperConfig) error {
+	report := &JobReport{
+		JobFile:   jobFilePath,
+		StartedAt: time.Now(),
+	}
+
+	results := make([]JobReportResult, len(jobs))
+	jobErrs := runPool(ctx, base.Concurrency, len(jobs), base.FailFast, func(ctx context.Context, i int) error {
+		job := jobs[i]
+		fmt.Fprintf(base.stdout(), "\n=== [%d/%d] %s ===\n", i+1, len(jobs), job.Name)
+		opts, jobConfig := jobOptions(job, base, config)
+
+		started := time.Now()
+		err := runPipeline(ctx, opts, jobConfig)
+		results[i] = JobReportResult{
+			Name:     job.Name,
+			Status:   JobStatusSucceeded,
+			Duration: time.Since(started).Seconds(),
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Job %s failed: %v\n", job.Name, err)
+			results[i].Status = JobStatusFailed
+			results[i].Error = err.Error()
+		}
+		return err
+	})
+
+	var errs []error
+	for i, err := range jobErrs {
+		if errors.Is(err, errTaskSkipped) {
+			results[i] = JobReportResult{Name: jobs[i].Name, Status: JobStatusSkipped}
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("job %s: %w", jobs[i].Name, err))
+		}
+	}
+	report.Jobs = results
+	report.FinishedAt = time.Now()
+
+	reportPath, err := writeJobReport(report, config)
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		fmt.Fprintf(base.stdout(), "\nBatch report: %s\n", reportPath)
+	}
+
+	succeeded := 0
+	for _, result := range report.Jobs {
+		if result.Status == JobStatusSucceeded {
+			succeeded++
+		}
+	}
+	fmt.Fprintf(base.stdout(), "Jobs succeeded: %d/%d\n", succeeded, len(jobs))
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d jobs did not succeed: %w", len(jobs)-succeeded, len(jobs), errors.Join(errs...))
+	}
+	return nil
+}
+
+// writeJobReport writes the batch report as JSON to the reports directory.
+func writeJobReport(report *JobReport, config *ViperConfig) (string, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal job report: %w", err)
+	}
+
+	reportPath := filepath.Join(config.ReportsDir(), report.StartedAt.Format(timestampLayout)+"_jobs.json")
+	if err := WriteFile(reportPath, data); err != nil {
+		return "", fmt.Errorf("failed to write job report: %w", err)
+	}
+	return reportPath, nil
+}
@@ -0,0 +1,223 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeJobFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jobs.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadJobFile(t *testing.T) {
+	path := writeJobFile(t, `jobs:
+  - name: weekly
+    file: prompts/weekly.md
+    aspect_ratio: "1:1"
+    image_lang: English
+    output_dir: out/weekly
+  - name: inline
+    prompt: Research AI trends
+    research_only: true
+`)
+
+	jobs, err := LoadJobFile(path)
+	if err != nil {
+		t.Fatalf("LoadJobFile() error = %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("LoadJobFile() = %d jobs, want 2", len(jobs))
+	}
+
+	// Relative paths are resolved against the job file directory
+	baseDir := filepath.Dir(path)
+	if want := filepath.Join(baseDir, "prompts", "weekly.md"); jobs[0].File != want {
+		t.Errorf("File = %s, want %s", jobs[0].File, want)
+	}
+	if want := filepath.Join(baseDir, "out", "weekly"); jobs[0].OutputDir != want {
+		t.Errorf("OutputDir = %s, want %s", jobs[0].OutputDir, want)
+	}
+	if jobs[0].AspectRatio != "1:1" || jobs[0].ImageLang != "English" {
+		t.Errorf("jobs[0] = %+v", jobs[0])
+	}
+	if jobs[1].Prompt != "Research AI trends" || !jobs[1].ResearchOnly {
+		t.Errorf("jobs[1] = %+v", jobs[1])
+	}
+}
+
+func TestLoadJobFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "empty",
+			content: "",
+			wantErr: "no jobs",
+		},
+		{
+			name:    "unknown key",
+			content: "jobs:\n  - name: a\n    prompt: x\n    aspect: \"1:1\"\n",
+			wantErr: "failed to parse job file",
+		},
+		{
+			name:    "missing name",
+			content: "jobs:\n  - prompt: x\n",
+			wantErr: "jobs[0]: name is required",
+		},
+		{
+			name:    "duplicate name",
+			content: "jobs:\n  - name: a\n    prompt: x\n  - name: a\n    prompt: y\n",
+			wantErr: "duplicate name",
+		},
+		{
+			name:    "prompt and file",
+			content: "jobs:\n  - name: a\n    prompt: x\n    file: a.md\n",
+			wantErr: "exactly one of prompt or file",
+		},
+		{
+			name:    "no prompt",
+			content: "jobs:\n  - name: a\n",
+			wantErr: "exactly one of prompt or file",
+		},
+		{
+			name:    "conflicting stages",
+			content: "jobs:\n  - name: a\n    prompt: x\n    research_only: true\n    image_only: true\n",
+			wantErr: "cannot both be set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadJobFile(writeJobFile(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadJobFile() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelectJobs(t *testing.T) {
+	jobs := []JobSpec{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+
+	selected, err := selectJobs(jobs, []string{"c", "a"})
+	if err != nil {
+		t.Fatalf("selectJobs() error = %v", err)
+	}
+	// Job file order is kept
+	if len(selected) != 2 || selected[0].Name != "a" || selected[1].Name != "c" {
+		t.Errorf("selectJobs() = %+v, want a and c", selected)
+	}
+
+	if _, err := selectJobs(jobs, []string{"unknown"}); err == nil {
+		t.Error("selectJobs() should fail for an unknown job")
+	}
+}
+
+func TestJobOptions(t *testing.T) {
+	config := &ViperConfig{Model: "config-model", AspectRatio: "16:9", ImageSize: "2K", ImageLang: "Japanese", OutputDir: "/out"}
+	base := &Options{Verbose: VerbosityDebug}
+
+	opts, jobConfig := jobOptions(JobSpec{Name: "a", Prompt: "x", AspectRatio: "1:1", ImageLang: "English"}, base, config)
+
+	if opts.AspectRatio != "1:1" || opts.Model != "config-model" || opts.ImageSize != "2K" || opts.Verbose != VerbosityDebug {
+		t.Errorf("options = %+v", opts)
+	}
+	if jobConfig.ImageLang != "English" || config.ImageLang != "Japanese" {
+		t.Errorf("ImageLang = %s (base %s), want English (base Japanese)", jobConfig.ImageLang, config.ImageLang)
+	}
+	// Job values win over front matter like flags
+	if !opts.FlagSet("aspect-ratio") || !opts.FlagSet("image-lang") || opts.FlagSet("model") {
+		t.Errorf("SetFlags = %v", opts.SetFlags)
+	}
+}
+
+func TestRunJobs_Report(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir(), StateDir: t.TempDir(), ImageLang: "English"}
+	jobs := []JobSpec{
+		{Name: "missing", File: filepath.Join(t.TempDir(), "missing.md")},
+		{Name: "inline", Prompt: "Research AI trends", ImageOnly: true},
+	}
+
+	// Dry-run keeps the pipelines offline
+	err := RunJobs(context.Background(), "jobs.yaml", jobs, &Options{DryRun: true}, config)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 jobs did not succeed") {
+		t.Fatalf("RunJobs() error = %v, want 1 of 2 failed", err)
+	}
+	if ExitCode(err) != ExitUsage {
+		t.Errorf("ExitCode() = %d, want %d", ExitCode(err), ExitUsage)
+	}
+
+	reports, err := filepath.Glob(filepath.Join(config.ReportsDir(), "*_jobs.json"))
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("report files = %v (err = %v), want 1", reports, err)
+	}
+	data, err := os.ReadFile(reports[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report JobReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	if len(report.Jobs) != 2 || report.Jobs[0].Status != JobStatusFailed || report.Jobs[1].Status != JobStatusSucceeded {
+		t.Errorf("report jobs = %+v", report.Jobs)
+	}
+	if report.Jobs[0].Error == "" {
+		t.Error("failed job should record the error")
+	}
+}
+
+func TestRunJobs_FailFastSkipsRemaining(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir(), StateDir: t.TempDir()}
+	jobs := []JobSpec{
+		{Name: "missing", File: filepath.Join(t.TempDir(), "missing.md")},
+		{Name: "inline", Prompt: "x", ImageOnly: true},
+	}
+
+	if err := RunJobs(context.Background(), "jobs.yaml", jobs, &Options{DryRun: true, FailFast: true}, config); err == nil {
+		t.Fatal("RunJobs() should return error")
+	}
+
+	reports, _ := filepath.Glob(filepath.Join(config.ReportsDir(), "*_jobs.json"))
+	if len(reports) != 1 {
+		t.Fatalf("report files = %v, want 1", reports)
+	}
+	data, _ := os.ReadFile(reports[0])
+	var report JobReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Jobs) != 2 || report.Jobs[1].Status != JobStatusSkipped {
+		t.Errorf("report jobs = %+v, want the second job skipped", report.Jobs)
+	}
+}
+
+func TestRunCommand_DryRun(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := writeJobFile(t, "jobs:\n  - name: weekly\n    prompt: x\n  - name: monthly\n    prompt: y\n")
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"run", path, "--dry-run", "--only", "monthly"})
+	buf := new(bytes.Buffer)
+	cmd.SetOut(buf)
+	cmd.SetErr(buf)
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "monthly") || strings.Contains(buf.String(), "weekly") {
+		t.Errorf("plan should list only the selected job: %q", buf.String())
+	}
+}
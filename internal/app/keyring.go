@@ -0,0 +1,104 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/term"
+)
+
+// Entry of the API key in the keyring of the OS.
+const (
+	keyringService = "deepviz"
+	keyringAccount = "api_key"
+)
+
+// keyringTimeout bounds the operations of the keyring, which may wait for it to be unlocked.
+const keyringTimeout = 30 * time.Second
+
+// ErrKeyringNotFound is returned by Keyring.Get when the keyring holds no API key.
+var ErrKeyringNotFound = errors.New("no API key in the keyring (store one with `deepviz config set-key`)")
+
+// Keyring stores the API key in the credential store of the OS (use_keyring).
+type Keyring interface {
+	// Get returns the API key, or ErrKeyringNotFound
+	Get() (string, error)
+	// Set stores the API key, replacing the previous one
+	Set(apiKey string) error
+}
+
+// OSKeyring returns the keyring of the platform: the macOS Keychain, the Secret Service on Linux
+// and the BSDs (over D-Bus, e.g. GNOME Keyring or KeePassXC) or the Windows Credential Manager.
+// It fails on other platforms.
+func OSKeyring() (Keyring, error) {
+	switch runtime.GOOS {
+	case "darwin", "linux", "freebsd", "openbsd", "netbsd", "windows":
+		return osKeyring{}, nil
+	}
+	return nil, fmt.Errorf("no keyring is supported on %s (use api_key_file or api_key_cmd instead)", runtime.GOOS)
+}
+
+// osKeyring is the Keyring of the OS, accessed with go-keyring.
+type osKeyring struct{}
+
+func (osKeyring) Get() (string, error) {
+	var apiKey string
+	err := withKeyringTimeout(func() error {
+		var err error
+		apiKey, err = keyring.Get(keyringService, keyringAccount)
+		return err
+	})
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrKeyringNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the keyring: %w", err)
+	}
+	apiKey = strings.TrimRight(apiKey, " \t\r\n")
+	if apiKey == "" {
+		return "", ErrKeyringNotFound
+	}
+	return apiKey, nil
+}
+
+func (osKeyring) Set(apiKey string) error {
+	return withKeyringTimeout(func() error {
+		return keyring.Set(keyringService, keyringAccount, apiKey)
+	})
+}
+
+// withKeyringTimeout runs an operation of the keyring, giving up after keyringTimeout.
+func withKeyringTimeout(operation func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- operation() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(keyringTimeout):
+		return fmt.Errorf("the keyring did not answer within %s (is it locked?)", keyringTimeout)
+	}
+}
+
+// readSecret reads a secret from in: a line typed without echo when in is a terminal, or the whole
+// input otherwise (e.g., `pass show gemini | deepviz config set-key`).
+func readSecret(in io.Reader, prompt io.Writer) (string, error) {
+	file, ok := in.(*os.File)
+	if !ok || !term.IsTerminal(int(file.Fd())) {
+		data, err := io.ReadAll(in)
+		return strings.TrimSpace(string(data)), err
+	}
+
+	fmt.Fprint(prompt, "Gemini API key: ")
+	secret, err := term.ReadPassword(int(file.Fd()))
+	fmt.Fprintln(prompt)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
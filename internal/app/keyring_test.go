@@ -0,0 +1,107 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// fakeKeyring is a Keyring in memory.
+type fakeKeyring struct {
+	apiKey string
+	err    error
+}
+
+func (k *fakeKeyring) Get() (string, error) {
+	if k.err != nil {
+		return "", k.err
+	}
+	if k.apiKey == "" {
+		return "", ErrKeyringNotFound
+	}
+	return k.apiKey, nil
+}
+
+func (k *fakeKeyring) Set(apiKey string) error {
+	if k.err != nil {
+		return k.err
+	}
+	k.apiKey = apiKey
+	return nil
+}
+
+func TestOSKeyring(t *testing.T) {
+	keyring.MockInit()
+	k, err := OSKeyring()
+	if err != nil {
+		t.Skipf("OSKeyring() error = %v", err)
+	}
+
+	if _, err := k.Get(); !errors.Is(err, ErrKeyringNotFound) {
+		t.Errorf("Get() of an empty keyring error = %v, want ErrKeyringNotFound", err)
+	}
+	if err := k.Set("AIzaTestKey"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, err := k.Get(); err != nil || got != "AIzaTestKey" {
+		t.Errorf("Get() = %q, %v, want the stored key", got, err)
+	}
+
+	keyring.MockInitWithError(errors.New("keyring is locked"))
+	if _, err := k.Get(); err == nil || errors.Is(err, ErrKeyringNotFound) || !strings.Contains(err.Error(), "keyring is locked") {
+		t.Errorf("Get() error = %v, want the error of the keyring", err)
+	}
+}
+
+func TestReadSecret(t *testing.T) {
+	var prompt bytes.Buffer
+	got, err := readSecret(strings.NewReader("AIzaTestKey\n"), &prompt)
+	if err != nil || got != "AIzaTestKey" {
+		t.Errorf("readSecret() = %q, %v, want the piped key", got, err)
+	}
+	if prompt.Len() != 0 {
+		t.Errorf("prompt = %q, want no prompt without a terminal", prompt.String())
+	}
+}
+
+func TestConfigSetKeyCommand(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	errUnavailable := errors.New("no keyring is supported")
+	tests := []struct {
+		name      string
+		stdin     string
+		openErr   error
+		wantKey   string
+		wantError bool
+	}{
+		{name: "stored", stdin: "AIzaSyTestKey1234\n", wantKey: "AIzaSyTestKey1234"},
+		{name: "empty key", stdin: "\n", wantError: true},
+		{name: "key with spaces", stdin: "AIza Test\n", wantError: true},
+		{name: "no keyring", stdin: "AIzaSyTestKey1234\n", openErr: errUnavailable, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring := &fakeKeyring{}
+			cmd := newConfigSetKeyCommandWith(func() (Keyring, error) { return keyring, tt.openErr })
+			cmd.SetArgs(nil)
+			cmd.SetIn(strings.NewReader(tt.stdin))
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			cmd.SetErr(&out)
+
+			err := cmd.Execute()
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Execute() error = %v, wantError %t", err, tt.wantError)
+			}
+			if keyring.apiKey != tt.wantKey {
+				t.Errorf("stored key = %q, want %q", keyring.apiKey, tt.wantKey)
+			}
+			if tt.wantKey != "" && (strings.Contains(out.String(), tt.wantKey) || !strings.Contains(out.String(), "use_keyring: true")) {
+				t.Errorf("output = %q, want the masked key and the use_keyring hint", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// listingCacheTTL is how long a listed set of models or agents is reused without fetching it again.
+const listingCacheTTL = 10 * time.Minute
+
+// completionTimeout bounds the live listing done by shell completion.
+const completionTimeout = 3 * time.Second
+
+// listingCache is a model or agent list cached in the state directory.
+type listingCache[T any] struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Items     []T       `json:"items"`
+}
+
+// loadListingCache reads a listing cache regardless of its age.
+func loadListingCache[T any](path string) (*listingCache[T], error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cache listingCache[T]
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse listing cache: %w", err)
+	}
+	return &cache, nil
+}
+
+// fresh reports whether the cache is younger than listingCacheTTL.
+func (c *listingCache[T]) fresh(now time.Time) bool {
+	return now.Sub(c.FetchedAt) <= listingCacheTTL
+}
+
+// saveListingCache writes items to a listing cache fetched at now.
+func saveListingCache[T any](path string, items []T, now time.Time) error {
+	data, err := json.Marshal(&listingCache[T]{FetchedAt: now, Items: items})
+	if err != nil {
+		return fmt.Errorf("failed to marshal listing cache: %w", err)
+	}
+	if err := WriteFile(path, data); err != nil {
+		return fmt.Errorf("failed to write listing cache: %w", err)
+	}
+	return nil
+}
+
+// completionListing returns the items to complete from the listing cache at path.
+//
+// A fresh cache is used as is. Otherwise the items are fetched within completionTimeout, and a
+// stale cache is used when fetching fails so that completion keeps working offline. ok is false
+// when there is nothing to complete from; errors are never reported since completion output goes
+// to the shell.
+func completionListing[T any](path string, now time.Time, fetch func(context.Context) ([]T, error)) (items []T, ok bool) {
+	cache, err := loadListingCache[T](path)
+	if err == nil && cache.fresh(now) {
+		return cache.Items, true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	if items, err := fetch(ctx); err == nil {
+		return items, true
+	}
+
+	if cache != nil {
+		return cache.Items, true
+	}
+	return nil, false
+}
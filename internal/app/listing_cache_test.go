@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListingCache_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agents.json")
+	fetchedAt := time.Date(2025, 12, 24, 10, 0, 0, 0, time.UTC)
+	if err := saveListingCache(path, []AgentInfo{{Name: "a"}}, fetchedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := loadListingCache[AgentInfo](path)
+	if err != nil {
+		t.Fatalf("loadListingCache() error = %v", err)
+	}
+	if len(cache.Items) != 1 || cache.Items[0].Name != "a" || !cache.FetchedAt.Equal(fetchedAt) {
+		t.Errorf("cache = %+v, want agent a fetched at %v", cache, fetchedAt)
+	}
+	if !cache.fresh(fetchedAt.Add(listingCacheTTL / 2)) {
+		t.Error("cache should be fresh within the TTL")
+	}
+	if cache.fresh(fetchedAt.Add(listingCacheTTL + time.Second)) {
+		t.Error("cache should expire after the TTL")
+	}
+
+	if _, err := loadListingCache[AgentInfo](filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("loadListingCache() should fail for a missing cache")
+	}
+}
+
+func TestCompletionListing(t *testing.T) {
+	fetchedAt := time.Date(2025, 12, 24, 10, 0, 0, 0, time.UTC)
+	fetched := func(context.Context) ([]string, error) { return []string{"fetched"}, nil }
+	failed := func(context.Context) ([]string, error) { return nil, errors.New("offline") }
+
+	tests := []struct {
+		name      string
+		cached    bool
+		now       time.Time
+		fetch     func(context.Context) ([]string, error)
+		wantItems []string
+		wantOK    bool
+	}{
+		{name: "fresh cache skips fetch", cached: true, now: fetchedAt.Add(time.Minute), fetch: failed, wantItems: []string{"cached"}, wantOK: true},
+		{name: "stale cache is refreshed", cached: true, now: fetchedAt.Add(time.Hour), fetch: fetched, wantItems: []string{"fetched"}, wantOK: true},
+		{name: "stale cache used offline", cached: true, now: fetchedAt.Add(time.Hour), fetch: failed, wantItems: []string{"cached"}, wantOK: true},
+		{name: "no cache", now: fetchedAt, fetch: fetched, wantItems: []string{"fetched"}, wantOK: true},
+		{name: "no cache offline", now: fetchedAt, fetch: failed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.json")
+			if tt.cached {
+				if err := saveListingCache(path, []string{"cached"}, fetchedAt); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			items, ok := completionListing(path, tt.now, tt.fetch)
+			if ok != tt.wantOK || len(items) != len(tt.wantItems) || (len(items) > 0 && items[0] != tt.wantItems[0]) {
+				t.Errorf("completionListing() = %v, %v, want %v, %v", items, ok, tt.wantItems, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package app
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// activeLogAge is the time since its last write under which a log may still be written by a
+// running pipeline. Such logs are never compressed.
+const activeLogAge = time.Hour
+
+// LogRetention is the retention policy of the log files (log_retention_days, log_max_files and
+// log_max_size_mb).
+type LogRetention struct {
+	MaxAge   time.Duration // Remove logs older than this (zero keeps them)
+	MaxFiles int           // Keep the most recent logs (zero for no limit)
+	MaxSize  int64         // Compress logs larger than this many bytes to .gz (zero for no limit)
+}
+
+// LogRetention returns the log retention policy of the configuration.
+func (c *ViperConfig) LogRetention() LogRetention {
+	return LogRetention{
+		MaxAge:   time.Duration(c.LogRetentionDays) * 24 * time.Hour,
+		MaxFiles: c.LogMaxFiles,
+		MaxSize:  int64(c.LogMaxSizeMB) << 20,
+	}
+}
+
+// Enabled reports whether the policy sets any limit.
+func (r LogRetention) Enabled() bool {
+	return r.MaxAge > 0 || r.MaxFiles > 0 || r.MaxSize > 0
+}
+
+// isLogFile reports whether a file name is a log, plain or compressed.
+func isLogFile(name string) bool {
+	return strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".log.gz")
+}
+
+// PlanLogRetention returns the log files removed and the log files compressed by a retention
+// policy, from the newest log.
+//
+// Logs are dated by the timestamp of their run, or by their modification time when it cannot be
+// parsed. Files other than logs are never returned.
+func PlanLogRetention(config *ViperConfig, policy LogRetention, now time.Time) (remove, compress []CleanFile, err error) {
+	files, err := runArtifactFiles(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type datedLog struct {
+		file     CleanFile
+		date     time.Time
+		modified time.Time
+	}
+	var logs []datedLog
+	namer := config.Namer()
+	for _, runFiles := range files {
+		for _, file := range runFiles {
+			if file.Kind != ArtifactLogs || !isLogFile(file.Path) {
+				continue
+			}
+			info, err := os.Stat(file.Path)
+			if err != nil {
+				continue
+			}
+			date, err := namer.ParseTimestamp(file.Timestamp)
+			if err != nil {
+				date = info.ModTime()
+			}
+			logs = append(logs, datedLog{file: file, date: date, modified: info.ModTime()})
+		}
+	}
+	slices.SortFunc(logs, func(a, b datedLog) int {
+		if c := b.date.Compare(a.date); c != 0 {
+			return c
+		}
+		return strings.Compare(b.file.Path, a.file.Path)
+	})
+
+	for i, log := range logs {
+		switch {
+		case policy.MaxFiles > 0 && i >= policy.MaxFiles,
+			policy.MaxAge > 0 && log.date.Before(now.Add(-policy.MaxAge)):
+			remove = append(remove, log.file)
+		case policy.MaxSize > 0 && log.file.Size > policy.MaxSize && strings.HasSuffix(log.file.Path, ".log") &&
+			log.modified.Before(now.Add(-activeLogAge)):
+			compress = append(compress, log.file)
+		}
+	}
+	return remove, compress, nil
+}
+
+// CompressLogFiles compresses the planned logs to .gz next to them and removes the originals.
+//
+// Every path is checked to be inside the output directory before it is replaced. Returns the
+// number of compressed logs and the bytes freed.
+func CompressLogFiles(config *ViperConfig, plan []CleanFile) (int, int64, error) {
+	var compressed int
+	var freed int64
+	for _, file := range plan {
+		if err := validateCleanPath(config.OutputDir, file.Path); err != nil {
+			return compressed, freed, err
+		}
+		size, err := compressFile(file.Path)
+		if err != nil {
+			return compressed, freed, fmt.Errorf("failed to compress %s: %w", file.Path, err)
+		}
+		compressed++
+		freed += file.Size - size
+	}
+	return compressed, freed, nil
+}
+
+// compressFile replaces a file with its gzip compression (path + ".gz") and returns the
+// compressed size. The modification time is kept so that the log ages as before.
+func compressFile(path string) (int64, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return 0, err
+	}
+
+	os.Chtimes(path+".gz", info.ModTime(), info.ModTime())
+	compressedInfo, err := os.Stat(path + ".gz")
+	if err != nil {
+		return 0, err
+	}
+	return compressedInfo.Size(), os.Remove(path)
+}
+
+// applyLogRetention applies the configured log retention policy on startup, before the logs of
+// the run are created, and logs what was pruned. Nothing is pruned in a dry run.
+func applyLogRetention(opts *Options, config *ViperConfig) {
+	policy := config.LogRetention()
+	if !policy.Enabled() || opts.DryRun {
+		return
+	}
+
+	// Progress events replace the console log
+	level := opts.logLevel()
+	if opts.Progress != nil {
+		level = quietLevel
+	}
+	logger := NewSlogLogger(ConsoleLogOptions{
+		Writer: LogConsole(config.LogOutput),
+		Format: config.LogFormat,
+		Level:  level,
+	}, FileLogOptions{}, config.APIKey)
+
+	remove, compress, err := PlanLogRetention(config, policy, time.Now())
+	if err != nil {
+		logger.Warn("Failed to apply the log retention policy", "error", err)
+		return
+	}
+	if len(remove) == 0 && len(compress) == 0 {
+		return
+	}
+	for _, file := range remove {
+		logger.Debug("Removing log file", "path", file.Path)
+	}
+	removed, freed, err := RemoveCleanFiles(config, remove)
+	if err != nil {
+		logger.Warn("Failed to remove log files", "error", err)
+	}
+	for _, file := range compress {
+		logger.Debug("Compressing log file", "path", file.Path)
+	}
+	compressed, compressedFreed, err := CompressLogFiles(config, compress)
+	if err != nil {
+		logger.Warn("Failed to compress log files", "error", err)
+	}
+	logger.Info("Log files pruned", "removed", removed, "compressed", compressed, "freed", formatSize(freed+compressedFreed))
+}
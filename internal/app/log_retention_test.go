@@ -0,0 +1,195 @@
+package app
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeLogFixtures writes a logs directory with logs of runs from 2025-09-01 to 2025-12-24, a
+// compressed log and files other than logs.
+func writeLogFixtures(t *testing.T, outputDir string) {
+	t.Helper()
+	logsDir := filepath.Join(outputDir, "logs")
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Date(2025, 12, 1, 0, 0, 0, 0, time.Local)
+	files := map[string]string{
+		"20251224_103045.log":    strings.Repeat("x", 2048),
+		"20251201_090000.log":    strings.Repeat("y", 2048),
+		"20251120_080000.log":    "small",
+		"20251001_120000.log.gz": "compressed",
+		"20250901_120000.log":    "oldest",
+		"notes.txt":              "not a log",
+	}
+	for name, content := range files {
+		path := filepath.Join(logsDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An image of an old run is not a log
+	if err := os.MkdirAll(filepath.Join(outputDir, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "images", "20250901_120000.png"), pngHeader, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// planNames returns the file names of a plan in order.
+func planNames(plan []CleanFile) []string {
+	var names []string
+	for _, file := range plan {
+		names = append(names, filepath.Base(file.Path))
+	}
+	return names
+}
+
+func TestPlanLogRetention(t *testing.T) {
+	outputDir := t.TempDir()
+	writeLogFixtures(t, outputDir)
+	config := &ViperConfig{OutputDir: outputDir}
+	now := time.Date(2025, 12, 31, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name         string
+		policy       LogRetention
+		now          time.Time
+		wantRemove   []string
+		wantCompress []string
+	}{
+		{
+			name:       "max files",
+			policy:     LogRetention{MaxFiles: 3},
+			now:        now,
+			wantRemove: []string{"20251001_120000.log.gz", "20250901_120000.log"},
+		},
+		{
+			name:       "max age",
+			policy:     LogRetention{MaxAge: 60 * 24 * time.Hour},
+			now:        now,
+			wantRemove: []string{"20251001_120000.log.gz", "20250901_120000.log"},
+		},
+		{
+			name:         "max size",
+			policy:       LogRetention{MaxSize: 1024},
+			now:          now,
+			wantCompress: []string{"20251224_103045.log", "20251201_090000.log"},
+		},
+		{
+			name:       "removed logs are not compressed",
+			policy:     LogRetention{MaxFiles: 1, MaxSize: 1024},
+			now:        now,
+			wantRemove: []string{"20251201_090000.log", "20251120_080000.log", "20251001_120000.log.gz", "20250901_120000.log"},
+			wantCompress: []string{
+				"20251224_103045.log",
+			},
+		},
+		{
+			name:   "recently written logs are not compressed",
+			policy: LogRetention{MaxSize: 1024},
+			now:    time.Date(2025, 12, 1, 0, 30, 0, 0, time.Local),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remove, compress, err := PlanLogRetention(config, tt.policy, tt.now)
+			if err != nil {
+				t.Fatalf("PlanLogRetention() error = %v", err)
+			}
+			if got := planNames(remove); !slices.Equal(got, tt.wantRemove) {
+				t.Errorf("remove = %v, want %v", got, tt.wantRemove)
+			}
+			if got := planNames(compress); !slices.Equal(got, tt.wantCompress) {
+				t.Errorf("compress = %v, want %v", got, tt.wantCompress)
+			}
+		})
+	}
+}
+
+func TestCompressLogFiles(t *testing.T) {
+	outputDir := t.TempDir()
+	writeLogFixtures(t, outputDir)
+	config := &ViperConfig{OutputDir: outputDir}
+
+	_, plan, err := PlanLogRetention(config, LogRetention{MaxSize: 1024}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, freed, err := CompressLogFiles(config, plan)
+	if err != nil {
+		t.Fatalf("CompressLogFiles() error = %v", err)
+	}
+	if compressed != 2 || freed <= 0 {
+		t.Errorf("CompressLogFiles() = %d, %d, want 2 logs and freed bytes", compressed, freed)
+	}
+
+	logPath := filepath.Join(outputDir, "logs", "20251224_103045.log")
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Errorf("original log still exists: %v", err)
+	}
+	file, err := os.Open(logPath + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != strings.Repeat("x", 2048) {
+		t.Errorf("decompressed log = %q, want the original content", data)
+	}
+
+	// Compressed logs keep their age and are not compressed again
+	remove, compress, err := PlanLogRetention(config, LogRetention{MaxFiles: 4, MaxSize: 1024}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compress) != 0 || !slices.Equal(planNames(remove), []string{"20250901_120000.log"}) {
+		t.Errorf("plan = %v, %v, want the oldest log removed only", planNames(remove), planNames(compress))
+	}
+}
+
+func TestApplyLogRetention(t *testing.T) {
+	outputDir := t.TempDir()
+	writeLogFixtures(t, outputDir)
+	config := &ViperConfig{OutputDir: outputDir, LogOutput: LogOutputFileOnly, LogMaxFiles: 2}
+
+	// Nothing is pruned in a dry run
+	applyLogRetention(&Options{DryRun: true}, config)
+	if _, err := os.Stat(filepath.Join(outputDir, "logs", "20250901_120000.log")); err != nil {
+		t.Fatalf("dry run removed a log: %v", err)
+	}
+
+	applyLogRetention(&Options{}, config)
+	entries, err := os.ReadDir(filepath.Join(outputDir, "logs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"20251201_090000.log", "20251224_103045.log", "notes.txt"}; !slices.Equal(names, want) {
+		t.Errorf("logs = %v, want %v", names, want)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "images", "20250901_120000.png")); err != nil {
+		t.Errorf("image of an old run removed: %v", err)
+	}
+}
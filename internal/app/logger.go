@@ -2,64 +2,250 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Logger is an interface for structured logging.
 type Logger interface {
 	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
 	Error(msg string, args ...any)
 	Debug(msg string, args ...any)
+	Trace(msg string, args ...any)
 }
 
+// LevelTrace is the level of raw HTTP request and response bodies, below DEBUG.
+const LevelTrace = slog.LevelDebug - 4
+
 // SlogLogger is a logger that uses slog.
 type SlogLogger struct {
-	logger *slog.Logger
+	logger  *slog.Logger
+	file    *os.File // Log file (nil when there is none or it could not be opened)
+	fileErr error    // Error opening the log file
 }
 
-// NewSlogLogger creates a new SlogLogger with JSON output.
-// Logs to both stdout and file. File output is always at DEBUG level.
-func NewSlogLogger(verbose bool, logFilePath string) *SlogLogger {
-	stdoutLevel := slog.LevelInfo
-	if verbose {
-		stdoutLevel = slog.LevelDebug
+// Destinations of the console log (log_output).
+const (
+	LogOutputStderr   = "stderr"    // Default: stdout is kept for the summary
+	LogOutputStdout   = "stdout"    // Logs mixed with the summary, as before
+	LogOutputFileOnly = "file-only" // No console log
+)
+
+// quietLevel is the console log level of quiet mode, above every level logged.
+const quietLevel = slog.LevelError + 4
+
+// Console log verbosities (the number of -v flags).
+const (
+	VerbosityDebug = 1 // -v: DEBUG messages
+	VerbosityTrace = 2 // -vv: TRACE messages (raw HTTP bodies) too
+)
+
+// VerbosityLevel returns the console log level of a verbosity: INFO by default, DEBUG with -v
+// and TRACE with -vv or more.
+func VerbosityLevel(verbose int) slog.Level {
+	switch {
+	case verbose >= VerbosityTrace:
+		return LevelTrace
+	case verbose == VerbosityDebug:
+		return slog.LevelDebug
 	}
+	return slog.LevelInfo
+}
 
-	// Create stdout handler
-	stdoutHandler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: stdoutLevel,
-	})
+// ParseLogOutput validates a log_output value.
+func ParseLogOutput(output string) (string, error) {
+	switch output {
+	case LogOutputStderr, LogOutputStdout, LogOutputFileOnly:
+		return output, nil
+	}
+	return "", fmt.Errorf("invalid log_output %q: must be %s, %s or %s", output, LogOutputStderr, LogOutputStdout, LogOutputFileOnly)
+}
 
-	// If log file path is provided, create file handler and multi-handler
-	if logFilePath != "" {
-		logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			// If file creation fails, fall back to stdout only
-			return &SlogLogger{
-				logger: slog.New(stdoutHandler),
-			}
-		}
+// LogConsole returns the console log writer of a log_output value (nil for file-only).
+func LogConsole(output string) io.Writer {
+	switch output {
+	case LogOutputStdout:
+		return os.Stdout
+	case LogOutputFileOnly:
+		return nil
+	}
+	return os.Stderr
+}
 
-		// File handler always logs at DEBUG level
-		fileHandler := slog.NewJSONHandler(logFile, &slog.HandlerOptions{
-			Level: slog.LevelDebug,
-		})
+// Formats of the console log (log_format).
+const (
+	LogFormatText = "text" // Compact lines for reading (e.g., "15:04:05 INFO Research started")
+	LogFormatJSON = "json" // JSON lines for machine parsing
+)
 
-		// Use multi-handler to write to both stdout and file
-		multiHandler := &multiHandler{
-			handlers: []slog.Handler{stdoutHandler, fileHandler},
+// ParseLogFormat validates a log_format value. Empty selects the format from the console.
+func ParseLogFormat(format string) (string, error) {
+	switch format {
+	case "", LogFormatText, LogFormatJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid log_format %q: must be %s or %s", format, LogFormatText, LogFormatJSON)
+}
+
+// ConsoleLogOptions configures the console log of a SlogLogger.
+type ConsoleLogOptions struct {
+	Writer  io.Writer    // Console log destination (nil for none)
+	Format  string       // text or json (empty selects text on a terminal and json otherwise)
+	Level   slog.Level   // Minimum level logged (the zero value logs INFO messages)
+	Handler slog.Handler // Receives the console log instead of Writer, at its own level (e.g., the logger of a program)
+}
+
+// FileLogOptions configures the log file of a SlogLogger.
+type FileLogOptions struct {
+	Path  string // Log file path (empty for none)
+	Trace bool   // Persist TRACE messages, which can be huge (default stops at DEBUG)
+}
+
+// format returns the console log format, resolving an unset format from the writer.
+func (o ConsoleLogOptions) format() string {
+	if o.Format != "" {
+		return o.Format
+	}
+	if isTerminalWriter(o.Writer) {
+		return LogFormatText
+	}
+	return LogFormatJSON
+}
+
+// handler returns the console log handler, or nil when there is no console writer or handler.
+func (o ConsoleLogOptions) handler() slog.Handler {
+	if o.Handler != nil {
+		return o.Handler
+	}
+	if o.Writer == nil {
+		return nil
+	}
+
+	if o.format() == LogFormatText {
+		return newTextLogHandler(o.Writer, o.Level, NewStyler(o.Writer))
+	}
+	return slog.NewJSONHandler(o.Writer, &slog.HandlerOptions{Level: o.Level, ReplaceAttr: replaceLevelName})
+}
+
+// levelName returns the name of a level, naming LevelTrace TRACE instead of DEBUG-4.
+func levelName(level slog.Level) string {
+	if level == LevelTrace {
+		return "TRACE"
+	}
+	return level.String()
+}
+
+// replaceLevelName is a slog.HandlerOptions.ReplaceAttr naming levels with levelName.
+func replaceLevelName(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			a.Value = slog.StringValue(levelName(level))
 		}
+	}
+	return a
+}
 
-		return &SlogLogger{
-			logger: slog.New(multiHandler),
+// NewSlogLogger creates a new SlogLogger.
+// Logs to both the console and file. File output is always JSON at DEBUG level, or TRACE level
+// when file.Trace is set. The secrets (e.g., the API key) and common key patterns are masked in
+// every log output.
+func NewSlogLogger(console ConsoleLogOptions, file FileLogOptions, secrets ...string) *SlogLogger {
+	var handlers []slog.Handler
+	if consoleHandler := console.handler(); consoleHandler != nil {
+		handlers = append(handlers, consoleHandler)
+	}
+
+	// If log file path is provided, also log to the file (skipped with a warning if the file
+	// cannot be created)
+	l := &SlogLogger{}
+	if file.Path != "" {
+		fileHandler, logFile, err := newLogFileHandler(file)
+		if err == nil {
+			handlers = append(handlers, fileHandler)
+			l.file = logFile
+		} else {
+			l.fileErr = err
 		}
 	}
 
-	return &SlogLogger{
-		logger: slog.New(stdoutHandler),
+	var handler slog.Handler
+	switch len(handlers) {
+	case 0:
+		handler = slog.NewJSONHandler(io.Discard, nil)
+	case 1:
+		handler = handlers[0]
+	default:
+		// Use multi-handler to write to both the console and file
+		handler = &multiHandler{handlers: handlers}
+	}
+	l.logger = slog.New(&redactHandler{next: handler, secrets: secrets})
+
+	if l.fileErr != nil {
+		l.Warn("Failed to open log file, logging to the console only", "path", file.Path, "error", l.fileErr)
+	}
+	return l
+}
+
+// NewFileLogger creates a new SlogLogger writing JSON output to the log file only (e.g., when
+// progress is reported as NDJSON). Nothing is logged if the file cannot be created (see FileError).
+func NewFileLogger(file FileLogOptions, secrets ...string) *SlogLogger {
+	return NewSlogLogger(ConsoleLogOptions{}, file, secrets...)
+}
+
+// newLogFileHandler opens a log file for appending and returns a DEBUG (or TRACE) level handler
+// writing to it.
+func newLogFileHandler(file FileLogOptions) (slog.Handler, *os.File, error) {
+	// The log is the first file of a run directory in the per-run layout
+	if err := EnsureDir(filepath.Dir(file.Path)); err != nil {
+		return nil, nil, err
+	}
+	logFile, err := os.OpenFile(file.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// File handler logs at DEBUG level unless trace bodies are persisted
+	level := slog.LevelDebug
+	if file.Trace {
+		level = LevelTrace
+	}
+	return slog.NewJSONHandler(logFile, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: replaceLevelName,
+	}), logFile, nil
+}
+
+// FileError returns the error opening the log file, or nil if it was opened (or not requested).
+func (l *SlogLogger) FileError() error {
+	return l.fileErr
+}
+
+// Close flushes and closes the log file. Nothing is logged to the file afterwards.
+func (l *SlogLogger) Close() error {
+	if l.file == nil {
+		return nil
 	}
+	file := l.file
+	l.file = nil
+	syncErr := file.Sync()
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+// With returns a logger adding args (key-value pairs, as in slog) to every log, writing to the
+// same outputs. It takes over the log file: close the returned logger instead of l.
+func (l *SlogLogger) With(args ...any) *SlogLogger {
+	return &SlogLogger{logger: l.logger.With(args...), file: l.file, fileErr: l.fileErr}
 }
 
 // Info outputs an information log.
@@ -67,6 +253,11 @@ func (l *SlogLogger) Info(msg string, args ...any) {
 	l.logger.Info(msg, args...)
 }
 
+// Warn outputs a warning log.
+func (l *SlogLogger) Warn(msg string, args ...any) {
+	l.logger.Warn(msg, args...)
+}
+
 // Error outputs an error log.
 func (l *SlogLogger) Error(msg string, args ...any) {
 	l.logger.Error(msg, args...)
@@ -77,6 +268,11 @@ func (l *SlogLogger) Debug(msg string, args ...any) {
 	l.logger.Debug(msg, args...)
 }
 
+// Trace outputs a trace log (e.g., raw HTTP bodies).
+func (l *SlogLogger) Trace(msg string, args ...any) {
+	l.logger.Log(context.Background(), LevelTrace, msg, args...)
+}
+
 // NullLogger is a logger that outputs nothing (for testing).
 type NullLogger struct{}
 
@@ -88,12 +284,18 @@ func NewNullLogger() *NullLogger {
 // Info does nothing.
 func (l *NullLogger) Info(msg string, args ...any) {}
 
+// Warn does nothing.
+func (l *NullLogger) Warn(msg string, args ...any) {}
+
 // Error does nothing.
 func (l *NullLogger) Error(msg string, args ...any) {}
 
 // Debug does nothing.
 func (l *NullLogger) Debug(msg string, args ...any) {}
 
+// Trace does nothing.
+func (l *NullLogger) Trace(msg string, args ...any) {}
+
 // mockLogger is a mock logger for testing.
 type mockLogger struct {
 	logger *slog.Logger
@@ -132,6 +334,11 @@ func (m *mockLogger) Info(msg string, args ...any) {
 	m.logger.Info(msg, args...)
 }
 
+// Warn records a warning log.
+func (m *mockLogger) Warn(msg string, args ...any) {
+	m.logger.Warn(msg, args...)
+}
+
 // Error records an error log.
 func (m *mockLogger) Error(msg string, args ...any) {
 	m.logger.Error(msg, args...)
@@ -142,6 +349,11 @@ func (m *mockLogger) Debug(msg string, args ...any) {
 	m.logger.Debug(msg, args...)
 }
 
+// Trace records a trace log.
+func (m *mockLogger) Trace(msg string, args ...any) {
+	m.logger.Log(context.Background(), LevelTrace, msg, args...)
+}
+
 // mockLogHandler is a custom slog handler for testing.
 type mockLogHandler struct {
 	buffer *mockLogBuffer
@@ -241,3 +453,114 @@ func (h *multiHandler) WithGroup(name string) slog.Handler {
 }
 
 var _ slog.Handler = (*multiHandler)(nil)
+
+// textLogHandler is a slog.Handler writing compact lines for reading on a terminal, e.g.
+// "15:04:05 INFO Research started interaction_id=abc".
+type textLogHandler struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	level  slog.Leveler
+	style  Styler // Colors of the level names
+	attrs  string // Attributes added with WithAttrs, already formatted
+	prefix string // Key prefix of the groups opened with WithGroup (e.g., "request.")
+}
+
+// newTextLogHandler creates a textLogHandler writing records from level to w.
+func newTextLogHandler(w io.Writer, level slog.Leveler, style Styler) *textLogHandler {
+	return &textLogHandler{mu: new(sync.Mutex), w: w, level: level, style: style}
+}
+
+func (h *textLogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *textLogHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	if !r.Time.IsZero() {
+		b.WriteString(r.Time.Format(time.TimeOnly))
+		b.WriteByte(' ')
+	}
+	b.WriteString(h.levelName(r.Level))
+	b.WriteByte(' ')
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendTextAttr(&b, h.prefix, a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+// levelName returns the name of a level, colored by severity.
+func (h *textLogHandler) levelName(level slog.Level) string {
+	name := levelName(level)
+	switch {
+	case level >= slog.LevelError:
+		return h.style.Error(name)
+	case level >= slog.LevelWarn:
+		return h.style.Warning(name)
+	case level < slog.LevelInfo:
+		return h.style.Dim(name)
+	}
+	return name
+}
+
+func (h *textLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, a := range attrs {
+		appendTextAttr(&b, h.prefix, a)
+	}
+	h2 := *h
+	h2.attrs += b.String()
+	return &h2
+}
+
+func (h *textLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix += name + "."
+	return &h2
+}
+
+// appendTextAttr appends " key=value" to b, flattening groups into dotted keys.
+func appendTextAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, attr := range a.Value.Group() {
+			appendTextAttr(b, prefix, attr)
+		}
+		return
+	}
+
+	value := a.Value.String()
+	if a.Value.Kind() == slog.KindTime {
+		value = a.Value.Time().Format(time.RFC3339)
+	}
+	b.WriteByte(' ')
+	b.WriteString(prefix + a.Key)
+	b.WriteByte('=')
+	b.WriteString(quoteTextValue(value))
+}
+
+// quoteTextValue quotes values that would be ambiguous in a key=value line.
+func quoteTextValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+var _ slog.Handler = (*textLogHandler)(nil)
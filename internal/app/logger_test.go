@@ -1,50 +1,362 @@
 package app
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestNewSlogLogger tests SlogLogger creation.
 func TestNewSlogLogger(t *testing.T) {
-	logger := NewSlogLogger(true, "")
+	logger := NewSlogLogger(ConsoleLogOptions{Writer: io.Discard, Level: slog.LevelDebug}, FileLogOptions{})
 	if logger == nil {
 		t.Fatal("expected non-nil logger")
 	}
 
-	logger2 := NewSlogLogger(false, "")
+	logger2 := NewSlogLogger(ConsoleLogOptions{Writer: io.Discard}, FileLogOptions{})
 	if logger2 == nil {
 		t.Fatal("expected non-nil logger")
 	}
 
 	// Test with log file
 	logFile := t.TempDir() + "/test.log"
-	logger3 := NewSlogLogger(false, logFile)
+	logger3 := NewSlogLogger(ConsoleLogOptions{Writer: io.Discard}, FileLogOptions{Path: logFile})
 	if logger3 == nil {
 		t.Fatal("expected non-nil logger with log file")
 	}
 }
 
+// TestNewSlogLogger_Quiet tests that quiet mode logs nothing to the console but still writes the log file.
+func TestNewSlogLogger_Quiet(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+
+	var console bytes.Buffer
+	logger := NewSlogLogger(ConsoleLogOptions{Writer: &console, Level: quietLevel}, FileLogOptions{Path: logFile})
+	logger.Info("quiet info")
+	logger.Error("quiet error")
+
+	if console.Len() != 0 {
+		t.Errorf("console = %q, want nothing", console.String())
+	}
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "quiet info") || !strings.Contains(string(data), "quiet error") {
+		t.Errorf("log = %q, want both messages", data)
+	}
+}
+
+// TestNewSlogLogger_Console tests that logs are written to the console writer.
+func TestNewSlogLogger_Console(t *testing.T) {
+	var console bytes.Buffer
+	logger := NewSlogLogger(ConsoleLogOptions{Writer: &console, Format: LogFormatJSON}, FileLogOptions{})
+	logger.Info("to console")
+	logger.Debug("hidden")
+
+	if !strings.Contains(console.String(), `"msg":"to console"`) || strings.Contains(console.String(), "hidden") {
+		t.Errorf("console = %q, want the info message only", console.String())
+	}
+}
+
+// TestNewSlogLogger_Handler tests that a console handler replaces the console writer.
+func TestNewSlogLogger_Handler(t *testing.T) {
+	var console, handled bytes.Buffer
+	handler := slog.NewTextHandler(&handled, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := NewSlogLogger(ConsoleLogOptions{Writer: &console, Handler: handler}, FileLogOptions{}, "secret-key")
+	logger.Debug("to handler", "key", "secret-key")
+
+	if console.Len() != 0 {
+		t.Errorf("console = %q, want nothing", console.String())
+	}
+	if !strings.Contains(handled.String(), "to handler") || strings.Contains(handled.String(), "secret-key") {
+		t.Errorf("handler = %q, want the debug message with the secret masked", handled.String())
+	}
+}
+
+// TestNewSlogLogger_Format tests the console log in each format, the log file staying JSON.
+func TestNewSlogLogger_Format(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{format: LogFormatText, want: []string{" INFO Research started interaction_id=abc elapsed=1.5s\n"}},
+		{format: LogFormatJSON, want: []string{`"level":"INFO"`, `"msg":"Research started"`, `"interaction_id":"abc"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), "test.log")
+			var console bytes.Buffer
+			logger := NewSlogLogger(ConsoleLogOptions{Writer: &console, Format: tt.format}, FileLogOptions{Path: logFile})
+			logger.Info("Research started", "interaction_id", "abc", "elapsed", 1500*time.Millisecond)
+
+			for _, want := range tt.want {
+				if !strings.Contains(console.String(), want) {
+					t.Errorf("console = %q, want %q", console.String(), want)
+				}
+			}
+			data, err := os.ReadFile(logFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), `"msg":"Research started"`) {
+				t.Errorf("log = %q, want JSON", data)
+			}
+		})
+	}
+}
+
+// TestConsoleLogOptions_Format tests that an unset format is JSON unless the console is a terminal.
+func TestConsoleLogOptions_Format(t *testing.T) {
+	if got := (ConsoleLogOptions{Writer: new(bytes.Buffer)}).format(); got != LogFormatJSON {
+		t.Errorf("format() = %q for a buffer, want %q", got, LogFormatJSON)
+	}
+	file, err := os.Create(filepath.Join(t.TempDir(), "console"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if got := (ConsoleLogOptions{Writer: file}).format(); got != LogFormatJSON {
+		t.Errorf("format() = %q for a regular file, want %q", got, LogFormatJSON)
+	}
+	if got := (ConsoleLogOptions{Writer: file, Format: LogFormatText}).format(); got != LogFormatText {
+		t.Errorf("format() = %q, want the configured %q", got, LogFormatText)
+	}
+}
+
+// TestTextLogHandler tests the compact line format of the text console log.
+func TestTextLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := newTextLogHandler(&buf, slog.LevelInfo, Styler{})
+	logger := slog.New(handler.WithAttrs([]slog.Attr{slog.String("timestamp", "20251224_103045")}).WithGroup("request"))
+
+	record := slog.NewRecord(time.Date(2025, 12, 24, 10, 30, 45, 0, time.Local), slog.LevelWarn, "Retrying", 0)
+	record.AddAttrs(slog.String("error", "rate limited"), slog.Int("attempt", 2), slog.Group("backoff", slog.String("delay", "")))
+	if err := logger.Handler().Handle(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("hidden")
+
+	want := `10:30:45 WARN Retrying timestamp=20251224_103045 request.error="rate limited" request.attempt=2 request.backoff.delay=""` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+// TestLogConsole tests the console writer of each log_output.
+func TestLogConsole(t *testing.T) {
+	if got := LogConsole(LogOutputStderr); got != os.Stderr {
+		t.Errorf("LogConsole(stderr) = %v, want os.Stderr", got)
+	}
+	if got := LogConsole(LogOutputStdout); got != os.Stdout {
+		t.Errorf("LogConsole(stdout) = %v, want os.Stdout", got)
+	}
+	if got := LogConsole(LogOutputFileOnly); got != nil {
+		t.Errorf("LogConsole(file-only) = %v, want nil", got)
+	}
+}
+
+// TestNewFileLogger tests that NewFileLogger writes to the log file only.
+func TestNewFileLogger(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "logs", "test.log")
+	logger := NewFileLogger(FileLogOptions{Path: logFile})
+	logger.Debug("file only", "key", "value")
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"msg":"file only"`) {
+		t.Errorf("log = %q, want the debug message", data)
+	}
+}
+
+// TestSlogLogger_Close tests that Close flushes and closes the log file.
+func TestSlogLogger_Close(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+	logger := NewFileLogger(FileLogOptions{Path: logFile})
+	logger.Info("before close")
+	file := logger.file
+
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := file.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Write() after Close() error = %v, want os.ErrClosed", err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Errorf("second Close() error = %v, want nil", err)
+	}
+	logger.Info("after close") // Verify no panic
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "before close") || strings.Contains(string(data), "after close") {
+		t.Errorf("log = %q, want the message logged before Close only", data)
+	}
+
+	// A logger without a log file has nothing to close
+	if err := NewSlogLogger(ConsoleLogOptions{Writer: io.Discard}, FileLogOptions{}).Close(); err != nil {
+		t.Errorf("Close() without a log file error = %v", err)
+	}
+}
+
+// TestSlogLogger_With tests that With adds attributes to the console and file logs.
+func TestSlogLogger_With(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+	var console bytes.Buffer
+	logger := NewSlogLogger(ConsoleLogOptions{Writer: &console, Format: LogFormatJSON}, FileLogOptions{Path: logFile}).With("run_id", "3f9c2a7b1d04")
+	logger.Info("Pipeline started")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, output := range map[string]string{"console": console.String(), "file": string(data)} {
+		if !strings.Contains(output, `"run_id":"3f9c2a7b1d04"`) {
+			t.Errorf("%s log = %q, want the run_id attribute", name, output)
+		}
+	}
+}
+
+// TestNewSlogLogger_FileError tests that a log file that cannot be opened is warned about on the console.
+func TestNewSlogLogger_FileError(t *testing.T) {
+	// A regular file where the log directory should be
+	parent := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(parent, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var console bytes.Buffer
+	logger := NewSlogLogger(ConsoleLogOptions{Writer: &console, Format: LogFormatJSON}, FileLogOptions{Path: filepath.Join(parent, "logs", "test.log")})
+	if logger.FileError() == nil {
+		t.Error("FileError() = nil, want the error opening the log file")
+	}
+	if !strings.Contains(console.String(), `"level":"WARN","msg":"Failed to open log file, logging to the console only"`) {
+		t.Errorf("console = %q, want the warning", console.String())
+	}
+	logger.Info("still logged")
+	if !strings.Contains(console.String(), "still logged") {
+		t.Errorf("console = %q, want logs after the warning", console.String())
+	}
+}
+
 // TestSlogLogger_Info tests SlogLogger Info method.
 func TestSlogLogger_Info(t *testing.T) {
-	logger := NewSlogLogger(true, "")
+	logger := NewSlogLogger(ConsoleLogOptions{Writer: io.Discard, Level: slog.LevelDebug}, FileLogOptions{})
 	logger.Info("test info message") // Verify no panic
 	logger.Info("test with attrs", "key", "value", "number", 42)
 }
 
 // TestSlogLogger_Error tests SlogLogger Error method.
 func TestSlogLogger_Error(t *testing.T) {
-	logger := NewSlogLogger(false, "")
+	logger := NewSlogLogger(ConsoleLogOptions{Writer: io.Discard}, FileLogOptions{})
 	logger.Error("test error message") // Verify no panic
 	logger.Error("test error with attrs", "error", "something went wrong")
 }
 
 // TestSlogLogger_Debug tests SlogLogger Debug method.
 func TestSlogLogger_Debug(t *testing.T) {
-	logger := NewSlogLogger(true, "")
+	logger := NewSlogLogger(ConsoleLogOptions{Writer: io.Discard, Level: slog.LevelDebug}, FileLogOptions{})
 	logger.Debug("test debug message") // Verify no panic
 	logger.Debug("test debug with attrs", "debug_key", "debug_value")
 }
 
+// TestVerbosityLevel tests the records each -v count logs to the console.
+func TestVerbosityLevel(t *testing.T) {
+	tests := []struct {
+		verbose int
+		want    []string
+	}{
+		{verbose: 0, want: []string{"info"}},
+		{verbose: VerbosityDebug, want: []string{"debug", "info"}},
+		{verbose: VerbosityTrace, want: []string{"trace", "debug", "info"}},
+		{verbose: 3, want: []string{"trace", "debug", "info"}},
+	}
+
+	for _, tt := range tests {
+		var console bytes.Buffer
+		logger := NewSlogLogger(ConsoleLogOptions{Writer: &console, Format: LogFormatText, Level: VerbosityLevel(tt.verbose)}, FileLogOptions{})
+		logger.Trace("trace")
+		logger.Debug("debug")
+		logger.Info("info")
+
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(console.String()), "\n") {
+			fields := strings.Fields(line)
+			got = append(got, fields[len(fields)-1])
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("verbose %d logged %v, want %v", tt.verbose, got, tt.want)
+		}
+	}
+}
+
+// TestSlogLogger_Trace tests that trace lines are logged only when enabled, on the console and in the log file.
+func TestSlogLogger_Trace(t *testing.T) {
+	tests := []struct {
+		name        string
+		console     ConsoleLogOptions
+		fileTrace   bool
+		wantConsole bool
+		wantFile    bool
+	}{
+		{name: "disabled", console: ConsoleLogOptions{Format: LogFormatJSON}},
+		{name: "verbose only", console: ConsoleLogOptions{Format: LogFormatJSON, Level: slog.LevelDebug}},
+		{name: "console trace", console: ConsoleLogOptions{Format: LogFormatJSON, Level: LevelTrace}, wantConsole: true},
+		{name: "file trace", console: ConsoleLogOptions{Format: LogFormatJSON}, fileTrace: true, wantFile: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), "test.log")
+			var console bytes.Buffer
+			tt.console.Writer = &console
+			logger := NewSlogLogger(tt.console, FileLogOptions{Path: logFile, Trace: tt.fileTrace})
+			logger.Trace("HTTP Response", "body", "{}")
+			logger.Debug("Response received")
+
+			if got := strings.Contains(console.String(), `"level":"TRACE","msg":"HTTP Response"`); got != tt.wantConsole {
+				t.Errorf("console = %q, want trace line %v", console.String(), tt.wantConsole)
+			}
+			data, err := os.ReadFile(logFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(string(data), `"level":"TRACE","msg":"HTTP Response"`); got != tt.wantFile {
+				t.Errorf("log = %q, want trace line %v", data, tt.wantFile)
+			}
+			if !strings.Contains(string(data), "Response received") {
+				t.Errorf("log = %q, want the debug message", data)
+			}
+		})
+	}
+}
+
+// TestTextLogHandler_Trace tests the level name of trace lines in the text console log.
+func TestTextLogHandler_Trace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &SlogLogger{logger: slog.New(newTextLogHandler(&buf, LevelTrace, Styler{}))}
+	logger.Trace("HTTP Request", "method", "POST")
+
+	if !strings.Contains(buf.String(), " TRACE HTTP Request method=POST\n") {
+		t.Errorf("output = %q, want a TRACE line", buf.String())
+	}
+}
+
 // TestNewNullLogger tests NullLogger creation.
 func TestNewNullLogger(t *testing.T) {
 	logger := NewNullLogger()
@@ -92,8 +404,9 @@ func TestMockLogger(t *testing.T) {
 func TestLoggerInterface_NullLogger(t *testing.T) {
 	var logger Logger = NewNullLogger()
 
-	// Call Info/Error/Debug to ensure coverage
+	// Call Info/Warn/Error/Debug to ensure coverage
 	logger.Info("test info")
+	logger.Warn("test warn")
 	logger.Error("test error")
 	logger.Debug("test debug")
 
@@ -102,10 +415,11 @@ func TestLoggerInterface_NullLogger(t *testing.T) {
 
 // TestLoggerInterface_SlogLogger tests that SlogLogger implements Logger interface.
 func TestLoggerInterface_SlogLogger(t *testing.T) {
-	var logger Logger = NewSlogLogger(true, "")
+	var logger Logger = NewSlogLogger(ConsoleLogOptions{Writer: io.Discard, Level: slog.LevelDebug}, FileLogOptions{})
 
-	// Call Info/Error/Debug to ensure coverage
+	// Call Info/Warn/Error/Debug to ensure coverage
 	logger.Info("test info")
+	logger.Warn("test warn")
 	logger.Error("test error")
 	logger.Debug("test debug")
 
@@ -116,8 +430,9 @@ func TestLoggerInterface_SlogLogger(t *testing.T) {
 func TestLoggerInterface_MockLogger(t *testing.T) {
 	var logger Logger = newMockLogger()
 
-	// Call Info/Error/Debug to ensure coverage
+	// Call Info/Warn/Error/Debug to ensure coverage
 	logger.Info("test info")
+	logger.Warn("test warn")
 	logger.Error("test error")
 	logger.Debug("test debug")
 
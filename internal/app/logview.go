@@ -0,0 +1,202 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// logFollowInterval is the interval at which `deepviz logs --follow` checks for new lines.
+const logFollowInterval = 500 * time.Millisecond
+
+// logMessageWidth is the width of the message column of rendered log lines.
+const logMessageWidth = 32
+
+// ParseLogLevel parses a level name (trace, debug, info, warn or error, in any case).
+func ParseLogLevel(name string) (slog.Level, error) {
+	if strings.EqualFold(name, "trace") {
+		return LevelTrace, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: must be trace, debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+// LogView prints the JSON lines of a log file for reading.
+type LogView struct {
+	MinLevel slog.Level // Lines below this level are skipped
+	Raw      bool       // Print the JSON lines as written instead of rendering them
+}
+
+// logField is a field of a JSON log line, in the order it was written.
+type logField struct {
+	key   string
+	value json.RawMessage
+}
+
+// parseLogLine returns the fields of a JSON log line, or false if it is not a JSON object.
+func parseLogLine(line []byte) ([]logField, bool) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, false
+	}
+	var fields []logField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, false
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, false
+		}
+		fields = append(fields, logField{key: key, value: value})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, false
+	}
+	return fields, true
+}
+
+// Line returns a log line formatted for printing without its newline, or false if its level is
+// below MinLevel. Lines that are not JSON log records are returned as is.
+func (v LogView) Line(line []byte) (string, bool) {
+	line = bytes.TrimRight(line, "\r\n")
+	fields, ok := parseLogLine(line)
+	if !ok {
+		return string(line), true
+	}
+
+	var t, level, msg string
+	var attrs strings.Builder
+	for _, field := range fields {
+		var text string
+		if err := json.Unmarshal(field.value, &text); err != nil {
+			// Numbers, booleans and objects are printed as JSON
+			text = string(field.value)
+		}
+		switch field.key {
+		case slog.TimeKey:
+			t = text
+		case slog.LevelKey:
+			level = text
+		case slog.MessageKey:
+			msg = text
+		default:
+			fmt.Fprintf(&attrs, " %s=%s", field.key, quoteTextValue(text))
+		}
+	}
+	if parsed, err := ParseLogLevel(level); err == nil && parsed < v.MinLevel {
+		return "", false
+	}
+
+	if v.Raw {
+		return string(line), true
+	}
+	if parsed, err := time.Parse(time.RFC3339Nano, t); err == nil {
+		t = parsed.Local().Format(time.DateTime)
+	}
+	return strings.TrimRight(fmt.Sprintf("%-19s %-5s %-*s%s", t, level, logMessageWidth, msg, attrs.String()), " "), true
+}
+
+// Print prints every line of r.
+func (v LogView) Print(w io.Writer, r io.Reader) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			v.printLine(w, line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Follow prints every line of a log file, then the lines appended to it until ctx is done.
+func (v LogView) Follow(ctx context.Context, w io.Writer, path string, interval time.Duration) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var partial []byte
+	for {
+		line, err := reader.ReadBytes('\n')
+		partial = append(partial, line...)
+		if err == nil {
+			v.printLine(w, partial)
+			partial = nil
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+
+		// Wait for the line being written to be completed
+		select {
+		case <-ctx.Done():
+			if len(partial) > 0 {
+				v.printLine(w, partial)
+			}
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// printLine prints a line unless its level is filtered out.
+func (v LogView) printLine(w io.Writer, line []byte) {
+	if text, ok := v.Line(line); ok {
+		fmt.Fprintln(w, text)
+	}
+}
+
+// openLogFile opens a log file, or its compressed form when it was compressed by the log
+// retention policy.
+func openLogFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err == nil {
+		return file, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	compressed, gzErr := os.Open(path + ".gz")
+	if gzErr != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(compressed)
+	if err != nil {
+		compressed.Close()
+		return nil, err
+	}
+	return &gzipFile{Reader: zr, file: compressed}, nil
+}
+
+// gzipFile reads a gzip file and closes it.
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (f *gzipFile) Close() error {
+	f.Reader.Close()
+	return f.file.Close()
+}
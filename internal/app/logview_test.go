@@ -0,0 +1,201 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testLogLines are JSON log lines as written by the log file handler.
+const testLogLines = `{"time":"2025-12-24T10:30:45.123+09:00","level":"INFO","msg":"Research started","interaction_id":"abc","elapsed":1.5}
+{"time":"2025-12-24T10:30:46+09:00","level":"DEBUG","msg":"Response received","status_code":200}
+{"time":"2025-12-24T10:30:46+09:00","level":"TRACE","msg":"HTTP Response","body":"{\"id\":\"abc\"}"}
+not a log record
+{"time":"2025-12-24T10:31:00+09:00","level":"ERROR","msg":"Image generation failed","error":"quota exceeded"}
+`
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    slog.Level
+		wantErr bool
+	}{
+		{name: "trace", want: LevelTrace},
+		{name: "TRACE", want: LevelTrace},
+		{name: "debug", want: slog.LevelDebug},
+		{name: "warn", want: slog.LevelWarn},
+		{name: "ERROR", want: slog.LevelError},
+		{name: "verbose", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLogLevel(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLogLevel(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseLogLevel(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLogView_Line(t *testing.T) {
+	tests := []struct {
+		name   string
+		view   LogView
+		line   string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "record",
+			view:   LogView{MinLevel: LevelTrace},
+			line:   `{"time":"2025-12-24T10:30:45Z","level":"INFO","msg":"Research started","interaction_id":"abc","elapsed":1.5,"error":"rate limited"}` + "\n",
+			want:   time.Date(2025, 12, 24, 10, 30, 45, 0, time.UTC).Local().Format(time.DateTime) + ` INFO  Research started                 interaction_id=abc elapsed=1.5 error="rate limited"`,
+			wantOK: true,
+		},
+		{
+			name: "below the level",
+			view: LogView{MinLevel: slog.LevelInfo},
+			line: `{"time":"2025-12-24T10:30:45Z","level":"DEBUG","msg":"Response received"}`,
+		},
+		{
+			name:   "raw",
+			view:   LogView{MinLevel: slog.LevelInfo, Raw: true},
+			line:   `{"time":"2025-12-24T10:30:45Z","level":"WARN","msg":"Retrying"}` + "\n",
+			want:   `{"time":"2025-12-24T10:30:45Z","level":"WARN","msg":"Retrying"}`,
+			wantOK: true,
+		},
+		{
+			name:   "not json",
+			view:   LogView{MinLevel: slog.LevelError},
+			line:   "panic: something\n",
+			want:   "panic: something",
+			wantOK: true,
+		},
+		{
+			name:   "truncated json",
+			view:   LogView{MinLevel: slog.LevelError},
+			line:   `{"time":"2025-12-24T10:30:45Z","level":"DEBUG","msg":"cut`,
+			want:   `{"time":"2025-12-24T10:30:45Z","level":"DEBUG","msg":"cut`,
+			wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.view.Line([]byte(tt.line))
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Line() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLogView_Print(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (LogView{MinLevel: slog.LevelInfo}).Print(&buf, strings.NewReader(testLogLines)); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("lines = %q, want the info and error records and the malformed line", lines)
+	}
+	if !strings.Contains(lines[0], " INFO  Research started") || lines[1] != "not a log record" || !strings.Contains(lines[2], " ERROR Image generation failed") {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLogView_Follow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.log")
+	if err := os.WriteFile(path, []byte(`{"level":"INFO","msg":"first"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out syncBuffer
+	done := make(chan error)
+	go func() {
+		done <- LogView{MinLevel: LevelTrace, Raw: true}.Follow(ctx, &out, path, time.Millisecond)
+	}()
+
+	// A line appended in two writes is printed once complete
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	file.WriteString(`{"level":"INFO",`)
+	time.Sleep(10 * time.Millisecond)
+	file.WriteString(`"msg":"second"}` + "\n")
+
+	want := `{"level":"INFO","msg":"first"}` + "\n" + `{"level":"INFO","msg":"second"}` + "\n"
+	deadline := time.Now().Add(5 * time.Second)
+	for out.String() != want && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Follow() error = %v", err)
+	}
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestOpenLogFile_Compressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.log")
+	file, err := os.Create(path + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(file)
+	zw.Write([]byte(testLogLines))
+	zw.Close()
+	file.Close()
+
+	r, err := openLogFile(path)
+	if err != nil {
+		t.Fatalf("openLogFile() error = %v", err)
+	}
+	defer r.Close()
+	var buf bytes.Buffer
+	if err := (LogView{MinLevel: slog.LevelError, Raw: true}).Print(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Image generation failed") {
+		t.Errorf("output = %q, want the records of the compressed log", buf.String())
+	}
+
+	if _, err := openLogFile(filepath.Join(t.TempDir(), "missing.log")); !os.IsNotExist(err) {
+		t.Errorf("openLogFile() error = %v, want not exist", err)
+	}
+}
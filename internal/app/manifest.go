@@ -0,0 +1,228 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yukiyan/deepviz/internal/version"
+)
+
+// manifestSchemaVersion is the schema version of run manifests.
+//
+// Bump it on incompatible changes; readers skip manifests with a newer version.
+const manifestSchemaVersion = 1
+
+// Run and stage statuses recorded in manifests.
+const (
+	RunStatusRunning     = "running"
+	RunStatusCompleted   = "completed"
+	RunStatusFailed      = "failed"
+	RunStatusInterrupted = "interrupted" // The research is kept on the server and can be resumed
+)
+
+// RunManifest describes a pipeline run and links all of its artifacts.
+//
+// It is written when the run starts and updated after each stage, so a run that fails or is
+// killed still leaves a manifest describing how far it got.
+type RunManifest struct {
+	SchemaVersion    int               `json:"schema_version"`
+	Timestamp        string            `json:"timestamp"`
+	Name             string            `json:"name,omitempty"`    // Artifact name when it differs from the timestamp (--name, filename_style)
+	RunID            string            `json:"run_id,omitempty"`  // Sent with every API request and logged with every log of the run
+	APIKey           *ManifestAPIKey   `json:"api_key,omitempty"` // Key of api_keys used by the run (nil with a single key)
+	Status           string            `json:"status"`
+	FailedStage      string            `json:"failed_stage,omitempty"`
+	Error            string            `json:"error,omitempty"`
+	StartedAt        time.Time         `json:"started_at"`
+	FinishedAt       *time.Time        `json:"finished_at,omitempty"`
+	Prompt           ManifestPrompt    `json:"prompt"`
+	Research         *ManifestResearch `json:"research,omitempty"` // Nil in ImageOnly mode
+	Image            *ManifestImage    `json:"image,omitempty"`    // Nil in ResearchOnly mode
+	ReportPath       string            `json:"report_path,omitempty"`
+	LogPath          string            `json:"log_path"`
+	Tags             []string          `json:"tags,omitempty"`
+	ResponsesSkipped bool              `json:"responses_skipped,omitempty"` // Raw API responses were not saved (--no-save-response)
+	DeepvizVersion   string            `json:"deepviz_version"`
+}
+
+// ManifestAPIKey identifies a key of api_keys: the one used by the last API request of a run, or
+// the one that created its research interaction.
+type ManifestAPIKey struct {
+	Index int    `json:"index"` // Index in api_keys, from 0
+	Key   string `json:"key"`   // Masked key
+}
+
+// ManifestPrompt identifies the prompt of a run.
+type ManifestPrompt struct {
+	Excerpt string `json:"excerpt"`
+	Hash    string `json:"hash"`           // SHA-256 of the full prompt
+	File    string `json:"file,omitempty"` // Prompt file (empty for --prompt)
+}
+
+// ManifestResearch is the research stage of a run.
+type ManifestResearch struct {
+	Status            string          `json:"status"`
+	InteractionID     string          `json:"interaction_id,omitempty"`
+	Agent             string          `json:"agent"`
+	Tools             []string        `json:"tools,omitempty"`   // Empty with --no-tools
+	APIKey            *ManifestAPIKey `json:"api_key,omitempty"` // Key of api_keys that created the interaction (nil with a single key)
+	ThinkingSummaries string          `json:"thinking_summaries,omitempty"`
+	StartedAt         time.Time       `json:"started_at"`
+	FinishedAt        *time.Time      `json:"finished_at,omitempty"`
+	DurationSeconds   float64         `json:"duration_seconds,omitempty"`
+	MarkdownPath      string          `json:"markdown_path,omitempty"`
+	PDFPath           string          `json:"pdf_path,omitempty"` // PDF export of the markdown (--export pdf)
+	SourcesPath       string          `json:"sources_path,omitempty"`
+	ResponsePath      string          `json:"response_path,omitempty"`
+}
+
+// ManifestImage is the image stage of a run.
+type ManifestImage struct {
+	Status          string     `json:"status"`
+	Model           string     `json:"model"`
+	FallbackFrom    string     `json:"fallback_from,omitempty"` // Model that failed before the fallback model was used
+	AspectRatio     string     `json:"aspect_ratio"`
+	ImageSize       string     `json:"image_size"`
+	Lang            string     `json:"lang"`
+	Style           string     `json:"style,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+	DurationSeconds float64    `json:"duration_seconds,omitempty"`
+	ImagePaths      []string   `json:"image_paths,omitempty"`
+	OriginalPaths   []string   `json:"original_paths,omitempty"`
+	InputImages     []string   `json:"input_images,omitempty"`
+	PromptPaths     []string   `json:"prompt_paths,omitempty"`
+	CaptionPath     string     `json:"caption_path,omitempty"`
+	ResponsePath    string     `json:"response_path,omitempty"`
+	RequestPath     string     `json:"request_path,omitempty"`
+}
+
+// newRunManifest creates the manifest of a run starting now.
+func newRunManifest(timestamp, prompt, file, logPath string, tags []string, now time.Time) *RunManifest {
+	return &RunManifest{
+		SchemaVersion: manifestSchemaVersion,
+		Timestamp:     timestamp,
+		Status:        RunStatusRunning,
+		StartedAt:     now,
+		Prompt: ManifestPrompt{
+			Excerpt: promptExcerpt(prompt),
+			Hash:    HashPrompt(prompt),
+			File:    file,
+		},
+		LogPath:        logPath,
+		Tags:           tags,
+		DeepvizVersion: version.Version(),
+	}
+}
+
+// finish records the end of the research stage.
+func (r *ManifestResearch) finish(result *ResearchResult, err error, now time.Time) {
+	r.FinishedAt = &now
+	r.DurationSeconds = now.Sub(r.StartedAt).Seconds()
+	if err != nil {
+		r.Status = stageStatus(err)
+		return
+	}
+	r.Status = RunStatusCompleted
+	r.MarkdownPath = result.MarkdownPath
+	r.SourcesPath = result.SourcesPath
+	r.ResponsePath = result.ResponsePath
+}
+
+// finish records the end of the image stage.
+func (i *ManifestImage) finish(result *ImageResult, err error, now time.Time) {
+	i.FinishedAt = &now
+	i.DurationSeconds = now.Sub(i.StartedAt).Seconds()
+	if err != nil {
+		i.Status = RunStatusFailed
+		return
+	}
+	i.Status = RunStatusCompleted
+	i.Model = result.Model
+	i.FallbackFrom = result.FallbackFrom
+	i.ImagePaths = result.ImagePaths
+	i.OriginalPaths = result.OriginalPaths
+	i.InputImages = result.InputImages
+	i.PromptPaths = result.PromptPaths
+	if len(i.PromptPaths) == 0 {
+		i.PromptPaths = []string{result.PromptPath}
+	}
+	i.CaptionPath = result.CaptionPath
+	i.ResponsePath = result.ResponsePath
+	i.RequestPath = result.RequestPath
+}
+
+// finish records the final status of the run from the error it ended with (nil on success).
+func (m *RunManifest) finish(err error, now time.Time) {
+	m.FinishedAt = &now
+	if err == nil {
+		m.Status = RunStatusCompleted
+	} else {
+		m.Status = stageStatus(err)
+		m.Error = err.Error()
+		var stageErr *StageError
+		if errors.As(err, &stageErr) {
+			m.FailedStage = stageErr.Stage
+		}
+	}
+
+	// A stage still running ended with the run (e.g., its client could not be created)
+	if m.Research != nil && m.Research.Status == RunStatusRunning {
+		m.Research.Status = m.Status
+	}
+	if m.Image != nil && m.Image.Status == RunStatusRunning {
+		m.Image.Status = m.Status
+	}
+}
+
+// stageStatus returns the status of a run or stage that ended with err.
+func stageStatus(err error) string {
+	var resumableErr *ResumableError
+	var interruptedErr *InterruptedError
+	if errors.As(err, &resumableErr) || errors.As(err, &interruptedErr) {
+		return RunStatusInterrupted
+	}
+	return RunStatusFailed
+}
+
+// runKey returns the name grouping the artifact files of the run (see RunNamer.SplitRunName).
+func (m *RunManifest) runKey(namer *RunNamer) string {
+	if m.Name == "" {
+		return m.Timestamp
+	}
+	run, _ := namer.SplitRunName(m.Name)
+	return run
+}
+
+// Save writes the manifest to path.
+func (m *RunManifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := WriteFile(path, data); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// LoadManifest reads the manifest at path.
+//
+// Manifests with a newer schema version than this build understands are an error.
+func LoadManifest(path string) (*RunManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest RunManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if manifest.SchemaVersion > manifestSchemaVersion {
+		return nil, fmt.Errorf("manifest %s has unsupported schema version %d", path, manifest.SchemaVersion)
+	}
+	return &manifest, nil
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yukiyan/deepviz/internal/version"
+)
+
+func TestRunManifest_SaveLoad(t *testing.T) {
+	startedAt := time.Date(2025, 12, 24, 10, 30, 45, 0, time.UTC)
+	manifest := newRunManifest("20251224_103045", "AI trends", "prompt.md", "logs/20251224_103045.log", []string{"weekly"}, startedAt)
+	manifest.Research = &ManifestResearch{Status: RunStatusRunning, Agent: "deep-research-pro-preview-12-2025", StartedAt: startedAt}
+	manifest.Research.finish(&ResearchResult{MarkdownPath: "research/20251224_103045.md"}, nil, startedAt.Add(time.Minute))
+	manifest.finish(nil, startedAt.Add(time.Minute))
+
+	path := filepath.Join(t.TempDir(), "manifests", "20251224_103045.json")
+	if err := manifest.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, manifest) {
+		t.Errorf("LoadManifest() = %+v, want %+v", loaded, manifest)
+	}
+	if loaded.SchemaVersion != manifestSchemaVersion || loaded.Prompt.Hash != HashPrompt("AI trends") || loaded.DeepvizVersion != version.Version() {
+		t.Errorf("manifest = %+v, want the schema version, prompt hash and version", loaded)
+	}
+	if loaded.Research.DurationSeconds != 60 {
+		t.Errorf("Research.DurationSeconds = %v, want 60", loaded.Research.DurationSeconds)
+	}
+}
+
+func TestLoadManifest_Errors(t *testing.T) {
+	dir := t.TempDir()
+	newer := filepath.Join(dir, "newer.json")
+	if err := os.WriteFile(newer, []byte(`{"schema_version": 99}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte(`{`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadManifest(newer); err == nil || !strings.Contains(err.Error(), "schema version 99") {
+		t.Errorf("LoadManifest(newer) error = %v, want an unsupported schema version error", err)
+	}
+	if _, err := LoadManifest(corrupt); err == nil {
+		t.Error("LoadManifest(corrupt) should fail")
+	}
+	if _, err := LoadManifest(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadManifest(missing) error = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestRunManifest_Finish(t *testing.T) {
+	now := time.Date(2025, 12, 24, 10, 30, 45, 0, time.UTC)
+	tests := []struct {
+		name            string
+		err             error
+		wantStatus      string
+		wantFailedStage string
+	}{
+		{name: "success", err: nil, wantStatus: RunStatusCompleted},
+		{name: "stage failure", err: &StageError{Stage: "image", Err: errors.New("boom")}, wantStatus: RunStatusFailed, wantFailedStage: "image"},
+		{name: "resumable", err: &StageError{Stage: "research", Err: &ResumableError{InteractionID: "id", Err: errors.New("timeout")}}, wantStatus: RunStatusInterrupted, wantFailedStage: "research"},
+		{name: "interrupted", err: &InterruptedError{InteractionID: "id", Err: errors.New("signal")}, wantStatus: RunStatusInterrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest := newRunManifest("20251224_103045", "AI trends", "", "", nil, now)
+			manifest.Image = &ManifestImage{Status: RunStatusRunning, StartedAt: now}
+			manifest.finish(tt.err, now.Add(time.Second))
+
+			if manifest.Status != tt.wantStatus || manifest.FailedStage != tt.wantFailedStage {
+				t.Errorf("Status, FailedStage = %q, %q, want %q, %q", manifest.Status, manifest.FailedStage, tt.wantStatus, tt.wantFailedStage)
+			}
+			if (manifest.Error != "") != (tt.err != nil) {
+				t.Errorf("Error = %q, want it set only on failure", manifest.Error)
+			}
+			if manifest.FinishedAt == nil {
+				t.Error("FinishedAt should be set")
+			}
+			// A stage still running ends with the run
+			if manifest.Image.Status != tt.wantStatus {
+				t.Errorf("Image.Status = %q, want %q", manifest.Image.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestManifestImage_Finish(t *testing.T) {
+	now := time.Date(2025, 12, 24, 10, 30, 45, 0, time.UTC)
+
+	image := &ManifestImage{Status: RunStatusRunning, Model: "gemini-3-pro-image-preview", StartedAt: now}
+	image.finish(&ImageResult{Model: "gemini-2.5-flash-image", FallbackFrom: "gemini-3-pro-image-preview", ImagePaths: []string{"images/a.png"}, PromptPath: "prompts/a.txt"}, nil, now.Add(2*time.Second))
+	if image.Status != RunStatusCompleted || image.Model != "gemini-2.5-flash-image" || image.FallbackFrom != "gemini-3-pro-image-preview" {
+		t.Errorf("image = %+v, want the completed fallback model", image)
+	}
+	if !reflect.DeepEqual(image.PromptPaths, []string{"prompts/a.txt"}) || image.DurationSeconds != 2 {
+		t.Errorf("image = %+v, want the prompt path and duration", image)
+	}
+
+	failed := &ManifestImage{Status: RunStatusRunning, StartedAt: now}
+	failed.finish(nil, errors.New("boom"), now)
+	if failed.Status != RunStatusFailed || len(failed.ImagePaths) != 0 {
+		t.Errorf("failed image = %+v, want failed without artifacts", failed)
+	}
+}
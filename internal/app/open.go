@@ -0,0 +1,63 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ResolveOpenPath returns the image (or research markdown with research) of a run to open.
+//
+// With last, the run is the most recent one recorded in the history ledger, or the newest run in
+// the output directory when the ledger is empty. Otherwise ref designates a run like in
+// `deepviz show`. A file that no longer exists is an error giving the path where it was expected.
+func ResolveOpenPath(config *ViperConfig, ref string, last, research bool, warn func(path string, err error)) (string, error) {
+	if last {
+		entry, err := NewRunHistory(config.HistoryPath()).Last()
+		if err == nil {
+			if research {
+				return existingRunFile("research", entry.Timestamp, entry.ResearchPath)
+			}
+			return existingRunFile("image", entry.Timestamp, entry.ImagePath)
+		}
+		if !errors.Is(err, ErrNoHistory) {
+			return "", err
+		}
+		ref = "latest"
+	}
+
+	runs, err := ListRuns(config, warn)
+	if err != nil {
+		return "", err
+	}
+	run, err := ResolveRun(runs, ref)
+	if err != nil {
+		return "", err
+	}
+
+	var path string
+	if research {
+		if run.Research != nil {
+			path = run.Research.MarkdownPath
+		}
+		return existingRunFile("research", run.Timestamp, path)
+	}
+	if run.Image != nil && len(run.Image.ImagePaths) > 0 {
+		path = run.Image.ImagePaths[0]
+	}
+	return existingRunFile("image", run.Timestamp, path)
+}
+
+// existingRunFile returns path if the file of a run still exists.
+func existingRunFile(kind, timestamp, path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("run %s has no %s", timestamp, kind)
+	}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%s of run %s no longer exists (expected at %s)", kind, timestamp, path)
+		}
+		return "", fmt.Errorf("failed to access %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveOpenPath(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	config := &ViperConfig{OutputDir: outputDir, StateDir: t.TempDir()}
+	byType := &ViperConfig{OutputDir: outputDir}
+	perRun := &ViperConfig{OutputDir: outputDir, Layout: LayoutPerRun}
+	ignore := func(string, error) {}
+
+	tests := []struct {
+		name     string
+		ref      string
+		last     bool
+		research bool
+		want     string
+		wantErr  string
+	}{
+		{name: "image of a run", ref: "20251120", want: byType.ImagePath("20251120_080000", ".png")},
+		{name: "research of a run", ref: "20251120_080000", research: true, want: byType.ResearchPath("20251120_080000")},
+		{name: "last without history", last: true, wantErr: "run 20251226_070000 has no image"},
+		{name: "per-run image", ref: "20251225", want: perRun.ImagePath("20251225_090000", ".jpg")},
+		{name: "no research", ref: "20251001", research: true, wantErr: "has no research"},
+		{name: "unknown run", ref: "2024", wantErr: "no run matches"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := ResolveOpenPath(config, tt.ref, tt.last, tt.research, ignore)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResolveOpenPath() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveOpenPath() error = %v", err)
+			}
+			if path != tt.want {
+				t.Errorf("ResolveOpenPath() = %s, want %s", path, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOpenPath_Last(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+	config := &ViperConfig{OutputDir: outputDir, StateDir: t.TempDir()}
+	imagePath := config.ImagePath("20251120_080000", ".png")
+	missing := config.ImagePath("20251231_000000", ".png")
+
+	history := NewRunHistory(config.HistoryPath())
+	if err := history.Append(&HistoryEntry{Timestamp: "20251120_080000", ImagePath: imagePath}); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := ResolveOpenPath(config, "", true, false, func(string, error) {}); err != nil || path != imagePath {
+		t.Errorf("ResolveOpenPath() = %s, %v, want %s", path, err, imagePath)
+	}
+	if _, err := ResolveOpenPath(config, "", true, true, func(string, error) {}); err == nil || !strings.Contains(err.Error(), "has no research") {
+		t.Errorf("ResolveOpenPath() error = %v, want no research", err)
+	}
+
+	// A removed file reports where it was expected
+	if err := history.Append(&HistoryEntry{Timestamp: "20251231_000000", ImagePath: missing}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResolveOpenPath(config, "", true, false, func(string, error) {}); err == nil || !strings.Contains(err.Error(), "expected at "+missing) {
+		t.Errorf("ResolveOpenPath() error = %v, want the expected path", err)
+	}
+}
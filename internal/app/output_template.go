@@ -0,0 +1,81 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// OutputTemplateData is the data available to output_template.
+type OutputTemplateData struct {
+	Timestamp string // Run timestamp
+	Date      string // Date of the run (YYYY-MM-DD)
+	Year      string // Year of the run (YYYY)
+	Month     string // Month of the run (MM)
+	Day       string // Day of the run (DD)
+	Slug      string // Slug of the prompt (empty when it has no ASCII letter or digit)
+	Kind      string // Artifact kind: research, image, response, prompt, log or report
+	Dir       string // Directory of the artifact kind in the by-type layout (research, images, ...)
+}
+
+// outputTemplateKinds maps the by-type directories to the artifact kinds of output_template.
+var outputTemplateKinds = map[string]string{
+	"research":  "research",
+	"images":    "image",
+	"responses": "response",
+	"prompts":   "prompt",
+	"logs":      "log",
+	"reports":   "report",
+}
+
+// ParseOutputTemplate parses an output_template.
+//
+// The template renders the directory of an artifact relative to the output directory. It is
+// rendered once for every artifact kind to catch unknown fields and directories outside the
+// output directory before any API call.
+func ParseOutputTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse output template: %w", err)
+	}
+
+	date := time.Date(2025, 12, 24, 10, 30, 45, 0, time.Local)
+	for dir := range outputTemplateKinds {
+		if _, err := renderOutputDir(tmpl, newOutputTemplateData("20251224_103045", date, "example-slug", dir)); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
+
+// newOutputTemplateData returns the output_template data of an artifact in dir.
+func newOutputTemplateData(timestamp string, date time.Time, slug, dir string) OutputTemplateData {
+	return OutputTemplateData{
+		Timestamp: timestamp,
+		Date:      date.Format(time.DateOnly),
+		Year:      date.Format("2006"),
+		Month:     date.Format("01"),
+		Day:       date.Format("02"),
+		Slug:      slug,
+		Kind:      outputTemplateKinds[dir],
+		Dir:       dir,
+	}
+}
+
+// renderOutputDir renders an output_template and returns the cleaned relative directory.
+//
+// Absolute directories and directories outside the output directory are an error.
+func renderOutputDir(tmpl *template.Template, data OutputTemplateData) (string, error) {
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", fmt.Errorf("failed to render output template: %w", err)
+	}
+
+	dir := filepath.Clean(filepath.FromSlash(strings.TrimSpace(builder.String())))
+	if filepath.IsAbs(dir) || filepath.VolumeName(dir) != "" || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("output template renders %q outside the output directory", builder.String())
+	}
+	return dir, nil
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseOutputTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{name: "date and slug", text: "{{.Dir}}/{{.Year}}/{{.Month}}/{{.Slug}}"},
+		{name: "kind and date", text: "{{.Kind}}/{{.Date}}"},
+		{name: "flat", text: "."},
+		{name: "syntax error", text: "{{.Kind", wantErr: "failed to parse"},
+		{name: "unknown field", text: "{{.Project}}", wantErr: "failed to render"},
+		{name: "parent directory", text: "../{{.Kind}}", wantErr: "outside the output directory"},
+		{name: "escape after cleaning", text: "{{.Kind}}/../../etc", wantErr: "outside the output directory"},
+		{name: "absolute", text: "/tmp/{{.Kind}}", wantErr: "outside the output directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseOutputTemplate(tt.text)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ParseOutputTemplate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseOutputTemplate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenderOutputDir(t *testing.T) {
+	date := time.Date(2025, 1, 5, 14, 30, 22, 0, time.Local)
+
+	tests := []struct {
+		name    string
+		text    string
+		data    OutputTemplateData
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "date and slug",
+			text: "{{.Dir}}/{{.Year}}/{{.Month}}/{{.Slug}}",
+			data: newOutputTemplateData("20250105_143022", date, "go-generics", "images"),
+			want: "images/2025/01/go-generics",
+		},
+		{
+			name: "empty slug is cleaned",
+			text: "{{.Kind}}/{{.Slug}}/",
+			data: newOutputTemplateData("20250105_143022", date, "", "responses"),
+			want: "response",
+		},
+		{
+			name:    "escape through data",
+			text:    "{{.Kind}}/{{.Slug}}",
+			data:    OutputTemplateData{Kind: "..", Slug: ".."},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseOutputTemplate(tt.text)
+			if err != nil {
+				t.Fatalf("ParseOutputTemplate() error = %v", err)
+			}
+			got, err := renderOutputDir(tmpl, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderOutputDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != filepath.FromSlash(tt.want) {
+				t.Errorf("renderOutputDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
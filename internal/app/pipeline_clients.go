@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"time"
+)
+
+// ResearchHooks are the callbacks of a research run, all optional.
+type ResearchHooks struct {
+	// OnStarted is called with the interaction ID as soon as a research is started
+	OnStarted func(interactionID string)
+	// OnThought is called with each new thought summary while polling
+	OnThought func(summary string)
+	// OnPoll is called with the status and the time spent polling after each in-progress poll
+	OnPoll func(status string, elapsed time.Duration)
+	// Progress shows the polling progress
+	Progress ProgressReporter
+}
+
+// ResearchExecutor runs the research stage of a pipeline. GenaiResearchClient implements it with
+// the Deep Research API.
+type ResearchExecutor interface {
+	// Execute starts a research of prompt and waits for its result, saved under timestamp.
+	Execute(ctx context.Context, prompt, timestamp string) (*ResearchResult, error)
+	// Resume waits for the result of a running research, saved under timestamp.
+	Resume(ctx context.Context, interactionID, timestamp string) (*ResearchResult, error)
+}
+
+// ImageGenerator runs the image generation stage of a pipeline. GenaiImageClient implements it
+// with the Gemini API.
+type ImageGenerator interface {
+	// BuildLangPrompt builds the prompt of an infographic of markdown in an image language.
+	BuildLangPrompt(lang, markdown string) (string, error)
+	// GenerateVariants generates count images from prompt, saved under timestamp.
+	GenerateVariants(ctx context.Context, prompt string, imgConfig ImageConfig, timestamp string, count int) (*ImageResult, error)
+	// GenerateLangs generates count images per language from the prompt of each language.
+	GenerateLangs(ctx context.Context, langs, prompts []string, imgConfig ImageConfig, timestamp string, count int) (*ImageResult, error)
+}
+
+var (
+	_ ResearchExecutor = (*GenaiResearchClient)(nil)
+	_ ImageGenerator   = (*GenaiImageClient)(nil)
+)
+
+// PipelineClients creates the clients a pipeline run depends on. Nil functions use the Gemini API
+// clients and the system opener, so the zero value runs the real pipeline; tests replace them
+// with fakes.
+type PipelineClients struct {
+	// Research creates the client of the research stage with the hooks of the run
+	Research func(ctx context.Context, config *ViperConfig, logger Logger, hooks ResearchHooks) (ResearchExecutor, error)
+	// Image creates the client of the image generation stage (progress may be nil)
+	Image func(ctx context.Context, config *ViperConfig, logger Logger, progress ProgressReporter) (ImageGenerator, error)
+	// Open opens a generated file with the default application
+	Open func(path string) error
+}
+
+// newResearch creates the research client of a run.
+func (c *PipelineClients) newResearch(ctx context.Context, config *ViperConfig, logger Logger, hooks ResearchHooks) (ResearchExecutor, error) {
+	if c != nil && c.Research != nil {
+		return c.Research(ctx, config, logger, hooks)
+	}
+	client, err := NewGenaiResearchClient(ctx, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	client.ResearchHooks = hooks
+	return client, nil
+}
+
+// newImage creates the image client of a run.
+func (c *PipelineClients) newImage(ctx context.Context, config *ViperConfig, logger Logger, progress ProgressReporter) (ImageGenerator, error) {
+	if c != nil && c.Image != nil {
+		return c.Image(ctx, config, logger, progress)
+	}
+	client, err := NewGenaiImageClient(ctx, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	client.Progress = progress
+	return client, nil
+}
+
+// open opens a generated file.
+func (c *PipelineClients) open(path string) error {
+	if c != nil && c.Open != nil {
+		return c.Open(path)
+	}
+	return OpenFile(path)
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"fmt"
+	"os"
+)
+
+// maxPollSnapshots is the number of poll snapshots kept per research; older ones are removed.
+const maxPollSnapshots = 20
+
+// pollSnapshots saves the interaction bodies seen while polling a research to the responses directory.
+//
+// With save_poll_snapshots every poll is saved as <timestamp>_poll_NN.json. Otherwise only the
+// last polled body is kept in memory and saved as <timestamp>_poll_last.json when polling fails.
+type pollSnapshots struct {
+	config    *ViperConfig
+	timestamp string
+	all       bool   // Save every poll, not only the last one on failure
+	count     int    // Number of polls recorded
+	last      []byte // Body of the latest poll
+}
+
+// newPollSnapshots creates the poll snapshots of the research run at timestamp.
+func newPollSnapshots(config *ViperConfig, timestamp string) *pollSnapshots {
+	return &pollSnapshots{
+		config:    config,
+		timestamp: timestamp,
+		all:       config.SavePollSnapshots,
+	}
+}
+
+// snapshotPath returns the path of the n-th poll snapshot.
+func (s *pollSnapshots) snapshotPath(n int) string {
+	return s.config.PollSnapshotPath(s.timestamp, fmt.Sprintf("%02d", n))
+}
+
+// record remembers the body of a poll, saving it when every poll is saved.
+//
+// Only the most recent maxPollSnapshots snapshots are kept on disk.
+func (s *pollSnapshots) record(body []byte) error {
+	if s == nil {
+		return nil
+	}
+	s.last = body
+	s.count++
+	if !s.all {
+		return nil
+	}
+
+	if err := WriteFile(s.snapshotPath(s.count), body); err != nil {
+		return fmt.Errorf("failed to write poll snapshot: %w", err)
+	}
+	if old := s.count - maxPollSnapshots; old > 0 {
+		if err := os.Remove(s.snapshotPath(old)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove old poll snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+// saveLast saves the latest polled body after polling failed and returns its path.
+//
+// When every poll is saved, the latest snapshot is returned without writing it again.
+// The path is empty when nothing was polled.
+func (s *pollSnapshots) saveLast() (string, error) {
+	if s == nil || s.last == nil {
+		return "", nil
+	}
+	if s.all {
+		return s.snapshotPath(s.count), nil
+	}
+
+	path := s.config.PollSnapshotPath(s.timestamp, "last")
+	if err := WriteFile(path, s.last); err != nil {
+		return "", fmt.Errorf("failed to write last poll snapshot: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPollSnapshots_Prune(t *testing.T) {
+	snapshots := newPollSnapshots(&ViperConfig{OutputDir: t.TempDir(), SavePollSnapshots: true}, "20251224_103045")
+
+	total := maxPollSnapshots + 5
+	for i := 0; i < total; i++ {
+		if err := snapshots.record([]byte(`{"status":"in_progress"}`)); err != nil {
+			t.Fatalf("record() error = %v", err)
+		}
+	}
+
+	matches, err := filepath.Glob(filepath.Join(snapshots.config.ResponsesDir(), "20251224_103045_poll_*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != maxPollSnapshots {
+		t.Errorf("kept %d snapshots, want %d", len(matches), maxPollSnapshots)
+	}
+	if _, err := os.Stat(snapshots.snapshotPath(5)); !os.IsNotExist(err) {
+		t.Error("oldest snapshots should be removed")
+	}
+	if _, err := os.Stat(snapshots.snapshotPath(total)); err != nil {
+		t.Errorf("latest snapshot should be kept: %v", err)
+	}
+
+	path, err := snapshots.saveLast()
+	if err != nil || path != snapshots.snapshotPath(total) {
+		t.Errorf("saveLast() = %q, %v, want the latest snapshot", path, err)
+	}
+}
+
+func TestPollSnapshots_SaveLast(t *testing.T) {
+	snapshots := newPollSnapshots(&ViperConfig{OutputDir: t.TempDir()}, "20251224_103045")
+
+	if path, err := snapshots.saveLast(); err != nil || path != "" {
+		t.Errorf("saveLast() before any poll = %q, %v, want nothing saved", path, err)
+	}
+
+	snapshots.record([]byte(`{"status":"in_progress"}`))
+	snapshots.record([]byte(`{"status":"failed"}`))
+	if matches, _ := filepath.Glob(filepath.Join(snapshots.config.ResponsesDir(), "*_poll_*.json")); len(matches) != 0 {
+		t.Errorf("snapshots = %v, want none saved while polling", matches)
+	}
+
+	path, err := snapshots.saveLast()
+	if err != nil {
+		t.Fatalf("saveLast() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "20251224_103045_poll_last.json" || string(data) != `{"status":"failed"}` {
+		t.Errorf("saved %s = %s, want the last polled body", path, data)
+	}
+}
+
+// TestGenaiResearchClient_AwaitResult_PollSnapshots tests the snapshots written while polling a research.
+func TestGenaiResearchClient_AwaitResult_PollSnapshots(t *testing.T) {
+	t.Run("every poll saved", func(t *testing.T) {
+		config := &ViperConfig{OutputDir: t.TempDir(), PollInterval: 10, PollMaxInterval: 10, PollTimeout: 600, PollMaxFailures: 3, SavePollSnapshots: true}
+		client, _ := newTestResearchClient(t, statusSequenceHandler("in_progress", "in_progress", "completed"), config)
+
+		if _, err := client.awaitResult(context.Background(), "test-id", "", "20251224_103045"); err != nil {
+			t.Fatalf("awaitResult() error = %v", err)
+		}
+		matches, err := filepath.Glob(filepath.Join(config.ResponsesDir(), "20251224_103045_poll_*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != 3 {
+			t.Errorf("snapshots = %v, want one per poll", matches)
+		}
+	})
+
+	t.Run("last poll saved on failure", func(t *testing.T) {
+		config := &ViperConfig{OutputDir: t.TempDir(), PollInterval: 10, PollMaxInterval: 10, PollTimeout: 600, PollMaxFailures: 3}
+		client, _ := newTestResearchClient(t, statusSequenceHandler("in_progress", "failed"), config)
+
+		if _, err := client.awaitResult(context.Background(), "test-id", "", "20251224_103045"); err == nil {
+			t.Fatal("awaitResult() should fail for a failed research")
+		}
+		data, err := os.ReadFile(filepath.Join(config.ResponsesDir(), "20251224_103045_poll_last.json"))
+		if err != nil {
+			t.Fatalf("last poll should be saved: %v", err)
+		}
+		if !strings.Contains(string(data), `"status":"failed"`) {
+			t.Errorf("last poll = %s, want the failed interaction", data)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errTaskSkipped is recorded for pool tasks that were never started.
+var errTaskSkipped = errors.New("skipped")
+
+// runPool runs task for each index in [0, n) with at most concurrency tasks in flight.
+//
+// It returns the error of each task by index. No new task starts once ctx is cancelled or,
+// with failFast, after a task fails; those tasks report errTaskSkipped. Running tasks are
+// expected to observe ctx and return when it is cancelled.
+func runPool(ctx context.Context, concurrency, n int, failFast bool, task func(ctx context.Context, i int) error) []error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = errTaskSkipped
+	}
+
+	var (
+		mu      sync.Mutex
+		stopped bool
+		wg      sync.WaitGroup
+	)
+	slots := make(chan struct{}, concurrency)
+	for i := range n {
+		// Wait for a free slot
+		slots <- struct{}{}
+
+		mu.Lock()
+		halt := stopped || ctx.Err() != nil
+		mu.Unlock()
+		if halt {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := task(ctx, i)
+
+			mu.Lock()
+			defer mu.Unlock()
+			errs[i] = err
+			if err != nil && failFast {
+				stopped = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakePipeline records how many tasks run at the same time.
+type fakePipeline struct {
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+	calls       atomic.Int32
+}
+
+func (f *fakePipeline) run(ctx context.Context, i int) error {
+	f.calls.Add(1)
+	current := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		observed := f.maxInFlight.Load()
+		if current <= observed || f.maxInFlight.CompareAndSwap(observed, current) {
+			break
+		}
+	}
+
+	// Simulate polling waits that overlap between jobs
+	select {
+	case <-time.After(5 * time.Millisecond):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+func TestRunPool_ConcurrencyLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		tasks       int
+	}{
+		{name: "serial", concurrency: 1, tasks: 5},
+		{name: "parallel", concurrency: 3, tasks: 10},
+		{name: "more workers than tasks", concurrency: 8, tasks: 2},
+		{name: "zero is treated as one", concurrency: 0, tasks: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakePipeline{}
+			errs := runPool(context.Background(), tt.concurrency, tt.tasks, false, fake.run)
+
+			for i, err := range errs {
+				if err != nil {
+					t.Errorf("errs[%d] = %v, want nil", i, err)
+				}
+			}
+			if got := int(fake.calls.Load()); got != tt.tasks {
+				t.Errorf("calls = %d, want %d", got, tt.tasks)
+			}
+			limit := max(tt.concurrency, 1)
+			if got := int(fake.maxInFlight.Load()); got > limit {
+				t.Errorf("max in-flight = %d, want <= %d", got, limit)
+			}
+		})
+	}
+}
+
+func TestRunPool_FailFast(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	errs := runPool(context.Background(), 1, 3, true, func(ctx context.Context, i int) error {
+		if i == 0 {
+			return errFailed
+		}
+		return nil
+	})
+
+	if !errors.Is(errs[0], errFailed) {
+		t.Errorf("errs[0] = %v, want errFailed", errs[0])
+	}
+	for i := 1; i < 3; i++ {
+		if !errors.Is(errs[i], errTaskSkipped) {
+			t.Errorf("errs[%d] = %v, want errTaskSkipped", i, errs[i])
+		}
+	}
+}
+
+func TestRunPool_ContinueOnError(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	errs := runPool(context.Background(), 2, 3, false, func(ctx context.Context, i int) error {
+		if i == 0 {
+			return errFailed
+		}
+		return nil
+	})
+
+	if !errors.Is(errs[0], errFailed) || errs[1] != nil || errs[2] != nil {
+		t.Errorf("errs = %v, want only the first to fail", errs)
+	}
+}
+
+func TestRunPool_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel while the first batch of tasks is running
+	var once sync.Once
+	errs := runPool(ctx, 2, 6, false, func(ctx context.Context, i int) error {
+		once.Do(cancel)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	started, skipped := 0, 0
+	for _, err := range errs {
+		switch {
+		case errors.Is(err, errTaskSkipped):
+			skipped++
+		case errors.Is(err, context.Canceled):
+			started++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if started > 2 {
+		t.Errorf("started = %d, want <= 2 (no task starts after cancel)", started)
+	}
+	if skipped == 0 {
+		t.Error("remaining tasks should be skipped after cancel")
+	}
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// showPromptMaxLength is the number of characters printed by --show-prompt before truncation.
+const showPromptMaxLength = 2000
+
+// printImagePrompt prints the final image generation prompt.
+//
+// The prompt is truncated to showPromptMaxLength characters unless full is true.
+// The API key is masked in case it appears in the prompt.
+func printImagePrompt(w io.Writer, prompt string, full bool, apiKey string) {
+	prompt = redactSecrets(prompt, apiKey)
+
+	fmt.Fprintln(w, "=== Image Prompt ===")
+	runes := []rune(prompt)
+	if !full && len(runes) > showPromptMaxLength {
+		fmt.Fprintln(w, string(runes[:showPromptMaxLength]))
+		fmt.Fprintf(w, "... (%d more characters, use --show-prompt-full to print everything)\n", len(runes)-showPromptMaxLength)
+	} else {
+		fmt.Fprintln(w, prompt)
+	}
+	fmt.Fprintln(w, "====================")
+}
+
+// printSystemInstruction prints the system instruction sent with image requests, if any.
+func printSystemInstruction(w io.Writer, instruction string, apiKey string) {
+	if instruction == "" {
+		return
+	}
+	fmt.Fprintln(w, "=== System Instruction ===")
+	fmt.Fprintln(w, redactSecrets(instruction, apiKey))
+	fmt.Fprintln(w, "==========================")
+}
+
+// printDryRun prints what the pipeline would do without making any API request.
+func printDryRun(w io.Writer, opts *Options, config *ViperConfig, prompt string) error {
+	fmt.Fprintln(w, "Dry run: no API requests will be made")
+
+	switch {
+	case opts.ImageOnly:
+		fmt.Fprintln(w, "Research: skipped (--image-only)")
+	case opts.InteractionID != "":
+		fmt.Fprintf(w, "Research: resume interaction %s\n", opts.InteractionID)
+	default:
+		fmt.Fprintf(w, "Research: agent %s\n", config.DeepResearchAgent)
+		fmt.Fprintf(w, "Thinking summaries: %s\n", cmp.Or(config.ThinkingSummaries, "auto"))
+		if config.NoResearchTools {
+			fmt.Fprintln(w, "Research tools: none (--no-tools)")
+			break
+		}
+		tools, err := json.Marshal(researchToolsBody(config.ResearchTools))
+		if err != nil {
+			return fmt.Errorf("failed to marshal research tools: %w", err)
+		}
+		fmt.Fprintf(w, "Research tools: %s\n", tools)
+	}
+
+	if config.NoSaveResponses {
+		fmt.Fprintln(w, "Responses: not saved (--no-save-response)")
+	}
+
+	if opts.ResearchOnly {
+		fmt.Fprintln(w, "Image: skipped (--research-only)")
+		return nil
+	}
+	fmt.Fprintf(w, "Image: model %s (aspect ratio %s, size %s, language %s)\n", opts.Model, opts.AspectRatio, opts.ImageSize, config.ImageLang)
+	if config.Style != "" {
+		fmt.Fprintf(w, "Style: %s\n", config.Style)
+	}
+	if config.FallbackModel != "" {
+		fmt.Fprintf(w, "Fallback model: %s\n", config.FallbackModel)
+	}
+	if len(config.SafetySettings) > 0 {
+		fmt.Fprintf(w, "Safety settings: %v\n", config.SafetySettings)
+	}
+	if config.ImageFormat != "" {
+		fmt.Fprintf(w, "Format: convert to %s (quality %d, keep original %t)\n", config.ImageFormat, config.ImageQuality, config.KeepOriginal)
+	}
+	if opts.Candidates > 1 {
+		fmt.Fprintf(w, "Candidates: %d per request\n", opts.Candidates)
+	}
+	for _, image := range opts.InputImages {
+		fmt.Fprintf(w, "Input image: %s (%s, %d bytes)\n", image.Path, image.MimeType, len(image.Data))
+	}
+	printSystemInstruction(w, config.ImageSystemInstruction, config.APIKey)
+
+	if opts.ShowPrompt {
+		if opts.ImageOnly {
+			imageClient, err := NewGenaiImageClient(context.Background(), config, NewNullLogger())
+			if err != nil {
+				return err
+			}
+			if len(opts.InputImages) > 0 {
+				printImagePrompt(w, prompt, opts.ShowPromptFull, config.APIKey)
+				return nil
+			}
+			langs := ParseImageLangs(config.ImageLang)
+			if len(langs) == 0 {
+				langs = []string{config.ImageLang}
+			}
+			for _, lang := range langs {
+				imagePrompt, err := imageClient.forLang(lang).BuildInfographicsPrompt(prompt)
+				if err != nil {
+					return fmt.Errorf("failed to build image prompt: %w", err)
+				}
+				printImagePrompt(w, imagePrompt, opts.ShowPromptFull, config.APIKey)
+			}
+		} else {
+			fmt.Fprintln(w, "Image prompt: built from the research result")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPrintImagePrompt_MatchesTemplate(t *testing.T) {
+	client, err := NewGenaiImageClient(context.Background(), &ViperConfig{ImageLang: "English"}, NewNullLogger())
+	if err != nil {
+		t.Fatalf("failed to create genai image client: %v", err)
+	}
+	prompt, err := client.BuildInfographicsPrompt("# AI Trends\n\n- Agents")
+	if err != nil {
+		t.Fatalf("BuildInfographicsPrompt() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	printImagePrompt(&buf, prompt, false, "")
+
+	want := "=== Image Prompt ===\n" +
+		"Take a good look at the content below and turn it into a single infographic image in English.\n" +
+		"```\n# AI Trends\n\n- Agents\n```\n" +
+		"====================\n"
+	if buf.String() != want {
+		t.Errorf("printImagePrompt() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintImagePrompt_Truncate(t *testing.T) {
+	prompt := strings.Repeat("a", showPromptMaxLength+5)
+
+	var buf bytes.Buffer
+	printImagePrompt(&buf, prompt, false, "")
+	if strings.Contains(buf.String(), strings.Repeat("a", showPromptMaxLength+1)) {
+		t.Error("prompt should be truncated")
+	}
+	if !strings.Contains(buf.String(), "5 more characters") {
+		t.Errorf("output should mention the truncated length: %q", buf.String())
+	}
+
+	buf.Reset()
+	printImagePrompt(&buf, prompt, true, "")
+	if !strings.Contains(buf.String(), prompt) {
+		t.Error("prompt should not be truncated with full")
+	}
+}
+
+func TestPrintImagePrompt_RedactsAPIKey(t *testing.T) {
+	apiKey := "AIzaSyTestSecretKey1234"
+
+	var buf bytes.Buffer
+	printImagePrompt(&buf, "key="+apiKey, false, apiKey)
+	if strings.Contains(buf.String(), apiKey) {
+		t.Errorf("output should not contain the API key: %q", buf.String())
+	}
+}
+
+func TestPrintDryRun(t *testing.T) {
+	config := &ViperConfig{DeepResearchAgent: "test-agent", ImageLang: "Japanese"}
+
+	tests := []struct {
+		name     string
+		opts     *Options
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "full pipeline",
+			opts:     &Options{Model: "test-model", ShowPrompt: true},
+			contains: []string{"Research: agent test-agent", "Thinking summaries: auto", `Research tools: [{"type":"google_search"},{"type":"url_context"}]`, "Image: model test-model", "built from the research result"},
+		},
+		{
+			name:     "image only prints the prompt",
+			opts:     &Options{ImageOnly: true, ShowPrompt: true},
+			contains: []string{"Research: skipped", "=== Image Prompt ===", "test prompt"},
+			excludes: []string{"Research tools"},
+		},
+		{
+			name:     "research only has no prompt",
+			opts:     &Options{ResearchOnly: true, ShowPrompt: true},
+			contains: []string{"Image: skipped"},
+			excludes: []string{"Image Prompt", "Image prompt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := printDryRun(&buf, tt.opts, config, "test prompt"); err != nil {
+				t.Fatalf("printDryRun() error = %v", err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(buf.String(), s) {
+					t.Errorf("output should contain %q: %q", s, buf.String())
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(buf.String(), s) {
+					t.Errorf("output should not contain %q: %q", s, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestPrintDryRun_NoTools(t *testing.T) {
+	config := &ViperConfig{DeepResearchAgent: "test-agent", NoResearchTools: true}
+
+	var buf bytes.Buffer
+	if err := printDryRun(&buf, &Options{ResearchOnly: true}, config, "test prompt"); err != nil {
+		t.Fatalf("printDryRun() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "Research tools: none") {
+		t.Errorf("output should show that tools are disabled: %q", buf.String())
+	}
+}
+
+func TestPrintDryRun_SystemInstruction(t *testing.T) {
+	config := &ViperConfig{ImageSystemInstruction: "Use a clean flat design"}
+
+	var buf bytes.Buffer
+	if err := printDryRun(&buf, &Options{ImageOnly: true}, config, "test prompt"); err != nil {
+		t.Fatalf("printDryRun() error = %v", err)
+	}
+	for _, s := range []string{"=== System Instruction ===", "Use a clean flat design"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("output should contain %q: %q", s, buf.String())
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ProgressFormatNDJSON is the format of machine-readable progress events (--progress ndjson).
+const ProgressFormatNDJSON = "ndjson"
+
+// Progress event types.
+const (
+	EventPipelineStarted   = "pipeline_started"
+	EventResearchStarted   = "research_started"
+	EventResearchPolling   = "research_polling"
+	EventResearchCompleted = "research_completed"
+	EventImageStarted      = "image_started"
+	EventImageCompleted    = "image_completed"
+	EventPipelineCompleted = "pipeline_completed"
+	EventWarning           = "warning"
+	EventError             = "error"
+)
+
+// ProgressEvent is a progress event, written as one JSON line.
+//
+// Timestamp identifies the run, so that the events of parallel batch runs can be told apart.
+type ProgressEvent struct {
+	Event          string         `json:"event"`
+	Time           time.Time      `json:"time"`
+	Timestamp      string         `json:"timestamp"`
+	InteractionID  string         `json:"interaction_id,omitempty"`  // research_started
+	Status         string         `json:"status,omitempty"`          // research_polling
+	ElapsedSeconds float64        `json:"elapsed_seconds,omitempty"` // research_polling
+	MarkdownPath   string         `json:"markdown_path,omitempty"`   // research_completed, pipeline_completed
+	ImagePath      string         `json:"image_path,omitempty"`      // image_completed, pipeline_completed
+	ImagePaths     []string       `json:"image_paths,omitempty"`     // image_completed, pipeline_completed
+	ManifestPath   string         `json:"manifest_path,omitempty"`   // pipeline_completed
+	Message        string         `json:"message,omitempty"`         // warning
+	Error          *ProgressError `json:"error,omitempty"`           // error
+}
+
+// ProgressError describes the error of an error event.
+type ProgressError struct {
+	Message       string `json:"message"`
+	ExitCode      int    `json:"exit_code"`
+	Stage         string `json:"stage,omitempty"`          // Failed pipeline stage (research or image)
+	Operation     string `json:"operation,omitempty"`      // Failed API operation
+	StatusCode    int    `json:"status_code,omitempty"`    // HTTP status code of an API error
+	Status        string `json:"status,omitempty"`         // Google error status of an API error
+	Hint          string `json:"hint,omitempty"`           // Suggestion for resolving the error
+	InteractionID string `json:"interaction_id,omitempty"` // Research that can be resumed
+}
+
+// newProgressError returns the structured fields of an error.
+func newProgressError(err error) *ProgressError {
+	progressErr := &ProgressError{Message: err.Error(), ExitCode: ExitCode(err)}
+
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		progressErr.Stage = stageErr.Stage
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		progressErr.Operation = apiErr.Operation
+		progressErr.StatusCode = apiErr.StatusCode
+		progressErr.Status = apiErr.Status
+		progressErr.Hint = apiErr.Hint()
+	}
+	var resumableErr *ResumableError
+	var interruptedErr *InterruptedError
+	switch {
+	case errors.As(err, &resumableErr):
+		progressErr.InteractionID = resumableErr.InteractionID
+	case errors.As(err, &interruptedErr):
+		progressErr.InteractionID = interruptedErr.InteractionID
+	}
+	return progressErr
+}
+
+// ProgressEmitter writes progress events as NDJSON.
+//
+// Each event is written with a single Write as soon as it is emitted, so that a reader sees it
+// immediately. A nil emitter emits nothing.
+type ProgressEmitter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+// NewProgressEmitter creates a ProgressEmitter writing to w.
+func NewProgressEmitter(w io.Writer) *ProgressEmitter {
+	return &ProgressEmitter{w: w, now: time.Now}
+}
+
+// Emit writes an event, setting its time.
+func (e *ProgressEmitter) Emit(event ProgressEvent) {
+	if e == nil {
+		return
+	}
+	event.Time = e.now()
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.w.Write(append(line, '\n'))
+}
+
+// EmitError writes an error event for a run.
+func (e *ProgressEmitter) EmitError(timestamp string, err error) {
+	e.Emit(ProgressEvent{Event: EventError, Timestamp: timestamp, Error: newProgressError(err)})
+}
@@ -0,0 +1,198 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// spinnerInterval is the redraw interval of the spinner.
+const spinnerInterval = 100 * time.Millisecond
+
+// spinnerFrames are the frames of the spinner animation.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// ProgressReporter shows the progress of the long-running phases of a pipeline (the research
+// polling and the image generation).
+type ProgressReporter interface {
+	// Start begins a phase (e.g., "Researching…"); the timeout is shown when positive.
+	Start(phase string, timeout time.Duration)
+	// Status updates the status shown with the current phase (e.g., the research status).
+	Status(status string)
+	// Stop ends the current phase.
+	Stop()
+}
+
+// nopProgressReporter is a ProgressReporter showing nothing.
+type nopProgressReporter struct{}
+
+func (nopProgressReporter) Start(string, time.Duration) {}
+func (nopProgressReporter) Status(string)               {}
+func (nopProgressReporter) Stop()                       {}
+
+// reporterOrNop returns progress, or a reporter showing nothing when it is nil.
+func reporterOrNop(progress ProgressReporter) ProgressReporter {
+	if progress == nil {
+		return nopProgressReporter{}
+	}
+	return progress
+}
+
+// SpinnerReporter is a ProgressReporter drawing a single-line spinner updated in place, e.g.
+// "⠋ Researching… 03:42 elapsed (timeout 10:00) — status: in_progress".
+//
+// The cursor is left at the start of the line, so that other output overwrites the spinner
+// instead of being appended to it. Text written through the reporter (io.Writer) clears the line
+// first.
+type SpinnerReporter struct {
+	w   io.Writer
+	now func() time.Time
+
+	mu      sync.Mutex
+	phase   string
+	timeout time.Duration
+	status  string
+	started time.Time
+	frame   int
+	active  int           // Number of times the current phase was started and not stopped
+	stop    chan struct{} // Closed to stop the redraw loop of the current phase
+	done    chan struct{} // Closed when the redraw loop has stopped
+}
+
+// NewSpinnerReporter creates a SpinnerReporter drawing to w.
+func NewSpinnerReporter(w io.Writer) *SpinnerReporter {
+	return &SpinnerReporter{w: w, now: time.Now}
+}
+
+// newTerminalProgress returns the spinner of a pipeline run, or nil when stdout or stderr is not a
+// terminal, progress is reported as events, in quiet mode or when prompt files run in parallel.
+func newTerminalProgress(opts *Options) *SpinnerReporter {
+	if opts.Progress != nil || opts.Quiet || opts.Concurrency > 1 || !isTerminal(os.Stdout) || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return NewSpinnerReporter(os.Stderr)
+}
+
+// Start begins a phase, replacing the current one.
+//
+// Starting the current phase again (e.g., image variants generated in parallel) keeps it running
+// until it has been stopped as many times.
+func (s *SpinnerReporter) Start(phase string, timeout time.Duration) {
+	s.mu.Lock()
+	if s.stop != nil && s.phase == phase {
+		s.active++
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	s.stopLoop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.phase, s.timeout, s.status = phase, timeout, ""
+	s.started = s.now()
+	s.active = 1
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	s.draw()
+	go s.loop(s.stop, s.done)
+}
+
+// Status updates the status shown with the current phase.
+func (s *SpinnerReporter) Status(status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	if s.stop != nil {
+		s.draw()
+	}
+}
+
+// Stop ends the current phase and clears the line.
+func (s *SpinnerReporter) Stop() {
+	s.mu.Lock()
+	if s.active--; s.active > 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	s.stopLoop()
+}
+
+// stopLoop stops the redraw loop and clears the line.
+func (s *SpinnerReporter) stopLoop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done, s.active = nil, nil, 0
+	s.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprint(s.w, "\r\033[K")
+}
+
+// Write clears the spinner line and writes p; the spinner is redrawn on the next tick.
+func (s *SpinnerReporter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		fmt.Fprint(s.w, "\r\033[K")
+	}
+	return s.w.Write(p)
+}
+
+// loop redraws the spinner until stop is closed.
+func (s *SpinnerReporter) loop(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.frame = (s.frame + 1) % len(spinnerFrames)
+			s.draw()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// draw redraws the spinner line. The caller holds mu.
+func (s *SpinnerReporter) draw() {
+	fmt.Fprint(s.w, "\r\033[K"+s.line()+"\r")
+}
+
+// line returns the spinner line. The caller holds mu.
+func (s *SpinnerReporter) line() string {
+	line := fmt.Sprintf("%s %s %s elapsed", spinnerFrames[s.frame], s.phase, formatClock(s.now().Sub(s.started)))
+	if s.timeout > 0 {
+		line += fmt.Sprintf(" (timeout %s)", formatClock(s.timeout))
+	}
+	if s.status != "" {
+		line += " — status: " + s.status
+	}
+	return line
+}
+
+// formatClock formats a duration as mm:ss, or h:mm:ss from one hour.
+func formatClock(d time.Duration) string {
+	seconds := int(d.Round(time.Second).Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// isTerminal reports whether a file is a terminal (a character device).
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingReporter is a ProgressReporter recording its calls.
+type recordingReporter struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recordingReporter) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recordingReporter) Start(phase string, timeout time.Duration) {
+	r.record("start " + phase + " " + timeout.String())
+}
+
+func (r *recordingReporter) Status(status string) {
+	r.record("status " + status)
+}
+
+func (r *recordingReporter) Stop() {
+	r.record("stop")
+}
+
+func TestSpinnerReporter_Line(t *testing.T) {
+	now := time.Date(2025, 12, 24, 10, 30, 45, 0, time.UTC)
+	tests := []struct {
+		name    string
+		phase   string
+		timeout time.Duration
+		status  string
+		elapsed time.Duration
+		want    string
+	}{
+		{
+			name:    "research",
+			phase:   "Researching…",
+			timeout: 10 * time.Minute,
+			status:  "in_progress",
+			elapsed: 3*time.Minute + 42*time.Second,
+			want:    "⠋ Researching… 03:42 elapsed (timeout 10:00) — status: in_progress",
+		},
+		{
+			name:    "image without timeout and status",
+			phase:   "Generating image…",
+			elapsed: 12 * time.Second,
+			want:    "⠋ Generating image… 00:12 elapsed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSpinnerReporter(new(bytes.Buffer))
+			s.now = func() time.Time { return now.Add(tt.elapsed) }
+			s.phase, s.timeout, s.status, s.started = tt.phase, tt.timeout, tt.status, now
+			if got := s.line(); got != tt.want {
+				t.Errorf("line() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpinnerReporter_StartStop(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewSpinnerReporter(&buf)
+
+	s.Start("Researching…", time.Minute)
+	s.Status("in_progress")
+	s.Stop()
+	s.Stop() // Stopping twice is harmless
+
+	output := buf.String()
+	if !strings.Contains(output, "Researching… 00:00 elapsed (timeout 01:00) — status: in_progress\r") {
+		t.Errorf("output = %q, want the spinner line with the status", output)
+	}
+	if !strings.HasSuffix(output, "\r\033[K") {
+		t.Errorf("output = %q, want the line cleared on stop", output)
+	}
+}
+
+func TestSpinnerReporter_NestedStart(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewSpinnerReporter(&buf)
+
+	// Parallel image variants start the same phase
+	s.Start("Generating image…", 0)
+	s.Start("Generating image…", 0)
+	s.Stop()
+	s.mu.Lock()
+	running := s.stop != nil
+	s.mu.Unlock()
+	if !running {
+		t.Error("phase stopped while still started once")
+	}
+	s.Stop()
+	if s.stop != nil {
+		t.Error("phase still running after being stopped as many times as started")
+	}
+}
+
+func TestSpinnerReporter_Write(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewSpinnerReporter(&buf)
+
+	// Without a phase, text is written as is
+	s.Write([]byte("before\n"))
+	if buf.String() != "before\n" {
+		t.Errorf("output = %q, want the text only", buf.String())
+	}
+
+	s.Start("Researching…", 0)
+	buf.Reset()
+	s.Write([]byte("Thinking: planning\n"))
+	s.Stop()
+	if !strings.HasPrefix(buf.String(), "\r\033[KThinking: planning\n") {
+		t.Errorf("output = %q, want the spinner line cleared before the text", buf.String())
+	}
+}
+
+func TestFormatClock(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "00:00"},
+		{d: 59*time.Second + 600*time.Millisecond, want: "01:00"},
+		{d: 10 * time.Minute, want: "10:00"},
+		{d: time.Hour + 2*time.Minute + 3*time.Second, want: "1:02:03"},
+	}
+
+	for _, tt := range tests {
+		if got := formatClock(tt.d); got != tt.want {
+			t.Errorf("formatClock(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestNewTerminalProgress(t *testing.T) {
+	// Progress events and parallel runs disable the spinner regardless of the terminal
+	if s := newTerminalProgress(&Options{Progress: NewProgressEmitter(new(bytes.Buffer))}); s != nil {
+		t.Error("newTerminalProgress() != nil with --progress ndjson")
+	}
+	if s := newTerminalProgress(&Options{Concurrency: 2}); s != nil {
+		t.Error("newTerminalProgress() != nil with parallel runs")
+	}
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if isTerminal(file) {
+		t.Error("isTerminal() = true for a regular file")
+	}
+}
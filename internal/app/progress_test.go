@@ -0,0 +1,141 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// decodeProgressEvents decodes NDJSON progress output.
+func decodeProgressEvents(t *testing.T, output string) []map[string]any {
+	t.Helper()
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event line %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestProgressEmitter_Emit(t *testing.T) {
+	tests := []struct {
+		name  string
+		event ProgressEvent
+		want  string
+	}{
+		{
+			name:  "pipeline started",
+			event: ProgressEvent{Event: EventPipelineStarted, Timestamp: "20251224_103045"},
+			want:  `{"event":"pipeline_started","time":"2025-12-24T10:30:45Z","timestamp":"20251224_103045"}`,
+		},
+		{
+			name:  "research started",
+			event: ProgressEvent{Event: EventResearchStarted, Timestamp: "20251224_103045", InteractionID: "abc"},
+			want:  `{"event":"research_started","time":"2025-12-24T10:30:45Z","timestamp":"20251224_103045","interaction_id":"abc"}`,
+		},
+		{
+			name:  "research polling",
+			event: ProgressEvent{Event: EventResearchPolling, Timestamp: "20251224_103045", Status: "in_progress", ElapsedSeconds: 12.5},
+			want:  `{"event":"research_polling","time":"2025-12-24T10:30:45Z","timestamp":"20251224_103045","status":"in_progress","elapsed_seconds":12.5}`,
+		},
+		{
+			name:  "research completed",
+			event: ProgressEvent{Event: EventResearchCompleted, Timestamp: "20251224_103045", MarkdownPath: "research/20251224_103045.md"},
+			want:  `{"event":"research_completed","time":"2025-12-24T10:30:45Z","timestamp":"20251224_103045","markdown_path":"research/20251224_103045.md"}`,
+		},
+		{
+			name:  "image completed",
+			event: ProgressEvent{Event: EventImageCompleted, Timestamp: "20251224_103045", ImagePath: "images/a.png", ImagePaths: []string{"images/a.png"}},
+			want:  `{"event":"image_completed","time":"2025-12-24T10:30:45Z","timestamp":"20251224_103045","image_path":"images/a.png","image_paths":["images/a.png"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			emitter := NewProgressEmitter(&buf)
+			emitter.now = func() time.Time { return time.Date(2025, 12, 24, 10, 30, 45, 0, time.UTC) }
+			emitter.Emit(tt.event)
+			if got := buf.String(); got != tt.want+"\n" {
+				t.Errorf("Emit() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgressEmitter_Nil(t *testing.T) {
+	var emitter *ProgressEmitter
+	emitter.Emit(ProgressEvent{Event: EventPipelineStarted}) // Verify no panic
+	emitter.EmitError("20251224_103045", errors.New("x"))
+}
+
+func TestProgressEmitter_Concurrent(t *testing.T) {
+	var buf bytes.Buffer
+	emitter := NewProgressEmitter(&buf)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			emitter.Emit(ProgressEvent{Event: EventResearchPolling, Timestamp: fmt.Sprintf("run%d", i)})
+		}()
+	}
+	wg.Wait()
+
+	// Every event is a whole line
+	if events := decodeProgressEvents(t, buf.String()); len(events) != 20 {
+		t.Errorf("events = %d, want 20", len(events))
+	}
+}
+
+func TestNewProgressError(t *testing.T) {
+	apiErr := &StageError{Stage: StageImage, Err: fmt.Errorf("failed to generate image: %w", &APIError{Operation: OpGenerateImage, StatusCode: 429, Status: "RESOURCE_EXHAUSTED", Message: "quota"})}
+	got := newProgressError(apiErr)
+	want := &ProgressError{
+		Message:    apiErr.Error(),
+		ExitCode:   ExitAPIAuth,
+		Stage:      StageImage,
+		Operation:  OpGenerateImage,
+		StatusCode: 429,
+		Status:     "RESOURCE_EXHAUSTED",
+		Hint:       "the API quota is exhausted; wait a moment and try again",
+	}
+	if *got != *want {
+		t.Errorf("newProgressError() = %+v, want %+v", got, want)
+	}
+
+	resumable := &StageError{Stage: StageResearch, Err: &ResumableError{InteractionID: "abc", Err: ErrPollTimeout}}
+	if got := newProgressError(resumable); got.InteractionID != "abc" || got.ExitCode != ExitTimeout || got.Stage != StageResearch {
+		t.Errorf("newProgressError() = %+v, want the interaction ID of the resumable research", got)
+	}
+}
+
+func TestExecutePipeline_ProgressError(t *testing.T) {
+	var buf bytes.Buffer
+	config := &ViperConfig{OutputDir: t.TempDir()}
+	opts := &Options{File: filepath.Join(t.TempDir(), "missing.md"), Timestamp: "20251224_103045", Progress: NewProgressEmitter(&buf)}
+
+	if _, err := ExecutePipeline(context.Background(), opts, config); err == nil {
+		t.Fatal("ExecutePipeline() error = nil, want an error for a missing prompt file")
+	}
+
+	events := decodeProgressEvents(t, buf.String())
+	if len(events) != 1 || events[0]["event"] != EventError || events[0]["timestamp"] != "20251224_103045" {
+		t.Fatalf("events = %v, want one error event", events)
+	}
+	progressErr, _ := events[0]["error"].(map[string]any)
+	if progressErr["exit_code"] != float64(ExitUsage) || !strings.Contains(progressErr["message"].(string), "failed to read prompt file") {
+		t.Errorf("error = %v, want the usage error", progressErr)
+	}
+}
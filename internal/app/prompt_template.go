@@ -0,0 +1,56 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultImagePromptTemplate is the built-in infographic prompt template.
+const DefaultImagePromptTemplate = "Take a good look at the content below and turn it into a single infographic image in {{.Lang}}.\n" +
+	"```\n" +
+	"{{.Content}}\n" +
+	"```"
+
+// templateContentSentinel is rendered as Content to verify that a template uses it.
+const templateContentSentinel = "\x00deepviz-content\x00"
+
+// ImagePromptData is the data available to image prompt templates.
+type ImagePromptData struct {
+	Lang    string // Image language (ImageLang)
+	Content string // Sanitized Markdown content
+}
+
+// ParseImagePromptTemplate parses an image prompt template.
+//
+// An empty text returns the built-in template. The template must reference {{.Content}}.
+func ParseImagePromptTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultImagePromptTemplate
+	}
+
+	tmpl, err := template.New("image_prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image prompt template: %w", err)
+	}
+
+	// Render once to catch unknown fields and a missing {{.Content}} before any API call
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, ImagePromptData{Lang: "English", Content: templateContentSentinel}); err != nil {
+		return nil, fmt.Errorf("failed to render image prompt template: %w", err)
+	}
+	if !strings.Contains(builder.String(), templateContentSentinel) {
+		return nil, fmt.Errorf("image prompt template must contain {{.Content}}")
+	}
+
+	return tmpl, nil
+}
+
+// renderImagePrompt renders an image prompt template.
+func renderImagePrompt(tmpl *template.Template, data ImagePromptData) (string, error) {
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", fmt.Errorf("failed to render image prompt template: %w", err)
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestBuildInfographicsPrompt_Golden(t *testing.T) {
+	markdown := "# AI Trends\n\n- Agents\n- Multimodal models"
+
+	tests := []struct {
+		name     string
+		template string // template file under testdata/prompt_template (empty uses the built-in)
+		golden   string
+	}{
+		{
+			name:   "default template",
+			golden: "default.golden",
+		},
+		{
+			name:     "custom template",
+			template: "timeline.tmpl",
+			golden:   "timeline.golden",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ViperConfig{ImageLang: "English"}
+			if tt.template != "" {
+				data, err := os.ReadFile(filepath.Join("testdata", "prompt_template", tt.template))
+				if err != nil {
+					t.Fatal(err)
+				}
+				config.ImagePromptTemplate = string(data)
+			}
+
+			client, err := NewGenaiImageClient(context.Background(), config, NewNullLogger())
+			if err != nil {
+				t.Fatalf("failed to create genai image client: %v", err)
+			}
+			got, err := client.BuildInfographicsPrompt(markdown)
+			if err != nil {
+				t.Fatalf("BuildInfographicsPrompt() error = %v", err)
+			}
+
+			goldenPath := filepath.Join("testdata", "prompt_template", tt.golden)
+			if *update {
+				if err := os.WriteFile(goldenPath, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("BuildInfographicsPrompt() =\n%s\nwant\n%s", got, string(want))
+			}
+		})
+	}
+}
+
+func TestParseImagePromptTemplate_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{
+			name:     "missing content",
+			template: "Draw something in {{.Lang}}",
+			wantErr:  "must contain {{.Content}}",
+		},
+		{
+			name:     "parse error",
+			template: "{{.Content",
+			wantErr:  "failed to parse",
+		},
+		{
+			name:     "unknown field",
+			template: "{{.Content}} {{.Color}}",
+			wantErr:  "failed to render",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseImagePromptTemplate(tt.template)
+			if err == nil {
+				t.Fatal("ParseImagePromptTemplate() should return error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRootCommand_InvalidPromptTemplate(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DEEPVIZ_OUTPUT_DIR", t.TempDir())
+	t.Setenv("DEEPVIZ_STATE_DIR", t.TempDir())
+	t.Setenv("GEMINI_API_KEY", "invalid")
+
+	templatePath := filepath.Join(t.TempDir(), "bad.tmpl")
+	if err := os.WriteFile(templatePath, []byte("no placeholder"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fails before research starts, so no request reaches the API
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--prompt", "test", "--prompt-template", templatePath})
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+
+	err := cmd.Execute()
+	if ExitCode(err) != ExitConfig {
+		t.Errorf("ExitCode() = %d, want %d (err = %v)", ExitCode(err), ExitConfig, err)
+	}
+}
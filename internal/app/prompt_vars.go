@@ -0,0 +1,63 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// PromptVarsDateFormat is the format of the built-in Date template variable.
+const PromptVarsDateFormat = "2006-01-02"
+
+// ParsePromptVars parses key=value pairs given by --var.
+func ParsePromptVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid variable %q (expected key=value)", pair)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// promptTemplateData returns the variables available to prompt templates.
+//
+// Built-in variables (Date, Timestamp) can be overridden by user variables.
+func promptTemplateData(vars map[string]string, timestamp string, now time.Time) map[string]string {
+	data := map[string]string{
+		"Date":      now.Format(PromptVarsDateFormat),
+		"Timestamp": timestamp,
+	}
+	for key, value := range vars {
+		data[key] = value
+	}
+	return data
+}
+
+// RenderPromptTemplate renders a prompt through text/template.
+//
+// Text without template actions is returned unchanged. The env function reads environment variables,
+// e.g. {{env "USER"}}. Errors include the template name and line number.
+func RenderPromptTemplate(name, text string, data map[string]string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"env": os.Getenv}).
+		Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePromptVars(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "key value pairs",
+			pairs: []string{"Topic=AI", "Query=a=b", "Empty="},
+			want:  map[string]string{"Topic": "AI", "Query": "a=b", "Empty": ""},
+		},
+		{
+			name:    "missing equals",
+			pairs:   []string{"Topic"},
+			wantErr: true,
+		},
+		{
+			name:    "empty key",
+			pairs:   []string{"=AI"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePromptVars(tt.pairs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePromptVars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParsePromptVars() = %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("vars[%s] = %q, want %q", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPromptTemplate(t *testing.T) {
+	now := time.Date(2025, 12, 24, 10, 30, 45, 0, time.UTC)
+	data := promptTemplateData(map[string]string{"Topic": "AI"}, "20251224_103045", now)
+	t.Setenv("DEEPVIZ_TEST_REGION", "Tokyo")
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "variables and built-ins",
+			text: "Summarize news about {{.Topic}} as of {{.Date}} ({{.Timestamp}})",
+			want: "Summarize news about AI as of 2025-12-24 (20251224_103045)",
+		},
+		{
+			name: "env function",
+			text: "Events in {{env \"DEEPVIZ_TEST_REGION\"}}",
+			want: "Events in Tokyo",
+		},
+		{
+			// Text without template syntax passes through unchanged even if it is not a valid template
+			name: "no template syntax",
+			text: "Price: $100 }} 50% \\n\r\n",
+			want: "Price: $100 }} 50% \\n\r\n",
+		},
+		{
+			name:    "undefined variable reports line number",
+			text:    "line 1\nline 2 {{.Missing}}",
+			wantErr: "prompt.md:2:",
+		},
+		{
+			name:    "parse error reports line number",
+			text:    "line 1\nline 2\n{{.Topic",
+			wantErr: "prompt.md:3:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderPromptTemplate("prompt.md", tt.text, data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RenderPromptTemplate() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderPromptTemplate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderPromptTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptTemplateData_UserOverridesBuiltIn(t *testing.T) {
+	data := promptTemplateData(map[string]string{"Date": "yesterday"}, "ts", time.Now())
+	if data["Date"] != "yesterday" {
+		t.Errorf("Date = %q, want yesterday", data["Date"])
+	}
+}
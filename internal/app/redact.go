@@ -0,0 +1,125 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// secretPatterns match common secrets whether or not they are configured. The first group is
+// kept and the second one is masked.
+var secretPatterns = []*regexp.Regexp{
+	// Google API keys
+	regexp.MustCompile(`()(AIza[0-9A-Za-z_\-]{35})`),
+	// Keys and tokens in URL query strings (e.g., "?key=...")
+	regexp.MustCompile(`(?i)([?&](?:key|api_key|access_token|token)=)([^&\s"']+)`),
+	// API key and authorization headers (e.g., "x-goog-api-key: ...", "Authorization: Bearer ...")
+	regexp.MustCompile(`(?i)((?:x-goog-api-key|authorization)"?\s*[:=]\s*"?(?:bearer\s+)?)([^\s"',;\]}]+)`),
+}
+
+// redactSecrets replaces the secrets (e.g., the configured API key) and the secrets matching
+// secretPatterns in text with their masked form.
+func redactSecrets(text string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, maskAPIKey(secret))
+		}
+	}
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllStringFunc(text, func(match string) string {
+			groups := pattern.FindStringSubmatch(match)
+			return groups[1] + maskAPIKey(groups[2])
+		})
+	}
+	return text
+}
+
+// inlineDataPattern matches the inline data of request and response bodies ("inlineData":
+// {"mimeType": ..., "data": ...}), the submatch being the base64 data.
+var inlineDataPattern = regexp.MustCompile(`"inline_?[dD]ata"\s*:\s*\{[^{}]*?"data"\s*:\s*"([^"]*)"`)
+
+// redactBody returns a saved request or response body with the secrets (e.g., the configured API
+// keys) masked. Unlike redactSecrets, only the secrets themselves are replaced and the inline data
+// is left as is: the patterns would match inside base64 images and corrupt them.
+func redactBody(body []byte, secrets ...string) []byte {
+	if !slices.ContainsFunc(secrets, func(secret string) bool { return secret != "" }) {
+		return body
+	}
+	replace := func(part []byte) []byte {
+		for _, secret := range secrets {
+			if secret != "" {
+				part = bytes.ReplaceAll(part, []byte(secret), []byte(maskAPIKey(secret)))
+			}
+		}
+		return part
+	}
+
+	redacted := make([]byte, 0, len(body))
+	last := 0
+	for _, match := range inlineDataPattern.FindAllSubmatchIndex(body, -1) {
+		redacted = append(redacted, replace(body[last:match[2]])...)
+		redacted = append(redacted, body[match[2]:match[3]]...)
+		last = match[3]
+	}
+	return append(redacted, replace(body[last:])...)
+}
+
+// redactHandler is a slog.Handler masking secrets in the message and attributes of records
+// before passing them to the next handler, so that they never reach the console or log file.
+type redactHandler struct {
+	next    slog.Handler
+	secrets []string
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, redactSecrets(r.Message, h.secrets...), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redactAttr(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(redacted), secrets: h.secrets}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name), secrets: h.secrets}
+}
+
+// redactAttr masks the secrets in the value of an attribute.
+func (h *redactHandler) redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(redactSecrets(a.Value.String(), h.secrets...))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, attr := range group {
+			redacted[i] = h.redactAttr(attr)
+		}
+		a.Value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		// Errors and other values are logged as text, which may contain a secret (e.g., a URL)
+		text := a.Value.String()
+		if redacted := redactSecrets(text, h.secrets...); redacted != text {
+			a.Value = slog.StringValue(redacted)
+		}
+	}
+	return a
+}
+
+var _ slog.Handler = (*redactHandler)(nil)
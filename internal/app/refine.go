@@ -0,0 +1,224 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// refineSuffixPattern matches the refinement suffix of a timestamp (e.g., "_r2").
+var refineSuffixPattern = regexp.MustCompile(`_r(\d+)$`)
+
+// RefineTarget is the image refined by `deepviz refine` and where the refinement is saved.
+type RefineTarget struct {
+	BaseTimestamp string // Timestamp of the original run
+	ImagePath     string // Image sent as the input of the refinement
+	Prompt        string // Saved image prompt of the original run (empty when not found)
+	Timestamp     string // Timestamp of the refinement (e.g., "<timestamp>_r1")
+}
+
+// ResolveRefineTarget resolves a run timestamp or an image path to refine.
+//
+// A timestamp of an original run resolves to its latest refinement so that refinements chain
+// (r1, r2, ...). A timestamp with a refinement suffix or an image path refines that image.
+// The refinement is numbered after the latest one of the original run in either case.
+func ResolveRefineTarget(config *ViperConfig, target string) (*RefineTarget, error) {
+	var name, imagePath string
+	if info, err := os.Stat(target); err == nil && !info.IsDir() {
+		imagePath = target
+		name = imagePathName(config, target)
+	} else {
+		name = target
+	}
+
+	base := refineSuffixPattern.ReplaceAllString(name, "")
+	latest, err := latestRefinement(config, base)
+	if err != nil {
+		return nil, err
+	}
+
+	if imagePath == "" {
+		// Refine the latest image of the chain unless a refinement was named explicitly
+		if name == base && latest > 0 {
+			name = refineTimestamp(base, latest)
+		}
+		imagePath = findRunImage(config, name)
+		if imagePath == "" {
+			return nil, fmt.Errorf("no image found for %s in %s", name, filepath.Dir(config.ImagePath(name, "")))
+		}
+	}
+
+	// The saved prompt gives the model the context of the original request
+	data, err := ReadFile(config.ImagePromptPath(base))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read prompt file: %w", err)
+	}
+
+	return &RefineTarget{
+		BaseTimestamp: base,
+		ImagePath:     imagePath,
+		Prompt:        strings.TrimSpace(string(data)),
+		Timestamp:     refineTimestamp(base, latest+1),
+	}, nil
+}
+
+// refineTimestamp returns the timestamp of the n-th refinement of a run.
+func refineTimestamp(base string, n int) string {
+	return base + "_r" + strconv.Itoa(n)
+}
+
+// imagePathName returns the artifact name of an image path (the inverse of ViperConfig.ImagePath).
+//
+// Images outside the output directory are named after their file name.
+func imagePathName(config *ViperConfig, path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if config.Layout == LayoutPerRun {
+		// runs/<timestamp>/image<suffix>.png
+		if suffix, ok := strings.CutPrefix(name, "image"); ok {
+			return filepath.Base(filepath.Dir(path)) + suffix
+		}
+	}
+	return name
+}
+
+// latestRefinement returns the number of the latest refinement of a run, or 0 if there is none.
+func latestRefinement(config *ViperConfig, base string) (int, error) {
+	// Refinement images share the path of "<base>_r" followed by their number
+	prefix := config.ImagePath(base+"_r", "")
+	entries, err := os.ReadDir(filepath.Dir(prefix))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read images directory: %w", err)
+	}
+
+	latest := 0
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		rest, ok := strings.CutPrefix(name, filepath.Base(prefix))
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(rest); err == nil && n > latest {
+			latest = n
+		}
+	}
+	return latest, nil
+}
+
+// findRunImage returns the path of the first image saved for a timestamp, or "" if there is none.
+func findRunImage(config *ViperConfig, timestamp string) string {
+	for _, ext := range []string{".png", ".jpg", ".webp"} {
+		path := config.ImagePath(timestamp, ext)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// buildRefinePrompt returns the text sent with the image being refined.
+func buildRefinePrompt(prompt, feedback string) string {
+	var b strings.Builder
+	b.WriteString("Revise the attached infographic according to the feedback below. ")
+	b.WriteString("Keep everything the feedback does not mention unchanged.\n\n")
+	if prompt != "" {
+		b.WriteString("The infographic was generated from this prompt:\n\n")
+		b.WriteString(prompt)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("Feedback:\n\n")
+	b.WriteString(strings.TrimSpace(feedback))
+	return b.String()
+}
+
+// RunRefine generates a refined version of a previous image with feedback.
+func RunRefine(ctx context.Context, target, feedback string, opts *Options, config *ViperConfig) error {
+	if strings.TrimSpace(feedback) == "" {
+		return &UsageError{Err: fmt.Errorf("feedback must not be empty")}
+	}
+
+	if err := config.EnsureDirectories(); err != nil {
+		return &ConfigError{Err: fmt.Errorf("failed to ensure directories: %w", err)}
+	}
+	if err := config.loadAPIKey(); err != nil {
+		return &ConfigError{Err: err}
+	}
+
+	refineTarget, err := ResolveRefineTarget(config, target)
+	if err != nil {
+		return &UsageError{Err: err}
+	}
+	inputImage, err := LoadInputImage(refineTarget.ImagePath)
+	if err != nil {
+		return &UsageError{Err: err}
+	}
+
+	logger := NewSlogLogger(ConsoleLogOptions{
+		Writer: LogConsole(config.LogOutput),
+		Format: config.LogFormat,
+		Level:  VerbosityLevel(opts.Verbose),
+	}, config.LogFile(refineTarget.Timestamp), config.APIKey)
+	defer logger.Close()
+	logger.Info("Refine started", "image", refineTarget.ImagePath, "timestamp", refineTarget.Timestamp)
+	if refineTarget.Prompt == "" {
+		logger.Warn("No saved prompt found for the original run, sending the feedback only", "timestamp", refineTarget.BaseTimestamp)
+	}
+
+	imageClient, err := NewGenaiImageClient(ctx, config, logger)
+	if err != nil {
+		return &StageError{Stage: StageImage, Err: fmt.Errorf("failed to create image client: %w", err)}
+	}
+
+	imgConfig := ImageConfig{
+		Model:       opts.Model,
+		AspectRatio: opts.AspectRatio,
+		ImageSize:   opts.ImageSize,
+		InputImages: []*InputImage{inputImage},
+	}
+	imagePrompt := buildRefinePrompt(refineTarget.Prompt, feedback)
+	imageResult, err := imageClient.GenerateVariants(ctx, imagePrompt, imgConfig, refineTarget.Timestamp, 1)
+	if err != nil {
+		return &StageError{Stage: StageImage, Err: fmt.Errorf("failed to refine image: %w", err)}
+	}
+	logger.Info("Refine completed", "image_path", imageResult.ImagePath, "model", imageResult.Model)
+
+	if !opts.NoOpen && config.AutoOpen {
+		if err := OpenFile(imageResult.ImagePath); err != nil {
+			logger.Info("Failed to open image", "path", imageResult.ImagePath, "error", err)
+		}
+	}
+
+	var summary strings.Builder
+	summary.WriteString("\n=== Refine Completed ===\n")
+	fmt.Fprintf(&summary, "Timestamp: %s\n", refineTarget.Timestamp)
+	fmt.Fprintf(&summary, "Refined from: %s\n", refineTarget.ImagePath)
+	for _, path := range imageResult.ImagePaths {
+		fmt.Fprintf(&summary, "Image: %s\n", path)
+	}
+	if imageResult.FallbackFrom != "" {
+		fmt.Fprintf(&summary, "Model: %s (fallback, %s failed)\n", imageResult.Model, imageResult.FallbackFrom)
+	}
+	fmt.Fprintf(&summary, "Prompt: %s\n", imageResult.PromptPath)
+	fmt.Fprint(opts.stdout(), summary.String())
+
+	// Record the refinement so that `deepviz last` finds it
+	entry := &HistoryEntry{
+		Timestamp:  refineTarget.Timestamp,
+		FinishedAt: time.Now(),
+		Prompt:     promptExcerpt(feedback),
+		ImagePath:  imageResult.ImagePath,
+		ImagePaths: imageResult.ImagePaths,
+	}
+	if err := NewRunHistory(config.HistoryPath()).Append(entry); err != nil {
+		logger.Error("Failed to record run history", "error", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveRefineTarget(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir()}
+	files := map[string]string{
+		filepath.Join(config.ImagesDir(), "20251224_103045.png"):    "original",
+		filepath.Join(config.ImagesDir(), "20251224_103045_r1.png"): "r1",
+		filepath.Join(config.ImagesDir(), "20251224_103045_r2.jpg"): "r2",
+		filepath.Join(config.ImagesDir(), "20251225_090000.png"):    "other",
+		filepath.Join(config.PromptsDir(), "20251224_103045.txt"):   "AI trends infographic\n",
+	}
+	for path, content := range files {
+		if err := WriteFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		target        string
+		wantImage     string
+		wantTimestamp string
+		wantPrompt    string
+		wantErr       bool
+	}{
+		{
+			name:          "timestamp resolves to the latest refinement",
+			target:        "20251224_103045",
+			wantImage:     "20251224_103045_r2.jpg",
+			wantTimestamp: "20251224_103045_r3",
+			wantPrompt:    "AI trends infographic",
+		},
+		{
+			name:          "explicit refinement",
+			target:        "20251224_103045_r1",
+			wantImage:     "20251224_103045_r1.png",
+			wantTimestamp: "20251224_103045_r3",
+			wantPrompt:    "AI trends infographic",
+		},
+		{
+			name:          "image path",
+			target:        filepath.Join(config.ImagesDir(), "20251224_103045.png"),
+			wantImage:     "20251224_103045.png",
+			wantTimestamp: "20251224_103045_r3",
+			wantPrompt:    "AI trends infographic",
+		},
+		{
+			name:          "run without refinements or saved prompt",
+			target:        "20251225_090000",
+			wantImage:     "20251225_090000.png",
+			wantTimestamp: "20251225_090000_r1",
+		},
+		{
+			name:    "unknown timestamp",
+			target:  "20240101_000000",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := ResolveRefineTarget(config, tt.target)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ResolveRefineTarget() should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveRefineTarget() error = %v", err)
+			}
+			if filepath.Base(target.ImagePath) != tt.wantImage {
+				t.Errorf("ImagePath = %s, want %s", target.ImagePath, tt.wantImage)
+			}
+			if target.Timestamp != tt.wantTimestamp {
+				t.Errorf("Timestamp = %s, want %s", target.Timestamp, tt.wantTimestamp)
+			}
+			if target.Prompt != tt.wantPrompt {
+				t.Errorf("Prompt = %q, want %q", target.Prompt, tt.wantPrompt)
+			}
+		})
+	}
+}
+
+func TestResolveRefineTarget_PerRunLayout(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir(), Layout: LayoutPerRun}
+	runDir := config.RunDir("20251224_103045")
+	for name, content := range map[string]string{
+		"image.png":    "original",
+		"image_r1.png": "r1",
+		"prompt.txt":   "AI trends infographic\n",
+	} {
+		if err := WriteFile(filepath.Join(runDir, name), []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, target := range []string{"20251224_103045", filepath.Join(runDir, "image_r1.png")} {
+		refineTarget, err := ResolveRefineTarget(config, target)
+		if err != nil {
+			t.Fatalf("ResolveRefineTarget(%s) error = %v", target, err)
+		}
+		if want := filepath.Join(runDir, "image_r1.png"); refineTarget.ImagePath != want {
+			t.Errorf("ImagePath = %s, want %s", refineTarget.ImagePath, want)
+		}
+		if refineTarget.Timestamp != "20251224_103045_r2" || refineTarget.Prompt != "AI trends infographic" {
+			t.Errorf("target = %+v, want the second refinement with the saved prompt", refineTarget)
+		}
+	}
+}
+
+func TestResolveRefineTarget_NoImagesDir(t *testing.T) {
+	config := &ViperConfig{OutputDir: filepath.Join(t.TempDir(), "missing")}
+	if _, err := ResolveRefineTarget(config, "20251224_103045"); err == nil || !strings.Contains(err.Error(), "no image found") {
+		t.Errorf("ResolveRefineTarget() error = %v, want no image found", err)
+	}
+}
+
+func TestBuildRefinePrompt(t *testing.T) {
+	prompt := buildRefinePrompt("AI trends infographic", "  Make the title bigger\n")
+	for _, want := range []string{"AI trends infographic", "Feedback:\n\nMake the title bigger"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt should contain %q: %q", want, prompt)
+		}
+	}
+	if !strings.HasSuffix(prompt, "Make the title bigger") {
+		t.Errorf("feedback should be trimmed and come last: %q", prompt)
+	}
+
+	// Without a saved prompt only the feedback is sent
+	if prompt := buildRefinePrompt("", "Drop the third section"); strings.Contains(prompt, "generated from") {
+		t.Errorf("prompt should not refer to a missing original prompt: %q", prompt)
+	}
+}
+
+func TestRunRefine_EmptyFeedback(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir()}
+	err := RunRefine(context.Background(), "20251224_103045", "  ", &Options{}, config)
+	var usageErr *UsageError
+	if !errors.As(err, &usageErr) {
+		t.Errorf("RunRefine() error = %v, want UsageError", err)
+	}
+	if _, err := os.Stat(config.LogsDir()); !os.IsNotExist(err) {
+		t.Error("nothing should be created for empty feedback")
+	}
+}
@@ -0,0 +1,233 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yukiyan/deepviz/internal/version"
+)
+
+// ReportFormatHTML is the format of run reports (--report html).
+const ReportFormatHTML = "html"
+
+// ReportData is the data available to report templates (report_template).
+type ReportData struct {
+	Title     string        // First line of the prompt
+	Timestamp string        // Run timestamp
+	Name      string        // Artifact name of the run
+	StartedAt time.Time     // Start time of the run
+	Prompt    string        // Prompt of the run (an excerpt for reports of stored runs)
+	Tags      []string      // Tags of the run
+	Agent     string        // Research agent (empty without research)
+	Model     string        // Image model (empty without image)
+	Research  template.HTML // Research rendered as HTML, without its front matter
+	Images    []ReportImage // Images embedded as data URIs
+	Version   string        // deepviz version
+}
+
+// ReportImage is an image embedded in a report.
+type ReportImage struct {
+	Path string       // Path of the image file
+	Src  template.URL // data: URI of the image
+}
+
+// defaultReportTemplate is the built-in report template.
+const defaultReportTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="generator" content="deepviz {{.Version}}">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "Hiragino Sans", "Noto Sans JP", sans-serif; line-height: 1.6; color: #1f2328; max-width: 960px; margin: 2rem auto; padding: 0 1rem; }
+header { border-bottom: 1px solid #d0d7de; margin-bottom: 1.5rem; }
+dl.meta { display: grid; grid-template-columns: max-content 1fr; gap: 0.25rem 1rem; font-size: 0.9rem; color: #59636e; }
+dl.meta dt { font-weight: 600; }
+dl.meta dd { margin: 0; }
+figure { margin: 1.5rem 0; }
+figure img { max-width: 100%; height: auto; border: 1px solid #d0d7de; }
+figcaption { font-size: 0.8rem; color: #59636e; }
+pre { background: #f6f8fa; padding: 1rem; overflow-x: auto; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 0.9em; }
+blockquote { border-left: 4px solid #d0d7de; margin: 0; padding: 0 1rem; color: #59636e; }
+table { border-collapse: collapse; margin: 1rem 0; }
+th, td { border: 1px solid #d0d7de; padding: 0.4rem 0.8rem; }
+details pre { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<dl class="meta">
+<dt>Timestamp</dt><dd>{{.Timestamp}}</dd>
+{{- if ne .Name .Timestamp}}
+<dt>Name</dt><dd>{{.Name}}</dd>
+{{- end}}
+{{- if not .StartedAt.IsZero}}
+<dt>Started</dt><dd>{{.StartedAt.Format "2006-01-02 15:04:05 MST"}}</dd>
+{{- end}}
+{{- with .Agent}}
+<dt>Agent</dt><dd>{{.}}</dd>
+{{- end}}
+{{- with .Model}}
+<dt>Model</dt><dd>{{.}}</dd>
+{{- end}}
+{{- with .Tags}}
+<dt>Tags</dt><dd>{{range $i, $tag := .}}{{if $i}}, {{end}}{{$tag}}{{end}}</dd>
+{{- end}}
+</dl>
+<details>
+<summary>Prompt</summary>
+<pre>{{.Prompt}}</pre>
+</details>
+</header>
+{{- range .Images}}
+<figure>
+<img src="{{.Src}}" alt="Infographic">
+<figcaption>{{.Path}}</figcaption>
+</figure>
+{{- end}}
+{{- with .Research}}
+<article>
+{{.}}
+</article>
+{{- end}}
+</body>
+</html>
+`
+
+// BuildReportData reads the research and images of a run for its report.
+//
+// prompt is the full prompt of the run; when empty (reports of stored runs), the prompt excerpt
+// of the manifest is used. A run without research and image, or whose files no longer exist,
+// is an error.
+func BuildReportData(run *RunManifest, prompt string) (*ReportData, error) {
+	if prompt == "" {
+		prompt = run.Prompt.Excerpt
+	}
+	data := &ReportData{
+		Title:     reportTitle(prompt),
+		Timestamp: run.Timestamp,
+		Name:      run.Timestamp,
+		StartedAt: run.StartedAt,
+		Prompt:    prompt,
+		Tags:      run.Tags,
+		Version:   version.Version(),
+	}
+	if run.Name != "" {
+		data.Name = run.Name
+	}
+
+	if research := run.Research; research != nil && research.MarkdownPath != "" {
+		path, err := existingRunFile("research", run.Timestamp, research.MarkdownPath)
+		if err != nil {
+			return nil, err
+		}
+		markdown, err := ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read research: %w", err)
+		}
+		_, content := ParseResearchFrontMatter(string(markdown))
+		data.Agent = research.Agent
+		data.Research = template.HTML(renderHTMLMarkdown(content))
+	}
+
+	if image := run.Image; image != nil {
+		for _, imagePath := range image.ImagePaths {
+			path, err := existingRunFile("image", run.Timestamp, imagePath)
+			if err != nil {
+				return nil, err
+			}
+			imageData, err := ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read image: %w", err)
+			}
+			data.Images = append(data.Images, ReportImage{Path: path, Src: imageDataURI(imageData)})
+		}
+		if len(data.Images) > 0 {
+			data.Model = image.Model
+		}
+	}
+
+	if data.Research == "" && len(data.Images) == 0 {
+		return nil, fmt.Errorf("run %s has no research or image to report", run.Timestamp)
+	}
+	return data, nil
+}
+
+// ParseReportTemplate parses a report template (html/template), the built-in one when text is empty.
+func ParseReportTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultReportTemplate
+	}
+	tmpl, err := template.New("report").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse report template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// RenderReport renders the report of a run with a template.
+func RenderReport(tmpl *template.Template, data *ReportData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteReport writes the HTML report of a run and returns its path.
+//
+// The report template is read from report_template when set. A report is derived from the run
+// artifacts, so an existing report of the run is rewritten in place.
+func WriteReport(config *ViperConfig, run *RunManifest, prompt string) (string, error) {
+	var text string
+	if config.ReportTemplate != "" {
+		data, err := ReadFile(config.ReportTemplate)
+		if err != nil {
+			return "", &ConfigError{Err: fmt.Errorf("failed to read report template: %w", err)}
+		}
+		text = string(data)
+	}
+	tmpl, err := ParseReportTemplate(text)
+	if err != nil {
+		return "", &ConfigError{Err: err}
+	}
+
+	data, err := BuildReportData(run, prompt)
+	if err != nil {
+		return "", err
+	}
+	report, err := RenderReport(tmpl, data)
+	if err != nil {
+		return "", err
+	}
+
+	path := config.ReportPath(data.Name)
+	if err := WriteFile(path, report); err != nil {
+		return "", fmt.Errorf("failed to save report: %w", err)
+	}
+	return path, nil
+}
+
+// reportTitle returns the first non-blank line of a prompt without heading markers.
+func reportTitle(prompt string) string {
+	for _, line := range strings.Split(prompt, "\n") {
+		if line = strings.TrimLeft(strings.TrimSpace(line), "#>-* "); line != "" {
+			return line
+		}
+	}
+	return "deepviz report"
+}
+
+// imageDataURI returns a data: URI embedding an image.
+func imageDataURI(data []byte) template.URL {
+	mimeType := mimeTypeBase(http.DetectContentType(data))
+	return template.URL("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data))
+}
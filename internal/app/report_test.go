@@ -0,0 +1,155 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pngHeader is enough of a PNG for content type detection.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// writeReportRun saves the research and image of a run and returns its manifest.
+func writeReportRun(t *testing.T, config *ViperConfig) *RunManifest {
+	t.Helper()
+	run := newRunManifest("20251224_103045", "# AI trends\nCover 2025.", "", config.LogPath("20251224_103045"), []string{"weekly"}, time.Date(2025, 12, 24, 10, 30, 45, 0, time.Local))
+	run.Research = &ManifestResearch{Status: RunStatusCompleted, Agent: "deep-research", MarkdownPath: config.ResearchPath("20251224_103045")}
+	run.Image = &ManifestImage{Status: RunStatusCompleted, Model: "image-model", ImagePaths: []string{config.ImagePath("20251224_103045", ".png")}}
+	research := "---\ninteraction_id: abc\n---\n# Findings\n\n<b>Growth</b> is **strong**."
+	if err := WriteFile(run.Research.MarkdownPath, []byte(research)); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(run.Image.ImagePaths[0], pngHeader); err != nil {
+		t.Fatal(err)
+	}
+	return run
+}
+
+func TestBuildReportData(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir()}
+	run := writeReportRun(t, config)
+
+	data, err := BuildReportData(run, "")
+	if err != nil {
+		t.Fatalf("BuildReportData() error = %v", err)
+	}
+	if data.Title != "AI trends Cover 2025." || data.Prompt != run.Prompt.Excerpt {
+		t.Errorf("Title = %q, Prompt = %q, want the prompt excerpt", data.Title, data.Prompt)
+	}
+	if data.Name != run.Timestamp || data.Agent != "deep-research" || data.Model != "image-model" {
+		t.Errorf("data = %+v, want the run metadata", data)
+	}
+	if strings.Contains(string(data.Research), "interaction_id") {
+		t.Errorf("Research = %q, want the front matter stripped", data.Research)
+	}
+	if research := string(data.Research); strings.Contains(research, "<b>") || !strings.Contains(research, "Growth<!-- raw HTML omitted --> is <strong>strong</strong>") {
+		t.Errorf("Research = %q, want rendered markdown without raw HTML", data.Research)
+	}
+	if len(data.Images) != 1 || !strings.HasPrefix(string(data.Images[0].Src), "data:image/png;base64,") {
+		t.Errorf("Images = %+v, want one PNG data URI", data.Images)
+	}
+
+	full, err := BuildReportData(run, "# AI trends\nCover 2025.")
+	if err != nil {
+		t.Fatalf("BuildReportData() error = %v", err)
+	}
+	if full.Title != "AI trends" || full.Prompt != "# AI trends\nCover 2025." {
+		t.Errorf("Title = %q, Prompt = %q, want the full prompt", full.Title, full.Prompt)
+	}
+}
+
+func TestBuildReportData_MissingFiles(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir()}
+
+	run := writeReportRun(t, config)
+	if err := os.Remove(run.Image.ImagePaths[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BuildReportData(run, ""); err == nil || !strings.Contains(err.Error(), "no longer exists") {
+		t.Errorf("BuildReportData() error = %v, want a missing image error", err)
+	}
+
+	empty := newRunManifest("20251224_103045", "AI trends", "", "", nil, time.Now())
+	if _, err := BuildReportData(empty, ""); err == nil {
+		t.Error("BuildReportData() error = nil, want an error for a run without research and image")
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir()}
+	run := writeReportRun(t, config)
+
+	path, err := WriteReport(config, run, "")
+	if err != nil {
+		t.Fatalf("WriteReport() error = %v", err)
+	}
+	if want := filepath.Join(config.OutputDir, "reports", "20251224_103045.html"); path != want {
+		t.Errorf("path = %s, want %s", path, want)
+	}
+	report, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<title>AI trends Cover 2025.</title>", "<h1>Findings</h1>", `src="data:image/png;base64,`, "weekly"} {
+		if !strings.Contains(string(report), want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+
+	// Reports are rewritten in place
+	if again, err := WriteReport(config, run, ""); err != nil || again != path {
+		t.Errorf("WriteReport() = %s, %v, want %s", again, err, path)
+	}
+}
+
+func TestWriteReport_Template(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir(), Layout: LayoutPerRun}
+	run := writeReportRun(t, config)
+
+	config.ReportTemplate = filepath.Join(t.TempDir(), "report.html")
+	if err := os.WriteFile(config.ReportTemplate, []byte("<h1>{{.Title}}</h1>{{.Research}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path, err := WriteReport(config, run, "")
+	if err != nil {
+		t.Fatalf("WriteReport() error = %v", err)
+	}
+	if want := filepath.Join(config.RunDir("20251224_103045"), "report.html"); path != want {
+		t.Errorf("path = %s, want %s", path, want)
+	}
+	if report, _ := os.ReadFile(path); !strings.HasPrefix(string(report), "<h1>AI trends Cover 2025.</h1><h1>Findings</h1>") {
+		t.Errorf("report = %q, want the custom template", report)
+	}
+
+	var configErr *ConfigError
+	if err := os.WriteFile(config.ReportTemplate, []byte("{{.Unknown}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WriteReport(config, run, ""); err == nil {
+		t.Error("WriteReport() error = nil, want an error for an unknown field")
+	}
+	config.ReportTemplate = filepath.Join(t.TempDir(), "missing.html")
+	if _, err := WriteReport(config, run, ""); !errors.As(err, &configErr) {
+		t.Errorf("WriteReport() error = %v, want ConfigError", err)
+	}
+}
+
+func TestReportTitle(t *testing.T) {
+	tests := []struct {
+		prompt string
+		want   string
+	}{
+		{prompt: "\n## Quantum computing\nDetails", want: "Quantum computing"},
+		{prompt: "量子コンピュータの現状", want: "量子コンピュータの現状"},
+		{prompt: "  \n", want: "deepviz report"},
+	}
+
+	for _, tt := range tests {
+		if got := reportTitle(tt.prompt); got != tt.want {
+			t.Errorf("reportTitle(%q) = %q, want %q", tt.prompt, got, tt.want)
+		}
+	}
+}
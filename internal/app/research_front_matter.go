@@ -0,0 +1,69 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yukiyan/deepviz/internal/version"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ResearchFrontMatter is the metadata written as YAML front matter at the top of saved research markdown.
+type ResearchFrontMatter struct {
+	Prompt         string    `yaml:"prompt,omitempty"`      // Prompt excerpt (empty when resumed)
+	PromptHash     string    `yaml:"prompt_hash,omitempty"` // SHA-256 of the full prompt
+	InteractionID  string    `yaml:"interaction_id"`
+	Agent          string    `yaml:"agent"`
+	ImageModel     string    `yaml:"image_model,omitempty"` // Model intended for the infographic
+	ImageLang      string    `yaml:"image_lang,omitempty"`  // Language intended for the infographic
+	CreatedAt      time.Time `yaml:"created_at"`
+	DeepvizVersion string    `yaml:"deepviz_version"`
+}
+
+// newResearchFrontMatter returns the front matter of a research started from prompt.
+func newResearchFrontMatter(config *ViperConfig, interactionID, prompt string, createdAt time.Time) *ResearchFrontMatter {
+	fm := &ResearchFrontMatter{
+		InteractionID:  interactionID,
+		Agent:          config.DeepResearchAgent,
+		ImageModel:     config.Model,
+		ImageLang:      config.ImageLang,
+		CreatedAt:      createdAt,
+		DeepvizVersion: version.Version(),
+	}
+	if prompt != "" {
+		fm.Prompt = promptExcerpt(prompt)
+		fm.PromptHash = HashPrompt(prompt)
+	}
+	return fm
+}
+
+// Prepend returns content with the front matter block at the top.
+func (fm *ResearchFrontMatter) Prepend(content string) (string, error) {
+	data, err := yaml.Marshal(fm)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal research front matter: %w", err)
+	}
+	return frontMatterDelimiter + "\n" + string(data) + frontMatterDelimiter + "\n\n" + content, nil
+}
+
+// ParseResearchFrontMatter splits saved research markdown into its front matter and content.
+//
+// Markdown without research front matter (a YAML block with an interaction_id) is returned
+// unchanged with nil front matter, so research saved with markdown_front_matter disabled and
+// prompt files with their own front matter are left as is.
+func ParseResearchFrontMatter(markdown string) (*ResearchFrontMatter, string) {
+	block, body, ok := splitFrontMatter(markdown)
+	if !ok {
+		return nil, markdown
+	}
+
+	var fm ResearchFrontMatter
+	if err := yaml.Unmarshal([]byte(block), &fm); err != nil || fm.InteractionID == "" {
+		return nil, markdown
+	}
+
+	// Prepend separates the front matter from the content with a blank line
+	return &fm, strings.TrimPrefix(body, "\n")
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yukiyan/deepviz/internal/version"
+)
+
+func TestResearchFrontMatter_RoundTrip(t *testing.T) {
+	config := &ViperConfig{DeepResearchAgent: "deep-research-pro-preview-12-2025", Model: "gemini-3-pro-image-preview", ImageLang: "Japanese"}
+	createdAt := time.Date(2025, 12, 24, 10, 30, 45, 0, time.UTC)
+	fm := newResearchFrontMatter(config, "interaction-1", "AI trends: what's next?\n---\nmore", createdAt)
+	content := "---\n\n# Report\n\nBody with a horizontal rule:\n\n---\n"
+
+	markdown, err := fm.Prepend(content)
+	if err != nil {
+		t.Fatalf("Prepend() error = %v", err)
+	}
+	if !strings.HasPrefix(markdown, "---\nprompt: ") {
+		t.Errorf("markdown = %q, want front matter at the top", markdown)
+	}
+
+	parsed, body := ParseResearchFrontMatter(markdown)
+	if parsed == nil {
+		t.Fatal("ParseResearchFrontMatter() should find the front matter")
+	}
+	if !reflect.DeepEqual(parsed, fm) {
+		t.Errorf("front matter = %+v, want %+v", parsed, fm)
+	}
+	if body != content {
+		t.Errorf("content = %q, want %q", body, content)
+	}
+	if parsed.PromptHash != HashPrompt("AI trends: what's next?\n---\nmore") || parsed.DeepvizVersion != version.Version() {
+		t.Errorf("front matter = %+v, want the prompt hash and version", parsed)
+	}
+}
+
+func TestParseResearchFrontMatter_Unchanged(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+	}{
+		{name: "no front matter", markdown: "# Report\n"},
+		{name: "prompt file front matter", markdown: "---\naspect_ratio: \"1:1\"\n---\nAI trends\n"},
+		{name: "horizontal rule", markdown: "---\n# Report\n---\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, body := ParseResearchFrontMatter(tt.markdown)
+			if fm != nil || body != tt.markdown {
+				t.Errorf("ParseResearchFrontMatter() = %+v, %q, want the markdown unchanged", fm, body)
+			}
+		})
+	}
+}
+
+func TestGenaiResearchClient_SaveResult_FrontMatter(t *testing.T) {
+	config := &ViperConfig{OutputDir: t.TempDir(), DeepResearchAgent: "test-agent", MarkdownFrontMatter: true}
+	client := &GenaiResearchClient{config: config, logger: NewNullLogger(), clock: newFakeClock()}
+	result := &ResearchResult{InteractionID: "interaction-1", Content: "# Report\n", body: []byte(`{}`)}
+
+	if err := client.saveResult(result, "AI trends", "20251224_103045"); err != nil {
+		t.Fatalf("saveResult() error = %v", err)
+	}
+	data, err := os.ReadFile(result.MarkdownPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fm, body := ParseResearchFrontMatter(string(data))
+	if fm == nil || fm.InteractionID != "interaction-1" || fm.Agent != "test-agent" || fm.Prompt != "AI trends" {
+		t.Errorf("front matter = %+v, want the run metadata", fm)
+	}
+	if body != "# Report\n" || result.Content != "# Report\n" {
+		t.Errorf("content = %q (result %q), want the research content without front matter", body, result.Content)
+	}
+}
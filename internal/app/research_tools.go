@@ -0,0 +1,48 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// researchToolTypes are the tools the research agent can be given with --tools.
+var researchToolTypes = []string{"google_search", "url_context", "code_execution"}
+
+// defaultResearchTools are the tools given to the research agent by default.
+var defaultResearchTools = []string{"google_search", "url_context"}
+
+// ParseResearchTools parses a comma-separated list of research tools.
+//
+// Names are case-insensitive and duplicates are dropped. Unknown names are an error.
+func ParseResearchTools(text string) ([]string, error) {
+	var tools []string
+	for _, name := range strings.Split(text, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || slices.Contains(tools, name) {
+			continue
+		}
+		if !slices.Contains(researchToolTypes, name) {
+			return nil, fmt.Errorf("unknown research tool %q (available: %s)", name, strings.Join(researchToolTypes, ", "))
+		}
+		tools = append(tools, name)
+	}
+	if len(tools) == 0 {
+		return nil, fmt.Errorf("no research tools given (available: %s; use --no-tools to disable them)", strings.Join(researchToolTypes, ", "))
+	}
+	return tools, nil
+}
+
+// researchToolsBody returns the tools array of an interaction request.
+//
+// An empty list uses defaultResearchTools; callers omit the array entirely when NoResearchTools is set.
+func researchToolsBody(tools []string) []map[string]interface{} {
+	if len(tools) == 0 {
+		tools = defaultResearchTools
+	}
+	body := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		body = append(body, map[string]interface{}{"type": tool})
+	}
+	return body
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseResearchTools(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    []string
+		wantErr string
+	}{
+		{text: "google_search,url_context", want: []string{"google_search", "url_context"}},
+		{text: " Code_Execution , google_search,code_execution", want: []string{"code_execution", "google_search"}},
+		{text: "google_search,web_browser", wantErr: `unknown research tool "web_browser"`},
+		{text: " , ", wantErr: "no research tools given"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseResearchTools(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseResearchTools() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseResearchTools() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseResearchTools() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildResearchRequest_Tools tests the tools array of the research request.
+func TestBuildResearchRequest_Tools(t *testing.T) {
+	tests := []struct {
+		name  string
+		tools []string
+		want  []map[string]interface{}
+	}{
+		{
+			name: "default",
+			want: []map[string]interface{}{{"type": "google_search"}, {"type": "url_context"}},
+		},
+		{
+			name:  "configured",
+			tools: []string{"google_search", "code_execution"},
+			want:  []map[string]interface{}{{"type": "google_search"}, {"type": "code_execution"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &GenaiResearchClient{config: &ViperConfig{DeepResearchAgent: "test-agent", ResearchTools: tt.tools}}
+			body := client.buildResearchRequest("prompt")
+			if !reflect.DeepEqual(body["tools"], tt.want) {
+				t.Errorf("tools = %v, want %v", body["tools"], tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildResearchRequest_NoTools tests that --no-tools omits the tools array.
+func TestBuildResearchRequest_NoTools(t *testing.T) {
+	client := &GenaiResearchClient{config: &ViperConfig{DeepResearchAgent: "test-agent", ResearchTools: []string{"google_search"}, NoResearchTools: true}}
+	body := client.buildResearchRequest("prompt")
+
+	if _, ok := body["tools"]; ok {
+		t.Errorf("tools = %v, want no tools array", body["tools"])
+	}
+	if body["input"] != "prompt" || body["agent"] != "test-agent" {
+		t.Errorf("request body = %v, the rest of the request should be unchanged", body)
+	}
+
+	// The serialized request has no tools key at all (not an empty array)
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"tools"`) {
+		t.Errorf("request = %s, want no tools key", data)
+	}
+}
+
+// TestBuildResearchRequest_Store tests that background research is always stored, so that it can
+// be polled and resumed.
+func TestBuildResearchRequest_Store(t *testing.T) {
+	client := &GenaiResearchClient{config: &ViperConfig{DeepResearchAgent: "test-agent"}}
+	body := client.buildResearchRequest("prompt")
+	if body["background"] != true || body["store"] != true {
+		t.Errorf("background, store = %v, %v, want true, true", body["background"], body["store"])
+	}
+}
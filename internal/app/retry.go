@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryBaseDelay is the initial delay between retries when the server gives no hint.
+const retryBaseDelay = 2 * time.Second
+
+// retryableError marks an API error that may succeed when the request is sent again.
+type retryableError struct {
+	err error
+	// retryAfter is the delay suggested by the server (zero if none)
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// isRetryableStatus reports whether a request failing with the status code should be retried.
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// newRetryableError wraps err as retryable when the response status allows it.
+//
+// The suggested delay is taken from the Retry-After header or the retryDelay field of the error body.
+func newRetryableError(err error, statusCode int, header http.Header, body []byte, now time.Time) error {
+	if !isRetryableStatus(statusCode) {
+		return err
+	}
+	return &retryableError{
+		err:        err,
+		retryAfter: parseRetryDelay(header, body, now),
+	}
+}
+
+// parseRetryDelay extracts the server-suggested retry delay.
+//
+// Supported sources (in order):
+//   - Retry-After header in seconds or HTTP-date format
+//   - retryDelay in google.rpc.RetryInfo error details (e.g., "37s")
+func parseRetryDelay(header http.Header, body []byte, now time.Time) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			if d := at.Sub(now); d > 0 {
+				return d
+			}
+			return 0
+		}
+	}
+
+	var errorBody struct {
+		Error struct {
+			Details []struct {
+				RetryDelay string `json:"retryDelay"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errorBody); err == nil {
+		for _, detail := range errorBody.Error.Details {
+			if detail.RetryDelay == "" {
+				continue
+			}
+			if d, err := time.ParseDuration(detail.RetryDelay); err == nil && d >= 0 {
+				return d
+			}
+		}
+	}
+
+	return 0
+}
+
+// retryConfig configures retries of API requests.
+type retryConfig struct {
+	// maxRetries is the number of retries after the first attempt
+	maxRetries int
+	// maxWait caps the total time spent waiting between attempts
+	maxWait time.Duration
+	clock   clock
+}
+
+// newRetryConfig creates a retryConfig from the application configuration.
+func newRetryConfig(config *ViperConfig, clk clock) retryConfig {
+	return retryConfig{
+		maxRetries: config.MaxRetries,
+		maxWait:    time.Duration(config.RetryMaxWait) * time.Second,
+		clock:      clk,
+	}
+}
+
+// withRetry calls attempt until it succeeds, returns a non-retryable error, or the retry budget is exhausted.
+//
+// The delay between attempts grows exponentially from retryBaseDelay unless the server suggests one.
+// Context cancellation aborts the wait immediately.
+func withRetry(ctx context.Context, rc retryConfig, logger Logger, operation string, attempt func(ctx context.Context) error) error {
+	var waited time.Duration
+	for n := 1; ; n++ {
+		err := attempt(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) {
+			return err
+		}
+		if n > rc.maxRetries {
+			return fmt.Errorf("%s failed after %d attempts: %w", operation, n, err)
+		}
+
+		delay := retryable.retryAfter
+		if delay == 0 {
+			delay = retryBaseDelay << (n - 1)
+		}
+		if waited+delay > rc.maxWait {
+			return fmt.Errorf("%s failed after %d attempts (retry wait limit %s reached): %w", operation, n, rc.maxWait, err)
+		}
+		waited += delay
+
+		logger.Info("Retrying request", "operation", operation, "attempt", n+1, "delay", delay.String(), "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-rc.clock.After(delay):
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestParseRetryDelay tests extraction of the server-suggested delay.
+func TestParseRetryDelay(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   string
+		want   time.Duration
+	}{
+		{
+			name:   "Retry-After seconds",
+			header: http.Header{"Retry-After": []string{"7"}},
+			want:   7 * time.Second,
+		},
+		{
+			name:   "Retry-After HTTP-date",
+			header: http.Header{"Retry-After": []string{now.Add(30 * time.Second).Format(http.TimeFormat)}},
+			want:   30 * time.Second,
+		},
+		{
+			name:   "retryDelay in error details",
+			header: http.Header{},
+			body:   `{"error":{"code":429,"status":"RESOURCE_EXHAUSTED","details":[{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"37s"}]}}`,
+			want:   37 * time.Second,
+		},
+		{
+			name:   "header takes precedence over body",
+			header: http.Header{"Retry-After": []string{"3"}},
+			body:   `{"error":{"details":[{"retryDelay":"37s"}]}}`,
+			want:   3 * time.Second,
+		},
+		{
+			name:   "no hint",
+			header: http.Header{},
+			body:   `not json`,
+			want:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryDelay(tt.header, []byte(tt.body), now); got != tt.want {
+				t.Errorf("parseRetryDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWithRetry_SucceedsAfterRetries tests exponential backoff until success.
+func TestWithRetry_SucceedsAfterRetries(t *testing.T) {
+	fc := newFakeClock()
+	rc := retryConfig{maxRetries: 3, maxWait: time.Minute, clock: fc}
+
+	var calls int
+	err := withRetry(context.Background(), rc, NewNullLogger(), "test", func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return &retryableError{err: errors.New("overloaded")}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withRetry() error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+
+	waits := fc.Waits()
+	want := []time.Duration{2 * time.Second, 4 * time.Second}
+	if fmt.Sprint(waits) != fmt.Sprint(want) {
+		t.Errorf("waits = %v, want %v", waits, want)
+	}
+}
+
+// TestWithRetry_HonorsRetryAfter tests that a server-suggested delay is used as is.
+func TestWithRetry_HonorsRetryAfter(t *testing.T) {
+	fc := newFakeClock()
+	rc := retryConfig{maxRetries: 3, maxWait: time.Minute, clock: fc}
+
+	var calls int
+	err := withRetry(context.Background(), rc, NewNullLogger(), "test", func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return &retryableError{err: errors.New("quota"), retryAfter: 9 * time.Second}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withRetry() error = %v", err)
+	}
+
+	waits := fc.Waits()
+	if len(waits) != 1 || waits[0] != 9*time.Second {
+		t.Errorf("waits = %v, want [9s]", waits)
+	}
+}
+
+// TestWithRetry_ExhaustsRetries tests that the final error mentions the number of attempts.
+func TestWithRetry_ExhaustsRetries(t *testing.T) {
+	rc := retryConfig{maxRetries: 2, maxWait: time.Minute, clock: newFakeClock()}
+
+	var calls int
+	err := withRetry(context.Background(), rc, NewNullLogger(), "generate_image", func(ctx context.Context) error {
+		calls++
+		return &retryableError{err: errors.New("overloaded")}
+	})
+	if err == nil {
+		t.Fatal("withRetry() should return error")
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+	if !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("error = %v, want attempt count", err)
+	}
+}
+
+// TestWithRetry_MaxWait tests that the total wait cap stops retries.
+func TestWithRetry_MaxWait(t *testing.T) {
+	fc := newFakeClock()
+	rc := retryConfig{maxRetries: 10, maxWait: 5 * time.Second, clock: fc}
+
+	var calls int
+	err := withRetry(context.Background(), rc, NewNullLogger(), "test", func(ctx context.Context) error {
+		calls++
+		return &retryableError{err: errors.New("overloaded")}
+	})
+	if err == nil || !strings.Contains(err.Error(), "retry wait limit") {
+		t.Fatalf("withRetry() error = %v, want wait limit error", err)
+	}
+	// 2s + 4s would exceed 5s, so only one wait happens
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+// TestWithRetry_NonRetryable tests that other errors are returned immediately.
+func TestWithRetry_NonRetryable(t *testing.T) {
+	rc := retryConfig{maxRetries: 3, maxWait: time.Minute, clock: newFakeClock()}
+	wantErr := errors.New("bad request")
+
+	var calls int
+	err := withRetry(context.Background(), rc, NewNullLogger(), "test", func(ctx context.Context) error {
+		calls++
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Errorf("error = %v, want %v", err, wantErr)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+// blockingClock is a clock whose After never fires.
+type blockingClock struct{}
+
+func (blockingClock) Now() time.Time                         { return time.Now() }
+func (blockingClock) After(d time.Duration) <-chan time.Time { return make(chan time.Time) }
+
+// TestWithRetry_ContextCancel tests that cancellation aborts the backoff sleep.
+func TestWithRetry_ContextCancel(t *testing.T) {
+	rc := retryConfig{maxRetries: 3, maxWait: time.Hour, clock: blockingClock{}}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var once sync.Once
+	err := withRetry(ctx, rc, NewNullLogger(), "test", func(ctx context.Context) error {
+		once.Do(cancel)
+		return &retryableError{err: errors.New("overloaded")}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
+
+// TestGenaiResearchClient_StartResearch_Retry tests that 429 responses are retried.
+func TestGenaiResearchClient_StartResearch_Retry(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		call := calls
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if call == 1 {
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"code":"RESOURCE_EXHAUSTED","message":"quota exceeded"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"test-id","status":"in_progress"}`)
+	})
+
+	config := &ViperConfig{MaxRetries: 3, RetryMaxWait: 60}
+	client, fc := newTestResearchClient(t, handler, config)
+
+	id, err := client.startResearch(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("startResearch() error = %v", err)
+	}
+	if id != "test-id" {
+		t.Errorf("id = %s, want test-id", id)
+	}
+	if waits := fc.Waits(); len(waits) != 1 || waits[0] != 5*time.Second {
+		t.Errorf("waits = %v, want [5s]", waits)
+	}
+}
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RunIDHeader is the header carrying the run ID on every API request of a run, so that a request
+// seen by the API (or quoted in a support ticket) can be matched with the logs and manifest of
+// its run.
+const RunIDHeader = "X-Deepviz-Run-Id"
+
+// NewRunID returns a random ID of a pipeline run: 12 hex digits, short enough to quote and unique
+// across the runs of a batch started in the same second (which share their timestamp prefix).
+func NewRunID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// runIDTransport sets the run ID header on the requests of a run.
+type runIDTransport struct {
+	base  http.RoundTripper
+	runID string
+}
+
+func (t *runIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(RunIDHeader, t.runID)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewRunID(t *testing.T) {
+	id := NewRunID()
+	if _, err := hex.DecodeString(id); err != nil || len(id) != 12 {
+		t.Errorf("NewRunID() = %q, want 12 hex digits", id)
+	}
+	if NewRunID() == id {
+		t.Error("NewRunID() returned the same ID twice")
+	}
+}
+
+func TestNewHTTPClient_RunID(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(RunIDHeader))
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name  string
+		runID string
+	}{
+		{name: "run", runID: "3f9c2a7b1d04"},
+		{name: "outside a run", runID: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+			resp, err := newHTTPClient(&ViperConfig{runID: tt.runID}, apiTimeout{}, NewNullLogger()).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if len(got) != 1 || got[0] != tt.runID {
+				t.Errorf("%s = %q, want %q", RunIDHeader, got, tt.runID)
+			}
+			if req.Header.Get(RunIDHeader) != "" {
+				t.Error("the request of the caller was modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Filename styles of run artifacts.
+const (
+	FilenameStyleTimestamp = "timestamp" // <timestamp>.png
+	FilenameStyleSlug      = "slug"      // <slug>.png
+	FilenameStyleBoth      = "both"      // <timestamp>_<slug>.png
+)
+
+// filenameStyles are the accepted filename_style values.
+var filenameStyles = []string{FilenameStyleTimestamp, FilenameStyleSlug, FilenameStyleBoth}
+
+// maxSlugLength is the maximum length of a slug derived from a prompt.
+const maxSlugLength = 60
+
+// runNamePattern matches explicit run names (--name).
+//
+// Underscores are reserved for the variant suffixes added to artifact names (e.g., "_ja", "_r1").
+var runNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.-]*$`)
+
+// asciiFolds maps accented Latin letters to their ASCII spelling.
+var asciiFolds = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// ParseFilenameStyle validates a filename_style value.
+func ParseFilenameStyle(s string) (string, error) {
+	if !slices.Contains(filenameStyles, s) {
+		return "", fmt.Errorf("invalid filename style %q: must be one of %s", s, strings.Join(filenameStyles, ", "))
+	}
+	return s, nil
+}
+
+// ValidateRunName validates an explicit run name (--name).
+func ValidateRunName(name string) error {
+	if !runNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid name %q: use letters, digits, dots and hyphens only", name)
+	}
+	return nil
+}
+
+// Slugify returns a lowercase, hyphenated ASCII slug of s truncated to maxSlugLength.
+//
+// Accented Latin letters are folded to ASCII and other characters (including Japanese) separate
+// words, so text without any ASCII letter or digit gives an empty slug.
+func Slugify(s string) string {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(s) {
+		word := ""
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			word = string(r)
+		case asciiFolds[r] != "":
+			word = asciiFolds[r]
+		case r == '\'' || r == '’':
+			// Apostrophes join words ("what's" becomes "whats")
+			continue
+		default:
+			pendingHyphen = b.Len() > 0
+			continue
+		}
+		if pendingHyphen {
+			b.WriteByte('-')
+			pendingHyphen = false
+		}
+		b.WriteString(word)
+	}
+
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		// Cut at the last word boundary that fits
+		slug = slug[:maxSlugLength]
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+	}
+	return slug
+}
+
+// promptSlug returns the slug of the first non-blank line of a prompt (without heading markers).
+func promptSlug(prompt string) string {
+	for _, line := range strings.Split(prompt, "\n") {
+		line = strings.TrimLeft(strings.TrimSpace(line), "#>-* ")
+		if line != "" {
+			return Slugify(line)
+		}
+	}
+	return ""
+}
+
+// RunName returns the artifact name of a run in a filename style.
+//
+// Without a slug (e.g., a Japanese prompt) the name is the timestamp whatever the style.
+func RunName(timestamp, slug, style string) string {
+	if slug == "" {
+		return timestamp
+	}
+	switch style {
+	case FilenameStyleSlug:
+		return slug
+	case FilenameStyleBoth:
+		return timestamp + "_" + slug
+	}
+	return timestamp
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "words", input: "Latest AI Trends", want: "latest-ai-trends"},
+		{name: "punctuation", input: "Go 1.24: what's new?!", want: "go-1-24-whats-new"},
+		{name: "curly apostrophe", input: "The world’s oceans", want: "the-worlds-oceans"},
+		{name: "accents", input: "Café crème à Montréal", want: "cafe-creme-a-montreal"},
+		{name: "ligatures", input: "Œuvres et Straße", want: "oeuvres-et-strasse"},
+		{name: "japanese only", input: "最新のAIトレンド", want: "ai"},
+		{name: "no ascii", input: "量子コンピュータの現状", want: ""},
+		{name: "mixed", input: "量子コンピュータ quantum 2025", want: "quantum-2025"},
+		{name: "leading and trailing separators", input: "  --Hello, world--  ", want: "hello-world"},
+		{name: "empty", input: "", want: ""},
+		{
+			name:  "truncated at a word boundary",
+			input: "Research the history of the printing press and its impact on European literacy rates",
+			want:  "research-the-history-of-the-printing-press-and-its-impact",
+		},
+		{name: "single long word", input: strings.Repeat("a", 70), want: strings.Repeat("a", 60)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Slugify(tt.input)
+			if got != tt.want {
+				t.Errorf("Slugify(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if len(got) > maxSlugLength {
+				t.Errorf("Slugify(%q) length = %d, want <= %d", tt.input, len(got), maxSlugLength)
+			}
+		})
+	}
+}
+
+func TestPromptSlug(t *testing.T) {
+	tests := []struct {
+		name   string
+		prompt string
+		want   string
+	}{
+		{name: "first line", prompt: "Latest AI trends\nCover the last year.", want: "latest-ai-trends"},
+		{name: "heading", prompt: "\n\n# Quantum computing\n\nDetails", want: "quantum-computing"},
+		{name: "list item", prompt: "- Solar power adoption\n- Wind", want: "solar-power-adoption"},
+		{name: "blank", prompt: "\n  \n", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promptSlug(tt.prompt); got != tt.want {
+				t.Errorf("promptSlug() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunName(t *testing.T) {
+	tests := []struct {
+		name  string
+		slug  string
+		style string
+		want  string
+	}{
+		{name: "timestamp", slug: "ai-trends", style: FilenameStyleTimestamp, want: "20251224_103045"},
+		{name: "slug", slug: "ai-trends", style: FilenameStyleSlug, want: "ai-trends"},
+		{name: "both", slug: "ai-trends", style: FilenameStyleBoth, want: "20251224_103045_ai-trends"},
+		{name: "slug without slug", slug: "", style: FilenameStyleSlug, want: "20251224_103045"},
+		{name: "both without slug", slug: "", style: FilenameStyleBoth, want: "20251224_103045"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RunName("20251224_103045", tt.slug, tt.style); got != tt.want {
+				t.Errorf("RunName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRunName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "slug", input: "ai-trends"},
+		{name: "dots and digits", input: "report.v2"},
+		{name: "empty", input: "", wantErr: true},
+		{name: "underscore", input: "ai_trends", wantErr: true},
+		{name: "path separator", input: "../etc", wantErr: true},
+		{name: "leading hyphen", input: "-x", wantErr: true},
+		{name: "space", input: "ai trends", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRunName(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRunName(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFilenameStyle(t *testing.T) {
+	for _, style := range filenameStyles {
+		if got, err := ParseFilenameStyle(style); err != nil || got != style {
+			t.Errorf("ParseFilenameStyle(%q) = %q, %v", style, got, err)
+		}
+	}
+	if _, err := ParseFilenameStyle("title"); err == nil {
+		t.Error("ParseFilenameStyle(\"title\") error = nil, want error")
+	}
+}
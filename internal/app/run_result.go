@@ -0,0 +1,199 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// RunResult is the outcome of a pipeline run.
+//
+// RunWithConfig and ExecutePipeline return it instead of printing, so that the CLI, the API server
+// and tests share one description of a run. It is also returned with an error, describing how far
+// the run got.
+type RunResult struct {
+	Timestamp        string
+	Name             string // Artifact name of the run
+	RunID            string // ID of the run in its API requests and logs
+	InteractionID    string // Deep Research interaction (empty in ImageOnly mode or when none was started)
+	OutputDir        string
+	ManifestPath     string
+	LogPath          string
+	Duration         time.Duration   // Time from the start to the end of the run
+	ResearchDuration time.Duration   // Time spent in the research stage (zero when it did not run)
+	ImageDuration    time.Duration   // Time spent in the image stage (zero when it did not run)
+	Manifest         *RunManifest    // Nil when the run stopped before starting (invalid prompt, dry run)
+	Research         *ResearchResult // Nil in ImageOnly mode or when the research failed
+	Image            *ImageResult    // Nil in ResearchOnly mode or when the image generation failed
+}
+
+// runResultJSON is the JSON document of a RunResult (--json).
+type runResultJSON struct {
+	Timestamp               string   `json:"timestamp"`
+	Name                    string   `json:"name"`
+	RunID                   string   `json:"run_id,omitempty"`
+	Status                  string   `json:"status,omitempty"`
+	InteractionID           string   `json:"interaction_id,omitempty"`
+	ResearchPath            string   `json:"research_path,omitempty"`
+	SourcesPath             string   `json:"sources_path,omitempty"`
+	PDFPath                 string   `json:"pdf_path,omitempty"`
+	ImagePaths              []string `json:"image_paths,omitempty"`
+	ImageModel              string   `json:"image_model,omitempty"`
+	ReportPath              string   `json:"report_path,omitempty"`
+	ManifestPath            string   `json:"manifest_path,omitempty"`
+	LogPath                 string   `json:"log_path,omitempty"`
+	OutputDir               string   `json:"output_dir"`
+	DurationSeconds         float64  `json:"duration_seconds"`
+	ResearchDurationSeconds float64  `json:"research_duration_seconds,omitempty"`
+	ImageDurationSeconds    float64  `json:"image_duration_seconds,omitempty"`
+}
+
+// MarshalJSON encodes the artifact paths and durations of the run, in seconds.
+func (r *RunResult) MarshalJSON() ([]byte, error) {
+	doc := runResultJSON{
+		Timestamp:               r.Timestamp,
+		Name:                    r.Name,
+		RunID:                   r.RunID,
+		InteractionID:           r.InteractionID,
+		ManifestPath:            r.ManifestPath,
+		LogPath:                 r.LogPath,
+		OutputDir:               r.OutputDir,
+		DurationSeconds:         r.Duration.Seconds(),
+		ResearchDurationSeconds: r.ResearchDuration.Seconds(),
+		ImageDurationSeconds:    r.ImageDuration.Seconds(),
+	}
+	if r.Manifest != nil {
+		doc.Status, doc.ReportPath = r.Manifest.Status, r.Manifest.ReportPath
+		if r.Manifest.Research != nil {
+			doc.PDFPath = r.Manifest.Research.PDFPath
+		}
+	}
+	if r.Research != nil {
+		doc.ResearchPath, doc.SourcesPath = r.Research.MarkdownPath, r.Research.SourcesPath
+	}
+	if r.Image != nil {
+		doc.ImagePaths, doc.ImageModel = r.Image.ImagePaths, r.Image.Model
+	}
+	return json.Marshal(doc)
+}
+
+// fillFromManifest sets the interaction ID, log path and durations of the run from its manifest.
+func (r *RunResult) fillFromManifest() {
+	m := r.Manifest
+	if m == nil {
+		return
+	}
+	r.LogPath = m.LogPath
+	if m.FinishedAt != nil {
+		r.Duration = m.FinishedAt.Sub(m.StartedAt)
+	}
+	if m.Research != nil {
+		r.InteractionID = m.Research.InteractionID
+		r.ResearchDuration = seconds(m.Research.DurationSeconds)
+	}
+	if m.Image != nil {
+		r.ImageDuration = seconds(m.Image.DurationSeconds)
+	}
+}
+
+// seconds converts a duration in seconds to a time.Duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// printRunResult prints the summary of a completed run, only its artifact paths in quiet mode, or
+// the result as JSON.
+func printRunResult(w io.Writer, result *RunResult, jsonOutput, quiet bool) error {
+	// Written at once so that parallel runs do not interleave
+	if jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal run result: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	if !quiet {
+		fmt.Fprint(w, result.summary(NewStyler(w)))
+		return nil
+	}
+	var paths strings.Builder
+	if result.Research != nil {
+		fmt.Fprintln(&paths, result.Research.MarkdownPath)
+	}
+	if result.Image != nil {
+		for _, path := range result.Image.ImagePaths {
+			fmt.Fprintln(&paths, path)
+		}
+	}
+	fmt.Fprint(w, paths.String())
+	return nil
+}
+
+// Summary returns the summary of a completed run printed by the CLI.
+func (r *RunResult) Summary() string {
+	return r.summary(Styler{})
+}
+
+// summary returns the summary of a completed run with its header, paths and failures styled.
+func (r *RunResult) summary(style Styler) string {
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "\n%s\n", style.Success(msg("summary.pipeline")))
+	fmt.Fprintf(&summary, "%s: %s\n", msg("summary.timestamp"), r.Timestamp)
+	if r.Name != r.Timestamp {
+		fmt.Fprintf(&summary, "%s: %s\n", msg("summary.name"), r.Name)
+	}
+	if r.RunID != "" {
+		fmt.Fprintf(&summary, "%s: %s\n", msg("summary.run_id"), r.RunID)
+	}
+	if r.Research != nil {
+		fmt.Fprintf(&summary, "%s: %s\n", msg("summary.research"), style.Dim(r.Research.MarkdownPath))
+		if r.Research.SourcesPath != "" {
+			fmt.Fprintf(&summary, "%s: %s (%d)\n", msg("summary.sources"), style.Dim(r.Research.SourcesPath), len(r.Research.Sources))
+		}
+		if r.Research.ResponsePath != "" {
+			fmt.Fprintf(&summary, "%s: %s\n", msg("summary.research_response"), style.Dim(r.Research.ResponsePath))
+		}
+		if r.Manifest.Research.PDFPath != "" {
+			fmt.Fprintf(&summary, "%s: %s\n", msg("summary.pdf"), style.Dim(r.Manifest.Research.PDFPath))
+		}
+	}
+	if r.Image != nil {
+		for _, path := range r.Image.ImagePaths {
+			fmt.Fprintf(&summary, "%s: %s\n", msg("summary.image"), style.Dim(path))
+		}
+		for _, path := range r.Image.InputImages {
+			fmt.Fprintf(&summary, "%s: %s\n", msg("summary.input_image"), style.Dim(path))
+		}
+		if r.Image.FallbackFrom != "" {
+			fmt.Fprintf(&summary, "%s: %s\n", msg("summary.model"), msg("summary.fallback", r.Image.Model, r.Image.FallbackFrom))
+		}
+		for _, path := range r.Image.OriginalPaths {
+			fmt.Fprintf(&summary, "%s: %s\n", msg("summary.original_image"), style.Dim(path))
+		}
+		for _, variantErr := range r.Image.VariantErrors {
+			fmt.Fprintf(&summary, "%s %s\n", style.Error(msg("summary.failed_image")), variantErr.Error())
+		}
+		for _, langErr := range r.Image.LangErrors {
+			fmt.Fprintf(&summary, "%s %s\n", style.Error(msg("summary.failed_image")), langErr.Error())
+		}
+		if r.Image.CaptionPath != "" {
+			fmt.Fprintf(&summary, "%s: %s\n", msg("summary.caption"), style.Dim(r.Image.CaptionPath))
+		}
+		if len(r.Image.PromptPaths) > 0 {
+			for _, path := range r.Image.PromptPaths {
+				fmt.Fprintf(&summary, "%s: %s\n", msg("summary.prompt"), style.Dim(path))
+			}
+		} else {
+			fmt.Fprintf(&summary, "%s: %s\n", msg("summary.prompt"), style.Dim(r.Image.PromptPath))
+		}
+	}
+	if r.Manifest.ReportPath != "" {
+		fmt.Fprintf(&summary, "%s: %s\n", msg("summary.report"), style.Dim(r.Manifest.ReportPath))
+	}
+	fmt.Fprintf(&summary, "%s: %s\n", msg("summary.manifest"), style.Dim(r.ManifestPath))
+	fmt.Fprintf(&summary, "%s: %s\n", msg("summary.output_dir"), style.Dim(r.OutputDir))
+	return summary.String()
+}
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintRunResult(t *testing.T) {
+	result := &RunResult{
+		Timestamp:    "20251224_103045",
+		Name:         "20251224_103045",
+		RunID:        "3f9c2a7b1d04",
+		OutputDir:    "output",
+		ManifestPath: "output/manifests/20251224_103045.json",
+		Manifest:     &RunManifest{Research: &ManifestResearch{}},
+		Research:     &ResearchResult{MarkdownPath: "output/research/20251224_103045.md", ResponsePath: "output/research/20251224_103045.json"},
+		Image:        &ImageResult{ImagePath: "output/images/20251224_103045_1.png", ImagePaths: []string{"output/images/20251224_103045_1.png", "output/images/20251224_103045_2.png"}},
+	}
+
+	var quiet bytes.Buffer
+	if err := printRunResult(&quiet, result, false, true); err != nil {
+		t.Fatal(err)
+	}
+	want := "output/research/20251224_103045.md\noutput/images/20251224_103045_1.png\noutput/images/20251224_103045_2.png\n"
+	if quiet.String() != want {
+		t.Errorf("quiet output = %q, want only the paths %q", quiet.String(), want)
+	}
+
+	var summary bytes.Buffer
+	if err := printRunResult(&summary, result, false, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(summary.String(), "=== Pipeline Completed ===") || !strings.Contains(summary.String(), "Manifest: output/manifests/20251224_103045.json") || !strings.Contains(summary.String(), "Run ID: 3f9c2a7b1d04") {
+		t.Errorf("output = %q, want the summary", summary.String())
+	}
+
+	// Research-only runs print the research path only
+	var researchOnly bytes.Buffer
+	if err := printRunResult(&researchOnly, &RunResult{Research: result.Research}, false, true); err != nil {
+		t.Fatal(err)
+	}
+	if researchOnly.String() != "output/research/20251224_103045.md\n" {
+		t.Errorf("quiet output = %q, want the research path", researchOnly.String())
+	}
+
+	// JSON wins over quiet mode
+	var jsonOutput bytes.Buffer
+	if err := printRunResult(&jsonOutput, result, true, true); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(jsonOutput.Bytes(), &doc); err != nil {
+		t.Fatalf("output = %q, want JSON: %v", jsonOutput.String(), err)
+	}
+	if doc["research_path"] != "output/research/20251224_103045.md" || doc["manifest_path"] != "output/manifests/20251224_103045.json" {
+		t.Errorf("JSON = %v, want the artifact paths", doc)
+	}
+}
+
+func TestRunResult_MarshalJSON(t *testing.T) {
+	result := &RunResult{
+		Timestamp:        "20251224_103045",
+		Name:             "20251224_103045-ai-trends",
+		RunID:            "3f9c2a7b1d04",
+		InteractionID:    "v1_abc",
+		OutputDir:        "output",
+		LogPath:          "output/logs/20251224_103045-ai-trends.log",
+		Duration:         90 * time.Second,
+		ResearchDuration: 60 * time.Second,
+		ImageDuration:    30 * time.Second,
+		Manifest:         &RunManifest{Status: RunStatusCompleted, Research: &ManifestResearch{PDFPath: "output/research/a.pdf"}},
+		Image:            &ImageResult{ImagePaths: []string{"output/images/a.png"}, Model: "test-model"},
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"name":"20251224_103045-ai-trends"`,
+		`"run_id":"3f9c2a7b1d04"`,
+		`"status":"completed"`,
+		`"interaction_id":"v1_abc"`,
+		`"pdf_path":"output/research/a.pdf"`,
+		`"image_paths":["output/images/a.png"]`,
+		`"image_model":"test-model"`,
+		`"log_path":"output/logs/20251224_103045-ai-trends.log"`,
+		`"duration_seconds":90`,
+		`"research_duration_seconds":60`,
+		`"image_duration_seconds":30`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON = %s, want %s", data, want)
+		}
+	}
+	if strings.Contains(string(data), "research_path") {
+		t.Errorf("JSON = %s, want no research path without a research", data)
+	}
+}
+
+func TestRunWithConfig_Result(t *testing.T) {
+	tests := []struct {
+		name         string
+		researchOnly bool
+		imageOnly    bool
+	}{
+		{name: "full pipeline"},
+		{name: "research only", researchOnly: true},
+		{name: "image only", imageOnly: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newPipelineTestConfig(t)
+			fakes := &pipelineFakes{research: &fakeResearch{content: "# Research"}, image: &fakeImage{count: 2}}
+			var stdout bytes.Buffer
+			opts := &Options{Prompt: "AI trends", Timestamp: "20251224_103045", ResearchOnly: tt.researchOnly, ImageOnly: tt.imageOnly, Model: "test-model", Count: 2, Quiet: true, NoOpen: true, Stdout: &stdout, Clients: fakes.clients()}
+
+			result, err := RunWithConfig(opts, config)
+			if err != nil {
+				t.Fatalf("RunWithConfig() error = %v", err)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("stdout = %q, want nothing printed", stdout.String())
+			}
+
+			if result.Timestamp != "20251224_103045" || result.Name == "" || result.OutputDir != config.OutputDir {
+				t.Errorf("result = %+v, want the run names", result)
+			}
+			if result.ManifestPath != config.ManifestPath(result.Name) || result.LogPath != config.LogPath(result.Name) {
+				t.Errorf("manifest = %s, log = %s, want the paths of the run", result.ManifestPath, result.LogPath)
+			}
+			if result.Manifest == nil || result.Manifest.Status != RunStatusCompleted {
+				t.Fatalf("manifest = %+v, want a completed run", result.Manifest)
+			}
+
+			wantResearch := !tt.imageOnly
+			if (result.Research != nil) != wantResearch || (result.InteractionID == "v1_fake") != wantResearch {
+				t.Errorf("research = %+v, interaction = %q, want research %v", result.Research, result.InteractionID, wantResearch)
+			}
+			wantImage := !tt.researchOnly
+			if (result.Image != nil) != wantImage {
+				t.Errorf("image = %+v, want image %v", result.Image, wantImage)
+			}
+			if wantImage && len(result.Image.ImagePaths) != 2 {
+				t.Errorf("image paths = %v, want 2 variants", result.Image.ImagePaths)
+			}
+
+			// Stage durations come from the manifest and are zero for skipped stages
+			if wantResearch != (result.Manifest.Research != nil) || wantResearch && result.ResearchDuration != seconds(result.Manifest.Research.DurationSeconds) || !wantResearch && result.ResearchDuration != 0 {
+				t.Errorf("research duration = %v, want the manifest duration", result.ResearchDuration)
+			}
+			if wantImage && result.ImageDuration != seconds(result.Manifest.Image.DurationSeconds) || !wantImage && result.ImageDuration != 0 {
+				t.Errorf("image duration = %v, want the manifest duration", result.ImageDuration)
+			}
+			if result.Duration < result.ResearchDuration+result.ImageDuration {
+				t.Errorf("duration = %v, want at least the stage durations", result.Duration)
+			}
+		})
+	}
+}
+
+func TestRunWithConfig_NoResult(t *testing.T) {
+	config := newPipelineTestConfig(t)
+	fakes := &pipelineFakes{research: &fakeResearch{content: "# Research"}, image: &fakeImage{}}
+
+	// Dry runs stop before any client is created
+	opts := &Options{Prompt: "AI trends", Model: "test-model", Count: 1, DryRun: true, Clients: fakes.clients()}
+	if result, err := RunWithConfig(opts, config); err != nil || result != nil {
+		t.Errorf("dry run = %+v, %v, want no result", result, err)
+	}
+
+	// Batch runs print the result of each prompt file as JSON
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"a.md", "b.md"} {
+		files = append(files, filepath.Join(dir, name))
+		if err := os.WriteFile(files[len(files)-1], []byte("# "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var stdout bytes.Buffer
+	opts = &Options{Files: files, Model: "test-model", Count: 1, Concurrency: 1, JSON: true, NoOpen: true, ResearchOnly: true, Stdout: &stdout, Clients: fakes.clients()}
+	result, err := RunWithConfig(opts, config)
+	if err != nil || result != nil {
+		t.Fatalf("batch run = %+v, %v, want no result", result, err)
+	}
+	decoder := json.NewDecoder(&stdout)
+	for range files {
+		var doc map[string]any
+		if err := decoder.Decode(&doc); err != nil {
+			t.Fatalf("stdout is not a JSON document per file: %v", err)
+		}
+		if doc["status"] != RunStatusCompleted {
+			t.Errorf("JSON = %v, want a completed run", doc)
+		}
+	}
+	if decoder.More() {
+		t.Error("stdout has more than the JSON documents")
+	}
+	if len(fakes.created) != 2 {
+		t.Errorf("created clients = %v, want one research per file", fakes.created)
+	}
+}
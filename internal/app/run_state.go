@@ -0,0 +1,163 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RunRecord is the persisted state of a research that has been started but not finished.
+type RunRecord struct {
+	InteractionID string           `json:"interaction_id"`
+	PromptHash    string           `json:"prompt_hash"`
+	Timestamp     string           `json:"timestamp"`
+	StartedAt     time.Time        `json:"started_at"`
+	Options       RunRecordOptions `json:"options"`
+}
+
+// RunRecordOptions holds the pipeline options needed to resume a run.
+type RunRecordOptions struct {
+	ResearchOnly bool   `json:"research_only"`
+	OutputDir    string `json:"output_dir"`
+	Model        string `json:"model"`
+	AspectRatio  string `json:"aspect_ratio"`
+	ImageSize    string `json:"image_size"`
+	ImageLang    string `json:"image_lang"`
+	APIKeyIndex  *int   `json:"api_key_index,omitempty"` // Key of api_keys that created the interaction
+}
+
+// RunState manages in-flight run records for crash recovery.
+//
+// Each record is stored in its own file so that concurrent runs never write the same file.
+type RunState struct {
+	dir string
+}
+
+// NewRunState creates a new RunState storing records under dir.
+func NewRunState(dir string) *RunState {
+	return &RunState{dir: dir}
+}
+
+// HashPrompt returns the SHA-256 hash of a prompt in hex.
+func HashPrompt(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// Save writes a record atomically.
+func (s *RunState) Save(record *RunRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run record: %w", err)
+	}
+
+	if err := EnsureDir(s.dir); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	// Write to a temporary file first so that readers never see a partial record
+	tmp, err := os.CreateTemp(s.dir, ".run-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to close state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(record.InteractionID)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to rename state file: %w", err)
+	}
+
+	return nil
+}
+
+// Complete removes the record of a finished run.
+//
+// It is not an error if the record does not exist.
+func (s *RunState) Complete(interactionID string) error {
+	if err := os.Remove(s.path(interactionID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove state file: %w", err)
+	}
+	return nil
+}
+
+// Load returns all unfinished run records, oldest first.
+//
+// Unreadable records are skipped.
+func (s *RunState) Load() ([]*RunRecord, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read state directory: %w", err)
+	}
+
+	var records []*RunRecord
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var record RunRecord
+		if err := json.Unmarshal(data, &record); err != nil || record.InteractionID == "" {
+			continue
+		}
+		records = append(records, &record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartedAt.Before(records[j].StartedAt)
+	})
+
+	return records, nil
+}
+
+// Get returns the run record of an interaction, or nil when there is none.
+func (s *RunState) Get(interactionID string) (*RunRecord, error) {
+	data, err := os.ReadFile(s.path(interactionID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read run record: %w", err)
+	}
+	var record RunRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse run record: %w", err)
+	}
+	return &record, nil
+}
+
+// path returns the record file path for an interaction ID.
+func (s *RunState) path(interactionID string) string {
+	return filepath.Join(s.dir, safeFileName(interactionID)+".json")
+}
+
+// safeFileName replaces characters that are not safe in file names.
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunState_SaveLoadComplete(t *testing.T) {
+	state := NewRunState(filepath.Join(t.TempDir(), "runs"))
+
+	// Loading from a missing directory is not an error
+	records, err := state.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("Load() = %d records, want 0", len(records))
+	}
+
+	startedAt := time.Date(2025, 12, 24, 10, 30, 45, 0, time.UTC)
+	record := &RunRecord{
+		InteractionID: "interactions/abc-123",
+		PromptHash:    HashPrompt("test prompt"),
+		Timestamp:     "20251224_103045",
+		StartedAt:     startedAt,
+		Options:       RunRecordOptions{ResearchOnly: true, Model: "test-model"},
+	}
+	if err := state.Save(record); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	records, err = state.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Load() = %d records, want 1", len(records))
+	}
+	got := records[0]
+	if got.InteractionID != record.InteractionID || got.PromptHash != record.PromptHash ||
+		got.Timestamp != record.Timestamp || !got.StartedAt.Equal(startedAt) || got.Options != record.Options {
+		t.Errorf("Load() = %+v, want %+v", got, record)
+	}
+
+	if err := state.Complete(record.InteractionID); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	records, err = state.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Load() after Complete() = %d records, want 0", len(records))
+	}
+
+	// Completing an unknown record is not an error
+	if err := state.Complete("unknown"); err != nil {
+		t.Errorf("Complete(unknown) error = %v", err)
+	}
+}
+
+func TestRunState_ConcurrentSave(t *testing.T) {
+	state := NewRunState(t.TempDir())
+	base := time.Date(2025, 12, 24, 10, 0, 0, 0, time.UTC)
+
+	const runs = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, runs)
+	for i := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- state.Save(&RunRecord{
+				InteractionID: fmt.Sprintf("interaction-%02d", i),
+				StartedAt:     base.Add(time.Duration(i) * time.Second),
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	records, err := state.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(records) != runs {
+		t.Fatalf("Load() = %d records, want %d", len(records), runs)
+	}
+	// Records are sorted by start time
+	for i, record := range records {
+		if want := fmt.Sprintf("interaction-%02d", i); record.InteractionID != want {
+			t.Errorf("records[%d].InteractionID = %s, want %s", i, record.InteractionID, want)
+		}
+	}
+}
+
+func TestRunState_LoadSkipsInvalidRecords(t *testing.T) {
+	dir := t.TempDir()
+	state := NewRunState(dir)
+
+	if err := state.Save(&RunRecord{InteractionID: "valid"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := state.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(records) != 1 || records[0].InteractionID != "valid" {
+		t.Errorf("Load() = %+v, want only the valid record", records)
+	}
+}
+
+func TestPrintUnfinishedRuns(t *testing.T) {
+	records := []*RunRecord{
+		{InteractionID: "first", Timestamp: "20251224_100000"},
+		{InteractionID: "second", Timestamp: "20251224_110000"},
+	}
+
+	var buf bytes.Buffer
+	printUnfinishedRuns(&buf, records, "second")
+	out := buf.String()
+	if !strings.Contains(out, "deepviz resume first") {
+		t.Errorf("output should list the unfinished run: %q", out)
+	}
+	if strings.Contains(out, "second") {
+		t.Errorf("output should not list the excluded run: %q", out)
+	}
+
+	buf.Reset()
+	printUnfinishedRuns(&buf, records[1:], "second")
+	if buf.Len() != 0 {
+		t.Errorf("output should be empty when nothing is pending: %q", buf.String())
+	}
+}
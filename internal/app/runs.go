@@ -0,0 +1,386 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// RunStatusUnknown is the status of a run reconstructed from its files, which has no manifest.
+const RunStatusUnknown = "unknown"
+
+// runsPromptLength is the maximum number of characters of the prompt shown in the run list.
+const runsPromptLength = 40
+
+// runImageExtensions are the extensions of saved images.
+var runImageExtensions = []string{".png", ".jpg", ".webp"}
+
+// ListRuns returns the runs found in the output directory, newest first.
+//
+// Runs are read from their manifests in both layouts, so runs saved before the layout was changed
+// are listed too. Runs without a manifest (saved before manifests were written) are reconstructed
+// from their research, image and log files with schema version 0 and the unknown status.
+// Manifests that cannot be read are skipped and reported to warn.
+func ListRuns(config *ViperConfig, warn func(path string, err error)) ([]*RunManifest, error) {
+	runs := make(map[string]*RunManifest)
+	namer := config.Namer()
+
+	var manifestPaths []string
+	entries, err := readDirIfExists(config.ManifestsDir())
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
+			manifestPaths = append(manifestPaths, filepath.Join(config.ManifestsDir(), entry.Name()))
+		}
+	}
+	runDirs, err := readDirIfExists(config.RunsDir())
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range runDirs {
+		path := filepath.Join(config.RunsDir(), entry.Name(), "manifest.json")
+		if _, err := os.Stat(path); entry.IsDir() && err == nil {
+			manifestPaths = append(manifestPaths, path)
+		}
+	}
+	for _, path := range manifestPaths {
+		manifest, err := LoadManifest(path)
+		if err == nil && manifest.Timestamp == "" {
+			err = fmt.Errorf("manifest %s has no timestamp", path)
+		}
+		if err != nil {
+			warn(path, err)
+			continue
+		}
+		runs[manifest.runKey(namer)] = manifest
+	}
+
+	// Runs without a manifest are reconstructed from their files
+	reconstructed := make(map[string]*RunManifest)
+	addFile := func(timestamp string, info os.FileInfo) *RunManifest {
+		if _, ok := runs[timestamp]; ok {
+			return nil
+		}
+		run, ok := reconstructed[timestamp]
+		if !ok {
+			run = &RunManifest{Timestamp: timestamp, Status: RunStatusUnknown}
+			if startedAt, err := namer.ParseTimestamp(timestamp); err == nil {
+				run.StartedAt = startedAt
+			} else {
+				run.StartedAt = info.ModTime()
+			}
+			reconstructed[timestamp] = run
+		}
+		return run
+	}
+	addResearch := func(timestamp, path string, info os.FileInfo) {
+		if run := addFile(timestamp, info); run != nil {
+			run.Research = &ManifestResearch{Status: RunStatusCompleted, MarkdownPath: path}
+		}
+	}
+	addImage := func(timestamp, path string, info os.FileInfo) {
+		if run := addFile(timestamp, info); run != nil {
+			if run.Image == nil {
+				run.Image = &ManifestImage{Status: RunStatusCompleted}
+			}
+			run.Image.ImagePaths = append(run.Image.ImagePaths, path)
+		}
+	}
+	addLog := func(timestamp, path string, info os.FileInfo) {
+		if run := addFile(timestamp, info); run != nil {
+			run.LogPath = path
+		}
+	}
+
+	// by-type layout: research/<name>.md, images/<name>.png and logs/<name>.log
+	if err := walkRunFiles(config.ResearchDir(), func(name, path string, info os.FileInfo) {
+		if stem, ok := strings.CutSuffix(name, ".md"); ok && !strings.HasSuffix(stem, ".sources") {
+			timestamp, _ := namer.SplitRunName(stem)
+			addResearch(timestamp, path, info)
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if err := walkRunFiles(config.ImagesDir(), func(name, path string, info os.FileInfo) {
+		if stem, ok := imageStem(name); ok {
+			timestamp, _ := namer.SplitRunName(stem)
+			addImage(timestamp, path, info)
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if err := walkRunFiles(config.LogsDir(), func(name, path string, info os.FileInfo) {
+		if stem, ok := strings.CutSuffix(name, ".log"); ok {
+			timestamp, _ := namer.SplitRunName(stem)
+			addLog(timestamp, path, info)
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	// per-run layout: runs/<timestamp>/research.md, image.png and run.log (with the variant suffixes of the name)
+	for _, entry := range runDirs {
+		if !entry.IsDir() {
+			continue
+		}
+		timestamp := entry.Name()
+		if err := walkRunFiles(filepath.Join(config.RunsDir(), timestamp), func(name, path string, info os.FileInfo) {
+			switch stem, isImage := imageStem(name); {
+			case strings.HasPrefix(name, "research") && strings.HasSuffix(name, ".md"):
+				addResearch(timestamp, path, info)
+			case isImage && strings.HasPrefix(stem, "image"):
+				addImage(timestamp, path, info)
+			case strings.HasPrefix(name, "run") && strings.HasSuffix(name, ".log"):
+				addLog(timestamp, path, info)
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	list := make([]*RunManifest, 0, len(runs)+len(reconstructed))
+	for _, run := range runs {
+		list = append(list, run)
+	}
+	for _, run := range reconstructed {
+		list = append(list, run)
+	}
+	slices.SortFunc(list, func(a, b *RunManifest) int {
+		if c := b.StartedAt.Compare(a.StartedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.Timestamp, a.Timestamp)
+	})
+	return list, nil
+}
+
+// readDirIfExists returns the entries of dir, or none if it does not exist.
+func readDirIfExists(dir string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+	return entries, nil
+}
+
+// walkRunFiles calls fn for every regular file directly in dir.
+func walkRunFiles(dir string, fn func(name, path string, info os.FileInfo)) error {
+	entries, err := readDirIfExists(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		fn(entry.Name(), filepath.Join(dir, entry.Name()), info)
+	}
+	return nil
+}
+
+// imageStem returns the name of a saved image file without its extension.
+//
+// Originals kept next to converted images are not reported.
+func imageStem(name string) (string, bool) {
+	ext := filepath.Ext(name)
+	if !slices.Contains(runImageExtensions, ext) {
+		return "", false
+	}
+	stem := strings.TrimSuffix(name, ext)
+	if strings.HasSuffix(stem, "_original") {
+		return "", false
+	}
+	return stem, true
+}
+
+// filterRuns returns the runs started at or after since (when set) with every tag of tags, up to
+// limit runs (when positive).
+func filterRuns(runs []*RunManifest, since time.Time, tags []string, limit int) []*RunManifest {
+	var filtered []*RunManifest
+	for _, run := range runs {
+		if !since.IsZero() && run.StartedAt.Before(since) {
+			continue
+		}
+		if !hasTags(run.Tags, tags) {
+			continue
+		}
+		filtered = append(filtered, run)
+		if limit > 0 && len(filtered) == limit {
+			break
+		}
+	}
+	return filtered
+}
+
+// ParseSince parses the --since value, a date (2006-01-02, local time) or an RFC 3339 time.
+func ParseSince(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use YYYY-MM-DD or RFC 3339", s)
+}
+
+// printRuns writes the runs as a table.
+func printRuns(w io.Writer, runs []*RunManifest) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIMESTAMP\tSTATUS\tRESEARCH\tIMAGE\tTAGS\tPROMPT\tOUTPUT")
+	for _, run := range runs {
+		research, image := "-", "-"
+		var output string
+		if run.Research != nil {
+			research = run.Research.Status
+			output = run.Research.MarkdownPath
+		}
+		if run.Image != nil {
+			image = run.Image.Status
+			if len(run.Image.ImagePaths) > 0 {
+				output = run.Image.ImagePaths[0]
+			}
+		}
+		prompt := run.Prompt.Excerpt
+		if runes := []rune(prompt); len(runes) > runsPromptLength {
+			prompt = string(runes[:runsPromptLength]) + "..."
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", run.Timestamp, run.Status, research, image, orDash(strings.Join(run.Tags, ",")), orDash(prompt), orDash(output))
+	}
+	tw.Flush()
+}
+
+// orDash returns s, or "-" when it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// ResolveRun returns the run a reference designates among runs listed newest first.
+//
+// The reference is "latest", a full timestamp or name, or a unique prefix of one; an ambiguous
+// prefix is an error listing the matching timestamps.
+func ResolveRun(runs []*RunManifest, ref string) (*RunManifest, error) {
+	if ref == "latest" {
+		if len(runs) == 0 {
+			return nil, fmt.Errorf("no runs found")
+		}
+		return runs[0], nil
+	}
+
+	var matches []*RunManifest
+	for _, run := range runs {
+		if run.Timestamp == ref || run.Name == ref {
+			return run, nil
+		}
+		if strings.HasPrefix(run.Timestamp, ref) || (run.Name != "" && strings.HasPrefix(run.Name, ref)) {
+			matches = append(matches, run)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no run matches %s", ref)
+	case 1:
+		return matches[0], nil
+	}
+	candidates := make([]string, len(matches))
+	for i, run := range matches {
+		candidates[i] = run.Timestamp
+	}
+	return nil, fmt.Errorf("%s is ambiguous, candidates: %s", ref, strings.Join(candidates, ", "))
+}
+
+// saveRunManifest saves the manifest of a run listed by ListRuns, after linking a new artifact.
+//
+// Runs reconstructed from their files have no manifest and are left as is.
+func saveRunManifest(config *ViperConfig, run *RunManifest) error {
+	if run.SchemaVersion == 0 {
+		return nil
+	}
+	return run.Save(runManifestPath(config, run))
+}
+
+// runManifestPath returns the manifest path of a run in the current layout.
+func runManifestPath(config *ViperConfig, run *RunManifest) string {
+	name := run.Timestamp
+	if run.Name != "" {
+		name = run.Name
+	}
+	return config.ManifestPath(name)
+}
+
+// printRun writes the details of a run.
+func printRun(w io.Writer, run *RunManifest) {
+	style := NewStyler(w)
+	fmt.Fprintf(w, "Timestamp: %s\n", run.Timestamp)
+	if run.Name != "" {
+		fmt.Fprintf(w, "Name: %s\n", run.Name)
+	}
+	fmt.Fprintf(w, "Status: %s\n", run.Status)
+	if run.FailedStage != "" {
+		fmt.Fprintf(w, "Failed stage: %s\n", style.Error(run.FailedStage))
+	}
+	if run.Error != "" {
+		fmt.Fprintf(w, "Error: %s\n", style.Error(run.Error))
+	}
+	if !run.StartedAt.IsZero() {
+		fmt.Fprintf(w, "Started: %s\n", run.StartedAt.Local().Format(time.DateTime))
+	}
+	if run.FinishedAt != nil {
+		fmt.Fprintf(w, "Finished: %s\n", run.FinishedAt.Local().Format(time.DateTime))
+	}
+	if run.Prompt.Excerpt != "" {
+		fmt.Fprintf(w, "Prompt: %s\n", run.Prompt.Excerpt)
+	}
+	if run.Prompt.File != "" {
+		fmt.Fprintf(w, "Prompt file: %s\n", run.Prompt.File)
+	}
+	if len(run.Tags) > 0 {
+		fmt.Fprintf(w, "Tags: %s\n", strings.Join(run.Tags, ", "))
+	}
+	if research := run.Research; research != nil {
+		fmt.Fprintf(w, "Research: %s\n", research.Status)
+		if research.InteractionID != "" {
+			fmt.Fprintf(w, "  Interaction ID: %s\n", research.InteractionID)
+		}
+		if research.Agent != "" {
+			fmt.Fprintf(w, "  Agent: %s\n", research.Agent)
+		}
+		if research.MarkdownPath != "" {
+			fmt.Fprintf(w, "  Markdown: %s\n", research.MarkdownPath)
+		}
+		if research.SourcesPath != "" {
+			fmt.Fprintf(w, "  Sources: %s\n", research.SourcesPath)
+		}
+		if research.PDFPath != "" {
+			fmt.Fprintf(w, "  PDF: %s\n", research.PDFPath)
+		}
+	}
+	if image := run.Image; image != nil {
+		fmt.Fprintf(w, "Image: %s\n", image.Status)
+		if image.Model != "" {
+			fmt.Fprintf(w, "  Model: %s\n", image.Model)
+		}
+		for _, path := range image.ImagePaths {
+			fmt.Fprintf(w, "  Path: %s\n", path)
+		}
+	}
+	if run.ReportPath != "" {
+		fmt.Fprintf(w, "Report: %s\n", run.ReportPath)
+	}
+	if run.LogPath != "" {
+		fmt.Fprintf(w, "Log: %s\n", run.LogPath)
+	}
+}
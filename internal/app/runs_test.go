@@ -0,0 +1,223 @@
+package app
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeRunFixtures populates an output directory with runs in both layouts.
+func writeRunFixtures(t *testing.T, outputDir string) {
+	t.Helper()
+	byType := &ViperConfig{OutputDir: outputDir, Layout: LayoutByType}
+	perRun := &ViperConfig{OutputDir: outputDir, Layout: LayoutPerRun}
+
+	// A by-type run with a manifest and one reconstructed from its files
+	manifest := newRunManifest("20251224_103045", "AI trends", "", byType.LogPath("20251224_103045"), nil, time.Date(2025, 12, 24, 10, 30, 45, 0, time.Local))
+	manifest.Research = &ManifestResearch{Status: RunStatusCompleted, MarkdownPath: byType.ResearchPath("20251224_103045")}
+	manifest.finish(nil, manifest.StartedAt.Add(time.Minute))
+	if err := manifest.Save(byType.ManifestPath("20251224_103045")); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{
+		byType.ResearchPath("20251224_103045"),
+		byType.ResearchPath("20251120_080000"),
+		byType.SourcesPath("20251120_080000"),
+		byType.ImagePath("20251120_080000", ".png"),
+		byType.ImagePath("20251120_080000_2", ".png"),
+		byType.ImagePath("20251120_080000_original", ".png"),
+		byType.LogPath("20251120_080000"),
+		// A run that failed before saving anything but its log
+		byType.LogPath("20251001_120000"),
+		// A per-run run without a manifest
+		perRun.ResearchPath("20251225_090000"),
+		perRun.ImagePath("20251225_090000", ".jpg"),
+		perRun.LogPath("20251225_090000"),
+	}
+	for _, path := range files {
+		if err := WriteFile(path, []byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A per-run run with a manifest
+	failed := newRunManifest("20251226_070000", "Broken run", "", perRun.LogPath("20251226_070000"), nil, time.Date(2025, 12, 26, 7, 0, 0, 0, time.Local))
+	failed.Image = &ManifestImage{Status: RunStatusRunning}
+	failed.finish(&StageError{Stage: StageImage, Err: ErrNoImageData}, failed.StartedAt)
+	if err := failed.Save(perRun.ManifestPath("20251226_070000")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt and partially written manifests are skipped
+	if err := WriteFile(byType.ManifestPath("20251227_000000"), []byte(`{"schema_version": 1, "timest`)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListRuns(t *testing.T) {
+	outputDir := t.TempDir()
+	writeRunFixtures(t, outputDir)
+
+	var warned []string
+	runs, err := ListRuns(&ViperConfig{OutputDir: outputDir}, func(path string, err error) {
+		warned = append(warned, filepath.Base(path))
+	})
+	if err != nil {
+		t.Fatalf("ListRuns() error = %v", err)
+	}
+	if len(warned) != 1 || warned[0] != "20251227_000000.json" {
+		t.Errorf("warned = %v, want the corrupt manifest", warned)
+	}
+
+	var timestamps []string
+	for _, run := range runs {
+		timestamps = append(timestamps, run.Timestamp)
+	}
+	want := []string{"20251226_070000", "20251225_090000", "20251224_103045", "20251120_080000", "20251001_120000"}
+	if strings.Join(timestamps, ",") != strings.Join(want, ",") {
+		t.Fatalf("timestamps = %v, want %v", timestamps, want)
+	}
+
+	if failed := runs[0]; failed.Status != RunStatusFailed || failed.Image.Status != RunStatusFailed {
+		t.Errorf("run = %+v, want the failed manifest", failed)
+	}
+	if perRun := runs[1]; perRun.Status != RunStatusUnknown || perRun.Research == nil || perRun.Image == nil || perRun.LogPath == "" {
+		t.Errorf("run = %+v, want a reconstructed per-run run with research, image and log", perRun)
+	}
+	if manifest := runs[2]; manifest.Status != RunStatusCompleted || manifest.Prompt.Excerpt != "AI trends" || manifest.Image != nil {
+		t.Errorf("run = %+v, want the manifest only", manifest)
+	}
+	if byType := runs[3]; byType.Research == nil || byType.Image == nil || len(byType.Image.ImagePaths) != 2 {
+		t.Errorf("run = %+v, want research and two images (no original)", byType)
+	}
+	if logOnly := runs[4]; logOnly.Research != nil || logOnly.Image != nil || logOnly.LogPath == "" {
+		t.Errorf("run = %+v, want only the log", logOnly)
+	}
+}
+
+func TestListRuns_Empty(t *testing.T) {
+	runs, err := ListRuns(&ViperConfig{OutputDir: filepath.Join(t.TempDir(), "missing")}, func(string, error) {
+		t.Error("nothing should be reported")
+	})
+	if err != nil || len(runs) != 0 {
+		t.Errorf("ListRuns() = %v, %v, want no runs", runs, err)
+	}
+}
+
+func TestFilterRuns(t *testing.T) {
+	day := func(d int) *RunManifest {
+		return &RunManifest{Timestamp: time.Date(2025, 1, d, 0, 0, 0, 0, time.Local).Format(timestampLayout), StartedAt: time.Date(2025, 1, d, 0, 0, 0, 0, time.Local)}
+	}
+	runs := []*RunManifest{day(5), day(4), day(3), day(2), day(1)}
+	runs[1].Tags = []string{"project-x", "weekly"}
+	runs[3].Tags = []string{"project-x"}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		tags  []string
+		limit int
+		want  int
+	}{
+		{name: "all", want: 5},
+		{name: "limit", limit: 2, want: 2},
+		{name: "since", since: time.Date(2025, 1, 3, 0, 0, 0, 0, time.Local), want: 3},
+		{name: "since and limit", since: time.Date(2025, 1, 3, 0, 0, 0, 0, time.Local), limit: 1, want: 1},
+		{name: "tag", tags: []string{"project-x"}, want: 2},
+		{name: "every tag", tags: []string{"project-x", "weekly"}, want: 1},
+		{name: "tag and limit", tags: []string{"project-x"}, limit: 1, want: 1},
+		{name: "unknown tag", tags: []string{"other"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterRuns(runs, tt.since, tt.tags, tt.limit); len(got) != tt.want {
+				t.Errorf("filterRuns() returned %d runs, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	if got, err := ParseSince("2024-01-01"); err != nil || !got.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("ParseSince(date) = %v, %v", got, err)
+	}
+	if got, err := ParseSince("2024-01-01T09:00:00Z"); err != nil || !got.Equal(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseSince(RFC 3339) = %v, %v", got, err)
+	}
+	if _, err := ParseSince("last week"); err == nil {
+		t.Error("ParseSince() should reject other formats")
+	}
+}
+
+func TestPrintRuns(t *testing.T) {
+	runs := []*RunManifest{{
+		Timestamp: "20251224_103045",
+		Status:    RunStatusCompleted,
+		Prompt:    ManifestPrompt{Excerpt: strings.Repeat("a", 50)},
+		Tags:      []string{"project-x", "weekly"},
+		Image:     &ManifestImage{Status: RunStatusCompleted, ImagePaths: []string{"/out/images/20251224_103045.png"}},
+	}}
+
+	var buf bytes.Buffer
+	printRuns(&buf, runs)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("output = %q, want a header and a row", buf.String())
+	}
+	for _, want := range []string{"20251224_103045", "project-x,weekly", strings.Repeat("a", runsPromptLength) + "...", "/out/images/20251224_103045.png"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("row %q should contain %q", lines[1], want)
+		}
+	}
+	if fields := strings.Fields(lines[1]); fields[2] != "-" {
+		t.Errorf("row %q should show the research that did not run as -", lines[1])
+	}
+}
+
+func TestResolveRun(t *testing.T) {
+	runs := []*RunManifest{
+		{Timestamp: "20251225_090000"},
+		{Timestamp: "20251224_103045", Name: "20251224_103045_ai-trends"},
+		{Timestamp: "20251224_090000", Name: "solar-power"},
+	}
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{name: "latest", ref: "latest", want: "20251225_090000"},
+		{name: "full timestamp", ref: "20251224_103045", want: "20251224_103045"},
+		{name: "unique prefix", ref: "20251224_10", want: "20251224_103045"},
+		{name: "ambiguous prefix", ref: "20251224", wantErr: "candidates: 20251224_103045, 20251224_090000"},
+		{name: "full name", ref: "20251224_103045_ai-trends", want: "20251224_103045"},
+		{name: "name prefix", ref: "solar", want: "20251224_090000"},
+		{name: "no match", ref: "2024", wantErr: "no run matches"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run, err := ResolveRun(runs, tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResolveRun() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveRun() error = %v", err)
+			}
+			if run.Timestamp != tt.want {
+				t.Errorf("ResolveRun() = %s, want %s", run.Timestamp, tt.want)
+			}
+		})
+	}
+
+	if _, err := ResolveRun(nil, "latest"); err == nil {
+		t.Error("ResolveRun(latest) should fail without runs")
+	}
+}
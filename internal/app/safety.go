@@ -0,0 +1,87 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SafetySetting is a safety threshold for a harm category of image requests.
+type SafetySetting struct {
+	Category  string `mapstructure:"category" json:"category"`
+	Threshold string `mapstructure:"threshold" json:"threshold"`
+}
+
+func (s SafetySetting) String() string {
+	return s.Category + "=" + s.Threshold
+}
+
+// safetyCategories are the harm categories accepted in safety settings.
+//
+// --safety-threshold applies to all of them except HARM_CATEGORY_CIVIC_INTEGRITY, which is
+// deprecated by the API and only set with safety_settings.
+var safetyCategories = []string{
+	"HARM_CATEGORY_HARASSMENT",
+	"HARM_CATEGORY_HATE_SPEECH",
+	"HARM_CATEGORY_SEXUALLY_EXPLICIT",
+	"HARM_CATEGORY_DANGEROUS_CONTENT",
+	"HARM_CATEGORY_CIVIC_INTEGRITY",
+}
+
+// safetyThresholds are the block thresholds accepted in safety settings.
+var safetyThresholds = []string{
+	"BLOCK_LOW_AND_ABOVE",
+	"BLOCK_MEDIUM_AND_ABOVE",
+	"BLOCK_ONLY_HIGH",
+	"BLOCK_NONE",
+	"OFF",
+}
+
+// ValidateSafetySettings normalizes category and threshold names to upper case and
+// rejects unknown names.
+func ValidateSafetySettings(settings []SafetySetting) error {
+	seen := make(map[string]bool, len(settings))
+	for i := range settings {
+		setting := &settings[i]
+		setting.Category = strings.ToUpper(strings.TrimSpace(setting.Category))
+		setting.Threshold = strings.ToUpper(strings.TrimSpace(setting.Threshold))
+
+		if !slices.Contains(safetyCategories, setting.Category) {
+			return fmt.Errorf("invalid safety category %q (valid: %s)", setting.Category, strings.Join(safetyCategories, ", "))
+		}
+		if err := validateSafetyThreshold(setting.Threshold); err != nil {
+			return err
+		}
+		if seen[setting.Category] {
+			return fmt.Errorf("duplicate safety category %q", setting.Category)
+		}
+		seen[setting.Category] = true
+	}
+	return nil
+}
+
+// validateSafetyThreshold rejects unknown threshold names.
+func validateSafetyThreshold(threshold string) error {
+	if !slices.Contains(safetyThresholds, threshold) {
+		return fmt.Errorf("invalid safety threshold %q (valid: %s)", threshold, strings.Join(safetyThresholds, ", "))
+	}
+	return nil
+}
+
+// SafetySettingsForThreshold returns safety settings applying one threshold to every harm category
+// but HARM_CATEGORY_CIVIC_INTEGRITY.
+func SafetySettingsForThreshold(threshold string) ([]SafetySetting, error) {
+	threshold = strings.ToUpper(strings.TrimSpace(threshold))
+	if err := validateSafetyThreshold(threshold); err != nil {
+		return nil, err
+	}
+
+	var settings []SafetySetting
+	for _, category := range safetyCategories {
+		if category == "HARM_CATEGORY_CIVIC_INTEGRITY" {
+			continue
+		}
+		settings = append(settings, SafetySetting{Category: category, Threshold: threshold})
+	}
+	return settings, nil
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidateSafetySettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []SafetySetting
+		wantErr  string
+	}{
+		{name: "empty"},
+		{
+			name:     "valid",
+			settings: []SafetySetting{{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_NONE"}},
+		},
+		{
+			name:     "lower case is normalized",
+			settings: []SafetySetting{{Category: "harm_category_hate_speech", Threshold: "off"}},
+		},
+		{
+			name:     "unknown category",
+			settings: []SafetySetting{{Category: "HARM_CATEGORY_VIOLENCE", Threshold: "BLOCK_NONE"}},
+			wantErr:  "invalid safety category",
+		},
+		{
+			name:     "unknown threshold",
+			settings: []SafetySetting{{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_SOME"}},
+			wantErr:  "invalid safety threshold",
+		},
+		{
+			name: "duplicate category",
+			settings: []SafetySetting{
+				{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_NONE"},
+				{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "OFF"},
+			},
+			wantErr: "duplicate safety category",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSafetySettings(tt.settings)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateSafetySettings() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateSafetySettings() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSafetySettingsForThreshold(t *testing.T) {
+	settings, err := SafetySettingsForThreshold("block_only_high")
+	if err != nil {
+		t.Fatalf("SafetySettingsForThreshold() error = %v", err)
+	}
+	if len(settings) != 4 {
+		t.Fatalf("settings = %v, want 4 categories", settings)
+	}
+	for _, setting := range settings {
+		if setting.Threshold != "BLOCK_ONLY_HIGH" {
+			t.Errorf("Threshold = %s, want BLOCK_ONLY_HIGH", setting.Threshold)
+		}
+		if setting.Category == "HARM_CATEGORY_CIVIC_INTEGRITY" {
+			t.Errorf("settings = %v, want the deprecated civic integrity category left out", settings)
+		}
+	}
+
+	if _, err := SafetySettingsForThreshold("BLOCK_EVERYTHING"); err == nil {
+		t.Error("SafetySettingsForThreshold() should return error for an unknown threshold")
+	}
+}
+
+func TestGenaiImageClient_Generate_SafetySettings(t *testing.T) {
+	settings := []SafetySetting{{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_ONLY_HIGH"}}
+
+	t.Run("sent when configured", func(t *testing.T) {
+		var request struct {
+			SafetySettings []SafetySetting `json:"safetySettings"`
+		}
+		var raw string
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			raw = string(body)
+			json.Unmarshal(body, &request)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, imageResponseJSON(testImageData))
+		})
+		client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir(), SafetySettings: settings})
+
+		if _, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045"); err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if len(request.SafetySettings) != 1 || request.SafetySettings[0] != settings[0] {
+			t.Errorf("safetySettings = %v, want %v (body: %s)", request.SafetySettings, settings, raw)
+		}
+	})
+
+	t.Run("omitted by default", func(t *testing.T) {
+		var raw string
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			raw = string(body)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, imageResponseJSON(testImageData))
+		})
+		client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir()})
+
+		if _, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045"); err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if strings.Contains(raw, "safetySettings") {
+			t.Errorf("request body should not contain safetySettings: %s", raw)
+		}
+	})
+
+	t.Run("blocked error lists the settings", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"promptFeedback":{"blockReason":"SAFETY"}}`)
+		})
+		client := newTestImageClient(t, handler, &ViperConfig{OutputDir: t.TempDir(), SafetySettings: settings})
+
+		_, err := client.Generate(context.Background(), "prompt", ImageConfig{Model: "test-model"}, "20251224_103045")
+		if !errors.Is(err, ErrContentBlocked) {
+			t.Fatalf("Generate() error = %v, want ErrContentBlocked", err)
+		}
+		if !strings.Contains(err.Error(), "HARM_CATEGORY_DANGEROUS_CONTENT=BLOCK_ONLY_HIGH") {
+			t.Errorf("error should list the safety settings in effect: %v", err)
+		}
+	})
+}
@@ -0,0 +1,405 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRunRequestSize is the maximum size of a POST /runs body.
+	maxRunRequestSize = 1 << 20
+	// maxQueuedRuns is the number of runs waiting for a slot beyond which POST /runs is refused.
+	maxQueuedRuns = 32
+	// maxServeImageCount is the maximum number of image variants of a run requested over HTTP.
+	maxServeImageCount = 8
+	// maxFinishedRuns is the number of finished runs kept in memory; older ones are forgotten.
+	maxFinishedRuns = 256
+)
+
+// RunStatusQueued is the status of a run waiting for a free slot of `deepviz serve`.
+const RunStatusQueued = "queued"
+
+// RunRequest is the body of POST /runs.
+//
+// Empty fields use the configuration.
+type RunRequest struct {
+	Prompt       string   `json:"prompt"`
+	Name         string   `json:"name,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	ResearchOnly bool     `json:"research_only,omitempty"`
+	ImageOnly    bool     `json:"image_only,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	AspectRatio  string   `json:"aspect_ratio,omitempty"`
+	ImageSize    string   `json:"image_size,omitempty"`
+	ImageLang    string   `json:"image_lang,omitempty"`
+	Style        string   `json:"style,omitempty"`
+	Count        int      `json:"count,omitempty"`
+	Report       string   `json:"report,omitempty"`
+}
+
+// RunStatus is the response of GET /runs/{id}.
+type RunStatus struct {
+	ID         string       `json:"id"`
+	Status     string       `json:"status"`
+	Error      string       `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	Manifest   *RunManifest `json:"manifest,omitempty"` // Artifacts of the run, once it has finished
+}
+
+// APIServer runs pipelines requested over HTTP (`deepviz serve`).
+//
+// Runs are executed in the background, at most concurrency at a time; their artifacts are written
+// to the output directory as usual. The last maxFinishedRuns finished runs are kept: older ones
+// are no longer known to the API (GET /runs/{id} returns 404), but their artifacts stay.
+type APIServer struct {
+	config *ViperConfig
+	token  string
+	slots  chan struct{}
+
+	// execute runs a pipeline (ExecutePipeline, the runner of the command or a fake in tests)
+	execute func(ctx context.Context, opts *Options, config *ViperConfig) (*RunResult, error)
+	// logf reports run events
+	logf func(format string, args ...any)
+
+	ctx    context.Context // Cancelled to interrupt the running pipelines
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu          sync.Mutex
+	runs        map[string]*apiRun
+	queued      int
+	finished    []string // IDs of the finished runs, oldest first
+	maxFinished int      // maxFinishedRuns, replaced in tests
+}
+
+// apiRun is a run requested over HTTP.
+type apiRun struct {
+	status RunStatus
+	result *RunResult
+}
+
+// NewAPIServer creates an API server running at most concurrency pipelines at a time.
+//
+// Requests must carry the token as a bearer token unless it is empty.
+func NewAPIServer(config *ViperConfig, token string, concurrency int, logf func(format string, args ...any)) *APIServer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &APIServer{
+		config:  config,
+		token:   token,
+		slots:   make(chan struct{}, concurrency),
+		execute: ExecutePipeline,
+		logf:    logf,
+		ctx:     ctx,
+		cancel:  cancel,
+		runs:    make(map[string]*apiRun),
+
+		maxFinished: maxFinishedRuns,
+	}
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *APIServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", s.handleCreateRun)
+	mux.HandleFunc("GET /runs/{id}", s.handleGetRun)
+	mux.HandleFunc("GET /runs/{id}/image", s.handleGetImage)
+	return s.authenticate(mux)
+}
+
+// Close waits for the running pipelines until ctx is done, then interrupts the remaining ones
+// (their research can be resumed with `deepviz resume`) and waits for them to stop.
+func (s *APIServer) Close(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	s.cancel()
+	<-done
+}
+
+// authenticate rejects requests without the bearer token.
+func (s *APIServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeJSONError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleCreateRun starts a run (POST /runs).
+func (s *APIServer) handleCreateRun(w http.ResponseWriter, r *http.Request) {
+	var req RunRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRunRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	opts, config, err := s.runOptions(&req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	if s.queued >= maxQueuedRuns {
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", "60")
+		writeJSONError(w, http.StatusTooManyRequests, "too many runs waiting, retry later")
+		return
+	}
+	s.queued++
+	run := &apiRun{status: RunStatus{ID: opts.Timestamp, Status: RunStatusQueued, CreatedAt: time.Now()}}
+	s.runs[run.status.ID] = run
+	status := run.status
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go s.run(run, opts, config)
+	s.logf("Run %s queued", status.ID)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// runOptions validates a run request and returns the options and configuration of the run.
+func (s *APIServer) runOptions(req *RunRequest) (*Options, *ViperConfig, error) {
+	if strings.TrimSpace(req.Prompt) == "" {
+		return nil, nil, fmt.Errorf("prompt is required")
+	}
+	if req.ResearchOnly && req.ImageOnly {
+		return nil, nil, fmt.Errorf("research_only and image_only cannot be combined")
+	}
+	if req.Name != "" {
+		if err := ValidateRunName(req.Name); err != nil {
+			return nil, nil, err
+		}
+	}
+	tags, err := ParseTags(req.Tags)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req.Count < 0 || req.Count > maxServeImageCount {
+		return nil, nil, fmt.Errorf("count must be between 1 and %d", maxServeImageCount)
+	}
+	if req.Report != "" && req.Report != ReportFormatHTML {
+		return nil, nil, fmt.Errorf("invalid report format %q: must be %s", req.Report, ReportFormatHTML)
+	}
+
+	// Every run has its own copy of the configuration, which the pipeline adjusts
+	config := *s.config
+	if req.Model != "" {
+		config.Model = req.Model
+	}
+	if req.AspectRatio != "" {
+		config.AspectRatio = req.AspectRatio
+	}
+	if req.ImageSize != "" {
+		config.ImageSize = req.ImageSize
+	}
+	if req.ImageLang != "" {
+		config.ImageLang = req.ImageLang
+	}
+	if req.Style != "" {
+		if _, err := ResolveStyle(req.Style, config.Styles); err != nil {
+			return nil, nil, err
+		}
+		config.Style = req.Style
+	}
+	if req.Count > 0 {
+		config.ImageCount = req.Count
+	}
+
+	opts := &Options{
+		Prompt:       req.Prompt,
+		Timestamp:    config.Namer().GenerateTimestamp(),
+		Name:         req.Name,
+		Tags:         tags,
+		Count:        config.ImageCount,
+		Candidates:   1,
+		ResearchOnly: req.ResearchOnly,
+		ImageOnly:    req.ImageOnly,
+		Model:        config.Model,
+		AspectRatio:  config.AspectRatio,
+		ImageSize:    config.ImageSize,
+		Output:       config.OutputDir,
+		NoOpen:       true,
+		QuietPoll:    true,
+		Report:       req.Report,
+	}
+	return opts, &config, nil
+}
+
+// run executes a queued run once a slot is free.
+func (s *APIServer) run(run *apiRun, opts *Options, config *ViperConfig) {
+	defer s.wg.Done()
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-s.ctx.Done():
+		s.finish(run, nil, fmt.Errorf("server stopped before the run started"))
+		return
+	}
+	defer func() { <-s.slots }()
+	// A panic of the pipeline fails the run instead of stopping the server
+	defer func() {
+		if r := recover(); r != nil {
+			s.logf("Run %s panicked: %v\n%s", run.status.ID, r, debug.Stack())
+			s.finish(run, nil, fmt.Errorf("internal error: %v", r))
+		}
+	}()
+
+	s.mu.Lock()
+	s.queued--
+	now := time.Now()
+	run.status.Status = RunStatusRunning
+	run.status.StartedAt = &now
+	s.mu.Unlock()
+	s.logf("Run %s started", run.status.ID)
+
+	result, err := s.execute(s.ctx, opts, config)
+	s.finish(run, result, err)
+}
+
+// finish records the outcome of a run.
+func (s *APIServer) finish(run *apiRun, result *RunResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run.status.Status == RunStatusQueued {
+		s.queued--
+	}
+	now := time.Now()
+	run.status.FinishedAt = &now
+	run.result = result
+	if result != nil {
+		run.status.Manifest = result.Manifest
+	}
+	if err != nil {
+		run.status.Status = stageStatus(err)
+		run.status.Error = err.Error()
+	} else {
+		run.status.Status = RunStatusCompleted
+	}
+	s.logf("Run %s %s", run.status.ID, run.status.Status)
+
+	s.finished = append(s.finished, run.status.ID)
+	for len(s.finished) > s.maxFinished {
+		delete(s.runs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+// lookup returns a copy of the status of a run and its result.
+func (s *APIServer) lookup(id string) (RunStatus, *RunResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return RunStatus{}, nil, false
+	}
+	return run.status, run.result, true
+}
+
+// handleGetRun returns the status of a run (GET /runs/{id}).
+func (s *APIServer) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	status, _, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "run not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleGetImage streams the first image of a run (GET /runs/{id}/image).
+func (s *APIServer) handleGetImage(w http.ResponseWriter, r *http.Request) {
+	status, result, ok := s.lookup(r.PathValue("id"))
+	switch {
+	case !ok:
+		writeJSONError(w, http.StatusNotFound, "run not found")
+	case status.FinishedAt == nil:
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("run is %s", status.Status))
+	case result == nil || result.Image == nil || len(result.Image.ImagePaths) == 0:
+		writeJSONError(w, http.StatusNotFound, "run has no image")
+	default:
+		http.ServeFile(w, r, result.Image.ImagePaths[0])
+	}
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// writeJSONError writes an error response ({"error": message}).
+func writeJSONError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
+
+// serveAPI serves the API on addr until ctx is cancelled, then shuts down gracefully: new
+// requests are refused and running pipelines are given gracePeriod to finish.
+func serveAPI(ctx context.Context, server *APIServer, addr string, gracePeriod time.Duration, ready func(addr string)) error {
+	httpServer := &http.Server{Addr: addr, Handler: server.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return &UsageError{Err: fmt.Errorf("failed to listen on %s: %w", addr, err)}
+	}
+	ready(listener.Addr().String())
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		server.Close(context.Background())
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	server.Close(shutdownCtx)
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// newLogf returns a logf writing timestamped lines to w.
+func newLogf(w io.Writer) func(format string, args ...any) {
+	var mu sync.Mutex
+	return func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, time.Now().Format(time.DateTime)+" "+format+"\n", args...)
+	}
+}
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestAPIServer returns an API server whose pipelines are run by execute.
+func newTestAPIServer(t *testing.T, token string, execute func(ctx context.Context, opts *Options, config *ViperConfig) (*RunResult, error)) (*APIServer, *httptest.Server) {
+	t.Helper()
+	config := &ViperConfig{OutputDir: t.TempDir(), ImageCount: 1, Model: "image-model"}
+	server := NewAPIServer(config, token, 1, func(string, ...any) {})
+	server.execute = execute
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Close(context.Background())
+	})
+	return server, httpServer
+}
+
+// apiRequest sends a request to the API and decodes the JSON response into v.
+func apiRequest(t *testing.T, method, url, token, body string, v any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// waitForRun polls a run until it has finished.
+func waitForRun(t *testing.T, url, token, id string) RunStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var status RunStatus
+		if code := apiRequest(t, http.MethodGet, url+"/runs/"+id, token, "", &status); code != http.StatusOK {
+			t.Fatalf("GET /runs/%s = %d, want 200", id, code)
+		}
+		if status.FinishedAt != nil {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("run %s did not finish", id)
+	return RunStatus{}
+}
+
+func TestAPIServer_Run(t *testing.T) {
+	var got *Options
+	var gotConfig *ViperConfig
+	_, httpServer := newTestAPIServer(t, "secret", func(ctx context.Context, opts *Options, config *ViperConfig) (*RunResult, error) {
+		got, gotConfig = opts, config
+		imagePath := filepath.Join(config.OutputDir, "images", opts.Timestamp+".png")
+		if err := WriteFile(imagePath, pngHeader); err != nil {
+			return nil, err
+		}
+		manifest := newRunManifest(opts.Timestamp, opts.Prompt, opts.Name, "", opts.Tags, time.Now())
+		manifest.Image = &ManifestImage{Status: RunStatusCompleted, ImagePaths: []string{imagePath}}
+		return &RunResult{Timestamp: opts.Timestamp, Manifest: manifest, Image: &ImageResult{ImagePaths: []string{imagePath}}}, nil
+	})
+
+	var created RunStatus
+	body := `{"prompt": "AI trends", "tags": ["weekly"], "aspect_ratio": "1:1", "count": 2}`
+	if code := apiRequest(t, http.MethodPost, httpServer.URL+"/runs", "secret", body, &created); code != http.StatusAccepted {
+		t.Fatalf("POST /runs = %d, want 202", code)
+	}
+	if created.ID == "" || created.Status != RunStatusQueued {
+		t.Errorf("created = %+v, want a queued run with an ID", created)
+	}
+
+	status := waitForRun(t, httpServer.URL, "secret", created.ID)
+	if status.Status != RunStatusCompleted || status.Manifest == nil || status.StartedAt == nil {
+		t.Errorf("status = %+v, want a completed run with its manifest", status)
+	}
+	if got.Prompt != "AI trends" || got.Timestamp != created.ID || !got.NoOpen || got.Count != 2 || len(got.Tags) != 1 || got.Tags[0] != "weekly" {
+		t.Errorf("opts = %+v, want the request options", got)
+	}
+	if gotConfig.AspectRatio != "1:1" || gotConfig.Model != "image-model" {
+		t.Errorf("config = %+v, want the request overrides on the configuration", gotConfig)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, httpServer.URL+"/runs/"+created.ID+"/image", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	image, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" || !bytes.Equal(image, pngHeader) {
+		t.Errorf("GET image = %d %s, want the PNG", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	if code := apiRequest(t, http.MethodGet, httpServer.URL+"/runs/unknown", "secret", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET /runs/unknown = %d, want 404", code)
+	}
+}
+
+func TestAPIServer_FailedRun(t *testing.T) {
+	_, httpServer := newTestAPIServer(t, "", func(ctx context.Context, opts *Options, config *ViperConfig) (*RunResult, error) {
+		return &RunResult{Timestamp: opts.Timestamp}, &StageError{Stage: "research", Err: errors.New("quota exceeded")}
+	})
+
+	var created RunStatus
+	if code := apiRequest(t, http.MethodPost, httpServer.URL+"/runs", "", `{"prompt": "AI trends"}`, &created); code != http.StatusAccepted {
+		t.Fatalf("POST /runs = %d, want 202", code)
+	}
+	status := waitForRun(t, httpServer.URL, "", created.ID)
+	if status.Status != RunStatusFailed || !strings.Contains(status.Error, "quota exceeded") {
+		t.Errorf("status = %+v, want a failed run with its error", status)
+	}
+	if code := apiRequest(t, http.MethodGet, httpServer.URL+"/runs/"+created.ID+"/image", "", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET image = %d, want 404", code)
+	}
+}
+
+func TestAPIServer_PanickedRun(t *testing.T) {
+	calls := 0
+	_, httpServer := newTestAPIServer(t, "", func(ctx context.Context, opts *Options, config *ViperConfig) (*RunResult, error) {
+		calls++
+		if calls == 1 {
+			panic("nil map")
+		}
+		return &RunResult{Timestamp: opts.Timestamp}, nil
+	})
+
+	var created RunStatus
+	apiRequest(t, http.MethodPost, httpServer.URL+"/runs", "", `{"prompt": "AI trends"}`, &created)
+	status := waitForRun(t, httpServer.URL, "", created.ID)
+	if status.Status != RunStatusFailed || !strings.Contains(status.Error, "nil map") {
+		t.Errorf("status = %+v, want a failed run with the panic", status)
+	}
+
+	// The slot of the run is released
+	apiRequest(t, http.MethodPost, httpServer.URL+"/runs", "", `{"prompt": "AI trends"}`, &created)
+	if status := waitForRun(t, httpServer.URL, "", created.ID); status.Status != RunStatusCompleted {
+		t.Errorf("status of the next run = %+v, want completed", status)
+	}
+}
+
+func TestAPIServer_EvictsFinishedRuns(t *testing.T) {
+	server, httpServer := newTestAPIServer(t, "", func(ctx context.Context, opts *Options, config *ViperConfig) (*RunResult, error) {
+		return &RunResult{Timestamp: opts.Timestamp}, nil
+	})
+	server.maxFinished = 2
+
+	var ids []string
+	for range 3 {
+		var created RunStatus
+		apiRequest(t, http.MethodPost, httpServer.URL+"/runs", "", `{"prompt": "AI trends"}`, &created)
+		waitForRun(t, httpServer.URL, "", created.ID)
+		ids = append(ids, created.ID)
+	}
+	if code := apiRequest(t, http.MethodGet, httpServer.URL+"/runs/"+ids[0], "", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET the oldest run = %d, want 404", code)
+	}
+	for _, id := range ids[1:] {
+		if code := apiRequest(t, http.MethodGet, httpServer.URL+"/runs/"+id, "", "", nil); code != http.StatusOK {
+			t.Errorf("GET run %s = %d, want 200", id, code)
+		}
+	}
+}
+
+func TestAPIServer_PendingRun(t *testing.T) {
+	release := make(chan struct{})
+	_, httpServer := newTestAPIServer(t, "", func(ctx context.Context, opts *Options, config *ViperConfig) (*RunResult, error) {
+		<-release
+		return &RunResult{Timestamp: opts.Timestamp}, nil
+	})
+	defer close(release)
+
+	var first, second RunStatus
+	apiRequest(t, http.MethodPost, httpServer.URL+"/runs", "", `{"prompt": "first"}`, &first)
+	apiRequest(t, http.MethodPost, httpServer.URL+"/runs", "", `{"prompt": "second"}`, &second)
+	if first.ID == second.ID {
+		t.Fatalf("run IDs = %s, %s, want unique IDs", first.ID, second.ID)
+	}
+	if code := apiRequest(t, http.MethodGet, httpServer.URL+"/runs/"+second.ID+"/image", "", "", nil); code != http.StatusConflict {
+		t.Errorf("GET image of a pending run = %d, want 409", code)
+	}
+}
+
+func TestAPIServer_Close(t *testing.T) {
+	server, httpServer := newTestAPIServer(t, "", func(ctx context.Context, opts *Options, config *ViperConfig) (*RunResult, error) {
+		<-ctx.Done()
+		return &RunResult{Timestamp: opts.Timestamp}, &InterruptedError{Err: ctx.Err()}
+	})
+
+	var created RunStatus
+	apiRequest(t, http.MethodPost, httpServer.URL+"/runs", "", `{"prompt": "AI trends"}`, &created)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	server.Close(ctx)
+
+	status, _, _ := server.lookup(created.ID)
+	if status.Status != RunStatusInterrupted {
+		t.Errorf("status = %s, want %s after the grace period", status.Status, RunStatusInterrupted)
+	}
+}
+
+func TestAPIServer_Auth(t *testing.T) {
+	_, httpServer := newTestAPIServer(t, "secret", nil)
+
+	for _, token := range []string{"", "wrong"} {
+		if code := apiRequest(t, http.MethodGet, httpServer.URL+"/runs/x", token, "", nil); code != http.StatusUnauthorized {
+			t.Errorf("GET with token %q = %d, want 401", token, code)
+		}
+	}
+}
+
+func TestAPIServer_Validation(t *testing.T) {
+	_, httpServer := newTestAPIServer(t, "", nil)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "invalid json", body: `{"prompt":`},
+		{name: "unknown field", body: `{"prompt": "x", "verbose": true}`},
+		{name: "missing prompt", body: `{"prompt": "  "}`},
+		{name: "conflicting stages", body: `{"prompt": "x", "research_only": true, "image_only": true}`},
+		{name: "invalid name", body: `{"prompt": "x", "name": "../x"}`},
+		{name: "invalid tag", body: `{"prompt": "x", "tags": ["a b"]}`},
+		{name: "unknown style", body: `{"prompt": "x", "style": "unknown"}`},
+		{name: "count out of range", body: `{"prompt": "x", "count": 100}`},
+		{name: "unknown report format", body: `{"prompt": "x", "report": "pdf"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp map[string]string
+			if code := apiRequest(t, http.MethodPost, httpServer.URL+"/runs", "", tt.body, &resp); code != http.StatusBadRequest {
+				t.Errorf("POST /runs = %d, want 400", code)
+			}
+			if resp["error"] == "" {
+				t.Errorf("response = %v, want an error message", resp)
+			}
+		})
+	}
+}
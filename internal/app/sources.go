@@ -0,0 +1,116 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yukiyan/deepviz/internal/genai/interactions"
+)
+
+// Source is a web source cited or consulted by a research.
+type Source struct {
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// researchSources collects the sources of a research from its interaction outputs.
+//
+// Sources come from text annotations and from Google Search and URL context results. Sources are
+// deduplicated by URL (or by title when there is none) in the order they first appear; a title
+// found later fills in a source listed without one. Outputs without source metadata are ignored.
+func researchSources(outputs []interactions.Content) []Source {
+	var sources []Source
+	index := make(map[string]int)
+	add := func(title, url string) {
+		title, url = strings.TrimSpace(title), strings.TrimSpace(url)
+		key := url
+		if key == "" {
+			key = title
+		}
+		if key == "" {
+			return
+		}
+		if i, ok := index[key]; ok {
+			if sources[i].Title == "" {
+				sources[i].Title = title
+			}
+			return
+		}
+		index[key] = len(sources)
+		sources = append(sources, Source{Title: title, URL: url})
+	}
+
+	for _, output := range outputs {
+		kind, err := output.Discriminator()
+		if err != nil {
+			continue
+		}
+		switch kind {
+		case "text":
+			text, err := output.AsTextContent()
+			if err != nil || text.Annotations == nil {
+				continue
+			}
+			// An annotation source is a URL, a title or another identifier
+			for _, annotation := range *text.Annotations {
+				if annotation.Source == nil {
+					continue
+				}
+				if source := *annotation.Source; isURL(source) {
+					add("", source)
+				} else {
+					add(source, "")
+				}
+			}
+		case "google_search_result":
+			search, err := output.AsGoogleSearchResultContent()
+			if err != nil || search.Result == nil {
+				continue
+			}
+			for _, result := range *search.Result {
+				add(deref(result.Title), deref(result.Url))
+			}
+		case "url_context_result":
+			urlContext, err := output.AsUrlContextResultContent()
+			if err != nil || urlContext.Result == nil {
+				continue
+			}
+			for _, result := range *urlContext.Result {
+				if result.Status != nil && *result.Status != interactions.UrlContextResultStatusSuccess {
+					continue
+				}
+				add("", deref(result.Url))
+			}
+		}
+	}
+	return sources
+}
+
+// isURL reports whether an annotation source is a web URL.
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
+
+// formatSources returns the sources as a numbered Markdown list of links.
+func formatSources(sources []Source) string {
+	var b strings.Builder
+	for i, source := range sources {
+		switch {
+		case source.URL == "":
+			fmt.Fprintf(&b, "%d. %s\n", i+1, source.Title)
+		case source.Title == "":
+			fmt.Fprintf(&b, "%d. <%s>\n", i+1, source.URL)
+		default:
+			fmt.Fprintf(&b, "%d. [%s](%s)\n", i+1, source.Title, source.URL)
+		}
+	}
+	return b.String()
+}
+
+// appendSources appends a "Sources" section listing the sources to research markdown.
+func appendSources(content string, sources []Source) string {
+	if len(sources) == 0 {
+		return content
+	}
+	return strings.TrimRight(content, "\n") + "\n\n## Sources\n\n" + formatSources(sources)
+}
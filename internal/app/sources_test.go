@@ -0,0 +1,132 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/yukiyan/deepviz/internal/genai/interactions"
+)
+
+// parseOutputs parses interaction outputs from JSON.
+func parseOutputs(t *testing.T, data string) []interactions.Content {
+	t.Helper()
+	var outputs []interactions.Content
+	if err := json.Unmarshal([]byte(data), &outputs); err != nil {
+		t.Fatal(err)
+	}
+	return outputs
+}
+
+func TestResearchSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs string
+		want    []Source
+	}{
+		{
+			name:    "no source metadata",
+			outputs: `[{"type":"thought","summary":[{"type":"text","text":"Planning"}]},{"type":"text","text":"report"}]`,
+		},
+		{
+			name: "annotations and tool results",
+			outputs: `[
+				{"type":"google_search_result","result":[{"title":"AI Index 2025","url":"https://example.com/ai-index"},{"title":"No URL"}]},
+				{"type":"url_context_result","result":[{"url":"https://example.com/report","status":"success"},{"url":"https://example.com/paywalled","status":"paywall"}]},
+				{"type":"text","text":"report","annotations":[{"source":"https://example.com/ai-index","start_index":0,"end_index":6},{"source":"https://example.com/new"},{"source":"Internal survey"}]}
+			]`,
+			want: []Source{
+				{Title: "AI Index 2025", URL: "https://example.com/ai-index"},
+				{Title: "No URL"},
+				{URL: "https://example.com/report"},
+				{URL: "https://example.com/new"},
+				{Title: "Internal survey"},
+			},
+		},
+		{
+			name: "title filled in from a later result",
+			outputs: `[
+				{"type":"text","text":"report","annotations":[{"source":"https://example.com/a"}]},
+				{"type":"google_search_result","result":[{"title":"Source A","url":"https://example.com/a"}]}
+			]`,
+			want: []Source{{Title: "Source A", URL: "https://example.com/a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := researchSources(parseOutputs(t, tt.outputs))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("researchSources() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSources(t *testing.T) {
+	got := formatSources([]Source{
+		{Title: "AI Index 2025", URL: "https://example.com/ai-index"},
+		{URL: "https://example.com/report"},
+		{Title: "Internal survey"},
+	})
+	want := "1. [AI Index 2025](https://example.com/ai-index)\n2. <https://example.com/report>\n3. Internal survey\n"
+	if got != want {
+		t.Errorf("formatSources() = %q, want %q", got, want)
+	}
+}
+
+func TestAppendSources(t *testing.T) {
+	sources := []Source{{Title: "A", URL: "https://example.com/a"}}
+
+	if got := appendSources("# Report\n", sources); got != "# Report\n\n## Sources\n\n1. [A](https://example.com/a)\n" {
+		t.Errorf("appendSources() = %q", got)
+	}
+	if got := appendSources("# Report\n", nil); got != "# Report\n" {
+		t.Errorf("appendSources() without sources = %q, want the content unchanged", got)
+	}
+}
+
+func TestGenaiResearchClient_SaveResult_Sources(t *testing.T) {
+	sources := []Source{{Title: "A", URL: "https://example.com/a"}}
+
+	tests := []struct {
+		name          string
+		sources       []Source
+		appendSources bool
+		wantFile      bool
+		wantAppended  bool
+	}{
+		{name: "sources saved", sources: sources, wantFile: true},
+		{name: "sources appended", sources: sources, appendSources: true, wantFile: true, wantAppended: true},
+		{name: "no sources", appendSources: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ViperConfig{OutputDir: t.TempDir(), AppendSources: tt.appendSources}
+			client := &GenaiResearchClient{config: config, logger: NewNullLogger()}
+			result := &ResearchResult{Content: "# Report\n", Sources: tt.sources, body: []byte(`{}`)}
+
+			if err := client.saveResult(result, "", "20251224_103045"); err != nil {
+				t.Fatalf("saveResult() error = %v", err)
+			}
+
+			sourcesPath := filepath.Join(config.ResearchDir(), "20251224_103045.sources.md")
+			_, err := os.Stat(sourcesPath)
+			if tt.wantFile != (err == nil) || (tt.wantFile && result.SourcesPath != sourcesPath) {
+				t.Errorf("sources file exists = %v, SourcesPath = %q, want file %v", err == nil, result.SourcesPath, tt.wantFile)
+			}
+
+			markdown, err := os.ReadFile(result.MarkdownPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if appended := strings.Contains(string(markdown), "## Sources"); appended != tt.wantAppended {
+				t.Errorf("markdown = %q, want sources appended %v", markdown, tt.wantAppended)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// builtinStyles are the built-in style presets selected with --style.
+var builtinStyles = map[string]string{
+	"flat": "Style: flat design with simple geometric shapes and icons, solid colors without gradients or shadows, " +
+		"generous white space and a clean sans-serif typeface.",
+	"hand-drawn": "Style: hand-drawn sketch look with marker and pencil strokes, slightly irregular lines, " +
+		"doodle-style icons and handwritten headings on an off-white paper background.",
+	"corporate": "Style: professional corporate look suitable for a business presentation, restrained palette of navy, " +
+		"gray and one accent color, grid-aligned layout, clear charts and a formal sans-serif typeface.",
+	"dark": "Style: dark theme with a near-black background, light text and vivid neon accent colors, " +
+		"high contrast and subtle glow effects on key figures.",
+}
+
+// ResolveStyle returns the instructions of a style preset.
+//
+// Presets defined in the config (styles) take precedence over built-in presets of the same name.
+// An empty name returns no instructions; an unknown name is an error listing the available presets.
+func ResolveStyle(name string, custom map[string]string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	if text, ok := custom[name]; ok {
+		return text, nil
+	}
+	if text, ok := builtinStyles[name]; ok {
+		return text, nil
+	}
+	return "", fmt.Errorf("unknown style %q (available: %s)", name, strings.Join(availableStyles(custom), ", "))
+}
+
+// availableStyles returns the names of built-in and config-defined style presets in sorted order.
+func availableStyles(custom map[string]string) []string {
+	names := slices.Collect(maps.Keys(builtinStyles))
+	for name := range custom {
+		if _, ok := builtinStyles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// appendStyle appends style instructions to an image prompt.
+func appendStyle(prompt, style string) string {
+	style = strings.TrimSpace(style)
+	if style == "" {
+		return prompt
+	}
+	return prompt + "\n\n" + style
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestResolveStyle(t *testing.T) {
+	custom := map[string]string{
+		"brand": "Use the brand colors #0055aa and #ffcc00.",
+		"dark":  "Custom dark theme.",
+	}
+
+	tests := []struct {
+		name    string
+		style   string
+		want    string
+		wantErr string
+	}{
+		{name: "none", style: "", want: ""},
+		{name: "built-in", style: "flat", want: builtinStyles["flat"]},
+		{name: "config-defined", style: "brand", want: "Use the brand colors #0055aa and #ffcc00."},
+		{name: "config overrides built-in", style: "dark", want: "Custom dark theme."},
+		{name: "unknown lists available", style: "retro", wantErr: "available: brand, corporate, dark, flat, hand-drawn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveStyle(tt.style, custom)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResolveStyle() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveStyle() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveStyle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildInfographicsPrompt_Style tests that style presets compose with the prompt template.
+func TestBuildInfographicsPrompt_Style(t *testing.T) {
+	config := &ViperConfig{
+		ImageLang:           "English",
+		ImagePromptTemplate: "Draw {{.Content}} in {{.Lang}}.",
+		Style:               "brand",
+		Styles:              map[string]string{"brand": "Use the brand colors."},
+	}
+	client, err := NewGenaiImageClient(context.Background(), config, NewNullLogger())
+	if err != nil {
+		t.Fatalf("NewGenaiImageClient() error = %v", err)
+	}
+
+	prompt, err := client.BuildInfographicsPrompt("AI trends")
+	if err != nil {
+		t.Fatalf("BuildInfographicsPrompt() error = %v", err)
+	}
+	if want := "Draw AI trends in English.\n\nUse the brand colors."; prompt != want {
+		t.Errorf("BuildInfographicsPrompt() = %q, want %q", prompt, want)
+	}
+}
+
+func TestNewGenaiImageClient_UnknownStyle(t *testing.T) {
+	_, err := NewGenaiImageClient(context.Background(), &ViperConfig{Style: "retro"}, NewNullLogger())
+	if err == nil || !strings.Contains(err.Error(), "unknown style") {
+		t.Errorf("NewGenaiImageClient() error = %v, want unknown style", err)
+	}
+}
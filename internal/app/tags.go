@@ -0,0 +1,37 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// maxTagLength is the maximum length of a run tag.
+const maxTagLength = 64
+
+// tagPattern matches run tags (--tag and the tags front matter key).
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ParseTags validates run tags and returns them without duplicates, in order.
+func ParseTags(tags []string) ([]string, error) {
+	var parsed []string
+	for _, tag := range tags {
+		if len(tag) > maxTagLength || !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: use up to %d letters, digits, dots, hyphens and underscores", tag, maxTagLength)
+		}
+		if !slices.Contains(parsed, tag) {
+			parsed = append(parsed, tag)
+		}
+	}
+	return parsed, nil
+}
+
+// hasTags reports whether a run has every tag of want.
+func hasTags(tags, want []string) bool {
+	for _, tag := range want {
+		if !slices.Contains(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", tags: nil, want: nil},
+		{name: "several", tags: []string{"project-x", "weekly", "v1.2_final"}, want: []string{"project-x", "weekly", "v1.2_final"}},
+		{name: "duplicates", tags: []string{"weekly", "project-x", "weekly"}, want: []string{"weekly", "project-x"}},
+		{name: "empty", tags: []string{""}, wantErr: true},
+		{name: "space", tags: []string{"project x"}, wantErr: true},
+		{name: "comma", tags: []string{"a,b"}, wantErr: true},
+		{name: "leading hyphen", tags: []string{"-x"}, wantErr: true},
+		{name: "path", tags: []string{"../x"}, wantErr: true},
+		{name: "too long", tags: []string{strings.Repeat("a", maxTagLength+1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTags(tt.tags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasTags(t *testing.T) {
+	tags := []string{"project-x", "weekly"}
+	if !hasTags(tags, nil) {
+		t.Error("hasTags() without wanted tags should match")
+	}
+	if !hasTags(tags, []string{"weekly", "project-x"}) {
+		t.Error("hasTags() should match every tag in any order")
+	}
+	if hasTags(tags, []string{"project-x", "daily"}) {
+		t.Error("hasTags() should require every tag")
+	}
+	if hasTags(nil, []string{"weekly"}) {
+		t.Error("hasTags() should not match untagged runs")
+	}
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// markdownLinkPattern matches inline links ([text](url)).
+	markdownLinkPattern = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]+)\)`)
+	// markdownEmphasisPattern matches bold and italic spans (**text**, __text__, *text*).
+	markdownEmphasisPattern = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__|\*([^*\s][^*]*)\*`)
+	// markdownCodePattern matches inline code spans (`code`).
+	markdownCodePattern = regexp.MustCompile("`([^`]+)`")
+	// markdownListPattern matches unordered list items and captures their indentation.
+	markdownListPattern = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+)
+
+// renderTerminalMarkdown renders markdown as plain text for the terminal.
+//
+// Headings are underlined, emphasis and code markers are removed, links show their URL after the
+// text and list bullets become "•". Code blocks are indented and otherwise left as is.
+func renderTerminalMarkdown(markdown string) string {
+	var b strings.Builder
+	inCode := false
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			b.WriteString("    " + line + "\n")
+			continue
+		}
+
+		if level, title, ok := markdownHeading(line); ok {
+			title = renderInlineMarkdown(title)
+			switch level {
+			case 1:
+				b.WriteString(title + "\n" + strings.Repeat("=", utf8.RuneCountInString(title)) + "\n")
+			case 2:
+				b.WriteString(title + "\n" + strings.Repeat("-", utf8.RuneCountInString(title)) + "\n")
+			default:
+				b.WriteString(title + "\n")
+			}
+			continue
+		}
+
+		if m := markdownListPattern.FindStringSubmatch(line); m != nil {
+			line = m[1] + "• " + line[len(m[0]):]
+		} else if quote, ok := strings.CutPrefix(line, ">"); ok {
+			line = "│ " + strings.TrimPrefix(quote, " ")
+		}
+		b.WriteString(renderInlineMarkdown(line) + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// markdownHeading returns the level and title of an ATX heading line ("## Title").
+func markdownHeading(line string) (int, string, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level == len(line) || line[level] != ' ' {
+		return 0, "", false
+	}
+	return level, strings.TrimSpace(strings.TrimRight(line[level:], "#")), true
+}
+
+// renderInlineMarkdown removes the inline markup of a line.
+func renderInlineMarkdown(line string) string {
+	line = markdownCodePattern.ReplaceAllString(line, "$1")
+	line = markdownLinkPattern.ReplaceAllStringFunc(line, func(link string) string {
+		m := markdownLinkPattern.FindStringSubmatch(link)
+		if m[1] == "" || m[1] == m[2] {
+			return m[2]
+		}
+		return m[1] + " (" + m[2] + ")"
+	})
+	return markdownEmphasisPattern.ReplaceAllString(line, "$1$2$3")
+}
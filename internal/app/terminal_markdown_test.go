@@ -0,0 +1,50 @@
+package app
+
+import "testing"
+
+func TestRenderTerminalMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "headings",
+			markdown: "# Report\n\n## Trends ##\n\n### Details",
+			want:     "Report\n======\n\nTrends\n------\n\nDetails\n",
+		},
+		{
+			name:     "inline markup",
+			markdown: "**Bold**, *italic*, __strong__ and `code` with [a link](https://example.com) and [https://example.org](https://example.org)",
+			want:     "Bold, italic, strong and code with a link (https://example.com) and https://example.org\n",
+		},
+		{
+			name:     "lists and quotes",
+			markdown: "- one\n  * nested\n> quoted\n1. numbered",
+			want:     "• one\n  • nested\n│ quoted\n1. numbered\n",
+		},
+		{
+			name:     "code block is left as is",
+			markdown: "```go\n# not a heading\n**x**\n```\nafter",
+			want:     "    # not a heading\n    **x**\nafter\n",
+		},
+		{
+			name:     "not a heading",
+			markdown: "#hashtag",
+			want:     "#hashtag\n",
+		},
+		{
+			name:     "multiplication is not emphasis",
+			markdown: "2 * 3 * 4",
+			want:     "2 * 3 * 4\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderTerminalMarkdown(tt.markdown); got != tt.want {
+				t.Errorf("renderTerminalMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/yukiyan/deepviz/internal/genai/interactions"
+)
+
+// thinkingSummariesValues are the thinking_summaries values accepted by the Interactions API.
+var thinkingSummariesValues = []string{
+	string(interactions.ThinkingSummariesAuto),
+	string(interactions.ThinkingSummariesNone),
+}
+
+// thinkingSummariesAliases map friendlier names to the values accepted by the API.
+var thinkingSummariesAliases = map[string]string{
+	"off": string(interactions.ThinkingSummariesNone),
+}
+
+// ParseThinkingSummaries validates a thinking_summaries value of the research agent.
+//
+// Names are case-insensitive and "off" is accepted for "none". An empty value uses "auto".
+func ParseThinkingSummaries(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return string(interactions.ThinkingSummariesAuto), nil
+	}
+	if alias, ok := thinkingSummariesAliases[value]; ok {
+		value = alias
+	}
+	if !slices.Contains(thinkingSummariesValues, value) {
+		return "", fmt.Errorf("unknown thinking summaries %q (available: %s, off)", value, strings.Join(thinkingSummariesValues, ", "))
+	}
+	return value, nil
+}
@@ -0,0 +1,52 @@
+package app
+
+import "testing"
+
+func TestParseThinkingSummaries(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: "auto"},
+		{value: "auto", want: "auto"},
+		{value: "none", want: "none"},
+		{value: "off", want: "none"},
+		{value: " OFF ", want: "none"},
+		{value: "detailed", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseThinkingSummaries(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseThinkingSummaries(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseThinkingSummaries(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildResearchRequest_ThinkingSummaries tests that the setting is sent in agent_config.
+func TestBuildResearchRequest_ThinkingSummaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting string
+		want    string
+	}{
+		{name: "default", setting: "", want: "auto"},
+		{name: "disabled", setting: "none", want: "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &GenaiResearchClient{config: &ViperConfig{DeepResearchAgent: "test-agent", ThinkingSummaries: tt.setting}}
+			agentConfig := client.buildResearchRequest("prompt")["agent_config"].(map[string]interface{})
+			if agentConfig["thinking_summaries"] != tt.want {
+				t.Errorf("thinking_summaries = %v, want %q", agentConfig["thinking_summaries"], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yukiyan/deepviz/internal/genai/interactions"
+)
+
+// thoughtSummaries returns the text of the thought summaries in an interaction output.
+//
+// Outputs other than thoughts and non-text summary items are ignored.
+func thoughtSummaries(output interactions.Content) []string {
+	if kind, err := output.Discriminator(); err != nil || kind != "thought" {
+		return nil
+	}
+	thought, err := output.AsThoughtContent()
+	if err != nil || thought.Summary == nil {
+		return nil
+	}
+
+	var summaries []string
+	for _, item := range *thought.Summary {
+		if kind, err := item.Discriminator(); err != nil || kind != "text" {
+			continue
+		}
+		text, err := item.AsTextContent()
+		if err != nil || text.Text == nil {
+			continue
+		}
+		if summary := strings.TrimSpace(*text.Text); summary != "" {
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries
+}
+
+// thoughtTracker remembers the thought summaries already shown while polling a research.
+type thoughtTracker struct {
+	byIndex []string        // Summary shown at each position of the outputs
+	seen    map[string]bool // Every summary shown, to avoid repeats when outputs shift
+}
+
+// newThoughtTracker creates a new thoughtTracker.
+func newThoughtTracker() *thoughtTracker {
+	return &thoughtTracker{seen: make(map[string]bool)}
+}
+
+// update returns the summaries of a poll that were not shown yet.
+//
+// Each poll returns every summary accumulated so far, so summaries at positions already shown
+// are skipped. A summary that grew since the previous poll yields only the appended text, and a
+// summary shown before at another position (e.g., when outputs are dropped or reordered) is not
+// repeated.
+func (t *thoughtTracker) update(summaries []string) []string {
+	var fresh []string
+	for i, summary := range summaries {
+		if i == len(t.byIndex) {
+			t.byIndex = append(t.byIndex, "")
+		}
+		prev := t.byIndex[i]
+		t.byIndex[i] = summary
+
+		switch {
+		case summary == prev || t.seen[summary]:
+		case prev != "" && strings.HasPrefix(summary, prev):
+			if rest := strings.TrimSpace(summary[len(prev):]); rest != "" {
+				fresh = append(fresh, rest)
+			}
+		default:
+			fresh = append(fresh, summary)
+		}
+		t.seen[summary] = true
+	}
+	return fresh
+}
+
+// printThought prints a thought summary with continuation lines indented under the label.
+func printThought(w io.Writer, summary string) {
+	lines := strings.Split(strings.TrimSpace(summary), "\n")
+	var b strings.Builder
+	fmt.Fprintf(&b, "Thinking: %s\n", lines[0])
+	for _, line := range lines[1:] {
+		fmt.Fprintf(&b, "          %s\n", line)
+	}
+	// A single write keeps the summary in one piece when runs share the terminal
+	io.WriteString(w, b.String())
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/yukiyan/deepviz/internal/genai/interactions"
+)
+
+func TestThoughtSummaries(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{
+			name:   "thought with text summaries",
+			output: `{"type":"thought","summary":[{"type":"text","text":"Searching for sources"},{"type":"text","text":"  "},{"type":"image","data":"AAAA"},{"type":"text","text":"Comparing results\n"}]}`,
+			want:   []string{"Searching for sources", "Comparing results"},
+		},
+		{name: "thought without summary", output: `{"type":"thought","signature":"c2ln"}`},
+		{name: "text output", output: `{"type":"text","text":"report"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output interactions.Content
+			if err := json.Unmarshal([]byte(tt.output), &output); err != nil {
+				t.Fatal(err)
+			}
+			if got := thoughtSummaries(output); !slices.Equal(got, tt.want) {
+				t.Errorf("thoughtSummaries() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThoughtTracker_Update(t *testing.T) {
+	// Each step is the full list of summaries returned by one poll
+	tests := []struct {
+		name  string
+		polls [][]string
+		want  [][]string
+	}{
+		{
+			name:  "accumulating summaries",
+			polls: [][]string{{}, {"a"}, {"a", "b"}, {"a", "b"}, {"a", "b", "c"}},
+			want:  [][]string{nil, {"a"}, {"b"}, nil, {"c"}},
+		},
+		{
+			name:  "several new summaries at once",
+			polls: [][]string{{"a"}, {"a", "b", "c"}},
+			want:  [][]string{{"a"}, {"b", "c"}},
+		},
+		{
+			name:  "growing summary yields the appended text",
+			polls: [][]string{{"Searching"}, {"Searching for sources"}, {"Searching for sources", "b"}},
+			want:  [][]string{{"Searching"}, {"for sources"}, {"b"}},
+		},
+		{
+			name:  "shifted outputs are not repeated",
+			polls: [][]string{{"a", "b"}, {"b", "c"}},
+			want:  [][]string{{"a", "b"}, {"c"}},
+		},
+		{
+			name:  "replaced summary is shown",
+			polls: [][]string{{"a"}, {"x"}},
+			want:  [][]string{{"a"}, {"x"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newThoughtTracker()
+			for i, poll := range tt.polls {
+				if got := tracker.update(poll); !slices.Equal(got, tt.want[i]) {
+					t.Errorf("poll %d: update(%q) = %q, want %q", i, poll, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPrintThought(t *testing.T) {
+	var buf bytes.Buffer
+	printThought(&buf, "Planning the research\nStep 1: search\n")
+
+	want := "Thinking: Planning the research\n          Step 1: search\n"
+	if buf.String() != want {
+		t.Errorf("printThought() = %q, want %q", buf.String(), want)
+	}
+}
+
+// TestGenaiResearchClient_PollUntilComplete_Thoughts tests that new thought summaries are reported once while polling.
+func TestGenaiResearchClient_PollUntilComplete_Thoughts(t *testing.T) {
+	payloads := []string{
+		`{"id":"test-id","status":"in_progress","outputs":[]}`,
+		`{"id":"test-id","status":"in_progress","outputs":[{"type":"thought","summary":[{"type":"text","text":"Planning"}]}]}`,
+		`{"id":"test-id","status":"in_progress","outputs":[{"type":"thought","summary":[{"type":"text","text":"Planning"}]},{"type":"thought","summary":[{"type":"text","text":"Searching"}]}]}`,
+		`{"id":"test-id","status":"completed","outputs":[{"type":"thought","summary":[{"type":"text","text":"Planning"}]},{"type":"thought","summary":[{"type":"text","text":"Searching"}]},{"type":"text","text":"report"}]}`,
+	}
+	var mu sync.Mutex
+	var calls int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		payload := payloads[min(calls, len(payloads)-1)]
+		calls++
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(payload))
+	})
+
+	config := &ViperConfig{PollInterval: 10, PollMaxInterval: 10, PollTimeout: 600, PollMaxFailures: 3}
+	client, _ := newTestResearchClient(t, handler, config)
+	var shown []string
+	client.OnThought = func(summary string) {
+		shown = append(shown, summary)
+	}
+
+	result, err := client.pollUntilComplete(context.Background(), "test-id", nil)
+	if err != nil {
+		t.Fatalf("pollUntilComplete() error = %v", err)
+	}
+	if result.Content != "report" {
+		t.Errorf("Content = %q, want the text output after the thoughts", result.Content)
+	}
+	if want := []string{"Planning", "Searching"}; !slices.Equal(shown, want) {
+		t.Errorf("shown thoughts = %q, want %q", shown, want)
+	}
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timestampLayout is the default time layout of run timestamps (YYYYMMDD_HHMMSS).
+const timestampLayout = "20060102_150405"
+
+// runTimestamps allocates the run timestamps of the process.
+var runTimestamps = newTimestampAllocator()
+
+// RunNamer generates and parses run timestamps in the configured format and timezone.
+type RunNamer struct {
+	Format string // Go time layout (empty for timestampLayout)
+	UTC    bool   // Use UTC instead of the local time
+	now    func() time.Time
+}
+
+// Namer returns the RunNamer of the configured timestamp format and timezone.
+func (c *ViperConfig) Namer() *RunNamer {
+	return &RunNamer{Format: c.TimestampFormat, UTC: c.TimestampUTC, now: time.Now}
+}
+
+// layout returns the time layout of the timestamps.
+func (n *RunNamer) layout() string {
+	if n.Format == "" {
+		return timestampLayout
+	}
+	return n.Format
+}
+
+// layouts returns the time layouts recognized in run names, the configured one first.
+func (n *RunNamer) layouts() []string {
+	if n.layout() == timestampLayout {
+		return []string{timestampLayout}
+	}
+	return []string{n.layout(), timestampLayout}
+}
+
+// location returns the timezone of the timestamps.
+func (n *RunNamer) location() *time.Location {
+	if n.UTC {
+		return time.UTC
+	}
+	return time.Local
+}
+
+// GenerateTimestamp generates a run timestamp from the current time.
+//
+// Format: the time layout followed by a random hexadecimal suffix (YYYYMMDD_HHMMSS-xxxx by
+// default). Timestamps are unique within the process and extremely unlikely to collide with runs
+// started in the same second by other processes.
+func (n *RunNamer) GenerateTimestamp() string {
+	now := time.Now
+	if n.now != nil {
+		now = n.now
+	}
+	return runTimestamps.Next(now().In(n.location()).Format(n.layout()))
+}
+
+// ParseTimestamp returns the time a run timestamp was generated at.
+//
+// The random suffix (and anything else after the time) is ignored. Timestamps in the default
+// format are recognized too, so runs saved before the format was changed keep their time.
+func (n *RunNamer) ParseTimestamp(timestamp string) (time.Time, error) {
+	var err error
+	for _, layout := range n.layouts() {
+		var t time.Time
+		value := timestamp
+		if width := timestampWidth(layout); len(value) > width {
+			value = value[:width]
+		}
+		location := n.location()
+		if layout != n.layout() {
+			location = time.Local
+		}
+		if t, err = time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// SplitRunName splits an artifact name into the run name and its variant suffix.
+//
+// The run name ends at the first underscore after the date and time of a timestamp, so the
+// underscores of the timestamp format and the "-xxxx" suffix of timestamps are kept whole. Names
+// without a leading timestamp (the slug filename style) end at their first underscore.
+func (n *RunNamer) SplitRunName(name string) (run, suffix string) {
+	start := 0
+	for _, layout := range n.layouts() {
+		if width := timestampWidth(layout); len(name) >= width {
+			if _, err := time.Parse(layout, name[:width]); err == nil {
+				start = width
+				break
+			}
+		}
+	}
+	if i := strings.IndexByte(name[start:], '_'); i >= 0 {
+		return name[:start+i], name[start+i:]
+	}
+	return name, ""
+}
+
+// timestampWidth returns the length of the timestamps of a time layout.
+func timestampWidth(layout string) int {
+	return len(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).Format(layout))
+}
+
+// ValidateTimestampFormat validates a timestamp_format value.
+//
+// The format must produce file names: no path separators (nor colons on Windows), a fixed width
+// so that the timestamp can be told apart from variant suffixes, and a value that changes over time.
+func ValidateTimestampFormat(layout string) error {
+	forbidden := `/\`
+	if runtime.GOOS == "windows" {
+		forbidden += `:*?"<>|`
+	}
+	samples := []time.Time{
+		time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		time.Date(2025, 12, 24, 9, 30, 45, 0, time.UTC),
+		time.Date(2025, 5, 31, 23, 59, 59, 0, time.Local),
+	}
+	varies := false
+	for i, sample := range samples {
+		formatted := sample.Format(layout)
+		if strings.ContainsAny(formatted, forbidden) || strings.ContainsFunc(formatted, func(r rune) bool { return r < ' ' }) {
+			return fmt.Errorf("invalid timestamp format %q: %q is not a valid file name", layout, formatted)
+		}
+		if len(formatted) != timestampWidth(layout) {
+			return fmt.Errorf("invalid timestamp format %q: timestamps must have a fixed width (use zero-padded fields)", layout)
+		}
+		if i > 0 && formatted != samples[0].Format(layout) {
+			varies = true
+		}
+	}
+	if !varies {
+		return fmt.Errorf("invalid timestamp format %q: it contains no date or time", layout)
+	}
+	return nil
+}
+
+// timestampAllocator hands out run timestamps that are unique within the process.
+type timestampAllocator struct {
+	mu     sync.Mutex
+	used   map[string]bool
+	random func() uint16
+}
+
+// newTimestampAllocator creates a new timestampAllocator.
+func newTimestampAllocator() *timestampAllocator {
+	return &timestampAllocator{
+		used:   make(map[string]bool),
+		random: func() uint16 { return uint16(rand.Uint32()) },
+	}
+}
+
+// Next returns a timestamp made of base and a random suffix that has not been returned before.
+//
+// The suffix is separated with a hyphen because underscores separate the variant suffixes of
+// artifact names (see RunNamer.SplitRunName).
+func (a *timestampAllocator) Next(base string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for {
+		timestamp := fmt.Sprintf("%s-%04x", base, a.random())
+		if !a.used[timestamp] {
+			a.used[timestamp] = true
+			return timestamp
+		}
+	}
+}
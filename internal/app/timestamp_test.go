@@ -0,0 +1,190 @@
+package app
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestGenerateTimestamp tests timestamp generation.
+func TestGenerateTimestamp(t *testing.T) {
+	timestamp := (&RunNamer{}).GenerateTimestamp()
+
+	// Verify format (YYYYMMDD_HHMMSS-xxxx = 20 characters)
+	if len(timestamp) != 20 {
+		t.Errorf("expected timestamp length 20, got %d", len(timestamp))
+	}
+
+	// Verify underscore and hyphen positions
+	if timestamp[8] != '_' {
+		t.Errorf("expected underscore at position 8, got %c", timestamp[8])
+	}
+	if timestamp[15] != '-' {
+		t.Errorf("expected hyphen at position 15, got %c", timestamp[15])
+	}
+
+	// Validate with time.Parse
+	_, err := time.Parse("20060102_150405", timestamp[:15])
+	if err != nil {
+		t.Errorf("failed to parse timestamp %s: %v", timestamp, err)
+	}
+
+	// The suffix keeps run timestamps apart from variant suffixes
+	if run, suffix := (&RunNamer{}).SplitRunName(timestamp); run != timestamp || suffix != "" {
+		t.Errorf("SplitRunName(%s) = %q, %q, want the whole timestamp as the run", timestamp, run, suffix)
+	}
+}
+
+// TestGenerateTimestamp_Unique tests that rapid successive timestamps differ.
+func TestGenerateTimestamp_Unique(t *testing.T) {
+	namer := &RunNamer{}
+	seen := make(map[string]bool)
+	for range 100 {
+		timestamp := namer.GenerateTimestamp()
+		if seen[timestamp] {
+			t.Fatalf("duplicate timestamp %s", timestamp)
+		}
+		seen[timestamp] = true
+	}
+}
+
+// TestRunNamer_GenerateTimestamp tests the configured format and timezone.
+func TestRunNamer_GenerateTimestamp(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	now := func() time.Time { return time.Date(2025, 12, 24, 10, 30, 45, 0, tokyo) }
+
+	tests := []struct {
+		name  string
+		namer *RunNamer
+		want  string
+	}{
+		{name: "default local", namer: &RunNamer{now: func() time.Time { return now().In(time.Local) }}, want: now().In(time.Local).Format(timestampLayout)},
+		{name: "utc", namer: &RunNamer{UTC: true, now: now}, want: "20251224_013045"},
+		{name: "custom format", namer: &RunNamer{Format: "2006-01-02T150405Z", UTC: true, now: now}, want: "2025-12-24T013045Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp := tt.namer.GenerateTimestamp()
+			base, suffix := timestamp[:len(timestamp)-5], timestamp[len(timestamp)-5:]
+			if base != tt.want || !strings.HasPrefix(suffix, "-") {
+				t.Errorf("GenerateTimestamp() = %s, want %s-xxxx", timestamp, tt.want)
+			}
+
+			// The timestamp parses back to the time it was generated at
+			parsed, err := tt.namer.ParseTimestamp(timestamp)
+			if err != nil {
+				t.Fatalf("ParseTimestamp(%s) error = %v", timestamp, err)
+			}
+			if !parsed.Equal(now()) {
+				t.Errorf("ParseTimestamp(%s) = %v, want %v", timestamp, parsed, now())
+			}
+		})
+	}
+}
+
+// TestRunNamer_ParseTimestamp_DefaultFormat tests that runs named before the format changed keep their time.
+func TestRunNamer_ParseTimestamp_DefaultFormat(t *testing.T) {
+	namer := &RunNamer{Format: "2006-01-02T150405Z", UTC: true}
+	got, err := namer.ParseTimestamp("20251224_103045")
+	if err != nil {
+		t.Fatalf("ParseTimestamp() error = %v", err)
+	}
+	if want := time.Date(2025, 12, 24, 10, 30, 45, 0, time.Local); !got.Equal(want) {
+		t.Errorf("ParseTimestamp() = %v, want %v", got, want)
+	}
+}
+
+func TestRunNamer_SplitRunName(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        string
+		wantTimestamp string
+		wantSuffix    string
+	}{
+		{name: "20251224_103045", wantTimestamp: "20251224_103045"},
+		{name: "20251224_103045_ja_2", wantTimestamp: "20251224_103045", wantSuffix: "_ja_2"},
+		{name: "20251224_103045-2_r1", wantTimestamp: "20251224_103045-2", wantSuffix: "_r1"},
+		{name: "20251224_103045-3f9c_ai-trends", wantTimestamp: "20251224_103045-3f9c", wantSuffix: "_ai-trends"},
+		{name: "test-timestamp", wantTimestamp: "test-timestamp"},
+		{name: "2025_12_24_103045-3f9c_ja", format: "2006_01_02_150405", wantTimestamp: "2025_12_24_103045-3f9c", wantSuffix: "_ja"},
+		{name: "20251224_103045_ja", format: "2006_01_02_150405", wantTimestamp: "20251224_103045", wantSuffix: "_ja"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp, suffix := (&RunNamer{Format: tt.format}).SplitRunName(tt.name)
+			if timestamp != tt.wantTimestamp || suffix != tt.wantSuffix {
+				t.Errorf("SplitRunName() = %q, %q, want %q, %q", timestamp, suffix, tt.wantTimestamp, tt.wantSuffix)
+			}
+		})
+	}
+}
+
+func TestValidateTimestampFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		wantErr bool
+	}{
+		{name: "default", format: timestampLayout},
+		{name: "iso-like", format: "20060102T150405Z"},
+		{name: "dashes", format: "2006-01-02_15-04-05"},
+		{name: "date only", format: "2006-01-02"},
+		{name: "path separator", format: "2006/01/02_150405", wantErr: true},
+		{name: "backslash", format: `2006\01\02`, wantErr: true},
+		{name: "variable width", format: "January_2_150405", wantErr: true},
+		{name: "constant", format: "run", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTimestampFormat(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTimestampFormat(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestTimestampAllocator tests that a suffix already handed out is drawn again.
+func TestTimestampAllocator(t *testing.T) {
+	allocator := newTimestampAllocator()
+	suffixes := []uint16{0x3f9c, 0x3f9c, 0x0001}
+	allocator.random = func() uint16 {
+		suffix := suffixes[0]
+		suffixes = suffixes[1:]
+		return suffix
+	}
+
+	want := []string{"20251224_103045-3f9c", "20251224_103045-0001"}
+	for _, w := range want {
+		if got := allocator.Next("20251224_103045"); got != w {
+			t.Errorf("Next() = %s, want %s", got, w)
+		}
+	}
+}
+
+// TestTimestampAllocator_Concurrent tests that concurrent runs get distinct timestamps.
+func TestTimestampAllocator_Concurrent(t *testing.T) {
+	allocator := newTimestampAllocator()
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			timestamp := allocator.Next("20251224_103045")
+			mu.Lock()
+			defer mu.Unlock()
+			if seen[timestamp] {
+				t.Errorf("duplicate timestamp %s", timestamp)
+			}
+			seen[timestamp] = true
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,322 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// Releases of deepviz on GitHub.
+const (
+	releasesURL       = "https://api.github.com/repos/yukiyan/deepviz/releases"
+	checksumsFileName = "checksums.txt" // sha256sum output for the assets of a release
+)
+
+// upgradeTimeout bounds the release lookup and the download of `deepviz upgrade`.
+const upgradeTimeout = 5 * time.Minute
+
+// Release is a GitHub release of deepviz.
+type Release struct {
+	TagName string         `json:"tag_name"`
+	HTMLURL string         `json:"html_url"`
+	Assets  []ReleaseAsset `json:"assets"`
+}
+
+// ReleaseAsset is a file attached to a release.
+type ReleaseAsset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+}
+
+// Asset returns the asset with the given name.
+func (r *Release) Asset(name string) (ReleaseAsset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return ReleaseAsset{}, false
+}
+
+// ReleaseSource looks up and downloads the releases (replaced in tests).
+type ReleaseSource interface {
+	Latest(ctx context.Context) (*Release, error)
+	Download(ctx context.Context, url string) ([]byte, error)
+}
+
+// githubReleases is the ReleaseSource of the GitHub releases API. GITHUB_TOKEN, when set, raises
+// the rate limit of the API.
+type githubReleases struct {
+	httpClient *http.Client
+	baseURL    string // releasesURL, replaced in tests
+}
+
+// newGitHubReleases creates the ReleaseSource of the GitHub releases of deepviz.
+func newGitHubReleases() *githubReleases {
+	return &githubReleases{httpClient: &http.Client{}, baseURL: releasesURL}
+}
+
+// Latest returns the latest release (drafts and pre-releases excluded).
+func (g *githubReleases) Latest(ctx context.Context) (*Release, error) {
+	body, err := g.get(ctx, g.baseURL+"/latest", "application/vnd.github+json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the latest release: %w", err)
+	}
+	var release Release
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse the latest release: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, errors.New("failed to parse the latest release: no tag name")
+	}
+	return &release, nil
+}
+
+// Download returns the content of a release asset.
+func (g *githubReleases) Download(ctx context.Context, url string) ([]byte, error) {
+	body, err := g.get(ctx, url, "application/octet-stream")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	return body, nil
+}
+
+// get returns the body of a successful GET request.
+func (g *githubReleases) get(ctx context.Context, url, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", userAgent(""))
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// canonicalVersion returns a version with the "v" prefix of semantic versions (e.g., v0.2.0), or
+// "" when it is not one (e.g., dev).
+func canonicalVersion(version string) string {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	if !semver.IsValid(version) {
+		return ""
+	}
+	return version
+}
+
+// isNewerVersion reports whether latest is newer than current. Builds without a release version
+// (e.g., dev) are older than any release.
+func isNewerVersion(current, latest string) bool {
+	latest = canonicalVersion(latest)
+	if latest == "" {
+		return false
+	}
+	current = canonicalVersion(current)
+	return current == "" || semver.Compare(latest, current) > 0
+}
+
+// releaseAssetName returns the name of the binary of a platform in the releases (see the
+// build-all target of the Makefile).
+func releaseAssetName(goos, goarch string) string {
+	name := fmt.Sprintf("deepviz-%s-%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// parseChecksum returns the SHA-256 checksum of the file name in a checksums file of sha256sum
+// (lines of "<hex>  <name>", with a "*" before the names of binary mode).
+func parseChecksum(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		if sum, err := hex.DecodeString(fields[0]); err != nil || len(sum) != sha256.Size {
+			return "", fmt.Errorf("invalid checksum of %s: %q", name, fields[0])
+		}
+		return strings.ToLower(fields[0]), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no checksum of %s in %s", name, checksumsFileName)
+}
+
+// verifyChecksum checks that the SHA-256 checksum of data is want.
+func verifyChecksum(data []byte, want string) error {
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("checksum mismatch: got %s, want %s", got, want)
+	}
+	return nil
+}
+
+// packageManagerHint returns how to upgrade an executable installed by a package manager, or ""
+// when it was installed by hand.
+func packageManagerHint(executable string) string {
+	path := filepath.ToSlash(executable)
+	switch {
+	case strings.Contains(path, "/Cellar/") || strings.Contains(path, "/homebrew/") || strings.Contains(path, "/linuxbrew/"):
+		return "deepviz was installed with Homebrew: run `brew upgrade deepviz`"
+	case strings.HasPrefix(path, "/nix/store/"):
+		return "deepviz was installed with Nix: upgrade it with your Nix configuration"
+	case strings.HasPrefix(path, "/usr/bin/") || strings.HasPrefix(path, "/bin/"):
+		return "deepviz was installed by the system package manager: upgrade it with the package manager"
+	}
+	return ""
+}
+
+// checkReplaceable checks that the executable can be replaced: a file can be created next to it
+// and renamed over it.
+func checkReplaceable(executable string) error {
+	file, err := os.CreateTemp(filepath.Dir(executable), ".deepviz-upgrade-")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// replaceExecutable replaces the executable with data atomically: data is written next to it and
+// renamed over it, keeping its permissions. A running executable cannot be replaced on Windows, so
+// it is renamed away first (and removed by the next upgrade).
+func replaceExecutable(executable string, data []byte, goos string) error {
+	info, err := os.Stat(executable)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(executable), ".deepviz-upgrade-")
+	if err != nil {
+		return err
+	}
+	tmp := file.Name()
+	defer os.Remove(tmp) // No-op once renamed
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	if goos == "windows" {
+		old := executable + ".old"
+		os.Remove(old)
+		if err := os.Rename(executable, old); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, executable); err != nil {
+			os.Rename(old, executable)
+			return err
+		}
+		return nil
+	}
+	return os.Rename(tmp, executable)
+}
+
+// upgrader upgrades the executable to the latest release.
+type upgrader struct {
+	source     ReleaseSource
+	current    string // Version of the running binary
+	executable string // Path of the running binary
+	goos       string
+	goarch     string
+}
+
+// run looks up the latest release and, unless checkOnly, installs it. When the executable cannot be
+// replaced, the instructions to upgrade by hand are printed instead.
+func (u *upgrader) run(ctx context.Context, w io.Writer, checkOnly bool) error {
+	styler := NewStyler(w)
+	release, err := u.source.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	if !isNewerVersion(u.current, release.TagName) {
+		fmt.Fprintf(w, "deepviz %s is up to date (latest: %s)\n", u.current, release.TagName)
+		return nil
+	}
+	fmt.Fprintf(w, "Update available: %s → %s\n", u.current, styler.Bold(release.TagName))
+	if release.HTMLURL != "" {
+		fmt.Fprintf(w, "  %s\n", styler.Dim(release.HTMLURL))
+	}
+	if checkOnly {
+		return nil
+	}
+
+	name := releaseAssetName(u.goos, u.goarch)
+	asset, ok := release.Asset(name)
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s (%s)", release.TagName, u.goos, u.goarch, name)
+	}
+	checksumsAsset, ok := release.Asset(checksumsFileName)
+	if !ok {
+		return fmt.Errorf("release %s has no %s to verify the download", release.TagName, checksumsFileName)
+	}
+
+	// Checked before downloading, so that nothing is left half replaced
+	manual := packageManagerHint(u.executable)
+	if manual == "" {
+		if err := checkReplaceable(u.executable); err != nil {
+			manual = fmt.Sprintf("%s cannot be replaced (%v): download %s from the release and replace it by hand (e.g., with sudo)", u.executable, err, name)
+		}
+	}
+	if manual != "" {
+		printWarning(w, "%s", manual)
+		return nil
+	}
+
+	checksums, err := u.source.Download(ctx, checksumsAsset.DownloadURL)
+	if err != nil {
+		return err
+	}
+	want, err := parseChecksum(checksums, name)
+	if err != nil {
+		return err
+	}
+	data, err := u.source.Download(ctx, asset.DownloadURL)
+	if err != nil {
+		return err
+	}
+	if err := verifyChecksum(data, want); err != nil {
+		return fmt.Errorf("refusing to install %s: %w", name, err)
+	}
+	if err := replaceExecutable(u.executable, data, u.goos); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", u.executable, err)
+	}
+	fmt.Fprintf(w, "%s Upgraded %s to %s\n", styler.Success("✓"), u.executable, release.TagName)
+	return nil
+}
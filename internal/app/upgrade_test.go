@@ -0,0 +1,293 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeReleaseSource serves a release and its assets from memory.
+type fakeReleaseSource struct {
+	release   *Release
+	files     map[string][]byte // Content by download URL
+	err       error
+	downloads []string
+}
+
+func (s *fakeReleaseSource) Latest(ctx context.Context) (*Release, error) {
+	return s.release, s.err
+}
+
+func (s *fakeReleaseSource) Download(ctx context.Context, url string) ([]byte, error) {
+	s.downloads = append(s.downloads, url)
+	data, ok := s.files[url]
+	if !ok {
+		return nil, fmt.Errorf("failed to download %s: status 404", url)
+	}
+	return data, nil
+}
+
+// newFakeRelease returns a release of tag with the linux/amd64 binary and its checksums file.
+func newFakeRelease(tag string, binary []byte, checksum string) *fakeReleaseSource {
+	base := "https://github.com/yukiyan/deepviz/releases/download/" + tag + "/"
+	return &fakeReleaseSource{
+		release: &Release{TagName: tag, HTMLURL: "https://github.com/yukiyan/deepviz/releases/tag/" + tag, Assets: []ReleaseAsset{
+			{Name: "deepviz-linux-amd64", DownloadURL: base + "deepviz-linux-amd64"},
+			{Name: "deepviz-darwin-arm64", DownloadURL: base + "deepviz-darwin-arm64"},
+			{Name: checksumsFileName, DownloadURL: base + checksumsFileName},
+		}},
+		files: map[string][]byte{
+			base + "deepviz-linux-amd64": binary,
+			base + checksumsFileName:     []byte(checksum + "  deepviz-linux-amd64\n" + strings.Repeat("0", 64) + "  deepviz-darwin-arm64\n"),
+		},
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestIsNewerVersion(t *testing.T) {
+	tests := []struct {
+		current string
+		latest  string
+		want    bool
+	}{
+		{current: "v0.1.0", latest: "v0.2.0", want: true},
+		{current: "0.1.0", latest: "v0.1.1", want: true},
+		{current: "v0.2.0", latest: "v0.2.0", want: false},
+		{current: "v0.3.0", latest: "v0.2.0", want: false},
+		{current: "v0.2.0-rc.1", latest: "v0.2.0", want: true},
+		{current: "dev", latest: "v0.2.0", want: true},
+		{current: "v0.0.0-20261015072137-2f64c141713b+dirty", latest: "v0.2.0", want: true},
+		{current: "v0.1.0", latest: "nightly", want: false},
+	}
+	for _, tt := range tests {
+		if got := isNewerVersion(tt.current, tt.latest); got != tt.want {
+			t.Errorf("isNewerVersion(%q, %q) = %t, want %t", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+func TestReleaseAssetName(t *testing.T) {
+	if got := releaseAssetName("linux", "arm64"); got != "deepviz-linux-arm64" {
+		t.Errorf("releaseAssetName(linux, arm64) = %q", got)
+	}
+	if got := releaseAssetName("windows", "amd64"); got != "deepviz-windows-amd64.exe" {
+		t.Errorf("releaseAssetName(windows, amd64) = %q", got)
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	sum := sha256Hex([]byte("binary"))
+	checksums := []byte(sum + "  deepviz-linux-amd64\n" + strings.ToUpper(sum) + " *deepviz-windows-amd64.exe\nnot-hex  deepviz-darwin-arm64\n")
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "deepviz-linux-amd64", want: sum},
+		{name: "deepviz-windows-amd64.exe", want: sum},
+		{name: "deepviz-darwin-arm64", wantErr: "invalid checksum"},
+		{name: "deepviz-linux-386", wantErr: "no checksum"},
+	}
+	for _, tt := range tests {
+		got, err := parseChecksum(checksums, tt.name)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseChecksum(%s) error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseChecksum(%s) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestPackageManagerHint(t *testing.T) {
+	tests := []struct {
+		path string
+		want string // Substring of the hint, "" for none
+	}{
+		{path: "/opt/homebrew/bin/deepviz", want: "brew upgrade"},
+		{path: "/usr/local/Cellar/deepviz/0.1.0/bin/deepviz", want: "brew upgrade"},
+		{path: "/nix/store/abc-deepviz-0.1.0/bin/deepviz", want: "Nix"},
+		{path: "/usr/bin/deepviz", want: "package manager"},
+		{path: "/home/user/.local/bin/deepviz"},
+		{path: "/usr/local/bin/deepviz"},
+	}
+	for _, tt := range tests {
+		got := packageManagerHint(tt.path)
+		if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("packageManagerHint(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	for _, goos := range []string{"linux", "windows"} {
+		t.Run(goos, func(t *testing.T) {
+			executable := filepath.Join(t.TempDir(), "deepviz")
+			if err := os.WriteFile(executable, []byte("old"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := replaceExecutable(executable, []byte("new"), goos); err != nil {
+				t.Fatalf("replaceExecutable() error = %v", err)
+			}
+			data, err := os.ReadFile(executable)
+			if err != nil || string(data) != "new" {
+				t.Errorf("executable = %q, %v, want the new binary", data, err)
+			}
+			if info, err := os.Stat(executable); runtime.GOOS != "windows" && (err != nil || info.Mode().Perm() != 0755) {
+				t.Errorf("mode = %v, %v, want 0755", info.Mode(), err)
+			}
+			entries, _ := os.ReadDir(filepath.Dir(executable))
+			for _, entry := range entries {
+				if strings.HasPrefix(entry.Name(), ".deepviz-upgrade-") {
+					t.Errorf("temporary file %s left", entry.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestUpgrader_Run(t *testing.T) {
+	binary := []byte("deepviz v0.2.0")
+	tests := []struct {
+		name       string
+		current    string
+		source     *fakeReleaseSource
+		executable string // "" for a temporary executable
+		checkOnly  bool
+		wantErr    string
+		wantOutput string
+		wantBinary string // Content of the executable after the run
+	}{
+		{name: "upgrade", current: "v0.1.0", source: newFakeRelease("v0.2.0", binary, sha256Hex(binary)), wantOutput: "Upgraded", wantBinary: "deepviz v0.2.0"},
+		{name: "up to date", current: "v0.2.0", source: newFakeRelease("v0.2.0", binary, sha256Hex(binary)), wantOutput: "up to date", wantBinary: "old"},
+		{name: "check", current: "v0.1.0", source: newFakeRelease("v0.2.0", binary, sha256Hex(binary)), checkOnly: true, wantOutput: "Update available: v0.1.0 → v0.2.0", wantBinary: "old"},
+		{name: "checksum mismatch", current: "v0.1.0", source: newFakeRelease("v0.2.0", binary, sha256Hex([]byte("tampered"))), wantErr: "checksum mismatch", wantBinary: "old"},
+		{name: "lookup failure", current: "v0.1.0", source: &fakeReleaseSource{err: errors.New("failed to look up the latest release: status 403")}, wantErr: "status 403", wantBinary: "old"},
+		{name: "package manager", current: "v0.1.0", source: newFakeRelease("v0.2.0", binary, sha256Hex(binary)), executable: "/opt/homebrew/bin/deepviz", wantOutput: "brew upgrade"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "1")
+			executable := tt.executable
+			if executable == "" {
+				executable = filepath.Join(t.TempDir(), "deepviz")
+				if err := os.WriteFile(executable, []byte("old"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			u := &upgrader{source: tt.source, current: tt.current, executable: executable, goos: "linux", goarch: "amd64"}
+			var out bytes.Buffer
+			err := u.run(context.Background(), &out, tt.checkOnly)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("run() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			if !strings.Contains(out.String(), tt.wantOutput) {
+				t.Errorf("output = %q, want %q", out.String(), tt.wantOutput)
+			}
+			if tt.executable != "" {
+				if len(tt.source.downloads) > 0 {
+					t.Errorf("downloads = %v, want none", tt.source.downloads)
+				}
+				return
+			}
+			if data, _ := os.ReadFile(executable); string(data) != tt.wantBinary {
+				t.Errorf("executable = %q, want %q", data, tt.wantBinary)
+			}
+		})
+	}
+}
+
+func TestUpgrader_Run_NotWritable(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() == 0 {
+		t.Skip("needs directory permissions")
+	}
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "deepviz")
+	if err := os.WriteFile(executable, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0755) })
+
+	source := newFakeRelease("v0.2.0", []byte("new"), sha256Hex([]byte("new")))
+	u := &upgrader{source: source, current: "v0.1.0", executable: executable, goos: "linux", goarch: "amd64"}
+	var out bytes.Buffer
+	if err := u.run(context.Background(), &out, false); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "replace it by hand") || len(source.downloads) > 0 {
+		t.Errorf("output = %q, downloads = %v, want instructions without downloads", out.String(), source.downloads)
+	}
+}
+
+func TestGitHubReleases(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "gh-token")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer gh-token" {
+			t.Errorf("Authorization = %q, want the GitHub token", got)
+		}
+		switch r.URL.Path {
+		case "/releases/latest":
+			fmt.Fprint(w, `{"tag_name": "v0.2.0", "html_url": "https://github.com/yukiyan/deepviz/releases/tag/v0.2.0", "assets": [{"name": "checksums.txt", "browser_download_url": "http://`+r.Host+`/download/checksums.txt"}]}`)
+		case "/download/checksums.txt":
+			fmt.Fprint(w, "checksums")
+		default:
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	g := &githubReleases{httpClient: server.Client(), baseURL: server.URL + "/releases"}
+
+	release, err := g.Latest(context.Background())
+	if err != nil || release.TagName != "v0.2.0" {
+		t.Fatalf("Latest() = %+v, %v, want v0.2.0", release, err)
+	}
+	asset, ok := release.Asset(checksumsFileName)
+	if !ok {
+		t.Fatalf("assets = %+v, want %s", release.Assets, checksumsFileName)
+	}
+	if data, err := g.Download(context.Background(), asset.DownloadURL); err != nil || string(data) != "checksums" {
+		t.Errorf("Download() = %q, %v, want the checksums", data, err)
+	}
+	if _, err := g.Download(context.Background(), server.URL+"/download/missing"); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("Download(missing) error = %v, want status 404", err)
+	}
+}
+
+func TestUpgradeCommand(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	source := newFakeRelease("v99.0.0", []byte("new"), sha256Hex([]byte("new")))
+	cmd := newUpgradeCommandWith(source, func() (string, error) { return "/home/user/.local/bin/deepviz", nil })
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--check"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "→ v99.0.0") || len(source.downloads) > 0 {
+		t.Errorf("output = %q, downloads = %v, want the update without downloads", out.String(), source.downloads)
+	}
+}
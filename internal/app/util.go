@@ -1,21 +1,16 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
-	"time"
+	"strings"
 )
 
-// GenerateTimestamp generates a timestamp string from the current time.
-//
-// Format: YYYYMMDD_HHMMSS
-func GenerateTimestamp() string {
-	return time.Now().Format("20060102_150405")
-}
-
 // EnsureDir ensures that a directory exists.
 //
 // Creates the directory if it doesn't exist.
@@ -34,6 +29,61 @@ func WriteFile(path string, data []byte) error {
 	return os.WriteFile(path, data, 0644)
 }
 
+// maxNoClobberSuffix is the largest suffix WriteFileNoClobber tries before giving up.
+const maxNoClobberSuffix = 1000
+
+// WriteFileNoClobber writes data to a file without overwriting an existing one.
+//
+// When path exists, the data is written to the first free path with "-1", "-2", ... appended to the
+// base name before the extension (e.g., "20251224_103045-1.png"). Returns the path written.
+func WriteFileNoClobber(path string, data []byte) (string, error) {
+	dir := filepath.Dir(path)
+	if err := EnsureDir(dir); err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	for n := 0; n <= maxNoClobberSuffix; n++ {
+		target := noClobberPath(path, n)
+		// O_EXCL makes the check and the creation atomic, so concurrent runs cannot pick the same path
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return "", err
+		}
+		return target, f.Close()
+	}
+	return "", fmt.Errorf("failed to find a free file name for %s", path)
+}
+
+// noClobberPath returns path with the suffix "-n" before its extension, or path itself for n = 0.
+func noClobberPath(path string, n int) string {
+	if n == 0 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), n, ext)
+}
+
+// writeArtifact writes a run artifact and returns the path written.
+//
+// Existing files are kept and the artifact saved under a new name (see WriteFileNoClobber) unless
+// overwrite is set.
+func writeArtifact(logger Logger, path string, data []byte, overwrite bool) (string, error) {
+	if overwrite {
+		return path, WriteFile(path, data)
+	}
+	written, err := WriteFileNoClobber(path, data)
+	if err == nil && written != path {
+		logger.Warn("File already exists, saved under another name", "path", path, "saved_path", written)
+	}
+	return written, err
+}
+
 // ReadFile reads data from a file.
 func ReadFile(path string) ([]byte, error) {
 	return os.ReadFile(path)
@@ -65,3 +115,17 @@ func OpenFile(path string) error {
 
 	return exec.Command(cmd, args...).Start()
 }
+
+// ptr returns a pointer to v.
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// deref returns the value p points to, or the zero value if p is nil.
+func deref[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
@@ -4,30 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
-	"time"
 )
 
-// TestGenerateTimestamp tests timestamp generation.
-func TestGenerateTimestamp(t *testing.T) {
-	timestamp := GenerateTimestamp()
-
-	// Verify format (YYYYMMDD_HHMMSS = 15 characters)
-	if len(timestamp) != 15 {
-		t.Errorf("expected timestamp length 15, got %d", len(timestamp))
-	}
-
-	// Verify underscore position
-	if timestamp[8] != '_' {
-		t.Errorf("expected underscore at position 8, got %c", timestamp[8])
-	}
-
-	// Validate with time.Parse
-	_, err := time.Parse("20060102_150405", timestamp)
-	if err != nil {
-		t.Errorf("failed to parse timestamp %s: %v", timestamp, err)
-	}
-}
-
 // TestEnsureDir tests directory creation.
 func TestEnsureDir(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -86,6 +64,58 @@ func TestWriteFile_InvalidPath(t *testing.T) {
 	}
 }
 
+// TestWriteFileNoClobber tests that existing files are kept and the data saved under a new name.
+func TestWriteFileNoClobber(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "images", "20251224_103045.png")
+
+	want := []string{
+		path,
+		filepath.Join(tmpDir, "images", "20251224_103045-1.png"),
+		filepath.Join(tmpDir, "images", "20251224_103045-2.png"),
+	}
+	for i, wantPath := range want {
+		data := []byte{byte(i)}
+		got, err := WriteFileNoClobber(path, data)
+		if err != nil {
+			t.Fatalf("WriteFileNoClobber() error = %v", err)
+		}
+		if got != wantPath {
+			t.Errorf("write %d: path = %s, want %s", i, got, wantPath)
+		}
+		saved, err := os.ReadFile(got)
+		if err != nil || string(saved) != string(data) {
+			t.Errorf("write %d: saved %v (%v), want %v", i, saved, err, data)
+		}
+	}
+
+	// The first file is untouched
+	if saved, _ := os.ReadFile(path); string(saved) != string([]byte{0}) {
+		t.Errorf("original file = %v, want it untouched", saved)
+	}
+}
+
+// TestNoClobberPath tests where the suffix goes with various extensions.
+func TestNoClobberPath(t *testing.T) {
+	tests := []struct {
+		path string
+		n    int
+		want string
+	}{
+		{path: "out/a.png", n: 0, want: "out/a.png"},
+		{path: "out/a.png", n: 1, want: "out/a-1.png"},
+		{path: "out/a_ja.png", n: 12, want: "out/a_ja-12.png"},
+		{path: "out/a.sources.md", n: 1, want: "out/a.sources-1.md"},
+		{path: "out/run.v2/research", n: 2, want: "out/run.v2/research-2"},
+	}
+
+	for _, tt := range tests {
+		if got := noClobberPath(filepath.FromSlash(tt.path), tt.n); got != filepath.FromSlash(tt.want) {
+			t.Errorf("noClobberPath(%q, %d) = %q, want %q", tt.path, tt.n, got, tt.want)
+		}
+	}
+}
+
 // TestReadFile_Success tests successful file reading.
 func TestReadFile_Success(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Authentication modes of the API requests (auth).
+const (
+	AuthAPIKey = "api_key" // Gemini API with an API key
+	AuthADC    = "adc"     // Vertex AI with Application Default Credentials
+)
+
+// vertexLocationPattern matches the Vertex AI locations (vertex_location).
+var vertexLocationPattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// vertexScope is the OAuth scope of the Vertex AI requests.
+const vertexScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// ErrNotSupportedOnVertex is returned for the features of the Gemini API missing on Vertex AI.
+var ErrNotSupportedOnVertex = errors.New("not supported on Vertex AI (auth: adc); use auth: api_key")
+
+// ParseAuthMode parses the auth setting.
+func ParseAuthMode(text string) (string, error) {
+	switch text {
+	case "", AuthAPIKey:
+		return AuthAPIKey, nil
+	case AuthADC:
+		return AuthADC, nil
+	default:
+		return "", fmt.Errorf("unknown auth mode %q (valid: %s, %s)", text, AuthAPIKey, AuthADC)
+	}
+}
+
+// adcCredentials are the Application Default Credentials of the Vertex AI requests, found when
+// first used and shared by the runs of a batch. Their tokens are refreshed before they expire.
+type adcCredentials struct {
+	// find returns the credentials (google.FindDefaultCredentials, replaced in tests)
+	find func(ctx context.Context, scopes ...string) (*google.Credentials, error)
+
+	once    sync.Once
+	source  oauth2.TokenSource
+	project string // Project of the credentials (empty for user credentials)
+	err     error
+}
+
+// newADCCredentials creates the Application Default Credentials, found when first used.
+func newADCCredentials() *adcCredentials {
+	return &adcCredentials{find: google.FindDefaultCredentials}
+}
+
+// load finds the credentials.
+func (c *adcCredentials) load() error {
+	c.once.Do(func() {
+		// The token source outlives the request that loaded it
+		creds, err := c.find(context.Background(), vertexScope)
+		if err != nil {
+			c.err = fmt.Errorf("failed to find Application Default Credentials (run `gcloud auth application-default login` or set GOOGLE_APPLICATION_CREDENTIALS): %w", err)
+			return
+		}
+		// Tokens are reused until they are about to expire, then refreshed (e.g., in long batches)
+		c.source, c.project = oauth2.ReuseTokenSource(nil, creds.TokenSource), creds.ProjectID
+	})
+	return c.err
+}
+
+// Token returns a valid access token.
+func (c *adcCredentials) Token() (*oauth2.Token, error) {
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	token, err := c.source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get an access token from Application Default Credentials: %w", err)
+	}
+	return token, nil
+}
+
+// Project returns the project of the credentials (empty when they have none).
+func (c *adcCredentials) Project() (string, error) {
+	if err := c.load(); err != nil {
+		return "", err
+	}
+	return c.project, nil
+}
+
+// bearerTransport authenticates the API requests with an OAuth access token instead of an API key.
+type bearerTransport struct {
+	base   http.RoundTripper
+	source oauth2.TokenSource
+}
+
+// RoundTrip sends the request with the access token.
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Del("x-goog-api-key")
+	token.SetAuthHeader(req)
+	return t.base.RoundTrip(req)
+}
+
+// vertexEndpoint is the Vertex AI endpoint of a project and location.
+type vertexEndpoint struct {
+	project  string
+	location string
+}
+
+// vertexEndpointOf returns the Vertex AI endpoint of the configuration: vertex_project, or the
+// project of the credentials or of GOOGLE_CLOUD_PROJECT, and vertex_location.
+func vertexEndpointOf(config *ViperConfig) (*vertexEndpoint, error) {
+	project := config.VertexProject
+	if project == "" {
+		project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if project == "" {
+		var err error
+		if project, err = config.adc.Project(); err != nil {
+			return nil, err
+		}
+	}
+	if project == "" {
+		return nil, errors.New("no Vertex AI project: set vertex_project (or GOOGLE_CLOUD_PROJECT)")
+	}
+	return &vertexEndpoint{project: project, location: config.VertexLocation}, nil
+}
+
+// baseURL returns the URL of the regional (or global) API of the endpoint.
+func (e *vertexEndpoint) baseURL() string {
+	if e.location == "global" {
+		return "https://aiplatform.googleapis.com"
+	}
+	return "https://" + e.location + "-aiplatform.googleapis.com"
+}
+
+// modelPath returns the path of a Gemini model method (e.g., generateContent).
+func (e *vertexEndpoint) modelPath(model, method string) string {
+	return fmt.Sprintf("/v1/projects/%s/locations/%s/publishers/google/models/%s:%s", e.project, e.location, strings.TrimPrefix(model, "models/"), method)
+}
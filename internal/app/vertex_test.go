@@ -0,0 +1,196 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// countingTokenSource returns a new token valid for validity at each call.
+type countingTokenSource struct {
+	validity time.Duration
+	count    int
+}
+
+func (s *countingTokenSource) Token() (*oauth2.Token, error) {
+	s.count++
+	return &oauth2.Token{AccessToken: fmt.Sprintf("token-%d", s.count), TokenType: "Bearer", Expiry: time.Now().Add(s.validity)}, nil
+}
+
+// newTestADC returns Application Default Credentials of project with tokens of source.
+func newTestADC(project string, source oauth2.TokenSource, err error) *adcCredentials {
+	return &adcCredentials{find: func(ctx context.Context, scopes ...string) (*google.Credentials, error) {
+		if err != nil {
+			return nil, err
+		}
+		return &google.Credentials{ProjectID: project, TokenSource: source}, nil
+	}}
+}
+
+func TestParseAuthMode(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{text: "", want: AuthAPIKey},
+		{text: "api_key", want: AuthAPIKey},
+		{text: "adc", want: AuthADC},
+		{text: "oauth", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAuthMode(tt.text)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseAuthMode(%q) = %q, %v, want %q (error %t)", tt.text, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestVertexEndpoint(t *testing.T) {
+	tests := []struct {
+		location string
+		wantURL  string
+	}{
+		{location: "global", wantURL: "https://aiplatform.googleapis.com/v1/projects/my-project/locations/global/publishers/google/models/gemini-3-pro-image-preview:generateContent"},
+		{location: "us-central1", wantURL: "https://us-central1-aiplatform.googleapis.com/v1/projects/my-project/locations/us-central1/publishers/google/models/gemini-3-pro-image-preview:generateContent"},
+	}
+	for _, tt := range tests {
+		endpoint := &vertexEndpoint{project: "my-project", location: tt.location}
+		if got := endpoint.baseURL() + endpoint.modelPath("models/gemini-3-pro-image-preview", "generateContent"); got != tt.wantURL {
+			t.Errorf("URL = %s, want %s", got, tt.wantURL)
+		}
+	}
+}
+
+func TestVertexEndpointOf(t *testing.T) {
+	errNoCredentials := errors.New("could not find default credentials")
+	tests := []struct {
+		name        string
+		project     string // vertex_project
+		env         string // GOOGLE_CLOUD_PROJECT
+		adc         *adcCredentials
+		wantProject string
+		wantErr     bool
+	}{
+		{name: "vertex_project", project: "config-project", env: "env-project", adc: newTestADC("creds-project", nil, nil), wantProject: "config-project"},
+		{name: "environment", env: "env-project", adc: newTestADC("creds-project", nil, nil), wantProject: "env-project"},
+		{name: "credentials", adc: newTestADC("creds-project", nil, nil), wantProject: "creds-project"},
+		{name: "no project", adc: newTestADC("", nil, nil), wantErr: true},
+		{name: "no credentials", adc: newTestADC("", nil, errNoCredentials), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOOGLE_CLOUD_PROJECT", tt.env)
+			config := &ViperConfig{VertexProject: tt.project, VertexLocation: "global", adc: tt.adc}
+			endpoint, err := vertexEndpointOf(config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("vertexEndpointOf() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err == nil && endpoint.project != tt.wantProject {
+				t.Errorf("project = %q, want %q", endpoint.project, tt.wantProject)
+			}
+		})
+	}
+}
+
+func TestADCCredentials_Refresh(t *testing.T) {
+	// Valid tokens are reused
+	source := &countingTokenSource{validity: time.Hour}
+	adc := newTestADC("my-project", source, nil)
+	for range 3 {
+		if token, err := adc.Token(); err != nil || token.AccessToken != "token-1" {
+			t.Fatalf("Token() = %v, %v, want the first token", token, err)
+		}
+	}
+
+	// Tokens about to expire are refreshed
+	source = &countingTokenSource{validity: time.Second}
+	adc = newTestADC("my-project", source, nil)
+	adc.Token()
+	if token, err := adc.Token(); err != nil || token.AccessToken != "token-2" {
+		t.Errorf("Token() = %v, %v, want a refreshed token", token, err)
+	}
+}
+
+func TestNewViperConfig_Auth(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantADC bool
+		wantErr bool
+	}{
+		{name: "default"},
+		{name: "adc", env: map[string]string{"DEEPVIZ_AUTH": "adc", "DEEPVIZ_VERTEX_LOCATION": "us-central1"}, wantADC: true},
+		{name: "unknown mode", env: map[string]string{"DEEPVIZ_AUTH": "oauth"}, wantErr: true},
+		{name: "invalid location", env: map[string]string{"DEEPVIZ_AUTH": "adc", "DEEPVIZ_VERTEX_LOCATION": "https://example.com"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"DEEPVIZ_AUTH", "DEEPVIZ_VERTEX_LOCATION"} {
+				t.Setenv(name, tt.env[name])
+			}
+			config, err := NewViperConfig(t.TempDir())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewViperConfig() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err == nil && (config.adc != nil) != tt.wantADC {
+				t.Errorf("auth = %s, credentials = %v, want credentials %t", config.Auth, config.adc, tt.wantADC)
+			}
+		})
+	}
+}
+
+func TestGenaiImageClient_Generate_Vertex(t *testing.T) {
+	var request struct {
+		Contents []struct {
+			Role string `json:"role"`
+		} `json:"contents"`
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want := "/v1/projects/my-project/locations/global/publishers/google/models/gemini-3-pro-image-preview:generateContent"; r.URL.Path != want {
+			t.Errorf("path = %s, want %s", r.URL.Path, want)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token-1" {
+			t.Errorf("Authorization = %q, want the access token", got)
+		}
+		if got := r.Header.Get("x-goog-api-key"); got != "" {
+			t.Errorf("x-goog-api-key = %q, want none", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, imageResponseJSON(testImageData))
+	})
+	config := &ViperConfig{OutputDir: t.TempDir(), Auth: AuthADC, VertexLocation: "global", adc: newTestADC("my-project", &countingTokenSource{validity: time.Hour}, nil)}
+	client := newTestImageClient(t, handler, config)
+
+	imageConfig := ImageConfig{Model: "gemini-3-pro-image-preview", AspectRatio: "16:9", ImageSize: "2K"}
+	if _, err := client.Generate(context.Background(), "A sunset", imageConfig, "20251224_103045"); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(request.Contents) != 1 || request.Contents[0].Role != "user" {
+		t.Errorf("contents = %+v, want the user role", request.Contents)
+	}
+}
+
+func TestVertex_UnsupportedClients(t *testing.T) {
+	config := &ViperConfig{Auth: AuthADC, adc: newTestADC("my-project", nil, nil)}
+	if _, err := NewGenaiResearchClient(context.Background(), config, NewNullLogger()); !errors.Is(err, ErrNotSupportedOnVertex) || !strings.Contains(err.Error(), "Deep Research") {
+		t.Errorf("NewGenaiResearchClient() error = %v, want ErrNotSupportedOnVertex", err)
+	}
+	if _, err := NewModelsClient(config, NewNullLogger()); !errors.Is(err, ErrNotSupportedOnVertex) {
+		t.Errorf("NewModelsClient() error = %v, want ErrNotSupportedOnVertex", err)
+	}
+	if _, err := NewInteractionsClient(config, NewNullLogger()); !errors.Is(err, ErrNotSupportedOnVertex) {
+		t.Errorf("NewInteractionsClient() error = %v, want ErrNotSupportedOnVertex", err)
+	}
+}
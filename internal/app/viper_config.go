@@ -1,35 +1,195 @@
 package app
 
 import (
+	"crypto/x509"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
+// Output directory layouts.
+const (
+	LayoutByType = "by-type" // research/, images/, responses/, ... directories shared by all runs
+	LayoutPerRun = "per-run" // A runs/<timestamp>/ directory per run
+)
+
 // ViperConfig holds application configuration using Viper.
 type ViperConfig struct {
 	// OutputDir is the base path for output directory
 	OutputDir string
+	// Layout arranges the output directory by artifact type (by-type) or by run (per-run)
+	Layout string
+	// FilenameStyle names run artifacts after the timestamp, a slug of the prompt, or both
+	FilenameStyle string
+	// OutputTemplate is a text/template of the artifact directories relative to OutputDir (empty uses Layout)
+	OutputTemplate string
+	// TimestampFormat is the Go time layout of run timestamps
+	TimestampFormat string
+	// TimestampUTC generates run timestamps in UTC instead of the local time
+	TimestampUTC bool
+	// Overwrite replaces existing output files instead of saving under a new name ("-1", "-2", ...)
+	Overwrite bool
+	// StateDir is the directory for run state (XDG_STATE_HOME compliant)
+	StateDir string
 	// APIKey is the Gemini API key
 	APIKey string
+	// APIKeyFile is a file holding the API key (e.g., a mounted secret), read when no API key
+	// environment variable is set
+	APIKeyFile string
+	// APIKeyCmd is a shell command printing the API key (e.g., `pass show gemini`), run when no API
+	// key environment variable or APIKeyFile is set
+	APIKeyCmd string
+	// APIKeys are the API keys of api_keys when it has several, switched to the next one when a
+	// key hits its quota (APIKey is the first one)
+	APIKeys []string
+	// Auth is the authentication of the API requests: api_key (Gemini API) or adc (Vertex AI with
+	// Application Default Credentials)
+	Auth string
+	// VertexProject is the Google Cloud project of the Vertex AI requests (empty uses the project of
+	// GOOGLE_CLOUD_PROJECT or of the credentials)
+	VertexProject string
+	// VertexLocation is the location of the Vertex AI requests (e.g., global, us-central1)
+	VertexLocation string
+	// UseKeyring reads the API key from the keyring of the OS when no other API key is set (see
+	// `deepviz config set-key`)
+	UseKeyring bool
 	// DeepResearchAgent is the Deep Research API agent name
 	DeepResearchAgent string
+	// ThinkingSummaries controls the thinking summaries of the research agent (auto or none)
+	ThinkingSummaries string
+	// ResearchTools are the tools given to the research agent (empty uses google_search and url_context)
+	ResearchTools []string
+	// NoResearchTools runs the research agent without any tools (set by --no-tools)
+	NoResearchTools bool
 	// PollInterval is the polling interval in seconds
 	PollInterval int
+	// PollMaxInterval is the maximum polling interval in seconds when backing off
+	PollMaxInterval int
 	// PollTimeout is the polling timeout in seconds
 	PollTimeout int
+	// PollMaxFailures is the number of consecutive transient polling errors tolerated before giving up
+	PollMaxFailures int
+	// KeepOnFailure keeps the server-side research instead of cancelling it when the pipeline fails
+	KeepOnFailure bool
+	// AppendSources appends a "Sources" section to the saved research markdown
+	AppendSources bool
+	// MarkdownFrontMatter writes YAML front matter with the run metadata at the top of saved research markdown
+	MarkdownFrontMatter bool
+	// SavePollSnapshots saves every polled interaction body to the responses directory
+	SavePollSnapshots bool
+	// SaveHTTPExchange saves every API request and response body of a run with an index
+	SaveHTTPExchange bool
+	// NoSaveResponses skips saving the raw research and image responses (save_responses: false)
+	NoSaveResponses bool
+	// MaxRetries is the number of retries for API requests failing with 429/503
+	MaxRetries int
+	// RetryMaxWait is the maximum total wait between retries in seconds
+	RetryMaxWait int
 	// Model is the image generation model name
 	Model string
+	// FallbackModel is the image generation model used when Model fails (empty disables the fallback)
+	FallbackModel string
 	// AspectRatio is the aspect ratio for image generation
 	AspectRatio string
 	// ImageSize is the image size for generation
 	ImageSize string
 	// ImageLang is the language for image generation (e.g., "Japanese", "English", "French")
 	ImageLang string
+	// ImageCount is the number of image variants generated from the same prompt
+	ImageCount int
+	// ImageRetries is the number of retries when the image response contains no image
+	ImageRetries int
+	// ImageFormat is the format generated images are converted to ("png", "jpeg" or "webp", empty keeps the API format)
+	ImageFormat string
+	// ImageQuality is the quality (1-100) used when converting images to a lossy format
+	ImageQuality int
+	// KeepOriginal keeps the image as returned by the API when converting it
+	KeepOriginal bool
+	// Seed is the sampling seed of image requests (nil leaves it to the API)
+	Seed *int
+	// Temperature is the sampling temperature (0-2) of image requests (nil leaves it to the API)
+	Temperature *float64
+	// TopP is the nucleus sampling probability (0-1) of image requests (nil leaves it to the API)
+	TopP *float64
+	// TopK is the top-k sampling size of image requests (nil leaves it to the API)
+	TopK *int
+	// SafetySettings are the safety thresholds of image requests (empty uses the API defaults)
+	SafetySettings []SafetySetting
+	// GenerationConfig holds overrides deep-merged over the generationConfig of image requests
+	// (generation_config: a YAML map or a JSON object)
+	GenerationConfig map[string]any
+	// SaveCaptions saves the text parts of the image response next to the image
+	SaveCaptions bool
+	// ImagePromptTemplate is a text/template for the image prompt (empty uses the built-in template)
+	ImagePromptTemplate string
+	// Style is the style preset appended to infographic prompts (empty uses none)
+	Style string
+	// Styles are user-defined style presets (name -> instructions), overriding built-in ones
+	Styles map[string]string
+	// ImageSystemInstruction is a system instruction sent with every image request (empty sends none)
+	ImageSystemInstruction string
 	// AutoOpen enables automatic opening of generated images
 	AutoOpen bool
+	// ReportTemplate is the path of an html/template file for run reports (empty uses the built-in template)
+	ReportTemplate string
+	// PDFConverter is the wkhtmltopdf or Chromium executable used by --export pdf (empty detects one)
+	PDFConverter string
+	// ServeToken is the bearer token required by `deepviz serve` (empty accepts every request)
+	ServeToken string
+	// ServeConcurrency is the number of pipelines `deepviz serve` runs at a time
+	ServeConcurrency int
+	// LogOutput is the destination of the console log: stderr, stdout or file-only
+	LogOutput string
+	// LogFormat is the format of the console log: text or json (empty selects text on a terminal)
+	LogFormat string
+	// LogTraceBodies persists TRACE logs (raw HTTP bodies, which can be huge) in the log file
+	LogTraceBodies bool
+	// LogRetentionDays removes log files older than this many days (0 keeps them)
+	LogRetentionDays int
+	// LogMaxFiles keeps only the most recent log files (0 for no limit)
+	LogMaxFiles int
+	// LogMaxSizeMB compresses log files larger than this many megabytes to .gz (0 for no limit)
+	LogMaxSizeMB int
+	// CLILang is the language of the CLI messages: en or ja (empty detects it from LANG)
+	CLILang string
+	// ProxyURL is the proxy of the API requests (empty uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY)
+	ProxyURL string
+	// CACertFile is a PEM bundle of CA certificates trusted by the API requests in addition to the
+	// system ones (empty trusts the system ones only)
+	CACertFile string
+	// InsecureSkipVerify disables the certificate verification of the API requests
+	InsecureSkipVerify bool
+	// ImageTimeout is the timeout of each image generation request in seconds
+	ImageTimeout int
+	// ResearchTimeout is the timeout of each Deep Research request in seconds (0 for no timeout)
+	ResearchTimeout int
+	// RequestTimeout is the timeout of each other API request in seconds (models, interactions)
+	RequestTimeout int
+	// ConnectTimeout is the timeout of establishing a connection to the API in seconds
+	ConnectTimeout int
+	// UserAgentSuffix is appended to the User-Agent of the API requests (e.g., the name of an
+	// application embedding deepviz)
+	UserAgentSuffix string
+
+	apiKeyLoader   *apiKeyLoader      // Resolves APIKey when a command needs it (see loadAPIKey)
+	apiKeySource   string             // Where APIKey comes from, for `config show`
+	apiKeyPool     *APIKeyPool        // Keys of APIKeys in use, shared by the runs of a batch
+	apiKeyRotation *apiKeyRotation    // Switches the run to the next key of apiKeyPool on quota errors
+	adc            *adcCredentials    // Credentials of the Vertex AI requests with auth: adc
+	outputTemplate *template.Template // Parsed OutputTemplate
+	runSlug        string             // Slug of the prompt of the run, for OutputTemplate
+	runID          string             // ID of the run, sent with its API requests (see RunIDHeader)
+	httpCapture    *HTTPCapture       // Captures the API exchanges of the run (SaveHTTPExchange)
+	cassette       *Cassette          // Records or replays the API exchanges of the run (--record, --replay)
+	proxyURL       *url.URL           // Parsed ProxyURL
+	rootCAs        *x509.CertPool     // System certificates and those of CACertFile
+	transport      *apiTransport      // Transport shared by the API clients (see sharedTransport)
 
 	configDir string
 	v         *viper.Viper
@@ -47,29 +207,7 @@ func NewViperConfig(configDir string) (*ViperConfig, error) {
 	// Create a new Viper instance (avoid global state)
 	v := viper.New()
 
-	// Set default output directory (XDG Base Directory compliant)
-	defaultOutputDir := "/tmp/deepviz-output"
-	xdgDataHome := os.Getenv("XDG_DATA_HOME")
-	if xdgDataHome == "" {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			xdgDataHome = filepath.Join(home, ".local", "share")
-		}
-	}
-	if xdgDataHome != "" {
-		defaultOutputDir = filepath.Join(xdgDataHome, "deepviz")
-	}
-
-	// Set default values
-	v.SetDefault("output_dir", defaultOutputDir)
-	v.SetDefault("deep_research_agent", "deep-research-pro-preview-12-2025")
-	v.SetDefault("poll_interval", 10)
-	v.SetDefault("poll_timeout", 600)
-	v.SetDefault("model", "gemini-3-pro-image-preview")
-	v.SetDefault("aspect_ratio", "16:9")
-	v.SetDefault("image_size", "2K")
-	v.SetDefault("image_lang", "Japanese")
-	v.SetDefault("auto_open", true)
+	setViperDefaults(v)
 
 	// Set environment variable prefix
 	v.SetEnvPrefix("DEEPVIZ")
@@ -77,16 +215,11 @@ func NewViperConfig(configDir string) (*ViperConfig, error) {
 
 	// Determine config file directory (XDG Base Directory compliant)
 	if configDir == "" {
-		// Use XDG_CONFIG_HOME if set, otherwise default to ~/.config
-		xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
-		if xdgConfigHome == "" {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				return nil, fmt.Errorf("failed to get home directory: %w", err)
-			}
-			xdgConfigHome = filepath.Join(home, ".config")
+		dir, err := defaultConfigDir()
+		if err != nil {
+			return nil, err
 		}
-		configDir = filepath.Join(xdgConfigHome, "deepviz")
+		configDir = dir
 	}
 
 	// Load config file
@@ -101,16 +234,6 @@ func NewViperConfig(configDir string) (*ViperConfig, error) {
 		}
 	}
 
-	// Map configuration to struct
-	// Priority: DEEPVIZ_API_KEY (env) > GEMINI_API_KEY (env) > config file
-	apiKey := os.Getenv("DEEPVIZ_API_KEY")
-	if apiKey == "" {
-		apiKey = os.Getenv("GEMINI_API_KEY")
-	}
-	if apiKey == "" {
-		apiKey = v.GetString("api_key")
-	}
-
 	// Priority: DEEPVIZ_MODEL (env) > GEMINI_MODEL (env) > config file
 	model := os.Getenv("DEEPVIZ_MODEL")
 	if model == "" {
@@ -129,24 +252,377 @@ func NewViperConfig(configDir string) (*ViperConfig, error) {
 		deepResearchAgent = v.GetString("deep_research_agent")
 	}
 
+	config, err := newConfigFromViper(v, configDir, model, deepResearchAgent)
+	if err != nil {
+		return nil, err
+	}
+	// Priority: DEEPVIZ_API_KEY (env) > GEMINI_API_KEY (env) > api_key_file > api_key_cmd > config file (api_keys, api_key) > keyring
+	// The key is resolved when a command needs it (see loadAPIKey)
+	config.apiKeyLoader = &apiKeyLoader{v: v, keyring: OSKeyring}
+	return config, nil
+}
+
+// defaultConfigDir returns the directory of the config file: $XDG_CONFIG_HOME/deepviz, or
+// ~/.config/deepviz when XDG_CONFIG_HOME is not set.
+func defaultConfigDir() (string, error) {
+	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfigHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		xdgConfigHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(xdgConfigHome, "deepviz"), nil
+}
+
+// loadCLILang returns the cli_lang of the environment (DEEPVIZ_CLI_LANG) or of the config file in
+// configDir (the default directory when empty). Only cli_lang is read, without resolving the API
+// key, so that the command descriptions can be translated before the commands are built. An
+// unreadable config file or an invalid value returns "" (detecting the language from the locale):
+// they are reported by the commands.
+func loadCLILang(configDir string) string {
+	v := viper.New()
+	v.SetEnvPrefix("DEEPVIZ")
+	v.AutomaticEnv()
+	if configDir == "" {
+		dir, err := defaultConfigDir()
+		if err != nil {
+			return ""
+		}
+		configDir = dir
+	}
+	v.SetConfigName("config")
+	v.SetConfigType("yaml")
+	v.AddConfigPath(configDir)
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return ""
+		}
+	}
+	lang, err := ParseCLILang(v.GetString("cli_lang"))
+	if err != nil {
+		return ""
+	}
+	return lang
+}
+
+// newConfigFromViper maps the values of v to a ViperConfig, without the API key (see loadAPIKey).
+// The model and agent are resolved by the caller, which reads them from several environment
+// variables.
+func newConfigFromViper(v *viper.Viper, configDir, model, deepResearchAgent string) (*ViperConfig, error) {
+	// A YAML list and a comma-separated string are both accepted
+	researchTools, err := ParseResearchTools(strings.Join(v.GetStringSlice("research_tools"), ","))
+	if err != nil {
+		return nil, fmt.Errorf("invalid research_tools: %w", err)
+	}
+
+	thinkingSummaries, err := ParseThinkingSummaries(v.GetString("thinking_summaries"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid thinking_summaries: %w", err)
+	}
+
+	logOutput, err := ParseLogOutput(v.GetString("log_output"))
+	if err != nil {
+		return nil, err
+	}
+	logFormat, err := ParseLogFormat(v.GetString("log_format"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range []string{"log_retention_days", "log_max_files", "log_max_size_mb"} {
+		if v.GetInt(key) < 0 {
+			return nil, fmt.Errorf("invalid %s %d: must not be negative", key, v.GetInt(key))
+		}
+	}
+
+	cliLang, err := ParseCLILang(v.GetString("cli_lang"))
+	if err != nil {
+		return nil, err
+	}
+
+	layout := v.GetString("layout")
+	if layout != LayoutByType && layout != LayoutPerRun {
+		return nil, fmt.Errorf("invalid layout %q: must be %s or %s", layout, LayoutByType, LayoutPerRun)
+	}
+
+	filenameStyle, err := ParseFilenameStyle(v.GetString("filename_style"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateTimestampFormat(v.GetString("timestamp_format")); err != nil {
+		return nil, err
+	}
+
+	var outputTemplate *template.Template
+	if text := v.GetString("output_template"); text != "" {
+		if outputTemplate, err = ParseOutputTemplate(text); err != nil {
+			return nil, fmt.Errorf("invalid output_template: %w", err)
+		}
+	}
+
+	auth, err := ParseAuthMode(v.GetString("auth"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth: %w", err)
+	}
+	vertexLocation := v.GetString("vertex_location")
+	if !vertexLocationPattern.MatchString(vertexLocation) {
+		return nil, fmt.Errorf("invalid vertex_location %q: must be a location such as global or us-central1", vertexLocation)
+	}
+	var adc *adcCredentials
+	if auth == AuthADC {
+		adc = newADCCredentials()
+	}
+
+	var proxyURL *url.URL
+	if text := v.GetString("proxy_url"); text != "" {
+		if proxyURL, err = ParseProxyURL(text); err != nil {
+			return nil, fmt.Errorf("invalid proxy_url: %w", err)
+		}
+	}
+
+	for _, key := range []string{"image_timeout", "request_timeout", "connect_timeout"} {
+		if seconds := v.GetInt(key); seconds <= 0 {
+			return nil, fmt.Errorf("invalid %s %d: must be a positive number of seconds", key, seconds)
+		}
+	}
+	if seconds := v.GetInt("research_timeout"); seconds < 0 {
+		return nil, fmt.Errorf("invalid research_timeout %d: must be 0 (no timeout) or a positive number of seconds", seconds)
+	}
+
+	if err := ValidateUserAgentSuffix(v.GetString("user_agent_suffix")); err != nil {
+		return nil, err
+	}
+
+	var rootCAs *x509.CertPool
+	if path := v.GetString("ca_cert_file"); path != "" {
+		if rootCAs, err = LoadCACertFile(path); err != nil {
+			return nil, fmt.Errorf("invalid ca_cert_file: %w", err)
+		}
+	}
+
+	generationConfig, err := generationConfigFromViper(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var safetySettings []SafetySetting
+	if err := v.UnmarshalKey("safety_settings", &safetySettings); err != nil {
+		return nil, fmt.Errorf("invalid safety_settings: %w", err)
+	}
+	if err := ValidateSafetySettings(safetySettings); err != nil {
+		return nil, fmt.Errorf("invalid safety_settings: %w", err)
+	}
+
+	// Sampling parameters are optional and only sent when set
+	var seed, topK *int
+	var temperature, topP *float64
+	if v.IsSet("seed") {
+		seed = ptr(v.GetInt("seed"))
+	}
+	if v.IsSet("temperature") {
+		temperature = ptr(v.GetFloat64("temperature"))
+	}
+	if v.IsSet("top_p") {
+		topP = ptr(v.GetFloat64("top_p"))
+	}
+	if v.IsSet("top_k") {
+		topK = ptr(v.GetInt("top_k"))
+	}
+
 	config := &ViperConfig{
-		OutputDir:         v.GetString("output_dir"),
-		APIKey:            apiKey,
-		DeepResearchAgent: deepResearchAgent,
-		PollInterval:      v.GetInt("poll_interval"),
-		PollTimeout:       v.GetInt("poll_timeout"),
-		Model:             model,
-		AspectRatio:       v.GetString("aspect_ratio"),
-		ImageSize:         v.GetString("image_size"),
-		ImageLang:         v.GetString("image_lang"),
-		AutoOpen:          v.GetBool("auto_open"),
-		configDir:         configDir,
-		v:                 v,
+		OutputDir:              v.GetString("output_dir"),
+		Layout:                 layout,
+		FilenameStyle:          filenameStyle,
+		OutputTemplate:         v.GetString("output_template"),
+		outputTemplate:         outputTemplate,
+		TimestampFormat:        v.GetString("timestamp_format"),
+		TimestampUTC:           v.GetBool("timestamp_utc"),
+		Overwrite:              v.GetBool("overwrite"),
+		StateDir:               v.GetString("state_dir"),
+		APIKeyFile:             v.GetString("api_key_file"),
+		APIKeyCmd:              v.GetString("api_key_cmd"),
+		UseKeyring:             v.GetBool("use_keyring"),
+		Auth:                   auth,
+		VertexProject:          v.GetString("vertex_project"),
+		VertexLocation:         vertexLocation,
+		adc:                    adc,
+		DeepResearchAgent:      deepResearchAgent,
+		ResearchTools:          researchTools,
+		ThinkingSummaries:      thinkingSummaries,
+		PollInterval:           v.GetInt("poll_interval"),
+		PollMaxInterval:        v.GetInt("poll_max_interval"),
+		PollTimeout:            v.GetInt("poll_timeout"),
+		PollMaxFailures:        v.GetInt("poll_max_failures"),
+		KeepOnFailure:          v.GetBool("keep_on_failure"),
+		SavePollSnapshots:      v.GetBool("save_poll_snapshots"),
+		SaveHTTPExchange:       v.GetBool("save_http_exchange"),
+		NoSaveResponses:        !v.GetBool("save_responses"),
+		AppendSources:          v.GetBool("append_sources"),
+		MarkdownFrontMatter:    v.GetBool("markdown_front_matter"),
+		MaxRetries:             v.GetInt("max_retries"),
+		RetryMaxWait:           v.GetInt("retry_max_wait"),
+		Model:                  model,
+		FallbackModel:          v.GetString("fallback_model"),
+		AspectRatio:            v.GetString("aspect_ratio"),
+		ImageSize:              v.GetString("image_size"),
+		ImageLang:              v.GetString("image_lang"),
+		ImageCount:             v.GetInt("image_count"),
+		ImageRetries:           v.GetInt("image_retries"),
+		ImageFormat:            v.GetString("image_format"),
+		ImageQuality:           v.GetInt("image_quality"),
+		KeepOriginal:           v.GetBool("keep_original"),
+		Seed:                   seed,
+		Temperature:            temperature,
+		TopP:                   topP,
+		TopK:                   topK,
+		SafetySettings:         safetySettings,
+		GenerationConfig:       generationConfig,
+		SaveCaptions:           v.GetBool("save_captions"),
+		ImagePromptTemplate:    v.GetString("image_prompt_template"),
+		ImageSystemInstruction: v.GetString("image_system_instruction"),
+		Style:                  v.GetString("style"),
+		Styles:                 v.GetStringMapString("styles"),
+		AutoOpen:               v.GetBool("auto_open"),
+		ReportTemplate:         v.GetString("report_template"),
+		PDFConverter:           v.GetString("pdf_converter"),
+		ServeToken:             v.GetString("serve_token"),
+		ServeConcurrency:       v.GetInt("serve_concurrency"),
+		LogOutput:              logOutput,
+		LogFormat:              logFormat,
+		LogTraceBodies:         v.GetBool("log_trace_bodies"),
+		LogRetentionDays:       v.GetInt("log_retention_days"),
+		LogMaxFiles:            v.GetInt("log_max_files"),
+		LogMaxSizeMB:           v.GetInt("log_max_size_mb"),
+		CLILang:                cliLang,
+		ProxyURL:               v.GetString("proxy_url"),
+		proxyURL:               proxyURL,
+		CACertFile:             v.GetString("ca_cert_file"),
+		InsecureSkipVerify:     v.GetBool("insecure_skip_verify"),
+		rootCAs:                rootCAs,
+		ImageTimeout:           v.GetInt("image_timeout"),
+		ResearchTimeout:        v.GetInt("research_timeout"),
+		RequestTimeout:         v.GetInt("request_timeout"),
+		ConnectTimeout:         v.GetInt("connect_timeout"),
+		UserAgentSuffix:        v.GetString("user_agent_suffix"),
+		configDir:              configDir,
+		v:                      v,
 	}
+	config.transport = newAPITransport(config)
 
 	return config, nil
 }
 
+// NewDefaultConfig creates a ViperConfig with the default values only, ignoring the environment
+// variables and the config file (e.g., to run the pipeline from a program).
+func NewDefaultConfig() (*ViperConfig, error) {
+	v := viper.New()
+	setViperDefaults(v)
+	return newConfigFromViper(v, "", v.GetString("model"), v.GetString("deep_research_agent"))
+}
+
+// defaultOutputDir returns the default output directory (XDG Base Directory compliant):
+// $XDG_DATA_HOME/deepviz, ~/.local/share/deepviz without XDG_DATA_HOME, or /tmp/deepviz-output
+// without a home directory.
+func defaultOutputDir() string {
+	xdgDataHome := os.Getenv("XDG_DATA_HOME")
+	if xdgDataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "/tmp/deepviz-output"
+		}
+		xdgDataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(xdgDataHome, "deepviz")
+}
+
+// defaultStateDir returns the default state directory (XDG Base Directory compliant):
+// $XDG_STATE_HOME/deepviz, ~/.local/state/deepviz without XDG_STATE_HOME, or deepviz-state in the
+// temporary directory without a home directory.
+func defaultStateDir() string {
+	xdgStateHome := os.Getenv("XDG_STATE_HOME")
+	if xdgStateHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(os.TempDir(), "deepviz-state")
+		}
+		xdgStateHome = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(xdgStateHome, "deepviz")
+}
+
+// setViperDefaults sets the default configuration values.
+func setViperDefaults(v *viper.Viper) {
+	// Set default values
+	v.SetDefault("output_dir", defaultOutputDir())
+	v.SetDefault("layout", LayoutByType)
+	v.SetDefault("filename_style", FilenameStyleTimestamp)
+	v.SetDefault("output_template", "")
+	v.SetDefault("timestamp_format", timestampLayout)
+	v.SetDefault("timestamp_utc", false)
+	v.SetDefault("overwrite", false)
+	v.SetDefault("state_dir", defaultStateDir())
+	v.SetDefault("api_key_file", "")
+	v.SetDefault("api_key_cmd", "")
+	v.SetDefault("api_keys", "")
+	v.SetDefault("use_keyring", false)
+	v.SetDefault("auth", AuthAPIKey)
+	v.SetDefault("vertex_project", "")
+	v.SetDefault("vertex_location", "global")
+	v.SetDefault("deep_research_agent", "deep-research-pro-preview-12-2025")
+	v.SetDefault("research_tools", "google_search,url_context")
+	v.SetDefault("thinking_summaries", "auto")
+	v.SetDefault("poll_interval", 10)
+	v.SetDefault("poll_max_interval", 60)
+	v.SetDefault("poll_timeout", 600)
+	v.SetDefault("poll_max_failures", 5)
+	v.SetDefault("keep_on_failure", false)
+	v.SetDefault("save_poll_snapshots", false)
+	v.SetDefault("save_http_exchange", false)
+	v.SetDefault("save_responses", true)
+	v.SetDefault("append_sources", false)
+	v.SetDefault("markdown_front_matter", true)
+	v.SetDefault("max_retries", 3)
+	v.SetDefault("retry_max_wait", 120)
+	v.SetDefault("model", "gemini-3-pro-image-preview")
+	v.SetDefault("fallback_model", "")
+	v.SetDefault("aspect_ratio", "16:9")
+	v.SetDefault("image_size", "2K")
+	v.SetDefault("image_lang", "Japanese")
+	v.SetDefault("image_count", 1)
+	v.SetDefault("image_retries", 2)
+	v.SetDefault("image_format", "")
+	v.SetDefault("image_quality", 90)
+	v.SetDefault("keep_original", false)
+	v.SetDefault("generation_config", "")
+	v.SetDefault("save_captions", true)
+	v.SetDefault("image_prompt_template", "")
+	v.SetDefault("image_system_instruction", "")
+	v.SetDefault("style", "")
+	v.SetDefault("auto_open", true)
+	v.SetDefault("report_template", "")
+	v.SetDefault("pdf_converter", "")
+	v.SetDefault("serve_token", "")
+	v.SetDefault("serve_concurrency", 1)
+	v.SetDefault("log_output", LogOutputStderr)
+	v.SetDefault("log_format", "")
+	v.SetDefault("log_trace_bodies", false)
+	v.SetDefault("log_retention_days", 0)
+	v.SetDefault("log_max_files", 0)
+	v.SetDefault("log_max_size_mb", 0)
+	v.SetDefault("cli_lang", "")
+	v.SetDefault("proxy_url", "")
+	v.SetDefault("ca_cert_file", "")
+	v.SetDefault("insecure_skip_verify", false)
+	v.SetDefault("image_timeout", 120)
+	v.SetDefault("research_timeout", 0)
+	v.SetDefault("request_timeout", 30)
+	v.SetDefault("connect_timeout", 30)
+	v.SetDefault("user_agent_suffix", "")
+}
+
 // ResearchDir returns the output directory for research results.
 func (c *ViperConfig) ResearchDir() string {
 	return filepath.Join(c.OutputDir, "research")
@@ -162,26 +638,176 @@ func (c *ViperConfig) ResponsesDir() string {
 	return filepath.Join(c.OutputDir, "responses")
 }
 
+// PromptsDir returns the image prompts directory path.
+func (c *ViperConfig) PromptsDir() string {
+	return filepath.Join(c.OutputDir, "prompts")
+}
+
+// ReportsDir returns the batch and run reports directory path.
+func (c *ViperConfig) ReportsDir() string {
+	return filepath.Join(c.OutputDir, "reports")
+}
+
 // LogsDir returns the output directory for logs.
 func (c *ViperConfig) LogsDir() string {
 	return filepath.Join(c.OutputDir, "logs")
 }
 
-// EnsureDirectories ensures all output directories exist.
-func (c *ViperConfig) EnsureDirectories() error {
-	dirs := []string{
-		c.ResearchDir(),
-		c.ImagesDir(),
-		c.ResponsesDir(),
-		c.LogsDir(),
+// RunsStateDir returns the directory for in-flight run records.
+func (c *ViperConfig) RunsStateDir() string {
+	return filepath.Join(c.StateDir, "runs")
+}
+
+// HistoryPath returns the path of the completed run ledger.
+func (c *ViperConfig) HistoryPath() string {
+	return filepath.Join(c.StateDir, "history.jsonl")
+}
+
+// AgentsCachePath returns the path of the cached Deep Research agent list.
+func (c *ViperConfig) AgentsCachePath() string {
+	return filepath.Join(c.StateDir, "agents.json")
+}
+
+// ModelsCachePath returns the path of the cached image model list.
+func (c *ViperConfig) ModelsCachePath() string {
+	return filepath.Join(c.StateDir, "models.json")
+}
+
+// ManifestsDir returns the output directory for run manifests.
+func (c *ViperConfig) ManifestsDir() string {
+	return filepath.Join(c.OutputDir, "manifests")
+}
+
+// RunsDir returns the output directory holding a directory per run in the per-run layout.
+func (c *ViperConfig) RunsDir() string {
+	return filepath.Join(c.OutputDir, "runs")
+}
+
+// RunDir returns the directory of the run at timestamp in the per-run layout.
+func (c *ViperConfig) RunDir(timestamp string) string {
+	return filepath.Join(c.RunsDir(), timestamp)
+}
+
+// artifactPath returns the path of an artifact of a run.
+//
+// name is the run timestamp, optionally followed by a variant suffix (e.g., "_ja", "_2" or "_r1").
+// In the by-type layout the artifact is dir/<name><byTypeTail>; in the per-run layout it is
+// runs/<timestamp>/<perRunBase><suffix><ext>. With an output template, the artifact is
+// <rendered directory>/<name><byTypeTail>, except for manifests which stay in manifests/ so that
+// runs can be listed.
+func (c *ViperConfig) artifactPath(name, dir, byTypeTail, perRunBase, ext string) string {
+	if c.OutputTemplate != "" && dir != "manifests" {
+		if templateDir, err := c.outputTemplateDir(name, dir); err == nil {
+			return filepath.Join(c.OutputDir, templateDir, name+byTypeTail)
+		}
+	}
+	if c.Layout != LayoutPerRun {
+		return filepath.Join(c.OutputDir, dir, name+byTypeTail)
 	}
+	timestamp, suffix := c.Namer().SplitRunName(name)
+	return filepath.Join(c.RunDir(timestamp), perRunBase+suffix+ext)
+}
 
-	for _, dir := range dirs {
-		if err := EnsureDir(dir); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+// outputTemplateDir renders the output template for an artifact of a run in dir.
+//
+// The template is validated when the configuration is loaded; should it still fail for a run, the
+// artifact falls back to the layout.
+func (c *ViperConfig) outputTemplateDir(name, dir string) (string, error) {
+	tmpl := c.outputTemplate
+	if tmpl == nil {
+		var err error
+		if tmpl, err = ParseOutputTemplate(c.OutputTemplate); err != nil {
+			return "", err
 		}
 	}
 
+	namer := c.Namer()
+	timestamp, _ := namer.SplitRunName(name)
+	date, err := namer.ParseTimestamp(timestamp)
+	if err != nil {
+		date = time.Now()
+	}
+	return renderOutputDir(tmpl, newOutputTemplateData(timestamp, date, c.runSlug, dir))
+}
+
+// ResearchPath returns the path of the research markdown of a run.
+func (c *ViperConfig) ResearchPath(name string) string {
+	return c.artifactPath(name, "research", ".md", "research", ".md")
+}
+
+// SourcesPath returns the path of the research sources of a run.
+func (c *ViperConfig) SourcesPath(name string) string {
+	return c.artifactPath(name, "research", ".sources.md", "sources", ".md")
+}
+
+// ResearchResponsePath returns the path of the completed research interaction of a run.
+func (c *ViperConfig) ResearchResponsePath(name string) string {
+	return c.artifactPath(name, "responses", "_research.json", "response_research", ".json")
+}
+
+// PollSnapshotPath returns the path of a polled research interaction of a run (label is "01", ... or "last").
+func (c *ViperConfig) PollSnapshotPath(name, label string) string {
+	return c.artifactPath(name, "responses", "_poll_"+label+".json", "poll_"+label, ".json")
+}
+
+// HTTPExchangeDir returns the directory of the captured API requests and responses of a run.
+func (c *ViperConfig) HTTPExchangeDir(name string) string {
+	return c.artifactPath(name, "responses", "", httpExchangeDirName, "")
+}
+
+// ImagePath returns the path of an image of a run with the extension ext.
+func (c *ViperConfig) ImagePath(name, ext string) string {
+	return c.artifactPath(name, "images", ext, "image", ext)
+}
+
+// CaptionPath returns the path of the text returned with the image of a run.
+func (c *ViperConfig) CaptionPath(name string) string {
+	return c.artifactPath(name, "images", ".caption.md", "image", ".caption.md")
+}
+
+// ImagePromptPath returns the path of the prompt sent to the image generation API for a run.
+func (c *ViperConfig) ImagePromptPath(name string) string {
+	return c.artifactPath(name, "prompts", ".txt", "prompt", ".txt")
+}
+
+// ImageResponsePath returns the path of the image generation response of a run.
+func (c *ViperConfig) ImageResponsePath(name string) string {
+	return c.artifactPath(name, "responses", "_image.json", "response_image", ".json")
+}
+
+// ImageRequestPath returns the path of the image generation request of a run.
+func (c *ViperConfig) ImageRequestPath(name string) string {
+	return c.artifactPath(name, "responses", "_image_request.json", "request_image", ".json")
+}
+
+// LogPath returns the path of the execution log of a run.
+func (c *ViperConfig) LogPath(name string) string {
+	return c.artifactPath(name, "logs", ".log", "run", ".log")
+}
+
+// LogFile returns the log file options of a run.
+func (c *ViperConfig) LogFile(name string) FileLogOptions {
+	return FileLogOptions{Path: c.LogPath(name), Trace: c.LogTraceBodies}
+}
+
+// ReportPath returns the HTML report path of a run.
+func (c *ViperConfig) ReportPath(name string) string {
+	return c.artifactPath(name, "reports", ".html", "report", ".html")
+}
+
+// ManifestPath returns the path of the manifest of a run.
+func (c *ViperConfig) ManifestPath(name string) string {
+	return c.artifactPath(name, "manifests", ".json", "manifest", ".json")
+}
+
+// EnsureDirectories ensures the output directory exists.
+//
+// Artifact directories are created when their first file is written, since they depend on the
+// layout, the output template and the run.
+func (c *ViperConfig) EnsureDirectories() error {
+	if err := EnsureDir(c.OutputDir); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", c.OutputDir, err)
+	}
 	return nil
 }
 
@@ -3,7 +3,9 @@ package app
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestViperConfig_DefaultValues(t *testing.T) {
@@ -36,6 +38,49 @@ func TestViperConfig_DefaultValues(t *testing.T) {
 	if config.PollTimeout != 600 {
 		t.Errorf("PollTimeout = %d, want 600", config.PollTimeout)
 	}
+
+	if config.PollMaxInterval != 60 {
+		t.Errorf("PollMaxInterval = %d, want 60", config.PollMaxInterval)
+	}
+
+	if config.PollMaxFailures != 5 {
+		t.Errorf("PollMaxFailures = %d, want 5", config.PollMaxFailures)
+	}
+}
+
+func TestNewDefaultConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("model: file-model\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("DEEPVIZ_MODEL", "env-model")
+	t.Setenv("DEEPVIZ_API_KEY", "env-key")
+
+	config, err := NewDefaultConfig()
+	if err != nil {
+		t.Fatalf("NewDefaultConfig() error = %v", err)
+	}
+	if config.Model != "gemini-3-pro-image-preview" || config.APIKey != "" || config.PollInterval != 10 {
+		t.Errorf("config = %+v, want the defaults only", config)
+	}
+}
+
+func TestViperConfig_StateDirDefault(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/xdg/state")
+	t.Setenv("DEEPVIZ_STATE_DIR", "")
+
+	config, err := NewViperConfig(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create viper config: %v", err)
+	}
+
+	if want := filepath.Join("/xdg/state", "deepviz"); config.StateDir != want {
+		t.Errorf("StateDir = %s, want %s", config.StateDir, want)
+	}
+	if want := filepath.Join("/xdg/state", "deepviz", "runs"); config.RunsStateDir() != want {
+		t.Errorf("RunsStateDir() = %s, want %s", config.RunsStateDir(), want)
+	}
 }
 
 func TestViperConfig_EnvironmentVariables(t *testing.T) {
@@ -60,6 +105,9 @@ func TestViperConfig_EnvironmentVariables(t *testing.T) {
 		t.Errorf("OutputDir = %s, want /custom/output", config.OutputDir)
 	}
 
+	if err := config.loadAPIKey(); err != nil {
+		t.Fatalf("loadAPIKey() error = %v", err)
+	}
 	if config.APIKey != "test-api-key" {
 		t.Errorf("APIKey = %s, want test-api-key", config.APIKey)
 	}
@@ -92,6 +140,9 @@ poll_timeout: 1200
 		t.Errorf("OutputDir = %s, want /file/output", config.OutputDir)
 	}
 
+	if err := config.loadAPIKey(); err != nil {
+		t.Fatalf("loadAPIKey() error = %v", err)
+	}
 	if config.APIKey != "file-api-key" {
 		t.Errorf("APIKey = %s, want file-api-key", config.APIKey)
 	}
@@ -141,6 +192,9 @@ api_key: file-api-key
 		t.Errorf("OutputDir = %s, want /env/output (env should override file)", config.OutputDir)
 	}
 
+	if err := config.loadAPIKey(); err != nil {
+		t.Fatalf("loadAPIKey() error = %v", err)
+	}
 	if config.APIKey != "env-api-key" {
 		t.Errorf("APIKey = %s, want env-api-key (env should override file)", config.APIKey)
 	}
@@ -182,6 +236,9 @@ func TestViperConfig_Save(t *testing.T) {
 	}
 
 	// Verify saved values are loaded
+	if err := newConfig.loadAPIKey(); err != nil {
+		t.Fatalf("loadAPIKey() error = %v", err)
+	}
 	if newConfig.APIKey != "new-api-key" {
 		t.Errorf("APIKey = %s, want new-api-key", newConfig.APIKey)
 	}
@@ -190,3 +247,537 @@ func TestViperConfig_Save(t *testing.T) {
 		t.Errorf("OutputDir = %s, want /new/output", newConfig.OutputDir)
 	}
 }
+
+func TestViperConfig_GenerationConfig(t *testing.T) {
+	t.Setenv("DEEPVIZ_GENERATION_CONFIG", `{"temperature": 0.5}`)
+	config, err := NewViperConfig(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create viper config: %v", err)
+	}
+	if config.GenerationConfig["temperature"] != 0.5 {
+		t.Errorf("GenerationConfig = %v, want temperature 0.5", config.GenerationConfig)
+	}
+
+	t.Setenv("DEEPVIZ_GENERATION_CONFIG", `["not", "an", "object"]`)
+	if _, err := NewViperConfig(t.TempDir()); err == nil {
+		t.Error("NewViperConfig() should return error for a non-object generation_config")
+	}
+}
+
+func TestViperConfig_Sampling(t *testing.T) {
+	config, err := NewViperConfig(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create viper config: %v", err)
+	}
+	if config.Seed != nil || config.Temperature != nil || config.TopP != nil || config.TopK != nil {
+		t.Error("sampling parameters should be unset by default")
+	}
+
+	t.Setenv("DEEPVIZ_SEED", "42")
+	t.Setenv("DEEPVIZ_TEMPERATURE", "0")
+	config, err = NewViperConfig(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create viper config: %v", err)
+	}
+	if config.Seed == nil || *config.Seed != 42 {
+		t.Errorf("Seed = %v, want 42", config.Seed)
+	}
+	if config.Temperature == nil || *config.Temperature != 0 {
+		t.Errorf("Temperature = %v, want 0", config.Temperature)
+	}
+}
+
+func TestViperConfig_SafetySettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []SafetySetting
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			content: `
+safety_settings:
+  - category: HARM_CATEGORY_DANGEROUS_CONTENT
+    threshold: block_only_high
+`,
+			want: []SafetySetting{{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_ONLY_HIGH"}},
+		},
+		{
+			name: "typo in category",
+			content: `
+safety_settings:
+  - category: HARM_CATEGORY_DANGEROUS
+    threshold: BLOCK_ONLY_HIGH
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			config, err := NewViperConfig(tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewViperConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(config.SafetySettings) != len(tt.want) || config.SafetySettings[0] != tt.want[0] {
+				t.Errorf("SafetySettings = %v, want %v", config.SafetySettings, tt.want)
+			}
+		})
+	}
+}
+
+func TestViperConfig_Styles(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `
+style: brand
+styles:
+  brand: Use the brand colors.
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	config, err := NewViperConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("NewViperConfig() error = %v", err)
+	}
+	if config.Style != "brand" {
+		t.Errorf("Style = %s, want brand", config.Style)
+	}
+	if config.Styles["brand"] != "Use the brand colors." {
+		t.Errorf("Styles = %v, want the brand preset", config.Styles)
+	}
+}
+
+func TestViperConfig_ResearchTools(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{name: "default", content: "", want: []string{"google_search", "url_context"}},
+		{name: "comma-separated", content: "research_tools: google_search,code_execution\n", want: []string{"google_search", "code_execution"}},
+		{name: "list", content: "research_tools:\n  - url_context\n", want: []string{"url_context"}},
+		{name: "unknown tool", content: "research_tools: web_browser\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			config, err := NewViperConfig(tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewViperConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if strings.Join(config.ResearchTools, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ResearchTools = %v, want %v", config.ResearchTools, tt.want)
+			}
+		})
+	}
+}
+
+func TestViperConfig_ThinkingSummaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", content: "", want: "auto"},
+		{name: "off", content: "thinking_summaries: off\n", want: "none"},
+		{name: "invalid", content: "thinking_summaries: detailed\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			config, err := NewViperConfig(tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewViperConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.ThinkingSummaries != tt.want {
+				t.Errorf("ThinkingSummaries = %q, want %q", config.ThinkingSummaries, tt.want)
+			}
+		})
+	}
+}
+
+func TestViperConfig_Layout(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", content: "", want: LayoutByType},
+		{name: "per-run", content: "layout: per-run\n", want: LayoutPerRun},
+		{name: "invalid", content: "layout: flat\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			config, err := NewViperConfig(tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewViperConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.Layout != tt.want {
+				t.Errorf("Layout = %q, want %q", config.Layout, tt.want)
+			}
+		})
+	}
+}
+
+func TestViperConfig_LogOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", content: "", want: LogOutputStderr},
+		{name: "stdout", content: "log_output: stdout\n", want: LogOutputStdout},
+		{name: "file-only", content: "log_output: file-only\n", want: LogOutputFileOnly},
+		{name: "invalid", content: "log_output: syslog\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			config, err := NewViperConfig(tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewViperConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.LogOutput != tt.want {
+				t.Errorf("LogOutput = %q, want %q", config.LogOutput, tt.want)
+			}
+		})
+	}
+}
+
+func TestViperConfig_LogFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "default selects from the console", content: "", want: ""},
+		{name: "text", content: "log_format: text\n", want: LogFormatText},
+		{name: "json", content: "log_format: json\n", want: LogFormatJSON},
+		{name: "invalid", content: "log_format: logfmt\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			config, err := NewViperConfig(tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewViperConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.LogFormat != tt.want {
+				t.Errorf("LogFormat = %q, want %q", config.LogFormat, tt.want)
+			}
+		})
+	}
+}
+
+func TestViperConfig_LogRetention(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := "log_retention_days: 30\nlog_max_files: 100\nlog_max_size_mb: 5\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	config, err := NewViperConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("NewViperConfig() error = %v", err)
+	}
+	want := LogRetention{MaxAge: 30 * 24 * time.Hour, MaxFiles: 100, MaxSize: 5 << 20}
+	if got := config.LogRetention(); got != want {
+		t.Errorf("LogRetention() = %+v, want %+v", got, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte("log_max_files: -1\n"), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	if _, err := NewViperConfig(tmpDir); err == nil {
+		t.Error("NewViperConfig() error = nil, want an error for a negative log_max_files")
+	}
+}
+
+func TestViperConfig_FilenameStyle(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", content: "", want: FilenameStyleTimestamp},
+		{name: "both", content: "filename_style: both\n", want: FilenameStyleBoth},
+		{name: "invalid", content: "filename_style: title\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			config, err := NewViperConfig(tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewViperConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.FilenameStyle != tt.want {
+				t.Errorf("FilenameStyle = %q, want %q", config.FilenameStyle, tt.want)
+			}
+		})
+	}
+}
+
+func TestViperConfig_TimestampFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantUTC bool
+		wantErr bool
+	}{
+		{name: "default", content: "", want: timestampLayout},
+		{name: "utc iso-like", content: "timestamp_format: 20060102T150405Z\ntimestamp_utc: true\n", want: "20060102T150405Z", wantUTC: true},
+		{name: "path separator", content: "timestamp_format: 2006/01/02\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			config, err := NewViperConfig(tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewViperConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.TimestampFormat != tt.want || config.TimestampUTC != tt.wantUTC {
+				t.Errorf("TimestampFormat, TimestampUTC = %q, %t, want %q, %t", config.TimestampFormat, config.TimestampUTC, tt.want, tt.wantUTC)
+			}
+		})
+	}
+}
+
+func TestViperConfig_OutputTemplate(t *testing.T) {
+	config := &ViperConfig{
+		OutputDir:      "/out",
+		Layout:         LayoutPerRun,
+		OutputTemplate: "{{.Dir}}/{{.Year}}/{{.Month}}/{{.Slug}}",
+		runSlug:        "go-generics",
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "image", path: config.ImagePath("20250105_143022-3f9c_ja", ".png"), want: "/out/images/2025/01/go-generics/20250105_143022-3f9c_ja.png"},
+		{name: "research", path: config.ResearchPath("20250105_143022"), want: "/out/research/2025/01/go-generics/20250105_143022.md"},
+		{name: "log", path: config.LogPath("20250105_143022"), want: "/out/logs/2025/01/go-generics/20250105_143022.log"},
+		{name: "manifest stays in place", path: config.ManifestPath("20250105_143022"), want: "/out/runs/20250105_143022/manifest.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.path != filepath.FromSlash(tt.want) {
+				t.Errorf("path = %s, want %s", tt.path, tt.want)
+			}
+		})
+	}
+
+	// An invalid template is rejected when the configuration is loaded
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte("output_template: \"../{{.Kind}}\"\n"), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	if _, err := NewViperConfig(tmpDir); err == nil {
+		t.Error("NewViperConfig() should reject an output template escaping the output directory")
+	}
+}
+
+func TestViperConfig_ArtifactPaths(t *testing.T) {
+	byType := &ViperConfig{OutputDir: "/out", Layout: LayoutByType}
+	perRun := &ViperConfig{OutputDir: "/out", Layout: LayoutPerRun}
+
+	tests := []struct {
+		name       string
+		path       func(c *ViperConfig) string
+		wantByType string
+		wantPerRun string
+	}{
+		{
+			name:       "research",
+			path:       func(c *ViperConfig) string { return c.ResearchPath("20251224_103045") },
+			wantByType: "/out/research/20251224_103045.md",
+			wantPerRun: "/out/runs/20251224_103045/research.md",
+		},
+		{
+			name:       "sources",
+			path:       func(c *ViperConfig) string { return c.SourcesPath("20251224_103045") },
+			wantByType: "/out/research/20251224_103045.sources.md",
+			wantPerRun: "/out/runs/20251224_103045/sources.md",
+		},
+		{
+			name:       "research response",
+			path:       func(c *ViperConfig) string { return c.ResearchResponsePath("20251224_103045") },
+			wantByType: "/out/responses/20251224_103045_research.json",
+			wantPerRun: "/out/runs/20251224_103045/response_research.json",
+		},
+		{
+			name:       "poll snapshot",
+			path:       func(c *ViperConfig) string { return c.PollSnapshotPath("20251224_103045", "03") },
+			wantByType: "/out/responses/20251224_103045_poll_03.json",
+			wantPerRun: "/out/runs/20251224_103045/poll_03.json",
+		},
+		{
+			name:       "image",
+			path:       func(c *ViperConfig) string { return c.ImagePath("20251224_103045", ".png") },
+			wantByType: "/out/images/20251224_103045.png",
+			wantPerRun: "/out/runs/20251224_103045/image.png",
+		},
+		{
+			name:       "image variant",
+			path:       func(c *ViperConfig) string { return c.ImagePath("20251224_103045_ja_2", ".jpg") },
+			wantByType: "/out/images/20251224_103045_ja_2.jpg",
+			wantPerRun: "/out/runs/20251224_103045/image_ja_2.jpg",
+		},
+		{
+			name:       "caption",
+			path:       func(c *ViperConfig) string { return c.CaptionPath("20251224_103045") },
+			wantByType: "/out/images/20251224_103045.caption.md",
+			wantPerRun: "/out/runs/20251224_103045/image.caption.md",
+		},
+		{
+			name:       "image prompt",
+			path:       func(c *ViperConfig) string { return c.ImagePromptPath("20251224_103045_r1") },
+			wantByType: "/out/prompts/20251224_103045_r1.txt",
+			wantPerRun: "/out/runs/20251224_103045/prompt_r1.txt",
+		},
+		{
+			name:       "image response",
+			path:       func(c *ViperConfig) string { return c.ImageResponsePath("20251224_103045") },
+			wantByType: "/out/responses/20251224_103045_image.json",
+			wantPerRun: "/out/runs/20251224_103045/response_image.json",
+		},
+		{
+			name:       "image request",
+			path:       func(c *ViperConfig) string { return c.ImageRequestPath("20251224_103045") },
+			wantByType: "/out/responses/20251224_103045_image_request.json",
+			wantPerRun: "/out/runs/20251224_103045/request_image.json",
+		},
+		{
+			name:       "log of a batch run",
+			path:       func(c *ViperConfig) string { return c.LogPath("20251224_103045-2") },
+			wantByType: "/out/logs/20251224_103045-2.log",
+			wantPerRun: "/out/runs/20251224_103045-2/run.log",
+		},
+		{
+			name:       "manifest",
+			path:       func(c *ViperConfig) string { return c.ManifestPath("20251224_103045") },
+			wantByType: "/out/manifests/20251224_103045.json",
+			wantPerRun: "/out/runs/20251224_103045/manifest.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.path(byType); got != filepath.FromSlash(tt.wantByType) {
+				t.Errorf("by-type path = %s, want %s", got, tt.wantByType)
+			}
+			if got := tt.path(perRun); got != filepath.FromSlash(tt.wantPerRun) {
+				t.Errorf("per-run path = %s, want %s", got, tt.wantPerRun)
+			}
+		})
+	}
+}
+
+func TestViperConfig_SaveResponses(t *testing.T) {
+	for _, tt := range []struct {
+		content string
+		want    bool
+	}{
+		{content: "", want: false},
+		{content: "save_responses: false\n", want: true},
+	} {
+		tmpDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		config, err := NewViperConfig(tmpDir)
+		if err != nil {
+			t.Fatalf("NewViperConfig() error = %v", err)
+		}
+		if config.NoSaveResponses != tt.want {
+			t.Errorf("NoSaveResponses with %q = %v, want %v", tt.content, config.NoSaveResponses, tt.want)
+		}
+	}
+}
+
+func TestNewViperConfig_ResearchStore(t *testing.T) {
+	// research_store is not a setting: the research interactions are always stored
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte("research_store: false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewViperConfig(tmpDir); err != nil {
+		t.Errorf("NewViperConfig() error = %v, want research_store ignored", err)
+	}
+}
@@ -0,0 +1,87 @@
+// Package version holds the build information of deepviz: the version, commit and build date
+// injected with -ldflags by the release builds (see the Makefile), or read from the build
+// information embedded by the Go toolchain otherwise (e.g., go install or go build).
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Injected with -ldflags "-X github.com/yukiyan/deepviz/internal/version.version=... -X ...".
+var (
+	version string // e.g., v0.2.0
+	commit  string // Git commit hash
+	date    string // Build date (RFC 3339)
+)
+
+// devVersion is the version of builds without version information.
+const devVersion = "dev"
+
+// Info is the build information of deepviz.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	return get(version, commit, date, debug.ReadBuildInfo)
+}
+
+// Version returns the version of the running binary ("dev" when unknown).
+func Version() string {
+	return Get().Version
+}
+
+// get returns the build information from the injected values, completed with the build
+// information of readBuildInfo.
+func get(version, commit, date string, readBuildInfo func() (*debug.BuildInfo, bool)) Info {
+	info := Info{Version: version, Commit: commit, Date: date, GoVersion: runtime.Version(), OS: runtime.GOOS, Arch: runtime.GOARCH}
+	if buildInfo, ok := readBuildInfo(); ok {
+		info.GoVersion = buildInfo.GoVersion
+		// go install module@version records the module version; go build records "(devel)"
+		if info.Version == "" && buildInfo.Main.Version != "" && buildInfo.Main.Version != "(devel)" {
+			info.Version = buildInfo.Main.Version
+		}
+		var modified bool
+		for _, setting := range buildInfo.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+	if info.Version == "" {
+		info.Version = devVersion
+	}
+	return info
+}
+
+// String returns the build information on several lines, as printed by deepviz --version.
+func (i Info) String() string {
+	commit, date := i.Commit, i.Date
+	if commit == "" {
+		commit = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return fmt.Sprintf("deepviz %s\n  commit:  %s\n  built:   %s\n  go:      %s\n  os/arch: %s/%s\n", i.Version, commit, date, i.GoVersion, i.OS, i.Arch)
+}
@@ -0,0 +1,86 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+// buildInfo returns a readBuildInfo returning a module version and VCS settings.
+func buildInfo(moduleVersion string, settings map[string]string) func() (*debug.BuildInfo, bool) {
+	return func() (*debug.BuildInfo, bool) {
+		info := &debug.BuildInfo{GoVersion: "go1.25.4", Main: debug.Module{Path: "deepviz", Version: moduleVersion}}
+		for key, value := range settings {
+			info.Settings = append(info.Settings, debug.BuildSetting{Key: key, Value: value})
+		}
+		return info, true
+	}
+}
+
+func noBuildInfo() (*debug.BuildInfo, bool) {
+	return nil, false
+}
+
+func TestGet(t *testing.T) {
+	vcs := map[string]string{"vcs.revision": "2f64c141713bb7b7f7a54bd9ac5461d06e377666", "vcs.time": "2026-10-01T09:30:00Z", "vcs.modified": "false"}
+	tests := []struct {
+		name          string
+		version       string // Injected with -ldflags
+		commit        string
+		date          string
+		readBuildInfo func() (*debug.BuildInfo, bool)
+		want          Info
+	}{
+		{
+			name:    "ldflags",
+			version: "v0.2.0", commit: "abc1234", date: "2026-10-02T00:00:00Z",
+			readBuildInfo: buildInfo("(devel)", vcs),
+			want:          Info{Version: "v0.2.0", Commit: "abc1234", Date: "2026-10-02T00:00:00Z", GoVersion: "go1.25.4"},
+		},
+		{
+			name:          "go install",
+			readBuildInfo: buildInfo("v0.2.0", nil),
+			want:          Info{Version: "v0.2.0", GoVersion: "go1.25.4"},
+		},
+		{
+			name:          "go build",
+			readBuildInfo: buildInfo("(devel)", vcs),
+			want:          Info{Version: "dev", Commit: "2f64c141713bb7b7f7a54bd9ac5461d06e377666", Date: "2026-10-01T09:30:00Z", GoVersion: "go1.25.4"},
+		},
+		{
+			name:          "modified tree",
+			readBuildInfo: buildInfo("(devel)", map[string]string{"vcs.revision": "2f64c14", "vcs.modified": "true"}),
+			want:          Info{Version: "dev", Commit: "2f64c14-dirty", GoVersion: "go1.25.4"},
+		},
+		{
+			name:          "version only from ldflags",
+			version:       "v0.2.0",
+			readBuildInfo: buildInfo("(devel)", vcs),
+			want:          Info{Version: "v0.2.0", Commit: "2f64c141713bb7b7f7a54bd9ac5461d06e377666", Date: "2026-10-01T09:30:00Z", GoVersion: "go1.25.4"},
+		},
+		{
+			name:          "no build information",
+			readBuildInfo: noBuildInfo,
+			want:          Info{Version: "dev", GoVersion: runtime.Version()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.OS, tt.want.Arch = runtime.GOOS, runtime.GOARCH
+			if got := get(tt.version, tt.commit, tt.date, tt.readBuildInfo); got != tt.want {
+				t.Errorf("get() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInfo_String(t *testing.T) {
+	info := Info{Version: "v0.2.0", Commit: "abc1234", GoVersion: "go1.25.4", OS: "linux", Arch: "amd64"}
+	got := info.String()
+	for _, want := range []string{"deepviz v0.2.0\n", "commit:  abc1234", "built:   unknown", "go:      go1.25.4", "os/arch: linux/amd64"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
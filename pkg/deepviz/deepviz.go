@@ -0,0 +1,343 @@
+// Package deepviz runs Gemini Deep Research and generates infographics of its results from Go
+// programs, with the same pipeline as the deepviz command.
+//
+// A Client is created from Options (the API key and where the artifacts go) instead of the
+// command-line flags, environment variables and config file of the command:
+//
+//	client, err := deepviz.New(deepviz.Options{APIKey: apiKey, OutputDir: "out"})
+//	if err != nil {
+//		return err
+//	}
+//	result, err := client.Run(ctx, "Trends in edge AI", nil)
+//
+// # Stability
+//
+// This package is the supported Go API of deepviz; the packages under internal/ are not and change
+// without notice. Until deepviz reaches v1, breaking changes to this package are possible in minor
+// releases and are listed in the release notes. Fields may be added to the option and result
+// structs at any time, so build them with field names. The zero value of an option keeps the
+// default of the deepviz command.
+//
+// The deepviz command itself runs its pipelines through a Client, configured by its flags,
+// environment variables and config file.
+package deepviz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yukiyan/deepviz/internal/app"
+)
+
+// ErrNoAPIKey is returned by New when Options has no API key.
+var ErrNoAPIKey = errors.New("deepviz: no API key")
+
+// Options configures a Client.
+type Options struct {
+	// APIKey is the Gemini API key (required)
+	APIKey string
+	// OutputDir is the directory the artifacts are written to, arranged as by the deepviz command
+	// (research/, images/, manifests/, logs/, ...). Either OutputDir or Sink is required.
+	OutputDir string
+	// Sink receives the artifacts of each run once it ends, instead of OutputDir. They are written
+	// to a temporary directory first, removed afterwards.
+	Sink Sink
+	// StateDir holds the run history and the state of running researches (empty uses the state
+	// directory of the deepviz command, so that `deepviz history` and `deepviz resume` see them)
+	StateDir string
+	// Logger receives the log of each run (nil logs to the log file of the run only)
+	Logger *slog.Logger
+	// UserAgentSuffix is appended to the User-Agent of the API requests, e.g., "myapp/1.2" to
+	// identify the program embedding deepviz in the API logs
+	UserAgentSuffix string
+}
+
+// Sink receives the artifacts of a run: the research markdown and sources, the images, the
+// prompts, the raw responses, the manifest and the log.
+type Sink interface {
+	// WriteArtifact writes an artifact named by its slash-separated path relative to the output
+	// directory (e.g., "images/20251224_103045.png").
+	WriteArtifact(name string, r io.Reader) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(name string, r io.Reader) error
+
+// WriteArtifact calls f(name, r).
+func (f SinkFunc) WriteArtifact(name string, r io.Reader) error {
+	return f(name, r)
+}
+
+// ResearchOptions configures the research of a run.
+type ResearchOptions struct {
+	// Agent is the Deep Research agent (empty uses the default agent)
+	Agent string
+	// Tools are the tools given to the agent: google_search, url_context, code_execution (nil uses
+	// google_search and url_context)
+	Tools []string
+}
+
+// ImageOptions configures the infographics of a run.
+type ImageOptions struct {
+	// Model is the image generation model (empty uses gemini-3-pro-image-preview)
+	Model string
+	// AspectRatio is the aspect ratio of the images (empty uses 16:9)
+	AspectRatio string
+	// ImageSize is the size of the images: 1K, 2K or 4K (empty uses 2K)
+	ImageSize string
+	// Lang is the language of the text in the images (empty uses Japanese)
+	Lang string
+	// Style is a built-in style preset: flat, hand-drawn, corporate or dark (empty uses none)
+	Style string
+	// Count is the number of image variants (0 generates one)
+	Count int
+}
+
+// RunOptions configures a research followed by the infographic of its result.
+type RunOptions struct {
+	// Name names the artifacts of the run (empty names them after the timestamp)
+	Name     string
+	Research ResearchOptions
+	Image    ImageOptions
+}
+
+// RunResult is the outcome of a run. It is also returned with an error, describing how far the
+// run got.
+//
+// Paths are in OutputDir, or the artifact names given to the Sink.
+type RunResult struct {
+	Timestamp        string
+	Name             string // Artifact name of the run
+	RunID            string // ID of the run, sent with its API requests and logged with its logs
+	InteractionID    string // Deep Research interaction (empty when no research ran)
+	Markdown         string // Research result (empty when no research ran)
+	ResearchPath     string
+	ImagePaths       []string
+	ManifestPath     string
+	LogPath          string
+	Duration         time.Duration
+	ResearchDuration time.Duration
+	ImageDuration    time.Duration
+}
+
+// Client runs the deepviz pipeline. It is safe for concurrent use; each call is an independent run.
+type Client struct {
+	opts    Options
+	config  *app.ViperConfig
+	clients *app.PipelineClients // Clients of the pipeline stages (the Gemini API clients when nil)
+}
+
+// New creates a Client.
+func New(opts Options) (*Client, error) {
+	if opts.APIKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	if (opts.OutputDir == "") == (opts.Sink == nil) {
+		return nil, errors.New("deepviz: exactly one of OutputDir and Sink is required")
+	}
+
+	config, err := app.NewDefaultConfig()
+	if err != nil {
+		return nil, fmt.Errorf("deepviz: %w", err)
+	}
+	config.APIKey = opts.APIKey
+	config.AutoOpen = false
+	config.LogOutput = app.LogOutputFileOnly
+	if opts.StateDir != "" {
+		config.StateDir = opts.StateDir
+	}
+	if err := config.SetUserAgentSuffix(opts.UserAgentSuffix); err != nil {
+		return nil, fmt.Errorf("deepviz: %w", err)
+	}
+	return &Client{opts: opts, config: config}, nil
+}
+
+// Research runs a Deep Research of prompt and saves its markdown. opts may be nil.
+func (c *Client) Research(ctx context.Context, prompt string, opts *ResearchOptions) (*RunResult, error) {
+	if opts == nil {
+		opts = &ResearchOptions{}
+	}
+	return c.run(ctx, &RunOptions{Research: *opts}, &app.Options{Prompt: prompt, ResearchOnly: true})
+}
+
+// GenerateImage generates infographics of markdown (e.g., a research result). opts may be nil.
+func (c *Client) GenerateImage(ctx context.Context, markdown string, opts *ImageOptions) (*RunResult, error) {
+	if opts == nil {
+		opts = &ImageOptions{}
+	}
+	return c.run(ctx, &RunOptions{Image: *opts}, &app.Options{Prompt: markdown, ImageOnly: true})
+}
+
+// Run runs a Deep Research of prompt and generates infographics of its result. opts may be nil.
+func (c *Client) Run(ctx context.Context, prompt string, opts *RunOptions) (*RunResult, error) {
+	if opts == nil {
+		opts = &RunOptions{}
+	}
+	return c.run(ctx, opts, &app.Options{Prompt: prompt})
+}
+
+// run executes the pipeline with the options of a call.
+func (c *Client) run(ctx context.Context, opts *RunOptions, pipelineOpts *app.Options) (*RunResult, error) {
+	config, err := c.runConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.opts.Sink != nil {
+		dir, err := os.MkdirTemp("", "deepviz-")
+		if err != nil {
+			return nil, fmt.Errorf("deepviz: failed to create output directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		config.OutputDir = dir
+	}
+
+	pipelineOpts.Name = opts.Name
+	pipelineOpts.Output = config.OutputDir
+	pipelineOpts.Model = config.Model
+	pipelineOpts.AspectRatio = config.AspectRatio
+	pipelineOpts.ImageSize = config.ImageSize
+	pipelineOpts.Count = max(opts.Image.Count, 1)
+	pipelineOpts.Candidates = 1
+	pipelineOpts.Quiet = true
+	pipelineOpts.QuietPoll = true
+	pipelineOpts.NoOpen = true
+	pipelineOpts.Stdout = io.Discard
+
+	pipelineResult, err := c.execute(ctx, pipelineOpts, config)
+	result := newRunResult(pipelineResult)
+	if c.opts.Sink != nil {
+		if sinkErr := writeArtifacts(c.opts.Sink, config.OutputDir, result); sinkErr != nil {
+			err = errors.Join(err, sinkErr)
+		}
+	}
+	return result, err
+}
+
+// execute runs the pipeline of a prompt with the configuration of the run, the stage clients and
+// the logger of the Client. The runs of the deepviz command go through it too.
+func (c *Client) execute(ctx context.Context, pipelineOpts *app.Options, config *app.ViperConfig) (*app.RunResult, error) {
+	if c.clients != nil {
+		pipelineOpts.Clients = c.clients
+	}
+	if c.opts.Logger != nil {
+		pipelineOpts.LogHandler = c.opts.Logger.Handler()
+	}
+	return app.ExecutePipeline(ctx, pipelineOpts, config)
+}
+
+// CommandRunner runs a pipeline of the deepviz command through a Client, configured by the flags,
+// environment variables and config file of the command instead of Options. It is the pipeline
+// runner of cmd/deepviz (see app.WithPipelineRunner) and not meant for other programs.
+func CommandRunner(ctx context.Context, opts *app.Options, config *app.ViperConfig) (*app.RunResult, error) {
+	return (&Client{config: config}).execute(ctx, opts, config)
+}
+
+// runConfig returns the configuration of a run with the options of the call applied.
+func (c *Client) runConfig(opts *RunOptions) (*app.ViperConfig, error) {
+	config := *c.config
+	config.OutputDir = c.opts.OutputDir
+
+	if opts.Research.Agent != "" {
+		config.DeepResearchAgent = opts.Research.Agent
+	}
+	if opts.Research.Tools != nil {
+		tools, err := app.ParseResearchTools(strings.Join(opts.Research.Tools, ","))
+		if err != nil {
+			return nil, fmt.Errorf("deepviz: %w", err)
+		}
+		config.ResearchTools = tools
+	}
+
+	image := opts.Image
+	for _, option := range []struct {
+		value  string
+		target *string
+	}{
+		{image.Model, &config.Model},
+		{image.AspectRatio, &config.AspectRatio},
+		{image.ImageSize, &config.ImageSize},
+		{image.Lang, &config.ImageLang},
+		{image.Style, &config.Style},
+	} {
+		if option.value != "" {
+			*option.target = option.value
+		}
+	}
+	if _, err := app.ResolveStyle(config.Style, config.Styles); err != nil {
+		return nil, fmt.Errorf("deepviz: %w", err)
+	}
+	return &config, nil
+}
+
+// newRunResult converts the result of a pipeline run.
+func newRunResult(r *app.RunResult) *RunResult {
+	result := &RunResult{
+		Timestamp:        r.Timestamp,
+		Name:             r.Name,
+		RunID:            r.RunID,
+		InteractionID:    r.InteractionID,
+		ManifestPath:     r.ManifestPath,
+		LogPath:          r.LogPath,
+		Duration:         r.Duration,
+		ResearchDuration: r.ResearchDuration,
+		ImageDuration:    r.ImageDuration,
+	}
+	if r.Research != nil {
+		result.Markdown, result.ResearchPath = r.Research.Content, r.Research.MarkdownPath
+	}
+	if r.Image != nil {
+		result.ImagePaths = slices.Clone(r.Image.ImagePaths)
+	}
+	return result
+}
+
+// writeArtifacts writes every file of dir to sink and replaces the paths of result with the
+// artifact names.
+func writeArtifacts(sink Sink, dir string, result *RunResult) error {
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := sink.WriteArtifact(artifactName(dir, path), file); err != nil {
+			return fmt.Errorf("deepviz: failed to write artifact %s: %w", artifactName(dir, path), err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, path := range []*string{&result.ResearchPath, &result.ManifestPath, &result.LogPath} {
+		if *path != "" {
+			*path = artifactName(dir, *path)
+		}
+	}
+	for i, path := range result.ImagePaths {
+		result.ImagePaths[i] = artifactName(dir, path)
+	}
+	return nil
+}
+
+// artifactName returns the slash-separated path of a file relative to the output directory.
+func artifactName(dir, path string) string {
+	name, err := filepath.Rel(dir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(name)
+}
@@ -0,0 +1,260 @@
+package deepviz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yukiyan/deepviz/internal/app"
+)
+
+// fakeResearch returns a canned research.
+type fakeResearch struct {
+	hooks app.ResearchHooks
+}
+
+func (f *fakeResearch) Execute(ctx context.Context, prompt, timestamp string) (*app.ResearchResult, error) {
+	f.hooks.OnStarted("v1_fake")
+	return &app.ResearchResult{InteractionID: "v1_fake", Status: "completed", Content: "# Research of " + prompt, MarkdownPath: timestamp + ".md"}, nil
+}
+
+func (f *fakeResearch) Resume(ctx context.Context, interactionID, timestamp string) (*app.ResearchResult, error) {
+	return nil, errors.New("not resumable")
+}
+
+// fakeImage writes one image per variant to the images directory.
+type fakeImage struct {
+	dir     string
+	prompts []string
+}
+
+func (f *fakeImage) BuildLangPrompt(lang, markdown string) (string, error) {
+	return lang + ": " + markdown, nil
+}
+
+func (f *fakeImage) GenerateVariants(ctx context.Context, prompt string, imgConfig app.ImageConfig, timestamp string, count int) (*app.ImageResult, error) {
+	f.prompts = append(f.prompts, prompt)
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return nil, err
+	}
+	result := &app.ImageResult{Model: imgConfig.Model}
+	for i := range count {
+		path := filepath.Join(f.dir, timestamp+"_"+string(rune('1'+i))+".png")
+		if err := os.WriteFile(path, []byte("png"), 0644); err != nil {
+			return nil, err
+		}
+		result.ImagePaths = append(result.ImagePaths, path)
+	}
+	result.ImagePath = result.ImagePaths[0]
+	return result, nil
+}
+
+func (f *fakeImage) GenerateLangs(ctx context.Context, langs, prompts []string, imgConfig app.ImageConfig, timestamp string, count int) (*app.ImageResult, error) {
+	return nil, errors.New("not supported")
+}
+
+// testClient is a Client running the pipeline with fake clients.
+type testClient struct {
+	*Client
+	image   *fakeImage
+	created []string // Stages whose client was created
+}
+
+// newTestClient creates a Client with fake research and image clients.
+func newTestClient(t *testing.T, opts Options) *testClient {
+	t.Helper()
+	opts.APIKey = "test-key"
+	opts.StateDir = t.TempDir()
+	client, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tc := &testClient{Client: client, image: &fakeImage{}}
+	client.clients = &app.PipelineClients{
+		Research: func(ctx context.Context, config *app.ViperConfig, logger app.Logger, hooks app.ResearchHooks) (app.ResearchExecutor, error) {
+			tc.created = append(tc.created, "research")
+			return &fakeResearch{hooks: hooks}, nil
+		},
+		Image: func(ctx context.Context, config *app.ViperConfig, logger app.Logger, progress app.ProgressReporter) (app.ImageGenerator, error) {
+			tc.created = append(tc.created, "image")
+			tc.image.dir = config.ImagesDir()
+			return tc.image, nil
+		},
+	}
+	return tc
+}
+
+func TestNew(t *testing.T) {
+	sink := SinkFunc(func(name string, r io.Reader) error { return nil })
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "output directory", opts: Options{APIKey: "key", OutputDir: "out"}},
+		{name: "sink", opts: Options{APIKey: "key", Sink: sink}},
+		{name: "no destination", opts: Options{APIKey: "key"}, wantErr: true},
+		{name: "both destinations", opts: Options{APIKey: "key", OutputDir: "out", Sink: sink}, wantErr: true},
+		{name: "user agent suffix", opts: Options{APIKey: "key", OutputDir: "out", UserAgentSuffix: "acme-reports/2.1"}},
+		{name: "invalid user agent suffix", opts: Options{APIKey: "key", OutputDir: "out", UserAgentSuffix: "acme\r\n"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opts); (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := New(Options{OutputDir: "out"}); !errors.Is(err, ErrNoAPIKey) {
+		t.Errorf("New() without an API key error = %v, want ErrNoAPIKey", err)
+	}
+}
+
+func TestNew_IgnoresEnvironment(t *testing.T) {
+	t.Setenv("DEEPVIZ_MODEL", "env-model")
+	t.Setenv("DEEPVIZ_AUTO_OPEN", "true")
+	client, err := New(Options{APIKey: "key", OutputDir: "out"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.config.Model == "env-model" || client.config.AutoOpen {
+		t.Errorf("config = %+v, want the defaults without auto-open", client.config)
+	}
+}
+
+func TestClient_Run(t *testing.T) {
+	dir := t.TempDir()
+	var logs bytes.Buffer
+	client := newTestClient(t, Options{OutputDir: dir, Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+
+	result, err := client.Run(context.Background(), "AI trends", &RunOptions{Name: "ai-trends", Image: ImageOptions{Lang: "English", Count: 2}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := strings.Join(client.created, " "); got != "research image" {
+		t.Errorf("created clients = %q, want research image", got)
+	}
+	if result.InteractionID != "v1_fake" || result.Markdown != "# Research of AI trends" || !strings.Contains(result.Name, "ai-trends") || result.RunID == "" {
+		t.Errorf("result = %+v, want the research", result)
+	}
+	if got := client.image.prompts; len(got) != 1 || got[0] != "English: # Research of AI trends" {
+		t.Errorf("image prompts = %q, want the English prompt of the research", got)
+	}
+	if len(result.ImagePaths) != 2 || !strings.HasPrefix(result.ImagePaths[0], dir) {
+		t.Errorf("image paths = %v, want 2 images in %s", result.ImagePaths, dir)
+	}
+	if _, err := os.Stat(result.ManifestPath); err != nil {
+		t.Errorf("manifest: %v", err)
+	}
+	if !strings.Contains(logs.String(), "Pipeline started") {
+		t.Errorf("logs = %q, want the log of the run", logs.String())
+	}
+}
+
+func TestClient_ResearchAndGenerateImage(t *testing.T) {
+	client := newTestClient(t, Options{OutputDir: t.TempDir()})
+
+	research, err := client.Research(context.Background(), "AI trends", nil)
+	if err != nil {
+		t.Fatalf("Research() error = %v", err)
+	}
+	if research.Markdown == "" || research.ImagePaths != nil || research.ImageDuration != 0 {
+		t.Errorf("Research() = %+v, want the research only", research)
+	}
+
+	image, err := client.GenerateImage(context.Background(), research.Markdown, &ImageOptions{Style: "flat"})
+	if err != nil {
+		t.Fatalf("GenerateImage() error = %v", err)
+	}
+	if image.InteractionID != "" || image.Markdown != "" || len(image.ImagePaths) != 1 {
+		t.Errorf("GenerateImage() = %+v, want one image only", image)
+	}
+	if got := strings.Join(client.created, " "); got != "research image" {
+		t.Errorf("created clients = %q, want one of each", got)
+	}
+	if got := client.image.prompts; len(got) != 1 || got[0] != "Japanese: "+research.Markdown {
+		t.Errorf("image prompts = %q, want the prompt of the markdown", got)
+	}
+}
+
+func TestClient_InvalidOptions(t *testing.T) {
+	client := newTestClient(t, Options{OutputDir: t.TempDir()})
+	if _, err := client.GenerateImage(context.Background(), "# Markdown", &ImageOptions{Style: "unknown"}); err == nil {
+		t.Error("GenerateImage() with an unknown style error = nil, want an error")
+	}
+	if _, err := client.Research(context.Background(), "AI trends", &ResearchOptions{Tools: []string{"unknown"}}); err == nil {
+		t.Error("Research() with an unknown tool error = nil, want an error")
+	}
+	if len(client.created) != 0 {
+		t.Errorf("created clients = %v, want none", client.created)
+	}
+}
+
+func TestClient_Sink(t *testing.T) {
+	var mu sync.Mutex
+	artifacts := map[string]string{}
+	sink := SinkFunc(func(name string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		mu.Lock()
+		defer mu.Unlock()
+		artifacts[name] = string(data)
+		return err
+	})
+	client := newTestClient(t, Options{Sink: sink})
+
+	result, err := client.Run(context.Background(), "AI trends", nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// The paths of the result are the artifact names
+	for _, name := range append([]string{result.ManifestPath, result.LogPath}, result.ImagePaths...) {
+		if _, ok := artifacts[name]; !ok || filepath.IsAbs(name) {
+			t.Errorf("artifact %q not written, got %v", name, slices.Sorted(maps.Keys(artifacts)))
+		}
+	}
+	if artifacts[result.ImagePaths[0]] != "png" {
+		t.Errorf("image = %q, want the generated image", artifacts[result.ImagePaths[0]])
+	}
+	if !strings.HasPrefix(result.ImagePaths[0], "images/") {
+		t.Errorf("image name = %q, want a slash-separated name", result.ImagePaths[0])
+	}
+
+	// A failing sink fails the call
+	sinkErr := errors.New("bucket unavailable")
+	client.opts.Sink = SinkFunc(func(name string, r io.Reader) error { return sinkErr })
+	if _, err := client.Run(context.Background(), "AI trends", nil); !errors.Is(err, sinkErr) {
+		t.Errorf("Run() error = %v, want the sink error", err)
+	}
+}
+
+func TestCommandRunsThroughClient(t *testing.T) {
+	dir := t.TempDir()
+	fakes := newTestClient(t, Options{OutputDir: dir})
+	config, err := app.NewDefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.APIKey, config.OutputDir, config.StateDir, config.AutoOpen = "test-key", dir, t.TempDir(), false
+
+	// cmd/deepviz runs the pipelines of the command with CommandRunner
+	opts := &app.Options{Prompt: "AI trends", Output: dir, Model: config.Model, Count: 1, Candidates: 1, Quiet: true, NoOpen: true, Stdout: io.Discard, Clients: fakes.clients, Runner: CommandRunner}
+	result, err := app.RunWithConfig(opts, config)
+	if err != nil {
+		t.Fatalf("RunWithConfig() error = %v", err)
+	}
+	if got := strings.Join(fakes.created, " "); got != "research image" || result.InteractionID != "v1_fake" {
+		t.Errorf("created clients = %q, result = %+v, want the pipeline run", got, result)
+	}
+}
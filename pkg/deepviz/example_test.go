@@ -0,0 +1,71 @@
+package deepviz_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/yukiyan/deepviz/pkg/deepviz"
+)
+
+func ExampleNew() {
+	_, err := deepviz.New(deepviz.Options{OutputDir: "out"})
+	fmt.Println(err)
+	// Output: deepviz: no API key
+}
+
+func ExampleClient_Run() {
+	client, err := deepviz.New(deepviz.Options{APIKey: os.Getenv("GEMINI_API_KEY"), OutputDir: "out"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	result, err := client.Run(context.Background(), "Trends in edge AI", &deepviz.RunOptions{
+		Name:  "edge-ai",
+		Image: deepviz.ImageOptions{Lang: "English", Style: "flat"},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result.ResearchPath)
+	fmt.Println(result.ImagePaths[0])
+}
+
+func ExampleClient_GenerateImage() {
+	client, err := deepviz.New(deepviz.Options{APIKey: os.Getenv("GEMINI_API_KEY"), OutputDir: "out"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	markdown, err := os.ReadFile("report.md")
+	if err != nil {
+		log.Fatal(err)
+	}
+	result, err := client.GenerateImage(context.Background(), string(markdown), &deepviz.ImageOptions{AspectRatio: "9:16", Count: 2})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result.ImagePaths)
+}
+
+func ExampleSinkFunc() {
+	// Artifacts are handed to the sink instead of being kept on disk (e.g., to upload them)
+	sink := deepviz.SinkFunc(func(name string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d bytes\n", name, len(data))
+		return nil
+	})
+
+	client, err := deepviz.New(deepviz.Options{APIKey: os.Getenv("GEMINI_API_KEY"), Sink: sink})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := client.Research(context.Background(), "Trends in edge AI", nil); err != nil {
+		log.Fatal(err)
+	}
+}